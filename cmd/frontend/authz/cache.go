@@ -0,0 +1,202 @@
+package authz
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var cacheTTL = env.Get("SRC_AUTHZ_CACHE_TTL", "0s", "how long repository permission decisions are cached per user (e.g. 30s); 0 disables the cache")
+
+var (
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_frontend_authz_cache_requests_total",
+		Help: "Number of repository permission lookups served by the authz cache, by result (hit or miss).",
+	}, []string{"result"})
+	cacheHitAge = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "src_frontend_authz_cache_hit_age_seconds",
+		Help:    "Age of cached repository permission decisions at the time they are served.",
+		Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	})
+	cacheInvalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_frontend_authz_cache_invalidations_total",
+		Help: "Number of authz cache invalidation events, by scope (user, repo or all).",
+	}, []string{"scope"})
+)
+
+// DefaultCache is the process-wide cache of repository permission decisions. It
+// is configured by SRC_AUTHZ_CACHE_TTL and disabled by default.
+var DefaultCache = NewCache(parseCacheTTL(cacheTTL))
+
+func parseCacheTTL(s string) time.Duration {
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		log15.Error("Invalid SRC_AUTHZ_CACHE_TTL, disabling authz cache", "value", s, "error", err)
+		return 0
+	}
+	return ttl
+}
+
+// cacheKey identifies a single permission decision. UserID is 0 for anonymous
+// users.
+type cacheKey struct {
+	UserID int32
+	RepoID api.RepoID
+	Perm   Perms
+}
+
+type cacheEntry struct {
+	allowed  bool
+	cachedAt time.Time
+}
+
+// Cache is a concurrency-safe cache of (user, repo) permission decisions. Entries
+// expire after the configured TTL, and are removed eagerly when an invalidation
+// event for the user or the repository is received (e.g. after a permissions
+// sync).
+//
+// Invalidation events of other processes (e.g. background permissions syncing
+// in repo-updater) are received with WatchInvalidations.
+type Cache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[cacheKey]cacheEntry
+	// gen is incremented by every invalidation, so that decisions computed
+	// concurrently with an invalidation are not stored.
+	gen uint64
+
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// NewCache returns a Cache whose entries expire after ttl. A non-positive ttl
+// disables the cache.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// SetTTL updates the TTL of the cache. Existing entries are dropped so that a
+// decreased TTL takes effect immediately.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+	c.gen++
+	c.entries = make(map[cacheKey]cacheEntry)
+}
+
+// Enabled returns true if the cache has a positive TTL.
+func (c *Cache) Enabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ttl > 0
+}
+
+// Filter returns the subset of repos (preserving their order) that userID has
+// permission p on. Decisions that are not cached are computed by calling fetch
+// with the uncached repositories, and are stored in the cache. Like authzFilter,
+// the repos slice is filtered in place and returned.
+func (c *Cache) Filter(userID int32, repos []*types.Repo, p Perms, fetch func([]*types.Repo) ([]*types.Repo, error)) ([]*types.Repo, error) {
+	allowed := make(map[api.RepoID]bool, len(repos))
+
+	var misses []*types.Repo
+	now := c.now()
+	c.mu.RLock()
+	gen := c.gen
+	for _, r := range repos {
+		e, ok := c.entries[cacheKey{UserID: userID, RepoID: r.ID, Perm: p}]
+		if !ok || now.Sub(e.cachedAt) >= c.ttl {
+			misses = append(misses, r)
+			continue
+		}
+		allowed[r.ID] = e.allowed
+		cacheHitAge.Observe(now.Sub(e.cachedAt).Seconds())
+	}
+	c.mu.RUnlock()
+
+	cacheRequests.WithLabelValues("hit").Add(float64(len(repos) - len(misses)))
+	cacheRequests.WithLabelValues("miss").Add(float64(len(misses)))
+
+	if len(misses) > 0 {
+		// fetch may filter misses in place, so remember what we asked for.
+		ids := make([]api.RepoID, len(misses))
+		for i, r := range misses {
+			ids[i] = r.ID
+		}
+
+		verified, err := fetch(misses)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range verified {
+			allowed[r.ID] = true
+		}
+
+		c.mu.Lock()
+		if c.gen == gen {
+			for _, id := range ids {
+				c.entries[cacheKey{UserID: userID, RepoID: id, Perm: p}] = cacheEntry{
+					allowed:  allowed[id],
+					cachedAt: now,
+				}
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	filtered := repos[:0]
+	for _, r := range repos {
+		if allowed[r.ID] {
+			filtered = append(filtered, r) // In-place filtering
+		}
+	}
+	for i := len(filtered); i < len(repos); i++ {
+		repos[i] = nil
+	}
+	return filtered, nil
+}
+
+// InvalidateUser removes all cached decisions for the given user.
+func (c *Cache) InvalidateUser(userID int32) {
+	cacheInvalidations.WithLabelValues("user").Inc()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for k := range c.entries {
+		if k.UserID == userID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// InvalidateRepo removes all cached decisions for the given repository.
+func (c *Cache) InvalidateRepo(repoID api.RepoID) {
+	cacheInvalidations.WithLabelValues("repo").Inc()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for k := range c.entries {
+		if k.RepoID == repoID {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// InvalidateAll removes all cached decisions.
+func (c *Cache) InvalidateAll() {
+	cacheInvalidations.WithLabelValues("all").Inc()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[cacheKey]cacheEntry)
+}
@@ -0,0 +1,97 @@
+package authz
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/redispool"
+)
+
+// cacheInvalidationChannel is the redis channel that invalidation events are
+// published on. Permissions are synced in repo-updater, so its events must
+// reach the caches of the frontend processes.
+const cacheInvalidationChannel = "authz-cache-invalidation"
+
+// InvalidateUser removes the cached decisions for the given user from the
+// caches of all processes.
+func InvalidateUser(userID int32) {
+	DefaultCache.InvalidateUser(userID)
+	publishInvalidation("user:" + strconv.FormatInt(int64(userID), 10))
+}
+
+// InvalidateRepo removes the cached decisions for the given repository from the
+// caches of all processes.
+func InvalidateRepo(repoID api.RepoID) {
+	DefaultCache.InvalidateRepo(repoID)
+	publishInvalidation("repo:" + strconv.FormatInt(int64(repoID), 10))
+}
+
+func publishInvalidation(event string) {
+	c := redispool.Store.Get()
+	defer c.Close()
+	if _, err := c.Do("PUBLISH", cacheInvalidationChannel, event); err != nil {
+		log15.Warn("Failed to publish authz cache invalidation", "event", event, "error", err)
+	}
+}
+
+// WatchInvalidations subscribes to the invalidation events published by all
+// processes (see InvalidateUser and InvalidateRepo) and applies them to c. It
+// never returns.
+//
+// Events published while the subscription is down are lost, so all entries are
+// dropped whenever it is (re)established.
+func (c *Cache) WatchInvalidations(pool *redis.Pool) {
+	for {
+		if err := c.watchInvalidations(pool); err != nil {
+			log15.Warn("Authz cache invalidation subscription failed, retrying", "error", err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (c *Cache) watchInvalidations(pool *redis.Pool) error {
+	conn := redis.PubSubConn{Conn: pool.Get()}
+	defer conn.Close()
+	if err := conn.Subscribe(cacheInvalidationChannel); err != nil {
+		return err
+	}
+	for {
+		switch v := conn.Receive().(type) {
+		case redis.Subscription:
+			if v.Kind == "subscribe" {
+				c.InvalidateAll()
+			}
+		case redis.Message:
+			c.applyInvalidation(string(v.Data))
+		case error:
+			return v
+		}
+	}
+}
+
+// applyInvalidation applies an invalidation event published by
+// publishInvalidation.
+func (c *Cache) applyInvalidation(event string) {
+	i := strings.IndexByte(event, ':')
+	if i < 0 {
+		log15.Warn("Invalid authz cache invalidation event", "event", event)
+		return
+	}
+	id, err := strconv.ParseInt(event[i+1:], 10, 32)
+	if err != nil {
+		log15.Warn("Invalid authz cache invalidation event", "event", event)
+		return
+	}
+	switch event[:i] {
+	case "user":
+		c.InvalidateUser(int32(id))
+	case "repo":
+		c.InvalidateRepo(api.RepoID(id))
+	default:
+		log15.Warn("Invalid authz cache invalidation event", "event", event)
+	}
+}
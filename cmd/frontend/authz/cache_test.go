@@ -0,0 +1,132 @@
+package authz
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestCacheFilter(t *testing.T) {
+	now := time.Now()
+	c := NewCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	repos := func(ids ...api.RepoID) []*types.Repo {
+		rs := make([]*types.Repo, len(ids))
+		for i, id := range ids {
+			rs[i] = &types.Repo{ID: id}
+		}
+		return rs
+	}
+	ids := func(rs []*types.Repo) []api.RepoID {
+		var ids []api.RepoID
+		for _, r := range rs {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	var fetched []api.RepoID
+	allowOdd := func(rs []*types.Repo) ([]*types.Repo, error) {
+		fetched = append(fetched, ids(rs)...)
+		filtered := rs[:0]
+		for _, r := range rs {
+			if r.ID%2 == 1 {
+				filtered = append(filtered, r)
+			}
+		}
+		return filtered, nil
+	}
+
+	filter := func(userID int32, rs ...api.RepoID) []api.RepoID {
+		t.Helper()
+		fetched = nil
+		filtered, err := c.Filter(userID, repos(rs...), Read, allowOdd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids(filtered)
+	}
+
+	if have, want := filter(1, 1, 2, 3), []api.RepoID{1, 3}; !cmp.Equal(have, want) {
+		t.Fatalf("filtered: %s", cmp.Diff(want, have))
+	}
+	if want := []api.RepoID{1, 2, 3}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched: %s", cmp.Diff(want, fetched))
+	}
+
+	// Cached decisions are reused, both positive and negative.
+	if have, want := filter(1, 3, 2, 4, 1), []api.RepoID{3, 1}; !cmp.Equal(have, want) {
+		t.Fatalf("filtered: %s", cmp.Diff(want, have))
+	}
+	if want := []api.RepoID{4}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched: %s", cmp.Diff(want, fetched))
+	}
+
+	// Decisions are per user.
+	filter(2, 1)
+	if want := []api.RepoID{1}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched: %s", cmp.Diff(want, fetched))
+	}
+
+	c.InvalidateRepo(1)
+	filter(1, 1, 3)
+	if want := []api.RepoID{1}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched after repo invalidation: %s", cmp.Diff(want, fetched))
+	}
+
+	c.InvalidateUser(1)
+	filter(1, 1, 3)
+	if want := []api.RepoID{1, 3}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched after user invalidation: %s", cmp.Diff(want, fetched))
+	}
+
+	now = now.Add(time.Minute)
+	filter(1, 1, 3)
+	if want := []api.RepoID{1, 3}; !cmp.Equal(fetched, want) {
+		t.Fatalf("fetched after expiry: %s", cmp.Diff(want, fetched))
+	}
+}
+
+func TestCacheEnabled(t *testing.T) {
+	c := NewCache(0)
+	if c.Enabled() {
+		t.Fatal("expected cache with zero TTL to be disabled")
+	}
+	c.SetTTL(time.Second)
+	if !c.Enabled() {
+		t.Fatal("expected cache with positive TTL to be enabled")
+	}
+}
+
+func TestCacheApplyInvalidation(t *testing.T) {
+	c := NewCache(time.Minute)
+	allowAll := func(rs []*types.Repo) ([]*types.Repo, error) { return rs, nil }
+	fill := func() {
+		t.Helper()
+		for _, userID := range []int32{1, 2} {
+			if _, err := c.Filter(userID, []*types.Repo{{ID: 1}, {ID: 2}}, Read, allowAll); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := map[string]int{
+		"user:1":   2,
+		"repo:2":   2,
+		"user:x":   4,
+		"org:1":    4,
+		"invalid":  4,
+		"user:999": 4,
+	}
+	for event, want := range tests {
+		fill()
+		c.applyInvalidation(event)
+		if have := len(c.entries); have != want {
+			t.Errorf("%s: got %d entries, want %d", event, have, want)
+		}
+	}
+}
@@ -33,6 +33,8 @@ func SetProviders(authzAllowByDefault bool, z []Provider) {
 
 	authzProviders = z
 	allowAccessByDefault = authzAllowByDefault
+	// Cached decisions were computed against the previous providers.
+	DefaultCache.InvalidateAll()
 	authzProvidersReadyOnce.Do(func() {
 		close(authzProvidersReady)
 	})
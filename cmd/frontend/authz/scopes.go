@@ -1,13 +1,119 @@
 package authz
 
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
 const (
 	// Access token scopes.
 	ScopeUserAll       = "user:all"        // Full control of all resources accessible to the user account.
 	ScopeSiteAdminSudo = "site-admin:sudo" // Ability to perform any action as any other user.
+	ScopeSearch        = "search"          // Ability to run search queries (and nothing else) as the user.
+
+	// Prefixes of access token scopes which further restrict a token with ScopeSearch.
+	ScopeSearchRepoPrefix    = "search:repo:"    // Followed by a regexp that the whole name of searched repositories must match.
+	ScopeSearchContextPrefix = "search:context:" // Followed by the name of a version context that searches must use.
 )
 
-// AllScopes is a list of all known access token scopes.
+// AllScopes is a list of all known access token scopes. It does not include the
+// parameterized ScopeSearchRepoPrefix and ScopeSearchContextPrefix scopes.
 var AllScopes = []string{
 	ScopeUserAll,
 	ScopeSiteAdminSudo,
+	ScopeSearch,
+}
+
+// SearchScope describes the restrictions of an access token that only has the
+// ScopeSearch scope (and not ScopeUserAll).
+type SearchScope struct {
+	// RepoPatterns, if non-empty, restricts searches to repositories whose name
+	// matches at least one of the patterns. The patterns are anchored, so they
+	// must match the whole name.
+	RepoPatterns []*regexp.Regexp
+
+	// VersionContexts, if non-empty, restricts searches to the named version
+	// contexts. Searches without a version context are rejected.
+	VersionContexts []string
+}
+
+// ParseSearchScope returns the SearchScope described by the given access token
+// scopes. It returns an error if a search restriction scope is malformed.
+func ParseSearchScope(scopes []string) (*SearchScope, error) {
+	s := &SearchScope{}
+	for _, scope := range scopes {
+		switch {
+		case strings.HasPrefix(scope, ScopeSearchRepoPrefix):
+			pattern := strings.TrimPrefix(scope, ScopeSearchRepoPrefix)
+			// Check the pattern alone, so that it can't close the group it is
+			// anchored with (e.g. "a)|(b").
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid repository pattern in access token scope %q: %s", scope, err)
+			}
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid repository pattern in access token scope %q: %s", scope, err)
+			}
+			s.RepoPatterns = append(s.RepoPatterns, re)
+		case strings.HasPrefix(scope, ScopeSearchContextPrefix):
+			name := strings.TrimPrefix(scope, ScopeSearchContextPrefix)
+			if name == "" {
+				return nil, fmt.Errorf("empty version context in access token scope %q", scope)
+			}
+			s.VersionContexts = append(s.VersionContexts, name)
+		}
+	}
+	return s, nil
+}
+
+// IsSearchRestrictionScope returns true if scope is a parameterized scope which
+// restricts a token with ScopeSearch.
+func IsSearchRestrictionScope(scope string) bool {
+	return strings.HasPrefix(scope, ScopeSearchRepoPrefix) || strings.HasPrefix(scope, ScopeSearchContextPrefix)
+}
+
+// AllowsRepo returns true if the repository may be searched.
+func (s *SearchScope) AllowsRepo(name api.RepoName) bool {
+	if len(s.RepoPatterns) == 0 {
+		return true
+	}
+	for _, re := range s.RepoPatterns {
+		if re.MatchString(string(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsVersionContext returns true if a search within the named version
+// context may be performed. An empty name means no version context.
+func (s *SearchScope) AllowsVersionContext(name string) bool {
+	if len(s.VersionContexts) == 0 {
+		return true
+	}
+	for _, vc := range s.VersionContexts {
+		if vc == name {
+			return true
+		}
+	}
+	return false
+}
+
+type searchScopeKey struct{}
+
+// WithSearchScope returns a context which records that the request was
+// authenticated with a search-only access token.
+func WithSearchScope(ctx context.Context, s *SearchScope) context.Context {
+	return context.WithValue(ctx, searchScopeKey{}, s)
+}
+
+// SearchScopeFromContext returns the SearchScope of the request, or nil if the
+// request was not authenticated with a search-only access token.
+func SearchScopeFromContext(ctx context.Context) *SearchScope {
+	s, _ := ctx.Value(searchScopeKey{}).(*SearchScope)
+	return s
 }
@@ -0,0 +1,34 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestSearchScope_AllowsRepo(t *testing.T) {
+	s, err := ParseSearchScope([]string{ScopeSearch, ScopeSearchRepoPrefix + `github\.com/acme/api`, ScopeSearchRepoPrefix + `github\.com/acme/web-.*`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[api.RepoName]bool{
+		"github.com/acme/api":          true,
+		"github.com/acme/web-app":      true,
+		"github.com/acme/api-internal": false,
+		"evil/github.com/acme/api":     false,
+		"github.com/acme/web":          false,
+	}
+	for name, want := range tests {
+		if got := s.AllowsRepo(name); got != want {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseSearchScope_invalidRepoPattern(t *testing.T) {
+	for _, pattern := range []string{"(", "a)|(b"} {
+		if _, err := ParseSearchScope([]string{ScopeSearch, ScopeSearchRepoPrefix + pattern}); err == nil {
+			t.Errorf("%q: got nil error", pattern)
+		}
+	}
+}
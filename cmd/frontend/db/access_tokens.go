@@ -150,6 +150,29 @@ func (s *accessTokens) GetByID(ctx context.Context, id int64) (*AccessToken, err
 	return results[0], nil
 }
 
+// GetByToken retrieves the access token (if any) given the secret token value itself.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is permitted to view this access token.
+func (s *accessTokens) GetByToken(ctx context.Context, tokenHexEncoded string) (*AccessToken, error) {
+	if Mocks.AccessTokens.GetByToken != nil {
+		return Mocks.AccessTokens.GetByToken(tokenHexEncoded)
+	}
+
+	token, err := hex.DecodeString(tokenHexEncoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "AccessTokens.GetByToken")
+	}
+
+	results, err := s.list(ctx, []*sqlf.Query{sqlf.Sprintf("value_sha256=%s", toSHA256Bytes(token)), sqlf.Sprintf("deleted_at IS NULL")}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrAccessTokenNotFound
+	}
+	return results[0], nil
+}
+
 // AccessTokensListOptions contains options for listing access tokens.
 type AccessTokensListOptions struct {
 	SubjectUserID  int32 // only list access tokens with this user as the subject
@@ -268,5 +291,6 @@ type MockAccessTokens struct {
 	Create     func(subjectUserID int32, scopes []string, note string, creatorUserID int32) (id int64, token string, err error)
 	DeleteByID func(id int64, subjectUserID int32) error
 	Lookup     func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error)
+	GetByToken func(tokenHexEncoded string) (*AccessToken, error)
 	GetByID    func(id int64) (*AccessToken, error)
 }
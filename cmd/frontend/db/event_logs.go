@@ -31,6 +31,9 @@ type Event struct {
 	Argument        json.RawMessage
 	Source          string
 	Timestamp       time.Time
+	// ExperimentArms are the arms of the search experiments that the user
+	// was assigned to, by experiment name.
+	ExperimentArms map[string]string
 }
 
 func (*eventLogs) Insert(ctx context.Context, e *Event) error {
@@ -38,10 +41,18 @@ func (*eventLogs) Insert(ctx context.Context, e *Event) error {
 	if argument == nil {
 		argument = json.RawMessage([]byte(`{}`))
 	}
+	var experimentArms interface{} // NULL if the user is in no experiment
+	if len(e.ExperimentArms) > 0 {
+		b, err := json.Marshal(e.ExperimentArms)
+		if err != nil {
+			return err
+		}
+		experimentArms = string(b)
+	}
 
 	_, err := dbconn.Global.ExecContext(
 		ctx,
-		"INSERT INTO event_logs(name, url, user_id, anonymous_user_id, source, argument, version, timestamp) VALUES($1, $2, $3, $4, $5, $6, $7, $8)",
+		"INSERT INTO event_logs(name, url, user_id, anonymous_user_id, source, argument, version, timestamp, experiment_arms) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)",
 		e.Name,
 		e.URL,
 		e.UserID,
@@ -50,6 +61,7 @@ func (*eventLogs) Insert(ctx context.Context, e *Event) error {
 		argument,
 		version.Version(),
 		e.Timestamp.UTC(),
+		experimentArms,
 	)
 	if err != nil {
 		return errors.Wrap(err, "INSERT")
@@ -23,4 +23,12 @@ type MockStores struct {
 	ExternalServices MockExternalServices
 
 	Authz MockAuthz
+
+	SearchAnalytics MockSearchAnalytics
+
+	SearchFeatureFlagOverrides MockSearchFeatureFlagOverrides
+
+	SearchExcludedRepos MockSearchExcludedRepos
+
+	SearchExports MockSearchExports
 }
@@ -98,6 +98,54 @@ func (s *repos) GetByName(ctx context.Context, nameOrURI api.RepoName) (*types.R
 	return repos[0], nil
 }
 
+// GetByURIs returns the repositories with the given names or URIs in a single
+// query. As with GetByName, a match on name is preferred over a match on URI.
+// Names and URIs that no repository has are omitted, so the result may have
+// fewer repositories than nameOrURIs.
+func (s *repos) GetByURIs(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+	if Mocks.Repos.GetByURIs != nil {
+		return Mocks.Repos.GetByURIs(ctx, nameOrURIs)
+	}
+
+	if len(nameOrURIs) == 0 {
+		return []*types.Repo{}, nil
+	}
+
+	items := make([]*sqlf.Query, len(nameOrURIs))
+	for i := range nameOrURIs {
+		items[i] = sqlf.Sprintf("%s", nameOrURIs[i])
+	}
+	list := sqlf.Join(items, ",")
+	repos, err := s.getBySQL(ctx, sqlf.Sprintf("name IN (%s) OR uri IN (%s)", list, list))
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(nameOrURIs))
+	for _, v := range nameOrURIs {
+		wanted[v] = true
+	}
+	found := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		if wanted[string(repo.Name)] {
+			found[string(repo.Name)] = true
+		}
+	}
+
+	results := repos[:0]
+	for _, repo := range repos {
+		switch {
+		case wanted[string(repo.Name)]:
+			results = append(results, repo)
+		case !found[repo.URI]:
+			// uri is not unique, so only return the first repository with it.
+			found[repo.URI] = true
+			results = append(results, repo)
+		}
+	}
+	return results, nil
+}
+
 // GetByIDs returns a list of repositories by given IDs. The number of results list could be less
 // than the candidate list due to no repository is associated with some IDs.
 func (s *repos) GetByIDs(ctx context.Context, ids ...api.RepoID) ([]*types.Repo, error) {
@@ -117,6 +165,50 @@ func (s *repos) GetByIDs(ctx context.Context, ids ...api.RepoID) ([]*types.Repo,
 	return s.getReposBySQL(ctx, true, q)
 }
 
+// GetSizes returns the sizes in bytes of the repositories with the given IDs,
+// as reported by their code host. Repositories whose size is unknown (which
+// is the case for all repositories that are not on GitHub) are omitted.
+func (s *repos) GetSizes(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int64, error) {
+	if Mocks.Repos.GetSizes != nil {
+		return Mocks.Repos.GetSizes(ctx, ids...)
+	}
+
+	sizes := map[api.RepoID]int64{}
+	if len(ids) == 0 {
+		return sizes, nil
+	}
+
+	items := make([]*sqlf.Query, len(ids))
+	for i := range ids {
+		items[i] = sqlf.Sprintf("%d", ids[i])
+	}
+	// The metadata of GitHub repositories is a github.Repository, whose
+	// DiskUsage is in kibibytes.
+	q := sqlf.Sprintf(`
+SELECT id, (metadata->>'DiskUsage')::bigint * 1024 FROM repo
+WHERE id IN (%s) AND deleted_at IS NULL AND jsonb_typeof(metadata->'DiskUsage') = 'number'`,
+		sqlf.Join(items, ","))
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   api.RepoID
+			size int64
+		)
+		if err := rows.Scan(&id, &size); err != nil {
+			return nil, err
+		}
+		if size > 0 {
+			sizes[id] = size
+		}
+	}
+	return sizes, rows.Err()
+}
+
 func (s *repos) Count(ctx context.Context, opt ReposListOptions) (int, error) {
 	if Mocks.Repos.Count != nil {
 		return Mocks.Repos.Count(ctx, opt)
@@ -161,6 +253,18 @@ func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types
 }
 
 func (s *repos) getReposBySQL(ctx context.Context, minimal bool, querySuffix *sqlf.Query) ([]*types.Repo, error) {
+	repos, err := s.queryRepos(ctx, minimal, querySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	// 🚨 SECURITY: This enforces repository permissions
+	return authzFilter(ctx, repos, authz.Read)
+}
+
+// queryRepos returns the repositories matching querySuffix, without
+// filtering them by the permissions of the current user.
+func (s *repos) queryRepos(ctx context.Context, minimal bool, querySuffix *sqlf.Query) ([]*types.Repo, error) {
 	columns := getBySQLColumns
 	if minimal {
 		columns = columns[:6]
@@ -193,9 +297,7 @@ func (s *repos) getReposBySQL(ctx context.Context, minimal bool, querySuffix *sq
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
-
-	// 🚨 SECURITY: This enforces repository permissions
-	return authzFilter(ctx, repos, authz.Read)
+	return repos, nil
 }
 
 func scanRepo(rows *sql.Rows, r *types.Repo) (err error) {
@@ -270,6 +372,14 @@ type ReposListOptions struct {
 	// OnlyPrivate excludes non-private repositories from the list.
 	OnlyPrivate bool
 
+	// NoSearchExcluded excludes repositories that site admins excluded from
+	// search (see SearchExcludedRepos) from the list.
+	NoSearchExcluded bool
+
+	// OnlySearchExcluded excludes repositories that are not excluded from
+	// search from the list.
+	OnlySearchExcluded bool
+
 	// OnlyRepoIDs skips fetching of RepoFields in each Repo.
 	OnlyRepoIDs bool
 
@@ -348,6 +458,95 @@ func (s *repos) List(ctx context.Context, opt ReposListOptions) (results []*type
 	return s.getReposBySQL(ctx, opt.OnlyRepoIDs, fetchSQL)
 }
 
+// Iterate returns an iterator over the repositories matching opt, which fetches
+// them in pages of pageSize repositories ordered by ID. Pages are fetched with
+// keyset pagination (i.e. by the ID of the last repository of the previous
+// page instead of an offset), so that enumerating all repositories doesn't
+// require a single large query or holding all repositories in memory at once.
+//
+// opt.OrderBy and opt.LimitOffset.Offset are ignored. opt.LimitOffset.Limit,
+// if set, limits the total number of repositories. pageSize must be positive.
+func (s *repos) Iterate(opt ReposListOptions, pageSize int) *ReposIterator {
+	it := &ReposIterator{s: s, opt: opt, pageSize: pageSize, remaining: -1}
+	if opt.LimitOffset != nil {
+		it.remaining = opt.LimitOffset.Limit
+	}
+	return it
+}
+
+// ReposIterator iterates over pages of repositories (see Repos.Iterate).
+type ReposIterator struct {
+	s         *repos
+	opt       ReposListOptions
+	pageSize  int
+	afterID   api.RepoID
+	remaining int // -1 if unlimited
+	done      bool
+}
+
+// Next returns the next page of repositories, or an empty page once all
+// repositories have been returned.
+func (it *ReposIterator) Next(ctx context.Context) (_ []*types.Repo, err error) {
+	// Mocks of List also return the repositories of iterators, in one page.
+	if Mocks.Repos.List != nil {
+		if it.done {
+			return nil, nil
+		}
+		it.done = true
+		return Mocks.Repos.List(ctx, it.opt)
+	}
+
+	// Skip pages of repositories the user can't see.
+	for !it.done {
+		repos, err := it.nextPage(ctx)
+		if err != nil || len(repos) > 0 {
+			return repos, err
+		}
+	}
+	return nil, nil
+}
+
+func (it *ReposIterator) nextPage(ctx context.Context) (_ []*types.Repo, err error) {
+	tr, ctx := trace.New(ctx, "repos.Iterate", "")
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	conds, err := it.s.listSQL(it.opt)
+	if err != nil {
+		return nil, err
+	}
+	conds = append(conds, sqlf.Sprintf("id > %d", it.afterID))
+
+	limit := it.pageSize
+	if it.remaining >= 0 && it.remaining < limit {
+		limit = it.remaining
+	}
+	fetchSQL := sqlf.Sprintf("%s ORDER BY id ASC LIMIT %d", sqlf.Join(conds, "AND"), limit)
+	tr.LogFields(trace.SQL(fetchSQL))
+
+	// Paginate by the repositories before they are filtered by permissions,
+	// so that a page of repositories the user can't see isn't mistaken for
+	// the last page.
+	repos, err := it.s.queryRepos(ctx, it.opt.OnlyRepoIDs, fetchSQL)
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) > 0 {
+		it.afterID = repos[len(repos)-1].ID
+	}
+	if it.remaining >= 0 {
+		it.remaining -= len(repos)
+	}
+	if len(repos) < limit || it.remaining == 0 {
+		it.done = true
+	}
+
+	// 🚨 SECURITY: This enforces repository permissions
+	return authzFilter(ctx, repos, authz.Read)
+}
+
 // ListEnabledNames returns a list of all enabled repo names. This is commonly
 // requested information by other services (repo-updater and
 // indexed-search). We special case just returning enabled names so that we
@@ -464,6 +663,12 @@ func (*repos) listSQL(opt ReposListOptions) (conds []*sqlf.Query, err error) {
 	if opt.OnlyPrivate {
 		conds = append(conds, sqlf.Sprintf("private"))
 	}
+	if opt.NoSearchExcluded {
+		conds = append(conds, sqlf.Sprintf("NOT EXISTS (SELECT 1 FROM search_excluded_repos WHERE search_excluded_repos.repo_id = repo.id)"))
+	}
+	if opt.OnlySearchExcluded {
+		conds = append(conds, sqlf.Sprintf("EXISTS (SELECT 1 FROM search_excluded_repos WHERE search_excluded_repos.repo_id = repo.id)"))
+	}
 	if len(opt.Names) > 0 {
 		queries := make([]*sqlf.Query, 0, len(opt.Names))
 		for _, repo := range opt.Names {
@@ -235,6 +235,63 @@ func TestRepos_GetByIDs(t *testing.T) {
 	}
 }
 
+func TestRepos_GetByURIs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	repos := mustCreate(ctx, t, &types.Repo{Name: "a"}, &types.Repo{Name: "b"}, &types.Repo{Name: "c"})
+	// The URI of c is the name of a, which must be preferred.
+	for _, u := range []struct {
+		uri string
+		id  api.RepoID
+	}{{"example.com/b", repos[1].ID}, {"a", repos[2].ID}} {
+		if _, err := dbconn.Global.ExecContext(ctx, "UPDATE repo SET uri = $1 WHERE id = $2", u.uri, u.id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Repos.GetByURIs(ctx, []string{"a", "example.com/b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []api.RepoName{"a", "b"}; !reflect.DeepEqual(sortedRepoNames(got), want) {
+		t.Errorf("got repos %v, want %v", sortedRepoNames(got), want)
+	}
+	for _, repo := range got {
+		if repo.RepoFields == nil {
+			t.Errorf("expected RepoFields of %s to be fetched", repo.Name)
+		}
+	}
+}
+
+func TestRepos_GetSizes(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	repos := mustCreate(ctx, t, &types.Repo{Name: "a"}, &types.Repo{Name: "b"}, &types.Repo{Name: "c"})
+	for i, metadata := range []string{`{"DiskUsage": 2}`, `{"DiskUsage": "2"}`, `{}`} {
+		if _, err := dbconn.Global.ExecContext(ctx, "UPDATE repo SET metadata = $1 WHERE id = $2", metadata, repos[i].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sizes, err := Repos.GetSizes(ctx, repos[0].ID, repos[1].ID, repos[2].ID, 404)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[api.RepoID]int64{repos[0].ID: 2048}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("got sizes %v, want %v", sizes, want)
+	}
+}
+
 func TestRepos_List(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -260,6 +317,59 @@ func TestRepos_List(t *testing.T) {
 	}
 }
 
+func TestRepos_Iterate(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	// Hide b, which must not end the iteration early.
+	MockAuthzFilter = func(ctx context.Context, repos []*types.Repo, p authz.Perms) ([]*types.Repo, error) {
+		var visible []*types.Repo
+		for _, repo := range repos {
+			if repo.Name != "b" {
+				visible = append(visible, repo)
+			}
+		}
+		return visible, nil
+	}
+	defer func() { MockAuthzFilter = nil }()
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+	ctx = actor.WithActor(ctx, &actor.Actor{})
+
+	createRepo(ctx, t, &types.Repo{Name: "a"})
+	createRepo(ctx, t, &types.Repo{Name: "b"})
+	createRepo(ctx, t, &types.Repo{Name: "c"})
+	createRepo(ctx, t, &types.Repo{Name: "d"})
+	createRepo(ctx, t, &types.Repo{Name: "e"})
+
+	for _, tc := range []struct {
+		opt  ReposListOptions
+		want []api.RepoName
+	}{
+		{opt: ReposListOptions{}, want: []api.RepoName{"a", "c", "d", "e"}},
+		{opt: ReposListOptions{LimitOffset: &LimitOffset{Limit: 3}}, want: []api.RepoName{"a", "c"}},
+		{opt: ReposListOptions{IncludePatterns: []string{"[bde]"}}, want: []api.RepoName{"d", "e"}},
+	} {
+		it := Repos.Iterate(tc.opt, 1)
+		var got []api.RepoName
+		for {
+			repos, err := it.Next(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(repos) == 0 {
+				break
+			}
+			got = append(got, repoNames(repos)...)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v: got %v, want %v", tc.opt, got, tc.want)
+		}
+	}
+}
+
 func TestRepos_List_fork(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
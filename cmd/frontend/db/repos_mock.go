@@ -12,7 +12,9 @@ import (
 type MockRepos struct {
 	Get       func(ctx context.Context, repo api.RepoID) (*types.Repo, error)
 	GetByName func(ctx context.Context, repo api.RepoName) (*types.Repo, error)
+	GetByURIs func(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error)
 	GetByIDs  func(ctx context.Context, ids ...api.RepoID) ([]*types.Repo, error)
+	GetSizes  func(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int64, error)
 	List      func(v0 context.Context, v1 ReposListOptions) ([]*types.Repo, error)
 	Count     func(ctx context.Context, opt ReposListOptions) (int, error)
 }
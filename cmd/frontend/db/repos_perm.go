@@ -38,6 +38,9 @@ var MockAuthzFilter func(ctx context.Context, repos []*types.Repo, p authz.Perms
 //
 // The enforcement policy:
 //
+// - If authz.DefaultCache is enabled, decisions cached for the current user are reused and only
+//   the remaining repositories are checked as described below.
+//
 // - If permissions user mapping is enabled, directly check permissions against local Postgres.
 //
 // - If there are no authz providers and `authzAllowByDefault` is true, then the repository is
@@ -102,6 +105,22 @@ func authzFilter(ctx context.Context, repos []*types.Repo, p authz.Perms) (filte
 		}
 	}
 
+	if authz.DefaultCache.Enabled() {
+		var userID int32
+		if currentUser != nil {
+			userID = currentUser.ID
+		}
+		return authz.DefaultCache.Filter(userID, repos, p, func(repos []*types.Repo) ([]*types.Repo, error) {
+			return authzFilterUncached(ctx, tr, currentUser, repos, p)
+		})
+	}
+	return authzFilterUncached(ctx, tr, currentUser, repos, p)
+}
+
+// authzFilterUncached enforces repository permissions for currentUser (which is
+// nil for anonymous users) without consulting authz.DefaultCache. See
+// authzFilter for the enforcement policy.
+func authzFilterUncached(ctx context.Context, tr *trace.Trace, currentUser *types.User, repos []*types.Repo, p authz.Perms) (filtered []*types.Repo, err error) {
 	authzAllowByDefault, authzProviders := authz.GetProviders()
 	tr.LogFields(
 		otlog.Bool("authzAllowByDefault", authzAllowByDefault),
@@ -56,7 +56,8 @@ func (s *savedSearches) ListAll(ctx context.Context) (savedSearches []api.SavedQ
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url FROM saved_searches
+		slack_webhook_url,
+		webhook_url FROM saved_searches
 	`)
 	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar))
 	if err != nil {
@@ -73,7 +74,8 @@ func (s *savedSearches) ListAll(ctx context.Context) (savedSearches []api.SavedQ
 			&sq.Config.NotifySlack,
 			&sq.Config.UserID,
 			&sq.Config.OrgID,
-			&sq.Config.SlackWebhookURL); err != nil {
+			&sq.Config.SlackWebhookURL,
+			&sq.Config.WebhookURL); err != nil {
 			return nil, errors.Wrap(err, "Scan")
 		}
 		sq.Spec.Key = sq.Config.Key
@@ -106,7 +108,8 @@ func (s *savedSearches) GetByID(ctx context.Context, id int32) (*api.SavedQueryS
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url
 		FROM saved_searches WHERE id=$1`, id).Scan(
 		&sq.Config.Key,
 		&sq.Config.Description,
@@ -115,7 +118,8 @@ func (s *savedSearches) GetByID(ctx context.Context, id int32) (*api.SavedQueryS
 		&sq.Config.NotifySlack,
 		&sq.Config.UserID,
 		&sq.Config.OrgID,
-		&sq.Config.SlackWebhookURL)
+		&sq.Config.SlackWebhookURL,
+		&sq.Config.WebhookURL)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +170,8 @@ func (s *savedSearches) ListSavedSearchesByUserID(ctx context.Context, userID in
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url
 		FROM saved_searches %v`, conds)
 
 	rows, err := dbconn.Global.QueryContext(ctx, query.Query(sqlf.PostgresBindVar), query.Args()...)
@@ -175,7 +180,7 @@ func (s *savedSearches) ListSavedSearchesByUserID(ctx context.Context, userID in
 	}
 	for rows.Next() {
 		var ss types.SavedSearch
-		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL); err != nil {
+		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL, &ss.WebhookURL); err != nil {
 			return nil, errors.Wrap(err, "Scan(2)")
 		}
 		savedSearches = append(savedSearches, &ss)
@@ -201,7 +206,8 @@ func (s *savedSearches) ListSavedSearchesByOrgID(ctx context.Context, orgID int3
 		notify_slack,
 		user_id,
 		org_id,
-		slack_webhook_url
+		slack_webhook_url,
+		webhook_url
 		FROM saved_searches %v`, conds)
 
 	rows, err := dbconn.Global.QueryContext(ctx, query.Query(sqlf.PostgresBindVar), query.Args()...)
@@ -210,7 +216,7 @@ func (s *savedSearches) ListSavedSearchesByOrgID(ctx context.Context, orgID int3
 	}
 	for rows.Next() {
 		var ss types.SavedSearch
-		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL); err != nil {
+		if err := rows.Scan(&ss.ID, &ss.Description, &ss.Query, &ss.Notify, &ss.NotifySlack, &ss.UserID, &ss.OrgID, &ss.SlackWebhookURL, &ss.WebhookURL); err != nil {
 			return nil, errors.Wrap(err, "Scan")
 		}
 		savedSearches = append(savedSearches, &ss)
@@ -246,6 +252,7 @@ func (s *savedSearches) Create(ctx context.Context, newSavedSearch *types.SavedS
 		NotifySlack: newSavedSearch.NotifySlack,
 		UserID:      newSavedSearch.UserID,
 		OrgID:       newSavedSearch.OrgID,
+		WebhookURL:  newSavedSearch.WebhookURL,
 	}
 
 	err = dbconn.Global.QueryRowContext(ctx, `INSERT INTO saved_searches(
@@ -254,14 +261,16 @@ func (s *savedSearches) Create(ctx context.Context, newSavedSearch *types.SavedS
 			notify_owner,
 			notify_slack,
 			user_id,
-			org_id
-		) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`,
+			org_id,
+			webhook_url
+		) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
 		newSavedSearch.Description,
 		newSavedSearch.Query,
 		newSavedSearch.Notify,
 		newSavedSearch.NotifySlack,
 		newSavedSearch.UserID,
 		newSavedSearch.OrgID,
+		newSavedSearch.WebhookURL,
 	).Scan(&savedQuery.ID)
 	if err != nil {
 		return nil, err
@@ -293,6 +302,7 @@ func (s *savedSearches) Update(ctx context.Context, savedSearch *types.SavedSear
 		UserID:          savedSearch.UserID,
 		OrgID:           savedSearch.OrgID,
 		SlackWebhookURL: savedSearch.SlackWebhookURL,
+		WebhookURL:      savedSearch.WebhookURL,
 	}
 
 	fieldUpdates := []*sqlf.Query{
@@ -304,6 +314,7 @@ func (s *savedSearches) Update(ctx context.Context, savedSearch *types.SavedSear
 		sqlf.Sprintf("user_id=%v", savedSearch.UserID),
 		sqlf.Sprintf("org_id=%v", savedSearch.OrgID),
 		sqlf.Sprintf("slack_webhook_url=%v", savedSearch.SlackWebhookURL),
+		sqlf.Sprintf("webhook_url=%v", savedSearch.WebhookURL),
 	}
 
 	updateQuery := sqlf.Sprintf(`UPDATE saved_searches SET %s WHERE ID=%v RETURNING id`, sqlf.Join(fieldUpdates, ", "), savedSearch.ID)
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+)
+
+// searchAnalytics provides access to the search_analytics_rollups table, which
+// holds daily counts of searches by anonymized query features.
+type searchAnalytics struct{}
+
+// Add adds counts to the rollups of day (truncated to the UTC day).
+func (*searchAnalytics) Add(ctx context.Context, day time.Time, counts map[types.SearchQueryFeatures]int32) error {
+	if Mocks.SearchAnalytics.Add != nil {
+		return Mocks.SearchAnalytics.Add(ctx, day, counts)
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	d := day.UTC().Format("2006-01-02")
+	values := make([]*sqlf.Query, 0, len(counts))
+	for f, count := range counts {
+		values = append(values, sqlf.Sprintf("(%s, %s, %s, %s, %s, %s, %s)", d, f.PatternType, f.PatternLength, f.Filters, f.Latency, f.ZeroResults, count))
+	}
+	q := sqlf.Sprintf(`
+INSERT INTO search_analytics_rollups (day, pattern_type, pattern_length, filters, latency, zero_results, count)
+VALUES %s
+ON CONFLICT (day, pattern_type, pattern_length, filters, latency, zero_results)
+DO UPDATE SET count = search_analytics_rollups.count + EXCLUDED.count
+`, sqlf.Join(values, ","))
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// List returns the rollups of all days since the given time (inclusive of its
+// UTC day), most recent day first.
+func (*searchAnalytics) List(ctx context.Context, since time.Time) ([]*types.SearchAnalyticsRollup, error) {
+	if Mocks.SearchAnalytics.List != nil {
+		return Mocks.SearchAnalytics.List(ctx, since)
+	}
+
+	q := sqlf.Sprintf(`
+SELECT day, pattern_type, pattern_length, filters, latency, zero_results, count
+FROM search_analytics_rollups
+WHERE day >= %s
+ORDER BY day DESC, count DESC, pattern_type, pattern_length, filters, latency, zero_results
+`, since.UTC().Format("2006-01-02"))
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rollups []*types.SearchAnalyticsRollup
+	for rows.Next() {
+		var r types.SearchAnalyticsRollup
+		if err := rows.Scan(&r.Day, &r.PatternType, &r.PatternLength, &r.Filters, &r.Latency, &r.ZeroResults, &r.Count); err != nil {
+			return nil, err
+		}
+		rollups = append(rollups, &r)
+	}
+	return rollups, rows.Err()
+}
+
+// DeleteBefore deletes the rollups of all days before the given time's UTC
+// day.
+func (*searchAnalytics) DeleteBefore(ctx context.Context, before time.Time) error {
+	q := sqlf.Sprintf("DELETE FROM search_analytics_rollups WHERE day < %s", before.UTC().Format("2006-01-02"))
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+type MockSearchAnalytics struct {
+	Add  func(ctx context.Context, day time.Time, counts map[types.SearchQueryFeatures]int32) error
+	List func(ctx context.Context, since time.Time) ([]*types.SearchAnalyticsRollup, error)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestSearchAnalytics_AddList(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	day := time.Date(2020, 6, 1, 15, 0, 0, 0, time.UTC)
+	literal := types.SearchQueryFeatures{PatternType: "literal", PatternLength: "4-10", Filters: "repo", Latency: "<100ms"}
+	regexp := types.SearchQueryFeatures{PatternType: "regexp", PatternLength: "1-3", Latency: "1s-5s", ZeroResults: true}
+
+	if err := SearchAnalytics.Add(ctx, day, map[types.SearchQueryFeatures]int32{literal: 2, regexp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SearchAnalytics.Add(ctx, day.Add(time.Hour), map[types.SearchQueryFeatures]int32{literal: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SearchAnalytics.Add(ctx, day.AddDate(0, 0, -2), map[types.SearchQueryFeatures]int32{literal: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	rollups, err := SearchAnalytics.List(ctx, day.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have []types.SearchAnalyticsRollup
+	for _, r := range rollups {
+		r.Day = r.Day.UTC()
+		have = append(have, *r)
+	}
+	midnight := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	want := []types.SearchAnalyticsRollup{
+		{Day: midnight, SearchQueryFeatures: literal, Count: 5},
+		{Day: midnight, SearchQueryFeatures: regexp, Count: 1},
+	}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatalf("rollups mismatch (-want +have):\n%s", diff)
+	}
+
+	if err := SearchAnalytics.DeleteBefore(ctx, day); err != nil {
+		t.Fatal(err)
+	}
+	rollups, err = SearchAnalytics.List(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rollups) != 2 {
+		t.Fatalf("got %d rollups after deletion, want 2", len(rollups))
+	}
+}
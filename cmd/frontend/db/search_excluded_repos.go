@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+)
+
+// searchExcludedRepos provides access to the search_excluded_repos table,
+// which holds the repositories that site admins excluded from search. Searches
+// skip them with ReposListOptions.NoSearchExcluded.
+type searchExcludedRepos struct{}
+
+// Add excludes a repository from search, or updates the reason it is
+// excluded for.
+func (*searchExcludedRepos) Add(ctx context.Context, repoID api.RepoID, reason string) error {
+	if Mocks.SearchExcludedRepos.Add != nil {
+		return Mocks.SearchExcludedRepos.Add(ctx, repoID, reason)
+	}
+
+	q := sqlf.Sprintf(`
+INSERT INTO search_excluded_repos (repo_id, reason)
+VALUES (%s, %s)
+ON CONFLICT (repo_id) DO UPDATE SET reason = EXCLUDED.reason
+`, repoID, reason)
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// Remove includes a repository in search again, if it was excluded.
+func (*searchExcludedRepos) Remove(ctx context.Context, repoID api.RepoID) error {
+	if Mocks.SearchExcludedRepos.Remove != nil {
+		return Mocks.SearchExcludedRepos.Remove(ctx, repoID)
+	}
+
+	q := sqlf.Sprintf("DELETE FROM search_excluded_repos WHERE repo_id=%s", repoID)
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// List returns the repositories excluded from search, most recently excluded
+// first.
+func (*searchExcludedRepos) List(ctx context.Context) ([]*types.SearchExcludedRepo, error) {
+	if Mocks.SearchExcludedRepos.List != nil {
+		return Mocks.SearchExcludedRepos.List(ctx)
+	}
+
+	q := sqlf.Sprintf(`
+SELECT search_excluded_repos.repo_id, search_excluded_repos.reason, search_excluded_repos.created_at
+FROM search_excluded_repos
+JOIN repo ON repo.id = search_excluded_repos.repo_id
+WHERE repo.deleted_at IS NULL
+ORDER BY search_excluded_repos.created_at DESC, search_excluded_repos.repo_id
+`)
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []*types.SearchExcludedRepo
+	for rows.Next() {
+		var r types.SearchExcludedRepo
+		if err := rows.Scan(&r.RepoID, &r.Reason, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		repos = append(repos, &r)
+	}
+	return repos, rows.Err()
+}
+
+type MockSearchExcludedRepos struct {
+	Add    func(ctx context.Context, repoID api.RepoID, reason string) error
+	Remove func(ctx context.Context, repoID api.RepoID) error
+	List   func(ctx context.Context) ([]*types.SearchExcludedRepo, error)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestSearchExcludedRepos(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	MockAuthzFilter = func(ctx context.Context, repos []*types.Repo, p authz.Perms) ([]*types.Repo, error) {
+		return repos, nil
+	}
+	defer func() { MockAuthzFilter = nil }()
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := actor.WithActor(context.Background(), &actor.Actor{})
+
+	searched := mustCreate(ctx, t, &types.Repo{Name: "a/r"})
+	excluded := mustCreate(ctx, t, &types.Repo{Name: "b/r"})
+
+	if err := SearchExcludedRepos.Add(ctx, excluded[0].ID, "too large"); err != nil {
+		t.Fatal(err)
+	}
+	// Adding an excluded repository again updates its reason.
+	if err := SearchExcludedRepos.Add(ctx, excluded[0].ID, "sensitive"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := SearchExcludedRepos.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].RepoID != excluded[0].ID || list[0].Reason != "sensitive" {
+		t.Errorf("got %+v, want %s excluded because it is sensitive", list, excluded[0].Name)
+	}
+
+	{
+		repos, err := Repos.List(ctx, ReposListOptions{NoSearchExcluded: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, searched, repos)
+	}
+	{
+		repos, err := Repos.List(ctx, ReposListOptions{OnlySearchExcluded: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertJSONEqual(t, excluded, repos)
+	}
+
+	if err := SearchExcludedRepos.Remove(ctx, excluded[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := SearchExcludedRepos.List(ctx); err != nil || len(list) != 0 {
+		t.Errorf("got %+v, %v after removing, want no excluded repositories", list, err)
+	}
+}
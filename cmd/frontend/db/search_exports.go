@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+)
+
+// ErrTooManySearchExports is returned by SearchExports.Create if the user
+// already has the maximum number of exports in progress.
+var ErrTooManySearchExports = errors.New("too many search exports in progress, wait for one of them to finish")
+
+// searchExports provides access to the search_exports table, which holds the
+// exports of search results of all frontend replicas.
+type searchExports struct{}
+
+// Create creates a processing export, unless its user already has maxPending
+// exports in progress that were created after staleBefore (older ones are
+// considered abandoned, e.g. because their frontend restarted).
+func (*searchExports) Create(ctx context.Context, e *types.SearchExport, maxPending int, staleBefore time.Time) error {
+	if Mocks.SearchExports.Create != nil {
+		return Mocks.SearchExports.Create(ctx, e, maxPending, staleBefore)
+	}
+
+	q := sqlf.Sprintf(`
+INSERT INTO search_exports (id, user_id, format, signing_key)
+SELECT %s, %s, %s, %s
+WHERE (
+	SELECT COUNT(*) FROM search_exports
+	WHERE user_id = %s AND state = 'PROCESSING' AND created_at > %s
+) < %s
+RETURNING state, created_at
+`, e.ID, e.UserID, e.Format, e.SigningKey, e.UserID, staleBefore, maxPending)
+	err := dbconn.Global.QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(&e.State, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrTooManySearchExports
+	}
+	return err
+}
+
+// GetByID returns the export with the given ID, without its contents, or nil
+// if there is none.
+func (*searchExports) GetByID(ctx context.Context, id string) (*types.SearchExport, error) {
+	if Mocks.SearchExports.GetByID != nil {
+		return Mocks.SearchExports.GetByID(ctx, id)
+	}
+
+	q := sqlf.Sprintf(`
+SELECT id, user_id, format, state, error, result_count, signing_key, created_at, expires_at
+FROM search_exports
+WHERE id = %s
+`, id)
+	var e types.SearchExport
+	err := dbconn.Global.QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(
+		&e.ID, &e.UserID, &e.Format, &e.State, &e.Error, &e.ResultCount, &e.SigningKey, &e.CreatedAt, &e.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Contents returns the file of a completed export, or nil if it has none.
+func (*searchExports) Contents(ctx context.Context, id string) ([]byte, error) {
+	if Mocks.SearchExports.Contents != nil {
+		return Mocks.SearchExports.Contents(ctx, id)
+	}
+
+	q := sqlf.Sprintf("SELECT contents FROM search_exports WHERE id = %s AND state = 'COMPLETED'", id)
+	var contents []byte
+	err := dbconn.Global.QueryRowContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...).Scan(&contents)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return contents, err
+}
+
+// Complete marks an export as completed with the given file.
+func (*searchExports) Complete(ctx context.Context, id string, resultCount int32, contents []byte, expiresAt time.Time) error {
+	if Mocks.SearchExports.Complete != nil {
+		return Mocks.SearchExports.Complete(ctx, id, resultCount, contents, expiresAt)
+	}
+
+	q := sqlf.Sprintf(`
+UPDATE search_exports
+SET state = 'COMPLETED', result_count = %s, contents = %s, expires_at = %s
+WHERE id = %s
+`, resultCount, contents, expiresAt, id)
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// Fail marks an export as errored.
+func (*searchExports) Fail(ctx context.Context, id string, message string, expiresAt time.Time) error {
+	if Mocks.SearchExports.Fail != nil {
+		return Mocks.SearchExports.Fail(ctx, id, message, expiresAt)
+	}
+
+	q := sqlf.Sprintf(`
+UPDATE search_exports
+SET state = 'ERRORED', error = %s, expires_at = %s
+WHERE id = %s
+`, message, expiresAt, id)
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// DeleteExpired deletes the exports that expired, and the processing exports
+// that were created before staleBefore.
+func (*searchExports) DeleteExpired(ctx context.Context, staleBefore time.Time) error {
+	if Mocks.SearchExports.DeleteExpired != nil {
+		return Mocks.SearchExports.DeleteExpired(ctx, staleBefore)
+	}
+
+	q := sqlf.Sprintf(`
+DELETE FROM search_exports
+WHERE expires_at < now() OR (state = 'PROCESSING' AND created_at < %s)
+`, staleBefore)
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+type MockSearchExports struct {
+	Create        func(ctx context.Context, e *types.SearchExport, maxPending int, staleBefore time.Time) error
+	GetByID       func(ctx context.Context, id string) (*types.SearchExport, error)
+	Contents      func(ctx context.Context, id string) ([]byte, error)
+	Complete      func(ctx context.Context, id string, resultCount int32, contents []byte, expiresAt time.Time) error
+	Fail          func(ctx context.Context, id string, message string, expiresAt time.Time) error
+	DeleteExpired func(ctx context.Context, staleBefore time.Time) error
+}
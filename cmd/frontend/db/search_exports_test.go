@@ -0,0 +1,71 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestSearchExports(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := Users.Create(ctx, NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staleBefore := time.Now().Add(-time.Hour)
+	for _, id := range []string{"a", "b"} {
+		e := &types.SearchExport{ID: id, UserID: user.ID, Format: "CSV", SigningKey: []byte("k")}
+		if err := SearchExports.Create(ctx, e, 2, staleBefore); err != nil {
+			t.Fatal(err)
+		}
+		if e.State != "PROCESSING" {
+			t.Errorf("got state %q, want PROCESSING", e.State)
+		}
+	}
+	// The user already has 2 exports in progress.
+	if err := SearchExports.Create(ctx, &types.SearchExport{ID: "c", UserID: user.ID, Format: "CSV", SigningKey: []byte("k")}, 2, staleBefore); err != ErrTooManySearchExports {
+		t.Fatalf("got error %v, want %v", err, ErrTooManySearchExports)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := SearchExports.Complete(ctx, "a", 3, []byte("x,y\n"), expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	e, err := SearchExports.GetByID(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e == nil || e.State != "COMPLETED" || e.ResultCount != 3 || e.ExpiresAt == nil || !bytes.Equal(e.SigningKey, []byte("k")) {
+		t.Errorf("got export %+v, want a completed export with 3 results", e)
+	}
+	if contents, err := SearchExports.Contents(ctx, "a"); err != nil || string(contents) != "x,y\n" {
+		t.Errorf("got contents %q (error %v), want %q", contents, err, "x,y\n")
+	}
+	if contents, err := SearchExports.Contents(ctx, "b"); err != nil || contents != nil {
+		t.Errorf("got contents %q (error %v) for a processing export, want none", contents, err)
+	}
+
+	if err := SearchExports.Fail(ctx, "b", "boom", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := SearchExports.DeleteExpired(ctx, staleBefore); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := SearchExports.GetByID(ctx, "b"); err != nil || e != nil {
+		t.Errorf("got export %+v (error %v), want the expired export to be deleted", e, err)
+	}
+	if e, err := SearchExports.GetByID(ctx, "a"); err != nil || e == nil {
+		t.Errorf("got export %+v (error %v), want the unexpired export to be kept", e, err)
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/keegancsmith/sqlf"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbutil"
+)
+
+// searchFeatureFlagOverrides provides access to the
+// search_feature_flag_overrides table, which holds the overrides of search
+// feature flags for users and organizations.
+type searchFeatureFlagOverrides struct{}
+
+// Set creates or updates the override of o.Flag for the user or organization
+// of o.
+func (*searchFeatureFlagOverrides) Set(ctx context.Context, o *types.SearchFeatureFlagOverride) error {
+	if Mocks.SearchFeatureFlagOverrides.Set != nil {
+		return Mocks.SearchFeatureFlagOverrides.Set(ctx, o)
+	}
+
+	conflict, err := searchFeatureFlagOverrideConflict(o.UserID, o.OrgID)
+	if err != nil {
+		return err
+	}
+	q := sqlf.Sprintf(`
+INSERT INTO search_feature_flag_overrides (flag, user_id, org_id, enabled)
+VALUES (%s, %s, %s, %s)
+ON CONFLICT %s
+DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = now()
+`, o.Flag, nullInt32Value(o.UserID), nullInt32Value(o.OrgID), o.Enabled, conflict)
+	_, err = dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// Delete deletes the override of flag for the user or organization (exactly
+// one of userID and orgID is nonzero), if any.
+func (*searchFeatureFlagOverrides) Delete(ctx context.Context, flag string, userID, orgID int32) error {
+	if Mocks.SearchFeatureFlagOverrides.Delete != nil {
+		return Mocks.SearchFeatureFlagOverrides.Delete(ctx, flag, userID, orgID)
+	}
+
+	if _, err := searchFeatureFlagOverrideConflict(userID, orgID); err != nil {
+		return err
+	}
+	q := sqlf.Sprintf("DELETE FROM search_feature_flag_overrides WHERE flag=%s AND user_id IS NOT DISTINCT FROM %s AND org_id IS NOT DISTINCT FROM %s", flag, nullInt32Value(userID), nullInt32Value(orgID))
+	_, err := dbconn.Global.ExecContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	return err
+}
+
+// List returns all overrides, sorted by flag, with organization overrides
+// before user overrides.
+func (*searchFeatureFlagOverrides) List(ctx context.Context) ([]*types.SearchFeatureFlagOverride, error) {
+	if Mocks.SearchFeatureFlagOverrides.List != nil {
+		return Mocks.SearchFeatureFlagOverrides.List(ctx)
+	}
+	return listSearchFeatureFlagOverrides(ctx, sqlf.Sprintf("TRUE"))
+}
+
+// ListForUser returns the overrides that apply to a user: those of the user
+// and those of the organizations the user is a member of.
+func (*searchFeatureFlagOverrides) ListForUser(ctx context.Context, userID int32) ([]*types.SearchFeatureFlagOverride, error) {
+	if Mocks.SearchFeatureFlagOverrides.ListForUser != nil {
+		return Mocks.SearchFeatureFlagOverrides.ListForUser(ctx, userID)
+	}
+	return listSearchFeatureFlagOverrides(ctx, sqlf.Sprintf("user_id=%s OR org_id IN (SELECT org_id FROM org_members WHERE user_id=%s)", userID, userID))
+}
+
+func listSearchFeatureFlagOverrides(ctx context.Context, cond *sqlf.Query) ([]*types.SearchFeatureFlagOverride, error) {
+	q := sqlf.Sprintf(`
+SELECT flag, user_id, org_id, enabled, updated_at
+FROM search_feature_flag_overrides
+WHERE %s
+ORDER BY flag, org_id NULLS LAST, user_id
+`, cond)
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*types.SearchFeatureFlagOverride
+	for rows.Next() {
+		var o types.SearchFeatureFlagOverride
+		if err := rows.Scan(&o.Flag, &dbutil.NullInt32{N: &o.UserID}, &dbutil.NullInt32{N: &o.OrgID}, &o.Enabled, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, &o)
+	}
+	return overrides, rows.Err()
+}
+
+// searchFeatureFlagOverrideConflict returns the conflict target of the unique
+// index of overrides for the user or organization.
+func searchFeatureFlagOverrideConflict(userID, orgID int32) (*sqlf.Query, error) {
+	switch {
+	case userID != 0 && orgID == 0:
+		return sqlf.Sprintf("(flag, user_id) WHERE user_id IS NOT NULL"), nil
+	case orgID != 0 && userID == 0:
+		return sqlf.Sprintf("(flag, org_id) WHERE org_id IS NOT NULL"), nil
+	default:
+		return nil, errors.New("search feature flag override must be for exactly one of a user and an organization")
+	}
+}
+
+// nullInt32Value returns a value that is NULL if n is zero.
+func nullInt32Value(n int32) dbutil.NullInt32 {
+	if n == 0 {
+		return dbutil.NullInt32{}
+	}
+	return dbutil.NullInt32{N: &n}
+}
+
+type MockSearchFeatureFlagOverrides struct {
+	Set         func(ctx context.Context, o *types.SearchFeatureFlagOverride) error
+	Delete      func(ctx context.Context, flag string, userID, orgID int32) error
+	List        func(ctx context.Context) ([]*types.SearchFeatureFlagOverride, error)
+	ListForUser func(ctx context.Context, userID int32) ([]*types.SearchFeatureFlagOverride, error)
+}
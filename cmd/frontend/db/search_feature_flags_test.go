@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
+)
+
+func TestSearchFeatureFlagOverrides(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	dbtesting.SetupGlobalTestDB(t)
+	ctx := context.Background()
+
+	user, err := Users.Create(ctx, NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Users.Create(ctx, NewUser{Username: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	org, err := Orgs.Create(ctx, "o", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgMembers.Create(ctx, org.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range []*types.SearchFeatureFlagOverride{
+		{Flag: "a", UserID: user.ID, Enabled: true},
+		{Flag: "a", UserID: user.ID, Enabled: false}, // updates the previous override
+		{Flag: "a", OrgID: org.ID, Enabled: true},
+		{Flag: "b", UserID: other.ID, Enabled: true},
+	} {
+		if err := SearchFeatureFlagOverrides.Set(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SearchFeatureFlagOverrides.Set(ctx, &types.SearchFeatureFlagOverride{Flag: "a", UserID: user.ID, OrgID: org.ID}); err == nil {
+		t.Error("got nil error for an override of both a user and an organization")
+	}
+
+	list := func(overrides []*types.SearchFeatureFlagOverride, err error) []types.SearchFeatureFlagOverride {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []types.SearchFeatureFlagOverride
+		for _, o := range overrides {
+			o.UpdatedAt = time.Time{}
+			have = append(have, *o)
+		}
+		return have
+	}
+	want := []types.SearchFeatureFlagOverride{
+		{Flag: "a", OrgID: org.ID, Enabled: true},
+		{Flag: "a", UserID: user.ID, Enabled: false},
+	}
+	if diff := cmp.Diff(want, list(SearchFeatureFlagOverrides.ListForUser(ctx, user.ID))); diff != "" {
+		t.Fatalf("overrides for user mismatch (-want +have):\n%s", diff)
+	}
+	want = append(want, types.SearchFeatureFlagOverride{Flag: "b", UserID: other.ID, Enabled: true})
+	if diff := cmp.Diff(want, list(SearchFeatureFlagOverrides.List(ctx))); diff != "" {
+		t.Fatalf("overrides mismatch (-want +have):\n%s", diff)
+	}
+
+	if err := SearchFeatureFlagOverrides.Delete(ctx, "a", 0, org.ID); err != nil {
+		t.Fatal(err)
+	}
+	want = []types.SearchFeatureFlagOverride{{Flag: "a", UserID: user.ID, Enabled: false}}
+	if diff := cmp.Diff(want, list(SearchFeatureFlagOverrides.ListForUser(ctx, user.ID))); diff != "" {
+		t.Fatalf("overrides after deletion mismatch (-want +have):\n%s", diff)
+	}
+}
@@ -17,6 +17,14 @@ var (
 
 	SurveyResponses = &surveyResponses{}
 
+	SearchAnalytics = &searchAnalytics{}
+
+	SearchFeatureFlagOverrides = &searchFeatureFlagOverrides{}
+
+	SearchExcludedRepos = &searchExcludedRepos{}
+
+	SearchExports = &searchExports{}
+
 	ExternalAccounts = &userExternalAccounts{}
 
 	OrgInvitations = &orgInvitations{}
@@ -46,20 +46,25 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 	}
 
 	// Validate scopes.
-	var hasUserAllScope bool
+	var hasUserAllScope, hasSearchScope, hasSudoScope, hasSearchRestrictions bool
 	seenScope := map[string]struct{}{}
 	sort.Strings(args.Scopes)
 	for _, scope := range args.Scopes {
-		switch scope {
-		case authz.ScopeUserAll:
+		switch {
+		case scope == authz.ScopeUserAll:
 			hasUserAllScope = true
-		case authz.ScopeSiteAdminSudo:
+		case scope == authz.ScopeSiteAdminSudo:
 			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" scope.
 			if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 				return nil, err
 			}
+			hasSudoScope = true
+		case scope == authz.ScopeSearch:
+			hasSearchScope = true
+		case authz.IsSearchRestrictionScope(scope):
+			hasSearchRestrictions = true
 		default:
-			return nil, fmt.Errorf("unknown access token scope %q (valid scopes: %q)", scope, authz.AllScopes)
+			return nil, fmt.Errorf("unknown access token scope %q (valid scopes: %q, or %q and %q followed by a restriction)", scope, authz.AllScopes, authz.ScopeSearchRepoPrefix, authz.ScopeSearchContextPrefix)
 		}
 
 		if _, seen := seenScope[scope]; seen {
@@ -67,8 +72,16 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 		}
 		seenScope[scope] = struct{}{}
 	}
-	if !hasUserAllScope {
-		return nil, fmt.Errorf("all access tokens must have scope %q", authz.ScopeUserAll)
+	switch {
+	case hasSearchScope && (hasUserAllScope || hasSudoScope):
+		return nil, fmt.Errorf("access tokens with scope %q may not have any other unrestricted scopes", authz.ScopeSearch)
+	case hasSearchRestrictions && !hasSearchScope:
+		return nil, fmt.Errorf("search restriction scopes may only be used together with scope %q", authz.ScopeSearch)
+	case !hasUserAllScope && !hasSearchScope:
+		return nil, fmt.Errorf("all access tokens must have scope %q or %q", authz.ScopeUserAll, authz.ScopeSearch)
+	}
+	if _, err := authz.ParseSearchScope(args.Scopes); err != nil {
+		return nil, err
 	}
 
 	id, token, err := db.AccessTokens.Create(ctx, userID, args.Scopes, args.Note, actor.FromContext(ctx).UID)
@@ -129,6 +129,44 @@ func TestMutation_CreateAccessToken(t *testing.T) {
 		})
 	})
 
+	t.Run("authenticated as user, using search scopes", func(t *testing.T) {
+		resetMocks()
+		mockAccessTokensCreate(t, 1, []string{authz.ScopeSearch, "search:repo:^github\\.com/foo/"})
+
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+		result, err := (&schemaResolver{}).CreateAccessToken(ctx, &createAccessTokenInput{
+			User:   uid1GQLID,
+			Scopes: []string{"search:repo:^github\\.com/foo/", authz.ScopeSearch},
+			Note:   "n",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == nil {
+			t.Error("result == nil")
+		}
+	})
+
+	t.Run("authenticated as user, using invalid search scopes", func(t *testing.T) {
+		for _, scopes := range [][]string{
+			{authz.ScopeSearch, authz.ScopeUserAll},
+			{authz.ScopeUserAll, "search:repo:foo"},
+			{authz.ScopeSearch, "search:repo:("},
+			{authz.ScopeSearch, "search:context:"},
+		} {
+			resetMocks()
+
+			ctx := actor.WithActor(context.Background(), &actor.Actor{UID: 1})
+			result, err := (&schemaResolver{}).CreateAccessToken(ctx, &createAccessTokenInput{User: uid1GQLID, Scopes: scopes, Note: "n"})
+			if err == nil {
+				t.Errorf("%q: err == nil", scopes)
+			}
+			if result != nil {
+				t.Errorf("%q: got result %v, want nil", scopes, result)
+			}
+		}
+	})
+
 	t.Run("authenticated as different user who is a site-admin", func(t *testing.T) {
 		resetMocks()
 		const differentSiteAdminUID = 234
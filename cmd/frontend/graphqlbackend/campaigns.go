@@ -45,6 +45,12 @@ type CreatePatchSetFromPatchesArgs struct {
 	Patches []PatchInput
 }
 
+type CreatePatchSetFromSearchArgs struct {
+	Query       string
+	PatternType *string
+	Replacement string
+}
+
 type PatchInput struct {
 	Repository   graphql.ID
 	BaseRevision api.CommitID
@@ -114,6 +120,7 @@ type CampaignsResolver interface {
 	AddChangesetsToCampaign(ctx context.Context, args *AddChangesetsToCampaignArgs) (CampaignResolver, error)
 
 	CreatePatchSetFromPatches(ctx context.Context, args CreatePatchSetFromPatchesArgs) (PatchSetResolver, error)
+	CreatePatchSetFromSearch(ctx context.Context, args *CreatePatchSetFromSearchArgs) (PatchSetResolver, error)
 	PatchSetByID(ctx context.Context, id graphql.ID) (PatchSetResolver, error)
 
 	PatchByID(ctx context.Context, id graphql.ID) (PatchResolver, error)
@@ -179,6 +186,10 @@ func (defaultCampaignsResolver) CreatePatchSetFromPatches(ctx context.Context, a
 	return nil, campaignsOnlyInEnterprise
 }
 
+func (defaultCampaignsResolver) CreatePatchSetFromSearch(ctx context.Context, args *CreatePatchSetFromSearchArgs) (PatchSetResolver, error) {
+	return nil, campaignsOnlyInEnterprise
+}
+
 func (defaultCampaignsResolver) PatchSetByID(ctx context.Context, id graphql.ID) (PatchSetResolver, error) {
 	return nil, campaignsOnlyInEnterprise
 }
@@ -0,0 +1,34 @@
+// +build gofuzz
+
+package graphqlbackend
+
+import (
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// FuzzSearcherResponse is an entry point for fuzzing the decoding of searcher
+// responses with https://github.com/dvyukov/go-fuzz. Run
+// go-fuzz-build -func FuzzSearcherResponse and then go-fuzz in this directory.
+//
+// Responses that decode are checked like those of searcher, and their matches
+// are then passed through the resolvers that use their line numbers and
+// offsets, which must not panic.
+func FuzzSearcherResponse(data []byte) int {
+	var r searcherResponse
+	if err := decodeSearcherResponseJSON(data, &r); err != nil {
+		return 0
+	}
+	if err := checkSearcherMatches(r.Matches); err != nil {
+		return 0
+	}
+	for _, fm := range r.Matches {
+		for _, lm := range fm.JLineMatches {
+			for _, unit := range []search.OffsetUnit{search.OffsetUnitCharacter, search.OffsetUnitByte, search.OffsetUnitUTF16} {
+				lm.OffsetAndLengths(&offsetUnitArgs{Unit: string(unit)})
+			}
+			lm.truncatePreview(fm, 10)
+		}
+		lineMatchRanges(fm.JLineMatches)
+	}
+	return 1
+}
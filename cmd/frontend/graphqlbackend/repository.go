@@ -33,6 +33,10 @@ type RepositoryResolver struct {
 
 	// rev optionally specifies a revision to go to for search results.
 	rev string
+
+	// batch, if non-nil, loads the fields of repo together with those of
+	// the other repositories of the search results.
+	batch *repositoryBatch
 }
 
 func NewRepositoryResolver(repo *types.Repo) *RepositoryResolver {
@@ -97,10 +101,7 @@ func (r *RepositoryResolver) IsArchived(ctx context.Context) (bool, error) {
 }
 
 func (r *RepositoryResolver) IsPrivate(ctx context.Context) (bool, error) {
-	err := r.hydrate(ctx)
-	if err != nil {
-		return false, err
-	}
+	// Private is loaded even when the other fields aren't.
 	return r.repo.Private, nil
 }
 
@@ -281,6 +282,9 @@ func (r *RepositoryResolver) Type() *types.Repo {
 
 func (r *RepositoryResolver) hydrate(ctx context.Context) error {
 	r.hydration.Do(func() {
+		if r.batch != nil {
+			r.batch.hydrate(ctx)
+		}
 		if r.repo.RepoFields != nil {
 			return
 		}
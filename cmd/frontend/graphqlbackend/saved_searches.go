@@ -3,6 +3,7 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/relay"
@@ -11,6 +12,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/cmd/query-runner/queryrunnerapi"
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/webhook"
 )
 
 type savedSearchResolver struct {
@@ -59,6 +61,7 @@ func savedSearchByID(ctx context.Context, id graphql.ID) (*savedSearchResolver,
 			UserID:          ss.Config.UserID,
 			OrgID:           ss.Config.OrgID,
 			SlackWebhookURL: ss.Config.SlackWebhookURL,
+			WebhookURL:      ss.Config.WebhookURL,
 		},
 	}
 	return savedSearch, nil
@@ -100,6 +103,8 @@ func (r savedSearchResolver) Namespace(ctx context.Context) (*NamespaceResolver,
 
 func (r savedSearchResolver) SlackWebhookURL() *string { return r.s.SlackWebhookURL }
 
+func (r savedSearchResolver) WebhookURL() *string { return r.s.WebhookURL }
+
 func toSavedSearchResolver(entry types.SavedSearch) *savedSearchResolver {
 	return &savedSearchResolver{entry}
 }
@@ -151,6 +156,7 @@ func (r *schemaResolver) CreateSavedSearch(ctx context.Context, args *struct {
 	NotifySlack bool
 	OrgID       *graphql.ID
 	UserID      *graphql.ID
+	WebhookURL  *string
 }) (*savedSearchResolver, error) {
 	var userID, orgID *int32
 	// 🚨 SECURITY: Make sure the current user has permission to create a saved search for the specified user or org.
@@ -179,6 +185,9 @@ func (r *schemaResolver) CreateSavedSearch(ctx context.Context, args *struct {
 	if !queryHasPatternType(args.Query) {
 		return nil, errMissingPatternType
 	}
+	if err := validateSavedSearchWebhookURL(ctx, args.WebhookURL); err != nil {
+		return nil, err
+	}
 
 	ss, err := db.SavedSearches.Create(ctx, &types.SavedSearch{
 		Description: args.Description,
@@ -187,6 +196,7 @@ func (r *schemaResolver) CreateSavedSearch(ctx context.Context, args *struct {
 		NotifySlack: args.NotifySlack,
 		UserID:      userID,
 		OrgID:       orgID,
+		WebhookURL:  args.WebhookURL,
 	})
 	if err != nil {
 		return nil, err
@@ -203,6 +213,7 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 	NotifySlack bool
 	OrgID       *graphql.ID
 	UserID      *graphql.ID
+	WebhookURL  *string
 }) (*savedSearchResolver, error) {
 	var userID, orgID *int32
 	// 🚨 SECURITY: Make sure the current user has permission to update a saved search for the specified user or org.
@@ -236,6 +247,9 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 	if !queryHasPatternType(args.Query) {
 		return nil, errMissingPatternType
 	}
+	if err := validateSavedSearchWebhookURL(ctx, args.WebhookURL); err != nil {
+		return nil, err
+	}
 
 	ss, err := db.SavedSearches.Update(ctx, &types.SavedSearch{
 		ID:          id,
@@ -245,6 +259,7 @@ func (r *schemaResolver) UpdateSavedSearch(ctx context.Context, args *struct {
 		NotifySlack: args.NotifySlack,
 		UserID:      userID,
 		OrgID:       orgID,
+		WebhookURL:  args.WebhookURL,
 	})
 	if err != nil {
 		return nil, err
@@ -289,4 +304,19 @@ func queryHasPatternType(query string) bool {
 	return patternTypeRegexp.Match([]byte(query))
 }
 
+// validateSavedSearchWebhookURL returns an error if webhookURL is set but is
+// not an absolute HTTP(S) URL of a public host.
+//
+// 🚨 SECURITY: The query-runner delivers webhooks from inside the deployment,
+// so users must not be able to point them at internal services.
+func validateSavedSearchWebhookURL(ctx context.Context, webhookURL *string) error {
+	if webhookURL == nil {
+		return nil
+	}
+	if err := webhook.CheckPublicURL(ctx, *webhookURL); err != nil {
+		return fmt.Errorf("invalid webhook URL %q: %s", *webhookURL, err)
+	}
+	return nil
+}
+
 var errMissingPatternType error = errors.New("a `patternType:` filter is required in the query for all saved searches. `patternType` can be \"literal\" or \"regexp\"")
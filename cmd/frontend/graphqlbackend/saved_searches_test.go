@@ -67,6 +67,7 @@ func TestCreateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		WebhookURL  *string
 	}{Description: "test query", Query: "test type:diff patternType:regexp", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err != nil {
 		t.Fatal(err)
@@ -97,10 +98,29 @@ func TestCreateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		WebhookURL  *string
 	}{Description: "test query", Query: "test type:diff", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err == nil {
 		t.Error("Expected error for createSavedSearch when query does not provide a patternType: field.")
 	}
+
+	// Ensure create saved search errors when the webhook URL is not an HTTP(S)
+	// URL of a public host.
+	for _, webhookURL := range []string{"file:///etc/passwd", "http://169.254.169.254/latest/meta-data", "http://gitserver-0:3178/exec"} {
+		webhookURL := webhookURL
+		_, err = (&schemaResolver{}).CreateSavedSearch(ctx, &struct {
+			Description string
+			Query       string
+			NotifyOwner bool
+			NotifySlack bool
+			OrgID       *graphql.ID
+			UserID      *graphql.ID
+			WebhookURL  *string
+		}{Description: "test query", Query: "test type:diff patternType:regexp", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID, WebhookURL: &webhookURL})
+		if err == nil {
+			t.Errorf("Expected error for createSavedSearch when the webhook URL is %q.", webhookURL)
+		}
+	}
 }
 
 func TestUpdateSavedSearch(t *testing.T) {
@@ -126,6 +146,7 @@ func TestUpdateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		WebhookURL  *string
 	}{ID: marshalSavedSearchID(key), Description: "updated query description", Query: "test type:diff patternType:regexp", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err != nil {
 		t.Fatal(err)
@@ -158,6 +179,7 @@ func TestUpdateSavedSearch(t *testing.T) {
 		NotifySlack bool
 		OrgID       *graphql.ID
 		UserID      *graphql.ID
+		WebhookURL  *string
 	}{ID: marshalSavedSearchID(key), Description: "updated query description", Query: "test type:diff", NotifyOwner: true, NotifySlack: false, OrgID: nil, UserID: &userID})
 	if err == nil {
 		t.Error("Expected error for updateSavedSearch when query does not provide a patternType: field.")
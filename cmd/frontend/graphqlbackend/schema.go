@@ -47,6 +47,20 @@ type Mutation {
         # created from this PatchSet.
         patches: [PatchInput!]!
     ): PatchSet!
+    # Create a patchset that replaces the matches of a search (literal or regexp, not structural).
+    # The search runs exhaustively, and the changes to the files in each repository become one
+    # patch. Nothing is changed until a campaign is created from the returned patchset.
+    #
+    # Only site admins may create patchsets from searches.
+    createPatchSetFromSearch(
+        # The search query (such as "repo:myrepo fmt.Errorf").
+        query: String!
+        # The pattern type of the query, if it is not specified in the query with the patternType: field.
+        patternType: SearchPatternType
+        # The replacement for the matches of the query's pattern. For regexp patterns, it can refer to
+        # submatches as $1 or ${name}.
+        replacement: String!
+    ): PatchSet!
     # Updates a campaign. Updating is not allowed when any of the following are true:
     #
     # - The campaign has already been closed.
@@ -195,6 +209,12 @@ type Mutation {
     # - "user:all": Full control of all resources accessible to the user account.
     # - "site-admin:sudo": Ability to perform any action as any other user. (Only site admins may create tokens
     #   with this scope.)
+    # - "search": Ability to run search queries (and nothing else) as the user. It may not be combined with
+    #   "user:all" or "site-admin:sudo".
+    # - "search:repo:PATTERN": Restricts a "search" token to repositories whose whole name matches the
+    #   regular expression PATTERN (e.g. github\.com/foo/.*). May be specified multiple times.
+    # - "search:context:NAME": Restricts a "search" token to searches within the version context NAME. May be
+    #   specified multiple times.
     #
     # Only the user or site admins may perform this mutation.
     createAccessToken(user: ID!, scopes: [String!]!, note: String!): CreateAccessTokenResult!
@@ -352,6 +372,50 @@ type Mutation {
     #
     # Only site admins may perform this mutation.
     reloadSite: EmptyResponse
+    # Cancels a search that is currently executing (see SearchLoad.searches), which stops
+    # searching. It must be called on the frontend instance that runs the search.
+    #
+    # Only site admins may perform this mutation.
+    cancelSearch(id: ID!): EmptyResponse!
+    # (experimental) Enables or disables a search feature flag (see Site.searchFeatureFlags) for a
+    # user or an organization, regardless of the search.featureFlags site configuration.
+    #
+    # Only site admins may perform this mutation.
+    setSearchFeatureFlagOverride(
+        # The name of the flag.
+        flag: String!
+        # The ID of the user or organization.
+        namespace: ID!
+        # Whether the flag is enabled.
+        enabled: Boolean!
+    ): EmptyResponse!
+    # (experimental) Deletes the override of a search feature flag for a user or an organization.
+    #
+    # Only site admins may perform this mutation.
+    deleteSearchFeatureFlagOverride(
+        # The name of the flag.
+        flag: String!
+        # The ID of the user or organization.
+        namespace: ID!
+    ): EmptyResponse!
+    # Excludes a repository from search (e.g. because it is sensitive or too large to search), or
+    # updates the reason it is excluded for. Searches skip it and report it in
+    # SearchResults.excludedRepositories.
+    #
+    # Only site admins may perform this mutation.
+    excludeRepositoryFromSearch(
+        # The ID of the repository.
+        repository: ID!
+        # Why the repository is excluded, for other site admins.
+        reason: String
+    ): EmptyResponse!
+    # Includes a repository that was excluded from search in searches again.
+    #
+    # Only site admins may perform this mutation.
+    includeRepositoryInSearch(
+        # The ID of the repository.
+        repository: ID!
+    ): EmptyResponse!
     # Submits a user satisfaction (NPS) survey.
     submitSurvey(input: SurveySubmissionInput!): EmptyResponse
     # Submits a request for a Sourcegraph Enterprise trial license.
@@ -370,6 +434,7 @@ type Mutation {
         notifySlack: Boolean!
         orgID: ID
         userID: ID
+        webhookURL: String
     ): SavedSearch!
     # Updates a saved search
     updateSavedSearch(
@@ -380,9 +445,48 @@ type Mutation {
         notifySlack: Boolean!
         orgID: ID
         userID: ID
+        webhookURL: String
     ): SavedSearch!
     # Deletes a saved search
     deleteSavedSearch(id: ID!): EmptyResponse
+    # Runs a search again, but only over the given repositories, e.g. the ones a previous
+    # search reported as timed out or failed (SearchResults.timedout and the affected
+    # repositories of its alerts). The arguments other than repositories must be those of the
+    # previous search.
+    #
+    # The results only contain matches in the given repositories (limited to those that the
+    # query matches), so clients should merge them into the results of the previous search
+    # instead of re-running the whole search.
+    retrySearch(
+        # The version of the search syntax being used.
+        version: SearchVersion = V1
+        # The pattern type of the previous search.
+        patternType: SearchPatternType
+        # The query of the previous search.
+        query: String!
+        # The version context of the previous search.
+        versionContext: String
+        # The repositories to search again.
+        repositories: [ID!]!
+    ): SearchResults!
+    # Starts exporting all file matches of a search (one row per line match) to a file
+    # that can be downloaded once the export is completed. Poll the export with
+    # Query.searchExport until it has a URL.
+    #
+    # Exports are stored temporarily in the database, and are deleted once they expire. Exports
+    # larger than 100 MB fail, and a user can have at most 3 exports in progress.
+    exportSearch(
+        # The version of the search syntax being used.
+        version: SearchVersion = V1
+        # The pattern type, if it is not specified in the query.
+        patternType: SearchPatternType
+        # The search query.
+        query: String!
+        # The version context.
+        versionContext: String
+        # The format of the export.
+        format: SearchExportFormat = CSV
+    ): SearchExport!
 
     # (experimental) The LSIF API may change substantially in the near future as we
     # continue to adjust it for our use cases. Changes will not be documented in the
@@ -1167,6 +1271,13 @@ type Query {
         # how many results to return per page. It must be in the range of 0-5000.
         first: Int
     ): Search
+    # Runs several independent searches at once, e.g. for a dashboard. Searches that search the
+    # same repositories only resolve them once, so this is faster than running them one by one.
+    # At most 50 queries can be searched at once.
+    searchBatch(queries: [SearchBatchQuery!]!): [Search!]!
+    # A search export of the current user started with Mutation.exportSearch, or null if
+    # it has expired.
+    searchExport(id: ID!): SearchExport
     # All saved searches configured for the current user, merged from all configurations.
     savedSearches: [SavedSearch!]!
     # All repository groups for the current user, merged from all configurations.
@@ -1243,12 +1354,68 @@ type ParentSourcegraphDetails {
     url: String!
 }
 
+# A query of a search batch (see Query.searchBatch).
+input SearchBatchQuery {
+    # The version of the search syntax being used.
+    # All new clients should use the latest version.
+    version: SearchVersion = V1
+    # PatternType controls the search pattern type, if and only if it is not specified in the query string using
+    # the patternType: field.
+    patternType: SearchPatternType
+    # The search query (such as "foo" or "repo:myrepo foo").
+    query: String!
+    # (experimental) Optionally specify the versionContext. If not specified the
+    # default version context is used (all repositories on the default branch).
+    versionContext: String
+}
+
 # A search.
 type Search {
     # The results.
     results: SearchResults!
     # The suggestions.
     suggestions(first: Int): [SearchSuggestion!]!
+    # Repository, file and symbol suggestions for the terms of the query, ranked
+    # in a single list for an omnibox. Only the suggestions found within a short
+    # time budget are returned.
+    omniboxSuggestions(first: Int): [SearchSuggestion!]!
+    # Counts the matches of the search by repository and by directory, for exploring where
+    # a pattern occurs without fetching the matches. Only the matches found within the
+    # result limit are counted (see limitHit), so add a count: filter to count more.
+    histogram(
+        # The maximum number of repositories and of paths to return.
+        first: Int = 10
+        # The number of leading path components of the directories to count matches by,
+        # e.g. 1 counts the matches in "cmd/a/a.go" and "cmd/b/b.go" together in "cmd".
+        # If it is 0, the matches are counted by the directory containing the file.
+        depth: Int = 2
+    ): SearchHistogram!
+    # The number of matches of the search at dates evenly spaced from since to until, e.g. to
+    # track how the uses of a deprecated API are burned down. Each repository is searched at
+    # its newest commit before each date on the searched revision (or the default branch).
+    # At most 50 repositories can be searched.
+    matchCountSeries(
+        # The date of the first point. Defaults to a year before until.
+        since: DateTime
+        # The date of the last point. Defaults to now.
+        until: DateTime
+        # The number of points, at most 52.
+        points: Int = 12
+    ): [SearchMatchCountPoint!]!
+    # The regions of the files matched by the filters of the search (such as repo: and lang:)
+    # that are near-duplicates of a code snippet, e.g. to track copies of a vulnerable snippet.
+    # Files are compared with the snippet by fingerprints of its tokens (winnowing), so the
+    # regions can differ in whitespace and in parts of the code. Only the files that contain
+    # one of the longest identifiers of the snippet are compared. The query must not have a
+    # search pattern.
+    similarCode(
+        # The code snippet.
+        snippet: String!
+        # The minimum similarity of the regions, from 0 (exclusive) to 1.
+        minSimilarity: Float = 0.5
+        # The maximum number of regions to return.
+        first: Int = 50
+    ): SimilarCodeResults!
     # A subset of results (excluding actual search results) which are heavily
     # cached and thus quicker to query. Useful for e.g. querying sparkline
     # data.
@@ -1302,6 +1469,24 @@ type SearchResultMatch {
     highlights: [Highlight!]!
 }
 
+# The time it took to search a repository revision without an index.
+type RepositorySearchTiming {
+    # The repository.
+    repository: Repository!
+    # The revision that was searched, as specified in the query.
+    rev: String!
+    # Whether searching the repository failed.
+    error: Boolean!
+    # The total time it took to search the repository.
+    totalMilliseconds: Int!
+    # The time it took to resolve the revision (and evaluate repohasfile filters).
+    resolveMilliseconds: Int!
+    # The time it took searcher to search the repository.
+    searchMilliseconds: Int!
+    # The time it took to merge the results into the overall results.
+    accumulateMilliseconds: Int!
+}
+
 # Search results.
 type SearchResults {
     # The results. Inside each SearchResult there may be multiple matches, e.g.
@@ -1369,18 +1554,236 @@ type SearchResults {
     timedout: [Repository!]!
     # True if indexed search is enabled but was not available during this search.
     indexUnavailable: Boolean!
+    # True if searcher was unavailable during this search, so that a limited number of repositories
+    # were searched with git grep instead. Results may be incomplete and lack precise match ranges.
+    degraded: Boolean!
+    # Why the search stopped before all repositories were searched, or null if it was not canceled.
+    cancellationReason: SearchCancellationReason
+    # The numbers of file matches that were removed from the results because their matching
+    # lines can't be shown as text, by reason. Use hexpreview:yes to return hexadecimal
+    # previews of these matches instead.
+    skippedFiles: [SearchSkippedFiles!]!
+    # The numbers of repositories matched by the repo-related filters of the query that were not
+    # searched, by reason: forks and archived repositories (unless the query includes them with
+    # fork:yes or archived:yes), and repositories excluded from search by a site admin.
+    excludedRepositories: [SearchExcludedRepositories!]!
     # An alert message that should be displayed before any results.
     alert: SearchAlert
+    # All alerts that apply to this search, the most important one (the same as the alert field) first.
+    # For example, if some repositories could not be searched, results from the other repositories are
+    # returned together with an alert listing the affected repositories.
+    alerts: [SearchAlert!]!
     # The time it took to generate these results.
     elapsedMilliseconds: Int!
+    # (experimental) The slowest repositories searched without an index, slowest first, with the
+    # time spent in each phase of searching them. Intended for debugging slow searches.
+    #
+    # Only site admins may access this field.
+    repositoryTimings(
+        # The maximum number of repositories to return.
+        first: Int = 10
+    ): [RepositorySearchTiming!]!
     # Dynamic filters generated by the search results
     dynamicFilters: [SearchFilter!]!
+    # Counts of the file matches by repository, language, file extension and directory, for
+    # faceted navigation of the results. The counts include matches that were found but not
+    # returned because of the result limit.
+    aggregations(
+        # The maximum number of buckets to return for each aggregation.
+        first: Int = 10
+    ): SearchAggregations!
     # Pagination information.
     #
     # This field is only applcable when the original request was a paginated one.
     pageInfo: PageInfo!
 }
 
+# Why file matches were removed from search results.
+enum SearchSkippedFileReason {
+    # The file is binary (contains NUL bytes).
+    BINARY
+    # The file is not valid UTF-8.
+    INVALID_UTF8
+}
+
+# The number of file matches that were removed from search results for a reason.
+type SearchSkippedFiles {
+    # Why the file matches were removed.
+    reason: SearchSkippedFileReason!
+    # The number of removed file matches.
+    count: Int!
+}
+
+# Why repositories were not searched.
+enum SearchExcludedRepositoryReason {
+    # The repository is a fork.
+    FORK
+    # The repository is archived.
+    ARCHIVED
+    # A site admin excluded the repository from search (see the excludeRepositoryFromSearch
+    # mutation).
+    EXCLUDED_BY_ADMIN
+}
+
+# The number of repositories that were not searched for a reason.
+type SearchExcludedRepositories {
+    # Why the repositories were not searched.
+    reason: SearchExcludedRepositoryReason!
+    # The number of repositories.
+    count: Int!
+}
+
+# A repository that a site admin excluded from search.
+type SearchExcludedRepository {
+    # The repository.
+    repository: Repository!
+    # Why the repository is excluded.
+    reason: String!
+    # When the repository was excluded.
+    createdAt: DateTime!
+}
+
+# The file formats of search exports.
+enum SearchExportFormat {
+    # Comma-separated values with the columns repository, path, line and preview.
+    CSV
+    # A JSON array of objects with the fields repository, path, line and preview.
+    JSON
+    # A SARIF 2.1.0 log with one result per match, for code scanning dashboards. The paths
+    # of results are relative to their repository, which is the "repository" property of
+    # the result.
+    SARIF
+}
+
+# The states of a search export.
+enum SearchExportState {
+    # The search is running.
+    PROCESSING
+    # The export can be downloaded.
+    COMPLETED
+    # The search failed.
+    ERRORED
+}
+
+# An export of the file matches of a search.
+type SearchExport {
+    # The unique ID of the export.
+    id: ID!
+    # The file format.
+    format: SearchExportFormat!
+    # The state of the export.
+    state: SearchExportState!
+    # The number of exported rows once completed (one per line match, or per file for path matches).
+    resultCount: Int!
+    # The error, if the export failed.
+    error: String
+    # The signed URL to download the export from, once it is completed. Anyone with the URL (who
+    # can access this site) can download the export until it expires.
+    url: String
+    # When the export and its URL expire, once it has finished.
+    expiresAt: DateTime
+}
+
+# The reasons a search can stop before all repositories were searched.
+enum SearchCancellationReason {
+    # The request was canceled, usually because the client disconnected.
+    CLIENT_DISCONNECTED
+    # The search did not complete within its timeout.
+    DEADLINE_EXCEEDED
+    # Enough results were found.
+    LIMIT_HIT
+    # An error occurred that made searching the remaining repositories pointless.
+    ERROR
+    # The results of all searches in progress used too much memory, so the results found so far
+    # were returned.
+    MEMORY_BUDGET_EXCEEDED
+}
+
+# Counts of the file matches of a search, by different properties of the matches. Each
+# aggregation lists the buckets with the most matches first.
+type SearchAggregations {
+    # The matches by repository.
+    repositories: [SearchAggregationBucket!]!
+    # The matches by language (as detected from the file name).
+    languages: [SearchAggregationBucket!]!
+    # The matches by file extension (lowercased, including the leading dot).
+    fileExtensions: [SearchAggregationBucket!]!
+    # The matches by the directory containing the file.
+    directories: [SearchAggregationBucket!]!
+}
+
+# The number of matches with a given value of a property.
+type SearchAggregationBucket {
+    # The value, e.g. a repository name or a language.
+    value: String!
+    # The number of matches.
+    count: Int!
+    # A query filter (e.g. "lang:go") that restricts a search to the matches in this bucket.
+    filter: String!
+}
+
+# The counts of the matches of a search by repository and by directory (see
+# Search.histogram), with the most matches first.
+type SearchHistogram {
+    # The matches by repository.
+    repositories: [SearchAggregationBucket!]!
+    # The matches by directory of a repository.
+    paths: [SearchHistogramPath!]!
+    # The number of matches that were counted.
+    matchCount: Int!
+    # Whether the search stopped at the result limit, so that not all matches were counted.
+    limitHit: Boolean!
+}
+
+# The number of matches in the files below a directory of a repository.
+type SearchHistogramPath {
+    # The name of the repository.
+    repository: String!
+    # The path of the directory, or "" for the files at the root of the repository.
+    path: String!
+    # The number of matches.
+    count: Int!
+    # A query filter that restricts a search to the matches below the directory.
+    filter: String!
+}
+
+# The regions of code that are near-duplicates of a snippet (see Search.similarCode), the
+# most similar first.
+type SimilarCodeResults {
+    # The regions.
+    regions: [SimilarCodeRegion!]!
+    # Whether not all regions were returned or not all candidate files were compared
+    # because of a limit or a timeout.
+    limitHit: Boolean!
+}
+
+# A region of a file that is a near-duplicate of a code snippet.
+type SimilarCodeRegion {
+    # The file.
+    file: GitBlob!
+    # The repository of the file.
+    repository: Repository!
+    # The first line of the region. 0-based.
+    startLine: Int!
+    # The last line of the region (inclusive). 0-based.
+    endLine: Int!
+    # The fraction of the fingerprints of the snippet that the region contains, from 0 to 1.
+    # It is 1 for copies of the snippet that only differ in whitespace.
+    similarity: Float!
+    # The lines of the region.
+    preview: String!
+}
+
+# The number of matches of a search at a date.
+type SearchMatchCountPoint {
+    # The date.
+    date: DateTime!
+    # The number of matches at the newest commits before the date.
+    matchCount: Int!
+    # Whether not all matches were counted because of a limit or a timeout.
+    limitHit: Boolean!
+}
+
 # Statistics about search results.
 type SearchResultsStats {
     # The approximate number of results returned.
@@ -1425,6 +1828,8 @@ type SearchAlert {
     description: String
     # "Did you mean: ____" query proposals
     proposedQueries: [SearchQueryDescription!]
+    # The repositories the alert is about, e.g. repositories that could not be searched.
+    affectedRepositories: [Repository!]!
 }
 
 # A saved search query, defined in settings.
@@ -1444,6 +1849,9 @@ type SavedSearch implements Node {
     namespace: Namespace!
     # The Slack webhook URL associated with this saved search, if any.
     slackWebhookURL: String
+    # The URL that is notified with a JSON POST request when this saved search has new results, if any.
+    # The request has the number of new results and a link to them, not the results themselves.
+    webhookURL: String
 }
 
 # A search query description.
@@ -2799,12 +3207,94 @@ type FileMatch {
     revSpec: GitRevSpec
     # The resource.
     resource: String! @deprecated(reason: "use the file field instead")
+    # The matches of the search pattern in the path of the file (file.path), as pairs of offset
+    # and length, if the search matches paths (type:path, or searches without a type: filter).
+    # The pairs are in the same format as those of LineMatch.offsetAndLengths, so clients can
+    # highlight the matching parts of the path.
+    pathOffsetAndLengths(
+        # The unit of the offsets and lengths.
+        unit: OffsetUnit = CHARACTER
+    ): [[Int!]!]!
+    # The license of the file at the searched commit, as an SPDX license expression (such as "MIT"
+    # or "Apache-2.0 OR MIT"): the SPDX-License-Identifier header of the file, or else the license
+    # of the nearest license file (such as LICENSE) in its directory or a parent directory. It is
+    # null if no license is detected. The license: search filter matches it.
+    license: String
     # The symbols found in this file that match the query.
     symbols: [Symbol!]!
     # The line matches.
     lineMatches: [LineMatch!]!
+    # The owners of the file (such as "@org/team") in the ownership file of the repository at
+    # the searched commit, in the CODEOWNERS format (see the search.ownershipFiles site setting).
+    # The owner: search filter matches them.
+    owners: [String!]!
+    # The ranges of the matches in the file. Unlike lineMatches, a match that spans multiple
+    # lines (e.g. of a regexp that matches a newline, or a structural search) is a single range.
+    # Characters are counted like the offsets of lineMatches (in Unicode code points, not bytes).
+    #
+    # The line matches of a multi-line match (one per line) are still returned in lineMatches,
+    # for clients that do not use the ranges.
+    matchRanges: [Range!]!
+    # The line matches grouped into hunks of consecutive lines, with up to contextLines lines of
+    # context before and after each matched line. Line matches whose context lines overlap or are
+    # adjacent are in the same hunk, so each line is only returned once. This is smaller and
+    # easier to render as code blocks than the line matches with separate context lines.
+    hunks(
+        # The number of context lines before and after each matched line (at most 10).
+        contextLines: Int = 1
+    ): [SearchResultHunk!]!
+    # The line matches, paginated. Unlike lineMatches, this is not limited to the line matches
+    # returned by the search: if limitHit is true, the file is searched again to return more of
+    # them (up to a maximum of 10,000).
+    lineMatchesConnection(
+        # Returns the first n line matches from the list.
+        first: Int!
+        # Opaque pagination cursor.
+        after: String
+    ): LineMatchConnection!
     # Whether or not the limit was hit.
     limitHit: Boolean!
+    # The ranges of commits that contain the matched lines, oldest first, if the search
+    # query searched the history of files with the experimental history: field (e.g.
+    # "history:v1.0.."). Otherwise, it is null.
+    #
+    # The match lives at the newest commit that contains it, and the ranges are runs of
+    # consecutive commits in the order they are listed by git log.
+    historyRanges: [FileMatchHistoryRange!]
+}
+
+# A hunk of consecutive lines of a file that contain line matches of a search, with context
+# lines around them (see FileMatch.hunks).
+type SearchResultHunk {
+    # The 0-based number of the first line of the hunk.
+    startLine: Int!
+    # The number of lines of the hunk.
+    lineCount: Int!
+    # The lines of the hunk, separated by newlines. Matched lines are the previews of their line
+    # matches (which may be truncated, see LineMatch.previewTruncated), and context lines are
+    # truncated like previews.
+    content: String!
+    # The line matches in the hunk.
+    lineMatches: [LineMatch!]!
+}
+
+# A range of consecutive commits that contain a match of a search of the history of
+# files.
+type FileMatchHistoryRange {
+    # The oldest commit of the range.
+    first: GitCommit!
+    # The newest commit of the range.
+    last: GitCommit!
+}
+
+# A list of line matches in a file.
+type LineMatchConnection {
+    # A list of line matches.
+    nodes: [LineMatch!]!
+    # The total count of line matches in the file, if known.
+    totalCount: Int
+    # Pagination information.
+    pageInfo: PageInfo!
 }
 
 # A line match.
@@ -2814,10 +3304,76 @@ type LineMatch {
     # The line number. 0-based. The first line will have lineNumber 0. Note: A
     # UI will normally display line numbers 1-based.
     lineNumber: Int!
-    # Tuples of [offset, length] measured in characters (not bytes).
-    offsetAndLengths: [[Int!]!]!
+    # Tuples of [offset, length] of the matches in the preview, measured in characters (not
+    # bytes) unless another unit is requested.
+    offsetAndLengths(
+        # The unit of the offsets and lengths.
+        unit: OffsetUnit = CHARACTER
+    ): [[Int!]!]!
+    # The location of each match in the file. The url and canonicalURL of a location link to the
+    # match (e.g. "/github.com/a/b@c/-/blob/a.go#L12:5-12:20"), like the URLs of the web app, so
+    # they can be used to copy a link to the match. Like in those URLs, the characters of the
+    # ranges are counted in UTF-16 code units. Lines with hexadecimal previews (see
+    # previewIsHex) have one location for the whole line.
+    locations: [Location!]!
     # Whether or not the limit was hit.
     limitHit: Boolean!
+    # Whether the preview is truncated because the line is long. If so, the
+    # preview only contains the part of the line around its first match, and
+    # offsetAndLengths only include the parts of the matches in the preview.
+    previewTruncated: Boolean!
+    # The offset in characters of the preview in the line. It is 0 unless the
+    # preview is truncated.
+    previewOffset: Int!
+    # The full line. If the preview is truncated, it is fetched when requested.
+    line: String!
+    # Tuples of [offset, length] of the matches in the full line, measured in characters
+    # unless another unit is requested.
+    lineOffsetAndLengths(
+        # The unit of the offsets and lengths.
+        unit: OffsetUnit = CHARACTER
+    ): [[Int!]!]!
+    # Whether the preview (and line) contains the bytes of the line in hexadecimal
+    # separated by spaces, e.g. "48 69 00", because the line is not valid text. Such
+    # lines are only returned for searches with hexpreview:yes. Offsets refer to the
+    # hexadecimal text.
+    previewIsHex: Boolean!
+    # The function, method or type definition that contains the line (e.g. to
+    # show "in func handleRequest"), determined with ctags. It is the closest
+    # such definition that starts before the line, or null if there is none.
+    # It is computed when requested, which requires the symbols of the file.
+    enclosingSymbol: Symbol
+    # The rules of the search.rules site setting that the line hits, such as
+    # security rules that flag the uses of insecure APIs.
+    rules: [SearchRule!]!
+}
+
+# A rule of the search.rules site setting that search results are checked against.
+type SearchRule {
+    # The ID of the rule, such as "go/weak-hash".
+    id: String!
+    # The description of the problems that the rule finds.
+    description: String
+    # The severity of the problems that the rule finds.
+    severity: SearchRuleSeverity!
+}
+
+# The severity of the problems that a search rule finds.
+enum SearchRuleSeverity {
+    LOW
+    MEDIUM
+    HIGH
+    CRITICAL
+}
+
+# The unit of offsets and lengths in text.
+enum OffsetUnit {
+    # Unicode code points. Each byte that is not valid UTF-8 is one code point.
+    CHARACTER
+    # Bytes of the UTF-8 encoded text, as used to index strings in Go.
+    BYTE
+    # UTF-16 code units, as used to index strings in JavaScript.
+    UTF16
 }
 
 # A hunk.
@@ -3344,6 +3900,25 @@ type Site implements SettingsSubject {
         # Months of history (based on current UTC time).
         months: Int
     ): CodeIntelUsageStatistics!
+    # (experimental) Daily counts of searches by anonymized query features, most recent day first.
+    # Only site admins may access this field.
+    searchAnalytics(
+        # Days of history (based on current UTC time), including today.
+        days: Int = 14
+    ): [SearchAnalyticsRollup!]!
+    # (experimental) The current search load of the frontend instance serving this request.
+    # Only site admins may access this field.
+    searchLoad: SearchLoad!
+    # (experimental) The flags that gate experimental search behavior, with their overrides for
+    # users and organizations. Only site admins may access this field.
+    searchFeatureFlags: [SearchFeatureFlag!]!
+    # (experimental) The search configuration that is in effect on the frontend instance serving this
+    # request. It follows changes to the site configuration without a restart. Only site admins may
+    # access this field.
+    searchConfiguration: SearchConfiguration!
+    # The repositories that site admins excluded from search, most recently excluded first. Only site
+    # admins may access this field.
+    searchExcludedRepositories: [SearchExcludedRepository!]!
     # Monitoring overview for this site.
     #
     # Note: This is primarily used for displaying recently-fired alerts in the web app. If your intent
@@ -3649,6 +4224,121 @@ type CodeIntelEventLatencies {
     p99: Float!
 }
 
+# The number of searches on a day that had the same anonymized query features. Query text,
+# repositories and users are never recorded.
+type SearchAnalyticsRollup {
+    # The day (in UTC) of the searches, formatted as YYYY-MM-DD.
+    day: String!
+    # The pattern type of the searches ("literal", "regexp" or "structural").
+    patternType: String!
+    # The bucketed length of the search pattern, e.g. "4-10".
+    patternLength: String!
+    # The names of the filters used in the queries, e.g. "repo" and "file".
+    filters: [String!]!
+    # The bucketed latency of the searches, e.g. "500ms-1s".
+    latency: String!
+    # Whether the searches returned no results.
+    zeroResults: Boolean!
+    # The number of searches.
+    count: Int!
+}
+
+# The current search load of a frontend instance. The same values are exported as the Prometheus
+# gauges src_graphql_search_active, src_graphql_search_repos_queued and
+# src_graphql_search_repos_active.
+type SearchLoad {
+    # The number of searches currently executing.
+    activeSearches: Int!
+    # The number of repositories, across all executing searches, waiting for a searcher request slot.
+    queuedRepositories: Int!
+    # The number of repository revisions, across all executing searches, currently being searched by
+    # searcher.
+    activeRepositories: Int!
+    # The maximum number of concurrent searcher requests.
+    searcherRequestLimit: Int!
+    # The searches currently executing on this frontend instance, oldest first.
+    searches: [ActiveSearch!]!
+}
+
+# A search that is currently executing on a frontend instance (see SearchLoad.searches).
+type ActiveSearch {
+    # The unique ID of the search, which can be passed to the cancelSearch mutation.
+    id: ID!
+    # The user who ran the search, or null for anonymous searches and searches run by
+    # Sourcegraph itself (such as saved search notifications).
+    user: User
+    # The structure of the query, without the values of its fields (e.g. "regexp pattern:1 repo:1").
+    queryShape: String!
+    # When the search started.
+    startedAt: DateTime!
+    # The time since the search started, in milliseconds.
+    elapsedMilliseconds: Int!
+    # The number of repository revisions that the search has yet to search for text matches.
+    repositoriesRemaining: Int!
+}
+
+# The search configuration that is in effect on a frontend instance.
+type SearchConfiguration {
+    # The URL specifier of the searcher service.
+    searcherURL: String!
+    # Whether searcherURL is from the search.searcherURL site configuration (or else from the
+    # SEARCHER_URL environment variable).
+    searcherURLFromSiteConfig: Boolean!
+    # The URLs of the searcher instances that searches are sent to, sorted.
+    searcherEndpoints: [String!]!
+    # The error listing the searcher instances (e.g. if they are discovered with the Kubernetes API
+    # and it is unreachable), or null.
+    searcherEndpointsError: String
+    # Whether indexed search is enabled.
+    indexedSearchEnabled: Boolean!
+    # The timeout of searches without a timeout: or count: filter, in milliseconds.
+    defaultTimeoutMilliseconds: Int!
+    # The maximum timeout of searches, in milliseconds.
+    maxTimeoutMilliseconds: Int!
+    # The maximum time spent searching a single repository without an index when searching
+    # multiple repositories, in milliseconds, or null if there is no limit.
+    repositoryTimeoutMilliseconds: Int
+    # The number of concurrent requests sent to each searcher instance.
+    searcherConcurrency: Int!
+    # The maximum number of concurrent searcher requests across all searches, or null if there is no
+    # limit.
+    maxSearcherRequestsInFlight: Int
+    # The maximum number of results of a paginated search request.
+    maxResultsPerRequest: Int!
+    # The maximum number of repositories searched by a search, or null if there is no limit.
+    maxRepositories: Int
+}
+
+# A flag that gates experimental search behavior, so that it can be rolled out gradually.
+type SearchFeatureFlag {
+    # The name of the flag, as used in the search.featureFlags site configuration.
+    name: String!
+    # What the flag enables.
+    description: String!
+    # Whether the flag is enabled if it is not set in the site configuration.
+    defaultEnabled: Boolean!
+    # Whether the flag is enabled for users without overrides.
+    enabled: Boolean!
+    # The percentage of signed-in users for whom the flag is enabled by an experiment (see the
+    # search.experiments site configuration), or null if there is no experiment on the flag. The
+    # analytics events of users in the experiment are tagged with their arm ("enabled" or
+    # "disabled").
+    experimentPercentage: Int
+    # The overrides of the flag for organizations and users. The override of a user takes
+    # precedence over those of their organizations, which enable the flag if any of them does.
+    overrides: [SearchFeatureFlagOverride!]!
+}
+
+# An override of a search feature flag for a user or an organization.
+type SearchFeatureFlagOverride {
+    # The user or organization.
+    namespace: Namespace!
+    # Whether the flag is enabled.
+    enabled: Boolean!
+    # When the override was last changed.
+    updatedAt: DateTime!
+}
+
 # A deployment configuration.
 type DeploymentConfiguration {
     # The email.
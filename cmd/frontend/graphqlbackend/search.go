@@ -41,6 +41,10 @@ import (
 // logic that spans out into all the other search_* files.
 var mockResolveRepositories func(effectiveRepoFieldValues []string) (repoRevs, missingRepoRevs []*search.RepositoryRevisions, excludedRepos *excludedRepos, overLimit bool, err error)
 
+// reposPageSize is the number of repositories listed at a time when resolving
+// the repositories to search.
+const reposPageSize = 5000
+
 func maxReposToSearch() int {
 	switch max := conf.Get().MaxReposToSearch; {
 	case max <= 0:
@@ -63,6 +67,10 @@ type SearchArgs struct {
 type SearchImplementer interface {
 	Results(context.Context) (*SearchResultsResolver, error)
 	Suggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error)
+	OmniboxSuggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error)
+	Histogram(context.Context, *searchHistogramArgs) (*searchHistogramResolver, error)
+	MatchCountSeries(context.Context, *matchCountSeriesArgs) ([]*matchCountPointResolver, error)
+	SimilarCode(context.Context, *similarCodeArgs) (*similarCodeResultsResolver, error)
 	//lint:ignore U1000 is used by graphql via reflection
 	Stats(context.Context) (*searchResultsStats, error)
 }
@@ -121,17 +129,26 @@ func NewSearchImplementer(args *SearchArgs) (SearchImplementer, error) {
 		}
 	}
 
+	zoekt, searcherURLs := search.Indexed(), search.SearcherURLs()
+	if mockSearchBackends != nil {
+		zoekt, searcherURLs = mockSearchBackends()
+	}
+
 	return &searchResolver{
 		query:          queryInfo,
 		originalQuery:  args.Query,
 		versionContext: args.VersionContext,
 		pagination:     pagination,
 		patternType:    searchType,
-		zoekt:          search.Indexed(),
-		searcherURLs:   search.SearcherURLs(),
+		zoekt:          zoekt,
+		searcherURLs:   searcherURLs,
 	}, nil
 }
 
+// mockSearchBackends, if set, returns the Zoekt client and searcher endpoints
+// of searches instead of the ones of the configuration.
+var mockSearchBackends func() (*searchbackend.Zoekt, *endpoint.Map)
+
 func (r *schemaResolver) Search(args *SearchArgs) (SearchImplementer, error) {
 	return NewSearchImplementer(args)
 }
@@ -148,8 +165,8 @@ func queryForStableResults(args *SearchArgs, queryInfo query.QueryInfo) (*Search
 				return nil, nil, err
 			}
 			stableResultCount = int32(count64)
-			if stableResultCount > maxSearchResultsPerPaginatedRequest {
-				return nil, nil, fmt.Errorf("Stable searches are limited to at max count:%d results. Consider removing 'stable:', narrowing the search with 'repo:', or using the paginated search API.", maxSearchResultsPerPaginatedRequest)
+			if max := maxSearchResultsPerPaginatedRequest(); stableResultCount > max {
+				return nil, nil, fmt.Errorf("Stable searches are limited to at max count:%d results. Consider removing 'stable:', narrowing the search with 'repo:', or using the paginated search API.", max)
 			}
 		} else {
 			stableResultCount = defaultMaxSearchResults
@@ -172,8 +189,13 @@ func processPaginationRequest(args *SearchArgs, queryInfo query.QueryInfo) (*sea
 		if err != nil {
 			return nil, err
 		}
-		if *args.First < 0 || *args.First > maxSearchResultsPerPaginatedRequest {
-			return nil, fmt.Errorf("search: requested pagination 'first' value outside allowed range (0 - %d)", maxSearchResultsPerPaginatedRequest)
+		max := maxSearchResultsPerPaginatedRequest()
+		if *args.First < 0 || *args.First > max {
+			err := fmt.Errorf("search: requested pagination 'first' value outside allowed range (0 - %d)", max)
+			if *args.First > max {
+				err = newSearchError(searchErrorTooManyResults, err)
+			}
+			return nil, err
 		}
 		pagination = &searchPaginationInfo{
 			cursor: cursor,
@@ -242,6 +264,10 @@ type searchResolver struct {
 	patternType    query.SearchType
 	versionContext *string
 
+	// onlyRepoIDs, if non-nil, restricts the search to these repositories
+	// (see retrySearch).
+	onlyRepoIDs map[api.RepoID]struct{}
+
 	// Cached resolveRepositories results.
 	reposMu                   sync.Mutex
 	repoRevs, missingRepoRevs []*search.RepositoryRevisions
@@ -249,8 +275,16 @@ type searchResolver struct {
 	repoOverLimit             bool
 	repoErr                   error
 
+	// repoCache, if non-nil, is shared with the other searches of a batch
+	// (see searchBatch), so that they only resolve the same repositories once.
+	repoCache *repoResolutionCache
+
 	zoekt        *searchbackend.Zoekt
 	searcherURLs *endpoint.Map
+
+	// resultStream, if non-nil, receives the file matches of doResults as
+	// they are found (see StreamSearch).
+	resultStream SearchStream
 }
 
 // rawQuery returns the original query string input.
@@ -265,7 +299,15 @@ func (r *searchResolver) countIsSet() bool {
 }
 
 const defaultMaxSearchResults = 30
-const maxSearchResultsPerPaginatedRequest = 5000
+
+// maxSearchResultsPerPaginatedRequest returns the maximum number of results of
+// a paginated search request, from the search.limits site configuration.
+func maxSearchResultsPerPaginatedRequest() int32 {
+	if limits := conf.Get().SearchLimits; limits != nil && limits.MaxResultsPerRequest > 0 {
+		return int32(limits.MaxResultsPerRequest)
+	}
+	return 5000
+}
 
 func (r *searchResolver) maxResults() int32 {
 	if r.pagination != nil {
@@ -370,16 +412,17 @@ func exactlyOneRepo(repoFilters []string) bool {
 type excludedRepos struct {
 	forks    int
 	archived int
+	byAdmin  int // excluded from search by a site admin (see db.SearchExcludedRepos)
 }
 
-// computeExcludedRepositories returns a list of excluded repositories (forks or
-// archives) based on the search query.
+// computeExcludedRepositories returns a list of excluded repositories (forks,
+// archives, or excluded by a site admin) based on the search query.
 func computeExcludedRepositories(ctx context.Context, q query.QueryInfo, op db.ReposListOptions) (excluded *excludedRepos) {
 	if q == nil {
 		return &excludedRepos{}
 	}
 	var err error
-	var numExcludedForks, numExcludedArchived int
+	var numExcludedForks, numExcludedArchived, numExcludedByAdmin int
 	forkStr, _ := q.StringValue(query.FieldFork)
 	fork := parseYesNoOnly(forkStr)
 	if fork == Invalid && !exactlyOneRepo(op.IncludePatterns) {
@@ -406,7 +449,19 @@ func computeExcludedRepositories(ctx context.Context, q query.QueryInfo, op db.R
 			log15.Warn("repo count for excluded archive", "err", err)
 		}
 	}
-	return &excludedRepos{forks: numExcludedForks, archived: numExcludedArchived}
+	if op.NoSearchExcluded {
+		// Repos excluded by a site admin can't be included by the query, so
+		// always find out how many there are, to tell the user why they
+		// weren't searched.
+		selectExcluded := op
+		selectExcluded.OnlySearchExcluded = true
+		selectExcluded.NoSearchExcluded = false
+		numExcludedByAdmin, err = db.Repos.Count(ctx, selectExcluded)
+		if err != nil {
+			log15.Warn("repo count for excluded by admin", "err", err)
+		}
+	}
+	return &excludedRepos{forks: numExcludedForks, archived: numExcludedArchived, byAdmin: numExcludedByAdmin}
 }
 
 // resolveRepositories calls doResolveRepositories, caching the result for the common
@@ -416,6 +471,13 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, effectiveRepoF
 		return mockResolveRepositories(effectiveRepoFieldValues)
 	}
 
+	// 🚨 SECURITY: The repositories of a tenant's searches are restricted to
+	// those of the tenant.
+	ctx, err = withSearchTenant(ctx)
+	if err != nil {
+		return nil, nil, nil, false, err
+	}
+
 	tr, ctx := trace.New(ctx, "graphql.resolveRepositories", fmt.Sprintf("effectiveRepoFieldValues: %v", effectiveRepoFieldValues))
 	defer func() {
 		if err != nil {
@@ -493,9 +555,23 @@ func (r *searchResolver) resolveRepositories(ctx context.Context, effectiveRepoF
 		onlyPrivate:        visibility == query.Private,
 		onlyPublic:         visibility == query.Public,
 		commitAfter:        commitAfter,
+		submodules:         r.query.BoolValue(query.FieldSubmodules),
 		query:              r.query,
+		tenant:             search.TenantFromContext(ctx),
+	}
+	if r.repoCache != nil {
+		repoRevs, missingRepoRevs, overLimit, excludedRepos, err = r.repoCache.resolveRepositories(ctx, options)
+	} else {
+		repoRevs, missingRepoRevs, overLimit, excludedRepos, err = resolveRepositories(ctx, options)
+	}
+	if err == nil {
+		// 🚨 SECURITY: Enforce the restrictions of search-only access tokens.
+		repoRevs, missingRepoRevs, err = checkSearchScope(ctx, versionContextName, repoRevs, missingRepoRevs)
+	}
+	if err == nil && r.onlyRepoIDs != nil {
+		repoRevs = filterRepoRevsByID(repoRevs, r.onlyRepoIDs)
+		missingRepoRevs = filterRepoRevsByID(missingRepoRevs, r.onlyRepoIDs)
 	}
-	repoRevs, missingRepoRevs, overLimit, excludedRepos, err = resolveRepositories(ctx, options)
 	tr.LazyPrintf("resolveRepositories - done")
 	if effectiveRepoFieldValues == nil {
 		r.repoRevs = repoRevs
@@ -612,7 +688,9 @@ type resolveRepoOp struct {
 	commitAfter        string
 	onlyPrivate        bool
 	onlyPublic         bool
+	submodules         bool
 	query              query.QueryInfo
+	tenant             *schema.SearchTenant // if set, only the tenant's repositories are resolved
 }
 
 func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions, missingRepoRevisions []*search.RepositoryRevisions, overLimit bool, excludedRepos *excludedRepos, err error) {
@@ -661,6 +739,13 @@ func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions,
 		return nil, nil, false, nil, err
 	}
 
+	// 🚨 SECURITY: Restrict the repositories of a tenant's searches to those
+	// of the tenant. This is done after findPatternRevs so that the tenant's
+	// patterns are not parsed for revision specs.
+	if op.tenant != nil {
+		includePatterns = append(includePatterns, unionRegExps(op.tenant.RepositoryPatterns))
+	}
+
 	// If a version context is specified, gather the list of repository names
 	// to limit the results to these repositories.
 	var versionContextRepositories []string
@@ -686,16 +771,27 @@ func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions,
 		if err != nil {
 			return nil, nil, false, nil, errors.Wrap(err, "getting list of default repos")
 		}
+		defaultRepos, excludedRepos, err = withoutSearchExcludedRepos(ctx, defaultRepos)
+		if err != nil {
+			return nil, nil, false, nil, err
+		}
 	}
 
-	var repos []*types.Repo
+	// Repositories are listed and associated with their revisions a page at a
+	// time, so that searches over all repositories don't hold a list of all
+	// repositories on top of their revisions.
+	var nextRepos func() ([]*types.Repo, error)
 	if len(defaultRepos) > 0 {
-		repos = defaultRepos
+		repos := defaultRepos
 		if len(repos) > maxRepoListSize {
 			repos = repos[:maxRepoListSize]
 		}
+		nextRepos = func() ([]*types.Repo, error) {
+			page := repos
+			repos = nil
+			return page, nil
+		}
 	} else {
-		tr.LazyPrintf("Repos.List - start")
 		options := db.ReposListOptions{
 			OnlyRepoIDs:     true,
 			IncludePatterns: includePatterns,
@@ -709,97 +805,160 @@ func resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevisions,
 			OnlyArchived: op.onlyArchived,
 			NoPrivate:    op.onlyPublic,
 			OnlyPrivate:  op.onlyPrivate,
+			// Site admins can exclude repos from search.
+			NoSearchExcluded: true,
 		}
 		excludedRepos = computeExcludedRepositories(ctx, op.query, options)
-		repos, err = db.Repos.List(ctx, options)
-		tr.LazyPrintf("Repos.List - done")
-		if err != nil {
-			return nil, nil, false, nil, err
+		it := db.Repos.Iterate(options, reposPageSize)
+		nextRepos = func() ([]*types.Repo, error) {
+			tr.LazyPrintf("Repos.Iterate - start")
+			defer tr.LazyPrintf("Repos.Iterate - done")
+			return it.Next(ctx)
 		}
 	}
-	overLimit = len(repos) >= maxRepoListSize
 
-	repoRevisions = make([]*search.RepositoryRevisions, 0, len(repos))
 	tr.LazyPrintf("Associate/validate revs - start")
 
-	for _, repo := range repos {
-		var repoRev search.RepositoryRevisions
-		var revs []search.RevisionSpecifier
-		// versionContext will be nil if the query contains revision specifiers
-		if versionContext != nil {
-			for _, vcRepoRev := range versionContext.Revisions {
-				if vcRepoRev.Repo == string(repo.Name) {
-					repoRev.Repo = repo
-					revs = append(revs, search.RevisionSpecifier{RevSpec: vcRepoRev.Rev})
-				}
-			}
-		} else {
-			var clashingRevs []search.RevisionSpecifier
-			revs, clashingRevs = getRevsForMatchedRepo(repo.Name, includePatternRevs)
-			repoRev.Repo = repo
-			// if multiple specified revisions clash, report this usefully:
-			if len(revs) == 0 && clashingRevs != nil {
-				missingRepoRevisions = append(missingRepoRevisions, &search.RepositoryRevisions{
-					Repo: repo,
-					Revs: clashingRevs,
-				})
-			}
+	repoRevisions = []*search.RepositoryRevisions{}
+	var (
+		latestReleases bool
+		numRepos       int
+	)
+	for {
+		repos, err := nextRepos()
+		if err != nil {
+			return nil, nil, false, nil, err
 		}
-
-		// We do in place filtering to reduce allocations. Common path is no
-		// filtering of revs.
-		if len(revs) > 0 {
-			repoRev.Revs = revs[:0]
+		if len(repos) == 0 {
+			break
 		}
-
-		// Check if the repository actually has the revisions that the user specified.
-		for _, rev := range revs {
-			if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
-				// Do not validate ref patterns. A ref pattern matching 0 refs is not necessarily
-				// invalid, so it's not clear what validation would even mean.
-			} else if isDefaultBranch := rev.RevSpec == ""; !isDefaultBranch { // skip default branch resolution to save time
-				// Validate the revspec.
-
-				// Do not trigger a repo-updater lookup (e.g.,
-				// backend.{GitRepo,Repos.ResolveRev}) because that would slow this operation
-				// down by a lot (if we're looping over many repos). This means that it'll fail if a
-				// repo is not on gitserver.
-				//
-				// TODO(sqs): make this NOT send gitserver this revspec in EnsureRevision, to avoid
-				// searches like "repo:@foobar" (where foobar is an invalid revspec on most repos)
-				// taking a long time because they all ask gitserver to try to fetch from the remote
-				// repo.
-				if _, err := git.ResolveRevision(ctx, repoRev.GitserverRepo(), nil, rev.RevSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true}); gitserver.IsRevisionNotFound(err) || err == context.DeadlineExceeded {
-					// The revspec does not exist, so don't include it, and report that it's missing.
-					if rev.RevSpec == "" {
-						// Report as HEAD not "" (empty string) to avoid user confusion.
-						rev.RevSpec = "HEAD"
+		numRepos += len(repos)
+		for _, repo := range repos {
+			var repoRev search.RepositoryRevisions
+			var revs []search.RevisionSpecifier
+			// versionContext will be nil if the query contains revision specifiers
+			if versionContext != nil {
+				for _, vcRepoRev := range versionContext.Revisions {
+					if vcRepoRev.Repo == string(repo.Name) {
+						repoRev.Repo = repo
+						revs = append(revs, search.RevisionSpecifier{RevSpec: vcRepoRev.Rev})
 					}
+				}
+			} else {
+				var clashingRevs []search.RevisionSpecifier
+				revs, clashingRevs = getRevsForMatchedRepo(repo.Name, includePatternRevs)
+				repoRev.Repo = repo
+				// if multiple specified revisions clash, report this usefully:
+				if len(revs) == 0 && clashingRevs != nil {
 					missingRepoRevisions = append(missingRepoRevisions, &search.RepositoryRevisions{
 						Repo: repo,
-						Revs: []search.RevisionSpecifier{{RevSpec: rev.RevSpec}},
+						Revs: clashingRevs,
 					})
-					continue
 				}
-				// If err != nil and is not one of the err values checked for above, cloning and other errors will be handled later, so just ignore an error
-				// if there is one.
 			}
 
-			repoRev.Revs = append(repoRev.Revs, rev)
-		}
+			// We do in place filtering to reduce allocations. Common path is no
+			// filtering of revs.
+			if len(revs) > 0 {
+				repoRev.Revs = revs[:0]
+			}
+
+			// Check if the repository actually has the revisions that the user specified.
+			for _, rev := range revs {
+				if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+					// Do not validate ref patterns. A ref pattern matching 0 refs is not necessarily
+					// invalid, so it's not clear what validation would even mean.
+				} else if rev.RevSpec == search.LatestReleaseRevSpec {
+					// Resolved below, for all repositories concurrently.
+					latestReleases = true
+				} else if isDefaultBranch := rev.RevSpec == ""; !isDefaultBranch { // skip default branch resolution to save time
+					// Validate the revspec.
+
+					// Do not trigger a repo-updater lookup (e.g.,
+					// backend.{GitRepo,Repos.ResolveRev}) because that would slow this operation
+					// down by a lot (if we're looping over many repos). This means that it'll fail if a
+					// repo is not on gitserver.
+					//
+					// TODO(sqs): make this NOT send gitserver this revspec in EnsureRevision, to avoid
+					// searches like "repo:@foobar" (where foobar is an invalid revspec on most repos)
+					// taking a long time because they all ask gitserver to try to fetch from the remote
+					// repo.
+					if _, err := git.ResolveRevision(ctx, repoRev.GitserverRepo(), nil, rev.RevSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true}); gitserver.IsRevisionNotFound(err) || err == context.DeadlineExceeded {
+						// The revspec does not exist, so don't include it, and report that it's missing.
+						if rev.RevSpec == "" {
+							// Report as HEAD not "" (empty string) to avoid user confusion.
+							rev.RevSpec = "HEAD"
+						}
+						missingRepoRevisions = append(missingRepoRevisions, &search.RepositoryRevisions{
+							Repo: repo,
+							Revs: []search.RevisionSpecifier{{RevSpec: rev.RevSpec}},
+						})
+						continue
+					}
+					// If err != nil and is not one of the err values checked for above, cloning and other errors will be handled later, so just ignore an error
+					// if there is one.
+				}
+
+				repoRev.Revs = append(repoRev.Revs, rev)
+			}
 
-		repoRevisions = append(repoRevisions, &repoRev)
+			repoRevisions = append(repoRevisions, &repoRev)
+		}
 	}
+	overLimit = numRepos >= maxRepoListSize
 
 	tr.LazyPrintf("Associate/validate revs - done")
 
+	if latestReleases {
+		tr.LazyPrintf("Resolve latest releases - start")
+		var missing []*search.RepositoryRevisions
+		repoRevisions, missing = resolveLatestReleases(ctx, repoRevisions)
+		missingRepoRevisions = append(missingRepoRevisions, missing...)
+		tr.LazyPrintf("Resolve latest releases - done")
+	}
+
 	if op.commitAfter != "" {
 		repoRevisions, err = filterRepoHasCommitAfter(ctx, repoRevisions, op.commitAfter)
+		if err != nil {
+			return nil, nil, false, nil, err
+		}
+	}
+
+	if op.submodules {
+		tr.LazyPrintf("Add submodules - start")
+		repoRevisions, err = addSubmodules(ctx, repoRevisions)
+		tr.LazyPrintf("Add submodules - done")
 	}
 
 	return repoRevisions, missingRepoRevisions, overLimit, excludedRepos, err
 }
 
+// withoutSearchExcludedRepos removes the repos that site admins excluded from
+// search from repos, and counts them.
+func withoutSearchExcludedRepos(ctx context.Context, repos []*types.Repo) ([]*types.Repo, *excludedRepos, error) {
+	excludedList, err := db.SearchExcludedRepos.List(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "listing repos excluded from search")
+	}
+	if len(excludedList) == 0 {
+		return repos, &excludedRepos{}, nil
+	}
+	excludedIDs := make(map[api.RepoID]bool, len(excludedList))
+	for _, r := range excludedList {
+		excludedIDs[r.RepoID] = true
+	}
+	excluded := &excludedRepos{}
+	filtered := repos[:0:0]
+	for _, repo := range repos {
+		if excludedIDs[repo.ID] {
+			excluded.byAdmin++
+			continue
+		}
+		filtered = append(filtered, repo)
+	}
+	return filtered, excluded, nil
+}
+
 type indexedReposFunc func(ctx context.Context, revs []*search.RepositoryRevisions) (indexed, unindexed []*search.RepositoryRevisions, err error)
 type defaultReposFunc func(ctx context.Context) ([]*types.Repo, error)
 
@@ -0,0 +1,133 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var (
+	searcherMaxInFlight  = env.Get("SEARCHER_MAX_IN_FLIGHT", "0", "maximum number of concurrent searcher requests across all searches of this frontend (0 means no limit)")
+	searcherMaxQueueWait = env.Get("SEARCHER_MAX_QUEUE_WAIT", "5s", "maximum time a searcher request waits for one of SEARCHER_MAX_IN_FLIGHT slots before its search is rejected")
+)
+
+var searcherAdmissionRejected = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "src_graphql_searcher_admission_rejected_total",
+	Help: "Number of searcher requests rejected because SEARCHER_MAX_IN_FLIGHT requests were in flight for longer than SEARCHER_MAX_QUEUE_WAIT.",
+})
+
+// searcherAdmission limits the number of searcher requests in flight across
+// all searches. Unlike textSearchLimiter, which only queues requests, it
+// rejects requests that cannot be admitted within a bounded wait, so that an
+// overloaded searcher fails some searches fast instead of slowing all of them
+// down.
+type searcherAdmission struct {
+	slots   chan struct{} // nil if there is no limit
+	maxWait time.Duration
+}
+
+var (
+	searcherAdmissionEnvOnce sync.Once
+	searcherAdmissionEnvMax  int
+	searcherAdmissionEnvWait time.Duration
+
+	textSearchAdmissionMu sync.Mutex
+	textSearchAdmission   *searcherAdmission
+)
+
+// getTextSearchAdmission returns the admission control configured by
+// search.limits.maxSearcherRequestsInFlight in the site configuration (or
+// SEARCHER_MAX_IN_FLIGHT if it is unset) and SEARCHER_MAX_QUEUE_WAIT. When the
+// configured limit changes, new requests are admitted by a new admission
+// control, while requests in flight release their slot in the old one.
+func getTextSearchAdmission() *searcherAdmission {
+	searcherAdmissionEnvOnce.Do(func() {
+		max, err := strconv.Atoi(searcherMaxInFlight)
+		if err != nil {
+			log15.Error("Invalid SEARCHER_MAX_IN_FLIGHT, disabling searcher admission control", "value", searcherMaxInFlight, "error", err)
+			max = 0
+		}
+		wait, err := time.ParseDuration(searcherMaxQueueWait)
+		if err != nil {
+			log15.Error("Invalid SEARCHER_MAX_QUEUE_WAIT, using 5s", "value", searcherMaxQueueWait, "error", err)
+			wait = 5 * time.Second
+		}
+		searcherAdmissionEnvMax, searcherAdmissionEnvWait = max, wait
+	})
+
+	max := searcherAdmissionEnvMax
+	if limits := conf.Get().SearchLimits; limits != nil && limits.MaxSearcherRequestsInFlight != nil {
+		max = *limits.MaxSearcherRequestsInFlight
+	}
+
+	textSearchAdmissionMu.Lock()
+	defer textSearchAdmissionMu.Unlock()
+	if textSearchAdmission == nil || textSearchAdmission.maxInFlight() != max {
+		textSearchAdmission = newSearcherAdmission(max, searcherAdmissionEnvWait)
+	}
+	return textSearchAdmission
+}
+
+func newSearcherAdmission(maxInFlight int, maxWait time.Duration) *searcherAdmission {
+	a := &searcherAdmission{maxWait: maxWait}
+	if maxInFlight > 0 {
+		a.slots = make(chan struct{}, maxInFlight)
+	}
+	return a
+}
+
+// maxInFlight returns the maximum number of requests in flight, or 0 if there
+// is no limit.
+func (a *searcherAdmission) maxInFlight() int {
+	return cap(a.slots)
+}
+
+// admit waits for a free slot for a searcher request. The caller must call the
+// returned release func once the request is done. If no slot becomes free
+// within the maximum wait, admit returns a SearcherOverloaded error that tells
+// clients to retry after the maximum wait.
+func (a *searcherAdmission) admit(ctx context.Context) (release func(), err error) {
+	if a.slots == nil {
+		return func() {}, nil
+	}
+	release = func() { <-a.slots }
+
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	timer := time.NewTimer(a.maxWait)
+	defer timer.Stop()
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		searcherAdmissionRejected.Inc()
+		return nil, &searchError{
+			code:       searchErrorSearcherOverloaded,
+			err:        fmt.Errorf("searcher is overloaded (%d requests in flight), try again later", cap(a.slots)),
+			retryAfter: a.retryAfter(),
+		}
+	}
+}
+
+// retryAfter returns the duration clients are asked to wait before retrying a
+// rejected search.
+func (a *searcherAdmission) retryAfter() time.Duration {
+	if a.maxWait < time.Second {
+		return time.Second
+	}
+	return a.maxWait
+}
@@ -0,0 +1,81 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearcherAdmission(t *testing.T) {
+	ctx := context.Background()
+	a := newSearcherAdmission(1, 10*time.Millisecond)
+
+	release, err := a.admit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = a.admit(ctx)
+	if code, _ := searchErrorCodeOf(err); code != searchErrorSearcherOverloaded {
+		t.Fatalf("got error %v, want code %q", err, searchErrorSearcherOverloaded)
+	}
+	ext := withSearchErrorCode(errors.Wrap(err, "failed to search")).(*searchError).Extensions()
+	if ext["retryAfter"] != 1 {
+		t.Errorf("got retryAfter %v, want 1", ext["retryAfter"])
+	}
+
+	release()
+	release, err = a.admit(ctx)
+	if err != nil {
+		t.Fatalf("expected admission after release, got %v", err)
+	}
+	release()
+}
+
+func TestSearcherAdmission_canceled(t *testing.T) {
+	a := newSearcherAdmission(1, time.Minute)
+	release, err := a.admit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.admit(ctx); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestSearcherAdmission_unlimited(t *testing.T) {
+	a := newSearcherAdmission(0, 0)
+	for i := 0; i < 100; i++ {
+		if _, err := a.admit(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetTextSearchAdmission_siteConfig(t *testing.T) {
+	defer conf.Mock(nil)
+	max := 2
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchLimits: &schema.SearchLimits{MaxSearcherRequestsInFlight: &max},
+	}})
+	a := getTextSearchAdmission()
+	if got := a.maxInFlight(); got != 2 {
+		t.Errorf("got max in flight %d, want 2", got)
+	}
+	if getTextSearchAdmission() != a {
+		t.Error("admission control changed although the configuration did not")
+	}
+
+	max = 0
+	if got := getTextSearchAdmission().maxInFlight(); got != 0 {
+		t.Errorf("got max in flight %d after reconfiguration, want 0", got)
+	}
+}
@@ -0,0 +1,156 @@
+package graphqlbackend
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/inventory"
+)
+
+// searchAggregations counts the matches of a search by repository, language,
+// file extension and directory. The counts are accumulated as the matches come
+// in from the search backends, so they include matches that were found but not
+// returned because of the result limit.
+type searchAggregations struct {
+	repos, languages, extensions, directories map[string]int32
+
+	// repoDirectories counts the matches by the directory containing the
+	// file in its repository ("" for the root), for the histogram of paths
+	// (see searchHistogramResolver).
+	repoDirectories map[repoDirectory]int32
+}
+
+type repoDirectory struct {
+	repo string
+	dir  string
+}
+
+func newSearchAggregations() *searchAggregations {
+	return &searchAggregations{
+		repos:       map[string]int32{},
+		languages:   map[string]int32{},
+		extensions:  map[string]int32{},
+		directories: map[string]int32{},
+
+		repoDirectories: map[repoDirectory]int32{},
+	}
+}
+
+// addFileMatches counts matches. It does not support concurrent use.
+func (a *searchAggregations) addFileMatches(matches []*FileMatchResolver) {
+	for _, fm := range matches {
+		n := fm.resultCount()
+		if fm.Repo != nil {
+			a.repos[string(fm.Repo.Name)] += n
+		}
+		if lang, _ := inventory.GetLanguageByFilename(fm.JPath); lang != "" {
+			a.languages[lang] += n
+		}
+		if ext := path.Ext(fm.JPath); ext != "" {
+			a.extensions[strings.ToLower(ext)] += n
+		}
+		dir := path.Dir(fm.JPath)
+		if dir != "." {
+			a.directories[dir] += n
+		} else {
+			dir = ""
+		}
+		if fm.Repo != nil {
+			a.repoDirectories[repoDirectory{repo: string(fm.Repo.Name), dir: dir}] += n
+		}
+	}
+}
+
+func (a *searchAggregations) merge(other *searchAggregations) {
+	for _, m := range []struct{ dst, src map[string]int32 }{
+		{a.repos, other.repos},
+		{a.languages, other.languages},
+		{a.extensions, other.extensions},
+		{a.directories, other.directories},
+	} {
+		for k, n := range m.src {
+			m.dst[k] += n
+		}
+	}
+	for k, n := range other.repoDirectories {
+		a.repoDirectories[k] += n
+	}
+}
+
+type searchAggregationsResolver struct {
+	a     *searchAggregations
+	first int
+}
+
+func (c *searchResultsCommon) Aggregations(args *struct{ First int32 }) *searchAggregationsResolver {
+	a := c.aggregations
+	if a == nil {
+		a = newSearchAggregations()
+	}
+	return &searchAggregationsResolver{a: a, first: int(args.First)}
+}
+
+func (r *searchAggregationsResolver) Repositories() []*searchAggregationBucketResolver {
+	return topBuckets(r.a.repos, r.first, func(repo string) string {
+		return fmt.Sprintf("repo:^%s$", regexp.QuoteMeta(repo))
+	})
+}
+
+func (r *searchAggregationsResolver) Languages() []*searchAggregationBucketResolver {
+	return topBuckets(r.a.languages, r.first, func(lang string) string {
+		lang = strings.ToLower(lang)
+		if strings.Contains(lang, " ") {
+			lang = strconv.Quote(lang)
+		}
+		return "lang:" + lang
+	})
+}
+
+func (r *searchAggregationsResolver) FileExtensions() []*searchAggregationBucketResolver {
+	return topBuckets(r.a.extensions, r.first, func(ext string) string {
+		return fmt.Sprintf(`file:%s$`, regexp.QuoteMeta(ext))
+	})
+}
+
+func (r *searchAggregationsResolver) Directories() []*searchAggregationBucketResolver {
+	return topBuckets(r.a.directories, r.first, func(dir string) string {
+		return fmt.Sprintf("file:^%s/", regexp.QuoteMeta(dir))
+	})
+}
+
+// topBuckets returns the first buckets of counts with the highest counts
+// (ties are broken by value), using filter to compute the query filter that
+// restricts a search to a bucket.
+func topBuckets(counts map[string]int32, first int, filter func(value string) string) []*searchAggregationBucketResolver {
+	buckets := make([]*searchAggregationBucketResolver, 0, len(counts))
+	for value, count := range counts {
+		buckets = append(buckets, &searchAggregationBucketResolver{value: value, count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].count != buckets[j].count {
+			return buckets[i].count > buckets[j].count
+		}
+		return buckets[i].value < buckets[j].value
+	})
+	if first >= 0 && len(buckets) > first {
+		buckets = buckets[:first]
+	}
+	for _, b := range buckets {
+		b.filter = filter(b.value)
+	}
+	return buckets
+}
+
+type searchAggregationBucketResolver struct {
+	value  string
+	count  int32
+	filter string
+}
+
+func (b *searchAggregationBucketResolver) Value() string  { return b.value }
+func (b *searchAggregationBucketResolver) Count() int32   { return b.count }
+func (b *searchAggregationBucketResolver) Filter() string { return b.filter }
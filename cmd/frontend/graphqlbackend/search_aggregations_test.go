@@ -0,0 +1,72 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSearchAggregations(t *testing.T) {
+	foo := &types.Repo{Name: "github.com/foo/foo"}
+	bar := &types.Repo{Name: "github.com/foo/bar"}
+
+	a := newSearchAggregations()
+	a.addFileMatches([]*FileMatchResolver{
+		{Repo: foo, JPath: "cmd/main.go", MatchCount: 3},
+		{Repo: foo, JPath: "cmd/util.go", MatchCount: 1},
+		{Repo: foo, JPath: "README.md"},
+	})
+	b := newSearchAggregations()
+	b.addFileMatches([]*FileMatchResolver{
+		{Repo: bar, JPath: "web/index.TS", MatchCount: 2},
+	})
+
+	common := &searchResultsCommon{aggregations: a}
+	common.update(searchResultsCommon{aggregations: b})
+	r := common.Aggregations(&struct{ First int32 }{First: 2})
+
+	type bucket struct {
+		Value, Filter string
+		Count         int32
+	}
+	toBuckets := func(rs []*searchAggregationBucketResolver) (bs []bucket) {
+		for _, r := range rs {
+			bs = append(bs, bucket{Value: r.Value(), Filter: r.Filter(), Count: r.Count()})
+		}
+		return bs
+	}
+
+	tests := map[string]struct {
+		got, want []bucket
+	}{
+		"repositories": {toBuckets(r.Repositories()), []bucket{
+			{"github.com/foo/foo", `repo:^github\.com/foo/foo$`, 5},
+			{"github.com/foo/bar", `repo:^github\.com/foo/bar$`, 2},
+		}},
+		"languages": {toBuckets(r.Languages()), []bucket{
+			{"Go", "lang:go", 4},
+			{"TypeScript", "lang:typescript", 2},
+		}},
+		"fileExtensions": {toBuckets(r.FileExtensions()), []bucket{
+			{".go", `file:\.go$`, 4},
+			{".ts", `file:\.ts$`, 2},
+		}},
+		"directories": {toBuckets(r.Directories()), []bucket{
+			{"cmd", "file:^cmd/", 4},
+			{"web", "file:^web/", 2},
+		}},
+	}
+	for name, test := range tests {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", name, test.got, test.want)
+		}
+	}
+}
+
+func TestSearchAggregations_none(t *testing.T) {
+	r := (&searchResultsCommon{}).Aggregations(&struct{ First int32 }{First: 10})
+	if len(r.Repositories()) != 0 || len(r.Languages()) != 0 {
+		t.Error("expected no buckets")
+	}
+}
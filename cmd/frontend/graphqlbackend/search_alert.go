@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/envvar"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
@@ -24,6 +25,8 @@ type searchAlert struct {
 	title           string
 	description     string
 	proposedQueries []*searchQueryDescription
+	// repositories are the repositories the alert is about, if any.
+	repositories []*types.Repo
 }
 
 func (a searchAlert) Title() string { return a.title }
@@ -42,6 +45,10 @@ func (a searchAlert) ProposedQueries() *[]*searchQueryDescription {
 	return &a.proposedQueries
 }
 
+func (a searchAlert) AffectedRepositories() []*RepositoryResolver {
+	return RepositoryResolvers(append(types.Repos(nil), a.repositories...))
+}
+
 func alertForCappedAndExpression() *searchAlert {
 	return &searchAlert{
 		prometheusType: "exceed_and_expression_search_limit",
@@ -126,11 +133,29 @@ func alertForQuotesInQueryInLiteralMode(p syntax.ParseTree) *searchAlert {
 	}
 }
 
+// alertForDefaultExclusions is raised for searches with file: filters and no
+// results, which may be looking for paths that are excluded by default.
+func alertForDefaultExclusions(r *searchResolver) *searchAlert {
+	return &searchAlert{
+		prometheusType: "no_results__default_exclusions",
+		title:          "No results. Some paths are excluded by default.",
+		description:    "Searches exclude the build output of some languages by default, such as dist/ and target/. Add defaultexclusions:no to the query to search these paths too.",
+		proposedQueries: []*searchQueryDescription{{
+			description: "include the paths that are excluded by default",
+			query:       omitQueryField(r.query.ParseTree(), query.FieldDefaultExclusions) + " defaultexclusions:no",
+			patternType: r.patternType,
+		}},
+	}
+}
+
 // reposExist returns true if one or more repos resolve. If the attempt
 // returns 0 repos or fails, it returns false. It is a helper function for
 // raising NoResolvedRepos alerts with suggestions when we know the original
 // query does not contain any repos to search.
 func reposExist(ctx context.Context, options resolveRepoOp) bool {
+	// 🚨 SECURITY: Suggestions must not reveal repositories outside of the
+	// tenant of the search.
+	options.tenant = search.TenantFromContext(ctx)
 	repos, _, _, _, err := resolveRepositories(ctx, options)
 	return err == nil && len(repos) > 0
 }
@@ -516,6 +541,69 @@ func alertForMissingRepoRevs(patternType query.SearchType, missingRepoRevs []*se
 	}
 }
 
+// maxFailedReposInProposedQuery is the maximum number of repositories that
+// could not be searched for which alertForFailedRepos proposes a query that
+// excludes them.
+const maxFailedReposInProposedQuery = 5
+
+// alertForFailedRepos returns an alert for a search that returned the results
+// of some repositories while others could not be searched because of errors.
+func (r *searchResolver) alertForFailedRepos(common *searchResultsCommon) *searchAlert {
+	failed := append(types.Repos(nil), common.failed...)
+	dedupSort(&failed)
+	searched := append(types.Repos(nil), common.searched...)
+	dedupSort(&searched)
+	completed := len(searched) - len(failed)
+	if completed < 0 {
+		completed = 0
+	}
+
+	alert := &searchAlert{
+		prometheusType: "failed_repos",
+		title:          fmt.Sprintf("Search completed on %d/%d repositories", completed, completed+len(failed)),
+		description:    fmt.Sprintf("%d repositories could not be searched because of errors. Results from the other repositories are shown.", len(failed)),
+		repositories:   failed,
+	}
+	if len(failed) == 1 {
+		alert.description = fmt.Sprintf("The repository %s could not be searched because of an error. Results from the other repositories are shown.", failed[0].Name)
+	}
+
+	if _, ok := r.query.(*query.OrdinaryQuery); ok && len(failed) <= maxFailedReposInProposedQuery {
+		excludes := make([]string, 0, len(failed))
+		for _, repo := range failed {
+			excludes = append(excludes, fmt.Sprintf("-repo:^%s$", regexp.QuoteMeta(string(repo.Name))))
+		}
+		alert.proposedQueries = []*searchQueryDescription{{
+			description: "exclude the repositories that could not be searched",
+			query:       omitQueryField(r.query.ParseTree(), query.FieldPatternType) + " " + strings.Join(excludes, " "),
+			patternType: r.patternType,
+		}}
+	}
+	return alert
+}
+
+// alertForReposExcludedByAdmin returns an alert for a search whose
+// repositories were all excluded from search by a site admin.
+func alertForReposExcludedByAdmin(count int) *searchAlert {
+	description := fmt.Sprintf("The %d repositories matched by your query were excluded from search by a site admin.", count)
+	if count == 1 {
+		description = "The repository matched by your query was excluded from search by a site admin."
+	}
+	return &searchAlert{
+		prometheusType: "no_resolved_repos__excluded_by_admin",
+		title:          "Repositories excluded by admin",
+		description:    description,
+	}
+}
+
+func alertForDegradedSearch() *searchAlert {
+	return &searchAlert{
+		prometheusType: "degraded",
+		title:          "Search is degraded",
+		description:    "The search service is unavailable, so only a limited number of repositories were searched using a slower fallback. Some results may be missing and matches may not be highlighted precisely.",
+	}
+}
+
 func omitQueryField(p syntax.ParseTree, field string) string {
 	omitField := func(e syntax.Expr) *syntax.Expr {
 		if e.Field == field {
@@ -603,4 +691,7 @@ func (a searchAlert) Results(context.Context) (*SearchResultsResolver, error) {
 func (searchAlert) Suggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error) {
 	return nil, nil
 }
+func (searchAlert) OmniboxSuggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error) {
+	return nil, nil
+}
 func (searchAlert) Stats(context.Context) (*searchResultsStats, error) { return nil, nil }
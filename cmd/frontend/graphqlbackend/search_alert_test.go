@@ -285,3 +285,47 @@ func TestAlertForOverRepoLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestAlertForFailedRepos(t *testing.T) {
+	repo := func(id api.RepoID, name string) *types.Repo {
+		return &types.Repo{ID: id, Name: api.RepoName(name)}
+	}
+	a, b, c := repo(1, "a/foo.js"), repo(2, "b"), repo(3, "c")
+
+	q, err := query.ParseAndCheck("a query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := searchResolver{query: q}
+	alert := sr.alertForFailedRepos(&searchResultsCommon{
+		searched: []*types.Repo{a, b, c, a},
+		failed:   []*types.Repo{a},
+	})
+	wantAlert := &searchAlert{
+		prometheusType: "failed_repos",
+		title:          "Search completed on 2/3 repositories",
+		description:    "The repository a/foo.js could not be searched because of an error. Results from the other repositories are shown.",
+		proposedQueries: []*searchQueryDescription{{
+			description: "exclude the repositories that could not be searched",
+			query:       `a query -repo:^a/foo\.js$`,
+		}},
+		repositories: []*types.Repo{a},
+	}
+	if !reflect.DeepEqual(alert, wantAlert) {
+		t.Fatalf("have alert %+v, want: %+v", alert, wantAlert)
+	}
+}
+
+func TestAlertForDefaultExclusions(t *testing.T) {
+	q, err := query.ParseAndCheck("file:^dist/app foo defaultexclusions:yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert := alertForDefaultExclusions(&searchResolver{query: q, patternType: query.SearchTypeLiteral})
+	if len(alert.proposedQueries) != 1 {
+		t.Fatalf("got %d proposed queries, want 1", len(alert.proposedQueries))
+	}
+	if got, want := alert.proposedQueries[0].query, "file:^dist/app foo defaultexclusions:no"; got != want {
+		t.Errorf("got proposed query %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/usagestats"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func (r *siteResolver) SearchAnalytics(ctx context.Context, args *struct {
+	Days int32
+}) ([]*searchAnalyticsRollupResolver, error) {
+	// 🚨 SECURITY: Only site admins may view search analytics.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	rollups, err := usagestats.GetSearchAnalytics(ctx, int(args.Days))
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*searchAnalyticsRollupResolver, 0, len(rollups))
+	for _, r := range rollups {
+		resolvers = append(resolvers, &searchAnalyticsRollupResolver{rollup: r})
+	}
+	return resolvers, nil
+}
+
+type searchAnalyticsRollupResolver struct {
+	rollup *types.SearchAnalyticsRollup
+}
+
+func (r *searchAnalyticsRollupResolver) Day() string {
+	return r.rollup.Day.UTC().Format("2006-01-02")
+}
+
+func (r *searchAnalyticsRollupResolver) PatternType() string { return r.rollup.PatternType }
+
+func (r *searchAnalyticsRollupResolver) PatternLength() string { return r.rollup.PatternLength }
+
+func (r *searchAnalyticsRollupResolver) Filters() []string {
+	if r.rollup.Filters == "" {
+		return []string{}
+	}
+	return strings.Split(r.rollup.Filters, ",")
+}
+
+func (r *searchAnalyticsRollupResolver) Latency() string { return r.rollup.Latency }
+
+func (r *searchAnalyticsRollupResolver) ZeroResults() bool { return r.rollup.ZeroResults }
+
+func (r *searchAnalyticsRollupResolver) Count() int32 { return r.rollup.Count }
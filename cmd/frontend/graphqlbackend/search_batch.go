@@ -0,0 +1,114 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// maxBatchSearchQueries is the maximum number of queries in a searchBatch
+// request.
+const maxBatchSearchQueries = 50
+
+type batchSearchQuery struct {
+	Version        string
+	PatternType    *string
+	Query          string
+	VersionContext *string
+}
+
+// SearchBatch returns a search for each of several independent queries, like
+// dashboards issue. The searches share the repositories they resolve, so
+// queries over the same repositories only resolve them once. (They already
+// share the connections to searcher and zoekt, which are pooled per process.)
+func (r *schemaResolver) SearchBatch(args *struct{ Queries []*batchSearchQuery }) ([]SearchImplementer, error) {
+	if len(args.Queries) > maxBatchSearchQueries {
+		return nil, fmt.Errorf("searchBatch: at most %d queries can be searched at once", maxBatchSearchQueries)
+	}
+
+	cache := &repoResolutionCache{m: map[string]*repoResolution{}}
+	searches := make([]SearchImplementer, 0, len(args.Queries))
+	for _, q := range args.Queries {
+		s, err := NewSearchImplementer(&SearchArgs{
+			Version:        q.Version,
+			PatternType:    q.PatternType,
+			Query:          q.Query,
+			VersionContext: q.VersionContext,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if sr, ok := s.(*searchResolver); ok {
+			sr.repoCache = cache
+		}
+		searches = append(searches, s)
+	}
+	return searches, nil
+}
+
+// repoResolutionCache caches the results of resolveRepositories for the
+// searches of a batch.
+type repoResolutionCache struct {
+	mu sync.Mutex
+	m  map[string]*repoResolution
+}
+
+type repoResolution struct {
+	mu   sync.Mutex
+	done bool
+
+	repoRevs, missingRepoRevs []*search.RepositoryRevisions
+	overLimit                 bool
+	excludedRepos             *excludedRepos
+	err                       error
+}
+
+// resolveRepositories is like the resolveRepositories func, but only resolves
+// the repositories for equal options once. Concurrent calls with equal options
+// wait for the first one.
+func (c *repoResolutionCache) resolveRepositories(ctx context.Context, op resolveRepoOp) (repoRevs, missingRepoRevs []*search.RepositoryRevisions, overLimit bool, excludedRepos *excludedRepos, err error) {
+	key := op.cacheKey()
+	c.mu.Lock()
+	e, ok := c.m[key]
+	if !ok {
+		e = &repoResolution{}
+		c.m[key] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.done {
+		e.repoRevs, e.missingRepoRevs, e.overLimit, e.excludedRepos, e.err = resolveRepositories(ctx, op)
+		// Errors caused by this search's own timeout or cancellation must not
+		// fail the other searches.
+		e.done = ctx.Err() == nil
+	}
+	// Copy the slices, since searches may modify them.
+	repoRevs = append([]*search.RepositoryRevisions(nil), e.repoRevs...)
+	missingRepoRevs = append([]*search.RepositoryRevisions(nil), e.missingRepoRevs...)
+	return repoRevs, missingRepoRevs, e.overLimit, e.excludedRepos, e.err
+}
+
+// cacheKey returns a key that is equal for options that resolve the same
+// repositories.
+func (op resolveRepoOp) cacheKey() string {
+	// The fork and archived fields of the query determine which excluded
+	// repositories are counted (see computeExcludedRepositories).
+	var fork, archived string
+	if op.query != nil {
+		fork, _ = op.query.StringValue(query.FieldFork)
+		archived, _ = op.query.StringValue(query.FieldArchived)
+	}
+	var tenant string
+	if op.tenant != nil {
+		tenant = op.tenant.Name
+	}
+	return fmt.Sprintf("%q %q %q %q %v %v %v %v %q %v %v %v %q %q %q",
+		op.repoFilters, op.minusRepoFilters, op.repoGroupFilters, op.versionContextName,
+		op.noForks, op.onlyForks, op.noArchived, op.onlyArchived, op.commitAfter,
+		op.onlyPrivate, op.onlyPublic, op.submodules, fork, archived, tenant)
+}
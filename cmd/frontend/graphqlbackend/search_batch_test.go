@@ -0,0 +1,67 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchBatch(t *testing.T) {
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		mu.Lock()
+		calls[strings.Join(op.IncludePatterns, ",")]++
+		mu.Unlock()
+		return []*types.Repo{{ID: 1, Name: "repo"}}, nil
+	}
+	db.Mocks.Repos.Count = mockCount
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	var queries []*batchSearchQuery
+	for _, q := range []string{"repo:a foo", "repo:a bar", "repo:b foo"} {
+		queries = append(queries, &batchSearchQuery{Version: "V2", Query: q})
+	}
+	searches, err := (&schemaResolver{}).SearchBatch(&struct{ Queries []*batchSearchQuery }{queries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != len(queries) {
+		t.Fatalf("got %d searches, want %d", len(searches), len(queries))
+	}
+
+	var wg sync.WaitGroup
+	for _, s := range searches {
+		wg.Add(1)
+		go func(sr *searchResolver) {
+			defer wg.Done()
+			repoRevs, _, _, _, err := sr.resolveRepositories(context.Background(), nil)
+			if err != nil {
+				t.Error(err)
+			} else if len(repoRevs) != 1 {
+				t.Errorf("%s: got %d repositories, want 1", sr.rawQuery(), len(repoRevs))
+			}
+		}(s.(*searchResolver))
+	}
+	wg.Wait()
+
+	// The two queries for repo:a share the resolved repositories.
+	if want := map[string]int{"a": 1, "b": 1}; len(calls) != len(want) || calls["a"] != want["a"] || calls["b"] != want["b"] {
+		t.Errorf("got Repos.List calls %v, want %v", calls, want)
+	}
+
+	tooMany := make([]*batchSearchQuery, maxBatchSearchQueries+1)
+	if _, err := (&schemaResolver{}).SearchBatch(&struct{ Queries []*batchSearchQuery }{tooMany}); err == nil {
+		t.Error("got no error for too many queries")
+	}
+}
@@ -0,0 +1,47 @@
+package graphqlbackend
+
+import "context"
+
+// searchCancellationReason is the reason a search stopped before all
+// repositories were searched (SearchCancellationReason GraphQL enum values).
+type searchCancellationReason string
+
+const (
+	// searchCanceledClientDisconnected means the request was canceled, usually
+	// because the client went away.
+	searchCanceledClientDisconnected searchCancellationReason = "CLIENT_DISCONNECTED"
+
+	// searchCanceledDeadlineExceeded means the search timed out.
+	searchCanceledDeadlineExceeded searchCancellationReason = "DEADLINE_EXCEEDED"
+
+	// searchCanceledLimitHit means enough results were found.
+	searchCanceledLimitHit searchCancellationReason = "LIMIT_HIT"
+
+	// searchCanceledError means an error made searching the remaining
+	// repositories pointless.
+	searchCanceledError searchCancellationReason = "ERROR"
+
+	// searchCanceledMemoryBudgetExceeded means the results of all in-flight
+	// searches used too much memory (see searchResultsMemory).
+	searchCanceledMemoryBudgetExceeded searchCancellationReason = "MEMORY_BUDGET_EXCEEDED"
+)
+
+// cancellationReasonOf returns the reason a search run with ctx (the context
+// it was started with, before any cancellation of its own) stopped early, or
+// "" if it was not canceled. The reasons of the caller take precedence over the
+// search's own ones, since they are what the user experienced.
+func cancellationReasonOf(ctx context.Context, limitHit, errored bool) searchCancellationReason {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return searchCanceledDeadlineExceeded
+	case context.Canceled:
+		return searchCanceledClientDisconnected
+	}
+	switch {
+	case limitHit:
+		return searchCanceledLimitHit
+	case errored:
+		return searchCanceledError
+	}
+	return ""
+}
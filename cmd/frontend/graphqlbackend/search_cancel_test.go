@@ -0,0 +1,35 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCancellationReasonOf(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		limitHit bool
+		errored  bool
+		want     searchCancellationReason
+	}{
+		{name: "not canceled", ctx: context.Background()},
+		{name: "client disconnected", ctx: canceled, limitHit: true, want: searchCanceledClientDisconnected},
+		{name: "deadline", ctx: expired, errored: true, want: searchCanceledDeadlineExceeded},
+		{name: "limit hit", ctx: context.Background(), limitHit: true, errored: true, want: searchCanceledLimitHit},
+		{name: "error", ctx: context.Background(), errored: true, want: searchCanceledError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := cancellationReasonOf(test.ctx, test.limitHit, test.errored); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package graphqlbackend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+)
+
+var searcherLatencyTarget = env.Get("SEARCHER_LATENCY_TARGET", "2s", "searcher response latency above which the number of concurrent searcher requests is reduced, and below which it is increased (0 means a fixed number of concurrent requests per searcher, see search.limits.searcherConcurrency in the site configuration)")
+
+// The bounds of the concurrency window, per searcher instance, for the default
+// search.limits.searcherConcurrency. They are scaled proportionally to the
+// configured concurrency.
+const (
+	searcherConcurrencyInitial = 32
+	searcherConcurrencyMin     = 4
+	searcherConcurrencyMax     = 128
+)
+
+// searcherConcurrencyPerSearcher returns the number of concurrent requests per
+// searcher instance, from the search.limits site configuration.
+func searcherConcurrencyPerSearcher() int {
+	if limits := conf.Get().SearchLimits; limits != nil && limits.SearcherConcurrency > 0 {
+		return limits.SearcherConcurrency
+	}
+	return searcherConcurrencyInitial
+}
+
+var (
+	searcherConcurrencyWindow = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_graphql_searcher_concurrency_window",
+		Help: "Current limit on the number of concurrent searcher requests.",
+	})
+	searcherConcurrencyBackoffs = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_graphql_searcher_concurrency_backoffs_total",
+		Help: "Number of times the limit on concurrent searcher requests was reduced because searcher latency degraded.",
+	})
+)
+
+// searcherConcurrency controls the limit of a limiter on concurrent searcher
+// requests with AIMD (additive increase, multiplicative decrease): while
+// searchers respond within the latency target the limit grows by about one
+// request per searcher per window of responses, and when they respond slower
+// or fail with a timeout or temporary error the limit is halved.
+type searcherConcurrency struct {
+	limiter *mutablelimiter.Limiter
+	target  time.Duration // 0 if the limit is fixed
+	now     func() time.Time
+
+	mu          sync.Mutex
+	searchers   int
+	perSearcher int
+	window      float64
+	limit       int
+	lastBackoff time.Time
+}
+
+var (
+	searcherConcurrencyOnce sync.Once
+	searcherConcurrencyCtl  *searcherConcurrency
+)
+
+// getSearcherConcurrency returns the controller of textSearchLimiter
+// configured by SEARCHER_LATENCY_TARGET.
+func getSearcherConcurrency() *searcherConcurrency {
+	searcherConcurrencyOnce.Do(func() {
+		target, err := time.ParseDuration(searcherLatencyTarget)
+		if err != nil || target < 0 {
+			log15.Error("Invalid SEARCHER_LATENCY_TARGET, using a fixed searcher concurrency", "value", searcherLatencyTarget, "error", err)
+			target = 0
+		}
+		searcherConcurrencyCtl = newSearcherConcurrency(textSearchLimiter, target)
+	})
+	return searcherConcurrencyCtl
+}
+
+func newSearcherConcurrency(limiter *mutablelimiter.Limiter, target time.Duration) *searcherConcurrency {
+	return &searcherConcurrency{limiter: limiter, target: target, now: time.Now, perSearcher: searcherConcurrencyInitial}
+}
+
+// setPerSearcher sets the initial number of concurrent requests per searcher
+// instance, which scales the bounds of the window. The window is reset to the
+// new initial number.
+func (c *searcherConcurrency) setPerSearcher(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n == c.perSearcher {
+		return
+	}
+	c.perSearcher = n
+	if c.searchers > 0 {
+		c.window = float64(c.searchers * n)
+		c.apply()
+	}
+}
+
+// setSearchers informs c of the number of searcher instances, which scales
+// the bounds of the window. The window is scaled proportionally.
+func (c *searcherConcurrency) setSearchers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n == c.searchers {
+		return
+	}
+	if c.searchers == 0 || c.target == 0 {
+		c.window = float64(n * c.perSearcher)
+	} else {
+		c.window = c.window * float64(n) / float64(c.searchers)
+	}
+	c.searchers = n
+	c.apply()
+}
+
+// observe adjusts the window after a searcher responded to a request in
+// latency. cached is whether searcher already had the archive of the
+// repository, since fetching the archive from gitserver is not searcher
+// latency. It must not be called for requests canceled by the caller.
+func (c *searcherConcurrency) observe(latency time.Duration, cached bool, err error) {
+	if c.target == 0 {
+		return
+	}
+	var degraded bool
+	switch {
+	case err != nil:
+		if !errcode.IsTimeout(err) && !errcode.IsTemporary(err) {
+			// The request itself is bad, which says nothing about load.
+			return
+		}
+		degraded = true
+	case latency > c.target:
+		if !cached {
+			return
+		}
+		degraded = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.searchers == 0 {
+		return
+	}
+	if degraded {
+		// The requests still in flight were sent with the old window, so
+		// back off at most once per target latency.
+		now := c.now()
+		if now.Sub(c.lastBackoff) < c.target {
+			return
+		}
+		c.lastBackoff = now
+		c.window /= 2
+		searcherConcurrencyBackoffs.Inc()
+	} else {
+		c.window += float64(c.searchers) / c.window
+	}
+	c.apply()
+}
+
+// apply clamps the window and updates the limit of the limiter. Lowering the
+// limit does not cancel requests in flight. c.mu must be held.
+func (c *searcherConcurrency) apply() {
+	min := c.searchers * c.perSearcher * searcherConcurrencyMin / searcherConcurrencyInitial
+	if min < c.searchers {
+		min = c.searchers
+	}
+	if c.window < float64(min) {
+		c.window = float64(min)
+	}
+	if max := float64(c.searchers * c.perSearcher * searcherConcurrencyMax / searcherConcurrencyInitial); c.window > max {
+		c.window = max
+	}
+	if limit := int(c.window); limit != c.limit {
+		c.limit = limit
+		c.limiter.SetLimitWithoutCancel(limit)
+		searcherConcurrencyWindow.Set(float64(limit))
+	}
+}
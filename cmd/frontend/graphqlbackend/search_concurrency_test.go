@@ -0,0 +1,76 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+)
+
+func TestSearcherConcurrency(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := mutablelimiter.New(1)
+	c := newSearcherConcurrency(l, time.Second)
+	c.now = func() time.Time { return now }
+
+	wantLimit := func(want int) {
+		t.Helper()
+		if cap, _ := l.GetLimit(); cap != want {
+			t.Errorf("got limit %d, want %d", cap, want)
+		}
+	}
+
+	c.setSearchers(2)
+	wantLimit(2 * searcherConcurrencyInitial)
+
+	// Healthy responses grow the window by about one per searcher per window
+	// of responses.
+	for i := 0; i < 2*searcherConcurrencyInitial; i++ {
+		c.observe(100*time.Millisecond, true, nil)
+	}
+	wantLimit(2*searcherConcurrencyInitial + 1)
+
+	// Slow responses halve the window, at most once per target latency.
+	c.observe(2*time.Second, true, nil)
+	wantLimit((2*searcherConcurrencyInitial + 1) / 2)
+	c.observe(2*time.Second, true, nil)
+	wantLimit((2*searcherConcurrencyInitial + 1) / 2)
+
+	// Slow responses that fetched the archive and bad requests are ignored.
+	now = now.Add(time.Second)
+	c.observe(2*time.Second, false, nil)
+	c.observe(0, true, errors.New("bad request"))
+	wantLimit((2*searcherConcurrencyInitial + 1) / 2)
+
+	// Timeouts back off down to the minimum.
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		c.observe(0, true, context.DeadlineExceeded)
+	}
+	wantLimit(2 * searcherConcurrencyMin)
+
+	// More searchers scale the window.
+	c.setSearchers(4)
+	wantLimit(4 * searcherConcurrencyMin)
+
+	// A change of the configured concurrency per searcher resets the window
+	// and scales its bounds.
+	c.setPerSearcher(8)
+	wantLimit(4 * 8)
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Second)
+		c.observe(0, true, context.DeadlineExceeded)
+	}
+	wantLimit(4 * 1)
+
+	// A fixed window only depends on the number of searchers and the
+	// configured concurrency per searcher.
+	fixed := newSearcherConcurrency(l, 0)
+	fixed.setSearchers(3)
+	fixed.observe(time.Minute, true, nil)
+	wantLimit(3 * searcherConcurrencyInitial)
+	fixed.setPerSearcher(10)
+	wantLimit(3 * 10)
+}
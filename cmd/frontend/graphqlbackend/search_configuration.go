@@ -0,0 +1,108 @@
+package graphqlbackend
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func (r *siteResolver) SearchConfiguration(ctx context.Context) (*searchConfigurationResolver, error) {
+	// 🚨 SECURITY: Only site admins may view the search configuration, which
+	// includes the URLs of internal services.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return &searchConfigurationResolver{}, nil
+}
+
+// searchConfigurationResolver resolves the search configuration that is in
+// effect, from the same funcs that searches use.
+type searchConfigurationResolver struct{}
+
+func (searchConfigurationResolver) SearcherURL() string {
+	spec, _ := search.SearcherURLSpecFromConfig()
+	return spec
+}
+
+func (searchConfigurationResolver) SearcherURLFromSiteConfig() bool {
+	_, fromSiteConfig := search.SearcherURLSpecFromConfig()
+	return fromSiteConfig
+}
+
+func (searchConfigurationResolver) SearcherEndpoints() []string {
+	eps, _ := search.SearcherURLs().Endpoints()
+	urls := make([]string, 0, len(eps))
+	for u := range eps {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func (searchConfigurationResolver) SearcherEndpointsError() *string {
+	if _, err := search.SearcherURLs().Endpoints(); err != nil {
+		msg := err.Error()
+		return &msg
+	}
+	return nil
+}
+
+func (searchConfigurationResolver) IndexedSearchEnabled() bool {
+	return conf.SearchIndexEnabled()
+}
+
+func (searchConfigurationResolver) DefaultTimeoutMilliseconds() int32 {
+	return durationMilliseconds(searchDefaultTimeout())
+}
+
+func (searchConfigurationResolver) MaxTimeoutMilliseconds() int32 {
+	return durationMilliseconds(searchMaxTimeout())
+}
+
+func (searchConfigurationResolver) RepositoryTimeoutMilliseconds() *int32 {
+	d := searchRepoTimeout()
+	if d <= 0 {
+		return nil
+	}
+	ms := durationMilliseconds(d)
+	return &ms
+}
+
+func (searchConfigurationResolver) SearcherConcurrency() int32 {
+	return int32(searcherConcurrencyPerSearcher())
+}
+
+func (searchConfigurationResolver) MaxSearcherRequestsInFlight() *int32 {
+	max := getTextSearchAdmission().maxInFlight()
+	if max <= 0 {
+		return nil
+	}
+	n := int32(max)
+	return &n
+}
+
+func (searchConfigurationResolver) MaxResultsPerRequest() int32 {
+	return maxSearchResultsPerPaginatedRequest()
+}
+
+func (searchConfigurationResolver) MaxRepositories() *int32 {
+	if conf.Get().MaxReposToSearch <= 0 {
+		return nil
+	}
+	n := int32(maxReposToSearch())
+	return &n
+}
+
+// durationMilliseconds returns d in milliseconds, capped to fit in a GraphQL
+// Int.
+func durationMilliseconds(d time.Duration) int32 {
+	if ms := d.Milliseconds(); ms < math.MaxInt32 {
+		return int32(ms)
+	}
+	return math.MaxInt32
+}
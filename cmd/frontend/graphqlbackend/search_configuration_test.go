@@ -0,0 +1,114 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchResolver_withTimeout(t *testing.T) {
+	defer conf.Mock(nil)
+
+	timeout := func(q string) time.Duration {
+		t.Helper()
+		parsed, err := query.ParseAndCheck(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel, err := (&searchResolver{query: parsed}).withTimeout(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+		return time.Until(deadline).Round(time.Second)
+	}
+
+	conf.Mock(&conf.Unified{})
+	for q, want := range map[string]time.Duration{
+		"a":              20 * time.Second,
+		"a timeout:5s":   5 * time.Second,
+		"a timeout:5m":   time.Minute,
+		"a count:100":    time.Minute,
+		"a timeout:1m1s": time.Minute,
+	} {
+		if got := timeout(q); got != want {
+			t.Errorf("%q: got timeout %s, want %s", q, got, want)
+		}
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchLimits: &schema.SearchLimits{DefaultTimeout: "10s", MaxTimeout: "2m"},
+	}})
+	for q, want := range map[string]time.Duration{
+		"a":            10 * time.Second,
+		"a timeout:5m": 2 * time.Minute,
+		"a count:100":  2 * time.Minute,
+	} {
+		if got := timeout(q); got != want {
+			t.Errorf("%q with search.limits: got timeout %s, want %s", q, got, want)
+		}
+	}
+}
+
+func TestSearchRepoTimeout(t *testing.T) {
+	defer conf.Mock(nil)
+	defer func(d time.Duration) { repoSearchTimeout = d }(repoSearchTimeout)
+	repoSearchTimeout = 3 * time.Second
+
+	conf.Mock(&conf.Unified{})
+	if got, want := searchRepoTimeout(), 3*time.Second; got != want {
+		t.Errorf("got %s, want %s from SEARCH_REPO_TIMEOUT", got, want)
+	}
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchLimits: &schema.SearchLimits{RepositoryTimeout: "0s"},
+	}})
+	if got := searchRepoTimeout(); got != 0 {
+		t.Errorf("got %s, want no limit from search.limits", got)
+	}
+}
+
+func TestSiteResolver_SearchConfiguration(t *testing.T) {
+	defer resetMocks()
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		MaxReposToSearch: 100,
+		SearchLimits:     &schema.SearchLimits{SearcherConcurrency: 8, RepositoryTimeout: "1500ms"},
+	}})
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1}, nil
+	}
+	if _, err := (&siteResolver{}).SearchConfiguration(context.Background()); err == nil {
+		t.Error("got no error for a non-admin, want an error")
+	}
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	r, err := (&siteResolver{}).SearchConfiguration(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.DefaultTimeoutMilliseconds(), int32(20000); got != want {
+		t.Errorf("got default timeout %d, want %d", got, want)
+	}
+	if got := r.RepositoryTimeoutMilliseconds(); got == nil || *got != 1500 {
+		t.Errorf("got repository timeout %v, want 1500", got)
+	}
+	if got, want := r.SearcherConcurrency(), int32(8); got != want {
+		t.Errorf("got searcher concurrency %d, want %d", got, want)
+	}
+	if got := r.MaxRepositories(); got == nil || *got != 100 {
+		t.Errorf("got max repositories %v, want 100", got)
+	}
+	if got, want := r.MaxResultsPerRequest(), int32(5000); got != want {
+		t.Errorf("got max results per request %d, want %d", got, want)
+	}
+}
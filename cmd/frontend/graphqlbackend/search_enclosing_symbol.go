@@ -0,0 +1,121 @@
+package graphqlbackend
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	lsp "github.com/sourcegraph/go-lsp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gituri"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+// maxFileSymbols is the maximum number of symbols of a file that are
+// considered when finding the enclosing symbols of line matches.
+const maxFileSymbols = 10000
+
+// fileSymbolsCache caches the symbols of recently searched files. The
+// symbols of a file at a commit never change.
+var (
+	fileSymbolsCacheMu sync.Mutex
+	fileSymbolsCache   = lru.New(500)
+)
+
+type fileSymbolsKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+	path   string
+}
+
+var mockListFileSymbols func(ctx context.Context, key fileSymbolsKey) ([]protocol.Symbol, error)
+
+// EnclosingSymbol returns the function, method or type definition that
+// contains the line of lm, i.e. the closest such definition that starts
+// before the line. Since ctags doesn't report where definitions end, a line
+// after the end of a definition is attributed to it too.
+func (lm *lineMatch) EnclosingSymbol(ctx context.Context) (*symbolResolver, error) {
+	fm := lm.file
+	if fm == nil || fm.Repo == nil || fm.CommitID == "" {
+		return nil, nil
+	}
+	symbols, err := listFileSymbols(ctx, fileSymbolsKey{repo: fm.Repo.Name, commit: fm.CommitID, path: fm.JPath})
+	if err != nil {
+		return nil, err
+	}
+	symbol, ok := enclosingSymbol(symbols, int(lm.JLineNumber))
+	if !ok {
+		return nil, nil
+	}
+
+	var inputRev string
+	if fm.InputRev != nil {
+		inputRev = *fm.InputRev
+	}
+	commit := &GitCommitResolver{
+		repo:     &RepositoryResolver{repo: fm.Repo},
+		oid:      GitObjectID(fm.CommitID),
+		inputRev: fm.InputRev,
+	}
+	return toSymbolResolver(symbol, gituri.New(fm.Repo.Name, inputRev, ""), strings.ToLower(symbol.Language), commit), nil
+}
+
+// enclosingSymbol returns the last symbol of a kind that contains code (see
+// isEnclosingSymbolKind) that starts before or at the line with the given
+// 0-based number.
+func enclosingSymbol(symbols []protocol.Symbol, lineNumber int) (symbol protocol.Symbol, ok bool) {
+	for _, s := range symbols {
+		// Symbol lines are 1-based.
+		if s.Line-1 > lineNumber || !isEnclosingSymbolKind(s.Kind) {
+			continue
+		}
+		if !ok || s.Line > symbol.Line {
+			symbol, ok = s, true
+		}
+	}
+	return symbol, ok
+}
+
+// isEnclosingSymbolKind reports whether symbols of the ctags kind contain
+// code that can be reported as the context of a match.
+func isEnclosingSymbolKind(kind string) bool {
+	switch ctagsKindToLSPSymbolKind(kind) {
+	case lsp.SKFunction, lsp.SKMethod, lsp.SKConstructor, lsp.SKClass, lsp.SKStruct, lsp.SKInterface, lsp.SKEnum, lsp.SKModule, lsp.SKNamespace:
+		return true
+	}
+	return false
+}
+
+// listFileSymbols returns the symbols of the file identified by key.
+func listFileSymbols(ctx context.Context, key fileSymbolsKey) ([]protocol.Symbol, error) {
+	if mockListFileSymbols != nil {
+		return mockListFileSymbols(ctx, key)
+	}
+
+	fileSymbolsCacheMu.Lock()
+	v, ok := fileSymbolsCache.Get(key)
+	fileSymbolsCacheMu.Unlock()
+	if ok {
+		return v.([]protocol.Symbol), nil
+	}
+
+	symbols, err := backend.Symbols.ListTags(ctx, search.SymbolsParameters{
+		Repo:            key.repo,
+		CommitID:        key.commit,
+		IsCaseSensitive: true,
+		IncludePatterns: []string{"^" + regexp.QuoteMeta(key.path) + "$"},
+		First:           maxFileSymbols,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fileSymbolsCacheMu.Lock()
+	fileSymbolsCache.Add(key, symbols)
+	fileSymbolsCacheMu.Unlock()
+	return symbols, nil
+}
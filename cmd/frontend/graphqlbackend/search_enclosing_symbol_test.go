@@ -0,0 +1,49 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+func TestLineMatch_EnclosingSymbol(t *testing.T) {
+	mockListFileSymbols = func(ctx context.Context, key fileSymbolsKey) ([]protocol.Symbol, error) {
+		if key.path != "a.go" || key.commit != "c1" {
+			t.Fatalf("unexpected symbols request %+v", key)
+		}
+		return []protocol.Symbol{
+			{Name: "Server", Path: "a.go", Line: 3, Kind: "struct", Language: "Go"},
+			{Name: "handleRequest", Path: "a.go", Line: 10, Kind: "method", Language: "Go", Parent: "Server"},
+			{Name: "err", Path: "a.go", Line: 12, Kind: "variable", Language: "Go"},
+		}, nil
+	}
+	defer func() { mockListFileSymbols = nil }()
+
+	fm := &FileMatchResolver{
+		JPath:    "a.go",
+		Repo:     &types.Repo{Name: "github.com/a/b"},
+		CommitID: "c1",
+		JLineMatches: []*lineMatch{
+			{JPreview: "package a", JLineNumber: 0},
+			{JPreview: "\tName string", JLineNumber: 3},
+			{JPreview: "\treturn err", JLineNumber: 12},
+		},
+	}
+	truncatePreviews(fm)
+
+	for i, want := range []string{"", "Server", "handleRequest"} {
+		s, err := fm.JLineMatches[i].EnclosingSymbol(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if s != nil {
+			got = s.Name()
+		}
+		if got != want {
+			t.Errorf("line %d: got enclosing symbol %q, want %q", fm.JLineMatches[i].JLineNumber, got, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package graphqlbackend
+
+import (
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+// searchErrorCode is a machine-readable code identifying the kind of a search
+// error. It is returned as the "code" GraphQL error extension so that clients
+// can branch on it instead of matching error messages.
+type searchErrorCode string
+
+const (
+	// searchErrorSearcherUnavailable means searcher could not be reached or
+	// was temporarily unable to serve the request.
+	searchErrorSearcherUnavailable searchErrorCode = "SearcherUnavailable"
+	// searchErrorPatternInvalid means the search pattern was rejected.
+	searchErrorPatternInvalid searchErrorCode = "PatternInvalid"
+	// searchErrorRepoNotFound means a repository to search does not exist.
+	searchErrorRepoNotFound searchErrorCode = "RepoNotFound"
+	// searchErrorTimeout means the search did not complete in time.
+	searchErrorTimeout searchErrorCode = "Timeout"
+	// searchErrorTooManyResults means the search asked for more results than
+	// are allowed.
+	searchErrorTooManyResults searchErrorCode = "TooManyResults"
+	// searchErrorSearcherOverloaded means too many searcher requests were
+	// already in flight. Clients should retry after the duration given in the
+	// "retryAfter" extension.
+	searchErrorSearcherOverloaded searchErrorCode = "SearcherOverloaded"
+	// searchErrorTenantQuotaExceeded means the tenant of the user (see the
+	// search.tenants site configuration) already has its maximum number of
+	// searches executing.
+	searchErrorTenantQuotaExceeded searchErrorCode = "TenantQuotaExceeded"
+)
+
+// searchError is an error with a searchErrorCode. It implements the
+// interface graphql-go uses to populate GraphQL error extensions.
+type searchError struct {
+	code       searchErrorCode
+	err        error
+	retryAfter time.Duration // if nonzero, reported as the "retryAfter" extension (in seconds)
+}
+
+func newSearchError(code searchErrorCode, err error) error {
+	return &searchError{code: code, err: err}
+}
+
+func (e *searchError) Error() string { return e.err.Error() }
+func (e *searchError) Cause() error  { return e.err }
+
+func (e *searchError) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": string(e.code)}
+	if e.retryAfter > 0 {
+		ext["retryAfter"] = int(e.retryAfter.Seconds())
+	}
+	return ext
+}
+
+// withSearchErrorCode returns err as a *searchError, so that its code is
+// reported to GraphQL clients. graphql-go only inspects the error returned by
+// a resolver itself, so codes attached further down the call stack (e.g. to a
+// single repository's error that was since wrapped or aggregated) are lifted to
+// the top here. Errors that do not match any code are returned unchanged.
+func withSearchErrorCode(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*searchError); ok {
+		return err
+	}
+	if e := findSearchError(err); e != nil {
+		return &searchError{code: e.code, err: err, retryAfter: e.retryAfter}
+	}
+	if code, ok := searchErrorCodeOf(err); ok {
+		return &searchError{code: code, err: err}
+	}
+	return err
+}
+
+// findSearchError returns the first *searchError found in err's chain of
+// causes (or in the errors of a *multierror.Error), or nil if there is none.
+func findSearchError(err error) *searchError {
+	type causer interface {
+		Cause() error
+	}
+	for e := err; e != nil; {
+		switch v := e.(type) {
+		case *searchError:
+			return v
+		case *multierror.Error:
+			for _, e := range v.Errors {
+				if se := findSearchError(e); se != nil {
+					return se
+				}
+			}
+			return nil
+		}
+		c, ok := e.(causer)
+		if !ok {
+			break
+		}
+		e = c.Cause()
+	}
+	return nil
+}
+
+// searchErrorCodeOf returns the code of the first *searchError found in err's
+// chain of causes (or in the errors of a *multierror.Error). If there is none,
+// timeouts, not found and bad request errors are classified using errcode.
+func searchErrorCodeOf(err error) (searchErrorCode, bool) {
+	if e := findSearchError(err); e != nil {
+		return e.code, true
+	}
+
+	switch {
+	case errcode.IsTimeout(err):
+		return searchErrorTimeout, true
+	case errcode.IsNotFound(err):
+		return searchErrorRepoNotFound, true
+	case errcode.IsBadRequest(err):
+		return searchErrorPatternInvalid, true
+	}
+	return "", false
+}
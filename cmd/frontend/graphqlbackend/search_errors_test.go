@@ -0,0 +1,53 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+)
+
+func TestWithSearchErrorCode(t *testing.T) {
+	unavailable := newSearchError(searchErrorSearcherUnavailable, errors.New("connection refused"))
+
+	tests := []struct {
+		name string
+		err  error
+		want searchErrorCode
+	}{
+		{name: "plain", err: errors.New("x")},
+		{name: "coded", err: unavailable, want: searchErrorSearcherUnavailable},
+		{name: "wrapped", err: errors.Wrap(unavailable, "failed to search"), want: searchErrorSearcherUnavailable},
+		{name: "multierror", err: multierror.Append(errors.New("x"), errors.Wrap(unavailable, "text search failed")), want: searchErrorSearcherUnavailable},
+		{name: "deadline", err: errors.Wrap(context.DeadlineExceeded, "searcher request failed"), want: searchErrorTimeout},
+		{name: "not found", err: &errcode.Mock{Message: "repo not found", IsNotFound: true}, want: searchErrorRepoNotFound},
+		{name: "bad request", err: &badRequestError{errors.New("the regexp \".*\" matches every line")}, want: searchErrorPatternInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := withSearchErrorCode(tt.err)
+			if err.Error() != tt.err.Error() {
+				t.Errorf("got message %q, want %q", err.Error(), tt.err.Error())
+			}
+			se, ok := err.(*searchError)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("got code %q, want none", se.code)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("got %T, want *searchError", err)
+			}
+			if got := se.Extensions()["code"]; got != string(tt.want) {
+				t.Errorf("got code %v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if withSearchErrorCode(nil) != nil {
+		t.Error("expected nil error to be returned unchanged")
+	}
+}
@@ -0,0 +1,51 @@
+package graphqlbackend
+
+import (
+	"context"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/searchevents"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// emitSearchEvent sends an event describing the completed search to the sinks
+// configured in "observability.searchEvents".
+func (r *searchResolver) emitSearchEvent(ctx context.Context, rr *SearchResultsResolver, err error, status, alertType string, duration time.Duration) {
+	patternType := "regexp"
+	switch r.patternType {
+	case query.SearchTypeStructural:
+		patternType = "structural"
+	case query.SearchTypeLiteral:
+		patternType = "literal"
+	}
+
+	e := searchevents.Event{
+		Timestamp:   time.Now().UTC(),
+		RequestID:   search.RequestID(ctx),
+		Source:      string(trace.RequestSource(ctx)),
+		PatternType: patternType,
+		Status:      status,
+		AlertType:   alertType,
+		DurationMs:  duration.Milliseconds(),
+	}
+	if rr != nil {
+		e.Results = int(rr.MatchCount())
+		e.LimitHit = rr.searchResultsCommon.limitHit
+		e.Repos = len(rr.searchResultsCommon.repos)
+		e.Searched = len(rr.searchResultsCommon.searched)
+		e.Indexed = len(rr.searchResultsCommon.indexed)
+		e.Cloning = len(rr.searchResultsCommon.cloning)
+		e.Missing = len(rr.searchResultsCommon.missing)
+		e.Timedout = len(rr.searchResultsCommon.timedout)
+	}
+	if err != nil {
+		// The error message is not sent, since it can contain the query.
+		e.ErrorCode = "Internal"
+		if code, ok := searchErrorCodeOf(err); ok {
+			e.ErrorCode = string(code)
+		}
+	}
+	searchevents.Emit(e)
+}
@@ -0,0 +1,113 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+func (c *searchResultsCommon) ExcludedRepositories() []*searchExcludedRepositoriesResolver {
+	resolvers := []*searchExcludedRepositoriesResolver{}
+	for _, e := range []struct {
+		reason string
+		count  int
+	}{
+		{"FORK", c.excluded.forks},
+		{"ARCHIVED", c.excluded.archived},
+		{"EXCLUDED_BY_ADMIN", c.excluded.byAdmin},
+	} {
+		if e.count > 0 {
+			resolvers = append(resolvers, &searchExcludedRepositoriesResolver{reason: e.reason, count: int32(e.count)})
+		}
+	}
+	return resolvers
+}
+
+// searchExcludedRepositoriesResolver resolves the number of repositories that
+// were not searched for a reason.
+type searchExcludedRepositoriesResolver struct {
+	reason string
+	count  int32
+}
+
+func (r *searchExcludedRepositoriesResolver) Reason() string { return r.reason }
+
+func (r *searchExcludedRepositoriesResolver) Count() int32 { return r.count }
+
+func (r *siteResolver) SearchExcludedRepositories(ctx context.Context) ([]*searchExcludedRepositoryResolver, error) {
+	// 🚨 SECURITY: Only site admins may view the repositories excluded from
+	// search.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	excluded, err := db.SearchExcludedRepos.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*searchExcludedRepositoryResolver, len(excluded))
+	for i, e := range excluded {
+		resolvers[i] = &searchExcludedRepositoryResolver{e: e}
+	}
+	return resolvers, nil
+}
+
+func (r *schemaResolver) ExcludeRepositoryFromSearch(ctx context.Context, args *struct {
+	Repository graphql.ID
+	Reason     *string
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may exclude repositories from search.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	repoID, err := UnmarshalRepositoryID(args.Repository)
+	if err != nil {
+		return nil, err
+	}
+	var reason string
+	if args.Reason != nil {
+		reason = *args.Reason
+	}
+	if err := db.SearchExcludedRepos.Add(ctx, repoID, reason); err != nil {
+		return nil, err
+	}
+	log15.Info("Excluded repository from search", "repo", repoID, "reason", reason, "actor", actor.FromContext(ctx))
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) IncludeRepositoryInSearch(ctx context.Context, args *struct {
+	Repository graphql.ID
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may exclude repositories from search.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	repoID, err := UnmarshalRepositoryID(args.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SearchExcludedRepos.Remove(ctx, repoID); err != nil {
+		return nil, err
+	}
+	log15.Info("Included repository in search", "repo", repoID, "actor", actor.FromContext(ctx))
+	return &EmptyResponse{}, nil
+}
+
+type searchExcludedRepositoryResolver struct {
+	e *types.SearchExcludedRepo
+}
+
+func (r *searchExcludedRepositoryResolver) Repository(ctx context.Context) (*RepositoryResolver, error) {
+	return RepositoryByIDInt32(ctx, r.e.RepoID)
+}
+
+func (r *searchExcludedRepositoryResolver) Reason() string { return r.e.Reason }
+
+func (r *searchExcludedRepositoryResolver) CreatedAt() DateTime {
+	return DateTime{Time: r.e.CreatedAt}
+}
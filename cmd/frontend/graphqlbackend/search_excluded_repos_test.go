@@ -0,0 +1,77 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestWithoutSearchExcludedRepos(t *testing.T) {
+	defer resetMocks()
+	db.Mocks.SearchExcludedRepos.List = func(context.Context) ([]*types.SearchExcludedRepo, error) {
+		return []*types.SearchExcludedRepo{{RepoID: 2, Reason: "too large"}}, nil
+	}
+
+	repos := []*types.Repo{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	got, excluded, err := withoutSearchExcludedRepos(context.Background(), repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*types.Repo{repos[0], repos[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v, want %v", got, want)
+	}
+	if excluded.byAdmin != 1 {
+		t.Errorf("got %d repos excluded by admin, want 1", excluded.byAdmin)
+	}
+	if len(repos) != 3 || repos[1].ID != 2 {
+		t.Errorf("the repos passed in were modified: %v", repos)
+	}
+}
+
+func TestSearchResultsCommon_ExcludedRepositories(t *testing.T) {
+	c := &searchResultsCommon{excluded: excludedRepos{forks: 2, byAdmin: 1}}
+	var got []searchExcludedRepositoriesResolver
+	for _, r := range c.ExcludedRepositories() {
+		got = append(got, *r)
+	}
+	want := []searchExcludedRepositoriesResolver{{reason: "FORK", count: 2}, {reason: "EXCLUDED_BY_ADMIN", count: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestExcludeRepositoryFromSearch(t *testing.T) {
+	defer resetMocks()
+	var added api.RepoID
+	db.Mocks.SearchExcludedRepos.Add = func(ctx context.Context, repoID api.RepoID, reason string) error {
+		added = repoID
+		return nil
+	}
+	args := &struct {
+		Repository graphql.ID
+		Reason     *string
+	}{Repository: MarshalRepositoryID(7)}
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1}, nil
+	}
+	if _, err := (&schemaResolver{}).ExcludeRepositoryFromSearch(context.Background(), args); err == nil {
+		t.Error("got no error for a non-admin, want an error")
+	}
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	if _, err := (&schemaResolver{}).ExcludeRepositoryFromSearch(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	if added != 7 {
+		t.Errorf("got repository %d excluded, want 7", added)
+	}
+}
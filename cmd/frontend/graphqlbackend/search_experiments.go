@@ -0,0 +1,59 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// experimentArm reports whether the user is assigned to the enabled arm of the
+// experiment on f (see the search.experiments site configuration), and
+// whether the user is part of an experiment on f. Anonymous users are not.
+//
+// The assignment is a hash of the flag name and the user ID, so a user stays
+// in the same arm for as long as the percentage of the experiment is
+// unchanged, and users are assigned to the arms of different experiments
+// independently.
+func (f *searchFeatureFlag) experimentArm(userID int32) (enabled, ok bool) {
+	percentage, ok := conf.Get().SearchExperiments[f.name]
+	if !ok || userID == 0 {
+		return false, false
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", f.name, userID)
+	return int(h.Sum32()%100) < percentage, true
+}
+
+// searchExperimentArms returns the arms ("enabled" or "disabled") of the
+// search experiments that the current user is part of, by flag name, to tag
+// the analytics events of the user with. Users with overrides of a flag are
+// not part of its experiment.
+func searchExperimentArms(ctx context.Context) map[string]string {
+	if len(conf.Get().SearchExperiments) == 0 {
+		return nil
+	}
+	ctx = withSearchFeatureFlags(ctx)
+	userID := actor.FromContext(ctx).UID
+
+	var arms map[string]string
+	for _, f := range searchFeatureFlags {
+		if _, ok := f.override(ctx); ok {
+			continue
+		}
+		enabled, ok := f.experimentArm(userID)
+		if !ok {
+			continue
+		}
+		if arms == nil {
+			arms = map[string]string{}
+		}
+		arms[f.name] = "disabled"
+		if enabled {
+			arms[f.name] = "enabled"
+		}
+	}
+	return arms
+}
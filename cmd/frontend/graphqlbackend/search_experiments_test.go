@@ -0,0 +1,89 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchFeatureFlag_experimentArm(t *testing.T) {
+	defer conf.Mock(nil)
+	mockExperiments := func(experiments map[string]int) {
+		conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchExperiments: experiments}})
+	}
+
+	mockExperiments(nil)
+	if _, ok := searchFeatureStreaming.experimentArm(1); ok {
+		t.Error("user is part of an experiment, but there is none")
+	}
+
+	mockExperiments(map[string]int{"streaming": 50})
+	if _, ok := searchFeatureStreaming.experimentArm(0); ok {
+		t.Error("anonymous user is part of an experiment")
+	}
+	enabled := 0
+	for userID := int32(1); userID <= 1000; userID++ {
+		arm, ok := searchFeatureStreaming.experimentArm(userID)
+		if !ok {
+			t.Fatalf("user %d is not part of the experiment", userID)
+		}
+		if again, _ := searchFeatureStreaming.experimentArm(userID); again != arm {
+			t.Fatalf("user %d was assigned to different arms", userID)
+		}
+		if arm {
+			enabled++
+		}
+	}
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("%d of 1000 users are in the enabled arm of a 50%% experiment", enabled)
+	}
+
+	for _, percentage := range []int{0, 100} {
+		mockExperiments(map[string]int{"streaming": percentage})
+		for userID := int32(1); userID <= 100; userID++ {
+			if arm, _ := searchFeatureStreaming.experimentArm(userID); arm != (percentage == 100) {
+				t.Fatalf("user %d in arm enabled=%v of a %d%% experiment", userID, arm, percentage)
+			}
+		}
+	}
+}
+
+func TestSearchExperimentArms(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchExperiments: map[string]int{"streaming": 100, "indexedSearch": 0},
+	}})
+	db.Mocks.SearchFeatureFlagOverrides.ListForUser = func(ctx context.Context, userID int32) ([]*types.SearchFeatureFlagOverride, error) {
+		if userID == 2 {
+			return []*types.SearchFeatureFlagOverride{{Flag: "streaming", OrgID: 1, Enabled: false}}, nil
+		}
+		return nil, nil
+	}
+	defer resetMocks()
+
+	arms := func(userID int32) map[string]string {
+		return searchExperimentArms(actor.WithActor(context.Background(), &actor.Actor{UID: userID}))
+	}
+	if got, want := arms(1), map[string]string{"streaming": "enabled", "indexedSearch": "disabled"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got arms %v, want %v", got, want)
+	}
+	// The override of the org takes the user out of the experiment.
+	if got, want := arms(2), map[string]string{"indexedSearch": "disabled"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got arms %v for user with override, want %v", got, want)
+	}
+	if got := arms(0); got != nil {
+		t.Errorf("got arms %v for anonymous user, want none", got)
+	}
+
+	// The search path uses the arm.
+	ctx := withSearchFeatureFlags(actor.WithActor(context.Background(), &actor.Actor{UID: 1}))
+	if searchFeatureIndexedSearch.enabled(ctx) {
+		t.Error("indexedSearch enabled for user in the disabled arm")
+	}
+}
@@ -0,0 +1,460 @@
+package graphqlbackend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/search/rules"
+)
+
+var (
+	searchExportTTL            = parseSearchExportTTL(env.Get("SEARCH_EXPORT_TTL", "1h", "how long completed search result exports can be downloaded before they are deleted"))
+	maxConcurrentSearchExports = 2
+
+	// maxPendingSearchExports is the number of exports a user can have in
+	// progress, including the ones waiting for one of the
+	// maxConcurrentSearchExports slots of a frontend.
+	maxPendingSearchExports = 3
+
+	// searchExportTimeout bounds the time an export runs. Processing exports
+	// that are older were abandoned (e.g. because their frontend restarted).
+	searchExportTimeout = time.Hour
+
+	// maxSearchExportSize is the size of the largest export file that is
+	// stored.
+	maxSearchExportSize = 100 << 20
+)
+
+func parseSearchExportTTL(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log15.Error("Invalid SEARCH_EXPORT_TTL, using 1h", "value", s, "error", err)
+		return time.Hour
+	}
+	return d
+}
+
+// searchExportState is the state of a search export (SearchExportState GraphQL
+// enum values).
+type searchExportState string
+
+const (
+	searchExportProcessing searchExportState = "PROCESSING"
+	searchExportCompleted  searchExportState = "COMPLETED"
+	searchExportErrored    searchExportState = "ERRORED"
+)
+
+// searchExportSem limits the number of exports that run concurrently on this
+// frontend.
+var searchExportSem = make(chan struct{}, maxConcurrentSearchExports)
+
+type exportSearchArgs struct {
+	Version        string
+	PatternType    *string
+	Query          string
+	VersionContext *string
+	Format         string
+}
+
+// ExportSearch starts an export of all file matches of a search. The returned
+// export is processing; clients poll it with the searchExport query until it
+// has a download URL.
+//
+// Exports are run by the frontend that created them, and stored in the
+// database so that all frontends can report their state and serve them.
+func (r *schemaResolver) ExportSearch(ctx context.Context, args *exportSearchArgs) (*searchExportResolver, error) {
+	// 🚨 SECURITY: Only signed-in users can export search results. The search
+	// runs as the current user, so it only matches repositories they can
+	// access.
+	a := actor.FromContext(ctx)
+	if !a.IsAuthenticated() {
+		return nil, backend.ErrNotAuthenticated
+	}
+
+	s, err := NewSearchImplementer(&SearchArgs{
+		Version:        args.Version,
+		PatternType:    args.PatternType,
+		Query:          args.Query,
+		VersionContext: args.VersionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := s.(*searchResolver)
+	if !ok {
+		// The query is invalid: run it to return the alert as an error.
+		results, err := s.Results(ctx)
+		if err == nil && results.alert != nil {
+			err = errors.New(results.alert.title)
+		}
+		return nil, err
+	}
+
+	staleBefore := time.Now().Add(-searchExportTimeout)
+	if err := db.SearchExports.DeleteExpired(ctx, staleBefore); err != nil {
+		log15.Warn("Failed to delete expired search exports", "error", err)
+	}
+
+	id, err := randomSearchExportBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := randomSearchExportBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	e := &types.SearchExport{
+		ID:         hex.EncodeToString(id),
+		UserID:     a.UID,
+		Format:     args.Format,
+		SigningKey: signingKey,
+	}
+	if err := db.SearchExports.Create(ctx, e, maxPendingSearchExports, staleBefore); err != nil {
+		return nil, err
+	}
+
+	// The export outlives the request, but must still run as the user.
+	go runSearchExport(actor.WithActor(context.Background(), a), e, sr)
+
+	return &searchExportResolver{e}, nil
+}
+
+func randomSearchExportBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// runSearchExport runs the search of the export and stores its file. The export
+// expires searchExportTTL after it finished.
+func runSearchExport(ctx context.Context, e *types.SearchExport, sr *searchResolver) {
+	ctx, cancel := context.WithTimeout(ctx, searchExportTimeout)
+	defer cancel()
+
+	var (
+		contents []byte
+		rows     int32
+		err      error
+	)
+	select {
+	case searchExportSem <- struct{}{}:
+		contents, rows, err = writeSearchExport(ctx, e.Format, sr)
+		<-searchExportSem
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// The export is stored even if ctx is done.
+	expiresAt := time.Now().Add(searchExportTTL)
+	if err != nil {
+		log15.Warn("Search export failed", "query", sr.rawQuery(), "error", err)
+		err = db.SearchExports.Fail(context.Background(), e.ID, err.Error(), expiresAt)
+	} else {
+		err = db.SearchExports.Complete(context.Background(), e.ID, rows, contents, expiresAt)
+	}
+	if err != nil {
+		log15.Error("Failed to store search export", "id", e.ID, "error", err)
+	}
+}
+
+// errSearchExportTooLarge is returned for exports larger than
+// maxSearchExportSize.
+var errSearchExportTooLarge = fmt.Errorf("the export is larger than %d MB, narrow down the search to export it", maxSearchExportSize>>20)
+
+// searchExportBuffer is a buffer that fails writes beyond maxSearchExportSize.
+type searchExportBuffer struct {
+	buf bytes.Buffer
+}
+
+func (b *searchExportBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > maxSearchExportSize {
+		return 0, errSearchExportTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// writeSearchExport searches exhaustively and returns a file in format with one
+// row per line match (or per file for path matches).
+func writeSearchExport(ctx context.Context, format string, sr *searchResolver) (contents []byte, rows int32, err error) {
+	spool, err := newFileMatchSpool("")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer spool.Close()
+	if _, err := sr.spillFileMatches(ctx, spool); err != nil {
+		return nil, 0, err
+	}
+
+	var buf searchExportBuffer
+	w := newSearchExportWriter(format, sr.rawQuery(), &buf)
+	err = spool.Each(func(fm *FileMatchResolver) error {
+		repo := ""
+		if fm.Repo != nil {
+			repo = string(fm.Repo.Name)
+		}
+		if len(fm.JLineMatches) == 0 {
+			rows++
+			return w.WriteRow(searchExportRow{Repository: repo, Path: fm.JPath})
+		}
+		for _, lm := range fm.JLineMatches {
+			rows++
+			if err := w.WriteRow(searchExportRow{Repository: repo, Path: fm.JPath, Line: lm.JLineNumber + 1, Preview: lm.JPreview, Offsets: lm.JOffsetAndLengths, RuleIDs: lm.JRuleIDs}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.buf.Bytes(), rows, nil
+}
+
+// searchExportRow is a row of an export. Line is 1-based, and 0 for path
+// matches.
+type searchExportRow struct {
+	Repository string `json:"repository"`
+	Path       string `json:"path"`
+	Line       int32  `json:"line"`
+	Preview    string `json:"preview"`
+	// RuleIDs are the IDs of the rules of the search.rules site setting that
+	// the line hits.
+	RuleIDs []string `json:"rules,omitempty"`
+
+	// Offsets are the character offsets and lengths of the matches in
+	// Preview. Only SARIF exports include them.
+	Offsets [][2]int32 `json:"-"`
+}
+
+type searchExportWriter interface {
+	WriteRow(searchExportRow) error
+	Close() error
+}
+
+// newSearchExportWriter returns a writer of rows in format. query is the
+// exported search query, which SARIF exports describe their results with.
+func newSearchExportWriter(format, query string, w io.Writer) searchExportWriter {
+	switch format {
+	case "JSON":
+		return &jsonSearchExportWriter{w: bufio.NewWriter(w)}
+	case "SARIF":
+		return &sarifSearchExportWriter{w: bufio.NewWriter(w), query: query, rules: rules.Current()}
+	}
+	return &csvSearchExportWriter{w: csv.NewWriter(w)}
+}
+
+type csvSearchExportWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvSearchExportWriter) writeHeader() error {
+	if c.wroteHeader {
+		return nil
+	}
+	c.wroteHeader = true
+	return c.w.Write([]string{"repository", "path", "line", "preview"})
+}
+
+func (c *csvSearchExportWriter) WriteRow(row searchExportRow) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{row.Repository, row.Path, strconv.Itoa(int(row.Line)), row.Preview})
+}
+
+func (c *csvSearchExportWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonSearchExportWriter writes a JSON array of rows, one row per line.
+type jsonSearchExportWriter struct {
+	w    *bufio.Writer
+	rows int
+}
+
+func (j *jsonSearchExportWriter) WriteRow(row searchExportRow) error {
+	sep := ",\n"
+	if j.rows == 0 {
+		sep = "[\n"
+	}
+	j.rows++
+	if _, err := j.w.WriteString(sep); err != nil {
+		return err
+	}
+	b, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonSearchExportWriter) Close() error {
+	end := "\n]\n"
+	if j.rows == 0 {
+		end = "[]\n"
+	}
+	if _, err := j.w.WriteString(end); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+func marshalSearchExportID(id string) graphql.ID {
+	return relay.MarshalID("SearchExport", id)
+}
+
+// SearchExport returns a search export of the current user by ID, or nil if
+// there is none (any more).
+func (r *schemaResolver) SearchExport(ctx context.Context, args *struct{ ID graphql.ID }) (*searchExportResolver, error) {
+	var id string
+	if err := relay.UnmarshalSpec(args.ID, &id); err != nil {
+		return nil, err
+	}
+	e, err := db.SearchExports.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || (e.ExpiresAt != nil && time.Now().After(*e.ExpiresAt)) {
+		return nil, nil
+	}
+	// 🚨 SECURITY: Only the user who started the export (and site admins) can
+	// see it, since it contains that user's search results.
+	if err := backend.CheckSiteAdminOrSameUser(ctx, e.UserID); err != nil {
+		return nil, err
+	}
+	return &searchExportResolver{e}, nil
+}
+
+type searchExportResolver struct {
+	e *types.SearchExport
+}
+
+func (r *searchExportResolver) ID() graphql.ID { return marshalSearchExportID(r.e.ID) }
+
+func (r *searchExportResolver) Format() string { return r.e.Format }
+
+func (r *searchExportResolver) State() string { return r.e.State }
+
+func (r *searchExportResolver) ResultCount() int32 { return r.e.ResultCount }
+
+func (r *searchExportResolver) Error() *string {
+	if r.e.State != string(searchExportErrored) {
+		return nil
+	}
+	return &r.e.Error
+}
+
+func (r *searchExportResolver) ExpiresAt() *DateTime {
+	if r.e.ExpiresAt == nil {
+		return nil
+	}
+	return &DateTime{Time: *r.e.ExpiresAt}
+}
+
+// URL returns the signed download URL of a completed export.
+func (r *searchExportResolver) URL() *string {
+	if r.e.State != string(searchExportCompleted) || r.e.ExpiresAt == nil {
+		return nil
+	}
+	expires := strconv.FormatInt(r.e.ExpiresAt.Unix(), 10)
+	u := globals.ExternalURL().ResolveReference(&neturl.URL{
+		Path:     "/.api/search/export/" + r.e.ID,
+		RawQuery: neturl.Values{"expires": {expires}, "signature": {hex.EncodeToString(searchExportSignature(r.e, expires))}}.Encode(),
+	}).String()
+	return &u
+}
+
+// searchExportSignature signs the download URL of e that expires at expires
+// with the signing key of e, which is stored with it so that all frontends can
+// verify the URL.
+func searchExportSignature(e *types.SearchExport, expires string) []byte {
+	mac := hmac.New(sha256.New, e.SigningKey)
+	fmt.Fprintf(mac, "%s\n%s", e.ID, expires)
+	return mac.Sum(nil)
+}
+
+// ServeSearchExport serves the file of a completed search export.
+//
+// 🚨 SECURITY: The signature of the URL is what authorizes the download, so
+// that the URL can be handed to other people and tools (like a ticket
+// attachment importer) for the lifetime of the export.
+func ServeSearchExport(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["ID"]
+	e, err := db.SearchExports.GetByID(r.Context(), id)
+	if err != nil {
+		log15.Error("Failed to get search export", "id", id, "error", err)
+		http.Error(w, "failed to get the export", http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		http.Error(w, "the export has expired", http.StatusGone)
+		return
+	}
+	expires := r.URL.Query().Get("expires")
+	signature, err := hex.DecodeString(r.URL.Query().Get("signature"))
+	if err != nil || !hmac.Equal(signature, searchExportSignature(e, expires)) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if unix, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().After(time.Unix(unix, 0)) {
+		http.Error(w, "the export has expired", http.StatusGone)
+		return
+	}
+
+	contents, err := db.SearchExports.Contents(r.Context(), id)
+	if err != nil {
+		log15.Error("Failed to get search export contents", "id", id, "error", err)
+		http.Error(w, "failed to get the export", http.StatusInternalServerError)
+		return
+	}
+	if contents == nil {
+		http.Error(w, "the export has expired", http.StatusGone)
+		return
+	}
+
+	filename, contentType := "search-results.csv", "text/csv; charset=utf-8"
+	switch e.Format {
+	case "JSON":
+		filename, contentType = "search-results.json", "application/json"
+	case "SARIF":
+		filename, contentType = "search-results.sarif", "application/sarif+json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	http.ServeContent(w, r, filename, e.CreatedAt, bytes.NewReader(contents))
+}
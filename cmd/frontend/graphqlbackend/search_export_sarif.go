@@ -0,0 +1,221 @@
+package graphqlbackend
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/rules"
+	"github.com/sourcegraph/sourcegraph/internal/version"
+)
+
+// SARIF (Static Analysis Results Interchange Format) 2.1.0 is the format that
+// code scanning dashboards (like GitHub code scanning) ingest. Only the parts
+// of it needed for search results are defined here. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+const (
+	sarifSchema  = "https://schemastore.azurewebsites.net/schemas/json/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifRuleID  = "sourcegraph-search"
+)
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]string `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion is a region of a file. Lines and columns are 1-based, and the
+// end column is exclusive.
+type sarifRegion struct {
+	StartLine   int32         `json:"startLine"`
+	StartColumn int32         `json:"startColumn,omitempty"`
+	EndColumn   int32         `json:"endColumn,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// sarifSearchExportWriter writes a SARIF log with a single run, which has one
+// result per match, or one result per rule for lines that hit rules of the
+// search.rules site setting. Results are streamed, so the log is only valid
+// JSON once the writer is closed.
+//
+// The files of results are relative to their repository, which is stored in
+// the "repository" property of results, since a search can match files in many
+// repositories.
+type sarifSearchExportWriter struct {
+	w       *bufio.Writer
+	query   string
+	rules   *rules.Ruleset
+	results int
+}
+
+func (s *sarifSearchExportWriter) writeHeader() error {
+	driverRules := []sarifRule{{
+		ID:               sarifRuleID,
+		ShortDescription: sarifMessage{Text: "Matches of the search query " + s.query},
+	}}
+	for _, r := range s.rules.Rules() {
+		driverRules = append(driverRules, sarifRule{ID: r.ID, ShortDescription: sarifMessage{Text: sarifRuleMessage(r)}})
+	}
+	tool, err := json.Marshal(sarifTool{Driver: sarifDriver{
+		Name:           "Sourcegraph",
+		Version:        version.Version(),
+		InformationURI: "https://sourcegraph.com",
+		Rules:          driverRules,
+	}})
+	if err != nil {
+		return err
+	}
+	// Columns are offsets in characters, as in search results.
+	_, err = fmt.Fprintf(s.w, `{"$schema":%q,"version":%q,"runs":[{"tool":%s,"columnKind":"unicodeCodePoints","results":[`, sarifSchema, sarifVersion, tool)
+	return err
+}
+
+func (s *sarifSearchExportWriter) WriteRow(row searchExportRow) error {
+	results := sarifRuleResults(row, s.rules)
+	if len(results) == 0 {
+		results = sarifResults(row, s.query)
+	}
+	for _, result := range results {
+		if s.results == 0 {
+			if err := s.writeHeader(); err != nil {
+				return err
+			}
+		} else if err := s.w.WriteByte(','); err != nil {
+			return err
+		}
+		s.results++
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if _, err := s.w.Write(append([]byte{'\n'}, b...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sarifSearchExportWriter) Close() error {
+	if s.results == 0 {
+		if err := s.writeHeader(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.w.WriteString("]}]}\n"); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// sarifResults returns the SARIF results for row: one per match on the line,
+// or a single one for the whole file of a path match.
+func sarifResults(row searchExportRow, query string) []sarifResult {
+	newResult := func(region *sarifRegion) sarifResult {
+		fingerprint := sha256.Sum256([]byte(row.Repository + "\x00" + row.Path + "\x00" + strings.TrimSpace(row.Preview)))
+		return sarifResult{
+			RuleID:  sarifRuleID,
+			Level:   "note",
+			Message: sarifMessage{Text: "Match of the search query " + query},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: row.Path},
+				Region:           region,
+			}}},
+			// The line number is not part of the fingerprint, so that
+			// dashboards can track a match across changes to other lines.
+			PartialFingerprints: map[string]string{"sourcegraphMatch/v1": hex.EncodeToString(fingerprint[:])},
+			Properties:          map[string]string{"repository": row.Repository},
+		}
+	}
+
+	if row.Line == 0 {
+		return []sarifResult{newResult(nil)}
+	}
+	snippet := &sarifMessage{Text: row.Preview}
+	if len(row.Offsets) == 0 {
+		return []sarifResult{newResult(&sarifRegion{StartLine: row.Line, Snippet: snippet})}
+	}
+	results := make([]sarifResult, 0, len(row.Offsets))
+	for _, ol := range row.Offsets {
+		results = append(results, newResult(&sarifRegion{
+			StartLine:   row.Line,
+			StartColumn: ol[0] + 1,
+			EndColumn:   ol[0] + ol[1] + 1,
+			Snippet:     snippet,
+		}))
+	}
+	return results
+}
+
+// sarifRuleResults returns the SARIF results for the rules that the line of
+// row hits: one per rule, with the level of its severity. Rules that are not
+// in rs are omitted.
+func sarifRuleResults(row searchExportRow, rs *rules.Ruleset) []sarifResult {
+	var results []sarifResult
+	for _, id := range row.RuleIDs {
+		r := rs.Rule(id)
+		if r == nil {
+			continue
+		}
+		fingerprint := sha256.Sum256([]byte(r.ID + "\x00" + row.Repository + "\x00" + row.Path + "\x00" + strings.TrimSpace(row.Preview)))
+		results = append(results, sarifResult{
+			RuleID:  r.ID,
+			Level:   r.Severity.SARIFLevel(),
+			Message: sarifMessage{Text: sarifRuleMessage(r)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: row.Path},
+				Region:           &sarifRegion{StartLine: row.Line, Snippet: &sarifMessage{Text: row.Preview}},
+			}}},
+			PartialFingerprints: map[string]string{"sourcegraphRule/v1": hex.EncodeToString(fingerprint[:])},
+			Properties:          map[string]string{"repository": row.Repository, "severity": r.Severity.String()},
+		})
+	}
+	return results
+}
+
+// sarifRuleMessage returns the message of the results of the rule.
+func sarifRuleMessage(r *rules.Rule) string {
+	if r.Description != "" {
+		return r.Description
+	}
+	return "Hit of the rule " + r.ID
+}
@@ -0,0 +1,106 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSarifSearchExportWriter(t *testing.T) {
+	write := func(rows ...searchExportRow) (log struct {
+		Version string
+		Runs    []struct {
+			ColumnKind string
+			Results    []sarifResult
+		}
+	}) {
+		t.Helper()
+		var buf bytes.Buffer
+		w := newSearchExportWriter("SARIF", "md5.Sum", &buf)
+		for _, row := range rows {
+			if err := w.WriteRow(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+			t.Fatalf("invalid SARIF log: %s\n%s", err, buf.String())
+		}
+		return log
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		log := write()
+		if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 0 {
+			t.Errorf("got %+v, want a single run without results", log)
+		}
+	})
+
+	t.Run("matches", func(t *testing.T) {
+		log := write(
+			searchExportRow{Repository: "r", Path: "a.go", Line: 3, Preview: "md5.Sum(md5.Sum(x))", Offsets: [][2]int32{{0, 7}, {8, 7}}},
+			searchExportRow{Repository: "r", Path: "md5.go"},
+		)
+		if got, want := log.Runs[0].ColumnKind, "unicodeCodePoints"; got != want {
+			t.Errorf("got columnKind %q, want %q", got, want)
+		}
+		results := log.Runs[0].Results
+		if len(results) != 3 {
+			t.Fatalf("got %d results, want 3", len(results))
+		}
+
+		var regions []*sarifRegion
+		for _, r := range results {
+			regions = append(regions, r.Locations[0].PhysicalLocation.Region)
+			if r.Properties["repository"] != "r" {
+				t.Errorf("got repository %q, want %q", r.Properties["repository"], "r")
+			}
+		}
+		snippet := &sarifMessage{Text: "md5.Sum(md5.Sum(x))"}
+		want := []*sarifRegion{
+			{StartLine: 3, StartColumn: 1, EndColumn: 8, Snippet: snippet},
+			{StartLine: 3, StartColumn: 9, EndColumn: 16, Snippet: snippet},
+			nil,
+		}
+		if !reflect.DeepEqual(regions, want) {
+			t.Errorf("got regions %+v, want %+v", regions, want)
+		}
+		if got := results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI; got != "md5.go" {
+			t.Errorf("got uri %q, want %q", got, "md5.go")
+		}
+		if results[0].PartialFingerprints["sourcegraphMatch/v1"] == results[2].PartialFingerprints["sourcegraphMatch/v1"] {
+			t.Error("got equal fingerprints for different matches")
+		}
+	})
+
+	t.Run("rules", func(t *testing.T) {
+		conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchRules: []*schema.SearchRule{
+			{Id: "go/weak-hash", Description: "Weak hash function", Pattern: `md5`, Severity: "high"},
+			{Id: "todo", Pattern: "TODO", Severity: "low"},
+		}}})
+		defer conf.Mock(nil)
+
+		log := write(
+			searchExportRow{Repository: "r", Path: "a.go", Line: 3, Preview: "md5.Sum(x) // TODO", Offsets: [][2]int32{{0, 7}}, RuleIDs: []string{"go/weak-hash", "todo"}},
+			searchExportRow{Repository: "r", Path: "b.go", Line: 1, Preview: "md5.Sum(x)", Offsets: [][2]int32{{0, 7}}},
+		)
+		var got [][3]string
+		for _, r := range log.Runs[0].Results {
+			got = append(got, [3]string{r.RuleID, r.Level, r.Message.Text})
+		}
+		want := [][3]string{
+			{"go/weak-hash", "error", "Weak hash function"},
+			{"todo", "note", "Hit of the rule todo"},
+			{"sourcegraph-search", "note", "Match of the search query md5.Sum"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got results %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSearchExportWriter(t *testing.T) {
+	rows := []searchExportRow{
+		{Repository: "r", Path: "a.go", Line: 3, Preview: `fmt.Println("a, b")`},
+		{Repository: "r", Path: "b.go"},
+	}
+	tests := []struct {
+		format string
+		rows   []searchExportRow
+		want   string
+	}{
+		{"CSV", rows, "repository,path,line,preview\nr,a.go,3,\"fmt.Println(\"\"a, b\"\")\"\nr,b.go,0,\n"},
+		{"CSV", nil, "repository,path,line,preview\n"},
+		{"JSON", rows, `[
+{"repository":"r","path":"a.go","line":3,"preview":"fmt.Println(\"a, b\")"},
+{"repository":"r","path":"b.go","line":0,"preview":""}
+]
+`},
+		{"JSON", nil, "[]\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		w := newSearchExportWriter(test.format, "a", &buf)
+		for _, row := range test.rows {
+			if err := w.WriteRow(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s with %d rows: got %q, want %q", test.format, len(test.rows), got, test.want)
+		}
+	}
+}
+
+func TestServeSearchExport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	e := &types.SearchExport{ID: "abc", Format: "CSV", State: "COMPLETED", SigningKey: []byte("key"), ExpiresAt: &expiresAt}
+	db.Mocks.SearchExports.GetByID = func(ctx context.Context, id string) (*types.SearchExport, error) {
+		if id != e.ID {
+			return nil, nil
+		}
+		return e, nil
+	}
+	db.Mocks.SearchExports.Contents = func(ctx context.Context, id string) ([]byte, error) {
+		return []byte("repository,path,line,preview\n"), nil
+	}
+	defer func() { db.Mocks.SearchExports = db.MockSearchExports{} }()
+
+	r := mux.NewRouter()
+	r.Path("/.api/search/export/{ID}").HandlerFunc(ServeSearchExport)
+	get := func(rawURL string) *httptest.ResponseRecorder {
+		t.Helper()
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", u.RequestURI(), nil))
+		return rec
+	}
+
+	u := *(&searchExportResolver{e}).URL()
+	rec := get(u)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got, want := rec.Body.String(), "repository,path,line,preview\n"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="search-results.csv"`; got != want {
+		t.Errorf("got Content-Disposition %q, want %q", got, want)
+	}
+
+	// Changing the expiry invalidates the signature.
+	parsed, _ := url.Parse(u)
+	q := parsed.Query()
+	q.Set("expires", "99999999999")
+	parsed.RawQuery = q.Encode()
+	if rec := get(parsed.String()); rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d for tampered URL, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// URLs signed with the key of another export are rejected.
+	other := &types.SearchExport{ID: "abc", SigningKey: []byte("other")}
+	forged := "/.api/search/export/abc?" + url.Values{"expires": {"99999999999"}, "signature": {hex.EncodeToString(searchExportSignature(other, "99999999999"))}}.Encode()
+	if rec := get(forged); rec.Code != http.StatusForbidden {
+		t.Errorf("got status %d for URL signed with another key, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// Expired URLs are rejected even if their signature is valid.
+	expires := "1"
+	expired := "/.api/search/export/abc?" + url.Values{"expires": {expires}, "signature": {hex.EncodeToString(searchExportSignature(e, expires))}}.Encode()
+	if rec := get(expired); rec.Code != http.StatusGone {
+		t.Errorf("got status %d for expired URL, want %d", rec.Code, http.StatusGone)
+	}
+
+	// Deleted exports are gone.
+	if rec := get("/.api/search/export/def"); rec.Code != http.StatusGone {
+		t.Errorf("got status %d for deleted export, want %d", rec.Code, http.StatusGone)
+	}
+}
+
+func TestSearchExportBuffer(t *testing.T) {
+	var buf searchExportBuffer
+	if _, err := buf.Write(make([]byte, maxSearchExportSize)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := buf.Write([]byte("a")); err != errSearchExportTooLarge {
+		t.Errorf("got error %v, want %v", err, errSearchExportTooLarge)
+	}
+}
@@ -0,0 +1,137 @@
+package graphqlbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// searchFaultPoint is a point of the search pipeline into which faults can be
+// injected.
+type searchFaultPoint string
+
+const (
+	// searchFaultTextSearch is each attempt of textSearch to send a request to
+	// searcher, so injected errors are retried like searcher errors.
+	searchFaultTextSearch searchFaultPoint = "textSearch"
+	// searchFaultSearchRepo is the search of a repository revision by
+	// searchFilesInRepos, so injected panics are recovered like the ones of
+	// searchFilesInRepo.
+	searchFaultSearchRepo searchFaultPoint = "searchRepo"
+)
+
+// searchFault is a fault injected into a search: a latency, followed by a
+// panic or an error (or neither).
+type searchFault struct {
+	Latency time.Duration
+	Panic   bool
+	// Err is the error returned at the fault point.
+	Err error
+}
+
+// searchFaultHook returns the fault to inject at point in the search of repo,
+// or nil. It is only called in builds with fault injection enabled (see
+// searchFaultInjectionEnabled), where it is set from SEARCH_FAULTS. Tests set it
+// directly.
+var searchFaultHook func(ctx context.Context, point searchFaultPoint, repo api.RepoName) *searchFault
+
+// injectSearchFault injects the fault returned by searchFaultHook at point in
+// the search of repo. It returns the error of the fault, or the error of ctx if
+// ctx is done during its latency.
+func injectSearchFault(ctx context.Context, point searchFaultPoint, repo api.RepoName) error {
+	if !searchFaultInjectionEnabled || searchFaultHook == nil {
+		return nil
+	}
+	f := searchFaultHook(ctx, point, repo)
+	if f == nil {
+		return nil
+	}
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if f.Panic {
+		panic(fmt.Sprintf("injected %s fault in %s", point, repo))
+	}
+	return f.Err
+}
+
+// searchFaultRule is a rule of SEARCH_FAULTS, which injects a fault into a
+// fraction of the searches of matching repositories at a fault point.
+type searchFaultRule struct {
+	Point searchFaultPoint
+	// Repo is a regexp of the names of the repositories. Empty matches all.
+	Repo string
+	// Rate is the fraction of the searches to inject the fault into. 0 means
+	// all.
+	Rate    float64
+	Latency string // e.g. "500ms"
+	Panic   bool
+	// Error is the message of an error of searcher with StatusCode (default
+	// 500) to return. 503 errors are temporary, and 400 errors are invalid
+	// patterns.
+	Error      string
+	StatusCode int
+
+	repo    *regexp.Regexp
+	latency time.Duration
+}
+
+// parseSearchFaultRules parses the JSON array of searchFaultRules in s.
+func parseSearchFaultRules(s string) ([]*searchFaultRule, error) {
+	var rules []*searchFaultRule
+	if err := json.Unmarshal([]byte(s), &rules); err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if r.Point != searchFaultTextSearch && r.Point != searchFaultSearchRepo {
+			return nil, errors.Errorf("invalid fault point %q (valid values are: %s, %s)", r.Point, searchFaultTextSearch, searchFaultSearchRepo)
+		}
+		var err error
+		if r.repo, err = regexp.Compile(r.Repo); err != nil {
+			return nil, errors.Wrapf(err, "invalid repo pattern %q", r.Repo)
+		}
+		if r.Latency != "" {
+			if r.latency, err = time.ParseDuration(r.Latency); err != nil {
+				return nil, errors.Wrapf(err, "invalid latency %q", r.Latency)
+			}
+		}
+		if r.StatusCode == 0 {
+			r.StatusCode = http.StatusInternalServerError
+		}
+	}
+	return rules, nil
+}
+
+// searchFaultHookFromRules returns a searchFaultHook that injects the fault of
+// the first rule that matches.
+func searchFaultHookFromRules(rules []*searchFaultRule) func(context.Context, searchFaultPoint, api.RepoName) *searchFault {
+	return func(_ context.Context, point searchFaultPoint, repo api.RepoName) *searchFault {
+		for _, r := range rules {
+			if r.Point != point || !r.repo.MatchString(string(repo)) {
+				continue
+			}
+			if r.Rate > 0 && rand.Float64() >= r.Rate {
+				return nil
+			}
+			f := &searchFault{Latency: r.latency, Panic: r.Panic}
+			if r.Error != "" {
+				f.Err = newSearcherError(&searcherError{StatusCode: r.StatusCode, Message: r.Error})
+			}
+			return f
+		}
+		return nil
+	}
+}
@@ -0,0 +1,8 @@
+// +build !faultinjection
+
+package graphqlbackend
+
+// searchFaultInjectionEnabled is whether faults can be injected into searches,
+// which is only the case in builds with the faultinjection build tag (for
+// resilience testing).
+const searchFaultInjectionEnabled = false
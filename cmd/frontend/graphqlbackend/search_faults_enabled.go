@@ -0,0 +1,29 @@
+// +build faultinjection
+
+package graphqlbackend
+
+import (
+	"log"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+// searchFaultInjectionEnabled is whether faults can be injected into searches,
+// which is only the case in builds with the faultinjection build tag (for
+// resilience testing).
+const searchFaultInjectionEnabled = true
+
+var searchFaults = env.Get("SEARCH_FAULTS", "", `JSON array of faults to inject into searches, e.g. [{"Point": "textSearch", "Repo": "^github\\.com/", "Rate": 0.1, "Latency": "1s", "Error": "unavailable", "StatusCode": 503}]`)
+
+func init() {
+	if searchFaults == "" {
+		return
+	}
+	rules, err := parseSearchFaultRules(searchFaults)
+	if err != nil {
+		log.Fatalf("invalid SEARCH_FAULTS: %s", err)
+	}
+	log15.Warn("Injecting faults into searches.", "faults", searchFaults)
+	searchFaultHook = searchFaultHookFromRules(rules)
+}
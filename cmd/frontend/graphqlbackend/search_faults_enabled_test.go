@@ -0,0 +1,99 @@
+// +build faultinjection
+
+package graphqlbackend
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/zoekt"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestInjectSearchFault_textSearchRetry(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+
+	fake := &FakeSearcherClient{Matches: map[api.RepoName][]protocol.FileMatch{"foo": {{Path: "a.go"}}}}
+	defer func(c SearcherClient) { DefaultSearcherClient = c }(DefaultSearcherClient)
+	DefaultSearcherClient = fake
+
+	// Fail the first attempt with a temporary error.
+	var mu sync.Mutex
+	attempts := 0
+	defer func() { searchFaultHook = nil }()
+	searchFaultHook = func(ctx context.Context, point searchFaultPoint, repo api.RepoName) *searchFault {
+		if point != searchFaultTextSearch {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts > 1 {
+			return nil
+		}
+		return &searchFault{Err: newSearcherError(&searcherError{StatusCode: http.StatusServiceUnavailable, Message: "injected"})}
+	}
+
+	searcherURLs := endpoint.Static("http://searcher-0", "http://searcher-1")
+	matches, _, err := textSearch(context.Background(), searcherURLs, gitserver.Repo{Name: "foo"}, "deadbeef", &search.TextPatternInfo{Pattern: "p"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || attempts != 2 || len(fake.Requests()) != 1 {
+		t.Errorf("got %d matches after %d attempts (%d requests), want the match after a retry", len(matches), attempts, len(fake.Requests()))
+	}
+}
+
+func TestInjectSearchFault_searchRepoPartialResults(t *testing.T) {
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		return []*FileMatchResolver{{uri: "git://" + string(repo.Name) + "?" + rev + "#main.go"}}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	defer func() { searchFaultHook = nil }()
+	searchFaultHook = func(ctx context.Context, point searchFaultPoint, repo api.RepoName) *searchFault {
+		switch {
+		case point != searchFaultSearchRepo:
+			return nil
+		case repo == "foo/panic":
+			return &searchFault{Latency: time.Millisecond, Panic: true}
+		case repo == "foo/error":
+			return &searchFault{Err: newSearcherError(&searcherError{StatusCode: http.StatusInternalServerError, Message: "injected"})}
+		}
+		return nil
+	}
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.TextParameters{
+		PatternInfo: &search.TextPatternInfo{
+			FileMatchLimit: defaultMaxSearchResults,
+			Pattern:        "foo",
+		},
+		Repos:        makeRepositoryRevisions("foo/one", "foo/panic", "foo/error"),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+		SearcherURLs: endpoint.Static("test"),
+	}
+	results, common, err := searchFilesInRepos(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(common.failed) != 2 {
+		t.Errorf("got %d results and %d failed repositories, want the results of foo/one and the others failed", len(results), len(common.failed))
+	}
+}
@@ -0,0 +1,42 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSearchFaultRules(t *testing.T) {
+	rules, err := parseSearchFaultRules(`[
+		{"Point": "textSearch", "Repo": "^foo/", "Latency": "1s", "Error": "unavailable", "StatusCode": 503},
+		{"Point": "searchRepo", "Repo": "panic", "Panic": true},
+		{"Point": "searchRepo", "Error": "boom", "Rate": 0.0001}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := searchFaultHookFromRules(rules)
+	ctx := context.Background()
+
+	f := hook(ctx, searchFaultTextSearch, "foo/bar")
+	if f == nil || f.Latency != time.Second || !isTemporarySearcherFault(f) {
+		t.Errorf("got fault %+v, want a temporary error after 1s", f)
+	}
+	if f := hook(ctx, searchFaultTextSearch, "bar/foo"); f != nil {
+		t.Errorf("got fault %+v for a repository that does not match", f)
+	}
+	if f := hook(ctx, searchFaultSearchRepo, "x/panic"); f == nil || !f.Panic || f.Err != nil {
+		t.Errorf("got fault %+v, want a panic", f)
+	}
+
+	for _, s := range []string{`[{"Point": "zoekt"}]`, `[{"Point": "textSearch", "Repo": "("}]`, `[{"Point": "textSearch", "Latency": "x"}]`, `{}`} {
+		if _, err := parseSearchFaultRules(s); err == nil {
+			t.Errorf("%s: got nil error", s)
+		}
+	}
+}
+
+func isTemporarySearcherFault(f *searchFault) bool {
+	code, _ := searchErrorCodeOf(f.Err)
+	return code == searchErrorSearcherUnavailable
+}
@@ -0,0 +1,241 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// searchFeatureFlag gates experimental search behavior, so that it can be
+// rolled out gradually. Site admins can enable or disable a flag for all users
+// in the search.featureFlags site configuration, and for organizations and
+// users with overrides, and run an experiment on it (see searchExperimentArms).
+// The override of a user takes precedence over those of their organizations
+// (which enable the flag if any of them does), which take precedence over the
+// experiment and the site configuration.
+type searchFeatureFlag struct {
+	name           string
+	description    string
+	defaultEnabled bool
+}
+
+var (
+	searchFeatureIndexedSearch = &searchFeatureFlag{
+		name:           "indexedSearch",
+		description:    "Search indexed repositories with the index. If disabled, all repositories are searched with searcher.",
+		defaultEnabled: true,
+	}
+	searchFeatureStreaming = &searchFeatureFlag{
+		name:           "streaming",
+		description:    "Send file matches to clients of the streaming search APIs as soon as a repository has been searched. If disabled, all results are sent once the search is done.",
+		defaultEnabled: true,
+	}
+)
+
+// searchFeatureFlags are all search feature flags.
+var searchFeatureFlags = []*searchFeatureFlag{
+	searchFeatureIndexedSearch,
+	searchFeatureStreaming,
+}
+
+func searchFeatureFlagByName(name string) (*searchFeatureFlag, error) {
+	for _, f := range searchFeatureFlags {
+		if f.name == name {
+			return f, nil
+		}
+	}
+	return nil, errors.Errorf("unknown search feature flag %q", name)
+}
+
+// siteEnabled reports whether f is enabled for users without overrides.
+func (f *searchFeatureFlag) siteEnabled() bool {
+	if enabled, ok := conf.Get().SearchFeatureFlags[f.name]; ok {
+		return enabled
+	}
+	return f.defaultEnabled
+}
+
+// enabled reports whether f is enabled for the search that runs in ctx. The
+// overrides of the user are those loaded by withSearchFeatureFlags.
+func (f *searchFeatureFlag) enabled(ctx context.Context) bool {
+	if enabled, ok := f.override(ctx); ok {
+		return enabled
+	}
+	if enabled, ok := f.experimentArm(actor.FromContext(ctx).UID); ok {
+		return enabled
+	}
+	return f.siteEnabled()
+}
+
+// override reports whether f is enabled by the overrides that apply to the
+// user of the search that runs in ctx, and whether there are any.
+func (f *searchFeatureFlag) override(ctx context.Context) (enabled, ok bool) {
+	overrides, _ := ctx.Value(searchFeatureFlagsKey{}).([]*types.SearchFeatureFlagOverride)
+	for _, o := range overrides {
+		if o.Flag != f.name {
+			continue
+		}
+		if o.UserID != 0 {
+			return o.Enabled, true
+		}
+		enabled = enabled || o.Enabled
+		ok = true
+	}
+	return enabled, ok
+}
+
+type searchFeatureFlagsKey struct{}
+
+// withSearchFeatureFlags returns a context with the overrides of search
+// feature flags that apply to the current user, so that they are loaded once
+// per search. If they can't be loaded, the flags are evaluated without them.
+func withSearchFeatureFlags(ctx context.Context) context.Context {
+	if ctx.Value(searchFeatureFlagsKey{}) != nil {
+		return ctx
+	}
+	overrides := []*types.SearchFeatureFlagOverride{}
+	if a := actor.FromContext(ctx); a.IsAuthenticated() {
+		userOverrides, err := db.SearchFeatureFlagOverrides.ListForUser(ctx, a.UID)
+		if err != nil {
+			log15.Warn("Failed to load search feature flag overrides.", "user", a.UID, "error", err)
+		} else if userOverrides != nil {
+			overrides = userOverrides
+		}
+	}
+	return context.WithValue(ctx, searchFeatureFlagsKey{}, overrides)
+}
+
+func (r *siteResolver) SearchFeatureFlags(ctx context.Context) ([]*searchFeatureFlagResolver, error) {
+	// 🚨 SECURITY: Only site admins may view the overrides of other users.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	overrides, err := db.SearchFeatureFlagOverrides.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*searchFeatureFlagResolver, len(searchFeatureFlags))
+	for i, f := range searchFeatureFlags {
+		resolvers[i] = &searchFeatureFlagResolver{f: f, overrides: []*searchFeatureFlagOverrideResolver{}}
+		for _, o := range overrides {
+			if o.Flag == f.name {
+				resolvers[i].overrides = append(resolvers[i].overrides, &searchFeatureFlagOverrideResolver{o: o})
+			}
+		}
+	}
+	return resolvers, nil
+}
+
+func (r *schemaResolver) SetSearchFeatureFlagOverride(ctx context.Context, args *struct {
+	Flag      string
+	Namespace graphql.ID
+	Enabled   bool
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change the search feature flags of
+	// users and organizations.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := searchFeatureFlagByName(args.Flag); err != nil {
+		return nil, err
+	}
+	userID, orgID, err := unmarshalSearchFeatureFlagNamespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	o := &types.SearchFeatureFlagOverride{Flag: args.Flag, UserID: userID, OrgID: orgID, Enabled: args.Enabled}
+	if err := db.SearchFeatureFlagOverrides.Set(ctx, o); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+func (r *schemaResolver) DeleteSearchFeatureFlagOverride(ctx context.Context, args *struct {
+	Flag      string
+	Namespace graphql.ID
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may change the search feature flags of
+	// users and organizations.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	userID, orgID, err := unmarshalSearchFeatureFlagNamespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.SearchFeatureFlagOverrides.Delete(ctx, args.Flag, userID, orgID); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+// unmarshalSearchFeatureFlagNamespace returns the user or organization ID of
+// the namespace with the given GraphQL ID.
+func unmarshalSearchFeatureFlagNamespace(id graphql.ID) (userID, orgID int32, err error) {
+	switch relay.UnmarshalKind(id) {
+	case "User":
+		userID, err = UnmarshalUserID(id)
+	case "Org":
+		orgID, err = UnmarshalOrgID(id)
+	default:
+		err = errors.New("invalid ID for namespace")
+	}
+	return userID, orgID, err
+}
+
+type searchFeatureFlagResolver struct {
+	f         *searchFeatureFlag
+	overrides []*searchFeatureFlagOverrideResolver
+}
+
+func (r *searchFeatureFlagResolver) Name() string { return r.f.name }
+
+func (r *searchFeatureFlagResolver) Description() string { return r.f.description }
+
+func (r *searchFeatureFlagResolver) DefaultEnabled() bool { return r.f.defaultEnabled }
+
+func (r *searchFeatureFlagResolver) Enabled() bool { return r.f.siteEnabled() }
+
+func (r *searchFeatureFlagResolver) ExperimentPercentage() *int32 {
+	percentage, ok := conf.Get().SearchExperiments[r.f.name]
+	if !ok {
+		return nil
+	}
+	p := int32(percentage)
+	return &p
+}
+
+func (r *searchFeatureFlagResolver) Overrides() []*searchFeatureFlagOverrideResolver {
+	return r.overrides
+}
+
+type searchFeatureFlagOverrideResolver struct {
+	o *types.SearchFeatureFlagOverride
+}
+
+func (r *searchFeatureFlagOverrideResolver) Namespace(ctx context.Context) (*NamespaceResolver, error) {
+	id := MarshalUserID(r.o.UserID)
+	if r.o.OrgID != 0 {
+		id = marshalOrgID(r.o.OrgID)
+	}
+	n, err := NamespaceByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &NamespaceResolver{n}, nil
+}
+
+func (r *searchFeatureFlagOverrideResolver) Enabled() bool { return r.o.Enabled }
+
+func (r *searchFeatureFlagOverrideResolver) UpdatedAt() DateTime {
+	return DateTime{Time: r.o.UpdatedAt}
+}
@@ -0,0 +1,101 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchFeatureFlag_enabled(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+	db.Mocks.SearchFeatureFlagOverrides.ListForUser = func(ctx context.Context, userID int32) ([]*types.SearchFeatureFlagOverride, error) {
+		switch userID {
+		case 1:
+			return []*types.SearchFeatureFlagOverride{
+				{Flag: "streaming", OrgID: 1, Enabled: false},
+				{Flag: "streaming", UserID: 1, Enabled: true},
+				{Flag: "indexedSearch", OrgID: 1, Enabled: false},
+			}, nil
+		case 2:
+			return []*types.SearchFeatureFlagOverride{
+				{Flag: "indexedSearch", OrgID: 1, Enabled: false},
+				{Flag: "indexedSearch", OrgID: 2, Enabled: true},
+			}, nil
+		}
+		return nil, nil
+	}
+	defer resetMocks()
+
+	enabled := func(userID int32, f *searchFeatureFlag) bool {
+		ctx := actor.WithActor(context.Background(), &actor.Actor{UID: userID})
+		return f.enabled(withSearchFeatureFlags(ctx))
+	}
+
+	// Without overrides, the default applies.
+	if !enabled(3, searchFeatureStreaming) || !enabled(0, searchFeatureIndexedSearch) {
+		t.Error("flags without overrides are disabled, want their defaults")
+	}
+	// The override of the user takes precedence over that of the org.
+	if !enabled(1, searchFeatureStreaming) {
+		t.Error("streaming disabled for user 1, want enabled by the user override")
+	}
+	if enabled(1, searchFeatureIndexedSearch) {
+		t.Error("indexedSearch enabled for user 1, want disabled by the org override")
+	}
+	// Any org can enable a flag.
+	if !enabled(2, searchFeatureIndexedSearch) {
+		t.Error("indexedSearch disabled for user 2, want enabled by the override of org 2")
+	}
+
+	// The site configuration applies to users without overrides.
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchFeatureFlags: map[string]bool{"streaming": false},
+	}})
+	if enabled(3, searchFeatureStreaming) {
+		t.Error("streaming enabled for user 3, want disabled by the site configuration")
+	}
+	if !enabled(1, searchFeatureStreaming) {
+		t.Error("streaming disabled for user 1, want enabled by the user override")
+	}
+}
+
+func TestSetSearchFeatureFlagOverride(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	var set *types.SearchFeatureFlagOverride
+	db.Mocks.SearchFeatureFlagOverrides.Set = func(ctx context.Context, o *types.SearchFeatureFlagOverride) error {
+		set = o
+		return nil
+	}
+	defer resetMocks()
+
+	r := &schemaResolver{}
+	if _, err := r.SetSearchFeatureFlagOverride(context.Background(), &struct {
+		Flag      string
+		Namespace graphql.ID
+		Enabled   bool
+	}{Flag: "streaming", Namespace: marshalOrgID(2), Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.SearchFeatureFlagOverride{Flag: "streaming", OrgID: 2, Enabled: true}); set == nil || *set != want {
+		t.Errorf("got override %+v, want %+v", set, want)
+	}
+
+	if _, err := r.SetSearchFeatureFlagOverride(context.Background(), &struct {
+		Flag      string
+		Namespace graphql.ID
+		Enabled   bool
+	}{Flag: "unknown", Namespace: MarshalUserID(1)}); err == nil {
+		t.Error("got nil error for an unknown flag")
+	}
+}
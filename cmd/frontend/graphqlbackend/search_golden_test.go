@@ -0,0 +1,161 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/testutil"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var updateSearchGolden = flag.Bool("update", false, "update the golden files of TestSearchGolden")
+
+// searchGoldenQuery is the GraphQL query of TestSearchGolden. It asks for the
+// fields that clients use to display search results.
+const searchGoldenQuery = `
+query Search($query: String!) {
+	search(query: $query, version: V2, patternType: literal) {
+		results {
+			__typename
+			matchCount
+			resultCount
+			approximateResultCount
+			limitHit
+			repositoriesCount
+			cloning { name }
+			missing { name }
+			timedout { name }
+			alert { title description proposedQueries { description query } }
+			results {
+				__typename
+				... on FileMatch {
+					repository { name }
+					file { path name }
+					limitHit
+					lineMatches { preview lineNumber offsetAndLengths limitHit }
+				}
+				... on Repository {
+					name
+				}
+			}
+		}
+	}
+}
+`
+
+// TestSearchGolden runs canned searches against a FakeSearcherClient and
+// compares the GraphQL responses with the golden files in
+// testdata/search-golden, so that changes of the response shape (or of the
+// results) that clients see are caught. Run it with -update to update the
+// golden files after intended changes, and review their diff.
+func TestSearchGolden(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+
+	// Search with the fake searcher only.
+	mockSearchBackends = func() (*searchbackend.Zoekt, *endpoint.Map) {
+		return &searchbackend.Zoekt{}, endpoint.Static("http://searcher")
+	}
+	defer func() { mockSearchBackends = nil }()
+
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+
+	repos := []*types.Repo{
+		{ID: 1, Name: "github.com/sourcegraph/go-langserver", RepoFields: &types.RepoFields{}},
+		{ID: 2, Name: "github.com/sourcegraph/jsonrpc2", RepoFields: &types.RepoFields{}},
+	}
+	defer func(mocks db.MockStores) { db.Mocks = mocks }(db.Mocks)
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		var matching []*types.Repo
+	nextRepo:
+		for _, repo := range repos {
+			for _, p := range op.IncludePatterns {
+				if !regexp.MustCompile(p).MatchString(string(repo.Name)) {
+					continue nextRepo
+				}
+			}
+			matching = append(matching, repo)
+		}
+		return matching, nil
+	}
+	db.Mocks.Repos.Count = mockCount
+	db.Mocks.Repos.GetSizes = func(context.Context, ...api.RepoID) (map[api.RepoID]int64, error) {
+		return nil, nil
+	}
+	db.Mocks.Repos.MockGetByName(t, "github.com/sourcegraph/go-langserver", 1)
+	db.Mocks.Repos.MockGet(t, 1)
+
+	defer git.ResetMocks()
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return "1234567890123456789012345678901234567890", nil
+	}
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	fake := &FakeSearcherClient{
+		Matches: map[api.RepoName][]protocol.FileMatch{
+			"github.com/sourcegraph/go-langserver": {
+				{
+					Path: "langserver/handler.go",
+					LineMatches: []protocol.LineMatch{
+						{Preview: "func (h *LangHandler) handle(ctx context.Context) {", LineNumber: 41, OffsetAndLengths: [][2]int{{23, 6}}},
+						{Preview: "\treturn h.handle(ctx)", LineNumber: 99, OffsetAndLengths: [][2]int{{10, 6}}},
+					},
+				},
+				{
+					Path:        "main.go",
+					LineMatches: []protocol.LineMatch{{Preview: "\th.handle(nil)", LineNumber: 9, OffsetAndLengths: [][2]int{{3, 6}}}},
+				},
+			},
+			"github.com/sourcegraph/jsonrpc2": {
+				{
+					Path:        "handler_with_error.go",
+					LineMatches: []protocol.LineMatch{{Preview: "\tresult, err := h.handleFunc(ctx, conn, req)", LineNumber: 47, OffsetAndLengths: [][2]int{{18, 6}}}},
+					LimitHit:    true,
+				},
+			},
+		},
+	}
+	defer func(c SearcherClient) { DefaultSearcherClient = c }(DefaultSearcherClient)
+	DefaultSearcherClient = fake
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "text", query: "handle"},
+		{name: "text-limit-hit", query: "handle count:1 deterministic:yes"},
+		{name: "repo", query: "repo:jsonrpc2 type:repo"},
+		{name: "text-repo", query: "repo:jsonrpc2 handle"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := mustParseGraphQLSchema(t).Exec(context.Background(), searchGoldenQuery, "", map[string]interface{}{"query": test.query})
+			if len(resp.Errors) > 0 {
+				t.Fatalf("GraphQL errors: %v", resp.Errors)
+			}
+			var got bytes.Buffer
+			if err := json.Indent(&got, resp.Data, "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			got.WriteByte('\n')
+			testutil.AssertGolden(t, filepath.Join("testdata", "search-golden", test.name+".json"), *updateSearchGolden, got.Bytes())
+		})
+	}
+}
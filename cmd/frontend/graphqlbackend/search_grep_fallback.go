@@ -0,0 +1,210 @@
+package graphqlbackend
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+var searcherFallbackMaxRepos = env.Get("SEARCHER_FALLBACK_MAX_REPOS", "10", "maximum number of repositories per search that are searched with git grep on gitserver when searcher is unavailable (0 disables the fallback)")
+
+// grepFallbackMaxLineMatches is the maximum number of matching lines returned
+// by git grep for a single repository.
+const grepFallbackMaxLineMatches = 1000
+
+// grepFallback is the degraded search path used when searcher is unavailable or
+// not configured: a limited number of repositories of a search are searched
+// with git grep on gitserver instead. The remaining repositories are reported
+// as timed out, so that the search returns partial results instead of an
+// error.
+type grepFallback struct {
+	mu        sync.Mutex
+	remaining int
+	used      bool
+}
+
+type grepFallbackKey struct{}
+
+// withGrepFallback returns a context that enables the git grep fallback for
+// the repository searches run with it. It returns a nil *grepFallback if the
+// fallback is disabled.
+func withGrepFallback(ctx context.Context) (context.Context, *grepFallback) {
+	max, err := strconv.Atoi(searcherFallbackMaxRepos)
+	if err != nil {
+		log15.Error("Invalid SEARCHER_FALLBACK_MAX_REPOS, disabling git grep fallback", "value", searcherFallbackMaxRepos, "error", err)
+		return ctx, nil
+	}
+	if max <= 0 {
+		return ctx, nil
+	}
+	f := &grepFallback{remaining: max}
+	return context.WithValue(ctx, grepFallbackKey{}, f), f
+}
+
+func grepFallbackFromContext(ctx context.Context) *grepFallback {
+	f, _ := ctx.Value(grepFallbackKey{}).(*grepFallback)
+	return f
+}
+
+// wasUsed returns true if any repository was searched with git grep.
+func (f *grepFallback) wasUsed() bool {
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.used
+}
+
+func (f *grepFallback) acquire() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.remaining == 0 {
+		return false
+	}
+	f.remaining--
+	f.used = true
+	return true
+}
+
+// fallbackSkippedError is returned for repositories that could not be searched
+// because searcher is unavailable and the fallback limit was reached. It is
+// temporary, so the repository is reported as timed out.
+type fallbackSkippedError struct{ err error }
+
+func (e *fallbackSkippedError) Error() string   { return e.err.Error() }
+func (e *fallbackSkippedError) Cause() error    { return e.err }
+func (e *fallbackSkippedError) Temporary() bool { return true }
+
+// search searches repo at commit with git grep if searchErr means that
+// searcher is unavailable. Otherwise, or if the fallback does not support the
+// query, it returns searchErr.
+func (f *grepFallback) search(ctx context.Context, repo gitserver.Repo, commit api.CommitID, info *search.TextPatternInfo, searchErr error) ([]*FileMatchResolver, bool, error) {
+	if code, ok := searchErrorCodeOf(searchErr); !ok || code != searchErrorSearcherUnavailable {
+		return nil, false, searchErr
+	}
+	if info.IsStructuralPat || info.IsPCRE || info.CaseFold || info.Normalization != "" || !info.PatternMatchesContent || (len(info.IncludePatterns) > 0 || info.ExcludePattern != "") && !info.PathPatternsAreRegExps {
+		return nil, false, searchErr
+	}
+	if !f.acquire() {
+		return nil, false, &fallbackSkippedError{err: searchErr}
+	}
+	return grepSearch(ctx, repo, commit, info)
+}
+
+// grepSearch searches repo at commit with git grep, returning results in the
+// same form as searcher.
+func grepSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, info *search.TextPatternInfo) ([]*FileMatchResolver, bool, error) {
+	lines, limitHit, err := git.Grep(ctx, repo, commit, git.GrepOptions{
+		Pattern:         info.Pattern,
+		IsRegExp:        info.IsRegExp,
+		IsCaseSensitive: info.IsCaseSensitive,
+		IsWordMatch:     info.IsWordMatch,
+		MaxMatches:      grepFallbackMaxLineMatches,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	includePath, err := grepPathMatcher(info)
+	if err != nil {
+		return nil, false, err
+	}
+	// The pattern is only used to highlight matches, so it is fine if git
+	// grep and Go regexps interpret it slightly differently.
+	match := grepPatternRegexp(info)
+
+	var matches []*FileMatchResolver
+	byPath := map[string]*FileMatchResolver{}
+	for _, l := range lines {
+		if !includePath(l.Path) {
+			continue
+		}
+		fm := byPath[l.Path]
+		if fm == nil {
+			if info.FileMatchLimit > 0 && len(matches) == int(info.FileMatchLimit) {
+				limitHit = true
+				break
+			}
+			fm = &FileMatchResolver{JPath: l.Path}
+			byPath[l.Path] = fm
+			matches = append(matches, fm)
+		}
+		lm := &lineMatch{JPreview: l.Line, JLineNumber: int32(l.LineNumber - 1)}
+		if match != nil {
+			for _, loc := range match.FindAllStringIndex(l.Line, -1) {
+				offset := utf8.RuneCountInString(l.Line[:loc[0]])
+				length := utf8.RuneCountInString(l.Line[loc[0]:loc[1]])
+				lm.JOffsetAndLengths = append(lm.JOffsetAndLengths, [2]int32{int32(offset), int32(length)})
+			}
+		}
+		fm.JLineMatches = append(fm.JLineMatches, lm)
+		if len(lm.JOffsetAndLengths) > 0 {
+			fm.MatchCount += len(lm.JOffsetAndLengths)
+		} else {
+			fm.MatchCount++
+		}
+	}
+	return matches, limitHit, nil
+}
+
+// grepPatternRegexp returns a Go regexp approximating the git grep pattern of
+// info, or nil if there is none.
+func grepPatternRegexp(info *search.TextPatternInfo) *regexp.Regexp {
+	expr := info.Pattern
+	if !info.IsRegExp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if info.IsWordMatch {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	if !info.IsCaseSensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// grepPathMatcher returns a function reporting whether a path matches the
+// include and exclude patterns of info.
+func grepPathMatcher(info *search.TextPatternInfo) (func(path string) bool, error) {
+	flags := "(?i)"
+	if info.PathPatternsAreCaseSensitive {
+		flags = ""
+	}
+	var include []*regexp.Regexp
+	for _, p := range info.IncludePatterns {
+		re, err := regexp.Compile(flags + p)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, re)
+	}
+	var exclude *regexp.Regexp
+	if info.ExcludePattern != "" {
+		var err error
+		if exclude, err = regexp.Compile(flags + info.ExcludePattern); err != nil {
+			return nil, err
+		}
+	}
+	return func(path string) bool {
+		for _, re := range include {
+			if !re.MatchString(path) {
+				return false
+			}
+		}
+		return exclude == nil || !exclude.MatchString(path)
+	}, nil
+}
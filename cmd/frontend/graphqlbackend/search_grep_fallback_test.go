@@ -0,0 +1,82 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestGrepFallback(t *testing.T) {
+	defer git.ResetMocks()
+	var gotOpt git.GrepOptions
+	git.Mocks.Grep = func(commit api.CommitID, opt git.GrepOptions) ([]git.GrepMatch, bool, error) {
+		gotOpt = opt
+		return []git.GrepMatch{
+			{Path: "a.go", LineNumber: 1, Line: "foo Foo"},
+			{Path: "a.go", LineNumber: 3, Line: "ü foo"},
+			{Path: "b_test.go", LineNumber: 2, Line: "foo"},
+			{Path: "c.go", LineNumber: 5, Line: "foo"},
+		}, false, nil
+	}
+
+	info := &search.TextPatternInfo{
+		Pattern:                "foo",
+		FileMatchLimit:         10,
+		PatternMatchesContent:  true,
+		ExcludePattern:         `_test\.go$`,
+		PathPatternsAreRegExps: true,
+	}
+	unavailable := newSearchError(searchErrorSearcherUnavailable, errors.New("connection refused"))
+
+	f := &grepFallback{remaining: 1}
+	matches, limitHit, err := f.search(context.Background(), gitserver.Repo{Name: "r"}, "c", info, unavailable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Error("unexpected limitHit")
+	}
+	if want := (git.GrepOptions{Pattern: "foo", MaxMatches: grepFallbackMaxLineMatches}); gotOpt != want {
+		t.Errorf("got options %+v, want %+v", gotOpt, want)
+	}
+	want := []*FileMatchResolver{
+		{
+			JPath: "a.go",
+			JLineMatches: []*lineMatch{
+				{JPreview: "foo Foo", JLineNumber: 0, JOffsetAndLengths: [][2]int32{{0, 3}, {4, 3}}},
+				{JPreview: "ü foo", JLineNumber: 2, JOffsetAndLengths: [][2]int32{{2, 3}}},
+			},
+			MatchCount: 3,
+		},
+		{
+			JPath:        "c.go",
+			JLineMatches: []*lineMatch{{JPreview: "foo", JLineNumber: 4, JOffsetAndLengths: [][2]int32{{0, 3}}}},
+			MatchCount:   1,
+		},
+	}
+	if !cmp.Equal(matches, want, cmp.AllowUnexported(FileMatchResolver{})) {
+		t.Errorf("matches: %s", cmp.Diff(want, matches, cmp.AllowUnexported(FileMatchResolver{})))
+	}
+	if !f.wasUsed() {
+		t.Error("expected fallback to be used")
+	}
+
+	// Once the limit is reached, repositories are reported as timed out.
+	_, _, err = f.search(context.Background(), gitserver.Repo{Name: "r2"}, "c", info, unavailable)
+	if !errcode.IsTemporary(err) {
+		t.Errorf("got error %v, want temporary error", err)
+	}
+
+	// Other errors are returned as is.
+	other := errors.New("other")
+	if _, _, err := (&grepFallback{remaining: 1}).search(context.Background(), gitserver.Repo{Name: "r"}, "c", info, other); err != other {
+		t.Errorf("got error %v, want %v", err, other)
+	}
+}
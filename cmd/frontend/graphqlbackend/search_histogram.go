@@ -0,0 +1,114 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// searchHistogramArgs are the arguments of Search.histogram.
+type searchHistogramArgs struct {
+	First int32
+	Depth int32
+}
+
+// Histogram runs the search and counts its matches by repository and by
+// directory, so that clients can show where a pattern occurs without fetching
+// and counting the matches themselves.
+func (r *searchResolver) Histogram(ctx context.Context, args *searchHistogramArgs) (*searchHistogramResolver, error) {
+	results, err := r.doResults(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	a := results.aggregations
+	if a == nil {
+		a = newSearchAggregations()
+	}
+	return &searchHistogramResolver{
+		a:          a,
+		first:      int(args.First),
+		depth:      int(args.Depth),
+		matchCount: results.MatchCount(),
+		limitHit:   results.LimitHit(),
+	}, nil
+}
+
+func (searchAlert) Histogram(context.Context, *searchHistogramArgs) (*searchHistogramResolver, error) {
+	return &searchHistogramResolver{a: newSearchAggregations()}, nil
+}
+
+type searchHistogramResolver struct {
+	a            *searchAggregations
+	first, depth int
+	matchCount   int32
+	limitHit     bool
+}
+
+func (r *searchHistogramResolver) Repositories() []*searchAggregationBucketResolver {
+	return (&searchAggregationsResolver{a: r.a, first: r.first}).Repositories()
+}
+
+// Paths returns the directories with the most matches, counting the matches
+// in the files below each directory up to r.depth path components deep.
+func (r *searchHistogramResolver) Paths() []*searchHistogramPathResolver {
+	counts := map[repoDirectory]int32{}
+	for rd, n := range r.a.repoDirectories {
+		rd.dir = truncatePath(rd.dir, r.depth)
+		counts[rd] += n
+	}
+
+	paths := make([]*searchHistogramPathResolver, 0, len(counts))
+	for rd, n := range counts {
+		paths = append(paths, &searchHistogramPathResolver{repoDirectory: rd, count: n})
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if paths[i].count != paths[j].count {
+			return paths[i].count > paths[j].count
+		}
+		if paths[i].repo != paths[j].repo {
+			return paths[i].repo < paths[j].repo
+		}
+		return paths[i].dir < paths[j].dir
+	})
+	if r.first >= 0 && len(paths) > r.first {
+		paths = paths[:r.first]
+	}
+	return paths
+}
+
+func (r *searchHistogramResolver) MatchCount() int32 { return r.matchCount }
+func (r *searchHistogramResolver) LimitHit() bool    { return r.limitHit }
+
+// truncatePath returns the first depth components of the slash-separated
+// path p, or p if depth is not positive.
+func truncatePath(p string, depth int) string {
+	if depth <= 0 {
+		return p
+	}
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' {
+			if depth--; depth == 0 {
+				return p[:i]
+			}
+		}
+	}
+	return p
+}
+
+type searchHistogramPathResolver struct {
+	repoDirectory
+	count int32
+}
+
+func (p *searchHistogramPathResolver) Repository() string { return p.repo }
+func (p *searchHistogramPathResolver) Path() string       { return p.dir }
+func (p *searchHistogramPathResolver) Count() int32       { return p.count }
+
+func (p *searchHistogramPathResolver) Filter() string {
+	filter := fmt.Sprintf("repo:^%s$ ", regexp.QuoteMeta(p.repo))
+	if p.dir == "" {
+		return filter + "file:^[^/]+$"
+	}
+	return filter + fmt.Sprintf("file:^%s/", regexp.QuoteMeta(p.dir))
+}
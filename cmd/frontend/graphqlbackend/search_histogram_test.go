@@ -0,0 +1,75 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestSearchHistogram(t *testing.T) {
+	foo := &types.Repo{Name: "github.com/foo/foo"}
+	bar := &types.Repo{Name: "github.com/foo/bar"}
+
+	a := newSearchAggregations()
+	a.addFileMatches([]*FileMatchResolver{
+		{Repo: foo, JPath: "cmd/a/main.go", MatchCount: 3},
+		{Repo: foo, JPath: "cmd/a/internal/util.go", MatchCount: 2},
+		{Repo: foo, JPath: "cmd/b/main.go", MatchCount: 1},
+		{Repo: foo, JPath: "README.md", MatchCount: 2},
+		{Repo: bar, JPath: ".github/workflows/ci.yml", MatchCount: 4},
+	})
+
+	type path struct {
+		Repository, Path, Filter string
+		Count                    int32
+	}
+	paths := func(depth, first int) (ps []path) {
+		r := &searchHistogramResolver{a: a, first: first, depth: depth}
+		for _, p := range r.Paths() {
+			ps = append(ps, path{Repository: p.Repository(), Path: p.Path(), Filter: p.Filter(), Count: p.Count()})
+		}
+		return ps
+	}
+
+	tests := []struct {
+		depth, first int
+		want         []path
+	}{
+		{
+			depth: 1,
+			first: 10,
+			want: []path{
+				{"github.com/foo/foo", "cmd", `repo:^github\.com/foo/foo$ file:^cmd/`, 6},
+				{"github.com/foo/bar", ".github", `repo:^github\.com/foo/bar$ file:^\.github/`, 4},
+				{"github.com/foo/foo", "", `repo:^github\.com/foo/foo$ file:^[^/]+$`, 2},
+			},
+		},
+		{
+			depth: 2,
+			first: 2,
+			want: []path{
+				{"github.com/foo/foo", "cmd/a", `repo:^github\.com/foo/foo$ file:^cmd/a/`, 5},
+				{"github.com/foo/bar", ".github/workflows", `repo:^github\.com/foo/bar$ file:^\.github/workflows/`, 4},
+			},
+		},
+		{
+			depth: 0,
+			first: 2,
+			want: []path{
+				{"github.com/foo/bar", ".github/workflows", `repo:^github\.com/foo/bar$ file:^\.github/workflows/`, 4},
+				{"github.com/foo/foo", "cmd/a", `repo:^github\.com/foo/foo$ file:^cmd/a/`, 3},
+			},
+		},
+	}
+	for _, test := range tests {
+		if got := paths(test.depth, test.first); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("depth %d: got %+v, want %+v", test.depth, got, test.want)
+		}
+	}
+
+	r := &searchHistogramResolver{a: a, first: 1}
+	if got := r.Repositories(); len(got) != 1 || got[0].Value() != "github.com/foo/foo" || got[0].Count() != 8 {
+		t.Errorf("got repositories %+v, want github.com/foo/foo with 8 matches", got)
+	}
+}
@@ -0,0 +1,245 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxHistoryCommits is the maximum number of commits per repository that
+	// a search of the history of files (with the history: field) searches. If
+	// the range has more commits, only the newest are searched.
+	maxHistoryCommits = 250
+
+	// maxHistoryFileMatchLimit is the maximum number of file matches that are
+	// fetched across all commits before they are combined.
+	maxHistoryFileMatchLimit = 10000
+)
+
+// historyRange is a range of consecutive commits (in the order they are
+// listed by git log) in which a match exists.
+type historyRange struct {
+	first, last api.CommitID
+}
+
+// repoHistory is the list of commits of a repository's history that is
+// searched.
+type repoHistory struct {
+	repo    *types.Repo
+	commits []git.CommitTree // oldest first
+
+	position map[api.CommitID]int // the index of each commit in commits
+	byTree   map[git.OID][]int    // the indexes of the commits that have each tree
+}
+
+// searchFilesInHistory is like searchFilesInRepos, but searches the file trees
+// of every commit in the revision range historyRange (see
+// query.ParseHistoryRange) of each repository. Commits with the same tree are
+// searched once.
+//
+// Matches of the same lines of a file in several commits are returned once,
+// at the newest commit, with the ranges of commits they exist in (see
+// FileMatchResolver.HistoryRanges). This makes it possible to find out when a
+// snippet was introduced and removed.
+func searchFilesInHistory(ctx context.Context, args *search.TextParameters, historyRange string) ([]*FileMatchResolver, *searchResultsCommon, error) {
+	since, head, err := query.ParseHistoryRange(historyRange)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	histories, failed := listRepoHistories(ctx, args.Repos, since, head)
+
+	var repoRevs []*search.RepositoryRevisions
+	for _, h := range histories {
+		for _, positions := range h.byTree {
+			// Search each tree at the newest commit that has it.
+			commitID := h.commits[positions[len(positions)-1]].CommitID
+			repoRevs = append(repoRevs, &search.RepositoryRevisions{
+				Repo: h.repo,
+				Revs: []search.RevisionSpecifier{{RevSpec: string(commitID)}},
+			})
+		}
+	}
+	sort.Slice(repoRevs, func(i, j int) bool { return repoRevs[i].String() < repoRevs[j].String() })
+
+	limit := int(args.PatternInfo.FileMatchLimit)
+	patternInfo := *args.PatternInfo
+	if n := limit * len(repoRevs); n < maxHistoryFileMatchLimit {
+		patternInfo.FileMatchLimit = int32(n)
+	} else {
+		patternInfo.FileMatchLimit = maxHistoryFileMatchLimit
+	}
+	historyArgs := *args
+	historyArgs.PatternInfo = &patternInfo
+	historyArgs.Repos = repoRevs
+
+	var (
+		results []*FileMatchResolver
+		common  = &searchResultsCommon{partial: map[api.RepoName]struct{}{}}
+	)
+	if len(repoRevs) > 0 {
+		results, common, err = searchFilesInRepos(ctx, &historyArgs)
+		if err != nil {
+			return nil, common, err
+		}
+	}
+	common.failed = append(common.failed, failed...)
+	for _, repos := range []*[]*types.Repo{&common.searched, &common.indexed, &common.cloning, &common.missing, &common.failed, &common.timedout} {
+		// Each repository was searched at many commits.
+		rs := types.Repos(*repos)
+		dedupSort(&rs)
+		*repos = rs
+	}
+
+	results = combineHistoryMatches(histories, results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+		common.limitHit = true
+	}
+	common.resultCount = int32(len(results))
+	return results, common, nil
+}
+
+// listRepoHistories lists the commits in the range since..head of each
+// repository concurrently. If head is empty, the first searched revision of
+// the repository is used. Repositories whose history can't be listed (e.g.
+// because they don't have since) are returned as failed.
+func listRepoHistories(ctx context.Context, repoRevs []*search.RepositoryRevisions, since, head string) (histories map[api.RepoID]*repoHistory, failed []*types.Repo) {
+	var (
+		mu  sync.Mutex
+		run = parallel.NewRun(revisionResolutionParallelism)
+	)
+	histories = make(map[api.RepoID]*repoHistory, len(repoRevs))
+	for _, repoRev := range repoRevs {
+		repoHead := head
+		if repoHead == "" {
+			for _, rev := range repoRev.Revs {
+				if rev.RefGlob == "" && rev.ExcludeRefGlob == "" {
+					repoHead = rev.RevSpec
+					break
+				}
+			}
+		}
+		repoRev := repoRev
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			commits, err := git.ListCommitTrees(ctx, repoRev.GitserverRepo(), since, repoHead, maxHistoryCommits)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if ctx.Err() == nil {
+					log15.Warn("Failed to list commits to search history", "repo", repoRev.Repo.Name, "since", since, "head", repoHead, "error", err)
+					failed = append(failed, repoRev.Repo)
+				}
+				return
+			}
+			h := &repoHistory{
+				repo:     repoRev.Repo,
+				commits:  commits,
+				position: make(map[api.CommitID]int, len(commits)),
+				byTree:   map[git.OID][]int{},
+			}
+			for i, c := range commits {
+				h.position[c.CommitID] = i
+				h.byTree[c.TreeID] = append(h.byTree[c.TreeID], i)
+			}
+			histories[repoRev.Repo.ID] = h
+		})
+	}
+	_ = run.Wait()
+	return histories, failed
+}
+
+// historyMatchKey identifies the matched lines of a file, regardless of the
+// commit they were found in.
+type historyMatchKey struct {
+	repo  api.RepoID
+	path  string
+	lines string
+}
+
+// combineHistoryMatches combines the file matches of the same lines found in
+// several commits into one match at the newest commit (keeping the order in
+// which they were first found) and sets its history ranges.
+func combineHistoryMatches(histories map[api.RepoID]*repoHistory, fms []*FileMatchResolver) []*FileMatchResolver {
+	type combined struct {
+		fm        *FileMatchResolver
+		newest    int
+		positions []int
+	}
+	var (
+		keys   []historyMatchKey
+		groups = map[historyMatchKey]*combined{}
+	)
+	for _, fm := range fms {
+		h := histories[fm.Repo.ID]
+		if h == nil {
+			continue
+		}
+		pos, ok := h.position[fm.CommitID]
+		if !ok {
+			continue
+		}
+		lines := make([]string, len(fm.JLineMatches))
+		for i, lm := range fm.JLineMatches {
+			lines[i] = lm.JPreview
+		}
+		key := historyMatchKey{repo: fm.Repo.ID, path: fm.JPath, lines: strings.Join(lines, "\n")}
+		g, ok := groups[key]
+		if !ok {
+			g = &combined{fm: fm, newest: -1}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		if pos > g.newest {
+			g.fm, g.newest = fm, pos
+		}
+		// The match exists in every commit that has the tree it was found in.
+		g.positions = append(g.positions, h.byTree[h.commits[pos].TreeID]...)
+	}
+
+	results := make([]*FileMatchResolver, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		h := histories[key.repo]
+		sort.Ints(g.positions)
+		var ranges []historyRange
+		for i, pos := range g.positions {
+			if i > 0 && pos <= g.positions[i-1]+1 {
+				ranges[len(ranges)-1].last = h.commits[pos].CommitID
+				continue
+			}
+			ranges = append(ranges, historyRange{first: h.commits[pos].CommitID, last: h.commits[pos].CommitID})
+		}
+		g.fm.historyRanges = ranges
+		results = append(results, g.fm)
+	}
+	return results
+}
+
+// historyRangeResolver resolves a range of commits of a search of the
+// history of files.
+type historyRangeResolver struct {
+	repo *RepositoryResolver
+	r    historyRange
+}
+
+func (r *historyRangeResolver) First() *GitCommitResolver {
+	return &GitCommitResolver{repo: r.repo, includeUserInfo: true, oid: GitObjectID(r.r.first)}
+}
+
+func (r *historyRangeResolver) Last() *GitCommitResolver {
+	return &GitCommitResolver{repo: r.repo, includeUserInfo: true, oid: GitObjectID(r.r.last)}
+}
@@ -0,0 +1,102 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/zoekt"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestSearchFilesInHistory(t *testing.T) {
+	tree := func(b byte) git.OID { return git.OID{b} }
+	git.Mocks.ListCommitTrees = func(since, head string, n uint) ([]git.CommitTree, error) {
+		if since != "v1" || head != "master" {
+			t.Errorf("got range %s..%s, want v1..master", since, head)
+		}
+		return []git.CommitTree{
+			{CommitID: "c1", TreeID: tree(1)},
+			{CommitID: "c2", TreeID: tree(2)},
+			{CommitID: "c3", TreeID: tree(2)},
+			{CommitID: "c4", TreeID: tree(3)},
+			{CommitID: "c5", TreeID: tree(4)},
+		}, nil
+	}
+	defer git.ResetMocks()
+
+	var (
+		mu       sync.Mutex
+		searched []string
+	)
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		mu.Lock()
+		searched = append(searched, rev)
+		mu.Unlock()
+		snippet := "vulnerable()"
+		if rev == "c4" {
+			snippet = "fixed()"
+		}
+		return []*FileMatchResolver{{
+			uri:          "git://" + string(repo.Name) + "?" + rev + "#a.go",
+			Repo:         repo,
+			CommitID:     api.CommitID(rev),
+			JPath:        "a.go",
+			JLineMatches: []*lineMatch{{JPreview: snippet, JLineNumber: int32(len(rev))}},
+		}}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	q, err := query.ParseAndCheck("history:v1..master foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.TextParameters{
+		PatternInfo:  &search.TextPatternInfo{FileMatchLimit: 10, Pattern: "foo"},
+		Repos:        makeRepositoryRevisions("foo/one"),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+		SearcherURLs: endpoint.Static("test"),
+	}
+	results, common, err := searchFilesInHistory(context.Background(), args, "v1..master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(searched) != 4 {
+		t.Errorf("got %d searched commits %v, want one per tree", len(searched), searched)
+	}
+	if len(common.searched) != 1 {
+		t.Errorf("got %d searched repositories, want 1", len(common.searched))
+	}
+	type match struct {
+		Commit api.CommitID
+		Line   string
+		Ranges []historyRange
+	}
+	var got []match
+	for _, fm := range results {
+		got = append(got, match{Commit: fm.CommitID, Line: fm.JLineMatches[0].JPreview, Ranges: fm.historyRanges})
+	}
+	want := map[string]match{
+		"vulnerable()": {Commit: "c5", Line: "vulnerable()", Ranges: []historyRange{{first: "c1", last: "c3"}, {first: "c5", last: "c5"}}},
+		"fixed()":      {Commit: "c4", Line: "fixed()", Ranges: []historyRange{{first: "c4", last: "c4"}}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results %+v, want %d", len(got), got, len(want))
+	}
+	for _, m := range got {
+		if !reflect.DeepEqual(m, want[m.Line]) {
+			t.Errorf("got %+v, want %+v", m, want[m.Line])
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxHunkContextLines is the maximum number of context lines before and after
+// the matched lines of a hunk.
+const maxHunkContextLines = 10
+
+type searchResultHunksArgs struct {
+	ContextLines int32
+}
+
+// Hunks groups the line matches of fm into hunks of consecutive lines, with up
+// to args.ContextLines lines of context before and after each matched line.
+// Line matches whose context lines overlap or are adjacent are in the same
+// hunk, so each line is returned once. If there are context lines, the file
+// is read to get them.
+func (fm *FileMatchResolver) Hunks(ctx context.Context, args *searchResultHunksArgs) ([]*searchResultHunkResolver, error) {
+	if args.ContextLines < 0 || args.ContextLines > maxHunkContextLines {
+		return nil, fmt.Errorf("contextLines must be between 0 and %d", maxHunkContextLines)
+	}
+	if len(fm.JLineMatches) == 0 {
+		return []*searchResultHunkResolver{}, nil
+	}
+
+	var fileLines [][]byte
+	if args.ContextLines > 0 {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		cachedRepo, err := backend.CachedGitRepo(ctx, fm.Repo)
+		if err != nil {
+			return nil, err
+		}
+		content, err := git.ReadFile(ctx, *cachedRepo, fm.CommitID, fm.JPath, 0)
+		if err != nil {
+			return nil, err
+		}
+		fileLines = bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	}
+	return searchResultHunks(fm.JLineMatches, int(args.ContextLines), fileLines), nil
+}
+
+// searchResultHunks returns the hunks of lms with contextLines lines of
+// context from fileLines, the lines of the file. The previews of the line
+// matches are used for the matched lines.
+func searchResultHunks(lms []*lineMatch, contextLines int, fileLines [][]byte) []*searchResultHunkResolver {
+	lms = append([]*lineMatch(nil), lms...)
+	sort.SliceStable(lms, func(i, j int) bool { return lms[i].JLineNumber < lms[j].JLineNumber })
+
+	// contextLine returns the line n of the file as a context line. Like
+	// previews, it is hexadecimal in files with hex previews, and truncated
+	// if it is too long.
+	hex := lms[0].previewHex
+	contextLine := func(n int) string {
+		if n >= len(fileLines) {
+			return ""
+		}
+		if hex {
+			return hexBytes(fileLines[n])
+		}
+		line := fileLines[n]
+		if maxPreviewLength > 0 && utf8.RuneCount(line) > maxPreviewLength {
+			return string([]rune(string(line))[:maxPreviewLength])
+		}
+		return string(line)
+	}
+
+	var (
+		hunks []*searchResultHunkResolver
+		h     *searchResultHunkResolver
+		// end is the last line of h, including its context lines after
+		// the matched lines.
+		end int
+	)
+	// finish appends the context lines after the matched lines of h.
+	finish := func() {
+		for n := h.endLine(); n <= end; n++ {
+			h.lines = append(h.lines, contextLine(n))
+		}
+	}
+	for _, lm := range lms {
+		line := int(lm.JLineNumber)
+		start := line - contextLines
+		if start < 0 {
+			start = 0
+		}
+		if h == nil || start > end+1 {
+			if h != nil {
+				finish()
+			}
+			h = &searchResultHunkResolver{startLine: start}
+			hunks = append(hunks, h)
+		}
+		for n := h.endLine(); n < line; n++ {
+			h.lines = append(h.lines, contextLine(n))
+		}
+		if h.endLine() == line {
+			h.lines = append(h.lines, lm.JPreview)
+		}
+		h.lineMatches = append(h.lineMatches, lm)
+
+		end = line + contextLines
+		if end >= len(fileLines) {
+			end = len(fileLines) - 1
+		}
+		if end < line {
+			end = line
+		}
+	}
+	finish()
+	return hunks
+}
+
+// searchResultHunkResolver is a hunk of the line matches of a file match (see
+// FileMatchResolver.Hunks).
+type searchResultHunkResolver struct {
+	startLine   int
+	lines       []string
+	lineMatches []*lineMatch
+}
+
+// endLine returns the number of the line after h.
+func (h *searchResultHunkResolver) endLine() int {
+	return h.startLine + len(h.lines)
+}
+
+func (h *searchResultHunkResolver) StartLine() int32 { return int32(h.startLine) }
+
+func (h *searchResultHunkResolver) LineCount() int32 { return int32(len(h.lines)) }
+
+func (h *searchResultHunkResolver) Content() string { return strings.Join(h.lines, "\n") }
+
+func (h *searchResultHunkResolver) LineMatches() []*lineMatch { return h.lineMatches }
@@ -0,0 +1,94 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestFileMatchResolver_Hunks(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return []byte("l0\nl1 foo\nl2\nl3 foo\nl4\nl5\nl6\nl7 foo\nl8\n"), nil
+	}
+	defer git.ResetMocks()
+
+	fm := &FileMatchResolver{
+		JPath:    "a.go",
+		Repo:     &types.Repo{Name: "r"},
+		CommitID: "c",
+		JLineMatches: []*lineMatch{
+			{JPreview: "l7 foo", JLineNumber: 7},
+			{JPreview: "l1 foo", JLineNumber: 1},
+			{JPreview: "l3 foo", JLineNumber: 3},
+		},
+	}
+	type hunk struct {
+		startLine, lineCount int32
+		content              string
+		matchedLines         []int32
+	}
+	hunks := func(contextLines int32) []hunk {
+		t.Helper()
+		hs, err := fm.Hunks(context.Background(), &searchResultHunksArgs{ContextLines: contextLines})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []hunk
+		for _, h := range hs {
+			var lines []int32
+			for _, lm := range h.LineMatches() {
+				lines = append(lines, lm.LineNumber())
+			}
+			got = append(got, hunk{h.StartLine(), h.LineCount(), h.Content(), lines})
+		}
+		return got
+	}
+
+	tests := []struct {
+		contextLines int32
+		want         []hunk
+	}{
+		{0, []hunk{
+			{1, 1, "l1 foo", []int32{1}},
+			{3, 1, "l3 foo", []int32{3}},
+			{7, 1, "l7 foo", []int32{7}},
+		}},
+		{1, []hunk{
+			{0, 5, "l0\nl1 foo\nl2\nl3 foo\nl4", []int32{1, 3}},
+			{6, 3, "l6\nl7 foo\nl8", []int32{7}},
+		}},
+		{2, []hunk{
+			{0, 9, "l0\nl1 foo\nl2\nl3 foo\nl4\nl5\nl6\nl7 foo\nl8", []int32{1, 3, 7}},
+		}},
+	}
+	for _, test := range tests {
+		if got := hunks(test.contextLines); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("contextLines %d: got %+v, want %+v", test.contextLines, got, test.want)
+		}
+	}
+
+	if _, err := fm.Hunks(context.Background(), &searchResultHunksArgs{ContextLines: maxHunkContextLines + 1}); err == nil {
+		t.Error("got nil error for too many context lines")
+	}
+}
+
+func TestSearchResultHunks_longContextLines(t *testing.T) {
+	orig := maxPreviewLength
+	maxPreviewLength = 5
+	defer func() { maxPreviewLength = orig }()
+
+	lms := []*lineMatch{{JPreview: "foo", JLineNumber: 1}}
+	fileLines := [][]byte{[]byte(strings.Repeat("x", 10)), []byte("foo")}
+	hs := searchResultHunks(lms, 1, fileLines)
+	if len(hs) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(hs))
+	}
+	if got, want := hs[0].Content(), "xxxxx\nfoo"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
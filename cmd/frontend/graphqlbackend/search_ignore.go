@@ -0,0 +1,113 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/ignore"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxSearchIgnoreFileSize is the maximum size of a search ignore file that is
+// read.
+const maxSearchIgnoreFileSize = 64 << 10
+
+// searchIgnoreCache caches the ignore file matchers of recently searched
+// commits. The contents of a file at a commit never change.
+var (
+	searchIgnoreCacheMu sync.Mutex
+	searchIgnoreCache   = lru.New(1000)
+)
+
+type searchIgnoreKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+}
+
+// filterSearchIgnored removes the file matches whose paths are listed in the
+// ignore file (see ignore.Path) of their repository at the searched commit.
+//
+// Failing to read or parse an ignore file does not fail the search, and the
+// matches in that commit are kept.
+func filterSearchIgnored(ctx context.Context, matches []*FileMatchResolver) []*FileMatchResolver {
+	var (
+		keys []searchIgnoreKey
+		seen = map[searchIgnoreKey]bool{}
+	)
+	for _, fm := range matches {
+		if fm.Repo == nil || fm.CommitID == "" {
+			continue
+		}
+		if key := (searchIgnoreKey{repo: fm.Repo.Name, commit: fm.CommitID}); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return matches
+	}
+
+	var (
+		mu       sync.Mutex
+		run      = parallel.NewRun(revisionResolutionParallelism)
+		matchers = make(map[searchIgnoreKey]*ignore.Matcher, len(keys))
+	)
+	for _, key := range keys {
+		key := key
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			m, err := searchIgnoreMatcher(ctx, key)
+			if err != nil && ctx.Err() == nil {
+				log15.Warn("Failed to read search ignore file", "repo", key.repo, "commit", key.commit, "error", err)
+			}
+			mu.Lock()
+			matchers[key] = m
+			mu.Unlock()
+		})
+	}
+	_ = run.Wait()
+
+	filtered := matches[:0]
+	for _, fm := range matches {
+		if fm.Repo != nil && matchers[searchIgnoreKey{repo: fm.Repo.Name, commit: fm.CommitID}].Match(fm.JPath) {
+			continue
+		}
+		filtered = append(filtered, fm)
+	}
+	return filtered
+}
+
+// searchIgnoreMatcher returns the matcher for the ignore file at the given
+// commit, or nil if the commit has no ignore file.
+func searchIgnoreMatcher(ctx context.Context, key searchIgnoreKey) (*ignore.Matcher, error) {
+	searchIgnoreCacheMu.Lock()
+	v, ok := searchIgnoreCache.Get(key)
+	searchIgnoreCacheMu.Unlock()
+	if ok {
+		return v.(*ignore.Matcher), nil
+	}
+
+	var m *ignore.Matcher
+	data, err := git.ReadFile(ctx, gitserver.Repo{Name: key.repo}, key.commit, ignore.Path, maxSearchIgnoreFileSize)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if m, err = ignore.Parse(data); err != nil {
+			return nil, err
+		}
+	}
+
+	searchIgnoreCacheMu.Lock()
+	searchIgnoreCache.Add(key, m)
+	searchIgnoreCacheMu.Unlock()
+	return m, nil
+}
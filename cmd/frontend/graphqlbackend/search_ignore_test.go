@@ -0,0 +1,43 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/ignore"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestFilterSearchIgnored(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if name != ignore.Path {
+			t.Fatalf("unexpected read of %s", name)
+		}
+		switch commit {
+		case "ignoring":
+			return []byte("vendor/\n*.min.js\n"), nil
+		case "broken":
+			return nil, errors.New("gitserver unavailable")
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	defer git.ResetMocks()
+
+	repo := &types.Repo{Name: "github.com/a/ignore-test"}
+	matches := []*FileMatchResolver{
+		{JPath: "vendor/a.go", Repo: repo, CommitID: "ignoring"},
+		{JPath: "a.go", Repo: repo, CommitID: "ignoring"},
+		{JPath: "app.min.js", Repo: repo, CommitID: "ignoring"},
+		{JPath: "vendor/a.go", Repo: repo, CommitID: "no-ignore-file"},
+		{JPath: "vendor/a.go", Repo: repo, CommitID: "broken"},
+	}
+	want := []*FileMatchResolver{matches[1], matches[3], matches[4]}
+	if got := filterSearchIgnored(context.Background(), matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,205 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/license"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxLicenseHeaderSize is the size of the beginning of a file that is
+	// searched for an SPDX-License-Identifier header.
+	maxLicenseHeaderSize = 2 << 10
+
+	// maxLicenseFileSize is the size of the beginning of a license file that
+	// its license is detected in.
+	maxLicenseFileSize = 64 << 10
+)
+
+// licenseCache caches the licenses of the files and directories of recently
+// searched commits.
+var (
+	licenseCacheMu sync.Mutex
+	licenseCache   = lru.New(10000)
+)
+
+type licenseKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+	path   string
+	dir    bool // whether path is a directory
+}
+
+// fileLicense returns the license expression of the file at path: the
+// SPDX-License-Identifier header of the file, or else the license of its
+// directory (see dirLicense). It is "" if no license is detected.
+func fileLicense(ctx context.Context, repo api.RepoName, commit api.CommitID, name string) (string, error) {
+	return cachedLicense(licenseKey{repo: repo, commit: commit, path: name}, func() (string, error) {
+		data, err := git.ReadFile(ctx, gitserver.Repo{Name: repo}, commit, name, maxLicenseHeaderSize)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if expr := license.Header(data); expr != "" {
+			return expr, nil
+		}
+		return dirLicense(ctx, repo, commit, path.Dir(name))
+	})
+}
+
+// dirLicense returns the license of the license files (such as LICENSE) in
+// the directory, or else of its parent directory. If a directory has several
+// license files with different licenses (e.g. LICENSE-APACHE and
+// LICENSE-MIT), they are alternatives, as in dual licensing.
+func dirLicense(ctx context.Context, repo api.RepoName, commit api.CommitID, dir string) (string, error) {
+	if dir == "." {
+		dir = ""
+	}
+	return cachedLicense(licenseKey{repo: repo, commit: commit, path: dir, dir: true}, func() (string, error) {
+		entries, err := git.ReadDir(ctx, gitserver.Repo{Name: repo}, commit, dir, false)
+		if err != nil {
+			return "", err
+		}
+		ids := map[string]bool{}
+		for _, e := range entries {
+			if e.Mode().IsDir() || !license.IsLicenseFile(e.Name()) {
+				continue
+			}
+			data, err := git.ReadFile(ctx, gitserver.Repo{Name: repo}, commit, e.Name(), maxLicenseFileSize)
+			if err != nil {
+				return "", err
+			}
+			if id := license.Detect(data); id != "" {
+				ids[id] = true
+			}
+		}
+		if len(ids) > 0 {
+			alternatives := make([]string, 0, len(ids))
+			for id := range ids {
+				alternatives = append(alternatives, id)
+			}
+			sort.Strings(alternatives)
+			return strings.Join(alternatives, " OR "), nil
+		}
+		if dir == "" {
+			return "", nil
+		}
+		return dirLicense(ctx, repo, commit, path.Dir(dir))
+	})
+}
+
+// cachedLicense returns the cached license of key, or computes and caches it
+// with f.
+func cachedLicense(key licenseKey, f func() (string, error)) (string, error) {
+	licenseCacheMu.Lock()
+	v, ok := licenseCache.Get(key)
+	licenseCacheMu.Unlock()
+	if ok {
+		return v.(string), nil
+	}
+
+	expr, err := f()
+	if err != nil {
+		return "", err
+	}
+
+	licenseCacheMu.Lock()
+	licenseCache.Add(key, expr)
+	licenseCacheMu.Unlock()
+	return expr, nil
+}
+
+// License returns the SPDX license expression of the file at the searched
+// commit (such as "MIT" or "Apache-2.0 OR MIT"), or nil if no license is
+// detected.
+func (fm *FileMatchResolver) License(ctx context.Context) (*string, error) {
+	if fm.Repo == nil || fm.CommitID == "" {
+		return nil, nil
+	}
+	expr, err := fileLicense(ctx, fm.Repo.Name, fm.CommitID, fm.JPath)
+	if err != nil || expr == "" {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// filterLicenses removes the file matches whose licenses don't satisfy the
+// license: filters of q. A match satisfies them if its license includes one
+// of the licenses of the license: filters and none of the licenses of the
+// -license: filters (see license.Matches).
+//
+// The matches whose licenses can't be detected because of an error are
+// removed.
+func filterLicenses(ctx context.Context, q query.QueryInfo, matches []*FileMatchResolver) []*FileMatchResolver {
+	ids, notIDs := q.StringValues(query.FieldLicense)
+	if len(ids) == 0 && len(notIDs) == 0 {
+		return matches
+	}
+
+	var (
+		run      = parallel.NewRun(revisionResolutionParallelism)
+		licenses = make([]string, len(matches))
+		failed   = make([]bool, len(matches))
+	)
+	for i, fm := range matches {
+		if fm.Repo == nil || fm.CommitID == "" {
+			failed[i] = true
+			continue
+		}
+		i, fm := i, fm
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			expr, err := fileLicense(ctx, fm.Repo.Name, fm.CommitID, fm.JPath)
+			if err != nil {
+				if ctx.Err() == nil {
+					log15.Warn("Failed to detect license", "repo", fm.Repo.Name, "commit", fm.CommitID, "path", fm.JPath, "error", err)
+				}
+				failed[i] = true
+				return
+			}
+			licenses[i] = expr
+		})
+	}
+	_ = run.Wait()
+
+	filtered := matches[:0]
+	for i, fm := range matches {
+		if failed[i] || !matchesLicenses(licenses[i], ids, notIDs) {
+			continue
+		}
+		filtered = append(filtered, fm)
+	}
+	return filtered
+}
+
+// matchesLicenses reports whether the license expression expr includes one
+// of ids (if there are any) and none of notIDs.
+func matchesLicenses(expr string, ids, notIDs []string) bool {
+	for _, id := range notIDs {
+		if license.Matches(expr, id) {
+			return false
+		}
+	}
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		if license.Matches(expr, id) {
+			return true
+		}
+	}
+	return false
+}
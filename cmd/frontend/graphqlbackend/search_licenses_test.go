@@ -0,0 +1,89 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/util"
+)
+
+func TestFilterLicenses(t *testing.T) {
+	files := map[string]string{
+		"LICENSE":                   "Permission is hereby granted, free of charge, to any person... The above copyright notice and this permission notice shall be included in all copies",
+		"a.go":                      "package a",
+		"spdx.go":                   "// SPDX-License-Identifier: GPL-2.0-only\npackage a",
+		"vendor/lib/LICENSE-APACHE": "Apache License\nVersion 2.0, January 2004",
+		"vendor/lib/LICENSE-MIT":    "Permission is hereby granted, free of charge... The above copyright notice and this permission notice shall be included",
+		"vendor/lib/src/lib.rs":     "fn main() {}",
+		"third_party/x/x.c":         "int main() {}",
+		"third_party/x/COPYING":     "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007",
+	}
+	dirs := map[string][]string{
+		"":               {"LICENSE", "a.go", "spdx.go"},
+		"vendor":         {},
+		"vendor/lib":     {"vendor/lib/LICENSE-APACHE", "vendor/lib/LICENSE-MIT"},
+		"vendor/lib/src": {"vendor/lib/src/lib.rs"},
+		"third_party":    {},
+		"third_party/x":  {"third_party/x/x.c", "third_party/x/COPYING"},
+	}
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return []byte(data), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	git.Mocks.ReadDir = func(commit api.CommitID, name string, recurse bool) ([]os.FileInfo, error) {
+		var fis []os.FileInfo
+		for _, entry := range dirs[name] {
+			fis = append(fis, &util.FileInfo{Name_: entry})
+		}
+		return fis, nil
+	}
+	defer git.ResetMocks()
+
+	repo := &types.Repo{Name: "github.com/a/license-test"}
+	matches := []*FileMatchResolver{
+		{JPath: "a.go", Repo: repo, CommitID: "c"},
+		{JPath: "spdx.go", Repo: repo, CommitID: "c"},
+		{JPath: "vendor/lib/src/lib.rs", Repo: repo, CommitID: "c"},
+		{JPath: "third_party/x/x.c", Repo: repo, CommitID: "c"},
+	}
+
+	var licenses []string
+	for _, fm := range matches {
+		l, err := fm.License(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		licenses = append(licenses, *l)
+	}
+	if want := []string{"MIT", "GPL-2.0-only", "Apache-2.0 OR MIT", "GPL-3.0"}; !reflect.DeepEqual(licenses, want) {
+		t.Errorf("got licenses %q, want %q", licenses, want)
+	}
+
+	tests := []struct {
+		query string
+		want  []*FileMatchResolver
+	}{
+		{query: "foo", want: matches},
+		{query: "foo license:mit", want: []*FileMatchResolver{matches[0], matches[2]}},
+		{query: "foo license:GPL-2.0-only license:GPL-3.0", want: []*FileMatchResolver{matches[1], matches[3]}},
+		{query: "foo -license:MIT", want: []*FileMatchResolver{matches[1], matches[3]}},
+	}
+	for _, test := range tests {
+		q, err := query.ParseAndCheck(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := append([]*FileMatchResolver{}, matches...)
+		if got := filterLicenses(context.Background(), q, input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.query, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/graphqlutil"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// lineMatchesQuery is what is needed to search a file of a FileMatchResolver
+// again, to get more line matches than searcher returns by default.
+type lineMatchesQuery struct {
+	searcherURLs *endpoint.Map
+	repo         gitserver.Repo
+	info         *search.TextPatternInfo
+	fetchTimeout time.Duration
+}
+
+// search returns up to limit line matches in fm's file, and whether there are
+// more.
+func (q *lineMatchesQuery) search(ctx context.Context, fm *FileMatchResolver, limit int) ([]*lineMatch, bool, error) {
+	// fm's file is known to match all the path filters of the query, so they
+	// are replaced by one that only matches it.
+	info := *q.info
+	info.IncludePatterns = []string{"^" + regexp.QuoteMeta(fm.JPath) + "$"}
+	info.ExcludePattern = ""
+	info.PathPatternsAreRegExps = true
+	info.PathPatternsAreCaseSensitive = true
+	info.PatternMatchesContent = true
+	info.PatternMatchesPath = false
+	info.FileMatchLimit = 1
+	info.CountOnly = false
+	info.MaxLineMatches = limit
+
+	matches, _, err := textSearch(ctx, q.searcherURLs, q.repo, fm.CommitID, &info, q.fetchTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, m := range matches {
+		if m.JPath == fm.JPath {
+			m.Repo, m.CommitID = fm.Repo, fm.CommitID
+			truncatePreviews(m)
+			return m.JLineMatches, m.JLimitHit, nil
+		}
+	}
+	// This should not happen, since fm was found by the same query.
+	return fm.JLineMatches, fm.JLimitHit, nil
+}
+
+type lineMatchesConnectionArgs struct {
+	First int32
+	After *string
+}
+
+func (fm *FileMatchResolver) LineMatchesConnection(ctx context.Context, args *lineMatchesConnectionArgs) (*lineMatchConnectionResolver, error) {
+	if args.First < 0 {
+		return nil, errors.New("first must be non-negative")
+	}
+	var offset int
+	if args.After != nil {
+		n, err := strconv.Atoi(*args.After)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid cursor %q", *args.After)
+		}
+		offset = n
+	}
+	end := offset + int(args.First)
+	if end > protocol.MaxLineMatchesLimit {
+		end = protocol.MaxLineMatchesLimit
+	}
+
+	matches, limitHit := fm.JLineMatches, fm.JLimitHit
+	canSearch := fm.lineMatchesQuery != nil
+	if limitHit && canSearch && end > len(matches) {
+		var err error
+		matches, limitHit, err = fm.lineMatchesQuery.search(ctx, fm, end)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := &lineMatchConnectionResolver{
+		hasNextPage: end < len(matches) || (limitHit && canSearch && end < protocol.MaxLineMatchesLimit),
+		end:         end,
+	}
+	if offset < len(matches) {
+		if end > len(matches) {
+			end = len(matches)
+		}
+		r.nodes = matches[offset:end]
+	}
+	if !limitHit {
+		n := int32(len(matches))
+		r.totalCount = &n
+	}
+	return r, nil
+}
+
+type lineMatchConnectionResolver struct {
+	nodes       []*lineMatch
+	totalCount  *int32
+	hasNextPage bool
+	end         int
+}
+
+func (r *lineMatchConnectionResolver) Nodes() []*lineMatch { return r.nodes }
+
+func (r *lineMatchConnectionResolver) TotalCount() *int32 { return r.totalCount }
+
+func (r *lineMatchConnectionResolver) PageInfo() *graphqlutil.PageInfo {
+	if !r.hasNextPage {
+		return graphqlutil.HasNextPage(false)
+	}
+	return graphqlutil.NextPageCursor(strconv.Itoa(r.end))
+}
@@ -0,0 +1,90 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestFileMatchResolver_LineMatchesConnection(t *testing.T) {
+	const total = 250
+	allLineMatches := make([]*lineMatch, total)
+	for i := range allLineMatches {
+		allLineMatches[i] = &lineMatch{JLineNumber: int32(i)}
+	}
+
+	var searches []*search.TextPatternInfo
+	mockTextSearch = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration) ([]*FileMatchResolver, bool, error) {
+		searches = append(searches, p)
+		n := total
+		if p.MaxLineMatches < n {
+			n = p.MaxLineMatches
+		}
+		return []*FileMatchResolver{{JPath: "a/b.go", JLineMatches: allLineMatches[:n], JLimitHit: n < total}}, false, nil
+	}
+	defer func() { mockTextSearch = nil }()
+
+	fm := &FileMatchResolver{
+		JPath:            "a/b.go",
+		JLineMatches:     allLineMatches[:100],
+		JLimitHit:        true,
+		lineMatchesQuery: &lineMatchesQuery{info: &search.TextPatternInfo{Pattern: "x", IncludePatterns: []string{`\.go$`}, PatternMatchesPath: true}},
+	}
+	page := func(first int32, after *string) *lineMatchConnectionResolver {
+		t.Helper()
+		r, err := fm.LineMatchesConnection(context.Background(), &lineMatchesConnectionArgs{First: first, After: after})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// The first page is served from the matches the file match already has.
+	r := page(100, nil)
+	if len(searches) != 0 {
+		t.Fatalf("got %d searches, want 0", len(searches))
+	}
+	if !reflect.DeepEqual(r.Nodes(), allLineMatches[:100]) || r.TotalCount() != nil || !r.PageInfo().HasNextPage() {
+		t.Fatalf("got %d nodes (totalCount %v, hasNextPage %v), want 100 nodes with a next page", len(r.Nodes()), r.TotalCount(), r.PageInfo().HasNextPage())
+	}
+
+	// The next page searches the file again.
+	r = page(100, r.PageInfo().EndCursor())
+	if len(searches) != 1 {
+		t.Fatalf("got %d searches, want 1", len(searches))
+	}
+	if got := searches[0]; got.MaxLineMatches != 200 || !reflect.DeepEqual(got.IncludePatterns, []string{`^a/b\.go$`}) || got.PatternMatchesPath {
+		t.Errorf("got search %s, want one for only the line matches in a/b.go", got)
+	}
+	if !reflect.DeepEqual(r.Nodes(), allLineMatches[100:200]) || !r.PageInfo().HasNextPage() {
+		t.Fatalf("got %d nodes (hasNextPage %v), want nodes 100-200 with a next page", len(r.Nodes()), r.PageInfo().HasNextPage())
+	}
+
+	// The last page has the total count.
+	r = page(100, r.PageInfo().EndCursor())
+	if !reflect.DeepEqual(r.Nodes(), allLineMatches[200:]) || r.PageInfo().HasNextPage() {
+		t.Errorf("got %d nodes (hasNextPage %v), want the last 50 nodes", len(r.Nodes()), r.PageInfo().HasNextPage())
+	}
+	if r.TotalCount() == nil || *r.TotalCount() != total {
+		t.Errorf("got totalCount %v, want %d", r.TotalCount(), total)
+	}
+
+	// File matches without a query can only be paginated through the line
+	// matches they have.
+	fm.lineMatchesQuery = nil
+	after := "50"
+	r = page(100, &after)
+	if !reflect.DeepEqual(r.Nodes(), allLineMatches[50:100]) || r.PageInfo().HasNextPage() {
+		t.Errorf("got %d nodes (hasNextPage %v), want the last 50 nodes", len(r.Nodes()), r.PageInfo().HasNextPage())
+	}
+
+	bad := "x"
+	if _, err := fm.LineMatchesConnection(context.Background(), &lineMatchesConnectionArgs{First: 1, After: &bad}); err == nil {
+		t.Error("got no error for invalid cursor")
+	}
+}
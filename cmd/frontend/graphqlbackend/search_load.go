@@ -0,0 +1,62 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+)
+
+// loadGauge is a Prometheus gauge whose current value can also be read back,
+// so that it can be reported by the site.searchLoad GraphQL field.
+type loadGauge struct {
+	n     int64
+	gauge prometheus.Gauge
+}
+
+func (g *loadGauge) add(delta int) {
+	atomic.AddInt64(&g.n, int64(delta))
+	g.gauge.Add(float64(delta))
+}
+
+func (g *loadGauge) value() int32 {
+	return int32(atomic.LoadInt64(&g.n))
+}
+
+var (
+	activeSearches = &loadGauge{gauge: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_graphql_search_active",
+		Help: "Number of searches currently executing.",
+	})}
+	queuedSearchRepos = &loadGauge{gauge: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_graphql_search_repos_queued",
+		Help: "Number of repositories, across all executing searches, waiting for a searcher request slot.",
+	})}
+	activeSearchRepos = &loadGauge{gauge: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_graphql_search_repos_active",
+		Help: "Number of repository revisions, across all executing searches, currently being searched by searcher.",
+	})}
+)
+
+func (r *siteResolver) SearchLoad(ctx context.Context) (*searchLoadResolver, error) {
+	// 🚨 SECURITY: Only site admins may view the search load.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return &searchLoadResolver{}, nil
+}
+
+type searchLoadResolver struct{}
+
+func (searchLoadResolver) ActiveSearches() int32 { return activeSearches.value() }
+
+func (searchLoadResolver) QueuedRepositories() int32 { return queuedSearchRepos.value() }
+
+func (searchLoadResolver) ActiveRepositories() int32 { return activeSearchRepos.value() }
+
+func (searchLoadResolver) SearcherRequestLimit() int32 {
+	limit, _ := textSearchLimiter.GetLimit()
+	return int32(limit)
+}
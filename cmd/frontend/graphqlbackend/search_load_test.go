@@ -0,0 +1,20 @@
+package graphqlbackend
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLoadGauge(t *testing.T) {
+	g := &loadGauge{gauge: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})}
+	g.add(3)
+	g.add(-1)
+	if got, want := g.value(), int32(2); got != want {
+		t.Errorf("got value %d, want %d", got, want)
+	}
+	if got, want := testutil.ToFloat64(g.gauge), 2.0; got != want {
+		t.Errorf("got gauge %v, want %v", got, want)
+	}
+}
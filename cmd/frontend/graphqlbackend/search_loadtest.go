@@ -0,0 +1,371 @@
+package graphqlbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+// searchLoadTestOptions configure a load test of the search pipeline.
+type searchLoadTestOptions struct {
+	// Queries are the queries of the query mix, run in turn.
+	Queries []string
+
+	// RepoSetSizes are the numbers of repositories (the first ones by name)
+	// that each query of the query mix searches, in turn. 0 means all
+	// repositories.
+	RepoSetSizes []int
+
+	// Concurrency is the number of searches to run at the same time.
+	Concurrency int
+
+	// Searches is the number of searches to run, and Duration for how long
+	// to run searches. The load test stops when either is reached (if set).
+	Searches int
+	Duration time.Duration
+
+	// Matches is the number of file matches the fake searcher returns for
+	// each repository, after Latency.
+	Matches int
+	Latency time.Duration
+}
+
+const searchLoadTestUsage = `POST to run a search load test against the search pipeline of this frontend
+instance, with a fake searcher and the internal actor. The parameters are:
+
+  q            a query of the query mix (repeatable, required)
+  repos        a number of repositories to search with each query (repeatable, default all)
+  concurrency  the number of concurrent searches (default 4)
+  searches     the number of searches to run (default 100, unless duration is set)
+  duration     for how long to run searches, e.g. 10m for a soak test
+  matches      the number of file matches of the fake searcher per repository (default 10)
+  latency      the latency of the fake searcher, e.g. 50ms (default 0)
+
+Queries are run with index:no, so that all repositories are searched by the
+fake searcher. Repository revisions are still resolved by gitserver.
+`
+
+func parseSearchLoadTestOptions(r *http.Request) (*searchLoadTestOptions, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	opts := &searchLoadTestOptions{
+		Queries:     r.Form["q"],
+		Concurrency: 4,
+		Matches:     10,
+	}
+	if len(opts.Queries) == 0 {
+		return nil, errors.New("at least one query (q) is required")
+	}
+	for _, v := range r.Form["repos"] {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid repos %q", v)
+		}
+		opts.RepoSetSizes = append(opts.RepoSetSizes, n)
+	}
+	if len(opts.RepoSetSizes) == 0 {
+		opts.RepoSetSizes = []int{0}
+	}
+
+	for name, p := range map[string]*int{"concurrency": &opts.Concurrency, "searches": &opts.Searches, "matches": &opts.Matches} {
+		if v := r.Form.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return nil, errors.Errorf("invalid %s %q", name, v)
+			}
+			*p = n
+		}
+	}
+	for name, p := range map[string]*time.Duration{"duration": &opts.Duration, "latency": &opts.Latency} {
+		if v := r.Form.Get(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				return nil, errors.Errorf("invalid %s %q", name, v)
+			}
+			*p = d
+		}
+	}
+	if opts.Concurrency == 0 {
+		return nil, errors.New("concurrency must be at least 1")
+	}
+	if opts.Searches == 0 && opts.Duration == 0 {
+		opts.Searches = 100
+	}
+	return opts, nil
+}
+
+// searchLoadTestWorkload is a query of the query mix run against a repository
+// set size.
+type searchLoadTestWorkload struct {
+	Query       string
+	RepoSetSize int
+}
+
+func (opts *searchLoadTestOptions) workloads() []searchLoadTestWorkload {
+	var ws []searchLoadTestWorkload
+	for _, n := range opts.RepoSetSizes {
+		for _, q := range opts.Queries {
+			ws = append(ws, searchLoadTestWorkload{Query: q, RepoSetSize: n})
+		}
+	}
+	return ws
+}
+
+type searchLoadTestLatencies struct {
+	P50, P90, P99, Max float64 // milliseconds
+}
+
+type searchLoadTestWorkloadReport struct {
+	searchLoadTestWorkload
+	Searches int
+	Errors   int
+	// Error is the first error of the searches, if any.
+	Error   string `json:",omitempty"`
+	Results int
+	Latency searchLoadTestLatencies
+
+	durations []time.Duration
+}
+
+type searchLoadTestReport struct {
+	Searches        int
+	Errors          int
+	DurationSeconds float64
+	Throughput      float64 // searches per second
+	Latency         searchLoadTestLatencies
+	Workloads       []*searchLoadTestWorkloadReport
+}
+
+// latencies returns the latency percentiles (nearest rank) of durations, which
+// it sorts.
+func latencies(durations []time.Duration) searchLoadTestLatencies {
+	if len(durations) == 0 {
+		return searchLoadTestLatencies{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	percentile := func(p int) float64 {
+		i := (p*len(durations)+99)/100 - 1
+		return float64(durations[i]) / float64(time.Millisecond)
+	}
+	return searchLoadTestLatencies{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: percentile(100),
+	}
+}
+
+// runSearchLoadTest runs the workloads of opts in turn with search, which
+// returns the number of results of a search, and reports their latencies.
+func runSearchLoadTest(ctx context.Context, opts *searchLoadTestOptions, search func(ctx context.Context, w searchLoadTestWorkload) (results int, err error)) *searchLoadTestReport {
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	workloads := opts.workloads()
+	reports := make([]*searchLoadTestWorkloadReport, len(workloads))
+	for i, w := range workloads {
+		reports[i] = &searchLoadTestWorkloadReport{searchLoadTestWorkload: w}
+	}
+
+	var (
+		mu   sync.Mutex
+		next int64 = -1
+		wg   sync.WaitGroup
+	)
+	start := time.Now()
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if (opts.Searches > 0 && i >= opts.Searches) || ctx.Err() != nil {
+					return
+				}
+				report := reports[i%len(reports)]
+
+				searchStart := time.Now()
+				results, err := search(ctx, report.searchLoadTestWorkload)
+				d := time.Since(searchStart)
+				// Searches interrupted by the end of the load test do not
+				// count.
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				report.Searches++
+				report.Results += results
+				report.durations = append(report.durations, d)
+				if err != nil {
+					if report.Errors == 0 {
+						report.Error = err.Error()
+					}
+					report.Errors++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := &searchLoadTestReport{
+		DurationSeconds: elapsed.Seconds(),
+		Workloads:       reports,
+	}
+	var durations []time.Duration
+	for _, r := range reports {
+		report.Searches += r.Searches
+		report.Errors += r.Errors
+		durations = append(durations, r.durations...)
+		r.Latency = latencies(r.durations)
+	}
+	report.Latency = latencies(durations)
+	if elapsed > 0 {
+		report.Throughput = float64(report.Searches) / elapsed.Seconds()
+	}
+	return report
+}
+
+// loadTestSearcherClient is the fake searcher of search load tests. It
+// returns the same number of synthetic file matches for every repository.
+type loadTestSearcherClient struct {
+	matches int
+	latency time.Duration
+}
+
+func (c *loadTestSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	if c.latency > 0 {
+		t := time.NewTimer(c.latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, false, false, ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	n := c.matches
+	if r.FileMatchLimit > 0 && n > r.FileMatchLimit {
+		n, limitHit = r.FileMatchLimit, true
+	}
+	fms := make([]protocol.FileMatch, n)
+	for i := range fms {
+		fms[i] = protocol.FileMatch{
+			Path:        fmt.Sprintf("loadtest/file%d.go", i),
+			LineMatches: []protocol.LineMatch{{Preview: r.Pattern, LineNumber: i, OffsetAndLengths: [][2]int{{0, len(r.Pattern)}}}},
+		}
+	}
+	return fileMatchesFromProtocol(fms), limitHit, true, nil
+}
+
+// searchLoadTestRepoFilters returns the repo: filters that restrict searches
+// to the first repositories by name, for each repository set size.
+func searchLoadTestRepoFilters(ctx context.Context, sizes []int) (map[int]string, error) {
+	max := 0
+	for _, n := range sizes {
+		if n > max {
+			max = n
+		}
+	}
+	filters := map[int]string{0: ""}
+	if max == 0 {
+		return filters, nil
+	}
+
+	repos, err := db.Repos.List(ctx, db.ReposListOptions{
+		OrderBy:     db.RepoListOrderBy{{Field: db.RepoListName}},
+		LimitOffset: &db.LimitOffset{Limit: max},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range sizes {
+		if n > len(repos) {
+			return nil, errors.Errorf("repository set size %d is larger than the number of repositories (%d)", n, len(repos))
+		}
+		names := make([]string, n)
+		for i, repo := range repos[:n] {
+			names[i] = regexp.QuoteMeta(string(repo.Name))
+		}
+		filters[n] = ` repo:^(` + strings.Join(names, "|") + `)$`
+	}
+	return filters, nil
+}
+
+// searchLoadTestRunning is 1 while a search load test runs.
+var searchLoadTestRunning int32
+
+// SearchLoadTestHandler returns the handler of the search load test debug
+// endpoint, which runs searches of a configurable query mix through the search
+// pipeline with a fake searcher (to validate the capacity of the frontend, for
+// example before upgrades) and reports their throughput and latencies. Only one
+// load test runs at a time.
+func SearchLoadTestHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(searchLoadTestUsage))
+			return
+		}
+		opts, err := parseSearchLoadTestOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !atomic.CompareAndSwapInt32(&searchLoadTestRunning, 0, 1) {
+			http.Error(w, "a search load test is already running", http.StatusConflict)
+			return
+		}
+		defer atomic.StoreInt32(&searchLoadTestRunning, 0)
+
+		ctx := actor.WithActor(r.Context(), &actor.Actor{Internal: true})
+		repoFilters, err := searchLoadTestRepoFilters(ctx, opts.RepoSetSizes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx = withSearcherClient(ctx, &loadTestSearcherClient{matches: opts.Matches, latency: opts.Latency})
+
+		report := runSearchLoadTest(ctx, opts, func(ctx context.Context, wl searchLoadTestWorkload) (int, error) {
+			sr, err := NewSearchImplementer(&SearchArgs{
+				Version: "V2",
+				Query:   wl.Query + " index:no" + repoFilters[wl.RepoSetSize],
+			})
+			if err != nil {
+				return 0, err
+			}
+			results, err := sr.Results(ctx)
+			if err != nil {
+				return 0, err
+			}
+			return len(results.SearchResults), nil
+		})
+
+		p, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			http.Error(w, "failed to marshal report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(p)
+	})
+}
@@ -0,0 +1,96 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+func TestParseSearchLoadTestOptions(t *testing.T) {
+	r := httptest.NewRequest("POST", "/search-load-test?q=a&q=b&repos=0&repos=10&concurrency=8&duration=1m&latency=50ms", nil)
+	got, err := parseSearchLoadTestOptions(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &searchLoadTestOptions{
+		Queries:      []string{"a", "b"},
+		RepoSetSizes: []int{0, 10},
+		Concurrency:  8,
+		Duration:     time.Minute,
+		Matches:      10,
+		Latency:      50 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	for _, q := range []string{"", "q=a&concurrency=0", "q=a&repos=-1", "q=a&latency=x"} {
+		if _, err := parseSearchLoadTestOptions(httptest.NewRequest("POST", "/search-load-test?"+q, nil)); err == nil {
+			t.Errorf("%q: got nil error", q)
+		}
+	}
+}
+
+func TestRunSearchLoadTest(t *testing.T) {
+	opts := &searchLoadTestOptions{
+		Queries:      []string{"a", "b"},
+		RepoSetSizes: []int{1, 2},
+		Concurrency:  3,
+		Searches:     10,
+	}
+	report := runSearchLoadTest(context.Background(), opts, func(ctx context.Context, w searchLoadTestWorkload) (int, error) {
+		if w.Query == "b" && w.RepoSetSize == 2 {
+			return 0, errors.New("boom")
+		}
+		return w.RepoSetSize, nil
+	})
+
+	if report.Searches != 10 || report.Errors != 2 {
+		t.Errorf("got %d searches with %d errors, want 10 with 2", report.Searches, report.Errors)
+	}
+	var got []string
+	for _, w := range report.Workloads {
+		got = append(got, fmt.Sprintf("%s/%d/%d/%d/%s", w.Query, w.RepoSetSize, w.Searches, w.Results, w.Error))
+	}
+	want := []string{"a/1/3/3/", "b/1/3/3/", "a/2/2/4/", "b/2/2/0/boom"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got workloads %q, want %q", got, want)
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	if got, want := latencies(durations), (searchLoadTestLatencies{P50: 50, P90: 90, P99: 99, Max: 100}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := latencies(nil); got != (searchLoadTestLatencies{}) {
+		t.Errorf("got %+v for no durations", got)
+	}
+}
+
+func TestLoadTestSearcherClient(t *testing.T) {
+	c := &loadTestSearcherClient{matches: 5}
+	ctx := withSearcherClient(context.Background(), c)
+	client, ok := searcherClientFromContext(ctx)
+	if !ok || client != c {
+		t.Fatal("got the default searcher client, want the one of the context")
+	}
+
+	matches, limitHit, _, err := client.Search(ctx, "", &protocol.Request{PatternInfo: protocol.PatternInfo{Pattern: "foo", FileMatchLimit: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 || !limitHit || !strings.HasPrefix(matches[0].JPath, "loadtest/") {
+		t.Errorf("got %d matches (limitHit %v), want 3 synthetic matches with the limit hit", len(matches), limitHit)
+	}
+}
@@ -0,0 +1,47 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// Locations returns a location for each match of lm. Their URLs link to the
+// match in the file (e.g. ".../-/blob/a.go#L12:5-12:20"), in the format of the
+// URLs of the web app, so clients can copy a link to a match without
+// building it themselves.
+//
+// Like in the web app's URLs (and in LSP), characters are counted in UTF-16
+// code units. Lines with hexadecimal previews link to the whole line.
+func (lm *lineMatch) Locations(ctx context.Context) ([]LocationResolver, error) {
+	if lm.file == nil || lm.file.Repo == nil {
+		return []LocationResolver{}, nil
+	}
+	resource := lm.file.File()
+	line := int(lm.JLineNumber)
+	if lm.previewHex {
+		r := lsp.Range{Start: lsp.Position{Line: line}, End: lsp.Position{Line: line}}
+		return []LocationResolver{NewLocationResolver(resource, &r)}, nil
+	}
+
+	text, offsets := lm.JPreview, lm.JOffsetAndLengths
+	if lm.previewTruncated {
+		var err error
+		if text, err = lm.Line(ctx); err != nil {
+			return nil, err
+		}
+		offsets = lm.lineOffsetAndLengths
+	}
+	offsets = search.ConvertOffsets(text, offsets, search.OffsetUnitUTF16)
+
+	locations := make([]LocationResolver, len(offsets))
+	for i, ol := range offsets {
+		r := lsp.Range{
+			Start: lsp.Position{Line: line, Character: int(ol[0])},
+			End:   lsp.Position{Line: line, Character: int(ol[0] + ol[1])},
+		}
+		locations[i] = NewLocationResolver(resource, &r)
+	}
+	return locations, nil
+}
@@ -0,0 +1,69 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestLineMatch_Locations(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return []byte("x\n😀 foo bar foo\n"), nil
+	}
+	defer git.ResetMocks()
+
+	rev := "master"
+	fm := &FileMatchResolver{
+		JPath:        "a.go",
+		Repo:         &types.Repo{Name: "github.com/a/b"},
+		CommitID:     "c1",
+		InputRev:     &rev,
+		JLineMatches: []*lineMatch{{JPreview: "😀 foo bar foo", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{2, 3}, {10, 3}}}},
+	}
+	truncatePreviews(fm)
+	lm := fm.JLineMatches[0]
+
+	urls := func() (urls, canonicalURLs []string) {
+		t.Helper()
+		locations, err := lm.Locations(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range locations {
+			url, err := l.URL(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			canonicalURL, err := l.CanonicalURL()
+			if err != nil {
+				t.Fatal(err)
+			}
+			urls = append(urls, url)
+			canonicalURLs = append(canonicalURLs, canonicalURL)
+		}
+		return urls, canonicalURLs
+	}
+
+	gotURLs, gotCanonicalURLs := urls()
+	if want := []string{"/github.com/a/b@master/-/blob/a.go#L2:4-2:7", "/github.com/a/b@master/-/blob/a.go#L2:12-2:15"}; !reflect.DeepEqual(gotURLs, want) {
+		t.Errorf("got URLs %v, want %v", gotURLs, want)
+	}
+	if want := []string{"/github.com/a/b@c1/-/blob/a.go#L2:4-2:7", "/github.com/a/b@c1/-/blob/a.go#L2:12-2:15"}; !reflect.DeepEqual(gotCanonicalURLs, want) {
+		t.Errorf("got canonical URLs %v, want %v", gotCanonicalURLs, want)
+	}
+
+	// Truncated previews link to the matches in the full line.
+	lm.truncatePreview(fm, 5)
+	if gotURLs, _ = urls(); len(gotURLs) != 2 || gotURLs[1] != "/github.com/a/b@master/-/blob/a.go#L2:12-2:15" {
+		t.Errorf("got URLs %v for truncated preview", gotURLs)
+	}
+
+	lm.previewHex = true
+	if gotURLs, _ = urls(); !reflect.DeepEqual(gotURLs, []string{"/github.com/a/b@master/-/blob/a.go#L2"}) {
+		t.Errorf("got URLs %v for hex preview, want the line", gotURLs)
+	}
+}
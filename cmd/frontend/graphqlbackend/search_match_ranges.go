@@ -0,0 +1,51 @@
+package graphqlbackend
+
+import (
+	"github.com/sourcegraph/go-langserver/pkg/lsp"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// MatchRanges returns the ranges of the matches in the file. Unlike the line
+// matches, a match that spans multiple lines is a single range.
+//
+// Searcher returns the ranges of regexp and structural matches. For other
+// results (such as those of indexed searches), they are derived from the line
+// matches, with one single-line range per match in a line.
+func (fm *FileMatchResolver) MatchRanges() []*rangeResolver {
+	ranges := fm.JMatchRanges
+	if len(ranges) == 0 {
+		ranges = lineMatchRanges(fm.JLineMatches)
+	}
+	resolvers := make([]*rangeResolver, len(ranges))
+	for i, r := range ranges {
+		resolvers[i] = &rangeResolver{lspRange: lsp.Range{
+			Start: lsp.Position{Line: r.Start.Line, Character: r.Start.Character},
+			End:   lsp.Position{Line: r.End.Line, Character: r.End.Character},
+		}}
+	}
+	return resolvers
+}
+
+// lineMatchRanges returns a range for each match in lms.
+func lineMatchRanges(lms []*lineMatch) []protocol.Range {
+	var ranges []protocol.Range
+	for _, lm := range lms {
+		if lm.previewHex {
+			// The offsets are in the hexadecimal preview, not in
+			// characters of the line.
+			continue
+		}
+		offsets := lm.JOffsetAndLengths
+		if lm.previewTruncated {
+			offsets = lm.lineOffsetAndLengths
+		}
+		line := int(lm.JLineNumber)
+		for _, ol := range offsets {
+			ranges = append(ranges, protocol.Range{
+				Start: protocol.Position{Line: line, Character: int(ol[0])},
+				End:   protocol.Position{Line: line, Character: int(ol[0] + ol[1])},
+			})
+		}
+	}
+	return ranges
+}
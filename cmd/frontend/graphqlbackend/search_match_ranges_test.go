@@ -0,0 +1,44 @@
+package graphqlbackend
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFileMatchResolver_MatchRanges(t *testing.T) {
+	type rng struct{ startLine, startCharacter, endLine, endCharacter int32 }
+	ranges := func(fm *FileMatchResolver) []rng {
+		var got []rng
+		for _, r := range fm.MatchRanges() {
+			got = append(got, rng{r.Start().Line(), r.Start().Character(), r.End().Line(), r.End().Character()})
+		}
+		return got
+	}
+
+	// Multi-line matches returned by searcher.
+	fm := &FileMatchResolver{
+		JLineMatches: []*lineMatch{
+			{JPreview: "foo(", JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 4}}},
+			{JPreview: ")", JLineNumber: 4, JOffsetAndLengths: [][2]int32{{0, 1}}},
+		},
+	}
+	if err := json.Unmarshal([]byte(`{"MatchRanges":[{"Start":{"Line":3},"End":{"Line":4,"Character":1}}]}`), fm); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ranges(fm), []rng{{3, 0, 4, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Line matches without ranges (e.g. from zoekt).
+	fm = &FileMatchResolver{
+		JLineMatches: []*lineMatch{
+			{JPreview: "foo foo", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{0, 3}, {4, 3}}},
+			{JPreview: "x foo", JLineNumber: 5, JOffsetAndLengths: [][2]int32{{2, 3}}},
+		},
+	}
+	truncatePreviews(fm)
+	if got, want := ranges(fm), []rng{{1, 0, 1, 3}, {1, 4, 1, 7}, {5, 2, 5, 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package graphqlbackend
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var searchResultsMemoryBudgetMB = env.Get("SEARCH_RESULTS_MEMORY_BUDGET_MB", "1024", "maximum size in MB of the file matches buffered by all in-flight searches of this frontend, after which searches return the results they have so far (0 means no limit)")
+
+var (
+	searchResultsMemoryBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "src_graphql_search_results_memory_bytes",
+		Help: "Approximate size of the file matches buffered by in-flight searches.",
+	})
+	searchResultsMemoryShed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "src_graphql_search_results_memory_shed_total",
+		Help: "Number of searches truncated because SEARCH_RESULTS_MEMORY_BUDGET_MB was exceeded.",
+	})
+)
+
+// searchResultsMemory tracks the (approximate) memory used by the file
+// matches that in-flight searches have buffered, so that correlated huge
+// searches cannot exhaust the memory of the frontend.
+type searchResultsMemory struct {
+	budget int64 // in bytes, 0 if there is no limit
+
+	mu   sync.Mutex
+	used int64
+}
+
+var (
+	searchResultsMemoryOnce   sync.Once
+	searchResultsMemoryBudget *searchResultsMemory
+)
+
+// getSearchResultsMemory returns the memory budget configured by
+// SEARCH_RESULTS_MEMORY_BUDGET_MB.
+func getSearchResultsMemory() *searchResultsMemory {
+	searchResultsMemoryOnce.Do(func() {
+		mb, err := strconv.ParseInt(searchResultsMemoryBudgetMB, 10, 64)
+		if err != nil || mb < 0 {
+			log15.Error("Invalid SEARCH_RESULTS_MEMORY_BUDGET_MB, disabling the search results memory budget", "value", searchResultsMemoryBudgetMB, "error", err)
+			mb = 0
+		}
+		searchResultsMemoryBudget = &searchResultsMemory{budget: mb << 20}
+	})
+	return searchResultsMemoryBudget
+}
+
+// reservation returns a new reservation of memory for the results of a
+// search. The caller must call release once the search is done.
+func (m *searchResultsMemory) reservation() *searchResultsReservation {
+	return &searchResultsReservation{m: m}
+}
+
+func (m *searchResultsMemory) reserve(n int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.budget > 0 && m.used+n > m.budget {
+		return false
+	}
+	m.used += n
+	searchResultsMemoryBytes.Add(float64(n))
+	return true
+}
+
+func (m *searchResultsMemory) release(n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used -= n
+	searchResultsMemoryBytes.Sub(float64(n))
+}
+
+// searchResultsReservation is the memory reserved for the results of one
+// search. It is not safe for concurrent use.
+type searchResultsReservation struct {
+	m    *searchResultsMemory
+	used int64
+}
+
+// reserve reserves memory for matches. It returns false (and reserves
+// nothing) if that would exceed the budget.
+func (r *searchResultsReservation) reserve(matches []*FileMatchResolver) bool {
+	n := fileMatchesSize(matches)
+	if !r.m.reserve(n) {
+		return false
+	}
+	r.used += n
+	return true
+}
+
+// releaseMatches releases the memory reserved for matches, which were
+// reserved with r and are no longer buffered (e.g. because they were evicted
+// from the best matches of the search).
+func (r *searchResultsReservation) releaseMatches(matches []*FileMatchResolver) {
+	n := fileMatchesSize(matches)
+	r.m.release(n)
+	r.used -= n
+}
+
+// release releases all memory reserved by r.
+func (r *searchResultsReservation) release() {
+	r.m.release(r.used)
+	r.used = 0
+}
+
+// fileMatchesSize returns the approximate number of bytes matches use.
+func fileMatchesSize(matches []*FileMatchResolver) int64 {
+	const (
+		fileMatchOverhead = 200
+		lineMatchOverhead = 80
+	)
+	var n int64
+	for _, fm := range matches {
+		n += fileMatchOverhead + int64(len(fm.JPath)+len(fm.uri))
+		for _, lm := range fm.JLineMatches {
+			n += lineMatchOverhead + int64(len(lm.JPreview)+8*len(lm.JOffsetAndLengths))
+		}
+	}
+	return n
+}
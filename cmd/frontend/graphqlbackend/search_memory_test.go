@@ -0,0 +1,43 @@
+package graphqlbackend
+
+import "testing"
+
+func TestSearchResultsMemory(t *testing.T) {
+	matches := []*FileMatchResolver{{JPath: "a.go", JLineMatches: []*lineMatch{{JPreview: "foo", JOffsetAndLengths: [][2]int32{{0, 3}}}}}}
+	size := fileMatchesSize(matches)
+	if size <= 0 {
+		t.Fatalf("got size %d, want > 0", size)
+	}
+
+	m := &searchResultsMemory{budget: 2 * size}
+	a, b := m.reservation(), m.reservation()
+	if !a.reserve(matches) || !b.reserve(matches) {
+		t.Fatal("failed to reserve memory within the budget")
+	}
+	if a.reserve(matches) {
+		t.Error("reserved memory over the budget")
+	}
+
+	// Memory released by one search can be used by the others.
+	a.releaseMatches(matches)
+	if !b.reserve(matches) {
+		t.Error("failed to reserve memory released for evicted matches")
+	}
+	b.releaseMatches(matches)
+	a.release()
+	if !b.reserve(matches) {
+		t.Error("failed to reserve released memory")
+	}
+	b.release()
+	if m.used != 0 {
+		t.Errorf("got %d bytes used after release, want 0", m.used)
+	}
+
+	unlimited := (&searchResultsMemory{}).reservation()
+	for i := 0; i < 10; i++ {
+		if !unlimited.reserve(matches) {
+			t.Fatal("failed to reserve memory without a budget")
+		}
+	}
+	unlimited.release()
+}
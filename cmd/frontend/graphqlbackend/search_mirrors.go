@@ -0,0 +1,86 @@
+package graphqlbackend
+
+import (
+	"regexp"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+)
+
+// dedupeMirroredFileMatches removes the file matches that are at the same
+// commit and path (and so have the same content) as a match in a more
+// preferred repository, if the search.mirrorDeduplication site configuration
+// enables it. The order of fms is kept.
+func dedupeMirroredFileMatches(fms []*FileMatchResolver) []*FileMatchResolver {
+	c := conf.Get().SearchMirrorDeduplication
+	if c == nil || !c.Enabled || len(fms) < 2 {
+		return fms
+	}
+	var preference []*regexp.Regexp
+	for _, p := range c.Preference {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			log15.Warn("Invalid search.mirrorDeduplication preference", "pattern", p, "error", err)
+			continue
+		}
+		preference = append(preference, re)
+	}
+	return dedupeFileMatchesByContent(fms, preference)
+}
+
+// fileContentKey identifies the content of a file match. The revision the
+// user requested is part of it, so that searches of several revisions of a
+// repository that resolve to the same commit are not deduplicated.
+type fileContentKey struct {
+	commit   api.CommitID
+	path     string
+	inputRev string
+}
+
+func dedupeFileMatchesByContent(fms []*FileMatchResolver, preference []*regexp.Regexp) []*FileMatchResolver {
+	rank := func(fm *FileMatchResolver) int {
+		for i, re := range preference {
+			if re.MatchString(string(fm.Repo.Name)) {
+				return i
+			}
+		}
+		return len(preference)
+	}
+	key := func(fm *FileMatchResolver) (fileContentKey, bool) {
+		if fm.CommitID == "" || fm.Repo == nil {
+			return fileContentKey{}, false
+		}
+		k := fileContentKey{commit: fm.CommitID, path: fm.JPath}
+		if fm.InputRev != nil {
+			k.inputRev = *fm.InputRev
+		}
+		return k, true
+	}
+
+	type candidate struct {
+		index, rank int
+	}
+	best := make(map[fileContentKey]candidate, len(fms))
+	for i, fm := range fms {
+		k, ok := key(fm)
+		if !ok {
+			continue
+		}
+		r := rank(fm)
+		if b, ok := best[k]; !ok || r < b.rank {
+			best[k] = candidate{index: i, rank: r}
+		}
+	}
+	if len(best) == len(fms) {
+		return fms
+	}
+
+	deduped := make([]*FileMatchResolver, 0, len(best))
+	for i, fm := range fms {
+		if k, ok := key(fm); !ok || best[k].index == i {
+			deduped = append(deduped, fm)
+		}
+	}
+	return deduped
+}
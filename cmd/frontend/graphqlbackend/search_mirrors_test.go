@@ -0,0 +1,51 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestDedupeMirroredFileMatches(t *testing.T) {
+	fm := func(repo api.RepoName, commit api.CommitID, path string) *FileMatchResolver {
+		return &FileMatchResolver{Repo: &types.Repo{Name: repo}, CommitID: commit, JPath: path}
+	}
+	rev := "v1"
+	fms := []*FileMatchResolver{
+		fm("old.example.com/a", "c1", "a.go"),
+		fm("github.com/a", "c1", "a.go"),
+		fm("old.example.com/a", "c1", "b.go"),
+		fm("gitlab.com/a", "c1", "b.go"),
+		fm("github.com/a", "c2", "a.go"),
+		{Repo: &types.Repo{Name: "github.com/a"}, CommitID: "c1", JPath: "a.go", InputRev: &rev},
+	}
+	paths := func(fms []*FileMatchResolver) (s []string) {
+		for _, fm := range fms {
+			s = append(s, string(fm.Repo.Name)+"@"+string(fm.CommitID)+":"+fm.JPath)
+		}
+		return s
+	}
+
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+	if got := dedupeMirroredFileMatches(fms); !reflect.DeepEqual(got, fms) {
+		t.Errorf("deduplicated while disabled: %v", paths(got))
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchMirrorDeduplication: &schema.SearchMirrorDeduplication{Enabled: true, Preference: []string{"^github\\.com/"}},
+	}})
+	want := []string{
+		"github.com/a@c1:a.go",
+		"old.example.com/a@c1:b.go",
+		"github.com/a@c2:a.go",
+		"github.com/a@c1:a.go",
+	}
+	if got := paths(dedupeMirroredFileMatches(fms)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package graphqlbackend
+
+import (
+	"context"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/errcode"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// omniboxSuggestionsBudget is the total time OmniboxSuggestions waits for its
+// suggesters. Suggesters that take longer are ignored, so that the omnibox
+// stays responsive while the user types.
+var omniboxSuggestionsBudget = 500 * time.Millisecond
+
+// OmniboxSuggestions returns a single list of repository, file and symbol
+// suggestions for the terms of the query, ranked by how well their names match
+// the terms. Unlike Suggestions, it always asks every kind of suggester, and
+// returns whatever the suggesters found within omniboxSuggestionsBudget.
+func (r *searchResolver) OmniboxSuggestions(ctx context.Context, args *searchSuggestionsArgs) (_ []*searchSuggestionResolver, err error) {
+	args.applyDefaultsAndConstraints()
+
+	var terms []string
+	for _, v := range r.query.Values(query.FieldDefault) {
+		if t := v.ToString(); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	term := strings.Join(terms, " ")
+
+	tr, ctx := trace.New(ctx, "graphql.OmniboxSuggestions", term)
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, omniboxSuggestionsBudget)
+	defer cancel()
+
+	suggesters := map[string]func(ctx context.Context) ([]*searchSuggestionResolver, error){
+		"repo": func(ctx context.Context) ([]*searchSuggestionResolver, error) {
+			if mockShowRepoSuggestions != nil {
+				return mockShowRepoSuggestions()
+			}
+			if _, err := regexp.Compile(term); err != nil {
+				return nil, nil
+			}
+			repoRevs, _, _, _, err := r.resolveRepositories(ctx, []string{term})
+			suggestions := make([]*searchSuggestionResolver, 0, len(repoRevs))
+			for _, rev := range repoRevs {
+				suggestions = append(suggestions, newSearchSuggestionResolver(&RepositoryResolver{repo: rev.Repo}, 0))
+			}
+			return suggestions, err
+		},
+		"file": func(ctx context.Context) ([]*searchSuggestionResolver, error) {
+			if mockShowFileSuggestions != nil {
+				return mockShowFileSuggestions()
+			}
+			return r.suggestFilePaths(ctx, int(*args.First))
+		},
+		"symbol": func(ctx context.Context) ([]*searchSuggestionResolver, error) {
+			if mockShowSymbolMatches != nil {
+				return mockShowSymbolMatches()
+			}
+			repoRevs, _, _, _, err := r.resolveRepositories(ctx, nil)
+			if err != nil {
+				return nil, err
+			}
+			p, err := r.getPatternInfo(nil)
+			if err != nil {
+				return nil, err
+			}
+			fileMatches, _, err := searchSymbols(ctx, &search.TextParameters{
+				PatternInfo:  p,
+				Repos:        repoRevs,
+				Query:        r.query,
+				Zoekt:        r.zoekt,
+				SearcherURLs: r.searcherURLs,
+			}, int(*args.First))
+			var suggestions []*searchSuggestionResolver
+			for _, fm := range fileMatches {
+				for _, sr := range fm.symbols {
+					suggestions = append(suggestions, newSearchSuggestionResolver(sr, 0))
+				}
+			}
+			return suggestions, err
+		},
+	}
+
+	type result struct {
+		name        string
+		suggestions []*searchSuggestionResolver
+		err         error
+	}
+	// Buffered so that suggesters still running when the budget is exhausted
+	// do not block forever.
+	results := make(chan result, len(suggesters))
+	for name, suggester := range suggesters {
+		go func(name string, suggester func(ctx context.Context) ([]*searchSuggestionResolver, error)) {
+			suggestions, err := suggester(ctx)
+			results <- result{name: name, suggestions: suggestions, err: err}
+		}(name, suggester)
+	}
+
+	var allSuggestions []*searchSuggestionResolver
+collect:
+	for pending := len(suggesters); pending > 0; pending-- {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				cause := errors.Cause(res.err)
+				if cause != context.DeadlineExceeded && cause != context.Canceled && !errcode.IsBadRequest(res.err) {
+					log15.Warn("omnibox suggester failed", "suggester", res.name, "query", r.rawQuery(), "error", res.err)
+				}
+			}
+			// Partial results of a failed suggester are still useful.
+			allSuggestions = append(allSuggestions, res.suggestions...)
+		case <-ctx.Done():
+			tr.LazyPrintf("budget exhausted with %d suggesters pending", pending)
+			break collect
+		}
+	}
+
+	allSuggestions = dedupSearchSuggestions(allSuggestions)
+	for _, s := range allSuggestions {
+		s.score = omniboxScore(s, term)
+	}
+	sortSearchSuggestions(allSuggestions)
+	if len(allSuggestions) > int(*args.First) {
+		allSuggestions = allSuggestions[:*args.First]
+	}
+	return allSuggestions, nil
+}
+
+// omniboxScore scores a suggestion by how well its name matches term, so that
+// suggestions of different kinds can be ranked against each other. Matches of
+// the last path component (or the symbol name) beat matches elsewhere in the
+// name. Among equally good matches, repositories rank before symbols, and
+// symbols before files.
+func omniboxScore(s *searchSuggestionResolver, term string) int {
+	var name string
+	var kindBonus int
+	switch r := s.result.(type) {
+	case *RepositoryResolver:
+		name, kindBonus = string(r.repo.Name), 2
+	case *searchSymbolResult:
+		name, kindBonus = r.symbol.Name, 1
+	case *GitTreeEntryResolver:
+		name = r.Path()
+	default:
+		return math.MinInt32
+	}
+
+	name, term = strings.ToLower(name), strings.ToLower(term)
+	base := path.Base(name)
+	var quality int
+	switch {
+	case base == term:
+		quality = 4
+	case strings.HasPrefix(base, term):
+		quality = 3
+	case strings.Contains(base, term):
+		quality = 2
+	case strings.Contains(name, term):
+		quality = 1
+	}
+	return quality*10 + kindBonus
+}
+
+// KeywordSearchSuggestion is a suggestion for clients that use Sourcegraph as
+// a keyword search engine, such as browsers (via OpenSearch) and launchers.
+type KeywordSearchSuggestion struct {
+	// Query is the search query that finds the suggested item.
+	Query string
+	// Description describes the suggested item, e.g. "Repository".
+	Description string
+	// URL is the URL path of the suggested item on Sourcegraph.
+	URL string
+}
+
+// KeywordSearchSuggestions returns up to first omnibox suggestions (see
+// OmniboxSuggestions) for the search query q.
+func KeywordSearchSuggestions(ctx context.Context, q string, first int32) ([]KeywordSearchSuggestion, error) {
+	s, err := NewSearchImplementer(&SearchArgs{Version: "V2", Query: q})
+	if err != nil {
+		return nil, err
+	}
+	suggestions, err := s.OmniboxSuggestions(ctx, &searchSuggestionsArgs{First: &first})
+	if err != nil {
+		return nil, err
+	}
+	keywordSuggestions := make([]KeywordSearchSuggestion, 0, len(suggestions))
+	for _, s := range suggestions {
+		var k KeywordSearchSuggestion
+		switch r := s.result.(type) {
+		case *RepositoryResolver:
+			k = KeywordSearchSuggestion{
+				Query:       "repo:^" + regexp.QuoteMeta(string(r.repo.Name)) + "$",
+				Description: "Repository",
+				URL:         r.URL(),
+			}
+		case *GitTreeEntryResolver:
+			repo := string(r.commit.repo.repo.Name)
+			k = KeywordSearchSuggestion{
+				Query:       "repo:^" + regexp.QuoteMeta(repo) + "$ file:^" + regexp.QuoteMeta(r.Path()) + "$",
+				Description: "File in " + repo,
+			}
+			if k.URL, err = r.URL(ctx); err != nil {
+				return nil, err
+			}
+		case *searchSymbolResult:
+			repo := string(r.commit.repo.repo.Name)
+			symbol := toSymbolResolver(r.symbol, r.baseURI, r.lang, r.commit)
+			k = KeywordSearchSuggestion{
+				Query:       "repo:^" + regexp.QuoteMeta(repo) + "$ type:symbol " + r.symbol.Name,
+				Description: strings.Title(strings.ToLower(symbol.Kind())) + " in " + repo,
+			}
+			if k.URL, err = symbol.URL(ctx); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		keywordSuggestions = append(keywordSuggestions, k)
+	}
+	return keywordSuggestions, nil
+}
@@ -0,0 +1,150 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/gituri"
+	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
+)
+
+func TestOmniboxSuggestions(t *testing.T) {
+	repo := &RepositoryResolver{repo: &types.Repo{ID: 1, Name: "github.com/gorilla/mux"}}
+	commit := &GitCommitResolver{repo: repo, oid: "c1"}
+	file := func(path string) *searchSuggestionResolver {
+		return newSearchSuggestionResolver(&GitTreeEntryResolver{commit: commit, stat: CreateFileInfo(path, false)}, 1)
+	}
+	symbol := func(name string) *searchSuggestionResolver {
+		return newSearchSuggestionResolver(&searchSymbolResult{symbol: protocol.Symbol{Name: name}, commit: commit}, 1)
+	}
+
+	describe := func(results []*searchSuggestionResolver) []string {
+		var descs []string
+		for _, r := range results {
+			switch r := r.result.(type) {
+			case *RepositoryResolver:
+				descs = append(descs, "repo:"+string(r.repo.Name))
+			case *GitTreeEntryResolver:
+				descs = append(descs, "file:"+r.Path())
+			case *searchSymbolResult:
+				descs = append(descs, "symbol:"+r.symbol.Name)
+			}
+		}
+		return descs
+	}
+
+	getSuggestions := func(t *testing.T, query string) []string {
+		t.Helper()
+		r, err := (&schemaResolver{}).Search(&SearchArgs{Query: query, Version: "V2"})
+		if err != nil {
+			t.Fatal("Search:", err)
+		}
+		results, err := r.OmniboxSuggestions(context.Background(), &searchSuggestionsArgs{})
+		if err != nil {
+			t.Fatal("OmniboxSuggestions:", err)
+		}
+		return describe(results)
+	}
+
+	t.Run("mixed ranking", func(t *testing.T) {
+		mockShowRepoSuggestions = func() ([]*searchSuggestionResolver, error) {
+			return []*searchSuggestionResolver{newSearchSuggestionResolver(repo, 1)}, nil
+		}
+		defer func() { mockShowRepoSuggestions = nil }()
+		mockShowFileSuggestions = func() ([]*searchSuggestionResolver, error) {
+			return []*searchSuggestionResolver{file("mux/doc.go"), file("mux.go"), file("mux.go"), file("muxer.go")}, nil
+		}
+		defer func() { mockShowFileSuggestions = nil }()
+		mockShowSymbolMatches = func() ([]*searchSuggestionResolver, error) {
+			return []*searchSuggestionResolver{symbol("NewMux"), symbol("mux")}, nil
+		}
+		defer func() { mockShowSymbolMatches = nil }()
+
+		got := getSuggestions(t, "mux")
+		want := []string{
+			"repo:github.com/gorilla/mux",
+			"symbol:mux",
+			"file:mux.go",
+			"file:muxer.go",
+			"symbol:NewMux",
+			"file:mux/doc.go",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got != want\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		defer func(d time.Duration) { omniboxSuggestionsBudget = d }(omniboxSuggestionsBudget)
+		omniboxSuggestionsBudget = 50 * time.Millisecond
+
+		// The file suggester is slower than the budget. It is released (and
+		// waited for) before the mocks are reset.
+		block, done := make(chan struct{}), make(chan struct{})
+
+		mockShowRepoSuggestions = func() ([]*searchSuggestionResolver, error) {
+			return []*searchSuggestionResolver{newSearchSuggestionResolver(repo, 1)}, nil
+		}
+		defer func() { mockShowRepoSuggestions = nil }()
+		mockShowFileSuggestions = func() ([]*searchSuggestionResolver, error) {
+			defer close(done)
+			<-block
+			return []*searchSuggestionResolver{file("mux.go")}, nil
+		}
+		defer func() { mockShowFileSuggestions = nil }()
+		mockShowSymbolMatches = func() ([]*searchSuggestionResolver, error) { return nil, nil }
+		defer func() { mockShowSymbolMatches = nil }()
+
+		got := getSuggestions(t, "mux")
+		close(block)
+		<-done
+		want := []string{"repo:github.com/gorilla/mux"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got != want\ngot:  %v\nwant: %v", got, want)
+		}
+	})
+
+	t.Run("no terms", func(t *testing.T) {
+		if got := getSuggestions(t, "repo:foo"); len(got) != 0 {
+			t.Errorf("got %v, want no suggestions", got)
+		}
+	})
+}
+
+func TestKeywordSearchSuggestions(t *testing.T) {
+	repo := &RepositoryResolver{repo: &types.Repo{ID: 1, Name: "github.com/gorilla/mux"}}
+	commit := &GitCommitResolver{repo: repo, oid: "c1"}
+
+	mockShowRepoSuggestions = func() ([]*searchSuggestionResolver, error) {
+		return []*searchSuggestionResolver{newSearchSuggestionResolver(repo, 1)}, nil
+	}
+	defer func() { mockShowRepoSuggestions = nil }()
+	mockShowFileSuggestions = func() ([]*searchSuggestionResolver, error) {
+		return []*searchSuggestionResolver{newSearchSuggestionResolver(&GitTreeEntryResolver{commit: commit, stat: CreateFileInfo("mux.go", false)}, 1)}, nil
+	}
+	defer func() { mockShowFileSuggestions = nil }()
+	mockShowSymbolMatches = func() ([]*searchSuggestionResolver, error) {
+		return []*searchSuggestionResolver{newSearchSuggestionResolver(&searchSymbolResult{
+			symbol:  protocol.Symbol{Name: "NewMux", Kind: "func", Path: "mux.go", Line: 3},
+			baseURI: gituri.New(repo.repo.Name, "c1", ""),
+			commit:  commit,
+		}, 1)}, nil
+	}
+	defer func() { mockShowSymbolMatches = nil }()
+
+	got, err := KeywordSearchSuggestions(context.Background(), "mux", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeywordSearchSuggestion{
+		{Query: `repo:^github\.com/gorilla/mux$`, Description: "Repository", URL: "/github.com/gorilla/mux"},
+		{Query: `repo:^github\.com/gorilla/mux$ file:^mux\.go$`, Description: "File in github.com/gorilla/mux", URL: "/github.com/gorilla/mux@c1/-/blob/mux.go"},
+		{Query: `repo:^github\.com/gorilla/mux$ type:symbol NewMux`, Description: "Function in github.com/gorilla/mux", URL: "/github.com/gorilla/mux@c1/-/blob/mux.go#L3:1-3:7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got != want\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/codeowners"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxOwnershipFileSize is the maximum size of an ownership file that is read.
+const maxOwnershipFileSize = 256 << 10
+
+// ownershipCache caches the ownership rules of recently searched commits.
+var (
+	ownershipCacheMu sync.Mutex
+	ownershipCache   = lru.New(1000)
+)
+
+type ownershipKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+	paths  string // the paths of the ownership files, separated by newlines
+}
+
+// ownershipFilePaths returns the paths of the ownership files of
+// repositories, in order of precedence.
+func ownershipFilePaths() []string {
+	if paths := conf.Get().SearchOwnershipFiles; len(paths) > 0 {
+		return paths
+	}
+	return codeowners.DefaultPaths
+}
+
+// ownershipRuleset returns the rules of the first ownership file (see
+// ownershipFilePaths) that exists at the given commit, or nil if the commit
+// has none.
+func ownershipRuleset(ctx context.Context, repo api.RepoName, commit api.CommitID) (*codeowners.Ruleset, error) {
+	paths := ownershipFilePaths()
+	key := ownershipKey{repo: repo, commit: commit, paths: strings.Join(paths, "\n")}
+
+	ownershipCacheMu.Lock()
+	v, ok := ownershipCache.Get(key)
+	ownershipCacheMu.Unlock()
+	if ok {
+		return v.(*codeowners.Ruleset), nil
+	}
+
+	var rs *codeowners.Ruleset
+	for _, path := range paths {
+		data, err := git.ReadFile(ctx, gitserver.Repo{Name: repo}, commit, path, maxOwnershipFileSize)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if rs, err = codeowners.Parse(data); err != nil {
+			return nil, errors.Wrap(err, path)
+		}
+		break
+	}
+
+	ownershipCacheMu.Lock()
+	ownershipCache.Add(key, rs)
+	ownershipCacheMu.Unlock()
+	return rs, nil
+}
+
+// Owners returns the owners of the file in the ownership file of its
+// repository at the searched commit.
+func (fm *FileMatchResolver) Owners(ctx context.Context) ([]string, error) {
+	if fm.Repo == nil || fm.CommitID == "" {
+		return []string{}, nil
+	}
+	rs, err := ownershipRuleset(ctx, fm.Repo.Name, fm.CommitID)
+	if err != nil {
+		return nil, err
+	}
+	owners := rs.Owners(fm.JPath)
+	if owners == nil {
+		owners = []string{}
+	}
+	return owners, nil
+}
+
+// filterOwners removes the file matches whose owners don't satisfy the owner:
+// filters of q. A match satisfies them if its file has every owner of the
+// owner: filters and none of the owners of the -owner: filters.
+//
+// The matches in commits whose ownership file can't be read or parsed are
+// removed, because their owners are unknown.
+func filterOwners(ctx context.Context, q query.QueryInfo, matches []*FileMatchResolver) []*FileMatchResolver {
+	owners, notOwners := q.StringValues(query.FieldOwner)
+	if len(owners) == 0 && len(notOwners) == 0 {
+		return matches
+	}
+
+	var (
+		keys []ownershipKey
+		seen = map[ownershipKey]bool{}
+	)
+	for _, fm := range matches {
+		if fm.Repo == nil || fm.CommitID == "" {
+			continue
+		}
+		if key := (ownershipKey{repo: fm.Repo.Name, commit: fm.CommitID}); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		run      = parallel.NewRun(revisionResolutionParallelism)
+		rulesets = make(map[ownershipKey]*codeowners.Ruleset, len(keys))
+		failed   = map[ownershipKey]bool{}
+	)
+	for _, key := range keys {
+		key := key
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			rs, err := ownershipRuleset(ctx, key.repo, key.commit)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if ctx.Err() == nil {
+					log15.Warn("Failed to read ownership file", "repo", key.repo, "commit", key.commit, "error", err)
+				}
+				failed[key] = true
+				return
+			}
+			rulesets[key] = rs
+		})
+	}
+	_ = run.Wait()
+
+	filtered := matches[:0]
+	for _, fm := range matches {
+		if fm.Repo == nil {
+			continue
+		}
+		key := ownershipKey{repo: fm.Repo.Name, commit: fm.CommitID}
+		if failed[key] || !hasOwners(rulesets[key].Owners(fm.JPath), owners, notOwners) {
+			continue
+		}
+		filtered = append(filtered, fm)
+	}
+	return filtered
+}
+
+// hasOwners reports whether fileOwners contains every owner of owners and
+// none of notOwners.
+func hasOwners(fileOwners, owners, notOwners []string) bool {
+	for _, owner := range owners {
+		if !codeowners.HasOwner(fileOwners, owner) {
+			return false
+		}
+	}
+	for _, owner := range notOwners {
+		if codeowners.HasOwner(fileOwners, owner) {
+			return false
+		}
+	}
+	return true
+}
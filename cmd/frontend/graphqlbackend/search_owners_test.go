@@ -0,0 +1,78 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestFilterOwners(t *testing.T) {
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		switch {
+		case commit == "owned" && name == "CODEOWNERS":
+			return []byte("* @org/everyone\n/cmd/ @org/backend alice@example.com\n"), nil
+		case commit == "broken":
+			return nil, errors.New("gitserver unavailable")
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	defer git.ResetMocks()
+
+	repo := &types.Repo{Name: "github.com/a/owners-test"}
+	matches := []*FileMatchResolver{
+		{JPath: "cmd/a.go", Repo: repo, CommitID: "owned"},
+		{JPath: "README.md", Repo: repo, CommitID: "owned"},
+		{JPath: "cmd/a.go", Repo: repo, CommitID: "no-ownership-file"},
+		{JPath: "cmd/a.go", Repo: repo, CommitID: "broken"},
+	}
+
+	tests := []struct {
+		query string
+		want  []*FileMatchResolver
+	}{
+		{query: "foo", want: matches},
+		{query: "foo owner:org/backend", want: []*FileMatchResolver{matches[0]}},
+		{query: "foo owner:@ORG/backend owner:alice@example.com", want: []*FileMatchResolver{matches[0]}},
+		{query: "foo owner:@org/everyone", want: []*FileMatchResolver{matches[1]}},
+		{query: "foo -owner:@org/backend", want: []*FileMatchResolver{matches[1], matches[2]}},
+	}
+	for _, test := range tests {
+		q, err := query.ParseAndCheck(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := append([]*FileMatchResolver{}, matches...)
+		if got := filterOwners(context.Background(), q, input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.query, got, test.want)
+		}
+	}
+
+	owners, err := matches[0].Owners(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"@org/backend", "alice@example.com"}; !reflect.DeepEqual(owners, want) {
+		t.Errorf("got owners %q, want %q", owners, want)
+	}
+}
+
+func TestOwnershipFilePaths(t *testing.T) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchOwnershipFiles: []string{"OWNERS"}}})
+	defer conf.Mock(nil)
+
+	if got, want := ownershipFilePaths(), []string{"OWNERS"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -568,6 +568,7 @@ func sliceSearchResultsCommon(common *searchResultsCommon, firstResultRepo, last
 	final.missing = doAppend(final.missing, common.missing)
 	final.excluded.forks = final.excluded.forks + common.excluded.forks
 	final.excluded.archived = final.excluded.archived + common.excluded.archived
+	final.excluded.byAdmin = final.excluded.byAdmin + common.excluded.byAdmin
 	final.timedout = doAppend(final.timedout, common.timedout)
 	return final
 }
@@ -0,0 +1,31 @@
+package graphqlbackend
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var searchPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "src_graphql_search_panics_total",
+	Help: "Number of panics recovered while searching, by search type.",
+}, []string{"type"})
+
+// recoverSearchPanic recovers from a panic while searching repo, e.g. because
+// of a malformed response from a search backend, and stores it in err. It must
+// be deferred directly, so that a panic only fails the search of one
+// repository instead of crashing the frontend:
+//
+//	defer recoverSearchPanic("text", repo, &err)
+func recoverSearchPanic(typ, repo string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	searchPanics.WithLabelValues(typ).Inc()
+	log15.Error("Recovered from panic while searching", "type", typ, "repo", repo, "panic", r, "stack", string(debug.Stack()))
+	*err = fmt.Errorf("panic while searching %s: %v", repo, r)
+}
@@ -0,0 +1,48 @@
+package graphqlbackend
+
+import (
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// pathMatchRegexp returns the regexp that highlights the matches of the
+// pattern of info in file paths, or nil if the pattern does not match paths
+// (e.g. for type:file searches) or is a PCRE regexp (engine:pcre).
+func pathMatchRegexp(info *search.TextPatternInfo) *regexp.Regexp {
+	if !info.PatternMatchesPath || info.IsStructuralPat || info.IsPCRE || info.Pattern == "" {
+		return nil
+	}
+	return grepPatternRegexp(info)
+}
+
+// setPathMatches sets the offsets and lengths of the matches of re in the
+// paths of matches. It does nothing if re is nil.
+func setPathMatches(re *regexp.Regexp, matches []*FileMatchResolver) {
+	if re == nil {
+		return
+	}
+	for _, fm := range matches {
+		fm.JPathOffsetAndLengths = pathOffsetAndLengths(re, fm.JPath)
+	}
+}
+
+// pathOffsetAndLengths returns the offsets and lengths (in characters) of the
+// matches of re in path.
+func pathOffsetAndLengths(re *regexp.Regexp, path string) [][2]int32 {
+	var offsetAndLengths [][2]int32
+	for _, loc := range re.FindAllStringIndex(path, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		offset := utf8.RuneCountInString(path[:loc[0]])
+		length := utf8.RuneCountInString(path[loc[0]:loc[1]])
+		offsetAndLengths = append(offsetAndLengths, [2]int32{int32(offset), int32(length)})
+	}
+	return offsetAndLengths
+}
+
+func (fm *FileMatchResolver) PathOffsetAndLengths(args *offsetUnitArgs) [][]int32 {
+	return offsetAndLengthsSlices(search.ConvertOffsets(fm.JPath, fm.JPathOffsetAndLengths, search.OffsetUnit(args.Unit)))
+}
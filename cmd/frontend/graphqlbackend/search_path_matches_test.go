@@ -0,0 +1,61 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestPathMatchRegexp(t *testing.T) {
+	for _, info := range []*search.TextPatternInfo{
+		{Pattern: "foo", PatternMatchesContent: true},
+		{Pattern: "", PatternMatchesPath: true},
+		{Pattern: "foo(:[x])", IsStructuralPat: true, PatternMatchesPath: true},
+	} {
+		if re := pathMatchRegexp(info); re != nil {
+			t.Errorf("got regexp %q for %+v, want nil", re, info)
+		}
+	}
+}
+
+func TestSetPathMatches(t *testing.T) {
+	tests := []struct {
+		info *search.TextPatternInfo
+		path string
+		want [][2]int32
+	}{
+		{
+			info: &search.TextPatternInfo{Pattern: "foo", PatternMatchesPath: true},
+			path: "cmd/Foo/foo.go",
+			want: [][2]int32{{4, 3}, {8, 3}},
+		},
+		{
+			info: &search.TextPatternInfo{Pattern: "foo", IsCaseSensitive: true, PatternMatchesPath: true, PatternMatchesContent: true},
+			path: "cmd/Foo/foo.go",
+			want: [][2]int32{{8, 3}},
+		},
+		{
+			info: &search.TextPatternInfo{Pattern: `b.r\.go$`, IsRegExp: true, PatternMatchesPath: true},
+			path: "日本/bar.go",
+			want: [][2]int32{{3, 6}},
+		},
+		{
+			info: &search.TextPatternInfo{Pattern: "x", PatternMatchesPath: true},
+			path: "a.go",
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		fm := &FileMatchResolver{JPath: test.path}
+		setPathMatches(pathMatchRegexp(test.info), []*FileMatchResolver{fm})
+		if !reflect.DeepEqual(fm.JPathOffsetAndLengths, test.want) {
+			t.Errorf("%q in %q: got %v, want %v", test.info.Pattern, test.path, fm.JPathOffsetAndLengths, test.want)
+		}
+	}
+
+	fm := &FileMatchResolver{JPath: "日本/bar.go", JPathOffsetAndLengths: [][2]int32{{3, 3}}}
+	if got, want := fm.PathOffsetAndLengths(&offsetUnitArgs{Unit: "BYTE"}), [][]int32{{7, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+package graphqlbackend
+
+import (
+	"context"
+	"hash/fnv"
+	"runtime/pprof"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// withSearchProfileLabels returns a context carrying pprof labels that
+// identify the search described by args: a hash of its pattern (so query text
+// does not end up in profiles) and the user running it. Goroutines started for
+// the search apply them with setSearchGoroutineLabels, so that CPU and heap
+// profiles taken during incidents can attribute cost to individual searches.
+func withSearchProfileLabels(ctx context.Context, args *search.TextParameters) context.Context {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(args.PatternInfo.String()))
+
+	user := "anonymous"
+	if a := actor.FromContext(ctx); a.Internal {
+		user = "internal"
+	} else if a.IsAuthenticated() {
+		user = strconv.Itoa(int(a.UID))
+	}
+
+	return pprof.WithLabels(ctx, pprof.Labels(
+		"search_query", strconv.FormatUint(h.Sum64(), 16),
+		"search_user", user,
+	))
+}
+
+// setSearchGoroutineLabels sets the pprof labels of ctx, plus the repository
+// being searched if repo is not empty, on the current goroutine.
+func setSearchGoroutineLabels(ctx context.Context, repo string) {
+	if repo != "" {
+		ctx = pprof.WithLabels(ctx, pprof.Labels("repo", repo))
+	}
+	pprof.SetGoroutineLabels(ctx)
+}
@@ -0,0 +1,37 @@
+package graphqlbackend
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestWithSearchProfileLabels(t *testing.T) {
+	args := func(pattern string) *search.TextParameters {
+		return &search.TextParameters{PatternInfo: &search.TextPatternInfo{Pattern: pattern}}
+	}
+	label := func(ctx context.Context, key string) string {
+		v, _ := pprof.Label(ctx, key)
+		return v
+	}
+
+	ctx := withSearchProfileLabels(actor.WithActor(context.Background(), &actor.Actor{UID: 42}), args("foo"))
+	if got := label(ctx, "search_user"); got != "42" {
+		t.Errorf("got user label %q, want %q", got, "42")
+	}
+	q := label(ctx, "search_query")
+	if q == "" || q == "foo" {
+		t.Errorf("got query label %q, want a hash of the pattern", q)
+	}
+
+	other := withSearchProfileLabels(context.Background(), args("bar"))
+	if got := label(other, "search_user"); got != "anonymous" {
+		t.Errorf("got user label %q, want %q", got, "anonymous")
+	}
+	if label(other, "search_query") == q {
+		t.Error("expected different patterns to have different query labels")
+	}
+}
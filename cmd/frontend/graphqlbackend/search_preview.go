@@ -0,0 +1,144 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// maxPreviewLength is the maximum length in characters of the preview of a
+// line match (0 means no limit). Previews of longer lines, which are
+// typically in minified files, are truncated around their matches.
+var maxPreviewLength = parseMaxPreviewLength(env.Get("SEARCH_MAX_PREVIEW_LENGTH", "500", "maximum length in characters of the preview of a search result line, after which it is truncated around its matches (0 means no limit)"))
+
+func parseMaxPreviewLength(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		log15.Error("Invalid SEARCH_MAX_PREVIEW_LENGTH, disabling preview truncation", "value", s, "error", err)
+		return 0
+	}
+	return n
+}
+
+// truncatePreviews truncates the previews of the line matches of fm that are
+// longer than maxPreviewLength, and links the line matches to fm. fm.Repo,
+// fm.CommitID and fm.JPath must be set, since they are needed to fetch the
+// full lines and the enclosing symbols of the line matches.
+func truncatePreviews(fm *FileMatchResolver) {
+	for _, lm := range fm.JLineMatches {
+		lm.file = fm
+		if maxPreviewLength > 0 {
+			lm.truncatePreview(fm, maxPreviewLength)
+		}
+	}
+}
+
+// truncatePreview truncates the preview of lm to max characters, starting
+// shortly before its first match. Its matches are clipped to the truncated
+// preview, and the matches in the full line are kept in
+// lineOffsetAndLengths.
+func (lm *lineMatch) truncatePreview(fm *FileMatchResolver, max int) {
+	if len(lm.JPreview) <= max || utf8.RuneCountInString(lm.JPreview) <= max {
+		return
+	}
+	line := []rune(lm.JPreview)
+
+	var start int
+	if len(lm.JOffsetAndLengths) > 0 {
+		start = int(lm.JOffsetAndLengths[0][0]) - max/4
+	}
+	if start > len(line)-max {
+		start = len(line) - max
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + max
+
+	offsets := make([][2]int32, 0, len(lm.JOffsetAndLengths))
+	for _, ol := range lm.JOffsetAndLengths {
+		s, e := int(ol[0]), int(ol[0]+ol[1])
+		if s < start {
+			s = start
+		}
+		if e > end {
+			e = end
+		}
+		if s < e {
+			offsets = append(offsets, [2]int32{int32(s - start), int32(e - s)})
+		}
+	}
+
+	lm.lineOffsetAndLengths = lm.JOffsetAndLengths
+	lm.JOffsetAndLengths = offsets
+	lm.JPreview = string(line[start:end])
+	lm.previewOffset = int32(start)
+	lm.previewTruncated = true
+	lm.file = fm
+}
+
+func (lm *lineMatch) PreviewOffset() int32 {
+	return lm.previewOffset
+}
+
+func (lm *lineMatch) PreviewTruncated() bool {
+	return lm.previewTruncated
+}
+
+// Line returns the full line of lm (in hexadecimal if the preview is). If the
+// preview is truncated, the line is read from the file again.
+func (lm *lineMatch) Line(ctx context.Context) (string, error) {
+	if !lm.previewTruncated {
+		return lm.JPreview, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cachedRepo, err := backend.CachedGitRepo(ctx, lm.file.Repo)
+	if err != nil {
+		return "", err
+	}
+	content, err := git.ReadFile(ctx, *cachedRepo, lm.file.CommitID, lm.file.JPath, 0)
+	if err != nil {
+		return "", err
+	}
+	lines := bytes.SplitN(content, []byte("\n"), int(lm.JLineNumber)+2)
+	if int(lm.JLineNumber) >= len(lines) {
+		// This should not happen, since the line was found in the file.
+		return lm.JPreview, nil
+	}
+	if lm.previewHex {
+		return hexBytes(lines[lm.JLineNumber]), nil
+	}
+	return string(lines[lm.JLineNumber]), nil
+}
+
+func (lm *lineMatch) PreviewIsHex() bool {
+	return lm.previewHex
+}
+
+// LineOffsetAndLengths returns the matches in the full line of lm. If the
+// preview is truncated and the unit isn't characters, the line is read from
+// the file again to convert the offsets.
+func (lm *lineMatch) LineOffsetAndLengths(ctx context.Context, args *offsetUnitArgs) ([][]int32, error) {
+	if !lm.previewTruncated {
+		return lm.OffsetAndLengths(args), nil
+	}
+	offsets := lm.lineOffsetAndLengths
+	if unit := search.OffsetUnit(args.Unit); unit != search.OffsetUnitCharacter {
+		line, err := lm.Line(ctx)
+		if err != nil {
+			return nil, err
+		}
+		offsets = search.ConvertOffsets(line, offsets, unit)
+	}
+	return offsetAndLengthsSlices(offsets), nil
+}
@@ -0,0 +1,128 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestLineMatch_truncatePreview(t *testing.T) {
+	line := strings.Repeat("a", 100) + "foo" + strings.Repeat("ü", 100) + "foo"
+	fm := &FileMatchResolver{JPath: "min.js"}
+
+	tests := []struct {
+		name        string
+		offsets     [][2]int32
+		wantPreview string
+		wantOffset  int32
+		wantOffsets [][2]int32
+	}{
+		{
+			name:        "around first match",
+			offsets:     [][2]int32{{100, 3}, {203, 3}},
+			wantPreview: strings.Repeat("a", 10) + "foo" + strings.Repeat("ü", 27),
+			wantOffset:  90,
+			wantOffsets: [][2]int32{{10, 3}},
+		},
+		{
+			name:        "match at the end",
+			offsets:     [][2]int32{{203, 3}},
+			wantPreview: strings.Repeat("ü", 37) + "foo",
+			wantOffset:  166,
+			wantOffsets: [][2]int32{{37, 3}},
+		},
+		{
+			name:        "clipped match",
+			offsets:     [][2]int32{{0, 206}},
+			wantPreview: strings.Repeat("a", 40),
+			wantOffsets: [][2]int32{{0, 40}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lm := &lineMatch{JPreview: line, JOffsetAndLengths: test.offsets}
+			lm.truncatePreview(fm, 40)
+			if lm.JPreview != test.wantPreview {
+				t.Errorf("got preview %q, want %q", lm.JPreview, test.wantPreview)
+			}
+			if lm.PreviewOffset() != test.wantOffset || !lm.PreviewTruncated() {
+				t.Errorf("got preview offset %d (truncated %v), want %d", lm.PreviewOffset(), lm.PreviewTruncated(), test.wantOffset)
+			}
+			if !reflect.DeepEqual(lm.JOffsetAndLengths, test.wantOffsets) {
+				t.Errorf("got offsets %v, want %v", lm.JOffsetAndLengths, test.wantOffsets)
+			}
+			if !reflect.DeepEqual(lm.lineOffsetAndLengths, test.offsets) {
+				t.Errorf("got line offsets %v, want %v", lm.lineOffsetAndLengths, test.offsets)
+			}
+		})
+	}
+
+	short := &lineMatch{JPreview: strings.Repeat("ü", 40), JOffsetAndLengths: [][2]int32{{0, 1}}}
+	short.truncatePreview(fm, 40)
+	if short.PreviewTruncated() || !reflect.DeepEqual(lineOffsets(short), [][]int32{{0, 1}}) {
+		t.Errorf("truncated a short preview: %+v", short)
+	}
+}
+
+func TestLineMatch_Line(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if commit != "c" || name != "min.js" {
+			t.Errorf("got ReadFile(%q, %q)", commit, name)
+		}
+		return []byte("first\nfoo bar baz\nlast\n"), nil
+	}
+	defer git.ResetMocks()
+
+	fm := &FileMatchResolver{JPath: "min.js", Repo: &types.Repo{Name: "r"}, CommitID: "c"}
+	lm := &lineMatch{JPreview: "foo bar baz", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{4, 3}}}
+	lm.truncatePreview(fm, 3)
+	if lm.JPreview != "bar" {
+		t.Fatalf("got preview %q, want bar", lm.JPreview)
+	}
+	line, err := lm.Line(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "foo bar baz" {
+		t.Errorf("got line %q, want %q", line, "foo bar baz")
+	}
+}
+
+func lineOffsets(lm *lineMatch) [][]int32 {
+	offsets, _ := lm.LineOffsetAndLengths(context.Background(), &offsetUnitArgs{Unit: string(search.OffsetUnitCharacter)})
+	return offsets
+}
+
+func TestLineMatch_offsetUnits(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return []byte("😀😀 foo bar\n"), nil
+	}
+	defer git.ResetMocks()
+
+	fm := &FileMatchResolver{JPath: "a.txt", Repo: &types.Repo{Name: "r"}, CommitID: "c"}
+	lm := &lineMatch{JPreview: "😀😀 foo bar", JOffsetAndLengths: [][2]int32{{7, 3}}}
+	if got, want := lm.OffsetAndLengths(&offsetUnitArgs{Unit: "UTF16"}), [][]int32{{9, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got UTF-16 offsets %v, want %v", got, want)
+	}
+
+	lm.truncatePreview(fm, 5)
+	if lm.JPreview != "o bar" {
+		t.Fatalf("got preview %q, want %q", lm.JPreview, "o bar")
+	}
+	if got, want := lm.OffsetAndLengths(&offsetUnitArgs{Unit: "BYTE"}), [][]int32{{2, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got byte offsets %v, want %v", got, want)
+	}
+	got, err := lm.LineOffsetAndLengths(context.Background(), &offsetUnitArgs{Unit: "BYTE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int32{{13, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got byte line offsets %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,115 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// resolveLatestReleases replaces the LATEST_RELEASE revspecs (see
+// search.LatestReleaseRevSpec) of repoRevs with the tags of the newest
+// releases of their repositories. The tags of all repositories are listed
+// concurrently. Repositories without a release are returned as missing, and
+// are omitted from resolved if they have no other revisions.
+func resolveLatestReleases(ctx context.Context, repoRevs []*search.RepositoryRevisions) (resolved, missing []*search.RepositoryRevisions) {
+	var (
+		tags = make([]string, len(repoRevs))
+		run  = parallel.NewRun(revisionResolutionParallelism)
+	)
+	for i, repoRev := range repoRevs {
+		if !hasLatestRelease(repoRev) {
+			continue
+		}
+		i, repoRev := i, repoRev
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			tag, err := latestReleaseTag(ctx, repoRev)
+			if err != nil && ctx.Err() == nil {
+				log15.Warn("Failed to list tags to find latest release", "repo", repoRev.Repo.Name, "error", err)
+			}
+			tags[i] = tag
+		})
+	}
+	_ = run.Wait()
+
+	resolved = repoRevs[:0]
+	for i, repoRev := range repoRevs {
+		if !hasLatestRelease(repoRev) {
+			resolved = append(resolved, repoRev)
+			continue
+		}
+		if tags[i] == "" {
+			missing = append(missing, &search.RepositoryRevisions{
+				Repo: repoRev.Repo,
+				Revs: []search.RevisionSpecifier{{RevSpec: search.LatestReleaseRevSpec}},
+			})
+		}
+		// Copy the revisions, which may be shared with the other repositories
+		// that matched the same pattern.
+		revs := make([]search.RevisionSpecifier, 0, len(repoRev.Revs))
+		for _, rev := range repoRev.Revs {
+			if rev.RevSpec == search.LatestReleaseRevSpec {
+				if tags[i] == "" {
+					continue
+				}
+				rev.RevSpec = tags[i]
+			}
+			revs = append(revs, rev)
+		}
+		if len(revs) == 0 {
+			continue
+		}
+		repoRev.Revs = revs
+		resolved = append(resolved, repoRev)
+	}
+	return resolved, missing
+}
+
+func hasLatestRelease(repoRev *search.RepositoryRevisions) bool {
+	for _, rev := range repoRev.Revs {
+		if rev.RevSpec == search.LatestReleaseRevSpec {
+			return true
+		}
+	}
+	return false
+}
+
+// latestReleaseTag returns the name of the tag of repoRev's repository with
+// the greatest semantic version that is not a prerelease, or "" if there is
+// none. Tags that are not semantic versions are ignored.
+func latestReleaseTag(ctx context.Context, repoRev *search.RepositoryRevisions) (string, error) {
+	listRefs := repoRev.ListRefs
+	if listRefs == nil {
+		listRefs = git.ListRefs
+	}
+	refs, err := listRefs(ctx, repoRev.GitserverRepo())
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		latest *semver.Version
+		tag    string
+	)
+	for _, ref := range refs {
+		name := strings.TrimPrefix(ref.Name, "refs/tags/")
+		if name == ref.Name {
+			continue
+		}
+		v, err := semver.NewVersion(name)
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest, tag = v, name
+		}
+	}
+	return tag, nil
+}
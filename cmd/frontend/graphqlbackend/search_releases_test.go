@@ -0,0 +1,66 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestResolveLatestReleases(t *testing.T) {
+	listRefs := func(names ...string) func(context.Context, gitserver.Repo) ([]git.Ref, error) {
+		return func(context.Context, gitserver.Repo) ([]git.Ref, error) {
+			refs := make([]git.Ref, len(names))
+			for i, name := range names {
+				refs[i] = git.Ref{Name: name, CommitID: "c"}
+			}
+			return refs, nil
+		}
+	}
+	latest := []search.RevisionSpecifier{{RevSpec: search.LatestReleaseRevSpec}}
+	repoRevs := []*search.RepositoryRevisions{
+		{
+			Repo:     &types.Repo{Name: "a"},
+			Revs:     latest,
+			ListRefs: listRefs("refs/heads/master", "refs/tags/v1.2.0", "refs/tags/v1.10.0", "refs/tags/v2.0.0-rc1", "refs/tags/nightly"),
+		},
+		{
+			Repo:     &types.Repo{Name: "b"},
+			Revs:     latest,
+			ListRefs: listRefs("refs/heads/v3.0.0", "refs/tags/nightly"),
+		},
+		{
+			Repo:     &types.Repo{Name: "c"},
+			Revs:     []search.RevisionSpecifier{{RevSpec: search.LatestReleaseRevSpec}, {RevSpec: "dev"}},
+			ListRefs: listRefs(),
+		},
+		{
+			Repo: &types.Repo{Name: "d"},
+			Revs: []search.RevisionSpecifier{{RevSpec: "v1.0.0"}},
+		},
+	}
+
+	resolved, missing := resolveLatestReleases(context.Background(), repoRevs)
+
+	got := make([]string, len(resolved))
+	for i, r := range resolved {
+		got[i] = r.String()
+	}
+	if want := []string{"a@v1.10.0", "c@dev", "d@v1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got resolved %v, want %v", got, want)
+	}
+	got = make([]string, len(missing))
+	for i, r := range missing {
+		got[i] = r.String()
+	}
+	if want := []string{"b@LATEST_RELEASE", "c@LATEST_RELEASE"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got missing %v, want %v", got, want)
+	}
+	if latest[0].RevSpec != search.LatestReleaseRevSpec {
+		t.Error("expected shared revisions not to be modified")
+	}
+}
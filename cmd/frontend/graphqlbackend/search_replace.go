@@ -0,0 +1,156 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/replace"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// SearchReplacementPatch is a patch that replaces the matches of a search in
+// the files of a repository.
+type SearchReplacementPatch struct {
+	Repo *types.Repo
+	// Commit is the commit that was searched, which the patch applies to.
+	Commit api.CommitID
+	// BaseRef is the ref that resolved to Commit when the repository was
+	// searched, e.g. "refs/heads/master".
+	BaseRef string
+	// Diff is the patch in unified diff format (see replace.FileDiff).
+	Diff string
+}
+
+// SearchReplacementPatches runs the search of args exhaustively and returns,
+// for every repository with matches, a patch that replaces the matches of the
+// search pattern in the matching files with replacement. In regexp searches,
+// replacement can refer to submatches of the pattern (see replace.New).
+//
+// Structural searches are not supported. They are rewritten with the replace:
+// filter instead.
+func SearchReplacementPatches(ctx context.Context, args *SearchArgs, replacement string) (_ []*SearchReplacementPatch, err error) {
+	tr, ctx := trace.New(ctx, "graphql.SearchReplacementPatches", args.Query)
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	s, err := NewSearchImplementer(args)
+	if err != nil {
+		return nil, err
+	}
+	sr, ok := s.(*searchResolver)
+	if !ok {
+		// The query is invalid: run it to return the alert as an error.
+		results, err := s.Results(ctx)
+		if err == nil && results.alert != nil {
+			err = errors.New(results.alert.title)
+		}
+		return nil, err
+	}
+
+	replacer, err := newSearchReplacer(sr, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	spool, err := newFileMatchSpool("")
+	if err != nil {
+		return nil, err
+	}
+	defer spool.Close()
+	if _, err := sr.spillFileMatches(ctx, spool); err != nil {
+		return nil, err
+	}
+
+	return searchReplacementPatches(ctx, spool, replacer)
+}
+
+// newSearchReplacer returns the replacer of the matches of the pattern of sr
+// with replacement. It uses the pattern as the user wrote it, not as it is sent
+// to searcher, since e.g. a trailing ".*" replaces the rest of the line.
+func newSearchReplacer(sr *searchResolver, replacement string) (*replace.Replacer, error) {
+	var p *getPatternInfoOptions
+	switch sr.patternType {
+	case query.SearchTypeStructural:
+		return nil, errors.New("structural searches are rewritten with the replace: filter")
+	case query.SearchTypeLiteral:
+		// Literal patterns are turned into regexps, but their replacements
+		// must stay literal.
+		replacement = strings.ReplaceAll(replacement, "$", "$$")
+		p = &getPatternInfoOptions{performLiteralSearch: true}
+	}
+	patternInfo, err := sr.getPatternInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	return replace.New(patternInfo, replacement)
+}
+
+// searchReplacementPatches returns the patches that replace the matches of
+// replacer in the files of the file matches in spool.
+func searchReplacementPatches(ctx context.Context, spool *fileMatchSpool, replacer *replace.Replacer) (patches []*SearchReplacementPatch, err error) {
+	type repoCommit struct {
+		repo   api.RepoID
+		commit api.CommitID
+	}
+	byRepoCommit := map[repoCommit]*SearchReplacementPatch{}
+	err = spool.Each(func(fm *FileMatchResolver) error {
+		if len(fm.JLineMatches) == 0 || fm.Repo == nil {
+			// Only the path matched.
+			return nil
+		}
+		content, err := git.ReadFile(ctx, gitserver.Repo{Name: fm.Repo.Name}, fm.CommitID, fm.JPath, 0)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s in %s", fm.JPath, fm.Repo.Name)
+		}
+		diff := replace.FileDiff(fm.JPath, string(content), replacer.Replace(string(content)))
+		if diff == "" {
+			return nil
+		}
+
+		key := repoCommit{repo: fm.Repo.ID, commit: fm.CommitID}
+		patch, ok := byRepoCommit[key]
+		if !ok {
+			patch = &SearchReplacementPatch{Repo: fm.Repo, Commit: fm.CommitID}
+			if patch.BaseRef, err = searchReplacementBaseRef(ctx, fm); err != nil {
+				return err
+			}
+			byRepoCommit[key] = patch
+			patches = append(patches, patch)
+		}
+		patch.Diff += diff
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(patches, func(i, j int) bool {
+		if patches[i].Repo.Name != patches[j].Repo.Name {
+			return patches[i].Repo.Name < patches[j].Repo.Name
+		}
+		return patches[i].BaseRef < patches[j].BaseRef
+	})
+	return patches, nil
+}
+
+// searchReplacementBaseRef returns the ref of the revision of fm that was
+// searched.
+func searchReplacementBaseRef(ctx context.Context, fm *FileMatchResolver) (string, error) {
+	if fm.InputRev != nil && *fm.InputRev != "" {
+		return git.EnsureRefPrefix(*fm.InputRev), nil
+	}
+	ref, err := (&RepositoryResolver{repo: fm.Repo}).DefaultBranch(ctx)
+	if err != nil || ref == nil {
+		return "", err
+	}
+	return ref.Name(), nil
+}
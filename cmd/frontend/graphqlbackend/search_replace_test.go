@@ -0,0 +1,98 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/replace"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestSearchReplacementPatches(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if name == "b.go" {
+			return []byte("package b\n"), nil
+		}
+		return []byte("package a\nreturn fmt.Errorf(\"x\")\n"), nil
+	}
+	defer git.ResetMocks()
+
+	spool, err := newFileMatchSpool("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+	repo := &types.Repo{ID: 1, Name: "github.com/a/b"}
+	rev := "dev"
+	lineMatches := []*lineMatch{{JPreview: `return fmt.Errorf("x")`, JLineNumber: 1}}
+	if err := spool.add([]*FileMatchResolver{
+		{JPath: "a.go", Repo: repo, CommitID: "c1", InputRev: &rev, JLineMatches: lineMatches},
+		// The file changed since it was indexed.
+		{JPath: "b.go", Repo: repo, CommitID: "c1", InputRev: &rev, JLineMatches: lineMatches},
+		{JPath: "fmt.Errorf.go", Repo: repo, CommitID: "c1", InputRev: &rev},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	replacer, err := replace.New(&search.TextPatternInfo{Pattern: `fmt\.Errorf\(`, IsRegExp: true}, "errors.Errorf(")
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches, err := searchReplacementPatches(context.Background(), spool, replacer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*SearchReplacementPatch{{
+		Repo:    repo,
+		Commit:  "c1",
+		BaseRef: "refs/heads/dev",
+		Diff: `diff --git a.go a.go
+--- a.go
++++ a.go
+@@ -1,2 +1,2 @@
+ package a
+-return fmt.Errorf("x")
++return errors.Errorf("x")
+`,
+	}}
+	if !reflect.DeepEqual(patches, want) {
+		t.Errorf("got %+v, want %+v", patches, want)
+	}
+}
+
+func TestNewSearchReplacer(t *testing.T) {
+	tests := map[string]struct {
+		query, patternType, replacement string
+		want                            string
+	}{
+		"regexp with trailing wildcard": {query: "foo.*", patternType: "regexp", replacement: "bar", want: "x bar\n"},
+		"regexp submatch":               {query: "(f)oo", patternType: "regexp", replacement: "${1}u", want: "x fu y\n"},
+		"literal":                       {query: "foo", patternType: "literal", replacement: "$1", want: "x $1 y\n"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSearchImplementer(&SearchArgs{Version: "V2", Query: test.query, PatternType: strptr(test.patternType)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			replacer, err := newSearchReplacer(s.(*searchResolver), test.replacement)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := replacer.Replace("x foo y\n"); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchReplacementPatches_structural(t *testing.T) {
+	_, err := SearchReplacementPatches(context.Background(), &SearchArgs{Version: "V2", Query: "foo(:[x])", PatternType: strptr("structural")}, "bar")
+	if err == nil {
+		t.Error("got nil error for structural search, want error")
+	}
+}
@@ -0,0 +1,72 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// repositoryBatch loads the fields of the repositories of a set of search
+// results together. Repositories are resolved without their fields for
+// search, so without it every result whose repository fields are requested
+// would query the database for its repository on its own.
+type repositoryBatch struct {
+	once  sync.Once
+	repos []*types.Repo
+}
+
+// batchRepositories makes the repositories of results load their fields in
+// one query. It must be called before the results are resolved.
+func batchRepositories(results []SearchResultResolver) {
+	b := &repositoryBatch{}
+	seen := map[*types.Repo]bool{}
+	add := func(repo *types.Repo) {
+		if repo != nil && repo.RepoFields == nil && !seen[repo] {
+			seen[repo] = true
+			b.repos = append(b.repos, repo)
+		}
+	}
+	for _, result := range results {
+		switch r := result.(type) {
+		case *FileMatchResolver:
+			add(r.Repo)
+			r.repoBatch = b
+		case *RepositoryResolver:
+			add(r.repo)
+			r.batch = b
+		}
+	}
+}
+
+// hydrate loads the fields of all repositories of b. Repositories that
+// cannot be loaded are left as they are, and load their fields on their own
+// (which reports the error).
+func (b *repositoryBatch) hydrate(ctx context.Context) {
+	b.once.Do(func() {
+		if len(b.repos) < 2 {
+			return
+		}
+		names := make([]string, len(b.repos))
+		for i, repo := range b.repos {
+			names[i] = string(repo.Name)
+		}
+		repos, err := db.Repos.GetByURIs(ctx, names)
+		if err != nil {
+			log15.Warn("Failed to load repositories of search results", "error", err)
+			return
+		}
+		byID := make(map[api.RepoID]*types.Repo, len(repos))
+		for _, repo := range repos {
+			byID[repo.ID] = repo
+		}
+		for _, repo := range b.repos {
+			if full, ok := byID[repo.ID]; ok {
+				repo.RepoFields = full.RepoFields
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestBatchRepositories(t *testing.T) {
+	var lookups [][]string
+	db.Mocks.Repos.GetByURIs = func(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+		lookups = append(lookups, nameOrURIs)
+		var repos []*types.Repo
+		for i, name := range nameOrURIs {
+			repos = append(repos, &types.Repo{ID: api.RepoID(i + 1), Name: api.RepoName(name), RepoFields: &types.RepoFields{Description: "about " + name}})
+		}
+		return repos, nil
+	}
+	db.Mocks.Repos.Get = func(ctx context.Context, id api.RepoID) (*types.Repo, error) {
+		t.Errorf("unexpected lookup of repository %d", id)
+		return nil, nil
+	}
+	defer func() { db.Mocks.Repos = db.MockRepos{} }()
+
+	foo := &types.Repo{ID: 1, Name: "foo"}
+	bar := &types.Repo{ID: 2, Name: "bar"}
+	results := []SearchResultResolver{
+		&FileMatchResolver{uri: "git://foo#a.go", Repo: foo},
+		&FileMatchResolver{uri: "git://foo#b.go", Repo: foo},
+		&RepositoryResolver{repo: bar},
+	}
+	batchRepositories(results)
+
+	ctx := context.Background()
+	for _, r := range []*RepositoryResolver{
+		results[0].(*FileMatchResolver).Repository(),
+		results[1].(*FileMatchResolver).Repository(),
+		results[2].(*RepositoryResolver),
+	} {
+		description, err := r.Description(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "about " + string(r.repo.Name); description != want {
+			t.Errorf("got description %q, want %q", description, want)
+		}
+	}
+	if want := [][]string{{"foo", "bar"}}; !reflect.DeepEqual(lookups, want) {
+		t.Errorf("got lookups %v, want %v", lookups, want)
+	}
+}
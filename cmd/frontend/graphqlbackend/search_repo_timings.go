@@ -0,0 +1,137 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// repoTiming is the time it took to search a single repository revision with
+// searcher, broken down by phase.
+type repoTiming struct {
+	repo *types.Repo
+	rev  string
+	err  bool
+
+	total      time.Duration
+	resolve    time.Duration // resolving the revision and evaluating repohasfile
+	search     time.Duration // the searcher request
+	accumulate time.Duration // merging the results into the overall results
+}
+
+func (t *repoTiming) String() string {
+	s := fmt.Sprintf("%s@%s=%s", t.repo.Name, t.rev, t.total.Round(time.Millisecond))
+	if t.err {
+		s += "(error)"
+	}
+	return s
+}
+
+// repoTimings collects repoTimings for a single search request. All leaves of
+// an and/or query share the same repoTimings.
+type repoTimings struct {
+	mu      sync.Mutex
+	timings []*repoTiming
+}
+
+type repoTimingsKey struct{}
+
+type repoTimingKey struct{}
+
+// withRepoTimings returns a context in which repository timings are collected,
+// and the collector. If ctx already collects timings, its collector is reused.
+func withRepoTimings(ctx context.Context) (context.Context, *repoTimings) {
+	if t, ok := ctx.Value(repoTimingsKey{}).(*repoTimings); ok {
+		return ctx, t
+	}
+	t := &repoTimings{}
+	return context.WithValue(ctx, repoTimingsKey{}, t), t
+}
+
+// withRepoTiming returns a context for the search of a single repository
+// revision, in which phase durations are recorded in t.
+func withRepoTiming(ctx context.Context, t *repoTiming) context.Context {
+	return context.WithValue(ctx, repoTimingKey{}, t)
+}
+
+// repoTimingFromContext returns the timing of the repository search running in
+// ctx, or nil.
+func repoTimingFromContext(ctx context.Context) *repoTiming {
+	t, _ := ctx.Value(repoTimingKey{}).(*repoTiming)
+	return t
+}
+
+// recordRepoTiming adds t to the timings collected for the search running in
+// ctx, if any. t must not be modified afterwards.
+func recordRepoTiming(ctx context.Context, t *repoTiming) {
+	timings, ok := ctx.Value(repoTimingsKey{}).(*repoTimings)
+	if !ok {
+		return
+	}
+	timings.mu.Lock()
+	timings.timings = append(timings.timings, t)
+	timings.mu.Unlock()
+}
+
+// slowest returns the n slowest timings, slowest first.
+func (t *repoTimings) slowest(n int) []*repoTiming {
+	t.mu.Lock()
+	timings := append([]*repoTiming(nil), t.timings...)
+	t.mu.Unlock()
+
+	sort.SliceStable(timings, func(i, j int) bool { return timings[i].total > timings[j].total })
+	if len(timings) > n {
+		timings = timings[:n]
+	}
+	return timings
+}
+
+func (sr *SearchResultsResolver) RepositoryTimings(ctx context.Context, args *struct {
+	First int32
+}) ([]*repoTimingResolver, error) {
+	// 🚨 SECURITY: Only site admins may see timings, since they include
+	// repositories that did not produce results.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if sr.repoTimings == nil || args.First <= 0 {
+		return []*repoTimingResolver{}, nil
+	}
+	slowest := sr.repoTimings.slowest(int(args.First))
+	resolvers := make([]*repoTimingResolver, len(slowest))
+	for i, t := range slowest {
+		resolvers[i] = &repoTimingResolver{t}
+	}
+	return resolvers, nil
+}
+
+type repoTimingResolver struct {
+	timing *repoTiming
+}
+
+func (r *repoTimingResolver) Repository() *RepositoryResolver {
+	return &RepositoryResolver{repo: r.timing.repo}
+}
+
+func (r *repoTimingResolver) Rev() string { return r.timing.rev }
+
+func (r *repoTimingResolver) Error() bool { return r.timing.err }
+
+func (r *repoTimingResolver) TotalMilliseconds() int32 { return milliseconds(r.timing.total) }
+
+func (r *repoTimingResolver) ResolveMilliseconds() int32 { return milliseconds(r.timing.resolve) }
+
+func (r *repoTimingResolver) SearchMilliseconds() int32 { return milliseconds(r.timing.search) }
+
+func (r *repoTimingResolver) AccumulateMilliseconds() int32 {
+	return milliseconds(r.timing.accumulate)
+}
+
+func milliseconds(d time.Duration) int32 {
+	return int32(d / time.Millisecond)
+}
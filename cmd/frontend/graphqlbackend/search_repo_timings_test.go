@@ -0,0 +1,85 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestRepoTimings(t *testing.T) {
+	// Recording without a collector in the context is a no-op.
+	recordRepoTiming(context.Background(), &repoTiming{})
+	if repoTimingFromContext(context.Background()) != nil {
+		t.Fatal("expected no repo timing in background context")
+	}
+
+	ctx, timings := withRepoTimings(context.Background())
+	if ctx2, timings2 := withRepoTimings(ctx); ctx2 != ctx || timings2 != timings {
+		t.Fatal("expected nested withRepoTimings to reuse the collector")
+	}
+
+	a := &repoTiming{repo: &types.Repo{Name: "a"}, rev: "master", total: time.Second}
+	b := &repoTiming{repo: &types.Repo{Name: "b"}, rev: "v1", total: 3 * time.Second, err: true}
+	c := &repoTiming{repo: &types.Repo{Name: "c"}, rev: "master", total: 2 * time.Second}
+	for _, timing := range []*repoTiming{a, b, c} {
+		if have := repoTimingFromContext(withRepoTiming(ctx, timing)); have != timing {
+			t.Fatalf("repoTimingFromContext returned %v, want %v", have, timing)
+		}
+		recordRepoTiming(ctx, timing)
+	}
+
+	var have []string
+	for _, timing := range timings.slowest(2) {
+		have = append(have, timing.String())
+	}
+	if want := []string{"b@v1=3s(error)", "c@master=2s"}; !cmp.Equal(have, want) {
+		t.Error(cmp.Diff(want, have))
+	}
+}
+
+func TestSearchResultsResolver_RepositoryTimings(t *testing.T) {
+	defer resetMocks()
+
+	_, timings := withRepoTimings(context.Background())
+	timings.timings = []*repoTiming{{
+		repo:       &types.Repo{Name: "a"},
+		rev:        "master",
+		total:      1500 * time.Millisecond,
+		resolve:    100 * time.Millisecond,
+		search:     1300 * time.Millisecond,
+		accumulate: 2 * time.Millisecond,
+	}}
+	sr := &SearchResultsResolver{repoTimings: timings}
+	args := &struct{ First int32 }{First: 10}
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: false}, nil
+	}
+	if _, err := sr.RepositoryTimings(context.Background(), args); err != backend.ErrMustBeSiteAdmin {
+		t.Fatalf("got err %v, want %v", err, backend.ErrMustBeSiteAdmin)
+	}
+
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1, SiteAdmin: true}, nil
+	}
+	resolvers, err := sr.RepositoryTimings(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolvers) != 1 {
+		t.Fatalf("got %d timings, want 1", len(resolvers))
+	}
+	r := resolvers[0]
+	have := []int32{r.TotalMilliseconds(), r.ResolveMilliseconds(), r.SearchMilliseconds(), r.AccumulateMilliseconds()}
+	if want := []int32{1500, 100, 1300, 2}; !cmp.Equal(have, want) {
+		t.Error(cmp.Diff(want, have))
+	}
+	if r.Repository().Name() != "a" || r.Rev() != "master" || r.Error() {
+		t.Errorf("unexpected timing %+v", r.timing)
+	}
+}
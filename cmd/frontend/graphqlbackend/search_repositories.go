@@ -5,8 +5,10 @@ import (
 	"math"
 	"regexp"
 
+	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
@@ -89,9 +91,47 @@ func searchRepositories(ctx context.Context, args *search.TextParameters, limit
 		}
 	}
 
+	loadRepositoryFields(ctx, results)
 	return results, common, nil
 }
 
+// loadRepositoryFields loads the fields of the repositories of repository
+// results (such as their descriptions), which repository results are usually
+// shown with, in one query. The repositories were resolved without their
+// fields, and would otherwise each be loaded on their own when the results
+// are resolved. Errors are logged, since the fields are then still loaded
+// that way.
+func loadRepositoryFields(ctx context.Context, results []SearchResultResolver) {
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		if r := result.(*RepositoryResolver); r.repo.RepoFields == nil {
+			names = append(names, string(r.repo.Name))
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	repos, err := db.Repos.GetByURIs(ctx, names)
+	if err != nil {
+		log15.Warn("Failed to load repositories of repository search results", "error", err)
+		return
+	}
+	byID := make(map[api.RepoID]*types.Repo, len(repos))
+	for _, repo := range repos {
+		byID[repo.ID] = repo
+	}
+	for _, result := range results {
+		r := result.(*RepositoryResolver)
+		if full, ok := byID[r.repo.ID]; ok && r.repo.RepoFields == nil {
+			// Copy the repository, which is shared with the other results of
+			// the search.
+			repo := *r.repo
+			repo.RepoFields = full.RepoFields
+			r.repo = &repo
+		}
+	}
+}
+
 // reposToAdd determines which repositories should be included in the result set based on whether they fit in the subset
 // of repostiories specified in the query's `repohasfile` and `-repohasfile` fields if they exist.
 func reposToAdd(ctx context.Context, args *search.TextParameters, repos []*search.RepositoryRevisions) ([]*search.RepositoryRevisions, error) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
@@ -23,6 +24,19 @@ func TestSearchRepositories(t *testing.T) {
 
 	zoekt := &searchbackend.Zoekt{Client: &fakeSearcher{}}
 
+	db.Mocks.Repos.GetByURIs = func(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+		var repos []*types.Repo
+		for _, r := range repositories {
+			for _, name := range nameOrURIs {
+				if string(r.Repo.Name) == name {
+					repos = append(repos, &types.Repo{ID: r.Repo.ID, Name: r.Repo.Name, RepoFields: &types.RepoFields{Description: "about " + name}})
+				}
+			}
+		}
+		return repos, nil
+	}
+	defer func() { db.Mocks.Repos.GetByURIs = nil }()
+
 	mockSearchFilesInRepos = func(args *search.TextParameters) (matches []*FileMatchResolver, common *searchResultsCommon, err error) {
 		repoName := args.Repos[0].Repo.Name
 		switch repoName {
@@ -106,6 +120,9 @@ func TestSearchRepositories(t *testing.T) {
 					t.Fatal("expected repo result")
 				}
 				got = append(got, string(r.repo.Name))
+				if r.repo.RepoFields == nil || r.repo.Description != "about "+string(r.repo.Name) {
+					t.Errorf("expected the fields of %s to be loaded, got %+v", r.repo.Name, r.repo.RepoFields)
+				}
 			}
 			sort.Strings(got)
 
@@ -0,0 +1,122 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// revisionResolutionParallelism is the number of revisions resolved
+// concurrently by a revisionPrefetch.
+const revisionResolutionParallelism = 32
+
+// revisionPrefetch resolves the revisions of the repositories of a search
+// concurrently, ahead of the searches of the repositories. Searching a
+// repository then does not hold a searcher request slot while it waits for
+// gitserver to resolve its revision (see resolveRepoToSearch).
+type revisionPrefetch struct {
+	revs map[revisionKey]*prefetchedRevision
+}
+
+type revisionKey struct {
+	repo api.RepoName
+	rev  string
+}
+
+type prefetchedRevision struct {
+	done   chan struct{}
+	commit api.CommitID
+	err    error
+}
+
+// prefetchRevisions starts resolving the revisions (except ref globs) of
+// repos, in the order of repos. wg is done once all revisions are resolved or
+// ctx is done.
+func prefetchRevisions(ctx context.Context, wg *sync.WaitGroup, repos []*search.RepositoryRevisions) *revisionPrefetch {
+	p := &revisionPrefetch{revs: map[revisionKey]*prefetchedRevision{}}
+	type item struct {
+		repoRev *search.RepositoryRevisions
+		rev     string
+		r       *prefetchedRevision
+	}
+	var items []item
+	for _, repoRev := range repos {
+		for _, rev := range repoRev.Revs {
+			if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+				continue
+			}
+			key := revisionKey{repo: repoRev.Repo.Name, rev: rev.RevSpec}
+			if _, ok := p.revs[key]; ok {
+				continue
+			}
+			r := &prefetchedRevision{done: make(chan struct{})}
+			p.revs[key] = r
+			items = append(items, item{repoRev: repoRev, rev: rev.RevSpec, r: r})
+		}
+	}
+	if len(items) == 0 {
+		return p
+	}
+
+	work := make(chan item)
+	workers := revisionResolutionParallelism
+	if len(items) < workers {
+		workers = len(items)
+	}
+	wg.Add(workers + 1)
+	go func() {
+		defer wg.Done()
+		defer close(work)
+		for _, it := range items {
+			select {
+			case work <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for it := range work {
+				it.r.commit, it.r.err = git.ResolveRevision(ctx, it.repoRev.GitserverRepo(), nil, it.rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+				close(it.r.done)
+			}
+		}()
+	}
+	return p
+}
+
+// wait waits for the prefetched resolution of rev in repo. It returns nil if
+// rev was not prefetched (or p is nil), or if ctx is done first.
+func (p *revisionPrefetch) wait(ctx context.Context, repo api.RepoName, rev string) *prefetchedRevision {
+	if p == nil {
+		return nil
+	}
+	r, ok := p.revs[revisionKey{repo: repo, rev: rev}]
+	if !ok {
+		return nil
+	}
+	select {
+	case <-r.done:
+		return r
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+type revisionPrefetchKey struct{}
+
+func withRevisionPrefetch(ctx context.Context, p *revisionPrefetch) context.Context {
+	return context.WithValue(ctx, revisionPrefetchKey{}, p)
+}
+
+// revisionPrefetchFromContext returns the revisionPrefetch of the search
+// running in ctx, or nil.
+func revisionPrefetchFromContext(ctx context.Context) *revisionPrefetch {
+	p, _ := ctx.Value(revisionPrefetchKey{}).(*revisionPrefetch)
+	return p
+}
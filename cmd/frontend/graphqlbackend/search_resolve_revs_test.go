@@ -0,0 +1,52 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestPrefetchRevisions(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = map[string]int{}
+	)
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		mu.Lock()
+		calls[spec]++
+		mu.Unlock()
+		return api.CommitID("commit-" + spec), nil
+	}
+	defer git.ResetMocks()
+
+	repos := []*search.RepositoryRevisions{
+		{Repo: &types.Repo{Name: "a"}, Revs: []search.RevisionSpecifier{{RevSpec: ""}, {RevSpec: "v1"}, {RefGlob: "refs/tags/*"}}},
+		{Repo: &types.Repo{Name: "b"}, Revs: []search.RevisionSpecifier{{RevSpec: "v1"}}},
+	}
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	p := prefetchRevisions(ctx, &wg, repos)
+
+	if r := p.wait(ctx, "b", "v1"); r == nil || r.commit != "commit-v1" || r.err != nil {
+		t.Errorf("got %+v, want commit-v1", r)
+	}
+	if r := p.wait(ctx, "a", "refs/tags/*"); r != nil {
+		t.Errorf("got %+v for a ref glob, want nil", r)
+	}
+	if r := p.wait(ctx, "c", ""); r != nil {
+		t.Errorf("got %+v for a repository that was not prefetched, want nil", r)
+	}
+	if r := (*revisionPrefetch)(nil).wait(ctx, "a", ""); r != nil {
+		t.Errorf("got %+v without a prefetch, want nil", r)
+	}
+
+	wg.Wait()
+	if want := map[string]int{"": 1, "v1": 2}; len(calls) != len(want) || calls[""] != want[""] || calls["v1"] != want["v1"] {
+		t.Errorf("got ResolveRevision calls %v, want %v", calls, want)
+	}
+}
@@ -38,6 +38,10 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
 	"github.com/sourcegraph/sourcegraph/internal/rcache"
 	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/contentclass"
+	"github.com/sourcegraph/sourcegraph/internal/search/exclusions"
+	"github.com/sourcegraph/sourcegraph/internal/search/goast"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
@@ -54,6 +58,7 @@ type searchResultsCommon struct {
 	indexed  []*types.Repo             // repos that were searched using an index
 	cloning  []*types.Repo             // repos that could not be searched because they were still being cloned
 	missing  []*types.Repo             // repos that could not be searched because they do not exist
+	failed   []*types.Repo             // repos that could not be searched because of an error
 	excluded excludedRepos             // repo counts of excluded repos because the search query doesn't apply to them, but that we want to know about (forks, archives)
 	partial  map[api.RepoName]struct{} // repos that were searched, but have results that were not returned due to exceeded limits
 
@@ -65,6 +70,14 @@ type searchResultsCommon struct {
 	timedout []*types.Repo
 
 	indexUnavailable bool // True if indexed search is enabled but was not available during this search.
+
+	degraded bool // True if searcher was unavailable and some repositories were searched with git grep instead.
+
+	cancellationReason searchCancellationReason // why the search stopped before all repos were searched, if it did
+
+	aggregations *searchAggregations // counts of file matches by repository, language, etc.
+
+	skippedFiles map[skippedFileReason]int32 // counts of file matches that were removed because they can't be shown as text
 }
 
 func (c *searchResultsCommon) LimitHit() bool {
@@ -103,6 +116,18 @@ func (c *searchResultsCommon) IndexUnavailable() bool {
 	return c.indexUnavailable
 }
 
+func (c *searchResultsCommon) Degraded() bool {
+	return c.degraded
+}
+
+func (c *searchResultsCommon) CancellationReason() *string {
+	if c.cancellationReason == "" {
+		return nil
+	}
+	reason := string(c.cancellationReason)
+	return &reason
+}
+
 func (c *searchResultsCommon) Equal(other *searchResultsCommon) bool {
 	return reflect.DeepEqual(c, other)
 }
@@ -117,16 +142,29 @@ func RepositoryResolvers(repos types.Repos) []*RepositoryResolver {
 func (c *searchResultsCommon) update(other searchResultsCommon) {
 	c.limitHit = c.limitHit || other.limitHit
 	c.indexUnavailable = c.indexUnavailable || other.indexUnavailable
+	c.degraded = c.degraded || other.degraded
+	if c.cancellationReason == "" {
+		c.cancellationReason = other.cancellationReason
+	}
+	if other.aggregations != nil {
+		if c.aggregations == nil {
+			c.aggregations = newSearchAggregations()
+		}
+		c.aggregations.merge(other.aggregations)
+	}
 
 	c.repos = append(c.repos, other.repos...)
 	c.searched = append(c.searched, other.searched...)
 	c.indexed = append(c.indexed, other.indexed...)
 	c.cloning = append(c.cloning, other.cloning...)
 	c.missing = append(c.missing, other.missing...)
+	c.failed = append(c.failed, other.failed...)
 	c.excluded.forks = c.excluded.forks + other.excluded.forks
 	c.excluded.archived = c.excluded.archived + other.excluded.archived
+	c.excluded.byAdmin = c.excluded.byAdmin + other.excluded.byAdmin
 	c.timedout = append(c.timedout, other.timedout...)
 	c.resultCount += other.resultCount
+	c.addSkippedFiles(other.skippedFiles)
 
 	if c.partial == nil {
 		c.partial = make(map[api.RepoName]struct{})
@@ -161,12 +199,16 @@ func dedupSort(repos *types.Repos) {
 type SearchResultsResolver struct {
 	SearchResults []SearchResultResolver
 	searchResultsCommon
-	alert *searchAlert
-	start time.Time // when the results started being computed
+	alert  *searchAlert
+	alerts []*searchAlert // all alerts, including alert
+	start  time.Time      // when the results started being computed
 
 	// cursor to return for paginated search requests, or nil if the request
 	// wasn't paginated.
 	cursor *searchCursor
+
+	// repoTimings are the timings of the repositories searched by searcher.
+	repoTimings *repoTimings
 }
 
 func (sr *SearchResultsResolver) Results() []SearchResultResolver {
@@ -194,6 +236,16 @@ func (sr *SearchResultsResolver) ApproximateResultCount() string {
 
 func (sr *SearchResultsResolver) Alert() *searchAlert { return sr.alert }
 
+func (sr *SearchResultsResolver) Alerts() []*searchAlert {
+	if sr.alerts == nil && sr.alert != nil {
+		return []*searchAlert{sr.alert}
+	}
+	if sr.alerts == nil {
+		return []*searchAlert{}
+	}
+	return sr.alerts
+}
+
 func (sr *SearchResultsResolver) ElapsedMilliseconds() int32 {
 	return int32(time.Since(sr.start).Nanoseconds() / int64(time.Millisecond))
 }
@@ -577,10 +629,40 @@ func (r *searchResolver) logSearchLatency(ctx context.Context, durationMs int32)
 	}
 }
 
+// recordSearchAnalytics counts the search towards the anonymized search
+// analytics rollups (see usagestats.RecordSearchQuery). It is called once per
+// search, not per operand of an and/or query.
+func (r *searchResolver) recordSearchAnalytics(rr *SearchResultsResolver, elapsed time.Duration) {
+	options := &getPatternInfoOptions{}
+	patternType := "regexp"
+	switch r.patternType {
+	case query.SearchTypeStructural:
+		options = &getPatternInfoOptions{performStructuralSearch: true}
+		patternType = "structural"
+	case query.SearchTypeLiteral:
+		options = &getPatternInfoOptions{performLiteralSearch: true}
+		patternType = "literal"
+	}
+	var patternLength int
+	if p, err := r.getPatternInfo(options); err == nil {
+		patternLength = len(p.Pattern)
+	}
+
+	var filters []string
+	for field := range r.query.Fields() {
+		if field != query.FieldDefault {
+			filters = append(filters, field)
+		}
+	}
+
+	usagestats.RecordSearchQuery(usagestats.NewSearchQueryFeatures(patternType, patternLength, filters, elapsed, rr.MatchCount() == 0))
+}
+
 // evaluateLeaf performs a single search operation and corresponds to the
 // evaluation of leaf expression in a query.
 func (r *searchResolver) evaluateLeaf(ctx context.Context) (*SearchResultsResolver, error) {
 	start := time.Now()
+	ctx, timings := withRepoTimings(ctx)
 	// If the request specifies stable:truthy, use pagination to return a stable ordering.
 	if r.query.BoolValue("stable") {
 		result, err := r.paginatedResults(ctx)
@@ -637,6 +719,7 @@ func (r *searchResolver) evaluateLeaf(ctx context.Context) (*SearchResultsResolv
 		string(trace.RequestSource(ctx)),
 		trace.GraphQLRequestName(ctx),
 	).Inc()
+	r.emitSearchEvent(ctx, rr, err, status, alertType, time.Since(start))
 
 	if v := conf.Get().ObservabilityLogSlowSearches; v != 0 && time.Since(start).Milliseconds() > int64(v) {
 		// Note: We don't care about the error here, we just extract the username if
@@ -647,15 +730,36 @@ func (r *searchResolver) evaluateLeaf(ctx context.Context) (*SearchResultsResolv
 			currentUserName = currentUser.Username()
 		}
 
-		log15.Warn("slow search request",
+		fields := []interface{}{
 			"time", time.Since(start),
-			"query", `"`+r.rawQuery()+`"`,
+			"query", `"` + r.rawQuery() + `"`,
+			"shape", queryShape(r.query, r.patternType),
 			"type", trace.GraphQLRequestName(ctx),
 			"user", currentUserName,
 			"source", trace.RequestSource(ctx),
 			"status", status,
 			"alertType", alertType,
-		)
+			"searchRequestID", search.RequestID(ctx),
+		}
+		if rr != nil {
+			fields = append(fields,
+				"repos", len(rr.repos),
+				"searched", len(rr.searched),
+				"indexed", len(rr.indexed),
+				"cloning", len(rr.cloning),
+				"missing", len(rr.missing),
+				"timedout", len(rr.timedout),
+				"limitHit", rr.LimitHit(),
+				"indexUnavailable", rr.indexUnavailable,
+			)
+		}
+		slowest := timings.slowest(slowSearchLogRepos)
+		slowestRepos := make([]string, len(slowest))
+		for i, t := range slowest {
+			slowestRepos[i] = t.String()
+		}
+		fields = append(fields, "slowestRepos", strings.Join(slowestRepos, " "))
+		log15.Warn("slow search request", fields...)
 	}
 	return rr, err
 }
@@ -708,6 +812,12 @@ func intersect(left, right *SearchResultsResolver) (*SearchResultsResolver, erro
 		}
 
 		ltmpFileMatch.JLineMatches = append(ltmpFileMatch.JLineMatches, rtmpFileMatch.JLineMatches...)
+		if len(ltmpFileMatch.JMatchRanges) > 0 && len(rtmpFileMatch.JMatchRanges) > 0 {
+			ltmpFileMatch.JMatchRanges = append(ltmpFileMatch.JMatchRanges, rtmpFileMatch.JMatchRanges...)
+		} else {
+			// Derive the ranges of both from the line matches instead.
+			ltmpFileMatch.JMatchRanges = nil
+		}
 		merged = append(merged, ltmp)
 	}
 	left.SearchResults = merged
@@ -900,11 +1010,40 @@ func (r *searchResolver) evaluate(ctx context.Context, q []query.Node) (*SearchR
 	if err != nil {
 		return nil, err
 	}
-	sortResults(result.SearchResults)
+	r.sortResults(ctx, result.SearchResults)
 	return result, nil
 }
 
 func (r *searchResolver) Results(ctx context.Context) (*SearchResultsResolver, error) {
+	if search.RequestID(ctx) == "" {
+		ctx = search.WithRequestID(ctx, search.NewRequestID())
+	}
+	ctx, err := withSearchTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, release, err := searchTenantQuotas.acquire(ctx)
+	if err != nil {
+		return nil, withSearchErrorCode(err)
+	}
+	defer release()
+	activeSearches.add(1)
+	defer activeSearches.add(-1)
+	ctx, done := runningSearches.start(ctx, queryShape(r.query, r.patternType))
+	defer done()
+	ctx = withSearchFeatureFlags(ctx)
+	ctx, timings := withRepoTimings(ctx)
+	start := time.Now()
+	rr, err := r.results(ctx)
+	if rr != nil {
+		rr.repoTimings = timings
+		batchRepositories(rr.SearchResults)
+		r.recordSearchAnalytics(rr, time.Since(start))
+	}
+	return rr, withSearchErrorCode(err)
+}
+
+func (r *searchResolver) results(ctx context.Context) (*SearchResultsResolver, error) {
 	switch q := r.query.(type) {
 	case *query.OrdinaryQuery:
 		return r.evaluateLeaf(ctx)
@@ -1021,11 +1160,19 @@ func (r *searchResolver) Stats(ctx context.Context) (stats *searchResultsStats,
 	// would never cache them. This fixes that by ensuring the first request
 	// 'kicks off loading' and places the result into cache regardless of
 	// whether or not the original querier of this information still wants it.
-	originalCtx := ctx
+	originalCtx, err := withSearchTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
 	ctx = context.Background()
 	ctx = opentracing.ContextWithSpan(ctx, opentracing.SpanFromContext(originalCtx))
+	ctx = withSearchTenantFrom(ctx, originalCtx)
 
+	// Tenants search different repositories, so they must not share stats.
 	cacheKey := r.rawQuery()
+	if name := search.TenantName(ctx); name != "" {
+		cacheKey = name + "\x00" + cacheKey
+	}
 	// Check if value is in the cache.
 	jsonRes, ok := searchResultsStatsCache.Get(cacheKey)
 	if ok {
@@ -1155,13 +1302,21 @@ func processSearchPattern(q query.QueryInfo, opts *getPatternInfoOptions) (strin
 				}
 			case v.Regexp != nil:
 				piece = v.Regexp.String()
+			case v.PCRE != nil:
+				piece = v.PCRE.String()
 			}
 			if piece == "" {
 				continue
 			}
 			pieces = append(pieces, piece)
 		}
-		pattern = orderedFuzzyRegexp(pieces)
+		if engine, _ := q.StringValue(query.FieldEngine); strings.EqualFold(engine, query.EnginePCRE) && len(pieces) > 1 {
+			// Non-capturing groups keep the numbers of the groups that
+			// backreferences in the pieces refer to.
+			pattern = "(?:" + strings.Join(pieces, ").*?(?:") + ")"
+		} else {
+			pattern = orderedFuzzyRegexp(pieces)
+		}
 	} else {
 		// TODO: We must have some pattern that always matches here, or else
 		// cmd/searcher/search/matcher.go:97 would cause a nil regexp panic
@@ -1194,6 +1349,8 @@ func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.Tex
 		combyRule = append(combyRule, v.ToString())
 	}
 
+	fileIncludePatterns := includePatterns
+
 	// Handle lang: and -lang: filters.
 	langIncludePatterns, langExcludePatterns, err := langIncludeExcludePatterns(q.StringValues(query.FieldLang))
 	if err != nil {
@@ -1204,10 +1361,109 @@ func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.Tex
 
 	languages, _ := q.StringValues(query.FieldLang)
 
+	// Exclude the paths that searches exclude by default, such as build
+	// output, unless the query opts out with defaultexclusions:no.
+	if defaultExclusionsEnabled(q) {
+		defaultExcludePatterns, err := defaultExclusionPatterns(languages, fileIncludePatterns)
+		if err != nil {
+			return nil, err
+		}
+		excludePatterns = append(excludePatterns, defaultExcludePatterns...)
+	}
+
+	identifierMode, _ := q.StringValue(query.FieldIdentifier)
+	isIdentifierMatch, identifierSubTokens, err := query.ParseIdentifierMode(identifierMode)
+	if err != nil {
+		return nil, err
+	}
+
+	within, err := withinContentClasses(q.StringValues(query.FieldWithin))
+	if err != nil {
+		return nil, err
+	}
+	if isStructuralPat && within != nil {
+		return nil, errors.New("the within: filter is not supported for structural search")
+	}
+
+	goCall, _ := q.StringValue(query.FieldGoCall)
+	goSelector, _ := q.StringValue(query.FieldGoSelector)
+	isCaseSensitive := q.IsCaseSensitive()
+	if goCall != "" || goSelector != "" {
+		// The pattern of Go AST searches matches the Go files that contain
+		// the name, which searcher then parses.
+		if goCall != "" && goSelector != "" {
+			return nil, errors.New("the gocall: and goselector: filters can't be used together")
+		}
+		if pattern != "" {
+			return nil, errors.New("the gocall: and goselector: filters can't be used with a search pattern")
+		}
+		n, err := goast.ParseName(goCall + goSelector)
+		if err != nil {
+			return nil, err
+		}
+		pattern, isRegExp, isCaseSensitive = regexp.QuoteMeta(n.Name), true, true
+		includePatterns = append(includePatterns, `\.go$`)
+	}
+
+	var modifiedSince time.Time
+	var modifiedSinceRevision string
+	if modified, _ := q.StringValue(query.FieldModified); modified != "" {
+		if isStructuralPat {
+			return nil, errors.New("the modified: filter is not supported for structural search")
+		}
+		d, revision, err := query.ParseModified(modified)
+		if err != nil {
+			return nil, err
+		}
+		if d > 0 {
+			modifiedSince = time.Now().Add(-d)
+		}
+		modifiedSinceRevision = revision
+	}
+
+	var isPCRE bool
+	if engine, _ := q.StringValue(query.FieldEngine); engine != "" {
+		engine, err := query.ParseEngine(engine)
+		if err != nil {
+			return nil, err
+		}
+		isPCRE = engine == query.EnginePCRE
+	}
+	if isPCRE {
+		if isStructuralPat {
+			return nil, errors.New("engine:pcre is not supported for structural search")
+		}
+		switch q.(type) {
+		case query.AndOrQuery, *query.AndOrQuery:
+			return nil, errors.New("engine:pcre is not supported for queries with and/or expressions")
+		}
+	}
+
+	var normalization string
+	if normalize, _ := q.StringValue(query.FieldNormalize); normalize != "" {
+		if isStructuralPat {
+			return nil, errors.New("the normalize: filter is not supported for structural search")
+		}
+		if normalization, err = query.ParseNormalization(normalize); err != nil {
+			return nil, err
+		}
+	}
+
+	// The word characters of the wordchars: filter apply to all languages,
+	// instead of those of the search.wordCharacters site setting.
+	wordCharacters := identifier.WordCharacters(conf.Get().SearchWordCharacters)
+	if values := q.Values(query.FieldWordChars); len(values) > 0 {
+		chars := values[0].ToString()
+		if err := identifier.CheckWordCharacters(chars); err != nil {
+			return nil, err
+		}
+		wordCharacters = identifier.WordCharacters{"*": chars}
+	}
+
 	patternInfo := &search.TextPatternInfo{
 		IsRegExp:                     isRegExp,
 		IsStructuralPat:              isStructuralPat,
-		IsCaseSensitive:              q.IsCaseSensitive(),
+		IsCaseSensitive:              isCaseSensitive,
 		FileMatchLimit:               opts.fileMatchLimit,
 		Pattern:                      pattern,
 		IncludePatterns:              includePatterns,
@@ -1217,6 +1473,19 @@ func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.Tex
 		Languages:                    languages,
 		PathPatternsAreCaseSensitive: q.IsCaseSensitive(),
 		CombyRule:                    strings.Join(combyRule, ""),
+		Deterministic:                q.BoolValue(query.FieldDeterministic),
+		IsIdentifierMatch:            isIdentifierMatch,
+		IdentifierSubTokens:          identifierSubTokens,
+		WordCharacters:               wordCharacters,
+		Within:                       within,
+		GoCall:                       goCall,
+		GoSelector:                   goSelector,
+		ModifiedSince:                modifiedSince,
+		ModifiedSinceRevision:        modifiedSinceRevision,
+		FirstMatchPerRepo:            q.BoolValue(query.FieldFirstMatch),
+		IsPCRE:                       isPCRE && isRegExp,
+		CaseFold:                     q.BoolValue(query.FieldCaseFold),
+		Normalization:                normalization,
 	}
 	if len(excludePatterns) > 0 {
 		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
@@ -1224,6 +1493,95 @@ func getPatternInfo(q query.QueryInfo, opts *getPatternInfoOptions) (*search.Tex
 	return patternInfo, nil
 }
 
+// defaultExclusionsEnabled returns true unless q opts out of the default
+// exclusions with defaultexclusions:no.
+func defaultExclusionsEnabled(q query.QueryInfo) bool {
+	return len(q.Values(query.FieldDefaultExclusions)) == 0 || q.BoolValue(query.FieldDefaultExclusions)
+}
+
+// defaultExclusionPatterns returns the regexps of the paths that searches
+// exclude by default (see the search.defaultExclusions site setting) for the
+// languages of the lang: filter values, or for all languages if there are
+// none. Paths named by a file: filter pattern, such as file:^dist/, are not
+// excluded, since the query asks for them explicitly.
+func defaultExclusionPatterns(values, fileIncludePatterns []string) ([]string, error) {
+	languages := make([]string, 0, len(values))
+	for _, value := range values {
+		if lang, ok := enry.GetLanguageByAlias(value); ok {
+			languages = append(languages, lang)
+		}
+	}
+	patterns, err := exclusions.Patterns(conf.Get().SearchDefaultExclusions).WithDefaults().Regexps(languages)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid search.defaultExclusions site setting")
+	}
+	if len(fileIncludePatterns) == 0 {
+		return patterns, nil
+	}
+
+	kept := patterns[:0]
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid search.defaultExclusions site setting")
+		}
+		named := false
+		for _, p := range fileIncludePatterns {
+			if re.MatchString(literalFilePath(p)) {
+				named = true
+				break
+			}
+		}
+		if !named {
+			kept = append(kept, pattern)
+		}
+	}
+	return kept, nil
+}
+
+// literalFilePath returns the path that the file: filter pattern p names,
+// e.g. "dist/app.js" for `^dist/app\.js$`. Regexp syntax other than anchors
+// and escapes is kept as is.
+func literalFilePath(p string) string {
+	p = strings.TrimSuffix(strings.TrimPrefix(p, "^"), "$")
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+1 < len(p) && !isAlphanumeric(p[i+1]) {
+			i++
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+func isAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// withinContentClasses returns the names of the content classes that matches
+// must start in according to the values of the within: and -within: filters,
+// or nil if matches can be anywhere.
+func withinContentClasses(values, negatedValues []string) ([]string, error) {
+	within, err := contentclass.ParseSet(values)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range negatedValues {
+		c, err := contentclass.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		within &^= contentclass.Set(c)
+	}
+	switch within {
+	case contentclass.All:
+		return nil, nil
+	case 0:
+		return nil, errors.New("the within: and -within: filters exclude all matches")
+	}
+	return within.Names(), nil
+}
+
 // langIncludeExcludePatterns returns regexps for the include/exclude path patterns given the lang:
 // and -lang: filter values in a search query. For example, a query containing "lang:go" should
 // include files whose paths match /\.go$/.
@@ -1261,13 +1619,35 @@ var (
 	maxTimeout = time.Minute
 )
 
+// searchDefaultTimeout returns the timeout of searches without a timeout: or
+// count: filter, from the search.limits site configuration.
+func searchDefaultTimeout() time.Duration {
+	if limits := conf.Get().SearchLimits; limits != nil {
+		if d, err := time.ParseDuration(limits.DefaultTimeout); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultTimeout
+}
+
+// searchMaxTimeout returns the maximum timeout of searches, from the
+// search.limits site configuration.
+func searchMaxTimeout() time.Duration {
+	if limits := conf.Get().SearchLimits; limits != nil {
+		if d, err := time.ParseDuration(limits.MaxTimeout); err == nil && d > 0 {
+			return d
+		}
+	}
+	return maxTimeout
+}
+
 func (r *searchResolver) searchTimeoutFieldSet() bool {
 	timeout, _ := r.query.StringValue(query.FieldTimeout)
 	return timeout != "" || r.countIsSet()
 }
 
 func (r *searchResolver) withTimeout(ctx context.Context) (context.Context, context.CancelFunc, error) {
-	d := defaultTimeout
+	d, max := searchDefaultTimeout(), searchMaxTimeout()
 	timeout, _ := r.query.StringValue(query.FieldTimeout)
 	if timeout != "" {
 		var err error
@@ -1277,11 +1657,11 @@ func (r *searchResolver) withTimeout(ctx context.Context) (context.Context, cont
 		}
 	} else if r.countIsSet() {
 		// If `count:` is set but `timeout:` is not explicitly set, use the max timeout
-		d = maxTimeout
+		d = max
 	}
-	// don't run queries longer than 1 minute.
-	if d.Minutes() > 1 {
-		d = maxTimeout
+	// don't run queries longer than the max timeout.
+	if d > max {
+		d = max
 	}
 	ctx, cancel := context.WithTimeout(ctx, d)
 	return ctx, cancel, nil
@@ -1323,6 +1703,9 @@ func (r *searchResolver) determineRepos(ctx context.Context, tr *trace.Trace, st
 	tr.LazyPrintf("searching %d repos, %d missing", len(repos), len(missingRepoRevs))
 	if len(repos) == 0 {
 		alert := r.alertForNoResolvedRepos(ctx)
+		if excludedRepos != nil && excludedRepos.byAdmin > 0 {
+			alert = alertForReposExcludedByAdmin(excludedRepos.byAdmin)
+		}
 		return nil, nil, nil, &SearchResultsResolver{alert: alert, start: start}, nil
 	}
 	if overLimit {
@@ -1401,6 +1784,9 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 	if err != nil {
 		return nil, err
 	}
+	if p.GoCall != "" || p.GoSelector != "" || p.FirstMatchPerRepo || p.IsPCRE {
+		forceOnlyResultType = "file"
+	}
 
 	// Fallback to literal search for searching repos and files if
 	// the structural search pattern is empty.
@@ -1539,12 +1925,22 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 				continue
 			}
 			searchedFileContentsOrPaths = true
+			searchFiles := searchFilesInRepos
+			if historyRange, _ := r.query.StringValue(query.FieldHistory); historyRange != "" {
+				searchFiles = func(ctx context.Context, args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+					return searchFilesInHistory(ctx, args, historyRange)
+				}
+			}
 			wg := waitGroup(true)
 			wg.Add(1)
 			goroutine.Go(func() {
 				defer wg.Done()
 
-				fileResults, fileCommon, err := searchFilesInRepos(ctx, &args)
+				fileCtx := ctx
+				if r.resultStream != nil {
+					fileCtx = withSearchStream(ctx, r.resultStream)
+				}
+				fileResults, fileCommon, err := searchFiles(fileCtx, &args)
 				// Timeouts are reported through searchResultsCommon so don't report an error for them
 				if err != nil && !isContextError(ctx, err) {
 					multiErrMu.Lock()
@@ -1555,7 +1951,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					// No results for structural search? Automatically search again and force Zoekt to resolve
 					// more potential file matches by setting a higher FileMatchLimit.
 					args.PatternInfo.FileMatchLimit = 1000
-					fileResults, fileCommon, err = searchFilesInRepos(ctx, &args)
+					fileResults, fileCommon, err = searchFiles(ctx, &args)
 					if err != nil && !isContextError(ctx, err) {
 						multiErrMu.Lock()
 						multiErr = multierror.Append(multiErr, errors.Wrap(err, "text search failed"))
@@ -1567,6 +1963,14 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 						fileCommon.limitHit = false // Ensure we don't display "Show more".
 					}
 				}
+				if args.PatternInfo.FirstMatchPerRepo {
+					// Only the repositories with matches are results.
+					repoResults := repositoryResultsOfFileMatches(fileResults)
+					resultsMu.Lock()
+					results = append(results, repoResults...)
+					resultsMu.Unlock()
+					fileResults = nil
+				}
 				for _, r := range fileResults {
 					key := r.uri
 					fileMatchesMu.Lock()
@@ -1727,9 +2131,30 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		alert = alertForMissingRepoRevs(r.patternType, missingRepoRevs)
 	}
 
+	// alerts are all alerts that apply to the search, the most important one
+	// (alert) first.
+	var alerts []*searchAlert
+	if alert != nil {
+		alerts = append(alerts, alert)
+	}
+	if common.degraded {
+		alerts = append(alerts, alertForDegradedSearch())
+	}
+	if len(common.failed) > 0 {
+		alerts = append(alerts, r.alertForFailedRepos(&common))
+	}
+	if alert == nil && len(alerts) > 0 {
+		alert = alerts[0]
+	}
+
 	if len(results) == 0 && strings.Contains(r.originalQuery, `"`) && r.patternType == query.SearchTypeLiteral {
 		alert = alertForQuotesInQueryInLiteralMode(r.query.ParseTree())
 	}
+	if len(results) == 0 && alert == nil && defaultExclusionsEnabled(r.query) {
+		if fileFilters, _ := r.query.RegexpPatterns(query.FieldFile); len(fileFilters) > 0 {
+			alert = alertForDefaultExclusions(r)
+		}
+	}
 
 	// If we have some results, only log the error instead of returning it,
 	// because otherwise the client would not receive the partial results
@@ -1738,13 +2163,14 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		multiErr = nil
 	}
 
-	sortResults(results)
+	r.sortResults(ctx, results)
 
 	resultsResolver := SearchResultsResolver{
 		start:               start,
 		searchResultsCommon: common,
 		SearchResults:       results,
 		alert:               alert,
+		alerts:              alerts,
 	}
 
 	return &resultsResolver, multiErr.ErrorOrNil()
@@ -1756,6 +2182,21 @@ func isContextError(ctx context.Context, err error) bool {
 	return ctx.Err() != nil || err == context.Canceled || err == context.DeadlineExceeded
 }
 
+// repositoryResultsOfFileMatches returns a repository result for each
+// repository that has file matches, in the order of their first match.
+func repositoryResultsOfFileMatches(matches []*FileMatchResolver) []SearchResultResolver {
+	var results []SearchResultResolver
+	seen := make(map[api.RepoID]struct{})
+	for _, fm := range matches {
+		if _, ok := seen[fm.Repo.ID]; ok {
+			continue
+		}
+		seen[fm.Repo.ID] = struct{}{}
+		results = append(results, &RepositoryResolver{repo: fm.Repo})
+	}
+	return results
+}
+
 // SearchResultResolver is a resolver for the GraphQL union type `SearchResult`.
 //
 // Supported types:
@@ -1794,6 +2235,60 @@ func sortResults(r []SearchResultResolver) {
 	sort.Slice(r, func(i, j int) bool { return compareSearchResults(r[i], r[j]) })
 }
 
+// sortResultsDeterministic sorts r like sortResults, but orders results that
+// sortResults considers equal too, so that the order does not depend on the
+// order in which the results were found. Commit results are ordered by date
+// (most recent first), repository and commit ID, and file matches with the same
+// repository and path by commit ID.
+func sortResultsDeterministic(r []SearchResultResolver) {
+	sort.SliceStable(r, func(i, j int) bool {
+		a, b := r[i], r[j]
+		if compareSearchResults(a, b) {
+			return true
+		}
+		if compareSearchResults(b, a) {
+			return false
+		}
+		return compareSearchResultsTiebreak(a, b)
+	})
+}
+
+func compareSearchResultsTiebreak(a, b SearchResultResolver) bool {
+	if ac, ok := a.ToCommitSearchResult(); ok {
+		bc, ok := b.ToCommitSearchResult()
+		if !ok {
+			return false
+		}
+		if adate, bdate := ac.commit.author.Date(), bc.commit.author.Date(); adate != bdate {
+			return adate > bdate
+		}
+		if arepo, brepo := ac.commit.repo.repo.Name, bc.commit.repo.repo.Name; arepo != brepo {
+			return arepo < brepo
+		}
+		return ac.commit.oid < bc.commit.oid
+	}
+	if afm, ok := a.ToFileMatch(); ok {
+		if bfm, ok := b.ToFileMatch(); ok {
+			return afm.CommitID < bfm.CommitID
+		}
+	}
+	return false
+}
+
+// sortResults sorts the results of the search by repository and path, and
+// then ranks good usage examples first if the search looks for usage examples
+// of an API symbol (see rankUsageExamples).
+func (r *searchResolver) sortResults(ctx context.Context, results []SearchResultResolver) {
+	if r.query.BoolValue(query.FieldDeterministic) {
+		sortResultsDeterministic(results)
+	} else {
+		sortResults(results)
+	}
+	if r.rankUsageExamples() {
+		sortUsageExamples(ctx, results)
+	}
+}
+
 // orderedFuzzyRegexp interpolate a lazy 'match everything' regexp pattern
 // to achieve an ordered fuzzy regexp match.
 func orderedFuzzyRegexp(pieces []string) string {
@@ -14,8 +14,10 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	searchquerytypes "github.com/sourcegraph/sourcegraph/internal/search/query/types"
 	"github.com/sourcegraph/sourcegraph/schema"
@@ -84,6 +86,10 @@ func TestSearchResults(t *testing.T) {
 		}
 		db.Mocks.Repos.MockGetByName(t, "repo", 1)
 		db.Mocks.Repos.MockGet(t, 1)
+		db.Mocks.Repos.GetByURIs = func(_ context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+			assertEqual(t, nameOrURIs, []string{"repo"})
+			return []*types.Repo{{ID: 1, Name: "repo", RepoFields: &types.RepoFields{}}}, nil
+		}
 		db.Mocks.Repos.Count = mockCount
 
 		mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
@@ -413,10 +419,82 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			PathPatternsAreRegExps: true,
 			ExcludePattern:         `f|(\.graphql$|\.gql$|\.graphqls$)`,
 		},
+		"p identifier:yes wordchars:-$": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			IsIdentifierMatch:      true,
+			WordCharacters:         identifier.WordCharacters{"*": "-$"},
+		},
+		"p within:comment": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			Within:                 []string{"comment"},
+		},
+		"p -within:string": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			Within:                 []string{"code", "comment"},
+		},
+		"gocall:mux.NewRouter file:f": {
+			Pattern:                "NewRouter",
+			IsRegExp:               true,
+			IsCaseSensitive:        true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{"f", `\.go$`},
+			GoCall:                 "mux.NewRouter",
+		},
+		"goselector:Handler": {
+			Pattern:                "Handler",
+			IsRegExp:               true,
+			IsCaseSensitive:        true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{`\.go$`},
+			GoSelector:             "Handler",
+		},
+		"p within:code within:comment within:string": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+		},
+		"p firstmatch:yes": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			FirstMatchPerRepo:      true,
+		},
+		"p modified:v1.0": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			ModifiedSinceRevision:  "v1.0",
+		},
+		"p casefold:yes normalize:NFD": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			CaseFold:               true,
+			Normalization:          "nfd",
+		},
+		"p(?=q) engine:pcre": {
+			Pattern:                "p(?=q)",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			IsPCRE:                 true,
+		},
+		`(\w)\1 p engine:pcre`: {
+			Pattern:                `(?:(\w)\1).*?(?:p)`,
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+			IsPCRE:                 true,
+		},
 	}
 	for queryStr, want := range tests {
 		t.Run(queryStr, func(t *testing.T) {
-			query, err := query.ParseAndCheck(queryStr)
+			// The default exclusions are tested separately.
+			query, err := query.ParseAndCheck(queryStr + " defaultexclusions:no")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -434,6 +512,150 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 	}
 }
 
+func TestSearchResolver_getPatternInfo_defaultExclusions(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchDefaultExclusions: map[string][]string{
+			"CSS":        {},
+			"Java":       {},
+			"JavaScript": {"dist/", "*.min.js"},
+			"Python":     {"__pycache__/"},
+			"Rust":       {"target/"},
+			"TypeScript": {},
+		},
+	}})
+
+	tests := map[string]string{
+		"p":                       `((?:^|/)[^/]*\.min\.js(?:/|$))|((?:^|/)__pycache__/)|((?:^|/)dist/)|((?:^|/)target/)`,
+		"p -file:f":               `f|((?:^|/)[^/]*\.min\.js(?:/|$))|((?:^|/)__pycache__/)|((?:^|/)dist/)|((?:^|/)target/)`,
+		"p file:^dist/":           `((?:^|/)[^/]*\.min\.js(?:/|$))|((?:^|/)__pycache__/)|((?:^|/)target/)`,
+		`p file:\.min\.js$`:       `((?:^|/)__pycache__/)|((?:^|/)dist/)|((?:^|/)target/)`,
+		"p lang:python":           `(?:^|/)__pycache__/`,
+		"p lang:go":               "",
+		"p defaultexclusions:no":  "",
+		"p defaultexclusions:yes": `((?:^|/)[^/]*\.min\.js(?:/|$))|((?:^|/)__pycache__/)|((?:^|/)dist/)|((?:^|/)target/)`,
+	}
+	for queryStr, want := range tests {
+		q, err := query.ParseAndCheck(queryStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := searchResolver{query: q}
+		p, err := sr.getPatternInfo(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ExcludePattern != want {
+			t.Errorf("%s: got exclude pattern %q, want %q", queryStr, p.ExcludePattern, want)
+		}
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchDefaultExclusions: map[string][]string{"*": {"[a"}},
+	}})
+	q, err := query.ParseAndCheck("p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := searchResolver{query: q}
+	if _, err := sr.getPatternInfo(nil); err == nil {
+		t.Error("got nil error for an invalid search.defaultExclusions site setting")
+	}
+}
+
+func TestSearchResolver_getPatternInfo_wordCharacters(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchWordCharacters: map[string]string{"CSS": "-", "Shell": "$"},
+	}})
+
+	tests := map[string]identifier.WordCharacters{
+		"p":              {"CSS": "-", "Shell": "$"},
+		"p wordchars:?":  {"*": "?"},
+		`p wordchars:""`: {"*": ""},
+	}
+	for queryStr, want := range tests {
+		q, err := query.ParseAndCheck(queryStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := searchResolver{query: q}
+		p, err := sr.getPatternInfo(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p.WordCharacters, want) {
+			t.Errorf("%s: got word characters %v, want %v", queryStr, p.WordCharacters, want)
+		}
+	}
+
+	q, err := query.ParseAndCheck("p wordchars:ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := searchResolver{query: q}
+	if _, err := sr.getPatternInfo(nil); err == nil {
+		t.Error("got no error for invalid word characters")
+	}
+}
+
+func TestSearchResolver_getPatternInfo_within(t *testing.T) {
+	tests := map[string]*getPatternInfoOptions{
+		"p within:code -within:code": {},
+		"p within:docs":              {},
+		"p within:comment":           {performStructuralSearch: true},
+		"p gocall:New":               {},
+		"gocall:New goselector:New":  {},
+		"p modified:14d":             {performStructuralSearch: true},
+		"p engine:pcre":              {performStructuralSearch: true},
+		"p engine:perl":              {},
+		"p normalize:nfc":            {performStructuralSearch: true},
+	}
+	for queryStr, opts := range tests {
+		q, err := query.ParseAndCheck(queryStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := getPatternInfo(q, opts); err == nil {
+			t.Errorf("%s: got no error", queryStr)
+		}
+	}
+}
+
+func TestSearchResolver_getPatternInfo_modified(t *testing.T) {
+	q, err := query.ParseAndCheck("p modified:2w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	p, err := getPatternInfo(q, &getPatternInfoOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := before.Add(-14 * 24 * time.Hour); p.ModifiedSince.Before(want) || p.ModifiedSince.After(want.Add(time.Minute)) {
+		t.Errorf("got modified since %s, want %s", p.ModifiedSince, want)
+	}
+	if p.ModifiedSinceRevision != "" {
+		t.Errorf("got modified since revision %q, want none", p.ModifiedSinceRevision)
+	}
+}
+
+func TestRepositoryResultsOfFileMatches(t *testing.T) {
+	foo, bar := &types.Repo{ID: 1, Name: "foo"}, &types.Repo{ID: 2, Name: "bar"}
+	results := repositoryResultsOfFileMatches([]*FileMatchResolver{
+		{JPath: "a", Repo: bar},
+		{JPath: "b", Repo: foo},
+		{JPath: "c", Repo: bar},
+	})
+	var got []string
+	for _, r := range results {
+		got = append(got, r.(*RepositoryResolver).Name())
+	}
+	if want := []string{"bar", "foo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repositories %q, want %q", got, want)
+	}
+}
+
 func TestSearchResolver_DynamicFilters(t *testing.T) {
 	repo := &types.Repo{Name: "testRepo"}
 
@@ -720,6 +942,40 @@ func TestCompareSearchResults(t *testing.T) {
 	}
 }
 
+func TestSortResultsDeterministic(t *testing.T) {
+	repo := func(name string) *RepositoryResolver {
+		return &RepositoryResolver{repo: &types.Repo{Name: api.RepoName(name)}}
+	}
+	commit := func(repoName string, oid GitObjectID, date time.Time) *commitSearchResultResolver {
+		return &commitSearchResultResolver{commit: &GitCommitResolver{repo: repo(repoName), oid: oid, author: signatureResolver{date: date}}}
+	}
+	fileMatch := func(repoName, path string, commitID api.CommitID) *FileMatchResolver {
+		return &FileMatchResolver{Repo: &types.Repo{Name: api.RepoName(repoName)}, JPath: path, CommitID: commitID}
+	}
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []SearchResultResolver{
+		repo("a"),
+		fileMatch("a", "x", "c1"),
+		fileMatch("a", "x", "c2"),
+		fileMatch("a", "y", "c1"),
+		fileMatch("b", "x", "c1"),
+		commit("b", "o2", t0.Add(time.Hour)),
+		commit("a", "o2", t0),
+		commit("b", "o1", t0),
+		commit("b", "o3", t0),
+	}
+	for i := 0; i < 20; i++ {
+		got := append([]SearchResultResolver(nil), want...)
+		rand.Shuffle(len(got), func(i, j int) { got[i], got[j] = got[j], got[i] })
+		sortResultsDeterministic(got)
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("got result %d of the shuffled results sorted as %+v, want %+v", j, got[j], want[j])
+			}
+		}
+	}
+}
+
 func TestLonger(t *testing.T) {
 	N := 2
 	noise := time.Nanosecond
@@ -838,6 +1094,9 @@ func TestSearchResultsHydration(t *testing.T) {
 	db.Mocks.Repos.Get = func(ctx context.Context, id api.RepoID) (*types.Repo, error) {
 		return hydratedRepo, nil
 	}
+	db.Mocks.Repos.GetByURIs = func(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+		return []*types.Repo{hydratedRepo}, nil
+	}
 
 	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
 		return []*types.Repo{repoWithIDs}, nil
@@ -0,0 +1,60 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+type retrySearchArgs struct {
+	Version        string
+	PatternType    *string
+	Query          string
+	VersionContext *string
+	Repositories   []graphql.ID
+}
+
+// RetrySearch runs a search again over only some of the repositories it
+// matches, e.g. the ones that timed out or failed in a previous run of it.
+func (r *schemaResolver) RetrySearch(ctx context.Context, args *retrySearchArgs) (*SearchResultsResolver, error) {
+	if len(args.Repositories) == 0 {
+		return nil, errors.New("at least one repository to search again is required")
+	}
+	repoIDs := make(map[api.RepoID]struct{}, len(args.Repositories))
+	for _, id := range args.Repositories {
+		repoID, err := UnmarshalRepositoryID(id)
+		if err != nil {
+			return nil, err
+		}
+		repoIDs[repoID] = struct{}{}
+	}
+
+	s, err := NewSearchImplementer(&SearchArgs{
+		Version:        args.Version,
+		PatternType:    args.PatternType,
+		Query:          args.Query,
+		VersionContext: args.VersionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sr, ok := s.(*searchResolver); ok {
+		sr.onlyRepoIDs = repoIDs
+	}
+	return s.Results(ctx)
+}
+
+// filterRepoRevsByID returns the repository revisions of repoRevs whose
+// repository is in ids.
+func filterRepoRevsByID(repoRevs []*search.RepositoryRevisions, ids map[api.RepoID]struct{}) []*search.RepositoryRevisions {
+	filtered := repoRevs[:0:0]
+	for _, rr := range repoRevs {
+		if _, ok := ids[rr.Repo.ID]; ok {
+			filtered = append(filtered, rr)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,32 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestFilterRepoRevsByID(t *testing.T) {
+	repoRevs := []*search.RepositoryRevisions{
+		{Repo: &types.Repo{ID: 1, Name: "a"}},
+		{Repo: &types.Repo{ID: 2, Name: "b"}},
+		{Repo: &types.Repo{ID: 3, Name: "c"}},
+	}
+	got := filterRepoRevsByID(repoRevs, map[api.RepoID]struct{}{1: {}, 3: {}, 4: {}})
+	if want := []*search.RepositoryRevisions{repoRevs[0], repoRevs[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(repoRevs) != 3 || repoRevs[1].Repo.ID != 2 {
+		t.Error("expected input to be left unchanged")
+	}
+}
+
+func TestRetrySearch_noRepositories(t *testing.T) {
+	if _, err := (&schemaResolver{}).RetrySearch(context.Background(), &retrySearchArgs{Version: "V2", Query: "foo"}); err == nil {
+		t.Error("expected an error when no repositories are given")
+	}
+}
@@ -0,0 +1,111 @@
+package graphqlbackend
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/rules"
+)
+
+// filterRules tags the line matches with the IDs of the rules of the
+// search.rules site setting that they hit (see rules.Ruleset.Match).
+//
+// If q has ruleid: or severity: filters, it also removes the line matches
+// that don't satisfy them, and the file matches that are left without line
+// matches. A line match satisfies them if it hits one of the rules of the
+// ruleid: filters (if there are any), none of the rules of the -ruleid:
+// filters and a rule with at least the severity of the severity: filter.
+func filterRules(q query.QueryInfo, matches []*FileMatchResolver) []*FileMatchResolver {
+	rs := rules.Current()
+	ids, notIDs := q.StringValues(query.FieldRuleID)
+	var minSeverity rules.Severity
+	if v, _ := q.StringValue(query.FieldSeverity); v != "" {
+		minSeverity, _ = rules.ParseSeverity(v)
+	}
+	filter := len(ids) > 0 || len(notIDs) > 0 || minSeverity != 0
+	if rs.Len() == 0 && !filter {
+		return matches
+	}
+
+	filtered := matches[:0]
+	for _, fm := range matches {
+		lineMatches := fm.JLineMatches[:0]
+		matchCount := 0
+		for _, lm := range fm.JLineMatches {
+			lm.JRuleIDs = rs.Match(fm.JPath, lm.JPreview)
+			if filter && !matchesRules(rs, lm.JRuleIDs, ids, notIDs, minSeverity) {
+				continue
+			}
+			lineMatches = append(lineMatches, lm)
+			matchCount += len(lm.JOffsetAndLengths)
+		}
+		if !filter {
+			filtered = append(filtered, fm)
+			continue
+		}
+		if len(lineMatches) == 0 {
+			continue
+		}
+		fm.JLineMatches = lineMatches
+		fm.MatchCount = matchCount
+		filtered = append(filtered, fm)
+	}
+	return filtered
+}
+
+// matchesRules reports whether the rules with the IDs hit include one of ids
+// (if there are any), none of notIDs and one with at least minSeverity.
+func matchesRules(rs *rules.Ruleset, hit, ids, notIDs []string, minSeverity rules.Severity) bool {
+	if len(hit) == 0 {
+		return false
+	}
+	hasID := func(want []string) bool {
+		for _, id := range hit {
+			for _, w := range want {
+				if id == w {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if hasID(notIDs) || (len(ids) > 0 && !hasID(ids)) {
+		return false
+	}
+	for _, id := range hit {
+		if r := rs.Rule(id); r != nil && r.Severity >= minSeverity {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules that the line hits. Rules that were removed from
+// the site configuration since the search are omitted.
+func (lm *lineMatch) Rules() []*searchRuleResolver {
+	rs := rules.Current()
+	var resolvers []*searchRuleResolver
+	for _, id := range lm.JRuleIDs {
+		if r := rs.Rule(id); r != nil {
+			resolvers = append(resolvers, &searchRuleResolver{rule: r})
+		}
+	}
+	return resolvers
+}
+
+type searchRuleResolver struct {
+	rule *rules.Rule
+}
+
+func (r *searchRuleResolver) ID() string { return r.rule.ID }
+
+func (r *searchRuleResolver) Description() *string {
+	if r.rule.Description == "" {
+		return nil
+	}
+	return &r.rule.Description
+}
+
+func (r *searchRuleResolver) Severity() string {
+	return strings.ToUpper(r.rule.Severity.String())
+}
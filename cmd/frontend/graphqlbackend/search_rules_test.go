@@ -0,0 +1,89 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestFilterRules(t *testing.T) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchRules: []*schema.SearchRule{
+		{Id: "go/weak-hash", Description: "Weak hash function", Pattern: `\bmd5\.`, Severity: "high", File: `\.go$`},
+		{Id: "todo", Pattern: "TODO", Severity: "low"},
+	}}})
+	defer conf.Mock(nil)
+
+	newMatches := func() []*FileMatchResolver {
+		return []*FileMatchResolver{
+			{JPath: "a.go", MatchCount: 3, JLineMatches: []*lineMatch{
+				{JPreview: "h := md5.Sum(x)", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{5, 3}}},
+				{JPreview: "// TODO: md5", JLineNumber: 2, JOffsetAndLengths: [][2]int32{{9, 3}}},
+				{JPreview: "md5 := 1", JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 3}}},
+			}},
+			{JPath: "a.py", MatchCount: 1, JLineMatches: []*lineMatch{
+				{JPreview: "md5.new() # TODO", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{0, 3}}},
+			}},
+		}
+	}
+
+	// lines returns the line numbers of the line matches of each file.
+	lines := func(matches []*FileMatchResolver) map[string][]int32 {
+		got := map[string][]int32{}
+		for _, fm := range matches {
+			for _, lm := range fm.JLineMatches {
+				got[fm.JPath] = append(got[fm.JPath], lm.JLineNumber)
+			}
+		}
+		return got
+	}
+
+	tests := []struct {
+		query string
+		want  map[string][]int32
+	}{
+		{query: "md5", want: map[string][]int32{"a.go": {1, 2, 3}, "a.py": {1}}},
+		{query: "md5 ruleid:go/weak-hash", want: map[string][]int32{"a.go": {1}}},
+		{query: "md5 -ruleid:go/weak-hash", want: map[string][]int32{"a.go": {2}, "a.py": {1}}},
+		{query: "md5 severity:medium", want: map[string][]int32{"a.go": {1}}},
+		{query: "md5 severity:low", want: map[string][]int32{"a.go": {1, 2}, "a.py": {1}}},
+		{query: "md5 ruleid:todo severity:critical", want: map[string][]int32{}},
+	}
+	for _, test := range tests {
+		q, err := query.ParseAndCheck(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lines(filterRules(q, newMatches())); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got lines %v, want %v", test.query, got, test.want)
+		}
+
+		// The search is incomplete if the filters removed line matches.
+		matches, limitHit := postFilterMatches(context.Background(), q, newMatches())
+		if got := lines(matches); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got post-filtered lines %v, want %v", test.query, got, test.want)
+		}
+		if wantLimitHit := test.query != "md5"; limitHit != wantLimitHit {
+			t.Errorf("%s: got limitHit %v, want %v", test.query, limitHit, wantLimitHit)
+		}
+	}
+
+	q, err := query.ParseAndCheck("md5 severity:high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := filterRules(q, newMatches())
+	if len(matches) != 1 || matches[0].MatchCount != 1 {
+		t.Fatalf("got %+v, want a.go with 1 match", matches)
+	}
+	ruleResolvers := matches[0].JLineMatches[0].Rules()
+	if len(ruleResolvers) != 1 {
+		t.Fatalf("got %d rules, want 1", len(ruleResolvers))
+	}
+	if r := ruleResolvers[0]; r.ID() != "go/weak-hash" || *r.Description() != "Weak hash function" || r.Severity() != "HIGH" {
+		t.Errorf("got rule %s (%v, %s), want go/weak-hash", r.ID(), r.Description(), r.Severity())
+	}
+}
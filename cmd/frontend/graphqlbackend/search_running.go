@@ -0,0 +1,170 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+// runningSearches are the searches currently executing on this frontend
+// instance, so that site admins can list and cancel them (see
+// SearchLoad.searches and the cancelSearch mutation).
+var runningSearches = &searchRegistry{searches: map[int64]*runningSearch{}}
+
+type searchRegistry struct {
+	mu       sync.Mutex
+	lastID   int64
+	searches map[int64]*runningSearch
+}
+
+// runningSearch is a search that is executing.
+type runningSearch struct {
+	id      int64
+	userID  int32 // 0 for anonymous and internal searches
+	shape   string
+	started time.Time
+	cancel  context.CancelFunc
+
+	// The number of repositories to search with text search, and of those
+	// that are searched. Accessed atomically.
+	reposTotal, reposDone int64
+}
+
+type runningSearchKey struct{}
+
+// start registers a search with the given query shape (see queryShape) that
+// runs in the returned context, which is canceled if the search is canceled.
+// The caller must call done when the search finishes. If ctx already belongs
+// to a registered search (e.g. for a search run by another one), it is
+// returned unchanged.
+func (reg *searchRegistry) start(ctx context.Context, shape string) (_ context.Context, done func()) {
+	if _, ok := ctx.Value(runningSearchKey{}).(*runningSearch); ok {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &runningSearch{
+		userID:  actor.FromContext(ctx).UID,
+		shape:   shape,
+		started: time.Now(),
+		cancel:  cancel,
+	}
+	reg.mu.Lock()
+	reg.lastID++
+	s.id = reg.lastID
+	reg.searches[s.id] = s
+	reg.mu.Unlock()
+
+	return context.WithValue(ctx, runningSearchKey{}, s), func() {
+		reg.mu.Lock()
+		delete(reg.searches, s.id)
+		reg.mu.Unlock()
+		cancel()
+	}
+}
+
+// list returns the registered searches, oldest first.
+func (reg *searchRegistry) list() []*runningSearch {
+	reg.mu.Lock()
+	searches := make([]*runningSearch, 0, len(reg.searches))
+	for _, s := range reg.searches {
+		searches = append(searches, s)
+	}
+	reg.mu.Unlock()
+	sort.Slice(searches, func(i, j int) bool { return searches[i].id < searches[j].id })
+	return searches
+}
+
+// cancel cancels the search with the given ID, and reports whether it is
+// registered.
+func (reg *searchRegistry) cancel(id int64) bool {
+	reg.mu.Lock()
+	s, ok := reg.searches[id]
+	reg.mu.Unlock()
+	if ok {
+		s.cancel()
+	}
+	return ok
+}
+
+// addSearchRepos records that the search running in ctx, if any, searches n
+// more repositories with text search.
+func addSearchRepos(ctx context.Context, n int) {
+	if s, ok := ctx.Value(runningSearchKey{}).(*runningSearch); ok {
+		atomic.AddInt64(&s.reposTotal, int64(n))
+	}
+}
+
+// doneSearchRepos records that the search running in ctx, if any, finished
+// searching n repositories.
+func doneSearchRepos(ctx context.Context, n int) {
+	if s, ok := ctx.Value(runningSearchKey{}).(*runningSearch); ok {
+		atomic.AddInt64(&s.reposDone, int64(n))
+	}
+}
+
+func (searchLoadResolver) Searches() []*runningSearchResolver {
+	searches := runningSearches.list()
+	resolvers := make([]*runningSearchResolver, len(searches))
+	for i, s := range searches {
+		resolvers[i] = &runningSearchResolver{s: s}
+	}
+	return resolvers
+}
+
+func (r *schemaResolver) CancelSearch(ctx context.Context, args *struct{ ID graphql.ID }) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins may cancel the searches of other users.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := unmarshalRunningSearchID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !runningSearches.cancel(id) {
+		return nil, errors.New("search not found (it may have finished, or be running on another frontend instance)")
+	}
+	log15.Info("Canceled search (from API request)", "id", id, "actor", actor.FromContext(ctx))
+	return &EmptyResponse{}, nil
+}
+
+func marshalRunningSearchID(id int64) graphql.ID { return relay.MarshalID("ActiveSearch", id) }
+
+func unmarshalRunningSearchID(id graphql.ID) (searchID int64, err error) {
+	err = relay.UnmarshalSpec(id, &searchID)
+	return
+}
+
+type runningSearchResolver struct {
+	s *runningSearch
+}
+
+func (r *runningSearchResolver) ID() graphql.ID { return marshalRunningSearchID(r.s.id) }
+
+func (r *runningSearchResolver) User(ctx context.Context) (*UserResolver, error) {
+	if r.s.userID == 0 {
+		return nil, nil
+	}
+	return UserByIDInt32(ctx, r.s.userID)
+}
+
+func (r *runningSearchResolver) QueryShape() string { return r.s.shape }
+
+func (r *runningSearchResolver) StartedAt() DateTime { return DateTime{Time: r.s.started} }
+
+func (r *runningSearchResolver) ElapsedMilliseconds() int32 {
+	return int32(time.Since(r.s.started).Milliseconds())
+}
+
+func (r *runningSearchResolver) RepositoriesRemaining() int32 {
+	return int32(atomic.LoadInt64(&r.s.reposTotal) - atomic.LoadInt64(&r.s.reposDone))
+}
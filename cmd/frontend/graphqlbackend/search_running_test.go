@@ -0,0 +1,78 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+)
+
+func TestSearchRegistry(t *testing.T) {
+	reg := &searchRegistry{searches: map[int64]*runningSearch{}}
+
+	ctx1, done1 := reg.start(actor.WithActor(context.Background(), &actor.Actor{UID: 7}), "regexp pattern:1")
+	ctx2, done2 := reg.start(context.Background(), "literal pattern:1 repo:1")
+	defer done2()
+
+	// A search run by another search is not registered again.
+	if nested, _ := reg.start(ctx1, "regexp"); nested != ctx1 {
+		t.Error("got a new context for a nested search")
+	}
+
+	addSearchRepos(ctx2, 3)
+	doneSearchRepos(ctx2, 1)
+
+	searches := reg.list()
+	if len(searches) != 2 {
+		t.Fatalf("got %d searches, want 2", len(searches))
+	}
+	r1, r2 := &runningSearchResolver{s: searches[0]}, &runningSearchResolver{s: searches[1]}
+	if r1.QueryShape() != "regexp pattern:1" || r1.s.userID != 7 {
+		t.Errorf("got first search %q by user %d, want the search of user 7", r1.QueryShape(), r1.s.userID)
+	}
+	if got, want := r2.RepositoriesRemaining(), int32(2); got != want {
+		t.Errorf("got %d repositories remaining, want %d", got, want)
+	}
+
+	id, err := unmarshalRunningSearchID(r1.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reg.cancel(id) {
+		t.Fatal("search not found")
+	}
+	if ctx1.Err() == nil {
+		t.Error("search was not canceled")
+	}
+	if ctx2.Err() != nil {
+		t.Error("other search was canceled")
+	}
+
+	done1()
+	if searches := reg.list(); len(searches) != 1 || searches[0].id != r2.s.id {
+		t.Errorf("got searches %+v after the first one finished, want the second one", searches)
+	}
+	if reg.cancel(id) {
+		t.Error("canceled a finished search")
+	}
+}
+
+func TestCancelSearch_nonAdmin(t *testing.T) {
+	db.Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: 1}, nil
+	}
+	defer func() { db.Mocks.Users.GetByCurrentAuthUser = nil }()
+
+	ctx, done := runningSearches.start(context.Background(), "regexp")
+	defer done()
+	id := (&runningSearchResolver{s: runningSearches.list()[0]}).ID()
+	if _, err := (&schemaResolver{}).CancelSearch(actor.WithActor(context.Background(), &actor.Actor{UID: 1}), &struct{ ID graphql.ID }{ID: id}); err == nil {
+		t.Error("got nil error for non-admin")
+	}
+	if ctx.Err() != nil {
+		t.Error("search was canceled by a non-admin")
+	}
+}
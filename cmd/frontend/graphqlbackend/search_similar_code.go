@@ -0,0 +1,186 @@
+package graphqlbackend
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/search/winnow"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxSimilarCodeCandidates is the maximum number of files that are
+	// compared with the snippet of a similar code search.
+	maxSimilarCodeCandidates = 1000
+
+	// maxSimilarCodeFileSize is the size of the beginning of a candidate file
+	// that is compared with the snippet.
+	maxSimilarCodeFileSize = 1 << 20
+
+	// similarCodeIdentifiers is the number of identifiers of the snippet that
+	// candidate files must contain one of.
+	similarCodeIdentifiers = 3
+)
+
+// similarCodeArgs are the arguments of Search.similarCode.
+type similarCodeArgs struct {
+	Snippet       string
+	MinSimilarity float64
+	First         int32
+}
+
+// SimilarCode finds the regions of the files matched by the filters of the
+// search query (such as repo: and lang:) that are near-duplicates of a code
+// snippet, such as copies of a vulnerable snippet (see package winnow).
+//
+// The candidate files are those that contain one of the longest identifiers
+// of the snippet, so near-duplicates that rename all of them aren't found.
+func (r *searchResolver) SimilarCode(ctx context.Context, args *similarCodeArgs) (*similarCodeResultsResolver, error) {
+	if args.MinSimilarity <= 0 || args.MinSimilarity > 1 {
+		return nil, errors.New("minSimilarity must be greater than 0 and at most 1")
+	}
+	if args.First < 1 {
+		return nil, errors.New("first must be positive")
+	}
+	if len(r.query.Values(query.FieldDefault)) > 0 {
+		return nil, errors.New("similar code searches find near-duplicates of the snippet, so the query must not have a search pattern (use filters such as repo: and lang: to select the searched files)")
+	}
+	snippet, err := winnow.NewSnippet(args.Snippet)
+	if err != nil {
+		return nil, err
+	}
+	identifiers := winnow.Identifiers(args.Snippet, similarCodeIdentifiers)
+	if len(identifiers) == 0 {
+		return nil, errors.New("the snippet has no identifiers to find candidate files with")
+	}
+
+	ctx, cancel, err := r.withTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	repos, _, _, _, err := r.resolveRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := r.getPatternInfo(&getPatternInfoOptions{fileMatchLimit: maxSimilarCodeCandidates})
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range identifiers {
+		identifiers[i] = regexp.QuoteMeta(id)
+	}
+	p.Pattern = `\b(?:` + strings.Join(identifiers, "|") + `)\b`
+	p.IsRegExp, p.IsStructuralPat, p.IsWordMatch, p.IsCaseSensitive = true, false, false, true
+	p.PatternMatchesContent, p.PatternMatchesPath = true, false
+
+	var (
+		candidates []*FileMatchResolver
+		common     = &searchResultsCommon{}
+	)
+	if len(repos) > 0 {
+		args := search.TextParameters{
+			PatternInfo:     p,
+			Repos:           repos,
+			Query:           r.query,
+			UseFullDeadline: r.searchTimeoutFieldSet(),
+			Zoekt:           r.zoekt,
+			SearcherURLs:    r.searcherURLs,
+		}
+		if err := args.PatternInfo.Validate(); err != nil {
+			return nil, &badRequestError{err}
+		}
+		candidates, common, err = searchFilesInRepos(ctx, &args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	regions := similarCodeRegions(ctx, snippet, candidates, args.MinSimilarity)
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].region.Similarity > regions[j].region.Similarity
+	})
+	results := &similarCodeResultsResolver{limitHit: common.limitHit || len(common.timedout) > 0}
+	if len(regions) > int(args.First) {
+		regions, results.limitHit = regions[:args.First], true
+	}
+	results.regions = regions
+	return results, nil
+}
+
+func (searchAlert) SimilarCode(context.Context, *similarCodeArgs) (*similarCodeResultsResolver, error) {
+	return nil, nil
+}
+
+// similarCodeRegions returns the regions of the candidate files that are at
+// least minSimilarity similar to the snippet, in the order of the files.
+func similarCodeRegions(ctx context.Context, snippet *winnow.Snippet, candidates []*FileMatchResolver, minSimilarity float64) []*similarCodeRegionResolver {
+	var (
+		run         = parallel.NewRun(revisionResolutionParallelism)
+		fileRegions = make([][]*similarCodeRegionResolver, len(candidates))
+	)
+	for i, fm := range candidates {
+		if fm.Repo == nil || fm.CommitID == "" {
+			continue
+		}
+		i, fm := i, fm
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			data, err := git.ReadFile(ctx, gitserver.Repo{Name: fm.Repo.Name}, fm.CommitID, fm.JPath, maxSimilarCodeFileSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log15.Warn("Failed to read file for similar code search", "repo", fm.Repo.Name, "commit", fm.CommitID, "path", fm.JPath, "error", err)
+				}
+				return
+			}
+			lines := strings.Split(string(data), "\n")
+			for _, region := range snippet.Regions(string(data), minSimilarity) {
+				fileRegions[i] = append(fileRegions[i], &similarCodeRegionResolver{
+					fm:      fm,
+					region:  region,
+					preview: strings.Join(lines[region.StartLine:region.EndLine+1], "\n"),
+				})
+			}
+		})
+	}
+	_ = run.Wait()
+
+	var regions []*similarCodeRegionResolver
+	for _, rs := range fileRegions {
+		regions = append(regions, rs...)
+	}
+	return regions
+}
+
+type similarCodeResultsResolver struct {
+	regions  []*similarCodeRegionResolver
+	limitHit bool
+}
+
+func (r *similarCodeResultsResolver) Regions() []*similarCodeRegionResolver { return r.regions }
+func (r *similarCodeResultsResolver) LimitHit() bool                        { return r.limitHit }
+
+type similarCodeRegionResolver struct {
+	fm      *FileMatchResolver
+	region  winnow.Region
+	preview string
+}
+
+func (r *similarCodeRegionResolver) File() *GitTreeEntryResolver     { return r.fm.File() }
+func (r *similarCodeRegionResolver) Repository() *RepositoryResolver { return r.fm.Repository() }
+func (r *similarCodeRegionResolver) StartLine() int32                { return int32(r.region.StartLine) }
+func (r *similarCodeRegionResolver) EndLine() int32                  { return int32(r.region.EndLine) }
+func (r *similarCodeRegionResolver) Similarity() float64             { return r.region.Similarity }
+func (r *similarCodeRegionResolver) Preview() string                 { return r.preview }
@@ -0,0 +1,113 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const similarCodeSnippet = `func hashPassword(password string) string {
+	sum := md5.Sum([]byte(password + salt))
+	return hex.EncodeToString(sum[:])
+}`
+
+func TestSearchSimilarCode(t *testing.T) {
+	repo := &types.Repo{ID: 1, Name: "github.com/foo/foo"}
+	mockResolveRepositories = func(effectiveRepoFieldValues []string) (repoRevs, missingRepoRevs []*search.RepositoryRevisions, excludedRepos *excludedRepos, overLimit bool, err error) {
+		return []*search.RepositoryRevisions{{Repo: repo, Revs: []search.RevisionSpecifier{{RevSpec: ""}}}}, nil, nil, false, nil
+	}
+	defer func() { mockResolveRepositories = nil }()
+
+	files := map[string]string{
+		"copy.go":     "package a\n\n" + similarCodeSnippet + "\n",
+		"modified.go": "package b\n\n// hashPassword hashes passwords.\nfunc hashPassword(password string) string {\n\tsum := sha256.Sum256([]byte(password + salt))\n\treturn hex.EncodeToString(sum[:])\n}\n",
+		"other.go":    "package c\n\nfunc password() string { return \"\" }\n",
+	}
+	var pattern string
+	mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+		pattern = args.PatternInfo.Pattern
+		return []*FileMatchResolver{
+			{Repo: repo, CommitID: "c", JPath: "other.go"},
+			{Repo: repo, CommitID: "c", JPath: "modified.go"},
+			{Repo: repo, CommitID: "c", JPath: "copy.go"},
+		}, &searchResultsCommon{}, nil
+	}
+	defer func() { mockSearchFilesInRepos = nil }()
+
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return []byte(data), nil
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	defer git.ResetMocks()
+
+	q, err := query.ParseAndCheck("lang:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &searchResolver{query: q, patternType: query.SearchTypeLiteral}
+	results, err := r.SimilarCode(context.Background(), &similarCodeArgs{Snippet: similarCodeSnippet, MinSimilarity: 0.2, First: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `\b(?:EncodeToString|hashPassword|password)\b`; pattern != want {
+		t.Errorf("got candidate pattern %q, want %q", pattern, want)
+	}
+
+	type region struct {
+		Path               string
+		StartLine, EndLine int32
+		Exact              bool
+	}
+	var got []region
+	for _, r := range results.Regions() {
+		got = append(got, region{Path: r.fm.JPath, StartLine: r.StartLine(), EndLine: r.EndLine(), Exact: r.Similarity() == 1})
+	}
+	want := []region{
+		{Path: "copy.go", StartLine: 2, EndLine: 4, Exact: true},
+		{Path: "modified.go", StartLine: 3, EndLine: 5, Exact: false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got regions %+v, want %+v", got, want)
+	}
+	if results.LimitHit() {
+		t.Error("got limitHit")
+	}
+	if got, want := results.Regions()[0].Preview(), "func hashPassword(password string) string {\n\tsum := md5.Sum([]byte(password + salt))\n\treturn hex.EncodeToString(sum[:])"; got != want {
+		t.Errorf("got preview %q, want %q", got, want)
+	}
+}
+
+func TestSearchSimilarCode_invalidArgs(t *testing.T) {
+	q, err := query.ParseAndCheck("hashPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := query.ParseAndCheck("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query query.QueryInfo
+		args  similarCodeArgs
+	}{
+		{empty, similarCodeArgs{Snippet: similarCodeSnippet, MinSimilarity: 0, First: 10}},
+		{empty, similarCodeArgs{Snippet: similarCodeSnippet, MinSimilarity: 0.5, First: 0}},
+		{empty, similarCodeArgs{Snippet: "a := b", MinSimilarity: 0.5, First: 10}},
+		{q, similarCodeArgs{Snippet: similarCodeSnippet, MinSimilarity: 0.5, First: 10}},
+	}
+	for _, test := range tests {
+		if _, err := (&searchResolver{query: test.query}).SimilarCode(context.Background(), &test.args); err == nil {
+			t.Errorf("%+v: got no error", test.args)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package graphqlbackend
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// smallRepoSize is the size in bytes (as reported by the code host) below
+// which repositories share searcher request slots (0 means they do not).
+var smallRepoSize = parseSmallRepoSize(env.Get("SEARCH_SMALL_REPO_SIZE_MB", "10", "repositories smaller than this size in MB (as reported by the code host) share a searcher request slot with other small repositories (0 disables sharing)"))
+
+// smallReposPerSlot is the number of small repositories that can be
+// searched concurrently in one searcher request slot.
+const smallReposPerSlot = 4
+
+func parseSmallRepoSize(s string) int64 {
+	mb, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || mb < 0 {
+		log15.Error("Invalid SEARCH_SMALL_REPO_SIZE_MB, disabling sharing of searcher request slots", "value", s, "error", err)
+		return 0
+	}
+	return mb << 20
+}
+
+// smallRepos returns the set of repos that are smaller than smallRepoSize.
+// Repositories of unknown size are not small. Errors are logged, since sizes
+// only affect scheduling.
+func smallRepos(ctx context.Context, repos []*search.RepositoryRevisions) map[api.RepoID]bool {
+	if smallRepoSize == 0 || len(repos) < 2 {
+		return nil
+	}
+	ids := make([]api.RepoID, len(repos))
+	for i, repo := range repos {
+		ids[i] = repo.Repo.ID
+	}
+	sizes, err := db.Repos.GetSizes(ctx, ids...)
+	if err != nil {
+		log15.Warn("Failed to get repository sizes for search scheduling", "error", err)
+		return nil
+	}
+	small := map[api.RepoID]bool{}
+	for id, size := range sizes {
+		if size < smallRepoSize {
+			small[id] = true
+		}
+	}
+	return small
+}
+
+// searcherSlots acquires searcher request slots from a limiter for the
+// repository searches of one search. Each large repository takes a slot of
+// its own, while up to smallReposPerSlot small repositories share one, so
+// that a search over many tiny repositories is not queued behind the slots
+// held by monorepos. It is not safe for concurrent use.
+type searcherSlots struct {
+	limiter *mutablelimiter.Limiter
+	shared  *sharedSlot // the slot small repositories currently join
+}
+
+// acquire acquires a slot for searching a repository. It returns the
+// context to search in and the function to call once the search is done.
+func (s *searcherSlots) acquire(ctx context.Context, small bool) (context.Context, context.CancelFunc, error) {
+	if !small {
+		return s.limiter.Acquire(ctx)
+	}
+	if s.shared != nil && s.shared.join() {
+		return s.shared.ctx, s.shared.leave, nil
+	}
+	limitCtx, limitDone, err := s.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.shared = &sharedSlot{ctx: limitCtx, done: limitDone, users: 1, remaining: smallReposPerSlot - 1}
+	return limitCtx, s.shared.leave, nil
+}
+
+// sharedSlot is a searcher request slot shared by several small
+// repositories. It is released once all of them are done.
+type sharedSlot struct {
+	ctx  context.Context
+	done context.CancelFunc
+
+	mu        sync.Mutex
+	users     int // repositories searching in the slot
+	remaining int // repositories that may still join
+}
+
+func (s *sharedSlot) join() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.remaining == 0 || s.users == 0 {
+		return false
+	}
+	s.remaining--
+	s.users++
+	return true
+}
+
+func (s *sharedSlot) leave() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users--
+	if s.users == 0 {
+		s.remaining = 0
+		s.done()
+	}
+}
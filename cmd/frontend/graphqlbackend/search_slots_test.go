@@ -0,0 +1,65 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestSearcherSlots(t *testing.T) {
+	s := &searcherSlots{limiter: mutablelimiter.New(1)}
+	ctx := context.Background()
+
+	// Small repositories share the only slot.
+	var dones []context.CancelFunc
+	for i := 0; i < smallReposPerSlot; i++ {
+		_, done, err := s.acquire(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dones = append(dones, done)
+	}
+
+	// The slot is full, so neither small nor large repositories get one.
+	for _, small := range []bool{true, false} {
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		_, _, err := s.acquire(timeoutCtx, small)
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got error %v acquiring a slot (small %v), want deadline exceeded", err, small)
+		}
+	}
+
+	// The slot is released once all small repositories are done.
+	for _, done := range dones {
+		done()
+	}
+	_, done, err := s.acquire(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+}
+
+func TestSmallRepos(t *testing.T) {
+	db.Mocks.Repos.GetSizes = func(ctx context.Context, ids ...api.RepoID) (map[api.RepoID]int64, error) {
+		return map[api.RepoID]int64{1: 1 << 10, 2: 1 << 40}, nil
+	}
+	defer func() { db.Mocks.Repos.GetSizes = nil }()
+
+	repos := []*search.RepositoryRevisions{
+		{Repo: &types.Repo{ID: 1}},
+		{Repo: &types.Repo{ID: 2}},
+		{Repo: &types.Repo{ID: 3}},
+	}
+	if got, want := smallRepos(context.Background(), repos), map[api.RepoID]bool{1: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package graphqlbackend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// slowSearchLogRepos is the maximum number of per-repository timings included
+// in a slow search log record.
+const slowSearchLogRepos = 10
+
+// queryShape describes the structure of a query without revealing the values
+// of its fields, e.g. "regexp pattern:1 file:2 repo:1". It allows grouping slow
+// queries by what they do rather than by what they search for.
+func queryShape(q query.QueryInfo, patternType query.SearchType) string {
+	var patternTypeName string
+	switch patternType {
+	case query.SearchTypeLiteral:
+		patternTypeName = "literal"
+	case query.SearchTypeStructural:
+		patternTypeName = "structural"
+	default:
+		patternTypeName = "regexp"
+	}
+	if q == nil {
+		return patternTypeName
+	}
+
+	fields := q.Fields()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := []string{patternTypeName}
+	for _, name := range names {
+		label := name
+		if name == query.FieldDefault {
+			label = "pattern"
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", label, len(fields[name])))
+	}
+	return strings.Join(parts, " ")
+}
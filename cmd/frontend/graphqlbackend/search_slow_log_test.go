@@ -0,0 +1,17 @@
+package graphqlbackend
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestQueryShape(t *testing.T) {
+	q, err := query.ParseAndCheck("repo:foo repo:bar file:baz hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := queryShape(q, query.SearchTypeLiteral), "literal pattern:2 file:1 repo:2"; have != want {
+		t.Errorf("have %q, want %q", have, want)
+	}
+}
@@ -0,0 +1,225 @@
+package graphqlbackend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// fileMatchSpool stores file matches in a temporary file instead of in memory,
+// so that searches for export and batch use cases can return every match in a
+// huge set of repositories (see searchResolver.spillFileMatches).
+//
+// Matches are stored as one JSON object per line. The (comparatively few)
+// repositories they belong to are kept in memory, so that they are only stored
+// once.
+type fileMatchSpool struct {
+	mu    sync.Mutex
+	f     *os.File
+	w     *bufio.Writer
+	repos map[api.RepoID]*types.Repo
+	count int
+}
+
+// spooledFileMatch is the on-disk form of a FileMatchResolver.
+type spooledFileMatch struct {
+	RepoID      api.RepoID
+	URI         string
+	CommitID    api.CommitID
+	InputRev    *string `json:",omitempty"`
+	Path        string
+	LineMatches []*lineMatch
+	LimitHit    bool
+	MatchCount  int
+	MatchRanges []protocol.Range `json:",omitempty"`
+
+	PathOffsetAndLengths [][2]int32 `json:",omitempty"`
+}
+
+// newFileMatchSpool creates a spool backed by a new temporary file in dir (or
+// the default temporary directory if dir is ""). The caller must call Close
+// to remove the file.
+func newFileMatchSpool(dir string) (*fileMatchSpool, error) {
+	f, err := ioutil.TempFile(dir, "search-spool-")
+	if err != nil {
+		return nil, errors.Wrap(err, "creating search result spool")
+	}
+	return &fileMatchSpool{
+		f:     f,
+		w:     bufio.NewWriter(f),
+		repos: map[api.RepoID]*types.Repo{},
+	}, nil
+}
+
+// add appends matches to the spool.
+func (s *fileMatchSpool) add(matches []*FileMatchResolver) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(s.w)
+	for _, fm := range matches {
+		var repoID api.RepoID
+		if fm.Repo != nil {
+			repoID = fm.Repo.ID
+			s.repos[repoID] = fm.Repo
+		}
+		if err := enc.Encode(spooledFileMatch{
+			RepoID:      repoID,
+			URI:         fm.uri,
+			CommitID:    fm.CommitID,
+			InputRev:    fm.InputRev,
+			Path:        fm.JPath,
+			LineMatches: fm.JLineMatches,
+			LimitHit:    fm.JLimitHit,
+			MatchCount:  fm.MatchCount,
+			MatchRanges: fm.JMatchRanges,
+
+			PathOffsetAndLengths: fm.JPathOffsetAndLengths,
+		}); err != nil {
+			return errors.Wrap(err, "writing to search result spool")
+		}
+		s.count++
+	}
+	return nil
+}
+
+// Len returns the number of file matches in the spool.
+func (s *fileMatchSpool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Each calls f for every file match in the spool, in the order they were
+// added. Only one file match is held in memory at a time. Matches must not be
+// added while Each is running.
+func (s *fileMatchSpool) Each(f func(*FileMatchResolver) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		return errors.Wrap(err, "writing to search result spool")
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	// Seek back to the end when done, so that matches can be added again.
+	defer func() { _, _ = s.f.Seek(0, io.SeekEnd) }()
+
+	dec := json.NewDecoder(bufio.NewReader(s.f))
+	for {
+		var m spooledFileMatch
+		if err := dec.Decode(&m); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "reading search result spool")
+		}
+		if err := f(&FileMatchResolver{
+			JPath:        m.Path,
+			JLineMatches: m.LineMatches,
+			JLimitHit:    m.LimitHit,
+			MatchCount:   m.MatchCount,
+			JMatchRanges: m.MatchRanges,
+			uri:          m.URI,
+			Repo:         s.repos[m.RepoID],
+			CommitID:     m.CommitID,
+			InputRev:     m.InputRev,
+
+			JPathOffsetAndLengths: m.PathOffsetAndLengths,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the spool's temporary file.
+func (s *fileMatchSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.f.Close()
+	if rmErr := os.Remove(s.f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+type fileMatchSpoolKey struct{}
+
+// withFileMatchSpool returns a context in which searchFilesInRepos adds all
+// matches to spool instead of returning them, and does not stop once enough
+// matches were found.
+func withFileMatchSpool(ctx context.Context, spool *fileMatchSpool) context.Context {
+	return context.WithValue(ctx, fileMatchSpoolKey{}, spool)
+}
+
+func fileMatchSpoolFromContext(ctx context.Context) *fileMatchSpool {
+	s, _ := ctx.Value(fileMatchSpoolKey{}).(*fileMatchSpool)
+	return s
+}
+
+// spillFileMatches runs the file content and path search of r exhaustively,
+// adding every match to spool. It is meant for exports and batch jobs, which
+// need all matches rather than the first page of them. Other result types are
+// ignored.
+func (r *searchResolver) spillFileMatches(ctx context.Context, spool *fileMatchSpool) (_ *searchResultsCommon, err error) {
+	tr, ctx := trace.New(ctx, "graphql.spillFileMatches", r.rawQuery())
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	ctx, cancel, err := r.withTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	repos, _, _, alertResult, err := r.determineRepos(ctx, tr, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if alertResult != nil {
+		return nil, errors.New(alertResult.alert.title)
+	}
+
+	options := &getPatternInfoOptions{}
+	switch r.patternType {
+	case query.SearchTypeStructural:
+		options.performStructuralSearch = true
+	case query.SearchTypeLiteral:
+		options.performLiteralSearch = true
+	}
+	p, err := r.getPatternInfo(options)
+	if err != nil {
+		return nil, err
+	}
+	// Searcher has no limit if FileMatchLimit is 0, but the frontend uses it to
+	// stop searching, so set the largest limit instead.
+	p.FileMatchLimit = math.MaxInt32
+	if err := p.Validate(); err != nil {
+		return nil, &badRequestError{err}
+	}
+
+	args := search.TextParameters{
+		PatternInfo:     p,
+		Repos:           repos,
+		Query:           r.query,
+		UseFullDeadline: true,
+		Zoekt:           r.zoekt,
+		SearcherURLs:    r.searcherURLs,
+	}
+	_, common, err := searchFilesInRepos(withFileMatchSpool(ctx, spool), &args)
+	return common, err
+}
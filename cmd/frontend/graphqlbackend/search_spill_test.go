@@ -0,0 +1,103 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/zoekt"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestFileMatchSpool(t *testing.T) {
+	spool, err := newFileMatchSpool("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := spool.f.Name()
+
+	repo := &types.Repo{ID: 1, Name: "foo"}
+	rev := "dev"
+	want := []*FileMatchResolver{
+		{JPath: "a.go", uri: "git://foo?c1#a.go", Repo: repo, CommitID: "c1", MatchCount: 1, JLineMatches: []*lineMatch{{JPreview: "x", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{0, 1}}}}},
+		{JPath: "b.go", uri: "git://foo?c1#b.go", Repo: repo, CommitID: "c1", InputRev: &rev, JLimitHit: true},
+	}
+	for _, fm := range want {
+		if err := spool.add([]*FileMatchResolver{fm}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spool.Len() != 2 {
+		t.Errorf("got Len %d, want 2", spool.Len())
+	}
+
+	var got []*FileMatchResolver
+	if err := spool.Each(func(fm *FileMatchResolver) error {
+		got = append(got, fm)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got[0].Repo != got[1].Repo {
+		t.Error("expected matches in the same repository to share it")
+	}
+
+	if err := spool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("expected spool file to be removed, got %v", err)
+	}
+}
+
+func TestSearchFilesInRepos_spool(t *testing.T) {
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		return []*FileMatchResolver{
+			{uri: "git://" + string(repo.Name) + "?" + rev + "#a.go", Repo: repo},
+			{uri: "git://" + string(repo.Name) + "?" + rev + "#b.go", Repo: repo},
+		}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	spool, err := newFileMatchSpool("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.TextParameters{
+		// A limit that would stop an interactive search after the first repository.
+		PatternInfo:  &search.TextPatternInfo{FileMatchLimit: 1, Pattern: "foo"},
+		Repos:        makeRepositoryRevisions("foo/one", "foo/two", "foo/three"),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+		SearcherURLs: endpoint.Static("test"),
+	}
+	results, common, err := searchFilesInRepos(withFileMatchSpool(context.Background(), spool), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected results to be spooled, got %d results", len(results))
+	}
+	if spool.Len() != 6 {
+		t.Errorf("got %d spooled results, want 6", spool.Len())
+	}
+	if common.limitHit || common.cancellationReason != "" {
+		t.Errorf("expected exhaustive search, got limitHit %v and cancellation reason %q", common.limitHit, common.cancellationReason)
+	}
+}
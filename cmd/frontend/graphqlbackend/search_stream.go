@@ -0,0 +1,96 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+// SearchEvent is a batch of search results sent to a SearchStream.
+type SearchEvent struct {
+	Results []SearchResultResolver
+
+	// RepositoriesSearched is the number of repository revisions whose
+	// search finished since the previous event. Events are also sent for
+	// repositories without results, so that receivers can report progress.
+	RepositoriesSearched int
+}
+
+// SearchStream receives the results of a search as they are found (see
+// StreamSearch). Send is never called concurrently, and the search waits for
+// it to return, so a slow receiver slows down the search instead of results
+// piling up in memory.
+type SearchStream interface {
+	Send(SearchEvent)
+}
+
+// SearchStreamFunc is a SearchStream that calls a function for each event.
+type SearchStreamFunc func(SearchEvent)
+
+func (f SearchStreamFunc) Send(e SearchEvent) {
+	f(e)
+}
+
+// StreamSearch runs the search s, sending its results to stream. File
+// matches of ordinary queries are sent as soon as a repository has been
+// searched, in the order they are found, so that the caller can write them to
+// the response while the search is still running. All other results are sent
+// in a final event once the search is done.
+//
+// The returned resolver describes the search (e.g. its alert, limitHit and
+// the repositories that weren't searched), but its results only contain those
+// that were not sent yet.
+func StreamSearch(ctx context.Context, s SearchImplementer, stream SearchStream) (*SearchResultsResolver, error) {
+	var rr *SearchResultsResolver
+	var err error
+	if r, ok := s.(*searchResolver); ok {
+		rr, err = r.stream(ctx, stream)
+	} else {
+		rr, err = s.Results(ctx)
+	}
+	if err != nil || rr == nil {
+		return rr, err
+	}
+	if len(rr.SearchResults) > 0 {
+		stream.Send(SearchEvent{Results: rr.SearchResults})
+	}
+	return rr, nil
+}
+
+// stream runs r with file matches sent to stream. Searches whose results
+// must be combined or paginated first (and/or queries, paginated and stable
+// searches, searches of the history of files, firstmatch: searches, whose
+// file matches become repository results, and structural searches, which
+// are retried if they have no results) do not stream, and neither do
+// searches of users for whom the streaming search feature flag is disabled.
+func (r *searchResolver) stream(ctx context.Context, stream SearchStream) (*SearchResultsResolver, error) {
+	ctx = withSearchFeatureFlags(ctx)
+	history, _ := r.query.StringValue(query.FieldHistory)
+	if _, ok := r.query.(*query.OrdinaryQuery); ok && r.pagination == nil && !r.query.BoolValue(query.FieldStable) && !r.query.BoolValue(query.FieldDeterministic) && history == "" && !r.query.BoolValue(query.FieldFirstMatch) && r.patternType != query.SearchTypeStructural && searchFeatureStreaming.enabled(ctx) {
+		r.resultStream = stream
+		defer func() { r.resultStream = nil }()
+	}
+	return r.Results(ctx)
+}
+
+type searchStreamKey struct{}
+
+// withSearchStream returns a context in which searchFilesInRepos sends
+// matches to stream instead of returning them.
+func withSearchStream(ctx context.Context, stream SearchStream) context.Context {
+	return context.WithValue(ctx, searchStreamKey{}, stream)
+}
+
+func searchStreamFromContext(ctx context.Context) SearchStream {
+	s, _ := ctx.Value(searchStreamKey{}).(SearchStream)
+	return s
+}
+
+// fileMatchesToSearchResults converts file matches to search results.
+func fileMatchesToSearchResults(matches []*FileMatchResolver) []SearchResultResolver {
+	results := make([]SearchResultResolver, len(matches))
+	for i, fm := range matches {
+		results[i] = fm
+	}
+	return results
+}
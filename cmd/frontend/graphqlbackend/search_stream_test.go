@@ -0,0 +1,101 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/zoekt"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+)
+
+func TestSearchFilesInRepos_stream(t *testing.T) {
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		return []*FileMatchResolver{
+			{uri: "git://" + string(repo.Name) + "?" + rev + "#a.go", Repo: repo},
+			{uri: "git://" + string(repo.Name) + "?" + rev + "#b.go", Repo: repo},
+		}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(limit int32) (events []SearchEvent, results []*FileMatchResolver, common *searchResultsCommon) {
+		args := &search.TextParameters{
+			PatternInfo:  &search.TextPatternInfo{FileMatchLimit: limit, Pattern: "foo"},
+			Repos:        makeRepositoryRevisions("foo/one", "foo/two", "foo/three"),
+			Query:        q,
+			Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+			SearcherURLs: endpoint.Static("test"),
+		}
+		stream := SearchStreamFunc(func(e SearchEvent) { events = append(events, e) })
+		results, common, err := searchFilesInRepos(withSearchStream(context.Background(), stream), args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events, results, common
+	}
+
+	t.Run("all", func(t *testing.T) {
+		events, results, common := run(10)
+		if len(results) != 0 {
+			t.Errorf("expected results to be streamed, got %d results", len(results))
+		}
+		if len(events) != 3 {
+			t.Errorf("got %d events, want one per repository", len(events))
+		}
+		if n := countStreamed(events); n != 6 || common.resultCount != 6 {
+			t.Errorf("got %d streamed results and result count %d, want 6", n, common.resultCount)
+		}
+		if common.limitHit {
+			t.Error("expected limitHit to be false")
+		}
+		var searched int
+		for _, e := range events {
+			searched += e.RepositoriesSearched
+		}
+		if searched != 3 {
+			t.Errorf("got %d searched repositories in events, want 3", searched)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		events, _, common := run(3)
+		if n := countStreamed(events); n != 3 {
+			t.Errorf("got %d streamed results, want the limit of 3", n)
+		}
+		if !common.limitHit || common.cancellationReason != searchCanceledLimitHit {
+			t.Errorf("expected the search to stop at the limit, got limitHit %v and cancellation reason %q", common.limitHit, common.cancellationReason)
+		}
+	})
+}
+
+func TestStreamSearch_notStreamed(t *testing.T) {
+	alert := alertForQuery("foo", &query.ValidationError{Msg: "bad query"})
+	var events []SearchEvent
+	rr, err := StreamSearch(context.Background(), alert, SearchStreamFunc(func(e SearchEvent) { events = append(events, e) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.alert == nil {
+		t.Error("expected the alert to be returned")
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for a search without results, got %d", len(events))
+	}
+}
+
+func countStreamed(events []SearchEvent) int {
+	var n int
+	for _, e := range events {
+		n += len(e.Results)
+	}
+	return n
+}
@@ -0,0 +1,184 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/config"
+)
+
+// maxGitmodulesSize is the maximum size of a .gitmodules file that is read to
+// find the submodules of a repository.
+const maxGitmodulesSize = 1 << 20
+
+// pinnedSubmodule is a submodule of a searched repository, identified by the
+// repository name or URI it refers to and its pinned commit.
+type pinnedSubmodule struct {
+	uri      string
+	commitID api.CommitID
+}
+
+// addSubmodules appends the repositories that are referenced as Git submodules
+// by the searched revisions of repoRevs, each at the commit it is pinned to,
+// so that their matches are attributed to the submodule repositories. Only
+// submodules of repositories known to Sourcegraph (and visible to the
+// current user) are added, and submodules of submodules are not followed.
+//
+// Failing to list the submodules of a repository does not fail the search.
+func addSubmodules(ctx context.Context, repoRevs []*search.RepositoryRevisions) ([]*search.RepositoryRevisions, error) {
+	var (
+		mu         sync.Mutex
+		submodules = map[pinnedSubmodule]bool{}
+		run        = parallel.NewRun(revisionResolutionParallelism)
+	)
+	for _, repoRev := range repoRevs {
+		for _, rev := range repoRev.Revs {
+			if rev.RefGlob != "" || rev.ExcludeRefGlob != "" {
+				// Listing the submodules of every matching ref would be too
+				// expensive.
+				continue
+			}
+			repoRev, rev := repoRev, rev
+			run.Acquire()
+			goroutine.Go(func() {
+				defer run.Release()
+				subs, err := listSubmodules(ctx, repoRev, rev.RevSpec)
+				if err != nil && ctx.Err() == nil {
+					log15.Warn("Failed to list submodules", "repo", repoRev.Repo.Name, "rev", rev.RevSpec, "error", err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, sub := range subs {
+					submodules[sub] = true
+				}
+			})
+		}
+	}
+	_ = run.Wait()
+	if len(submodules) == 0 {
+		return repoRevs, nil
+	}
+
+	uris := make([]string, 0, len(submodules))
+	seen := make(map[string]bool, len(submodules))
+	for sub := range submodules {
+		if !seen[sub.uri] {
+			seen[sub.uri] = true
+			uris = append(uris, sub.uri)
+		}
+	}
+	// 🚨 SECURITY: GetByURIs only returns the repositories that the current
+	// user is allowed to see, so submodules don't expose private repositories.
+	repos, err := db.Repos.GetByURIs(ctx, uris)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't search a submodule revision twice if it is also searched directly.
+	searched := make(map[pinnedSubmodule]bool, len(repoRevs))
+	for _, repoRev := range repoRevs {
+		for _, rev := range repoRev.Revs {
+			searched[pinnedSubmodule{uri: string(repoRev.Repo.Name), commitID: api.CommitID(rev.RevSpec)}] = true
+		}
+	}
+	for _, repo := range repos {
+		var revs []search.RevisionSpecifier
+		for sub := range submodules {
+			if sub.uri != string(repo.Name) && (repo.RepoFields == nil || sub.uri != repo.URI) {
+				continue
+			}
+			key := pinnedSubmodule{uri: string(repo.Name), commitID: sub.commitID}
+			if searched[key] {
+				continue
+			}
+			searched[key] = true
+			revs = append(revs, search.RevisionSpecifier{RevSpec: string(sub.commitID)})
+		}
+		if len(revs) > 0 {
+			sort.Slice(revs, func(i, j int) bool { return revs[i].RevSpec < revs[j].RevSpec })
+			repoRevs = append(repoRevs, &search.RepositoryRevisions{Repo: repo, Revs: revs})
+		}
+	}
+	return repoRevs, nil
+}
+
+// listSubmodules returns the submodules listed in the .gitmodules file of
+// repoRev's repository at revSpec, with the commits they are pinned to.
+func listSubmodules(ctx context.Context, repoRev *search.RepositoryRevisions, revSpec string) ([]pinnedSubmodule, error) {
+	gitserverRepo := repoRev.GitserverRepo()
+	commitID, err := git.ResolveRevision(ctx, gitserverRepo, nil, revSpec, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+	if err != nil {
+		return nil, err
+	}
+	data, err := git.ReadFile(ctx, gitserverRepo, commitID, ".gitmodules", maxGitmodulesSize)
+	if err != nil {
+		// Most repositories don't have submodules.
+		return nil, nil
+	}
+	var cfg config.Config
+	if err := config.NewDecoder(bytes.NewReader(data)).Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	var subs []pinnedSubmodule
+	for _, s := range cfg.Section("submodule").Subsections {
+		uri := submoduleRepoURI(repoRev.Repo.Name, s.Option("url"))
+		if uri == "" || s.Option("path") == "" {
+			continue
+		}
+		fi, err := git.Stat(ctx, gitserverRepo, commitID, s.Option("path"))
+		if err != nil {
+			// The submodule is listed in .gitmodules but isn't present at
+			// this commit.
+			continue
+		}
+		sub, ok := fi.Sys().(git.Submodule)
+		if !ok || fi.Mode()&git.ModeSubmodule != git.ModeSubmodule {
+			continue
+		}
+		subs = append(subs, pinnedSubmodule{uri: uri, commitID: sub.CommitID})
+	}
+	return subs, nil
+}
+
+// submoduleRepoURI returns the repository URI (e.g. "github.com/foo/bar")
+// that the submodule clone URL refers to, or "" if it can't be determined.
+// Relative URLs are resolved against the name of the repository (parent) that
+// references the submodule.
+func submoduleRepoURI(parent api.RepoName, cloneURL string) string {
+	var uri string
+	switch {
+	case strings.HasPrefix(cloneURL, "./") || strings.HasPrefix(cloneURL, "../"):
+		uri = path.Join(string(parent), cloneURL)
+	case strings.Contains(cloneURL, "://"):
+		u, err := url.Parse(cloneURL)
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		uri = u.Hostname() + "/" + strings.TrimPrefix(u.Path, "/")
+	default:
+		// SCP-like syntax, e.g. git@github.com:foo/bar.git.
+		i := strings.Index(cloneURL, ":")
+		if i <= 0 {
+			return ""
+		}
+		host := cloneURL[:i]
+		if j := strings.LastIndex(host, "@"); j >= 0 {
+			host = host[j+1:]
+		}
+		uri = host + "/" + strings.TrimPrefix(cloneURL[i+1:], "/")
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(uri, "/"), ".git")
+}
@@ -0,0 +1,100 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/util"
+)
+
+func TestAddSubmodules(t *testing.T) {
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return api.CommitID("commit-" + spec), nil
+	}
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		if commit != "commit-master" {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return []byte(`[submodule "vendor/lib"]
+	path = vendor/lib
+	url = https://github.com/foo/lib.git
+[submodule "vendor/sibling"]
+	path = vendor/sibling
+	url = ../sibling
+[submodule "vendor/unknown"]
+	path = vendor/unknown
+	url = git@example.com:foo/unknown.git
+[submodule "vendor/removed"]
+	path = vendor/removed
+	url = https://github.com/foo/removed
+`), nil
+	}
+	git.Mocks.Stat = func(commit api.CommitID, name string) (os.FileInfo, error) {
+		if name == "vendor/removed" {
+			return nil, &os.PathError{Op: "ls-tree", Path: name, Err: os.ErrNotExist}
+		}
+		return &util.FileInfo{Name_: name, Mode_: git.ModeSubmodule, Sys_: git.Submodule{Path: name, CommitID: api.CommitID("pinned-" + name)}}, nil
+	}
+	defer git.ResetMocks()
+
+	var lookups []string
+	db.Mocks.Repos.GetByURIs = func(ctx context.Context, nameOrURIs []string) ([]*types.Repo, error) {
+		lookups = append(lookups, nameOrURIs...)
+		return []*types.Repo{
+			{ID: 2, Name: "lib", RepoFields: &types.RepoFields{URI: "github.com/foo/lib"}},
+			{ID: 3, Name: "github.com/foo/sibling"},
+		}, nil
+	}
+	defer func() { db.Mocks.Repos = db.MockRepos{} }()
+
+	repoRevs := []*search.RepositoryRevisions{
+		{Repo: &types.Repo{ID: 1, Name: "github.com/foo/parent"}, Revs: []search.RevisionSpecifier{{RevSpec: "master"}, {RevSpec: "dev"}}},
+		{Repo: &types.Repo{ID: 3, Name: "github.com/foo/sibling"}, Revs: []search.RevisionSpecifier{{RevSpec: "pinned-vendor/sibling"}}},
+	}
+	repoRevs, err := addSubmodules(context.Background(), repoRevs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, len(repoRevs))
+	for i, r := range repoRevs {
+		got[i] = r.String()
+	}
+	want := []string{
+		"github.com/foo/parent@master:dev",
+		"github.com/foo/sibling@pinned-vendor/sibling",
+		"lib@pinned-vendor/lib",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	sort.Strings(lookups)
+	if want := []string{"example.com/foo/unknown", "github.com/foo/lib", "github.com/foo/sibling"}; !reflect.DeepEqual(lookups, want) {
+		t.Errorf("got lookups %v, want %v", lookups, want)
+	}
+}
+
+func TestSubmoduleRepoURI(t *testing.T) {
+	for cloneURL, want := range map[string]string{
+		"https://github.com/foo/bar.git":      "github.com/foo/bar",
+		"https://user@github.com:443/foo/bar": "github.com/foo/bar",
+		"ssh://git@gitlab.example.com/a/b/c":  "gitlab.example.com/a/b/c",
+		"git@github.com:foo/bar.git":          "github.com/foo/bar",
+		"../baz.git":                          "github.com/foo/baz",
+		"./nested":                            "github.com/foo/bar/nested",
+		"/local/path":                         "",
+		"":                                    "",
+	} {
+		if got := submoduleRepoURI("github.com/foo/bar", cloneURL); got != want {
+			t.Errorf("submoduleRepoURI(%q) = %q, want %q", cloneURL, got, want)
+		}
+	}
+}
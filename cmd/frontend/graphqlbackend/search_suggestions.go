@@ -46,6 +46,11 @@ var (
 func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestionsArgs) ([]*searchSuggestionResolver, error) {
 	args.applyDefaultsAndConstraints()
 
+	ctx, err := withSearchTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(r.query.ParseTree()) == 0 {
 		return nil, nil
 	}
@@ -324,7 +329,19 @@ func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestion
 		log15.Error("error getting search suggestions: ", "error", err)
 	}
 
-	// Eliminate duplicates.
+	allSuggestions = dedupSearchSuggestions(allSuggestions)
+
+	sortSearchSuggestions(allSuggestions)
+	if len(allSuggestions) > int(*args.First) {
+		allSuggestions = allSuggestions[:*args.First]
+	}
+
+	return allSuggestions, nil
+}
+
+// dedupSearchSuggestions removes duplicate suggestions, keeping the first one.
+// It reuses the backing array of suggestions.
+func dedupSearchSuggestions(suggestions []*searchSuggestionResolver) []*searchSuggestionResolver {
 	type key struct {
 		repoName api.RepoName
 		repoRev  string
@@ -332,9 +349,9 @@ func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestion
 		symbol   string
 		lang     string
 	}
-	seen := make(map[key]struct{}, len(allSuggestions))
-	uniqueSuggestions := allSuggestions[:0]
-	for _, s := range allSuggestions {
+	seen := make(map[key]struct{}, len(suggestions))
+	uniqueSuggestions := suggestions[:0]
+	for _, s := range suggestions {
 		var k key
 		switch s := s.result.(type) {
 		case *RepositoryResolver:
@@ -365,14 +382,7 @@ func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestion
 			seen[k] = struct{}{}
 		}
 	}
-	allSuggestions = uniqueSuggestions
-
-	sortSearchSuggestions(allSuggestions)
-	if len(allSuggestions) > int(*args.First) {
-		allSuggestions = allSuggestions[:*args.First]
-	}
-
-	return allSuggestions, nil
+	return uniqueSuggestions
 }
 
 func allEmptyStrings(ss1, ss2 []string) bool {
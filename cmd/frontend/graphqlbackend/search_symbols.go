@@ -3,8 +3,6 @@ package graphqlbackend
 import (
 	"context"
 	"fmt"
-	"net/url"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -73,7 +71,10 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		zoektRepos    []*search.RepositoryRevisions
 	)
 
-	if args.Zoekt.Enabled() {
+	// The index is not used for users for whom the indexed search feature
+	// flag is disabled.
+	zoektEnabled := args.Zoekt.Enabled() && searchFeatureIndexedSearch.enabled(ctx)
+	if zoektEnabled {
 		filter := func(repo *zoekt.Repository) bool {
 			return repo.HasSymbols
 		}
@@ -105,11 +106,11 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		switch parseYesNoOnly(index) {
 		case Yes, True:
 			// default
-			if args.Zoekt.Enabled() {
+			if zoektEnabled {
 				tr.LogFields(otlog.Int("indexed-repos", len(zoektRepos)), otlog.Int("unindexed-repos", len(searcherRepos)))
 			}
 		case Only:
-			if !args.Zoekt.Enabled() {
+			if !zoektEnabled {
 				return nil, common, fmt.Errorf("invalid index:%q (indexed search is not enabled)", index)
 			}
 			common.missing = make([]*types.Repo, len(searcherRepos))
@@ -133,7 +134,7 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		run = parallel.NewRun(conf.SearchSymbolsParallelism())
 		mu  sync.Mutex
 
-		unflattened       [][]*FileMatchResolver
+		topK              = newFileMatchTopK(int(args.PatternInfo.FileMatchLimit), args.PatternInfo.Deterministic)
 		flattenedSize     int
 		overLimitCanceled bool
 	)
@@ -141,18 +142,16 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 	addMatches := func(matches []*FileMatchResolver) {
 		if len(matches) > 0 {
 			common.resultCount += int32(len(matches))
-			sort.Slice(matches, func(i, j int) bool {
-				a, b := matches[i].uri, matches[j].uri
-				return a > b
-			})
-			unflattened = append(unflattened, matches)
+			topK.add(matches)
 			flattenedSize += len(matches)
 
 			if flattenedSize > int(args.PatternInfo.FileMatchLimit) {
 				tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize, args.PatternInfo.FileMatchLimit)
-				overLimitCanceled = true
 				common.limitHit = true
-				cancelAll()
+				if !args.PatternInfo.Deterministic {
+					overLimitCanceled = true
+					cancelAll()
+				}
 			}
 		}
 	}
@@ -216,8 +215,7 @@ func searchSymbols(ctx context.Context, args *search.TextParameters, limit int)
 		})
 	}
 	err = run.Wait()
-	flattened := flattenFileMatches(unflattened, int(args.PatternInfo.FileMatchLimit))
-	res2 := limitSymbolResults(flattened, limit)
+	res2 := limitSymbolResults(topK.results(), limit)
 	common.limitHit = symbolCount(res2) < symbolCount(res)
 	return res2, common, err
 }
@@ -273,10 +271,7 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 		return nil, err
 	}
 	span.SetTag("commit", string(commitID))
-	baseURI, err := gituri.Parse("git://" + string(repoRevs.Repo.Name) + "?" + url.QueryEscape(inputRev))
-	if err != nil {
-		return nil, err
-	}
+	baseURI := gituri.New(repoRevs.Repo.Name, inputRev, "")
 
 	symbols, err := backend.Symbols.ListTags(ctx, search.SymbolsParameters{
 		Repo:            repoRevs.Repo.Name,
@@ -327,12 +322,7 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 // makeFileMatchURIFromSymbol makes a git://repo?rev#path URI from a symbol
 // search result to use in a fileMatchResolver
 func makeFileMatchURIFromSymbol(symbolResult *searchSymbolResult, inputRev string) string {
-	uri := "git:/" + string(symbolResult.commit.repo.URL())
-	if inputRev != "" {
-		uri += "?" + inputRev
-	}
-	uri += "#" + symbolResult.uri().Fragment
-	return uri
+	return fileMatchURI(symbolResult.commit.repo.repo.Name, inputRev, symbolResult.uri().Fragment)
 }
 
 func symbolRange(s protocol.Symbol) lsp.Range {
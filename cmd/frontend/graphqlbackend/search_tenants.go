@@ -0,0 +1,141 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var (
+	searchTenantActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "src_graphql_search_tenant_active",
+		Help: "Number of searches currently executing, by tenant.",
+	}, []string{"tenant"})
+	searchTenantSearches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "src_graphql_search_tenant_searches_total",
+		Help: "Number of searches, by tenant and whether they were rejected by the tenant's quota.",
+	}, []string{"tenant", "rejected"})
+)
+
+// searchTenantLabel returns the value of the tenant metrics label for the
+// search running in ctx.
+func searchTenantLabel(ctx context.Context) string {
+	if name := search.TenantName(ctx); name != "" {
+		return name
+	}
+	return "none"
+}
+
+type searchTenantKey struct{}
+
+// withSearchTenant returns a context with the tenant (see the search.tenants
+// site configuration) of the current user, so that it is resolved once per
+// search. The tenant is the first one that has an organization the user is a
+// member of.
+func withSearchTenant(ctx context.Context) (context.Context, error) {
+	if ctx.Value(searchTenantKey{}) != nil {
+		return ctx, nil
+	}
+	t, err := searchTenantOf(ctx, actor.FromContext(ctx).UID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, searchTenantKey{}, true)
+	if t == nil {
+		return ctx, nil
+	}
+	return search.WithTenant(ctx, t), nil
+}
+
+// searchTenantOf returns the tenant of the user with the given ID, or nil if
+// the user does not belong to one.
+func searchTenantOf(ctx context.Context, userID int32) (*schema.SearchTenant, error) {
+	tenants := conf.Get().SearchTenants
+	if len(tenants) == 0 || userID == 0 {
+		return nil, nil
+	}
+	orgs, err := db.Orgs.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tenants {
+		for _, name := range t.Organizations {
+			for _, org := range orgs {
+				if org.Name == name {
+					return t, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// searchTenantQuotas are the numbers of searches of each tenant currently
+// executing on this frontend instance, to enforce the tenants'
+// maxConcurrentSearches.
+var searchTenantQuotas = &tenantQuotas{active: map[string]int{}}
+
+type tenantQuotas struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+type searchTenantQuotaKey struct{}
+
+// acquire records the start of a search of the tenant of ctx, which runs in
+// the returned context. It fails with a TenantQuotaExceeded search error if
+// the tenant already has its maximum number of searches executing. The caller
+// must call release when the search finishes. If ctx already belongs to a
+// search that acquired the quota (e.g. for a search run by another one), it is
+// returned unchanged.
+func (q *tenantQuotas) acquire(ctx context.Context) (_ context.Context, release func(), err error) {
+	if ctx.Value(searchTenantQuotaKey{}) != nil {
+		return ctx, func() {}, nil
+	}
+	ctx = context.WithValue(ctx, searchTenantQuotaKey{}, true)
+
+	label := searchTenantLabel(ctx)
+	t := search.TenantFromContext(ctx)
+	if t == nil {
+		searchTenantSearches.WithLabelValues(label, "false").Inc()
+		return ctx, func() {}, nil
+	}
+
+	q.mu.Lock()
+	if t.MaxConcurrentSearches > 0 && q.active[t.Name] >= t.MaxConcurrentSearches {
+		q.mu.Unlock()
+		searchTenantSearches.WithLabelValues(label, "true").Inc()
+		return nil, nil, newSearchError(searchErrorTenantQuotaExceeded, fmt.Errorf("tenant %q already has the maximum of %d searches executing, try again later", t.Name, t.MaxConcurrentSearches))
+	}
+	q.active[t.Name]++
+	q.mu.Unlock()
+
+	searchTenantSearches.WithLabelValues(label, "false").Inc()
+	searchTenantActive.WithLabelValues(label).Inc()
+	return ctx, func() {
+		q.mu.Lock()
+		if q.active[t.Name]--; q.active[t.Name] <= 0 {
+			delete(q.active, t.Name)
+		}
+		q.mu.Unlock()
+		searchTenantActive.WithLabelValues(label).Dec()
+	}, nil
+}
+
+// withSearchTenantFrom returns ctx with the tenant resolved in from, for
+// searches that run in a context that is not derived from the request's.
+func withSearchTenantFrom(ctx, from context.Context) context.Context {
+	ctx = context.WithValue(ctx, searchTenantKey{}, true)
+	if t := search.TenantFromContext(from); t != nil {
+		ctx = search.WithTenant(ctx, t)
+	}
+	return ctx
+}
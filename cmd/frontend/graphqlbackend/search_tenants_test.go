@@ -0,0 +1,161 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/actor"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func mockSearchTenants(tenants ...*schema.SearchTenant) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchTenants: tenants}})
+}
+
+func TestWithSearchTenant(t *testing.T) {
+	acme := &schema.SearchTenant{Name: "acme", Organizations: []string{"acme"}, RepositoryPatterns: []string{"^acme/"}}
+	mockSearchTenants(&schema.SearchTenant{Name: "other", Organizations: []string{"other"}, RepositoryPatterns: []string{"^other/"}}, acme)
+	defer conf.Mock(nil)
+
+	defer func(mocks db.MockStores) { db.Mocks = mocks }(db.Mocks)
+
+	calls := 0
+	db.Mocks.Orgs.GetByUserID = func(_ context.Context, userID int32) ([]*types.Org, error) {
+		calls++
+		if userID == 1 {
+			return []*types.Org{{Name: "unrelated"}, {Name: "acme"}}, nil
+		}
+		return []*types.Org{}, nil
+	}
+
+	ctx, err := withSearchTenant(actor.WithActor(context.Background(), &actor.Actor{UID: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := search.TenantFromContext(ctx); got != acme {
+		t.Errorf("got tenant %+v, want %+v", got, acme)
+	}
+	if _, err := withSearchTenant(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls to Orgs.GetByUserID, want the tenant to be resolved once", calls)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"not a member": actor.WithActor(context.Background(), &actor.Actor{UID: 2}),
+		"anonymous":    context.Background(),
+	} {
+		ctx, err := withSearchTenant(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := search.TenantFromContext(ctx); got != nil {
+			t.Errorf("%s: got tenant %+v, want none", name, got)
+		}
+	}
+}
+
+func TestTenantQuotas(t *testing.T) {
+	q := &tenantQuotas{active: map[string]int{}}
+	ctx := search.WithTenant(context.Background(), &schema.SearchTenant{Name: "acme", MaxConcurrentSearches: 1})
+
+	ctx1, release1, err := q.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.acquire(ctx); err == nil {
+		t.Fatal("got nil error, want the tenant's quota to be exceeded")
+	} else if code, _ := searchErrorCodeOf(err); code != searchErrorTenantQuotaExceeded {
+		t.Errorf("got error code %q, want %q", code, searchErrorTenantQuotaExceeded)
+	}
+
+	// Searches run by a search do not count against the quota again.
+	_, release2, err := q.acquire(ctx1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release2()
+
+	// Searches of other users are not limited.
+	if _, release, err := q.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+
+	release1()
+	_, release3, err := q.acquire(ctx)
+	if err != nil {
+		t.Fatalf("got error %v after the quota was released", err)
+	}
+	release3()
+}
+
+func TestSearchResolver_resolveRepositories_tenant(t *testing.T) {
+	mockDecodedViewerFinalSettings = &schema.Settings{}
+	defer func() { mockDecodedViewerFinalSettings = nil }()
+	mockSearchTenants(&schema.SearchTenant{Name: "acme", Organizations: []string{"acme"}, RepositoryPatterns: []string{"^acme/a", "^acme/b"}})
+	defer conf.Mock(nil)
+
+	defer func(mocks db.MockStores) { db.Mocks = mocks }(db.Mocks)
+	db.Mocks.Orgs.GetByUserID = func(context.Context, int32) ([]*types.Org, error) {
+		return []*types.Org{{Name: "acme"}}, nil
+	}
+	var includePatterns []string
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		includePatterns = op.IncludePatterns
+		return []*types.Repo{{ID: 1, Name: "acme/a"}}, nil
+	}
+	db.Mocks.Repos.Count = mockCount
+
+	q, err := query.ParseAndCheck("repo:foo@bar x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &searchResolver{query: q}
+	if _, _, _, _, err := r.resolveRepositories(actor.WithActor(context.Background(), &actor.Actor{UID: 1}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"foo", "^acme/a|^acme/b"}; !reflect.DeepEqual(includePatterns, want) {
+		t.Errorf("got include patterns %q, want %q", includePatterns, want)
+	}
+}
+
+func TestReposExist_tenant(t *testing.T) {
+	mockSearchTenants(&schema.SearchTenant{Name: "acme", Organizations: []string{"acme"}, RepositoryPatterns: []string{"^acme/"}})
+	defer conf.Mock(nil)
+
+	defer func(mocks db.MockStores) { db.Mocks = mocks }(db.Mocks)
+	db.Mocks.Orgs.GetByUserID = func(context.Context, int32) ([]*types.Org, error) {
+		return []*types.Org{{Name: "acme"}}, nil
+	}
+	var includePatterns []string
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		includePatterns = op.IncludePatterns
+		return nil, nil
+	}
+
+	ctx, err := withSearchTenant(actor.WithActor(context.Background(), &actor.Actor{UID: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reposExist(ctx, resolveRepoOp{repoFilters: []string{"foo"}})
+	if want := []string{"foo", "^acme/"}; !reflect.DeepEqual(includePatterns, want) {
+		t.Errorf("got include patterns %q, want %q", includePatterns, want)
+	}
+}
+
+func TestResolveRepoOpCacheKey_tenant(t *testing.T) {
+	a := resolveRepoOp{repoFilters: []string{"foo"}, tenant: &schema.SearchTenant{Name: "a"}}
+	b := resolveRepoOp{repoFilters: []string{"foo"}, tenant: &schema.SearchTenant{Name: "b"}}
+	if a.cacheKey() == b.cacheKey() {
+		t.Error("got the same repository cache key for different tenants")
+	}
+}
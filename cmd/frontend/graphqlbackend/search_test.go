@@ -484,7 +484,7 @@ func TestEueryForStableResults(t *testing.T) {
 		},
 		{
 			query:     "foo stable:yes count:5001",
-			wantError: fmt.Errorf("Stable searches are limited to at max count:%d results. Consider removing 'stable:', narrowing the search with 'repo:', or using the paginated search API.", maxSearchResultsPerPaginatedRequest),
+			wantError: fmt.Errorf("Stable searches are limited to at max count:%d results. Consider removing 'stable:', narrowing the search with 'repo:', or using the paginated search API.", maxSearchResultsPerPaginatedRequest()),
 		},
 	}
 	for _, c := range cases {
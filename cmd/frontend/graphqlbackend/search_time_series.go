@@ -0,0 +1,245 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxMatchCountSeriesPoints is the maximum number of points of a match
+	// count series.
+	maxMatchCountSeriesPoints = 52
+
+	// maxMatchCountSeriesRepos is the maximum number of repositories whose
+	// history a match count series samples.
+	maxMatchCountSeriesRepos = 50
+
+	// maxMatchCountSeriesFileMatchLimit is the maximum number of file matches
+	// that are counted across all sampled commits.
+	maxMatchCountSeriesFileMatchLimit = 100000
+)
+
+// matchCountSeriesArgs are the arguments of Search.matchCountSeries.
+type matchCountSeriesArgs struct {
+	Since  *DateTime
+	Until  *DateTime
+	Points int32
+}
+
+// MatchCountSeries evaluates the search at commits sampled evenly in time
+// from the history of the searched repositories, and returns the number of
+// matches at each sampled date. This shows how the occurrences of a pattern
+// change over time (e.g. how the uses of a deprecated API are burned down).
+//
+// Each repository is searched at its newest commit before each date on the
+// searched revision (or the default branch). Commits that are sampled for
+// several dates are searched once.
+func (r *searchResolver) MatchCountSeries(ctx context.Context, args *matchCountSeriesArgs) ([]*matchCountPointResolver, error) {
+	until := time.Now()
+	if args.Until != nil {
+		until = args.Until.Time
+	}
+	since := until.AddDate(-1, 0, 0)
+	if args.Since != nil {
+		since = args.Since.Time
+	}
+	if !since.Before(until) {
+		return nil, errors.New("since must be before until")
+	}
+	if args.Points < 1 || args.Points > maxMatchCountSeriesPoints {
+		return nil, fmt.Errorf("points must be between 1 and %d", maxMatchCountSeriesPoints)
+	}
+	dates := sampleDates(since, until, int(args.Points))
+
+	ctx, cancel, err := r.withTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	repos, _, _, _, err := r.resolveRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(repos) > maxMatchCountSeriesRepos {
+		return nil, fmt.Errorf("match count series search the history of at most %d repositories, but the query matches %d (use repo: to search fewer)", maxMatchCountSeriesRepos, len(repos))
+	}
+
+	options := &getPatternInfoOptions{fileMatchLimit: maxMatchCountSeriesFileMatchLimit}
+	if r.patternType == query.SearchTypeStructural {
+		options.performStructuralSearch = true
+	}
+	if r.patternType == query.SearchTypeLiteral {
+		options.performLiteralSearch = true
+	}
+	p, err := r.getPatternInfo(options)
+	if err != nil {
+		return nil, err
+	}
+	// Only the number of matches is needed, so searcher doesn't need to
+	// return line matches.
+	p.CountOnly = true
+
+	samples := sampleRepoCommits(ctx, repos, dates)
+
+	var repoRevs []*search.RepositoryRevisions
+	seen := map[repoCommit]bool{}
+	for _, repo := range repos {
+		for _, commitID := range samples[repo.Repo.ID] {
+			rc := repoCommit{repo: repo.Repo.ID, commitID: commitID}
+			if commitID == "" || seen[rc] {
+				continue
+			}
+			seen[rc] = true
+			repoRevs = append(repoRevs, &search.RepositoryRevisions{
+				Repo: repo.Repo,
+				Revs: []search.RevisionSpecifier{{RevSpec: string(commitID)}},
+			})
+		}
+	}
+
+	var (
+		counts  = map[repoCommit]int32{}
+		common  = &searchResultsCommon{}
+		results []*FileMatchResolver
+	)
+	if len(repoRevs) > 0 {
+		args := search.TextParameters{
+			PatternInfo:     p,
+			Repos:           repoRevs,
+			Query:           r.query,
+			UseFullDeadline: r.searchTimeoutFieldSet(),
+			Zoekt:           r.zoekt,
+			SearcherURLs:    r.searcherURLs,
+		}
+		if err := args.PatternInfo.Validate(); err != nil {
+			return nil, &badRequestError{err}
+		}
+		results, common, err = searchFilesInRepos(ctx, &args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, fm := range results {
+		counts[repoCommit{repo: fm.Repo.ID, commitID: fm.CommitID}] += fm.resultCount()
+	}
+
+	// The matches of a repository may be incomplete at every commit it was
+	// searched at.
+	incomplete := map[api.RepoName]bool{}
+	for name := range common.partial {
+		incomplete[name] = true
+	}
+	for _, repo := range common.timedout {
+		incomplete[repo.Name] = true
+	}
+
+	points := make([]*matchCountPointResolver, len(dates))
+	for i, date := range dates {
+		point := &matchCountPointResolver{date: date, limitHit: common.limitHit}
+		for _, repo := range repos {
+			commitID := samples[repo.Repo.ID][i]
+			if commitID == "" {
+				continue
+			}
+			point.matchCount += counts[repoCommit{repo: repo.Repo.ID, commitID: commitID}]
+			if incomplete[repo.Repo.Name] {
+				point.limitHit = true
+			}
+		}
+		points[i] = point
+	}
+	return points, nil
+}
+
+func (searchAlert) MatchCountSeries(context.Context, *matchCountSeriesArgs) ([]*matchCountPointResolver, error) {
+	return nil, nil
+}
+
+// sampleDates returns n dates evenly spaced from since to until (inclusive).
+// If n is 1, only until is returned.
+func sampleDates(since, until time.Time, n int) []time.Time {
+	if n == 1 {
+		return []time.Time{until}
+	}
+	dates := make([]time.Time, n)
+	step := until.Sub(since) / time.Duration(n-1)
+	for i := range dates {
+		dates[i] = since.Add(step * time.Duration(i))
+	}
+	dates[n-1] = until
+	return dates
+}
+
+// repoCommit identifies a commit of a repository.
+type repoCommit struct {
+	repo     api.RepoID
+	commitID api.CommitID
+}
+
+// sampleRepoCommits returns, for each repository and each date, the newest
+// commit before the date on the first searched revision of the repository
+// (or its default branch). The commit is empty if the repository has no
+// commits before the date or if its history can't be listed.
+func sampleRepoCommits(ctx context.Context, repoRevs []*search.RepositoryRevisions, dates []time.Time) map[api.RepoID][]api.CommitID {
+	var (
+		run     = parallel.NewRun(revisionResolutionParallelism)
+		samples = make(map[api.RepoID][]api.CommitID, len(repoRevs))
+	)
+	for _, repoRev := range repoRevs {
+		var head string
+		for _, rev := range repoRev.Revs {
+			if rev.RefGlob == "" && rev.ExcludeRefGlob == "" {
+				head = rev.RevSpec
+				break
+			}
+		}
+		commitIDs := make([]api.CommitID, len(dates))
+		samples[repoRev.Repo.ID] = commitIDs
+		for i, date := range dates {
+			repoRev, i, date := repoRev, i, date
+			run.Acquire()
+			goroutine.Go(func() {
+				defer run.Release()
+				commits, err := git.Commits(ctx, repoRev.GitserverRepo(), git.CommitsOptions{
+					Range:  head,
+					N:      1,
+					Before: date.Format(time.RFC3339),
+				})
+				if err != nil {
+					if ctx.Err() == nil {
+						log15.Warn("Failed to list commits to sample match counts", "repo", repoRev.Repo.Name, "head", head, "before", date, "error", err)
+					}
+					return
+				}
+				if len(commits) == 0 {
+					return
+				}
+				commitIDs[i] = commits[0].ID
+			})
+		}
+	}
+	_ = run.Wait()
+	return samples
+}
+
+// matchCountPointResolver resolves a point of a match count series.
+type matchCountPointResolver struct {
+	date       time.Time
+	matchCount int32
+	limitHit   bool
+}
+
+func (p *matchCountPointResolver) Date() DateTime    { return DateTime{Time: p.date} }
+func (p *matchCountPointResolver) MatchCount() int32 { return p.matchCount }
+func (p *matchCountPointResolver) LimitHit() bool    { return p.limitHit }
@@ -0,0 +1,119 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestSearchMatchCountSeries(t *testing.T) {
+	foo := &types.Repo{ID: 1, Name: "github.com/foo/foo"}
+	bar := &types.Repo{ID: 2, Name: "github.com/foo/bar"}
+	mockResolveRepositories = func(effectiveRepoFieldValues []string) (repoRevs, missingRepoRevs []*search.RepositoryRevisions, excludedRepos *excludedRepos, overLimit bool, err error) {
+		return []*search.RepositoryRevisions{
+			{Repo: foo, Revs: []search.RevisionSpecifier{{RevSpec: ""}}},
+			{Repo: bar, Revs: []search.RevisionSpecifier{{RevSpec: "dev"}}},
+		}, nil, nil, false, nil
+	}
+	defer func() { mockResolveRepositories = nil }()
+
+	git.Mocks.Commits = func(repo gitserver.Repo, opt git.CommitsOptions) ([]*git.Commit, error) {
+		switch {
+		case repo.Name == bar.Name:
+			if opt.Range != "dev" {
+				t.Errorf("got range %q, want dev", opt.Range)
+			}
+			return []*git.Commit{{ID: "b1"}}, nil
+		case opt.Before < "2020-01-02":
+			return nil, nil
+		case opt.Before < "2020-01-04":
+			return []*git.Commit{{ID: "f1"}}, nil
+		}
+		return []*git.Commit{{ID: "f2"}}, nil
+	}
+	defer git.ResetMocks()
+
+	var (
+		mu       sync.Mutex
+		searched []string
+	)
+	mockSearchFilesInRepos = func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error) {
+		if !args.PatternInfo.CountOnly {
+			t.Error("got a search that is not count-only")
+		}
+		counts := map[string]int{"f1": 3, "f2": 1, "b1": 2}
+		var results []*FileMatchResolver
+		for _, repoRev := range args.Repos {
+			rev := repoRev.Revs[0].RevSpec
+			mu.Lock()
+			searched = append(searched, rev)
+			mu.Unlock()
+			results = append(results, &FileMatchResolver{Repo: repoRev.Repo, CommitID: api.CommitID(rev), JPath: "a.go", MatchCount: counts[rev]})
+		}
+		return results, &searchResultsCommon{partial: map[api.RepoName]struct{}{bar.Name: {}}}, nil
+	}
+	defer func() { mockSearchFilesInRepos = nil }()
+
+	q, err := query.ParseAndCheck("deprecated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &searchResolver{query: q, patternType: query.SearchTypeLiteral}
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	points, err := r.MatchCountSeries(context.Background(), &matchCountSeriesArgs{
+		Since:  &DateTime{Time: since},
+		Until:  &DateTime{Time: since.AddDate(0, 0, 3)},
+		Points: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(searched)
+	if want := []string{"b1", "f1", "f2"}; !reflect.DeepEqual(searched, want) {
+		t.Errorf("got searched commits %v, want %v", searched, want)
+	}
+
+	type point struct {
+		Date       string
+		MatchCount int32
+		LimitHit   bool
+	}
+	var got []point
+	for _, p := range points {
+		got = append(got, point{Date: p.Date().Format("2006-01-02"), MatchCount: p.MatchCount(), LimitHit: p.LimitHit()})
+	}
+	want := []point{
+		{"2020-01-01", 2, true},
+		{"2020-01-02", 5, true},
+		{"2020-01-03", 5, true},
+		{"2020-01-04", 3, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSearchMatchCountSeries_invalidArgs(t *testing.T) {
+	now := time.Now()
+	tests := []*matchCountSeriesArgs{
+		{Since: &DateTime{Time: now}, Until: &DateTime{Time: now.Add(-time.Hour)}, Points: 12},
+		{Points: 0},
+		{Points: maxMatchCountSeriesPoints + 1},
+	}
+	for _, args := range tests {
+		if _, err := (&searchResolver{}).MatchCountSeries(context.Background(), args); err == nil {
+			t.Errorf("%+v: got no error", args)
+		}
+	}
+}
@@ -0,0 +1,514 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// searchScopeFields are the fields a request authenticated with a search-only
+// access token may select, by the name of the type they belong to, with the
+// name of the type of their values ("" for scalars and enums). __typename may be
+// selected on all listed types, and listed types may be used as type
+// conditions of fragments.
+//
+// 🚨 SECURITY: Fields whose values lead to data outside of the search results,
+// such as the commits, contributors and settings of repositories, must not be
+// listed.
+var searchScopeFields = map[string]map[string]string{
+	"Query":  {"search": "Search"},
+	"Search": {"results": "SearchResults"},
+	"SearchResults": {
+		"results":                "SearchResult",
+		"matchCount":             "",
+		"resultCount":            "",
+		"approximateResultCount": "",
+		"limitHit":               "",
+		"indexUnavailable":       "",
+		"elapsedMilliseconds":    "",
+		"alert":                  "SearchAlert",
+		"dynamicFilters":         "SearchFilter",
+	},
+	"SearchResult":                 {}, // a union of FileMatch, CommitSearchResult and Repository
+	"GenericSearchResultInterface": genericSearchResultScopeFields,
+	"CommitSearchResult":           genericSearchResultScopeFields,
+	"FileMatch": {
+		"file":        "GitBlob",
+		"repository":  "Repository",
+		"resource":    "",
+		"lineMatches": "LineMatch",
+		"limitHit":    "",
+		"license":     "",
+		"owners":      "",
+	},
+	"GitBlob":    {"path": "", "name": "", "url": "", "canonicalURL": ""},
+	"Repository": {"name": "", "url": ""},
+	"LineMatch": {
+		"preview":          "",
+		"lineNumber":       "",
+		"offsetAndLengths": "",
+		"limitHit":         "",
+		"rules":            "SearchRule",
+	},
+	"SearchRule":             {"id": "", "description": "", "severity": ""},
+	"SearchResultMatch":      {"url": "", "body": "Markdown", "highlights": "Highlight"},
+	"Markdown":               {"text": "", "html": ""},
+	"Highlight":              {"line": "", "character": "", "length": ""},
+	"SearchAlert":            {"title": "", "description": "", "proposedQueries": "SearchQueryDescription"},
+	"SearchQueryDescription": {"description": "", "query": ""},
+	"SearchFilter":           {"value": "", "label": "", "count": "", "limitHit": "", "kind": ""},
+}
+
+var genericSearchResultScopeFields = map[string]string{
+	"icon":    "",
+	"label":   "Markdown",
+	"url":     "",
+	"detail":  "Markdown",
+	"matches": "SearchResultMatch",
+}
+
+// CheckSearchScopedRequest returns an error if the request is authenticated with a
+// search-only access token (see authz.ScopeSearch) and the GraphQL query does
+// anything other than running searches. It is a no-op for all other requests.
+//
+// The whole query, including its fragments, is checked against
+// searchScopeFields, since the results of searches lead to other data (e.g. the
+// commits of their repositories) that the token must not grant access to.
+//
+// 🚨 SECURITY: This must be called before executing the query, since search-only
+// tokens otherwise grant the same privileges as the token's subject user.
+func CheckSearchScopedRequest(ctx context.Context, query string) error {
+	if authz.SearchScopeFromContext(ctx) == nil {
+		return nil
+	}
+	operations, fragments, err := parseGraphQLQuery(query)
+	if err != nil {
+		return err
+	}
+	c := searchScopeChecker{fragments: fragments, checked: map[string]bool{}}
+	for _, sels := range operations {
+		if err := c.check("Query", sels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchScopeChecker checks the selections of a query against
+// searchScopeFields.
+type searchScopeChecker struct {
+	fragments map[string]*graphQLFragment
+	checked   map[string]bool // the fragments that are (being) checked
+}
+
+// check returns an error if sels selects fields of typ that are not listed in
+// searchScopeFields.
+func (c *searchScopeChecker) check(typ string, sels []graphQLSelection) error {
+	fields, ok := searchScopeFields[typ]
+	if !ok {
+		return fmt.Errorf("access tokens with scope %q may not query the type %q", authz.ScopeSearch, typ)
+	}
+	for _, sel := range sels {
+		switch {
+		case sel.spread != "":
+			// Fragments are checked once, which also stops at cycles (they
+			// are rejected when the query is validated).
+			if c.checked[sel.spread] {
+				continue
+			}
+			c.checked[sel.spread] = true
+			f, ok := c.fragments[sel.spread]
+			if !ok {
+				return fmt.Errorf("unknown fragment %q", sel.spread)
+			}
+			if err := c.check(f.typeCond, f.selections); err != nil {
+				return err
+			}
+		case sel.field == "":
+			inlineTyp := typ
+			if sel.typeCond != "" {
+				inlineTyp = sel.typeCond
+			}
+			if err := c.check(inlineTyp, sel.selections); err != nil {
+				return err
+			}
+		case sel.field == "__typename":
+		default:
+			fieldTyp, ok := fields[sel.field]
+			if !ok {
+				return fmt.Errorf("access tokens with scope %q may not query the field %q of %q", authz.ScopeSearch, sel.field, typ)
+			}
+			if fieldTyp != "" {
+				if err := c.check(fieldTyp, sel.selections); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkSearchScope enforces the repository and version context restrictions of a
+// search-only access token. It returns the subsets of repoRevs and
+// missingRepoRevs that may be searched, and an error if the version context may
+// not be used.
+func checkSearchScope(ctx context.Context, versionContext string, repoRevs, missingRepoRevs []*search.RepositoryRevisions) (allowed, allowedMissing []*search.RepositoryRevisions, err error) {
+	scope := authz.SearchScopeFromContext(ctx)
+	if scope == nil {
+		return repoRevs, missingRepoRevs, nil
+	}
+	if !scope.AllowsVersionContext(versionContext) {
+		if versionContext == "" {
+			return nil, nil, fmt.Errorf("this access token may only be used to search within the version contexts %q", scope.VersionContexts)
+		}
+		return nil, nil, fmt.Errorf("this access token may not be used to search within the version context %q", versionContext)
+	}
+	// The filtered lists are new slices, since repoRevs may be shared with the
+	// repository resolution cache and other searches.
+	filter := func(repoRevs []*search.RepositoryRevisions) []*search.RepositoryRevisions {
+		var allowed []*search.RepositoryRevisions
+		for _, rr := range repoRevs {
+			if scope.AllowsRepo(rr.Repo.Name) {
+				allowed = append(allowed, rr)
+			}
+		}
+		return allowed
+	}
+	return filter(repoRevs), filter(missingRepoRevs), nil
+}
+
+// graphQLSelection is a field, fragment spread or inline fragment of the
+// selection set of a GraphQL operation or fragment.
+type graphQLSelection struct {
+	field      string             // the name (not alias) of a selected field
+	spread     string             // the name of a spread fragment
+	typeCond   string             // the type condition of an inline fragment, if any
+	selections []graphQLSelection // the selection set of a field or inline fragment
+}
+
+// graphQLFragment is a fragment definition of a GraphQL document.
+type graphQLFragment struct {
+	typeCond   string
+	selections []graphQLSelection
+}
+
+// parseGraphQLQuery returns the selection sets of the query operations of the
+// GraphQL document and its fragment definitions by name. Mutations and
+// subscriptions are reported as errors.
+func parseGraphQLQuery(doc string) (operations [][]graphQLSelection, fragments map[string]*graphQLFragment, err error) {
+	l := &graphQLLexer{src: doc}
+	fragments = map[string]*graphQLFragment{}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch tok {
+		case "":
+			return operations, fragments, nil
+		case "{":
+			sels, err := l.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			operations = append(operations, sels)
+		case "query":
+			// Skip name, variable definitions and directives until the selection set.
+			if err := l.skipToSelectionSet(); err != nil {
+				return nil, nil, err
+			}
+			sels, err := l.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			operations = append(operations, sels)
+		case "fragment":
+			name, err := l.next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if on, err := l.next(); err != nil || on != "on" {
+				return nil, nil, fmt.Errorf("expected type condition of fragment %q", name)
+			}
+			typeCond, err := l.next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if !isGraphQLName(name) || !isGraphQLName(typeCond) {
+				return nil, nil, fmt.Errorf("invalid fragment %q on %q", name, typeCond)
+			}
+			if err := l.skipToSelectionSet(); err != nil {
+				return nil, nil, err
+			}
+			sels, err := l.selectionSet()
+			if err != nil {
+				return nil, nil, err
+			}
+			fragments[name] = &graphQLFragment{typeCond: typeCond, selections: sels}
+		default:
+			return nil, nil, fmt.Errorf("unsupported GraphQL operation %q", tok)
+		}
+	}
+}
+
+// skipToSelectionSet consumes tokens up to and including the opening brace of
+// the next selection set, skipping parenthesized arguments and variable
+// definitions.
+func (l *graphQLLexer) skipToSelectionSet() error {
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return fmt.Errorf("unexpected end of query, expected %q", "{")
+		case "(":
+			if err := l.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+		case "{":
+			return nil
+		}
+	}
+}
+
+// selectionSet returns the selections of the selection set whose opening brace
+// has just been consumed.
+func (l *graphQLLexer) selectionSet() ([]graphQLSelection, error) {
+	var sels []graphQLSelection
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok == "}":
+			return sels, nil
+		case tok == "":
+			return nil, fmt.Errorf("unexpected end of selection set")
+		case tok == "...":
+			sel, err := l.fragmentSelection()
+			if err != nil {
+				return nil, err
+			}
+			sels = append(sels, sel)
+			continue
+		case !isGraphQLName(tok):
+			return nil, fmt.Errorf("unexpected %q in selection set", tok)
+		}
+
+		sel := graphQLSelection{field: tok}
+		if l.peek() == ":" {
+			_, _ = l.next()
+			if sel.field, err = l.next(); err != nil {
+				return nil, err
+			}
+			if !isGraphQLName(sel.field) {
+				return nil, fmt.Errorf("unexpected %q after alias", sel.field)
+			}
+		}
+		if l.peek() == "(" {
+			_, _ = l.next()
+			if err := l.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+		if err := l.skipDirectives(); err != nil {
+			return nil, err
+		}
+		if l.peek() == "{" {
+			_, _ = l.next()
+			if sel.selections, err = l.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		sels = append(sels, sel)
+	}
+}
+
+// fragmentSelection returns the fragment spread or inline fragment whose "..."
+// has just been consumed.
+func (l *graphQLLexer) fragmentSelection() (graphQLSelection, error) {
+	var sel graphQLSelection
+	switch tok := l.peek(); {
+	case tok == "on":
+		_, _ = l.next()
+		typeCond, err := l.next()
+		if err != nil {
+			return sel, err
+		}
+		if !isGraphQLName(typeCond) {
+			return sel, fmt.Errorf("unexpected %q in type condition", typeCond)
+		}
+		sel.typeCond = typeCond
+	case isGraphQLName(tok):
+		_, _ = l.next()
+		sel.spread = tok
+		return sel, l.skipDirectives()
+	}
+
+	if err := l.skipDirectives(); err != nil {
+		return sel, err
+	}
+	if tok, err := l.next(); err != nil {
+		return sel, err
+	} else if tok != "{" {
+		return sel, fmt.Errorf("unexpected %q, expected selection set of inline fragment", tok)
+	}
+	var err error
+	sel.selections, err = l.selectionSet()
+	return sel, err
+}
+
+// skipDirectives consumes the directives (e.g. "@include(if: $x)") that follow.
+func (l *graphQLLexer) skipDirectives() error {
+	for l.peek() == "@" {
+		_, _ = l.next()
+		if _, err := l.next(); err != nil {
+			return err
+		}
+		if l.peek() == "(" {
+			_, _ = l.next()
+			if err := l.skipBalanced("(", ")"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// graphQLLexer is a minimal GraphQL tokenizer. It only distinguishes what is
+// needed to find selected fields: names, punctuators and (opaque) string and
+// number values.
+type graphQLLexer struct {
+	src string
+	pos int
+}
+
+// skipBalanced consumes tokens until the close token matching an already
+// consumed open token.
+func (l *graphQLLexer) skipBalanced(open, close string) error {
+	depth := 1
+	for depth > 0 {
+		tok, err := l.next()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "":
+			return fmt.Errorf("unexpected end of query, expected %q", close)
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+	}
+	return nil
+}
+
+func (l *graphQLLexer) peek() string {
+	pos := l.pos
+	tok, _ := l.next()
+	l.pos = pos
+	return tok
+}
+
+// next returns the next token, or "" at the end of the input. String values are
+// returned as `"`.
+func (l *graphQLLexer) next() (string, error) {
+	// Skip ignored tokens: whitespace, commas, comments and the BOM.
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+		} else if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if len(l.src[l.pos:]) >= 3 && l.src[l.pos:l.pos+3] == "\uFEFF" {
+			l.pos += 3
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return "", nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '.':
+		if len(l.src[l.pos:]) < 3 || l.src[l.pos:l.pos+3] != "..." {
+			return "", fmt.Errorf("unexpected %q at offset %d", c, l.pos)
+		}
+		l.pos += 3
+		return "...", nil
+	case c == '"':
+		if len(l.src[l.pos:]) >= 3 && l.src[l.pos:l.pos+3] == `"""` {
+			l.pos += 3
+			for {
+				if l.pos >= len(l.src) {
+					return "", fmt.Errorf("unterminated block string at offset %d", start)
+				}
+				if l.src[l.pos] == '\\' && len(l.src[l.pos:]) >= 4 && l.src[l.pos:l.pos+4] == `\"""` {
+					l.pos += 4
+					continue
+				}
+				if len(l.src[l.pos:]) >= 3 && l.src[l.pos:l.pos+3] == `"""` {
+					l.pos += 3
+					return `"`, nil
+				}
+				l.pos++
+			}
+		}
+		l.pos++
+		for {
+			if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+				return "", fmt.Errorf("unterminated string at offset %d", start)
+			}
+			switch l.src[l.pos] {
+			case '\\':
+				l.pos += 2
+			case '"':
+				l.pos++
+				return `"`, nil
+			default:
+				l.pos++
+			}
+		}
+	case isGraphQLNameStart(c):
+		for l.pos < len(l.src) && (isGraphQLNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return l.src[start:l.pos], nil
+	case isDigit(c) || c == '-':
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.' || l.src[l.pos] == 'e' || l.src[l.pos] == 'E' || l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		return l.src[start:l.pos], nil
+	default:
+		switch c {
+		case '{', '}', '(', ')', '[', ']', ':', '@', '$', '=', '!', '|', '&':
+			l.pos++
+			return string(c), nil
+		}
+		return "", fmt.Errorf("unexpected %q at offset %d", c, l.pos)
+	}
+}
+
+func isGraphQLName(tok string) bool {
+	return tok != "" && isGraphQLNameStart(tok[0])
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
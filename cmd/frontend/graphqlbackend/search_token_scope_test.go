@@ -0,0 +1,92 @@
+package graphqlbackend
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestCheckSearchScopedRequest(t *testing.T) {
+	query := `{ currentUser { username } }`
+	if err := CheckSearchScopedRequest(context.Background(), query); err != nil {
+		t.Errorf("unexpected error for unscoped request: %s", err)
+	}
+
+	ctx := authz.WithSearchScope(context.Background(), &authz.SearchScope{})
+	tests := []struct {
+		doc     string
+		wantErr bool
+	}{
+		{doc: `{ search(query: "foo") { results { matchCount } } }`},
+		{doc: `query Search($q: String!) { s: search(query: $q) @include(if: true) { results { __typename } } __typename }`},
+		{doc: `# comment with { braces
+			query ($q: String = "} currentUser {") { search(query: $q) { results { alert { title } } } }`},
+		{doc: `query { search(query: """ { currentUser } """) { results { matchCount } } }`},
+		{doc: `query { search { ...F } } fragment F on Search { results { matchCount } }`},
+		{doc: `{ search { results { results { __typename ... on FileMatch { file { path url } repository { name } lineMatches { preview lineNumber rules { id } } } ... on Repository { name } ... on CommitSearchResult { label { text } matches { url } } } } } }`},
+		{doc: `{ search { results { results { ...R } } } } fragment R on SearchResult { ... @include(if: true) { __typename } ...R }`},
+		{doc: `{ ... on Query { search { results { matchCount } } } }`},
+		{doc: `{ search { results { matchCount } } currentUser { username } }`, wantErr: true},
+		{doc: `{ search: currentUser { username } }`, wantErr: true},
+		{doc: `{ ...F } fragment F on Query { currentUser { username } }`, wantErr: true},
+		{doc: `{ ... on Query { currentUser { username } } }`, wantErr: true},
+		{doc: `{ search { results { results { ... on FileMatch { repository { commit(rev: "HEAD") { oid } } } } } } }`, wantErr: true},
+		{doc: `{ search { results { results { ... on FileMatch { file { commit { author { person { user { username } } } } } } } } } }`, wantErr: true},
+		{doc: `{ search { results { results { ... on CommitSearchResult { commit { oid } } } } } }`, wantErr: true},
+		{doc: `{ search { results { results { ... on FileMatch { ...F } } } } } fragment F on FileMatch { repository { name } ...G } fragment G on Repository { contributors { totalCount } }`, wantErr: true},
+		{doc: `{ search { results { results { ... on User { username } } } } }`, wantErr: true},
+		{doc: `{ search { results { cloning { name } } } }`, wantErr: true},
+		{doc: `{ search { results { results { ...Missing } } } }`, wantErr: true},
+		{doc: `mutation { deleteUser(user: "VXNlcjox") { alwaysNil } }`, wantErr: true},
+		{doc: `{ search(query: "foo) { results }`, wantErr: true},
+		{doc: `{ search { results }`, wantErr: true},
+	}
+	for _, test := range tests {
+		if err := CheckSearchScopedRequest(ctx, test.doc); (err != nil) != test.wantErr {
+			t.Errorf("%s: got err %v, want error %v", test.doc, err, test.wantErr)
+		}
+	}
+}
+
+func TestCheckSearchScope(t *testing.T) {
+	repoRevs := func(names ...string) []*search.RepositoryRevisions {
+		var rs []*search.RepositoryRevisions
+		for _, name := range names {
+			rs = append(rs, &search.RepositoryRevisions{Repo: &types.Repo{Name: api.RepoName(name)}})
+		}
+		return rs
+	}
+
+	scope := &authz.SearchScope{
+		RepoPatterns:    []*regexp.Regexp{regexp.MustCompile(`^github\.com/foo/`)},
+		VersionContexts: []string{"ctx1"},
+	}
+	ctx := authz.WithSearchScope(context.Background(), scope)
+
+	if _, _, err := checkSearchScope(ctx, "", nil, nil); err == nil {
+		t.Error("expected error when searching without a version context")
+	}
+	if _, _, err := checkSearchScope(ctx, "ctx2", nil, nil); err == nil {
+		t.Error("expected error when searching an unlisted version context")
+	}
+
+	all := repoRevs("github.com/bar/b", "github.com/foo/a")
+	allowed, allowedMissing, err := checkSearchScope(ctx, "ctx1", all, repoRevs("github.com/bar/c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allowed) != 1 || allowed[0].Repo.Name != "github.com/foo/a" {
+		t.Errorf("unexpected allowed repos %v", allowed)
+	}
+	if all[0].Repo.Name != "github.com/bar/b" || all[1].Repo.Name != "github.com/foo/a" {
+		t.Errorf("checkSearchScope modified its input %v", all)
+	}
+	if len(allowedMissing) != 0 {
+		t.Errorf("unexpected allowed missing repos %v", allowedMissing)
+	}
+}
@@ -0,0 +1,204 @@
+package graphqlbackend
+
+import (
+	"container/heap"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// fileMatchTopK accumulates the best k file matches of a search as they come
+// in, so that memory stays proportional to k (instead of to the number of
+// matches in all repositories searched), and the best matches so far can be
+// taken at any time.
+//
+// Matches are ranked by their position among the matches in their repository,
+// and then by URI, which ensures that the results include matches from as
+// many repositories as possible. If deterministic, matches are only ranked by
+// URI, since their positions depend on the order in which they are added.
+//
+// Matches are appended to h until there are k of them, which is the common
+// case for large k (e.g. with a large count:), and only then is h turned into
+// a heap.
+type fileMatchTopK struct {
+	k             int
+	deterministic bool
+	h             rankedFileMatchHeap
+	heapified     bool                 // whether h is a heap, which it is once it holds k matches
+	perRepo       map[api.RepoName]int // number of matches added per repository
+}
+
+// maxTopKPresize is the maximum number of matches that a fileMatchTopK makes
+// room for up front.
+const maxTopKPresize = 4096
+
+func newFileMatchTopK(k int, deterministic bool) *fileMatchTopK {
+	presize := k
+	if presize > maxTopKPresize {
+		presize = maxTopKPresize
+	}
+	return &fileMatchTopK{k: k, deterministic: deterministic, h: make(rankedFileMatchHeap, 0, presize), perRepo: map[api.RepoName]int{}}
+}
+
+// add adds matches. Matches of a repository that are added in the same call
+// are ranked in descending order of their URIs. It returns the matches that
+// are not among the best k anymore, which may include some of matches.
+func (t *fileMatchTopK) add(matches []*FileMatchResolver) (evicted []*FileMatchResolver) {
+	sort.Sort(fileMatchesByURIDesc(matches))
+	for _, fm := range matches {
+		var repo api.RepoName
+		if fm.Repo != nil {
+			repo = fm.Repo.Name
+		}
+		m := rankedFileMatch{fm: fm}
+		if !t.deterministic {
+			m.rank = t.perRepo[repo]
+			t.perRepo[repo]++
+		}
+
+		if len(t.h) < t.k {
+			t.h = append(t.h, m)
+			continue
+		}
+		if len(t.h) == 0 {
+			evicted = append(evicted, fm)
+			continue
+		}
+		if !t.heapified {
+			heap.Init(&t.h)
+			t.heapified = true
+		}
+		if m.better(t.h[0]) {
+			evicted = append(evicted, t.h[0].fm)
+			t.h[0] = m
+			heap.Fix(&t.h, 0)
+		} else {
+			evicted = append(evicted, fm)
+		}
+	}
+	return evicted
+}
+
+// results returns the best k matches added so far, sorted by URI in
+// descending order.
+func (t *fileMatchTopK) results() []*FileMatchResolver {
+	if len(t.h) == 0 {
+		return nil
+	}
+	results := make([]*FileMatchResolver, len(t.h))
+	for i, m := range t.h {
+		results[i] = m.fm
+	}
+	sortFileMatchesByURIDesc(results)
+	return results
+}
+
+// parallelSortMinLen is the length from which sortFileMatchesByURIDesc sorts
+// in parallel.
+const parallelSortMinLen = 1 << 14
+
+// sortFileMatchesByURIDesc sorts fms by URI in descending order. Large slices
+// are split into chunks that are sorted concurrently and then merged pairwise,
+// also concurrently.
+func sortFileMatchesByURIDesc(fms []*FileMatchResolver) {
+	chunks := runtime.GOMAXPROCS(0)
+	if len(fms) < parallelSortMinLen || chunks < 2 {
+		sort.Sort(fileMatchesByURIDesc(fms))
+		return
+	}
+
+	// bounds[i] is the start of chunk i, and bounds[chunks] is len(fms).
+	bounds := make([]int, chunks+1)
+	for i := range bounds {
+		bounds[i] = i * len(fms) / chunks
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < chunks; i++ {
+		chunk := fms[bounds[i]:bounds[i+1]]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sort.Sort(fileMatchesByURIDesc(chunk))
+		}()
+	}
+	wg.Wait()
+
+	// Merge adjacent runs until one is left, alternating between fms and buf.
+	src, dst := fms, make([]*FileMatchResolver, len(fms))
+	for len(bounds) > 2 {
+		next := []int{0}
+		for i := 0; i+1 < len(bounds); i += 2 {
+			lo, mid, hi := bounds[i], bounds[i+1], bounds[i+1]
+			if i+2 < len(bounds) {
+				hi = bounds[i+2]
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mergeFileMatchesByURIDesc(dst[lo:hi], src[lo:mid], src[mid:hi])
+			}()
+			next = append(next, hi)
+		}
+		wg.Wait()
+		src, dst, bounds = dst, src, next
+	}
+	if &src[0] != &fms[0] {
+		copy(fms, src)
+	}
+}
+
+// fileMatchesByURIDesc sorts file matches by URI in descending order. Unlike
+// sort.Slice, sorting it does not allocate.
+type fileMatchesByURIDesc []*FileMatchResolver
+
+func (fms fileMatchesByURIDesc) Len() int           { return len(fms) }
+func (fms fileMatchesByURIDesc) Less(i, j int) bool { return fms[i].uri > fms[j].uri }
+func (fms fileMatchesByURIDesc) Swap(i, j int)      { fms[i], fms[j] = fms[j], fms[i] }
+
+// mergeFileMatchesByURIDesc merges a and b, which are sorted by URI in
+// descending order, into dst.
+func mergeFileMatchesByURIDesc(dst, a, b []*FileMatchResolver) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if b[j].uri > a[i].uri {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
+
+type rankedFileMatch struct {
+	fm   *FileMatchResolver
+	rank int // the position of fm among the matches in its repository
+}
+
+func (m rankedFileMatch) better(o rankedFileMatch) bool {
+	if m.rank != o.rank {
+		return m.rank < o.rank
+	}
+	return m.fm.uri > o.fm.uri
+}
+
+// rankedFileMatchHeap is a heap with the worst match at the root, which is
+// the one to evict when a better match is added.
+type rankedFileMatchHeap []rankedFileMatch
+
+func (h rankedFileMatchHeap) Len() int            { return len(h) }
+func (h rankedFileMatchHeap) Less(i, j int) bool  { return h[j].better(h[i]) }
+func (h rankedFileMatchHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *rankedFileMatchHeap) Push(x interface{}) { *h = append(*h, x.(rankedFileMatch)) }
+func (h *rankedFileMatchHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
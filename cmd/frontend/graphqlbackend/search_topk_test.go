@@ -0,0 +1,135 @@
+package graphqlbackend
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestFileMatchTopK(t *testing.T) {
+	repoMatches := func(repo string, files ...string) []*FileMatchResolver {
+		var matches []*FileMatchResolver
+		for _, f := range files {
+			matches = append(matches, &FileMatchResolver{uri: "git://" + repo + "#" + f, Repo: &types.Repo{Name: api.RepoName(repo)}})
+		}
+		return matches
+	}
+	uris := func(matches []*FileMatchResolver) []string {
+		var uris []string
+		for _, fm := range matches {
+			uris = append(uris, fm.uri)
+		}
+		return uris
+	}
+
+	topK := newFileMatchTopK(4, false)
+	if got := topK.results(); got != nil {
+		t.Errorf("got %v, want no results", uris(got))
+	}
+
+	// A repository with many matches does not crowd out the others.
+	topK.add(repoMatches("a", "1", "2", "3", "4", "5"))
+	if got, want := uris(topK.results()), []string{"git://a#5", "git://a#4", "git://a#3", "git://a#2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	evicted := topK.add(repoMatches("b", "1", "2"))
+	evicted = append(evicted, topK.add(repoMatches("c", "1"))...)
+	if got, want := uris(topK.results()), []string{"git://c#1", "git://b#2", "git://b#1", "git://a#5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The matches that are not among the best anymore are returned, so that
+	// their memory can be released.
+	got := uris(evicted)
+	sort.Strings(got)
+	if want := []string{"git://a#2", "git://a#3", "git://a#4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got evicted %v, want %v", got, want)
+	}
+
+	topK = newFileMatchTopK(0, false)
+	topK.add(repoMatches("a", "1"))
+	if got := topK.results(); len(got) != 0 {
+		t.Error("got results with k = 0, want none")
+	}
+
+	// Deterministic: the results do not depend on the order in which the
+	// matches are added.
+	var orders [][]string
+	for _, order := range [][]string{{"a", "b"}, {"b", "a"}} {
+		topK := newFileMatchTopK(3, true)
+		for _, repo := range order {
+			topK.add(repoMatches(repo, "1"))
+			topK.add(repoMatches(repo, "2"))
+		}
+		orders = append(orders, uris(topK.results()))
+	}
+	if want := []string{"git://b#2", "git://b#1", "git://a#2"}; !reflect.DeepEqual(orders[0], want) || !reflect.DeepEqual(orders[1], want) {
+		t.Errorf("got %v, want %v for both orders", orders, want)
+	}
+}
+
+func TestSortFileMatchesByURIDesc(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, procs := range []int{1, 3, 4} {
+		runtime.GOMAXPROCS(procs)
+		for _, n := range []int{0, 5, parallelSortMinLen + 7} {
+			fms := make([]*FileMatchResolver, n)
+			for i := range fms {
+				fms[i] = &FileMatchResolver{uri: fmt.Sprintf("git://repo%d#%d", rand.Intn(100), rand.Intn(1000))}
+			}
+			want := append([]*FileMatchResolver(nil), fms...)
+			sort.SliceStable(want, func(i, j int) bool { return want[i].uri > want[j].uri })
+
+			sortFileMatchesByURIDesc(fms)
+			for i := range fms {
+				if fms[i].uri != want[i].uri {
+					t.Fatalf("GOMAXPROCS=%d, %d matches: got URI %q at %d, want %q", procs, n, fms[i].uri, i, want[i].uri)
+				}
+			}
+		}
+	}
+}
+
+// benchmarkMatches returns n file matches in batches of 10 per repository, as
+// searcher returns them.
+func benchmarkMatches(n int) [][]*FileMatchResolver {
+	var batches [][]*FileMatchResolver
+	for i := 0; i < n; i += 10 {
+		repo := &types.Repo{Name: api.RepoName(fmt.Sprintf("github.com/org%d/repo%d", i%97, i))}
+		batch := make([]*FileMatchResolver, 0, 10)
+		for j := i; j < i+10 && j < n; j++ {
+			path := fmt.Sprintf("dir%d/file%d.go", j%13, j)
+			batch = append(batch, &FileMatchResolver{JPath: path, MatchCount: 1, uri: "git://" + string(repo.Name) + "#" + path, Repo: repo})
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// BenchmarkAccumulate benchmarks accumulating and ranking the matches of the
+// repositories of a search, when all of them are returned (as with a large
+// count:) and when only a page of them is.
+func BenchmarkAccumulate(b *testing.B) {
+	for _, n := range []int{10000, 100000, 1000000} {
+		for _, k := range []int{defaultMaxSearchResults, n} {
+			batches := benchmarkMatches(n)
+			b.Run(fmt.Sprintf("matches=%d/k=%d", n, k), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					aggregations := newSearchAggregations()
+					topK := newFileMatchTopK(k, false)
+					for _, batch := range batches {
+						aggregations.addFileMatches(batch)
+						topK.add(batch)
+					}
+					sortResults(fileMatchesToSearchResults(topK.results()))
+				}
+			})
+		}
+	}
+}
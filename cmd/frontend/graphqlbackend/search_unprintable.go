@@ -0,0 +1,167 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+// skippedFileReason is why a file match was removed from the search results.
+type skippedFileReason string
+
+const (
+	// skippedFileBinary files contain NUL bytes. Searcher only skips files
+	// whose first bytes contain a NUL byte, so matches in binary files can
+	// still be found.
+	skippedFileBinary skippedFileReason = "BINARY"
+	// skippedFileInvalidUTF8 files contain bytes that are not valid UTF-8.
+	// Searcher encodes them as replacement characters (U+FFFD) in previews.
+	skippedFileInvalidUTF8 skippedFileReason = "INVALID_UTF8"
+)
+
+// unprintableReason returns why preview can't be shown as text, or "" if it
+// can.
+func unprintableReason(preview string) skippedFileReason {
+	if strings.IndexByte(preview, 0) >= 0 {
+		return skippedFileBinary
+	}
+	if !utf8.ValidString(preview) || strings.ContainsRune(preview, utf8.RuneError) {
+		return skippedFileInvalidUTF8
+	}
+	return ""
+}
+
+// handleUnprintableMatches removes the file matches with line previews that
+// can't be shown as text, such as matches in binary files, which would show up
+// as garbage in clients and break JSON consumers. It returns the number of
+// removed file matches by reason.
+//
+// If hexPreviews is true (see the hexpreview: filter), the previews of such
+// lines are replaced by the hexadecimal bytes of the lines instead, e.g.
+// "48 65 6c 6c 6f 00".
+func handleUnprintableMatches(ctx context.Context, matches []*FileMatchResolver, hexPreviews bool) (_ []*FileMatchResolver, skipped map[skippedFileReason]int32) {
+	kept := matches[:0]
+	for _, fm := range matches {
+		var reason skippedFileReason
+		for _, lm := range fm.JLineMatches {
+			if reason = unprintableReason(lm.JPreview); reason != "" {
+				break
+			}
+		}
+		if reason != "" && hexPreviews && fm.Repo != nil {
+			err := setHexPreviews(ctx, fm)
+			if err == nil {
+				reason = ""
+			} else if ctx.Err() == nil {
+				log15.Warn("Failed to read file for hex previews of search results", "repo", fm.Repo.Name, "commit", fm.CommitID, "path", fm.JPath, "error", err)
+			}
+		}
+		if reason != "" {
+			if skipped == nil {
+				skipped = map[skippedFileReason]int32{}
+			}
+			skipped[reason]++
+			continue
+		}
+		kept = append(kept, fm)
+	}
+	return kept, skipped
+}
+
+// setHexPreviews sets the previews of the line matches of fm that can't be
+// shown as text to the hexadecimal bytes of the lines. The lines are read from
+// the file again, since the previews don't contain the original bytes.
+func setHexPreviews(ctx context.Context, fm *FileMatchResolver) error {
+	content, err := git.ReadFile(ctx, gitserver.Repo{Name: fm.Repo.Name}, fm.CommitID, fm.JPath, 0)
+	if err != nil {
+		return err
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	for _, lm := range fm.JLineMatches {
+		if unprintableReason(lm.JPreview) == "" || int(lm.JLineNumber) >= len(lines) {
+			continue
+		}
+		line := lines[lm.JLineNumber]
+
+		// Offsets are in characters of the full line, where each invalid
+		// byte is one character.
+		offsets := lm.JOffsetAndLengths
+		if lm.previewTruncated {
+			offsets = lm.lineOffsetAndLengths
+		}
+		hexOffsets := make([][2]int32, 0, len(offsets))
+		for _, ol := range offsets {
+			start := byteOffset(line, int(ol[0]))
+			end := byteOffset(line, int(ol[0]+ol[1]))
+			if end > start {
+				hexOffsets = append(hexOffsets, [2]int32{int32(3 * start), int32(3*(end-start) - 1)})
+			}
+		}
+
+		lm.JPreview = hexBytes(line)
+		lm.JOffsetAndLengths = hexOffsets
+		lm.previewHex = true
+		lm.previewTruncated, lm.previewOffset, lm.lineOffsetAndLengths = false, 0, nil
+		lm.file = fm
+		if maxPreviewLength > 0 {
+			lm.truncatePreview(fm, maxPreviewLength)
+		}
+	}
+	return nil
+}
+
+// byteOffset returns the offset in bytes of the character with the given
+// offset in line.
+func byteOffset(line []byte, chars int) int {
+	var offset int
+	for i := 0; i < chars && offset < len(line); i++ {
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// hexBytes returns the bytes of b in hexadecimal, separated by spaces.
+func hexBytes(b []byte) string {
+	return fmt.Sprintf("% x", b)
+}
+
+// searchSkippedFilesResolver resolves the number of file matches that were
+// removed from the search results for a reason.
+type searchSkippedFilesResolver struct {
+	reason skippedFileReason
+	count  int32
+}
+
+func (r *searchSkippedFilesResolver) Reason() string { return string(r.reason) }
+
+func (r *searchSkippedFilesResolver) Count() int32 { return r.count }
+
+func (c *searchResultsCommon) SkippedFiles() []*searchSkippedFilesResolver {
+	resolvers := make([]*searchSkippedFilesResolver, 0, len(c.skippedFiles))
+	for reason, count := range c.skippedFiles {
+		resolvers = append(resolvers, &searchSkippedFilesResolver{reason: reason, count: count})
+	}
+	sort.Slice(resolvers, func(i, j int) bool { return resolvers[i].reason < resolvers[j].reason })
+	return resolvers
+}
+
+// addSkippedFiles adds the numbers of removed file matches by reason to c.
+func (c *searchResultsCommon) addSkippedFiles(skipped map[skippedFileReason]int32) {
+	if len(skipped) == 0 {
+		return
+	}
+	if c.skippedFiles == nil {
+		c.skippedFiles = make(map[skippedFileReason]int32, len(skipped))
+	}
+	for reason, count := range skipped {
+		c.skippedFiles[reason] += count
+	}
+}
@@ -0,0 +1,71 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestHandleUnprintableMatches(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return []byte("first\nab\xffcd x\n"), nil
+	}
+	defer git.ResetMocks()
+
+	repo := &types.Repo{Name: "github.com/a/b"}
+	newMatches := func() []*FileMatchResolver {
+		return []*FileMatchResolver{
+			{JPath: "a.go", Repo: repo, CommitID: "c1", JLineMatches: []*lineMatch{{JPreview: "text", JOffsetAndLengths: [][2]int32{{0, 4}}}}},
+			// Searcher previews contain replacement characters for invalid bytes.
+			{JPath: "latin1.txt", Repo: repo, CommitID: "c1", JLineMatches: []*lineMatch{{JPreview: "ab�cd x", JLineNumber: 1, JOffsetAndLengths: [][2]int32{{2, 3}}}}},
+			{JPath: "a.bin", Repo: repo, CommitID: "c1", JLineMatches: []*lineMatch{{JPreview: "x\x00y"}}},
+			{JPath: "path-match.bin", Repo: repo, CommitID: "c1"},
+		}
+	}
+
+	matches, skipped := handleUnprintableMatches(context.Background(), newMatches(), false)
+	var paths []string
+	for _, fm := range matches {
+		paths = append(paths, fm.JPath)
+	}
+	if want := []string{"a.go", "path-match.bin"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %v, want %v", paths, want)
+	}
+	if want := map[skippedFileReason]int32{skippedFileBinary: 1, skippedFileInvalidUTF8: 1}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("got skipped %v, want %v", skipped, want)
+	}
+
+	matches, skipped = handleUnprintableMatches(context.Background(), newMatches(), true)
+	if len(matches) != 4 || skipped != nil {
+		t.Fatalf("got %d matches and skipped %v, want all matches with hex previews", len(matches), skipped)
+	}
+	lm := matches[1].JLineMatches[0]
+	if want := "61 62 ff 63 64 20 78"; lm.JPreview != want || !lm.PreviewIsHex() {
+		t.Errorf("got preview %q (hex %v), want %q", lm.JPreview, lm.PreviewIsHex(), want)
+	}
+	// The match "\xffcd" is bytes 2 to 5 of the line.
+	if want := [][2]int32{{6, 8}}; !reflect.DeepEqual(lm.JOffsetAndLengths, want) {
+		t.Errorf("got offsets %v, want %v", lm.JOffsetAndLengths, want)
+	}
+	if matches[0].JLineMatches[0].PreviewIsHex() {
+		t.Error("got hex preview for text line")
+	}
+}
+
+func TestSearchResultsCommon_SkippedFiles(t *testing.T) {
+	var c searchResultsCommon
+	c.update(searchResultsCommon{skippedFiles: map[skippedFileReason]int32{skippedFileInvalidUTF8: 2}})
+	c.update(searchResultsCommon{skippedFiles: map[skippedFileReason]int32{skippedFileBinary: 1, skippedFileInvalidUTF8: 1}})
+	var got []string
+	for _, r := range c.SkippedFiles() {
+		got = append(got, fmt.Sprintf("%s:%d", r.Reason(), r.Count()))
+	}
+	if want := []string{"BINARY:1", "INVALID_UTF8:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
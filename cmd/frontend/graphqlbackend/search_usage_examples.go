@@ -0,0 +1,196 @@
+package graphqlbackend
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache/lru"
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"github.com/src-d/enry/v2"
+)
+
+const (
+	// usageExampleShortFileSize is the size up to which files score the full
+	// weight of short files. Larger files score a fraction of it that is
+	// inversely proportional to their size.
+	usageExampleShortFileSize = 4 << 10
+
+	// usageExampleRecentEditHalfLife is the age of the last commit that
+	// changed a file at which the file scores half the weight of recent
+	// edits.
+	usageExampleRecentEditHalfLife = 90 * 24 * time.Hour
+
+	// maxUsageExampleResults is the number of results that are ranked for
+	// usage examples. The results after them are not scored, since scoring
+	// requests the size and history of each file.
+	maxUsageExampleResults = 500
+)
+
+// defaultUsageExampleWeights are the weights of the qualities of usage
+// examples if the search.usageExamples site setting doesn't set them.
+var defaultUsageExampleWeights = schema.Weights{NonTest: 4, NonVendored: 4, ShortFile: 2, RecentEdit: 2}
+
+// testPath matches the paths of test files and of files in test directories
+// of common languages, such as a_test.go, test_a.py, a.spec.ts and
+// src/test/java/A.java.
+var testPath = regexp.MustCompile(`(?i)(^|/)(tests?|__tests__|spec|testdata|fixtures?)/|(^|/)test_[^/]*$|[_.-](test|spec)s?\.[^/]+$|(?-i:[A-Za-z0-9]Tests?)\.[^/.]+$`)
+
+// rankUsageExamples reports whether the file matches of the search are ranked
+// for usage examples (see sortUsageExamples): if the query has rank:examples,
+// or if its pattern looks like an API symbol (see query.LooksLikeSymbol) and
+// it doesn't have rank:default.
+func (r *searchResolver) rankUsageExamples() bool {
+	if rank, _ := r.query.StringValue(query.FieldRank); rank != "" {
+		rank, _ = query.ParseRank(rank)
+		return rank == query.RankExamples
+	}
+	if c := conf.Get().SearchUsageExamples; c != nil && c.AutoDetect != nil && !*c.AutoDetect {
+		return false
+	}
+	patterns := r.query.Values(query.FieldDefault)
+	return len(patterns) == 1 && query.LooksLikeSymbol(patterns[0].ToString())
+}
+
+// usageExampleInfo is the information about a file that usage examples are
+// ranked by.
+type usageExampleInfo struct {
+	size     int64     // -1 if unknown
+	lastEdit time.Time // the date of the last commit that changed the file, zero if unknown
+}
+
+// usageExampleCache caches the usageExampleInfo of the files of recently
+// searched commits.
+var (
+	usageExampleCacheMu sync.Mutex
+	usageExampleCache   = lru.New(10000)
+)
+
+type usageExampleKey struct {
+	repo   api.RepoName
+	commit api.CommitID
+	path   string
+}
+
+// fileUsageExampleInfo returns the usageExampleInfo of the file at path.
+func fileUsageExampleInfo(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) (usageExampleInfo, error) {
+	key := usageExampleKey{repo: repo, commit: commit, path: path}
+	usageExampleCacheMu.Lock()
+	v, ok := usageExampleCache.Get(key)
+	usageExampleCacheMu.Unlock()
+	if ok {
+		return v.(usageExampleInfo), nil
+	}
+
+	info := usageExampleInfo{size: -1}
+	fi, err := git.Stat(ctx, gitserver.Repo{Name: repo}, commit, path)
+	if err != nil {
+		return info, err
+	}
+	info.size = fi.Size()
+	commits, err := git.Commits(ctx, gitserver.Repo{Name: repo}, git.CommitsOptions{Range: string(commit), Path: path, N: 1})
+	if err != nil {
+		return info, err
+	}
+	if len(commits) > 0 {
+		info.lastEdit = commits[0].Author.Date
+		if commits[0].Committer != nil {
+			info.lastEdit = commits[0].Committer.Date
+		}
+	}
+
+	usageExampleCacheMu.Lock()
+	usageExampleCache.Add(key, info)
+	usageExampleCacheMu.Unlock()
+	return info, nil
+}
+
+// usageExampleScore returns the score of the file at path as a usage example:
+// the sum of the weights of the qualities it has. Short files and recent
+// edits score a fraction of their weight.
+func usageExampleScore(path string, info usageExampleInfo, weights schema.Weights, now time.Time) float64 {
+	var score float64
+	if !testPath.MatchString(path) {
+		score += weights.NonTest
+	}
+	if !enry.IsVendor(path) {
+		score += weights.NonVendored
+	}
+	if info.size >= 0 {
+		score += weights.ShortFile * math.Min(1, float64(usageExampleShortFileSize)/math.Max(1, float64(info.size)))
+	}
+	if !info.lastEdit.IsZero() {
+		age := math.Max(0, float64(now.Sub(info.lastEdit)))
+		score += weights.RecentEdit * math.Pow(0.5, age/float64(usageExampleRecentEditHalfLife))
+	}
+	return score
+}
+
+// sortUsageExamples sorts the file matches of results by their scores as
+// usage examples (see usageExampleScore), the best first, tuned by the
+// search.usageExamples site setting. File matches with equal scores keep
+// their order, and other results follow the file matches in their order.
+// Only the first maxUsageExampleResults results are ranked.
+//
+// Files whose size or history can't be read only score the qualities of
+// their paths.
+func sortUsageExamples(ctx context.Context, results []SearchResultResolver) {
+	weights := defaultUsageExampleWeights
+	if c := conf.Get().SearchUsageExamples; c != nil && c.Weights != nil {
+		weights = *c.Weights
+	}
+
+	var (
+		run    = parallel.NewRun(revisionResolutionParallelism)
+		now    = time.Now()
+		scores = make([]float64, len(results))
+	)
+	for i, result := range results {
+		fm, ok := result.ToFileMatch()
+		if !ok || i >= maxUsageExampleResults {
+			scores[i] = math.Inf(-1)
+			continue
+		}
+		if fm.Repo == nil || fm.CommitID == "" {
+			scores[i] = usageExampleScore(fm.JPath, usageExampleInfo{size: -1}, weights, now)
+			continue
+		}
+		i, fm := i, fm
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			info, err := fileUsageExampleInfo(ctx, fm.Repo.Name, fm.CommitID, fm.JPath)
+			if err != nil && ctx.Err() == nil {
+				log15.Warn("Failed to read file for usage example ranking", "repo", fm.Repo.Name, "commit", fm.CommitID, "path", fm.JPath, "error", err)
+			}
+			scores[i] = usageExampleScore(fm.JPath, info, weights, now)
+		})
+	}
+	_ = run.Wait()
+
+	sort.Stable(usageExamplesByScore{results: results, scores: scores})
+}
+
+// usageExamplesByScore sorts results by their scores in descending order.
+type usageExamplesByScore struct {
+	results []SearchResultResolver
+	scores  []float64
+}
+
+func (s usageExamplesByScore) Len() int           { return len(s.results) }
+func (s usageExamplesByScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s usageExamplesByScore) Swap(i, j int) {
+	s.results[i], s.results[j] = s.results[j], s.results[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
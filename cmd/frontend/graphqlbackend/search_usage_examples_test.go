@@ -0,0 +1,126 @@
+package graphqlbackend
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/util"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestSearchResolver_rankUsageExamples(t *testing.T) {
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	tests := map[string]bool{
+		"http.NewRequest":                   true,
+		"NewRequest(":                       true,
+		"request":                           false,
+		"http.NewRequest body":              false,
+		"request rank:examples":             true,
+		"http.NewRequest rank:default":      false,
+		"repo:foo http.NewRequest lang:go":  true,
+		"http.NewRequest -file:_test\\.go$": true,
+	}
+	for q, want := range tests {
+		parsed, err := query.ParseAndCheck(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (&searchResolver{query: parsed}).rankUsageExamples(); got != want {
+			t.Errorf("%q: got %v, want %v", q, got, want)
+		}
+	}
+
+	autoDetect := false
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchUsageExamples: &schema.SearchUsageExamples{AutoDetect: &autoDetect}}})
+	parsed, err := query.ParseAndCheck("http.NewRequest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (&searchResolver{query: parsed}).rankUsageExamples() {
+		t.Error("got usage example ranking with autoDetect disabled")
+	}
+}
+
+func TestTestPath(t *testing.T) {
+	tests := map[string]bool{
+		"a_test.go":                true,
+		"pkg/test_a.py":            true,
+		"src/a.spec.ts":            true,
+		"src/test/java/A.java":     true,
+		"src/__tests__/a.js":       true,
+		"AServiceTest.java":        true,
+		"testdata/a.json":          true,
+		"a.go":                     false,
+		"attestation/attest.go":    false,
+		"src/main/java/Latest.txt": false,
+		"contest.py":               false,
+	}
+	for path, want := range tests {
+		if got := testPath.MatchString(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSortUsageExamples(t *testing.T) {
+	conf.Mock(&conf.Unified{})
+	defer conf.Mock(nil)
+
+	now := time.Now()
+	sizes := map[string]int64{"a.go": 1 << 10, "big.go": 1 << 20, "a_test.go": 1 << 10, "vendor/x/x.go": 1 << 10, "old.go": 1 << 10}
+	edits := map[string]time.Time{"a.go": now, "big.go": now, "a_test.go": now, "vendor/x/x.go": now, "old.go": now.AddDate(-1, 0, 0)}
+	git.Mocks.Stat = func(commit api.CommitID, path string) (os.FileInfo, error) {
+		return &util.FileInfo{Name_: path, Size_: sizes[path]}, nil
+	}
+	git.Mocks.Commits = func(repo gitserver.Repo, opt git.CommitsOptions) ([]*git.Commit, error) {
+		return []*git.Commit{{ID: "c", Author: git.Signature{Date: edits[opt.Path]}}}, nil
+	}
+	defer git.ResetMocks()
+
+	repo := &types.Repo{ID: 1, Name: "github.com/foo/sort-usage-examples"}
+	var results []SearchResultResolver
+	for _, path := range []string{"a_test.go", "big.go", "old.go", "vendor/x/x.go", "a.go"} {
+		results = append(results, &FileMatchResolver{Repo: repo, CommitID: "c", JPath: path})
+	}
+	results = append([]SearchResultResolver{&RepositoryResolver{repo: repo}}, results...)
+
+	sortUsageExamples(context.Background(), results)
+
+	var got []string
+	for _, r := range results {
+		if fm, ok := r.ToFileMatch(); ok {
+			got = append(got, fm.JPath)
+		} else {
+			got = append(got, "repo")
+		}
+	}
+	if want := []string{"a.go", "old.go", "big.go", "a_test.go", "vendor/x/x.go", "repo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The weights of the site setting replace the defaults.
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchUsageExamples: &schema.SearchUsageExamples{
+		Weights: &schema.Weights{NonVendored: 10, NonTest: 1},
+	}}})
+	sortUsageExamples(context.Background(), results)
+	got = got[:0]
+	for _, r := range results {
+		if fm, ok := r.ToFileMatch(); ok {
+			got = append(got, fm.JPath)
+		}
+	}
+	if want := []string{"a.go", "old.go", "big.go", "a_test.go", "vendor/x/x.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
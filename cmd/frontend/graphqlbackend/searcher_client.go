@@ -0,0 +1,129 @@
+package graphqlbackend
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// SearcherClient sends text search requests to searcher instances. textSearch
+// chooses the searcher instance for each request (and the one to retry on),
+// so a SearcherClient only implements the transport.
+type SearcherClient interface {
+	// Search sends r to the searcher instance at searcherURL. It returns
+	// whether the archive of the repository was cached by searcher in
+	// addition to the results. If the deadline of r was hit, it returns the
+	// partial results with context.DeadlineExceeded.
+	Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error)
+}
+
+// DefaultSearcherClient is the SearcherClient that searches use (see
+// newDefaultSearcherClient), unless another one is set for a search with
+// withSearcherClient. Tests replace it with a FakeSearcherClient.
+var DefaultSearcherClient = newDefaultSearcherClient()
+
+type searcherClientKey struct{}
+
+// withSearcherClient returns a context whose searches send their searcher
+// requests with client instead of DefaultSearcherClient.
+func withSearcherClient(ctx context.Context, client SearcherClient) context.Context {
+	return context.WithValue(ctx, searcherClientKey{}, client)
+}
+
+// searcherClientFromContext returns the SearcherClient that searches with ctx
+// use, and whether it was set with withSearcherClient.
+func searcherClientFromContext(ctx context.Context) (client SearcherClient, ok bool) {
+	if client, ok := ctx.Value(searcherClientKey{}).(SearcherClient); ok {
+		return client, true
+	}
+	return DefaultSearcherClient, false
+}
+
+// httpSearcherClient sends search requests to searcher over HTTP.
+type httpSearcherClient struct{}
+
+func (httpSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	return textSearchURL(ctx, searcherURL+"?"+searcherRequestQuery(r).Encode())
+}
+
+// searcherRequestQuery returns the URL query of the searcher HTTP API for r.
+func searcherRequestQuery(r *protocol.Request) url.Values {
+	q := url.Values{
+		"Repo":            []string{string(r.Repo)},
+		"URL":             []string{r.URL},
+		"Commit":          []string{string(r.Commit)},
+		"Pattern":         []string{r.Pattern},
+		"ExcludePattern":  []string{r.ExcludePattern},
+		"IncludePatterns": r.IncludePatterns,
+		"FetchTimeout":    []string{r.FetchTimeout},
+		"Languages":       r.Languages,
+		"CombyRule":       []string{r.CombyRule},
+		"WordCharacters":  r.WordCharacters,
+		"Within":          r.Within,
+	}
+	if r.Deadline != "" {
+		q.Set("Deadline", r.Deadline)
+	}
+	q.Set("FileMatchLimit", strconv.Itoa(r.FileMatchLimit))
+	if r.IsRegExp {
+		q.Set("IsRegExp", "true")
+	}
+	if r.IsStructuralPat {
+		q.Set("IsStructuralPat", "true")
+	}
+	if r.IsPCRE {
+		q.Set("IsPCRE", "true")
+	}
+	if r.IsWordMatch {
+		q.Set("IsWordMatch", "true")
+	}
+	if r.IsIdentifierMatch {
+		q.Set("IsIdentifierMatch", "true")
+	}
+	if r.IdentifierSubTokens {
+		q.Set("IdentifierSubTokens", "true")
+	}
+	if r.GoCall != "" {
+		q.Set("GoCall", r.GoCall)
+	}
+	if r.GoSelector != "" {
+		q.Set("GoSelector", r.GoSelector)
+	}
+	if r.ModifiedSince != "" {
+		q.Set("ModifiedSince", r.ModifiedSince)
+	}
+	if r.ModifiedSinceRevision != "" {
+		q.Set("ModifiedSinceRevision", r.ModifiedSinceRevision)
+	}
+	if r.IsCaseSensitive {
+		q.Set("IsCaseSensitive", "true")
+	}
+	if r.CaseFold {
+		q.Set("CaseFold", "true")
+	}
+	if r.Normalization != "" {
+		q.Set("Normalization", r.Normalization)
+	}
+	if r.PathPatternsAreRegExps {
+		q.Set("PathPatternsAreRegExps", "true")
+	}
+	if r.PathPatternsAreCaseSensitive {
+		q.Set("PathPatternsAreCaseSensitive", "true")
+	}
+	if r.CountOnly {
+		q.Set("CountOnly", "true")
+	}
+	if r.MaxLineMatches > 0 {
+		q.Set("MaxLineMatches", strconv.Itoa(r.MaxLineMatches))
+	}
+	if r.Deterministic {
+		q.Set("Deterministic", "true")
+	}
+	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
+	// these fields from old frontends that do not (and provide a default in the latter case).
+	q.Set("PatternMatchesContent", strconv.FormatBool(r.PatternMatchesContent))
+	q.Set("PatternMatchesPath", strconv.FormatBool(r.PatternMatchesPath))
+	return q
+}
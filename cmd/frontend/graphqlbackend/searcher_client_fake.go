@@ -0,0 +1,62 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// FakeSearcherClient is an in-memory SearcherClient that serves fixtures, so
+// that the search pipeline can be exercised without searcher instances.
+type FakeSearcherClient struct {
+	// Matches are the file matches of searches of each repository (at any
+	// commit, with any pattern). Repositories without matches have none.
+	Matches map[api.RepoName][]protocol.FileMatch
+
+	// Errors are the errors of searches of each repository, returned instead
+	// of its matches.
+	Errors map[api.RepoName]error
+
+	mu       sync.Mutex
+	requests []*protocol.Request
+}
+
+func (c *FakeSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, r)
+	c.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, false, false, err
+	}
+	if err := c.Errors[r.Repo]; err != nil {
+		return nil, false, false, err
+	}
+
+	fixtures := c.Matches[r.Repo]
+	if r.FileMatchLimit > 0 && len(fixtures) > r.FileMatchLimit {
+		fixtures, limitHit = fixtures[:r.FileMatchLimit], true
+	}
+	if r.CountOnly {
+		counts := make([]protocol.FileMatch, len(fixtures))
+		for i, m := range fixtures {
+			counts[i] = protocol.FileMatch{Path: m.Path, LimitHit: m.LimitHit, MatchCount: m.MatchCount}
+			if counts[i].MatchCount == 0 {
+				counts[i].MatchCount = len(m.LineMatches)
+			}
+		}
+		fixtures = counts
+	}
+	// Convert the fixtures for each request, since callers modify the
+	// FileMatchResolvers.
+	return fileMatchesFromProtocol(fixtures), limitHit, true, nil
+}
+
+// Requests returns the requests the client received, in order.
+func (c *FakeSearcherClient) Requests() []*protocol.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*protocol.Request(nil), c.requests...)
+}
@@ -0,0 +1,230 @@
+package graphqlbackend
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
+	"github.com/sourcegraph/sourcegraph/internal/search/contentclass"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+)
+
+var searcherLocalDir = env.Get("SEARCHER_LOCAL_DIR", "", "directory of local checkouts of repositories (at <dir>/<repository name>) to search in-process instead of sending requests to searcher, for development")
+
+const (
+	// localSearcherMaxFileSize is the size of the largest files that
+	// localSearcherClient searches the contents of.
+	localSearcherMaxFileSize = 1 << 20
+
+	// localSearcherMaxLineMatches is the default limit on the number of
+	// matches in a file, like the one of searcher.
+	localSearcherMaxLineMatches = 100
+)
+
+// NewLocalSearcherClient returns a SearcherClient that searches the local
+// checkouts of repositories in dir in-process, so that the search resolvers
+// can be developed without running searcher. The checkout of a repository is
+// at dir/<repository name>, and its working tree is searched, regardless of
+// the requested commit. Structural and Go AST search, the modified: and
+// normalize: filters and engine:pcre are not supported. Case-insensitive
+// searches always fold the case of all Unicode letters.
+func NewLocalSearcherClient(dir string) SearcherClient {
+	return &localSearcherClient{dir: dir}
+}
+
+type localSearcherClient struct {
+	dir string
+}
+
+func (c *localSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	if r.IsStructuralPat {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, errors.New("structural search is not supported by the local searcher (SEARCHER_LOCAL_DIR)"))
+	}
+	if r.GoCall != "" || r.GoSelector != "" {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, errors.New("Go AST search is not supported by the local searcher (SEARCHER_LOCAL_DIR)"))
+	}
+	if r.ModifiedSince != "" || r.ModifiedSinceRevision != "" {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, errors.New("the modified: filter is not supported by the local searcher (SEARCHER_LOCAL_DIR)"))
+	}
+	if r.IsPCRE {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, errors.New("engine:pcre is not supported by the local searcher (SEARCHER_LOCAL_DIR)"))
+	}
+	if r.Normalization != "" {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, errors.New("the normalize: filter is not supported by the local searcher (SEARCHER_LOCAL_DIR)"))
+	}
+	root := filepath.Join(c.dir, filepath.FromSlash(string(r.Repo)))
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		return nil, false, false, &vcs.RepoNotExistError{Repo: r.Repo}
+	}
+
+	re, err := compileLocalSearchPattern(&r.PatternInfo)
+	if err != nil {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, err)
+	}
+	matchPath, err := pathmatch.CompilePathPatterns(r.IncludePatterns, r.ExcludePattern, pathmatch.CompileOptions{
+		RegExp:        r.PathPatternsAreRegExps,
+		CaseSensitive: r.PathPatternsAreCaseSensitive,
+	})
+	if err != nil {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, err)
+	}
+	wordCharacters, err := identifier.ParseWordCharacters(r.WordCharacters)
+	if err != nil {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, err)
+	}
+	within, err := contentclass.ParseSet(r.Within)
+	if err != nil {
+		return nil, false, false, newSearchError(searchErrorPatternInvalid, err)
+	}
+	maxLineMatches := r.MaxLineMatches
+	if maxLineMatches <= 0 {
+		maxLineMatches = localSearcherMaxLineMatches
+	}
+
+	var fms []protocol.FileMatch
+	errLimitHit := errors.New("file match limit hit")
+	err = filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if fi.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if !matchPath.MatchPath(name) {
+			return nil
+		}
+
+		fm := protocol.FileMatch{Path: name}
+		matched := re == nil || (r.PatternMatchesPath && re.MatchString(name))
+		if re != nil && r.PatternMatchesContent && fi.Size() <= localSearcherMaxFileSize {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if !bytes.Contains(data, []byte{0}) { // skip binary files
+				var keep func(start, end int) bool
+				if r.IsIdentifierMatch || r.IsWordMatch || within != contentclass.All {
+					m := wordCharacters.MatcherForPath(name, r.IsIdentifierMatch && r.IdentifierSubTokens)
+					var ranges contentclass.Ranges
+					if within != contentclass.All {
+						ranges, _ = contentclass.LexPath(name, data)
+					}
+					keep = func(start, end int) bool {
+						if (r.IsIdentifierMatch || r.IsWordMatch) && !m.Match(data, start, end) {
+							return false
+						}
+						return within.Has(ranges.ClassAt(start))
+					}
+				}
+				fm.LineMatches, fm.LimitHit = localLineMatches(re, data, maxLineMatches, keep)
+				fm.MatchCount = len(fm.LineMatches)
+				matched = matched || len(fm.LineMatches) > 0
+			}
+		}
+		if !matched {
+			return nil
+		}
+
+		if r.FileMatchLimit > 0 && len(fms) == r.FileMatchLimit {
+			return errLimitHit
+		}
+		if r.CountOnly {
+			fm.LineMatches = nil
+		}
+		fms = append(fms, fm)
+		return nil
+	})
+	switch {
+	case err == errLimitHit:
+		limitHit, err = true, nil
+	case err == context.DeadlineExceeded:
+		// Return the partial results, like searcher.
+	case err != nil:
+		return nil, false, false, err
+	}
+	return fileMatchesFromProtocol(fms), limitHit, true, err
+}
+
+// compileLocalSearchPattern returns the regexp that matches the pattern of p,
+// or nil if p has no pattern.
+func compileLocalSearchPattern(p *protocol.PatternInfo) (*regexp.Regexp, error) {
+	if p.Pattern == "" {
+		return nil, nil
+	}
+	expr := p.Pattern
+	if !p.IsRegExp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	expr = "(?m:" + expr + ")"
+	if !p.IsCaseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// localLineMatches returns a line match for each match of re in data (that
+// keep reports to be a match, if it is set), up to limit. Matches that span
+// multiple lines only match the rest of their first line.
+func localLineMatches(re *regexp.Regexp, data []byte, limit int, keep func(start, end int) bool) (matches []protocol.LineMatch, limitHit bool) {
+	var locs [][]int
+	if keep == nil {
+		locs = re.FindAllIndex(data, limit+1)
+	} else {
+		for _, loc := range re.FindAllIndex(data, -1) {
+			if keep(loc[0], loc[1]) {
+				if locs = append(locs, loc); len(locs) > limit {
+					break
+				}
+			}
+		}
+	}
+	if len(locs) > limit {
+		locs, limitHit = locs[:limit], true
+	}
+	lineNumber, lineStart := 0, 0
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		lineNumber += bytes.Count(data[lineStart:start], []byte{'\n'})
+		if i := bytes.LastIndexByte(data[:start], '\n'); i >= 0 {
+			lineStart = i + 1
+		} else {
+			lineStart = 0
+		}
+		lineEnd := len(data)
+		if i := bytes.IndexByte(data[start:], '\n'); i >= 0 {
+			lineEnd = start + i
+		}
+		if end > lineEnd {
+			end = lineEnd
+		}
+		matches = append(matches, protocol.LineMatch{
+			Preview:          string(data[lineStart:lineEnd]),
+			LineNumber:       lineNumber,
+			OffsetAndLengths: [][2]int{{utf8.RuneCount(data[lineStart:start]), utf8.RuneCount(data[start:end])}},
+		})
+	}
+	return matches, limitHit
+}
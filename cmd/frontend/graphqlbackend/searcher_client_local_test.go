@@ -0,0 +1,92 @@
+package graphqlbackend
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/vcs"
+)
+
+func TestLocalSearcherClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "local-searcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"github.com/foo/bar/README.md":     "# Hello\n",
+		"github.com/foo/bar/cmd/main.go":   "package main\n\n// héllo, hello\nfunc hello() {}\n",
+		"github.com/foo/bar/hello.bin":     "hello\x00",
+		"github.com/foo/bar/.git/config":   "hello",
+		"github.com/foo/bar/docs/world.md": "nothing to see\n",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := NewLocalSearcherClient(dir)
+	search := func(p protocol.PatternInfo) (map[string][]protocol.LineMatch, bool) {
+		t.Helper()
+		p.PathPatternsAreRegExps = true
+		matches, limitHit, _, err := client.Search(context.Background(), "", &protocol.Request{Repo: "github.com/foo/bar", PatternInfo: p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][]protocol.LineMatch{}
+		for _, m := range matches {
+			var lms []protocol.LineMatch
+			for _, lm := range m.JLineMatches {
+				lms = append(lms, protocol.LineMatch{Preview: lm.JPreview, LineNumber: int(lm.JLineNumber), OffsetAndLengths: [][2]int{{int(lm.JOffsetAndLengths[0][0]), int(lm.JOffsetAndLengths[0][1])}}})
+			}
+			got[m.JPath] = lms
+		}
+		return got, limitHit
+	}
+
+	got, _ := search(protocol.PatternInfo{Pattern: "hello", PatternMatchesContent: true})
+	want := map[string][]protocol.LineMatch{
+		"README.md": {{Preview: "# Hello", LineNumber: 0, OffsetAndLengths: [][2]int{{2, 5}}}},
+		"cmd/main.go": {
+			{Preview: "// héllo, hello", LineNumber: 2, OffsetAndLengths: [][2]int{{10, 5}}},
+			{Preview: "func hello() {}", LineNumber: 3, OffsetAndLengths: [][2]int{{5, 5}}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	got, _ = search(protocol.PatternInfo{Pattern: "hello", IsCaseSensitive: true, IncludePatterns: []string{`\.go$`}, PatternMatchesContent: true, MaxLineMatches: 1})
+	if len(got) != 1 || len(got["cmd/main.go"]) != 1 {
+		t.Errorf("got %+v, want the first match in cmd/main.go", got)
+	}
+
+	got, _ = search(protocol.PatternInfo{Pattern: "hello", IncludePatterns: []string{`\.go$`}, PatternMatchesContent: true, Within: []string{"comment"}})
+	if lms := got["cmd/main.go"]; len(lms) != 1 || lms[0].LineNumber != 2 {
+		t.Errorf("got %+v, want the match in the comment of cmd/main.go", got)
+	}
+
+	got, _ = search(protocol.PatternInfo{Pattern: "world", PatternMatchesPath: true, PatternMatchesContent: true})
+	if _, ok := got["docs/world.md"]; !ok || len(got) != 1 {
+		t.Errorf("got %+v, want the path match docs/world.md", got)
+	}
+
+	if got, limitHit := search(protocol.PatternInfo{Pattern: "hello", PatternMatchesContent: true, FileMatchLimit: 1}); len(got) != 1 || !limitHit {
+		t.Errorf("got %d file matches (limitHit %v), want 1 with the limit hit", len(got), limitHit)
+	}
+
+	if _, _, _, err := client.Search(context.Background(), "", &protocol.Request{Repo: "github.com/foo/missing"}); !vcs.IsRepoNotExist(err) {
+		t.Errorf("got error %v for a repository without a checkout, want a RepoNotExistError", err)
+	}
+}
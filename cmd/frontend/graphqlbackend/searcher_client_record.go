@@ -0,0 +1,186 @@
+package graphqlbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var (
+	searcherRecordDir = env.Get("SEARCHER_RECORD_DIR", "", "directory to record searcher requests and responses in, to replay them with SEARCHER_REPLAY_DIR (the recorded responses contain the contents of matching files)")
+	searcherReplayDir = env.Get("SEARCHER_REPLAY_DIR", "", "directory of searcher requests and responses recorded with SEARCHER_RECORD_DIR, to respond to searcher requests with instead of sending them to searcher")
+)
+
+// newDefaultSearcherClient returns the SearcherClient that searches use,
+// which records or replays searcher interactions if SEARCHER_RECORD_DIR or
+// SEARCHER_REPLAY_DIR is set, and searches local checkouts in-process if
+// SEARCHER_LOCAL_DIR is set.
+func newDefaultSearcherClient() SearcherClient {
+	switch {
+	case searcherReplayDir != "":
+		log15.Warn("Replaying recorded searcher responses instead of sending requests to searcher.", "dir", searcherReplayDir)
+		return NewReplayingSearcherClient(searcherReplayDir)
+	case searcherLocalDir != "":
+		log15.Warn("Searching local checkouts of repositories instead of sending requests to searcher.", "dir", searcherLocalDir)
+		return NewLocalSearcherClient(searcherLocalDir)
+	case searcherRecordDir != "":
+		log15.Warn("Recording searcher requests and responses.", "dir", searcherRecordDir)
+		return NewRecordingSearcherClient(httpSearcherClient{}, searcherRecordDir)
+	}
+	return httpSearcherClient{}
+}
+
+// searcherRecording is a searcher request and its response, as recorded in a
+// file.
+type searcherRecording struct {
+	Request  *protocol.Request
+	Response searcherResponse
+	Error    *searcherRecordingError `json:",omitempty"`
+}
+
+type searcherRecordingError struct {
+	Message    string
+	StatusCode int             `json:",omitempty"` // if the error is a *searcherError
+	Draining   bool            `json:",omitempty"`
+	Code       searchErrorCode `json:",omitempty"` // if the error is another *searchError
+}
+
+// recordingPath returns the path of the recording of r in dir. Requests that
+// only differ in their deadline share a recording, so that they can be
+// replayed.
+func recordingPath(dir string, r *protocol.Request) (string, error) {
+	key := *r
+	key.Deadline = ""
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// NewRecordingSearcherClient returns a SearcherClient that sends requests with
+// client and records them and their responses in files in dir, to replay them
+// with a client returned by NewReplayingSearcherClient. The recording of a
+// request replaces earlier ones of the same request.
+func NewRecordingSearcherClient(client SearcherClient, dir string) SearcherClient {
+	return &recordingSearcherClient{client: client, dir: dir}
+}
+
+type recordingSearcherClient struct {
+	client SearcherClient
+	dir    string
+}
+
+func (c *recordingSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	matches, limitHit, cached, err = c.client.Search(ctx, searcherURL, r)
+	// The results of canceled requests depend on when they were canceled.
+	if ctx.Err() != nil && err != context.DeadlineExceeded {
+		return matches, limitHit, cached, err
+	}
+
+	rec := searcherRecording{
+		Request: r,
+		Response: searcherResponse{
+			Matches:     matches,
+			LimitHit:    limitHit,
+			DeadlineHit: err == context.DeadlineExceeded,
+			Cached:      cached,
+		},
+	}
+	if err != nil && err != context.DeadlineExceeded {
+		rec.Error = &searcherRecordingError{Message: err.Error()}
+		if e, ok := errors.Cause(err).(*searcherError); ok {
+			rec.Error.StatusCode, rec.Error.Draining = e.StatusCode, e.Draining
+		} else if e := findSearchError(err); e != nil {
+			rec.Error.Code = e.code
+		}
+	}
+	if err := c.write(&rec); err != nil {
+		log15.Error("Failed to record searcher request.", "repo", r.Repo, "commit", r.Commit, "error", err)
+	}
+	return matches, limitHit, cached, err
+}
+
+func (c *recordingSearcherClient) write(rec *searcherRecording) error {
+	path, err := recordingPath(c.dir, rec.Request)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that concurrent recordings of the
+	// same request do not corrupt each other.
+	f, err := ioutil.TempFile(c.dir, ".recording-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// NewReplayingSearcherClient returns a SearcherClient that responds to
+// requests with the responses recorded in dir by a client returned by
+// NewRecordingSearcherClient. Requests without a recording fail.
+func NewReplayingSearcherClient(dir string) SearcherClient {
+	return &replayingSearcherClient{dir: dir}
+}
+
+type replayingSearcherClient struct {
+	dir string
+}
+
+func (c *replayingSearcherClient) Search(ctx context.Context, searcherURL string, r *protocol.Request) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
+	path, err := recordingPath(c.dir, r)
+	if err != nil {
+		return nil, false, false, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, false, errors.Errorf("no recorded searcher response for %s@%s %s", r.Repo, r.Commit, r.PatternInfo.String())
+	} else if err != nil {
+		return nil, false, false, err
+	}
+	var rec searcherRecording
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, false, false, errors.Wrapf(err, "invalid searcher recording %s", path)
+	}
+
+	if e := rec.Error; e != nil {
+		switch {
+		case e.StatusCode != 0:
+			err = newSearcherError(&searcherError{StatusCode: e.StatusCode, Message: e.Message, Draining: e.Draining})
+		case e.Code != "":
+			err = newSearchError(e.Code, errors.New(e.Message))
+		default:
+			err = errors.New(e.Message)
+		}
+		return nil, false, false, err
+	}
+	if rec.Response.DeadlineHit {
+		err = context.DeadlineExceeded
+	}
+	return rec.Response.Matches, rec.Response.LimitHit, rec.Response.Cached, err
+}
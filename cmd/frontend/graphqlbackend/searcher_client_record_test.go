@@ -0,0 +1,64 @@
+package graphqlbackend
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestRecordReplaySearcherClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "searcher-recordings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fake := &FakeSearcherClient{
+		Matches: map[api.RepoName][]protocol.FileMatch{
+			"foo": {{Path: "a.go", LineMatches: []protocol.LineMatch{{Preview: "p", LineNumber: 1, OffsetAndLengths: [][2]int{{0, 1}}}}}},
+		},
+		Errors: map[api.RepoName]error{
+			"draining": newSearcherError(&searcherError{StatusCode: http.StatusServiceUnavailable, Message: "draining", Draining: true}),
+		},
+	}
+	recorder := NewRecordingSearcherClient(fake, dir)
+	ctx := context.Background()
+	foo := &protocol.Request{Repo: "foo", Commit: "deadbeef", PatternInfo: protocol.PatternInfo{Pattern: "p"}, Deadline: "2020-01-01T00:00:00Z"}
+	wantMatches, wantLimitHit, _, err := recorder.Search(ctx, "http://searcher", foo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	draining := &protocol.Request{Repo: "draining", Commit: "deadbeef"}
+	if _, _, _, err := recorder.Search(ctx, "http://searcher", draining); err == nil {
+		t.Fatal("got nil error, want the error of the fake")
+	}
+
+	replayer := NewReplayingSearcherClient(dir)
+
+	// Requests that only differ in their deadline are replayed.
+	replayed := *foo
+	replayed.Deadline = "2020-06-01T00:00:00Z"
+	matches, limitHit, cached, err := replayer.Search(ctx, "http://other-searcher", &replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(matches, wantMatches) || limitHit != wantLimitHit || !cached {
+		t.Errorf("got replayed matches %+v (limitHit %v, cached %v), want %+v (limitHit %v, cached true)", matches, limitHit, cached, wantMatches, wantLimitHit)
+	}
+
+	if _, _, _, err := replayer.Search(ctx, "http://searcher", draining); !isSearcherDraining(err) {
+		t.Errorf("got error %v, want the recorded draining searcher error", err)
+	} else if code, _ := searchErrorCodeOf(err); code != searchErrorSearcherUnavailable {
+		t.Errorf("got error code %q, want %q", code, searchErrorSearcherUnavailable)
+	}
+
+	if _, _, _, err := replayer.Search(ctx, "http://searcher", &protocol.Request{Repo: "bar"}); err == nil {
+		t.Error("got nil error for a request without a recording")
+	}
+}
@@ -0,0 +1,104 @@
+package graphqlbackend
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestSearcherRequestQuery(t *testing.T) {
+	r := &protocol.Request{
+		Repo:   "github.com/foo/bar",
+		URL:    "https://github.com/foo/bar",
+		Commit: "deadbeef",
+		PatternInfo: protocol.PatternInfo{
+			Pattern:               "p",
+			IsRegExp:              true,
+			IncludePatterns:       []string{"a", "b"},
+			FileMatchLimit:        30,
+			PatternMatchesContent: true,
+			MaxLineMatches:        5,
+			IsIdentifierMatch:     true,
+			IdentifierSubTokens:   true,
+			WordCharacters:        []string{"*:$"},
+			Within:                []string{"comment"},
+			GoCall:                "mux.NewRouter",
+			ModifiedSinceRevision: "v1.0",
+			IsPCRE:                true,
+			CaseFold:              true,
+			Normalization:         "nfc",
+		},
+		FetchTimeout: "500ms",
+	}
+	got := searcherRequestQuery(r).Encode()
+	want := "CaseFold=true&CombyRule=&Commit=deadbeef&ExcludePattern=&FetchTimeout=500ms&FileMatchLimit=30&GoCall=mux.NewRouter&IdentifierSubTokens=true&IncludePatterns=a&IncludePatterns=b&IsIdentifierMatch=true&IsPCRE=true&IsRegExp=true&MaxLineMatches=5&ModifiedSinceRevision=v1.0&Normalization=nfc&Pattern=p&PatternMatchesContent=true&PatternMatchesPath=false&Repo=github.com%2Ffoo%2Fbar&URL=https%3A%2F%2Fgithub.com%2Ffoo%2Fbar&Within=comment&WordCharacters=%2A%3A%24"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestTextSearch_fakeSearcherClient(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+
+	fake := &FakeSearcherClient{
+		Matches: map[api.RepoName][]protocol.FileMatch{
+			"foo": {
+				{Path: "a.go", LineMatches: []protocol.LineMatch{{Preview: "p", LineNumber: 1, OffsetAndLengths: [][2]int{{0, 1}}}}},
+				{Path: "b.go"},
+			},
+		},
+		Errors: map[api.RepoName]error{
+			"unavailable": &searcherError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable"},
+		},
+	}
+	defer func(c SearcherClient) { DefaultSearcherClient = c }(DefaultSearcherClient)
+	DefaultSearcherClient = fake
+
+	searcherURLs := endpoint.Static("http://searcher-0", "http://searcher-1")
+	matches, limitHit, err := textSearch(context.Background(), searcherURLs, gitserver.Repo{Name: "foo"}, "deadbeef", &search.TextPatternInfo{Pattern: "p", FileMatchLimit: 1}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].JPath != "a.go" || len(matches[0].JLineMatches) != 1 || !limitHit {
+		t.Errorf("got %d matches (limitHit %v), want a.go with the file match limit hit", len(matches), limitHit)
+	}
+	if got, want := fake.Requests()[0], (&protocol.Request{
+		Repo:         "foo",
+		Commit:       "deadbeef",
+		PatternInfo:  protocol.PatternInfo{Pattern: "p", FileMatchLimit: 1},
+		FetchTimeout: "1s",
+	}); !cmp.Equal(got, want) {
+		t.Errorf("request mismatch (-got +want):\n%s", cmp.Diff(got, want))
+	}
+
+	// firstmatch: searches only ask for the first match of the repository,
+	// which is all there is to find.
+	matches, limitHit, err = textSearch(context.Background(), searcherURLs, gitserver.Repo{Name: "foo"}, "deadbeef", &search.TextPatternInfo{Pattern: "p", FileMatchLimit: 30, FirstMatchPerRepo: true}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || limitHit {
+		t.Errorf("got %d matches (limitHit %v), want 1 match without the limit hit", len(matches), limitHit)
+	}
+	if got := fake.Requests()[1].PatternInfo; got.FileMatchLimit != 1 || got.MaxLineMatches != 1 {
+		t.Errorf("got file match limit %d and max line matches %d, want 1", got.FileMatchLimit, got.MaxLineMatches)
+	}
+
+	// Temporary errors are retried on another searcher.
+	if _, _, err := textSearch(context.Background(), searcherURLs, gitserver.Repo{Name: "unavailable"}, "deadbeef", &search.TextPatternInfo{Pattern: "p"}, time.Second); err == nil {
+		t.Error("got nil error, want the error of the searchers")
+	}
+	if got := len(fake.Requests()); got != 4 {
+		t.Errorf("got %d requests, want the failed one to be retried once", got)
+	}
+}
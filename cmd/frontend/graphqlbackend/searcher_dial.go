@@ -0,0 +1,71 @@
+package graphqlbackend
+
+import (
+	"log"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var searcherAllowedNetworks = env.Get("SEARCHER_ALLOWED_NETWORKS", "", "comma separated list of CIDRs (e.g. 10.0.0.0/8,fd00::/8) the frontend may connect to for searcher requests; empty allows all networks")
+
+// searcherDialer returns the dialer used for connections to searcher. If
+// SEARCHER_ALLOWED_NETWORKS is set, connections to addresses outside of the
+// allowed networks are refused. The check happens after name resolution, so it
+// applies to the addresses SEARCHER_URL actually resolves to.
+func searcherDialer() *net.Dialer {
+	nets, err := parseAllowedNetworks(searcherAllowedNetworks)
+	if err != nil {
+		log.Fatalf("invalid SEARCHER_ALLOWED_NETWORKS: %s", err)
+	}
+	d := &net.Dialer{
+		// Same as http.DefaultTransport.
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if len(nets) > 0 {
+		d.Control = allowedNetworksControl(nets)
+	}
+	return d
+}
+
+func parseAllowedNetworks(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// allowedNetworksControl returns a net.Dialer Control function which refuses to
+// connect to addresses outside of nets.
+func allowedNetworksControl(nets []*net.IPNet) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return errors.Errorf("searcher address %q is not an IP address", host)
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return nil
+			}
+		}
+		return errors.Errorf("searcher address %s is not in SEARCHER_ALLOWED_NETWORKS", ip)
+	}
+}
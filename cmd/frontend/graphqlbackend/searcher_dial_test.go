@@ -0,0 +1,33 @@
+package graphqlbackend
+
+import "testing"
+
+func TestAllowedNetworksControl(t *testing.T) {
+	nets, err := parseAllowedNetworks("10.0.0.0/8, fd00::/8,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	control := allowedNetworksControl(nets)
+
+	for address, allowed := range map[string]bool{
+		"10.1.2.3:3181":        true,
+		"[fd00::1]:3181":       true,
+		"127.0.0.1:3181":       false,
+		"169.254.169.254:80":   false,
+		"[::1]:3181":           false,
+		"searcher:3181":        false,
+		"missing-port.invalid": false,
+	} {
+		err := control("tcp", address, nil)
+		if allowed && err != nil {
+			t.Errorf("%s: unexpected error: %s", address, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("%s: expected error", address)
+		}
+	}
+
+	if _, err := parseAllowedNetworks("10.0.0.0"); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
@@ -0,0 +1,58 @@
+package graphqlbackend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+)
+
+// searcherDrainTTL is how long a searcher that announced it is draining is
+// not sent new requests. By then it has usually shut down and been removed
+// from the searcher endpoints.
+const searcherDrainTTL = time.Minute
+
+// drainingSearchers are the searcher instances that announced they are
+// draining (see protocol.DrainingHeader), so that textSearch sends requests
+// for their repositories to other searchers.
+var drainingSearchers = &searcherDrainSet{until: map[string]time.Time{}}
+
+type searcherDrainSet struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// add records that the searcher at url is draining.
+func (s *searcherDrainSet) add(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.until[url]; !ok {
+		log15.Info("Searcher is draining, sending requests to other searchers.", "url", url)
+	}
+	s.until[url] = time.Now().Add(searcherDrainTTL)
+}
+
+// excluded returns a set of the searchers that are draining, to exclude when
+// choosing a searcher for a request.
+func (s *searcherDrainSet) excluded() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	excluded := make(map[string]bool, len(s.until))
+	now := time.Now()
+	for url, until := range s.until {
+		if now.After(until) {
+			delete(s.until, url)
+			continue
+		}
+		excluded[url] = true
+	}
+	return excluded
+}
+
+// isSearcherDraining reports whether err is from a searcher that rejected a
+// request because it is draining.
+func isSearcherDraining(err error) bool {
+	e, ok := errors.Cause(err).(*searcherError)
+	return ok && e.Draining
+}
@@ -0,0 +1,368 @@
+package graphqlbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/query"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// searcherReplica is a fake searcher replica that serves the searcher HTTP
+// API on a local port, with its own latency and version.
+type searcherReplica struct {
+	name string
+
+	// latency is how long it takes to respond, unless the request is
+	// canceled first.
+	latency time.Duration
+
+	// protobuf is whether it responds in the protobuf encoding when asked
+	// to, like current searchers. Older searchers always respond in JSON.
+	protobuf bool
+
+	// status is the status of its responses if not 200, and draining whether
+	// it announces that it is draining.
+	status   int
+	draining bool
+
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	requests map[api.RepoName]int
+}
+
+func newSearcherReplica(r *searcherReplica) *searcherReplica {
+	r.requests = map[api.RepoName]int{}
+	r.srv = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	return r
+}
+
+func (r *searcherReplica) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	repo := api.RepoName(req.URL.Query().Get("Repo"))
+	r.mu.Lock()
+	r.requests[repo]++
+	r.mu.Unlock()
+
+	if r.latency > 0 {
+		t := time.NewTimer(r.latency)
+		defer t.Stop()
+		select {
+		case <-req.Context().Done():
+			return
+		case <-t.C:
+		}
+	}
+	if r.draining {
+		w.Header().Set(protocol.DrainingHeader, "true")
+		http.Error(w, "searcher is draining", http.StatusServiceUnavailable)
+		return
+	}
+	if r.status != 0 {
+		http.Error(w, r.name+" failed", r.status)
+		return
+	}
+
+	resp := protocol.Response{
+		Matches: []protocol.FileMatch{{
+			Path:        "main.go",
+			MatchCount:  1,
+			LineMatches: []protocol.LineMatch{{Preview: req.URL.Query().Get("Pattern") + " in " + string(repo), OffsetAndLengths: [][2]int{{0, 3}}}},
+		}},
+	}
+	if r.protobuf && strings.Contains(req.Header.Get("Accept"), protocol.ProtobufContentType) {
+		w.Header().Set("Content-Type", protocol.ProtobufContentType)
+		_, _ = w.Write(resp.MarshalProto())
+		return
+	}
+	_ = json.NewEncoder(w).Encode(&resp)
+}
+
+// searched returns the repositories that r was sent requests for, and the
+// number of requests.
+func (r *searcherReplica) searched() (repos []api.RepoName, requests int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for repo, n := range r.requests {
+		repos = append(repos, repo)
+		requests += n
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i] < repos[j] })
+	return repos, requests
+}
+
+// searcherReplicaSet is a set of fake searcher replicas, with the endpoint
+// map that the frontend routes requests to them with.
+type searcherReplicaSet struct {
+	replicas []*searcherReplica
+	urls     *endpoint.Map
+}
+
+func newSearcherReplicaSet(replicas ...*searcherReplica) *searcherReplicaSet {
+	s := &searcherReplicaSet{}
+	var urls []string
+	for _, r := range replicas {
+		r = newSearcherReplica(r)
+		s.replicas = append(s.replicas, r)
+		urls = append(urls, r.srv.URL)
+	}
+	s.urls = endpoint.Static(urls...)
+	return s
+}
+
+func (s *searcherReplicaSet) close() {
+	for _, r := range s.replicas {
+		r.srv.Close()
+	}
+}
+
+// routedTo returns the replica that the frontend routes the searches of repo
+// at commit to when all replicas are available.
+func (s *searcherReplicaSet) routedTo(t *testing.T, repo api.RepoName, commit api.CommitID) *searcherReplica {
+	t.Helper()
+	url, err := s.urls.Get(string(repo)+"@"+string(commit), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range s.replicas {
+		if r.srv.URL == url {
+			return r
+		}
+	}
+	t.Fatalf("no replica at %s", url)
+	return nil
+}
+
+func searcherReplicasTestRepos(n int) []api.RepoName {
+	repos := make([]api.RepoName, n)
+	for i := range repos {
+		repos[i] = api.RepoName(fmt.Sprintf("github.com/org/repo-%d", i))
+	}
+	return repos
+}
+
+// TestSearcherReplicas runs searches against several fake searcher replicas
+// (over HTTP) with differing latencies, versions and failures, and checks how
+// the frontend routes its requests to them, retries them, and merges their
+// results, so that searching with multiple searcher endpoints doesn't regress.
+func TestSearcherReplicas(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+	defer func() { drainingSearchers = &searcherDrainSet{until: map[string]time.Time{}} }()
+
+	t.Run("Routing", testSearcherReplicasRouting)
+	t.Run("Retries", testSearcherReplicasRetries)
+	t.Run("Merge", testSearcherReplicasMerge)
+}
+
+// testSearcherReplicasRouting checks that the searches of a repository at a
+// commit are consistently sent to the same replica (whose archive cache is
+// warm), that the repositories are spread over all replicas, and that each
+// search sends a single request.
+func testSearcherReplicasRouting(t *testing.T) {
+	replicas := newSearcherReplicaSet(
+		&searcherReplica{name: "current", protobuf: true},
+		&searcherReplica{name: "old", latency: 5 * time.Millisecond},
+		&searcherReplica{name: "slow", protobuf: true, latency: 20 * time.Millisecond},
+	)
+	defer replicas.close()
+
+	const commit = "deadbeef"
+	repos := searcherReplicasTestRepos(30)
+	want := map[string][]api.RepoName{}
+	for _, repo := range repos {
+		r := replicas.routedTo(t, repo, commit)
+		want[r.name] = append(want[r.name], repo)
+	}
+	for _, r := range replicas.replicas {
+		if len(want[r.name]) == 0 {
+			t.Fatalf("no repository is routed to replica %s", r.name)
+		}
+		sort.Slice(want[r.name], func(i, j int) bool { return want[r.name][i] < want[r.name][j] })
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, repo := range repos {
+			matches, _, err := textSearch(context.Background(), replicas.urls, gitserver.Repo{Name: repo}, commit, &search.TextPatternInfo{Pattern: "foo"}, time.Second)
+			if err != nil {
+				t.Fatalf("%s: %v", repo, err)
+			}
+			if len(matches) != 1 || matches[0].JLineMatches[0].JPreview != "foo in "+string(repo) {
+				t.Fatalf("%s: got unexpected matches %+v", repo, matches)
+			}
+		}
+	}
+
+	for _, r := range replicas.replicas {
+		got, requests := r.searched()
+		if !reflect.DeepEqual(got, want[r.name]) {
+			t.Errorf("replica %s: got requests for %v, want %v", r.name, got, want[r.name])
+		}
+		if requests != 2*len(want[r.name]) {
+			t.Errorf("replica %s: got %d requests, want one per search (%d)", r.name, requests, 2*len(want[r.name]))
+		}
+	}
+}
+
+// testSearcherReplicasRetries checks that searches sent to an unavailable
+// replica are retried once on another replica, and that a draining replica is
+// sent no more requests once it announced that it is draining.
+func testSearcherReplicasRetries(t *testing.T) {
+	defer func() { drainingSearchers = &searcherDrainSet{until: map[string]time.Time{}} }()
+
+	replicas := newSearcherReplicaSet(
+		&searcherReplica{name: "healthy", protobuf: true},
+		&searcherReplica{name: "unavailable", status: http.StatusServiceUnavailable},
+		&searcherReplica{name: "draining", protobuf: true, draining: true},
+		&searcherReplica{name: "old"},
+	)
+	defer replicas.close()
+	healthy, unavailable, draining, old := replicas.replicas[0], replicas.replicas[1], replicas.replicas[2], replicas.replicas[3]
+
+	const commit = "deadbeef"
+	repos := searcherReplicasTestRepos(40)
+	var routedToUnavailable int
+	for _, repo := range repos {
+		if replicas.routedTo(t, repo, commit) == unavailable {
+			routedToUnavailable++
+		}
+		matches, _, err := textSearch(context.Background(), replicas.urls, gitserver.Repo{Name: repo}, commit, &search.TextPatternInfo{Pattern: "foo"}, time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", repo, err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("%s: got %d matches, want 1", repo, len(matches))
+		}
+	}
+	if routedToUnavailable == 0 {
+		t.Fatal("no repository is routed to the unavailable replica")
+	}
+
+	_, unavailableRequests := unavailable.searched()
+	_, drainingRequests := draining.searched()
+	_, healthyRequests := healthy.searched()
+	_, oldRequests := old.searched()
+	// Retries of searches that failed on another replica can still be sent
+	// to the unavailable replica, and fail the search.
+	if unavailableRequests < routedToUnavailable {
+		t.Errorf("got %d requests to the unavailable replica, want at least %d", unavailableRequests, routedToUnavailable)
+	}
+	if drainingRequests != 1 {
+		t.Errorf("got %d requests to the draining replica, want 1", drainingRequests)
+	}
+	if healthyRequests+oldRequests != len(repos) {
+		t.Errorf("got %d requests to the available replicas, want one per repository (%d)", healthyRequests+oldRequests, len(repos))
+	}
+}
+
+// testSearcherReplicasMerge checks that searchFilesInRepos merges the results
+// of all replicas, whatever their version, and reports the repositories of a
+// replica that is slower than the repository timeout as timed out, without
+// waiting for it.
+func testSearcherReplicasMerge(t *testing.T) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchLimits: &schema.SearchLimits{RepositoryTimeout: "200ms"},
+	}})
+	defer conf.Mock(&conf.Unified{})
+
+	defer func(mocks db.MockStores) { db.Mocks = mocks }(db.Mocks)
+	db.Mocks.Repos.GetSizes = func(context.Context, ...api.RepoID) (map[api.RepoID]int64, error) {
+		return nil, nil
+	}
+	defer git.ResetMocks()
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		return "deadbeef", nil
+	}
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	replicas := newSearcherReplicaSet(
+		&searcherReplica{name: "current", protobuf: true, latency: 10 * time.Millisecond},
+		&searcherReplica{name: "old", latency: 20 * time.Millisecond},
+		&searcherReplica{name: "stuck", protobuf: true, latency: time.Minute},
+	)
+	defer replicas.close()
+	stuck := replicas.replicas[2]
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := searcherReplicasTestRepos(30)
+	var repoRevs []string
+	var wantResults, wantTimedout []api.RepoName
+	for _, repo := range repos {
+		repoRevs = append(repoRevs, string(repo))
+		if replicas.routedTo(t, repo, "deadbeef") == stuck {
+			wantTimedout = append(wantTimedout, repo)
+		} else {
+			wantResults = append(wantResults, repo)
+		}
+	}
+	if len(wantTimedout) == 0 {
+		t.Fatal("no repository is routed to the stuck replica")
+	}
+
+	start := time.Now()
+	results, common, err := searchFilesInRepos(context.Background(), &search.TextParameters{
+		PatternInfo:  &search.TextPatternInfo{Pattern: "foo", FileMatchLimit: defaultMaxSearchResults},
+		Repos:        makeRepositoryRevisions(repoRevs...),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{},
+		SearcherURLs: replicas.urls,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 30*time.Second {
+		t.Errorf("the search took %s, want it not to wait for the stuck replica", d)
+	}
+
+	var gotResults []api.RepoName
+	for _, fm := range results {
+		if want := "foo in " + string(fm.Repo.Name); len(fm.JLineMatches) != 1 || fm.JLineMatches[0].JPreview != want {
+			t.Errorf("%s: got line matches %+v, want one with preview %q", fm.Repo.Name, fm.JLineMatches, want)
+		}
+		gotResults = append(gotResults, fm.Repo.Name)
+	}
+	sort.Slice(gotResults, func(i, j int) bool { return gotResults[i] < gotResults[j] })
+	sort.Slice(wantResults, func(i, j int) bool { return wantResults[i] < wantResults[j] })
+	if !reflect.DeepEqual(gotResults, wantResults) {
+		t.Errorf("got results in %v, want %v", gotResults, wantResults)
+	}
+
+	gotTimedout := toRepoNames(common.timedout)
+	sort.Slice(gotTimedout, func(i, j int) bool { return gotTimedout[i] < gotTimedout[j] })
+	sort.Slice(wantTimedout, func(i, j int) bool { return wantTimedout[i] < wantTimedout[j] })
+	if !reflect.DeepEqual(gotTimedout, wantTimedout) {
+		t.Errorf("got timed out repositories %v, want %v", gotTimedout, wantTimedout)
+	}
+	// Timed out searches are not retried on another replica.
+	if _, requests := stuck.searched(); requests != len(wantTimedout) {
+		t.Errorf("got %d requests to the stuck replica, want %d", requests, len(wantTimedout))
+	}
+}
@@ -0,0 +1,65 @@
+package graphqlbackend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+)
+
+var (
+	searcherTLSCertFile   = env.Get("SEARCHER_TLS_CERT_FILE", "", "path to a PEM encoded client certificate presented to searcher (requires SEARCHER_TLS_KEY_FILE)")
+	searcherTLSKeyFile    = env.Get("SEARCHER_TLS_KEY_FILE", "", "path to the PEM encoded private key of SEARCHER_TLS_CERT_FILE")
+	searcherTLSCAFile     = env.Get("SEARCHER_TLS_CA_FILE", "", "path to PEM encoded CA certificates used to verify searcher's certificate instead of the system roots")
+	searcherTLSServerName = env.Get("SEARCHER_TLS_SERVER_NAME", "", "server name used to verify searcher's certificate, useful when SEARCHER_URL resolves to IP addresses")
+)
+
+// searcherTLSConfig returns the TLS configuration used when connecting to
+// searcher over https. It returns nil if none of the SEARCHER_TLS_* environment
+// variables are set, in which case the default configuration is used.
+//
+// Misconfiguration is fatal: silently falling back to an unauthenticated client
+// would defeat the point of configuring mutual TLS.
+func searcherTLSConfig() *tls.Config {
+	config, err := newSearcherTLSConfig(searcherTLSCertFile, searcherTLSKeyFile, searcherTLSCAFile, searcherTLSServerName)
+	if err != nil {
+		log.Fatalf("invalid searcher TLS configuration: %s", err)
+	}
+	return config
+}
+
+func newSearcherTLSConfig(certFile, keyFile, caFile, serverName string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" && serverName == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{ServerName: serverName}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("SEARCHER_TLS_CERT_FILE and SEARCHER_TLS_KEY_FILE must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading CA certificates")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
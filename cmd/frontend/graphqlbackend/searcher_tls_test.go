@@ -0,0 +1,103 @@
+package graphqlbackend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSearcherTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "searcher-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	t.Run("unset", func(t *testing.T) {
+		config, err := newSearcherTLSConfig("", "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config != nil {
+			t.Fatalf("expected nil config, got %+v", config)
+		}
+	})
+
+	t.Run("client certificate and CA", func(t *testing.T) {
+		config, err := newSearcherTLSConfig(certFile, keyFile, certFile, "searcher")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(config.Certificates) != 1 {
+			t.Errorf("got %d certificates, want 1", len(config.Certificates))
+		}
+		if config.RootCAs == nil {
+			t.Error("expected RootCAs to be set")
+		}
+		if config.ServerName != "searcher" {
+			t.Errorf("got server name %q, want %q", config.ServerName, "searcher")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for name, args := range map[string][3]string{
+			"cert without key": {certFile, "", ""},
+			"key without cert": {"", keyFile, ""},
+			"missing cert":     {filepath.Join(dir, "missing.pem"), keyFile, ""},
+			"missing CA":       {"", "", filepath.Join(dir, "missing.pem")},
+			"CA without certs": {"", "", keyFile},
+		} {
+			if _, err := newSearcherTLSConfig(args[0], args[1], args[2], ""); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+// writeTestCertificate writes a self-signed certificate and its key to dir and
+// returns their paths.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "searcher"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
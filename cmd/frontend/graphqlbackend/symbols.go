@@ -139,7 +139,7 @@ func searchZoektSymbols(ctx context.Context, commit *GitCommitResolver, branch s
 		return nil, err
 	}
 
-	baseURI, err := gituri.Parse("git://" + string(commit.repo.repo.Name) + "?" + string(commit.oid))
+	baseURI := gituri.New(commit.repo.repo.Name, string(commit.oid), "")
 	for _, file := range resp.Files {
 		for _, l := range file.LineMatches {
 			if l.FileName {
@@ -196,10 +196,7 @@ func computeSymbols(ctx context.Context, commit *GitCommitResolver, query *strin
 	if query != nil {
 		searchArgs.Query = *query
 	}
-	baseURI, err := gituri.Parse("git://" + string(commit.repo.repo.Name) + "?" + string(commit.oid))
-	if err != nil {
-		return nil, err
-	}
+	baseURI := gituri.New(commit.repo.repo.Name, string(commit.oid), "")
 	symbols, err := backend.Symbols.ListTags(ctx, searchArgs)
 	if baseURI == nil {
 		return
@@ -5,11 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/url"
 	"reflect"
-	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,10 +23,13 @@ import (
 
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
+	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/errcode"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/gituri"
 	"github.com/sourcegraph/sourcegraph/internal/mutablelimiter"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
@@ -37,9 +39,23 @@ import (
 )
 
 var (
-	// A global limiter on number of concurrent searcher searches.
+	// A global limiter on number of concurrent searcher searches. Its limit
+	// is controlled by getSearcherConcurrency.
 	textSearchLimiter = mutablelimiter.New(32)
 
+	// repoSearchTimeout is the maximum time spent searching a single
+	// repository revision when searching multiple repositories (0 means no
+	// limit other than the deadline of the search).
+	repoSearchTimeout = parseRepoSearchTimeout(env.Get("SEARCH_REPO_TIMEOUT", "0", "maximum time spent searching a single repository without an index when searching multiple repositories, after which it is reported as timed out (0 means no limit)"))
+
+	// searcherProtobuf is whether searcher responses are requested in the
+	// protobuf encoding (see protocol.ProtobufContentType) instead of JSON.
+	searcherProtobuf = env.Get("SEARCHER_PROTOBUF", "true", "request searcher responses in the protobuf encoding, which is cheaper to decode than JSON") != "false"
+
+	// searcherFastJSON is whether JSON searcher responses are decoded with
+	// decodeSearcherResponseJSON instead of encoding/json.
+	searcherFastJSON = env.Get("SEARCHER_FAST_JSON", "true", "decode JSON searcher responses with a specialized decoder instead of encoding/json") != "false"
+
 	requestCounter = metrics.NewRequestMeter("textsearch", "Total number of requests sent to the textsearch API.")
 
 	searchHTTPClient = &http.Client{
@@ -48,6 +64,10 @@ var (
 			RoundTripper: requestCounter.Transport(&http.Transport{
 				// Default is 2, but we can send many concurrent requests
 				MaxIdleConnsPerHost: 500,
+				// Restricts the addresses SEARCHER_URLs may resolve to.
+				DialContext: searcherDialer().DialContext,
+				// Client certificates and CAs for SEARCHER_URLs using https.
+				TLSClientConfig: searcherTLSConfig(),
 			}, func(u *url.URL) string {
 				// TODO(uwedeportivo): remove once codemod has its own client
 				if strings.Contains(u.String(), "replacer") {
@@ -68,14 +88,30 @@ type FileMatchResolver struct {
 	JLineMatches []*lineMatch `json:"LineMatches"`
 	JLimitHit    bool         `json:"LimitHit"`
 	MatchCount   int          // Number of matches. Different from len(JLineMatches), as multiple lines may correspond to one logical match.
-	symbols      []*searchSymbolResult
-	uri          string
-	Repo         *types.Repo
-	CommitID     api.CommitID
+	// JMatchRanges are the ranges of the matches, if the searcher that found
+	// them returns ranges (see MatchRanges).
+	JMatchRanges []protocol.Range `json:"MatchRanges"`
+	// JPathOffsetAndLengths are the matches of the search pattern in JPath,
+	// if the pattern matches paths (see setPathMatches).
+	JPathOffsetAndLengths [][2]int32 `json:"PathOffsetAndLengths"`
+	symbols               []*searchSymbolResult
+	uri                   string
+	Repo                  *types.Repo
+	CommitID              api.CommitID
 	// InputRev is the Git revspec that the user originally requested to search. It is used to
 	// preserve the original revision specifier from the user instead of navigating them to the
 	// absolute commit ID when they select a result.
 	InputRev *string
+	// lineMatchesQuery is the searcher query that found this match, if any.
+	// It is used to fetch more line matches than searcher initially returned.
+	lineMatchesQuery *lineMatchesQuery
+	// repoBatch, if non-nil, loads the fields of Repo together with those
+	// of the other repositories of the search results.
+	repoBatch *repositoryBatch
+	// historyRanges are the ranges of commits that contain the match, if it
+	// was found by a search of the history of files (see
+	// searchFilesInHistory).
+	historyRanges []historyRange
 }
 
 func (fm *FileMatchResolver) Equal(other *FileMatchResolver) bool {
@@ -92,7 +128,7 @@ func (fm *FileMatchResolver) File() *GitTreeEntryResolver {
 	// values for all other fields.
 	return &GitTreeEntryResolver{
 		commit: &GitCommitResolver{
-			repo:     &RepositoryResolver{repo: fm.Repo},
+			repo:     &RepositoryResolver{repo: fm.Repo, batch: fm.repoBatch},
 			oid:      GitObjectID(fm.CommitID),
 			inputRev: fm.InputRev,
 		},
@@ -101,7 +137,7 @@ func (fm *FileMatchResolver) File() *GitTreeEntryResolver {
 }
 
 func (fm *FileMatchResolver) Repository() *RepositoryResolver {
-	return &RepositoryResolver{repo: fm.Repo}
+	return &RepositoryResolver{repo: fm.Repo, batch: fm.repoBatch}
 }
 
 func (fm *FileMatchResolver) RevSpec() *gitRevSpec {
@@ -133,6 +169,18 @@ func (fm *FileMatchResolver) LimitHit() bool {
 	return fm.JLimitHit
 }
 
+func (fm *FileMatchResolver) HistoryRanges() *[]*historyRangeResolver {
+	if fm.historyRanges == nil {
+		return nil
+	}
+	repo := fm.Repository()
+	ranges := make([]*historyRangeResolver, len(fm.historyRanges))
+	for i, r := range fm.historyRanges {
+		ranges[i] = &historyRangeResolver{repo: repo, r: r}
+	}
+	return &ranges
+}
+
 func (fm *FileMatchResolver) ToRepository() (*RepositoryResolver, bool) { return nil, false }
 func (fm *FileMatchResolver) ToFileMatch() (*FileMatchResolver, bool)   { return fm, true }
 func (fm *FileMatchResolver) ToCommitSearchResult() (*commitSearchResultResolver, bool) {
@@ -161,6 +209,21 @@ type lineMatch struct {
 	JOffsetAndLengths [][2]int32 `json:"OffsetAndLengths"`
 	JLineNumber       int32      `json:"LineNumber"`
 	JLimitHit         bool       `json:"LimitHit"`
+	// JRuleIDs are the IDs of the rules of the search.rules site setting that
+	// the line hits (see filterRules).
+	JRuleIDs []string `json:"RuleIDs,omitempty"`
+
+	// file is the file match of the line, if known (see truncatePreviews).
+	file *FileMatchResolver
+	// If previewTruncated, JPreview is truncated (see truncatePreview),
+	// previewOffset is the offset of JPreview in the line and
+	// lineOffsetAndLengths are the matches in the full line.
+	previewTruncated     bool
+	previewOffset        int32
+	lineOffsetAndLengths [][2]int32
+	// previewHex is whether JPreview contains the bytes of the line in
+	// hexadecimal (see setHexPreviews).
+	previewHex bool
 }
 
 func (lm *lineMatch) Preview() string {
@@ -171,10 +234,18 @@ func (lm *lineMatch) LineNumber() int32 {
 	return lm.JLineNumber
 }
 
-func (lm *lineMatch) OffsetAndLengths() [][]int32 {
-	r := make([][]int32, len(lm.JOffsetAndLengths))
-	for i := range lm.JOffsetAndLengths {
-		r[i] = lm.JOffsetAndLengths[i][:]
+type offsetUnitArgs struct {
+	Unit string
+}
+
+func (lm *lineMatch) OffsetAndLengths(args *offsetUnitArgs) [][]int32 {
+	return offsetAndLengthsSlices(search.ConvertOffsets(lm.JPreview, lm.JOffsetAndLengths, search.OffsetUnit(args.Unit)))
+}
+
+func offsetAndLengthsSlices(offsetAndLengths [][2]int32) [][]int32 {
+	r := make([][]int32, len(offsetAndLengths))
+	for i := range offsetAndLengths {
+		r[i] = offsetAndLengths[i][:]
 	}
 	return r
 }
@@ -198,48 +269,71 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 		tr.Finish()
 	}()
 
-	q := url.Values{
-		"Repo":            []string{string(repo.Name)},
-		"URL":             []string{repo.URL},
-		"Commit":          []string{string(commit)},
-		"Pattern":         []string{p.Pattern},
-		"ExcludePattern":  []string{p.ExcludePattern},
-		"IncludePatterns": p.IncludePatterns,
-		"FetchTimeout":    []string{fetchTimeout.String()},
-		"Languages":       p.Languages,
-		"CombyRule":       []string{p.CombyRule},
+	// Send the searches of tenants with dedicated searcher instances to them.
+	if t := search.TenantFromContext(ctx); t != nil && t.SearcherURL != "" {
+		searcherURLs = search.SearcherURLsForTenant(ctx)
 	}
-	if deadline, ok := ctx.Deadline(); ok {
-		t, err := deadline.MarshalText()
-		if err != nil {
-			return nil, false, err
-		}
-		q.Set("Deadline", string(t))
+
+	release, err := getTextSearchAdmission().admit(ctx)
+	if err != nil {
+		return nil, false, err
 	}
-	q.Set("FileMatchLimit", strconv.FormatInt(int64(p.FileMatchLimit), 10))
+	defer release()
+
+	pattern := p.Pattern
 	if p.IsRegExp {
-		q.Set("IsRegExp", "true")
+		// Only the request is trimmed: the users of p other than searcher
+		// (e.g. replacements) need the pattern as it was written.
+		pattern = search.TrimRegexpWildcards(pattern)
 	}
-	if p.IsStructuralPat {
-		q.Set("IsStructuralPat", "true")
-	}
-	if p.IsWordMatch {
-		q.Set("IsWordMatch", "true")
+	r := &protocol.Request{
+		Repo:   repo.Name,
+		URL:    repo.URL,
+		Commit: commit,
+		PatternInfo: protocol.PatternInfo{
+			Pattern:                      pattern,
+			IsRegExp:                     p.IsRegExp,
+			IsStructuralPat:              p.IsStructuralPat,
+			IsWordMatch:                  p.IsWordMatch,
+			IsCaseSensitive:              p.IsCaseSensitive,
+			ExcludePattern:               p.ExcludePattern,
+			IncludePatterns:              p.IncludePatterns,
+			PathPatternsAreRegExps:       p.PathPatternsAreRegExps,
+			PathPatternsAreCaseSensitive: p.PathPatternsAreCaseSensitive,
+			FileMatchLimit:               int(p.FileMatchLimit),
+			PatternMatchesContent:        p.PatternMatchesContent,
+			PatternMatchesPath:           p.PatternMatchesPath,
+			Languages:                    p.Languages,
+			CombyRule:                    p.CombyRule,
+			CountOnly:                    p.CountOnly,
+			MaxLineMatches:               p.MaxLineMatches,
+			Deterministic:                p.Deterministic,
+			IsIdentifierMatch:            p.IsIdentifierMatch,
+			IdentifierSubTokens:          p.IdentifierSubTokens,
+			WordCharacters:               p.WordCharacters.Entries(),
+			Within:                       p.Within,
+			GoCall:                       p.GoCall,
+			GoSelector:                   p.GoSelector,
+			ModifiedSinceRevision:        p.ModifiedSinceRevision,
+			IsPCRE:                       p.IsPCRE,
+			CaseFold:                     p.CaseFold,
+			Normalization:                p.Normalization,
+		},
+		FetchTimeout: fetchTimeout.String(),
 	}
-	if p.IsCaseSensitive {
-		q.Set("IsCaseSensitive", "true")
+	if !p.ModifiedSince.IsZero() {
+		r.ModifiedSince = p.ModifiedSince.UTC().Format(time.RFC3339)
 	}
-	if p.PathPatternsAreRegExps {
-		q.Set("PathPatternsAreRegExps", "true")
+	if p.FirstMatchPerRepo {
+		r.FileMatchLimit, r.MaxLineMatches = 1, 1
 	}
-	if p.PathPatternsAreCaseSensitive {
-		q.Set("PathPatternsAreCaseSensitive", "true")
+	if deadline, ok := ctx.Deadline(); ok {
+		t, err := deadline.MarshalText()
+		if err != nil {
+			return nil, false, err
+		}
+		r.Deadline = string(t)
 	}
-	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
-	// these fields from old frontends that do not (and provide a default in the latter case).
-	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
-	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
-	rawQuery := q.Encode()
 
 	// Searcher caches the file contents for repo@commit since it is
 	// relatively expensive to fetch from gitserver. So we use consistent
@@ -247,9 +341,11 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	consistentHashKey := string(repo.Name) + "@" + string(commit)
 	tr.LazyPrintf("%s", consistentHashKey)
 
+	client, clientOverridden := searcherClientFromContext(ctx)
 	var (
-		// When we retry do not use a host we already tried.
-		excludedSearchURLs = map[string]bool{}
+		// When we retry do not use a host we already tried, nor (in the
+		// first place) one that is draining.
+		excludedSearchURLs = drainingSearchers.excluded()
 		attempt            = 0
 		maxAttempts        = 2
 	)
@@ -258,23 +354,38 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 
 		searcherURL, err := searcherURLs.Get(consistentHashKey, excludedSearchURLs)
 		if err != nil {
-			return nil, false, err
+			return nil, false, newSearchError(searchErrorSearcherUnavailable, err)
 		}
 
 		// Fallback to a bad host if nothing is left
-		if searcherURL == "" {
+		fallback := searcherURL == ""
+		if fallback {
 			tr.LazyPrintf("failed to find endpoint, trying again without excludes")
 			searcherURL, err = searcherURLs.Get(consistentHashKey, nil)
 			if err != nil {
-				return nil, false, err
+				return nil, false, newSearchError(searchErrorSearcherUnavailable, err)
 			}
 		}
 
-		url := searcherURL + "?" + rawQuery
-		tr.LazyPrintf("attempt %d: %s", attempt, url)
-		matches, limitHit, err = textSearchURL(ctx, url)
+		tr.LazyPrintf("attempt %d: %s", attempt, searcherURL)
+		var cached bool
+		start := time.Now()
+		if err = injectSearchFault(ctx, searchFaultTextSearch, repo.Name); err == nil {
+			matches, limitHit, cached, err = client.Search(ctx, searcherURL, r)
+		}
+		// The latencies of clients set for a search (such as the fake searchers
+		// of load tests) say nothing about the latencies of searcher.
+		if ctx.Err() == nil && !clientOverridden {
+			getSearcherConcurrency().observe(time.Since(start), cached, err)
+		}
+		if err == nil {
+			tr.SetTag("cached", cached)
+			tr.SetTag("results", len(matches))
+		}
 		if err == nil || errcode.IsTimeout(err) {
-			return matches, limitHit, err
+			// The first match of a repository is all there is to find in it
+			// if only the repositories with matches are results.
+			return matches, limitHit && !p.FirstMatchPerRepo, err
 		}
 
 		// If we are canceled, return that error.
@@ -282,6 +393,16 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 			return nil, false, err
 		}
 
+		// A draining searcher rejected the request before searching, so
+		// trying another searcher does not count as a retry (unless there is
+		// no other searcher left).
+		if isSearcherDraining(err) {
+			drainingSearchers.add(searcherURL)
+			if !fallback {
+				attempt--
+			}
+		}
+
 		// If not temporary or our last attempt then don't try again.
 		if !errcode.IsTemporary(err) || attempt == maxAttempts {
 			return nil, false, err
@@ -293,12 +414,22 @@ func textSearch(ctx context.Context, searcherURLs *endpoint.Map, repo gitserver.
 	}
 }
 
-func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool, error) {
+// textSearchURL sends a search request to a searcher. It returns whether the
+// archive of the repository was cached by searcher in addition to the results.
+func textSearchURL(ctx context.Context, url string) (matches []*FileMatchResolver, limitHit, cached bool, err error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, false, err
+		return nil, false, false, err
 	}
 	req = req.WithContext(ctx)
+	if searcherProtobuf {
+		// Searchers that do not support protobuf ignore this and respond with
+		// JSON.
+		req.Header.Set("Accept", protocol.ProtobufContentType+", application/json")
+	}
+	if id := search.RequestID(ctx); id != "" {
+		req.Header.Set(protocol.RequestIDHeader, id)
+	}
 
 	req, ht := nethttp.TraceRequest(ot.GetTracer(ctx), req,
 		nethttp.OperationName("Searcher Client"),
@@ -313,37 +444,130 @@ func textSearchURL(ctx context.Context, url string) ([]*FileMatchResolver, bool,
 		// If we failed due to cancellation or timeout (with no partial results in the response
 		// body), return just that.
 		if ctx.Err() != nil {
-			err = ctx.Err()
+			return nil, false, false, errors.Wrap(ctx.Err(), "searcher request failed")
 		}
-		return nil, false, errors.Wrap(err, "searcher request failed")
+		return nil, false, false, newSearchError(searchErrorSearcherUnavailable, errors.Wrap(err, "searcher request failed"))
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return nil, false, err
+			return nil, false, false, err
 		}
-		return nil, false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+		return nil, false, false, newSearcherError(&searcherError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+			Draining:   resp.Header.Get(protocol.DrainingHeader) == "true",
+		})
 	}
 
-	r := struct {
-		Matches     []*FileMatchResolver
-		LimitHit    bool
-		DeadlineHit bool
-	}{}
-	err = json.NewDecoder(resp.Body).Decode(&r)
-	if err != nil {
-		return nil, false, errors.Wrap(err, "searcher response invalid")
+	var r searcherResponse
+	if resp.Header.Get("Content-Type") == protocol.ProtobufContentType {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, false, errors.Wrap(err, "searcher response invalid")
+		}
+		var pr protocol.Response
+		if err := pr.UnmarshalProto(body); err != nil {
+			return nil, false, false, errors.Wrap(err, "searcher response invalid")
+		}
+		r.Matches = fileMatchesFromProtocol(pr.Matches)
+		r.LimitHit, r.DeadlineHit, r.Cached = pr.LimitHit, pr.DeadlineHit, pr.Cached
+	} else if searcherFastJSON {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, false, errors.Wrap(err, "searcher response invalid")
+		}
+		if err := decodeSearcherResponseJSON(body, &r); err != nil {
+			return nil, false, false, errors.Wrap(err, "searcher response invalid")
+		}
+	} else if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, false, false, errors.Wrap(err, "searcher response invalid")
+	}
+	if err := checkSearcherMatches(r.Matches); err != nil {
+		return nil, false, false, errors.Wrap(err, "searcher response invalid")
 	}
 	if r.DeadlineHit {
 		err = context.DeadlineExceeded
 	}
-	return r.Matches, r.LimitHit, err
+	return r.Matches, r.LimitHit, r.Cached, err
+}
+
+// fileMatchesFromProtocol converts the matches of a searcher response to the
+// FileMatchResolvers that the JSON encoding of the response decodes to.
+func fileMatchesFromProtocol(matches []protocol.FileMatch) []*FileMatchResolver {
+	fms := make([]*FileMatchResolver, len(matches))
+	for i, m := range matches {
+		fm := &FileMatchResolver{
+			JPath:        m.Path,
+			JLimitHit:    m.LimitHit,
+			MatchCount:   m.MatchCount,
+			JMatchRanges: m.MatchRanges,
+		}
+		if m.LineMatches != nil {
+			fm.JLineMatches = make([]*lineMatch, len(m.LineMatches))
+		}
+		for j, l := range m.LineMatches {
+			lm := &lineMatch{
+				JPreview:    l.Preview,
+				JLineNumber: int32(l.LineNumber),
+				JLimitHit:   l.LimitHit,
+			}
+			if l.OffsetAndLengths != nil {
+				lm.JOffsetAndLengths = make([][2]int32, len(l.OffsetAndLengths))
+			}
+			for k, ol := range l.OffsetAndLengths {
+				lm.JOffsetAndLengths[k] = [2]int32{int32(ol[0]), int32(ol[1])}
+			}
+			fm.JLineMatches[j] = lm
+		}
+		fms[i] = fm
+	}
+	return fms
+}
+
+// checkSearcherMatches returns an error if matches (decoded from a searcher
+// response) have line numbers or offsets that the resolvers can't handle:
+// negative ones, or matches that end past the largest offset.
+func checkSearcherMatches(matches []*FileMatchResolver) error {
+	for _, fm := range matches {
+		if fm == nil {
+			return errors.New("null file match")
+		}
+		for _, lm := range fm.JLineMatches {
+			if lm == nil {
+				return errors.Errorf("null line match in %q", fm.JPath)
+			}
+			if lm.JLineNumber < 0 {
+				return errors.Errorf("negative line number %d in %q", lm.JLineNumber, fm.JPath)
+			}
+			for _, ol := range lm.JOffsetAndLengths {
+				if ol[0] < 0 || ol[1] < 0 || int64(ol[0])+int64(ol[1]) > math.MaxInt32 {
+					return errors.Errorf("invalid offset and length %v on line %d of %q", ol, lm.JLineNumber, fm.JPath)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// newSearcherError returns the error for a searcher response with an error
+// status, with the search error code of the status.
+func newSearcherError(e *searcherError) error {
+	var err error = e
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		err = newSearchError(searchErrorPatternInvalid, err)
+	case http.StatusServiceUnavailable:
+		err = newSearchError(searchErrorSearcherUnavailable, err)
+	}
+	return errors.WithStack(err)
 }
 
 type searcherError struct {
 	StatusCode int
 	Message    string
+	Draining   bool // the searcher is draining (see protocol.DrainingHeader)
 }
 
 func (e *searcherError) BadRequest() bool {
@@ -365,16 +589,12 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 		return mockSearchFilesInRepo(ctx, repo, gitserverRepo, rev, info, fetchTimeout)
 	}
 
-	// Do not trigger a repo-updater lookup (e.g.,
-	// backend.{GitRepo,Repos.ResolveRev}) because that would slow this operation
-	// down by a lot (if we're looping over many repos). This means that it'll fail if a
-	// repo is not on gitserver.
-	commit, err := git.ResolveRevision(ctx, gitserverRepo, nil, rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
-	if err != nil {
-		return nil, false, err
+	timing := repoTimingFromContext(ctx)
+	start := time.Now()
+	commit, shouldBeSearched, err := resolveRepoToSearch(ctx, searcherURLs, gitserverRepo, rev, info, fetchTimeout)
+	if timing != nil {
+		timing.resolve = time.Since(start)
 	}
-
-	shouldBeSearched, err := repoShouldBeSearched(ctx, searcherURLs, info, gitserverRepo, commit, fetchTimeout)
 	if err != nil {
 		return nil, false, err
 	}
@@ -382,22 +602,64 @@ func searchFilesInRepo(ctx context.Context, searcherURLs *endpoint.Map, repo *ty
 		return nil, false, err
 	}
 
+	start = time.Now()
 	matches, limitHit, err = textSearch(ctx, searcherURLs, gitserverRepo, commit, info, fetchTimeout)
+	if f := grepFallbackFromContext(ctx); err != nil && f != nil {
+		matches, limitHit, err = f.search(ctx, gitserverRepo, commit, info, err)
+	}
+	if timing != nil {
+		timing.search = time.Since(start)
+	}
 	if err != nil {
 		return nil, false, err
 	}
 
-	workspace := fileMatchURI(repo.Name, rev, "")
+	var lmq *lineMatchesQuery
+	if !info.IsStructuralPat {
+		lmq = &lineMatchesQuery{searcherURLs: searcherURLs, repo: gitserverRepo, info: info, fetchTimeout: fetchTimeout}
+	}
 	for _, fm := range matches {
-		fm.uri = workspace + fm.JPath
+		fm.uri = fileMatchURI(repo.Name, rev, fm.JPath)
 		fm.Repo = repo
 		fm.CommitID = commit
 		fm.InputRev = &rev
+		fm.lineMatchesQuery = lmq
+		truncatePreviews(fm)
 	}
 
 	return matches, limitHit, err
 }
 
+// resolveRepoToSearch resolves rev to a commit and determines whether the
+// repository should be searched at that commit. It is the "resolve" phase of a
+// repository search in traces.
+func resolveRepoToSearch(ctx context.Context, searcherURLs *endpoint.Map, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (commit api.CommitID, shouldBeSearched bool, err error) {
+	tr, ctx := trace.New(ctx, "resolve", fmt.Sprintf("%s@%s", gitserverRepo.Name, rev))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	// Do not trigger a repo-updater lookup (e.g.,
+	// backend.{GitRepo,Repos.ResolveRev}) because that would slow this operation
+	// down by a lot (if we're looping over many repos). This means that it'll fail if a
+	// repo is not on gitserver.
+	if r := revisionPrefetchFromContext(ctx).wait(ctx, gitserverRepo.Name, rev); r != nil {
+		tr.SetTag("prefetched", true)
+		commit, err = r.commit, r.err
+	} else {
+		commit, err = git.ResolveRevision(ctx, gitserverRepo, nil, rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+	}
+	if err != nil {
+		return "", false, err
+	}
+	tr.SetTag("commit", string(commit))
+
+	shouldBeSearched, err = repoShouldBeSearched(ctx, searcherURLs, info, gitserverRepo, commit, fetchTimeout)
+	tr.SetTag("shouldBeSearched", shouldBeSearched)
+	return commit, shouldBeSearched, err
+}
+
 // repoShouldBeSearched determines whether a repository should be searched in, based on whether the repository
 // fits in the subset of repositories specified in the query's `repohasfile` and `-repohasfile` flags if they exist.
 func repoShouldBeSearched(ctx context.Context, searcherURLs *endpoint.Map, searchPattern *search.TextPatternInfo, gitserverRepo gitserver.Repo, commit api.CommitID, fetchTimeout time.Duration) (shouldBeSearched bool, err error) {
@@ -438,19 +700,57 @@ func repoHasFilesWithNamesMatching(ctx context.Context, searcherURLs *endpoint.M
 	return true, nil
 }
 
+// fileMatchURI returns the git://repo?rev#path URI of a file match. The
+// repository and revision are escaped by gituri.New; the path is appended
+// as is.
 func fileMatchURI(name api.RepoName, ref, path string) string {
-	var b strings.Builder
-	ref = url.QueryEscape(ref)
-	b.Grow(len(name) + len(ref) + len(path) + len("git://?#"))
-	b.WriteString("git://")
-	b.WriteString(string(name))
-	if ref != "" {
-		b.WriteByte('?')
-		b.WriteString(ref)
+	return gituri.New(name, ref, "").String() + "#" + path
+}
+
+func parseRepoSearchTimeout(s string) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log15.Error("Invalid SEARCH_REPO_TIMEOUT, disabling per-repository timeout", "value", s, "error", err)
+		return 0
+	}
+	return d
+}
+
+// searchRepoTimeout returns the maximum time spent searching a single
+// repository revision when searching multiple repositories, from the
+// search.limits site configuration or SEARCH_REPO_TIMEOUT.
+func searchRepoTimeout() time.Duration {
+	if limits := conf.Get().SearchLimits; limits != nil && limits.RepositoryTimeout != "" {
+		if d, err := time.ParseDuration(limits.RepositoryTimeout); err == nil {
+			return d
+		}
+	}
+	return repoSearchTimeout
+}
+
+// postFilterMatches applies the filters of q that are evaluated on the results
+// of the search backends (owner:, license:, ruleid: and severity:) to
+// matches. The backends stop at the file match limit before these filters
+// run, so the filtered matches can be fewer than requested although more
+// matches exist elsewhere. limitHit is therefore true if the filters removed
+// any file or line matches.
+func postFilterMatches(ctx context.Context, q query.QueryInfo, matches []*FileMatchResolver) (_ []*FileMatchResolver, limitHit bool) {
+	files, lines := len(matches), 0
+	for _, fm := range matches {
+		lines += len(fm.JLineMatches)
+	}
+
+	matches = filterOwners(ctx, q, matches)
+	matches = filterLicenses(ctx, q, matches)
+	matches = filterRules(q, matches)
+
+	if len(matches) < files {
+		return matches, true
 	}
-	b.WriteByte('#')
-	b.WriteString(path)
-	return b.String()
+	for _, fm := range matches {
+		lines -= len(fm.JLineMatches)
+	}
+	return matches, lines > 0
 }
 
 var mockSearchFilesInRepos func(args *search.TextParameters) ([]*FileMatchResolver, *searchResultsCommon, error)
@@ -461,7 +761,14 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		return mockSearchFilesInRepos(args)
 	}
 
+	if search.RequestID(ctx) == "" {
+		ctx = search.WithRequestID(ctx, search.NewRequestID())
+	}
+	requestID := search.RequestID(ctx)
+	ctx = withSearchProfileLabels(ctx, args)
+
 	tr, ctx := trace.New(ctx, "searchFilesInRepos", fmt.Sprintf("query: %s, numRepoRevs: %d", args.PatternInfo.Pattern, len(args.Repos)))
+	tr.SetTag("searchRequestID", requestID)
 	defer func() {
 		tr.SetError(err)
 		tr.Finish()
@@ -471,18 +778,28 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		trace.Stringer("query", &fields),
 		trace.Stringer("info", args.PatternInfo),
 	)
+	hexPreviews := args.Query.BoolValue(query.FieldHexPreview)
+	pathMatchRe := pathMatchRegexp(args.PatternInfo)
 
+	parentCtx := ctx
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	common = &searchResultsCommon{partial: make(map[api.RepoName]struct{})}
+	ctx, fallback := withGrepFallback(ctx)
+	spool := fileMatchSpoolFromContext(ctx)
+	stream := searchStreamFromContext(ctx)
+
+	common = &searchResultsCommon{partial: make(map[api.RepoName]struct{}), aggregations: newSearchAggregations()}
 
 	var (
 		searcherRepos = args.Repos
 		zoektRepos    []*search.RepositoryRevisions
 	)
 
-	if args.Zoekt.Enabled() {
+	// The index is not used for users for whom the indexed search feature
+	// flag is disabled.
+	zoektEnabled := args.Zoekt.Enabled() && searchFeatureIndexedSearch.enabled(ctx)
+	if zoektEnabled {
 		zoektRepos, searcherRepos, err = zoektIndexedRepos(ctx, args.Zoekt, args.Repos, nil)
 		if err != nil {
 			// Don't hard fail if index is not available yet.
@@ -518,11 +835,11 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		switch parseYesNoOnly(index) {
 		case Yes, True:
 			// default
-			if args.Zoekt.Enabled() {
+			if zoektEnabled {
 				tr.LazyPrintf("%d indexed repos, %d unindexed repos", len(zoektRepos), len(searcherRepos))
 			}
 		case Only:
-			if !args.Zoekt.Enabled() {
+			if !zoektEnabled {
 				return nil, common, fmt.Errorf("invalid index:%q (indexed search is not enabled)", index)
 			}
 			common.missing = make([]*types.Repo, len(searcherRepos))
@@ -540,31 +857,94 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		}
 	}
 
+	// Zoekt can't tell comments and strings from code, nor parse Go files,
+	// nor does it know the history of files, support PCRE regexps or
+	// normalize Unicode, so within:, Go AST, modified:, engine:pcre and
+	// normalize: searches of indexed repositories are sent to searcher,
+	// which lexes or parses the files, asks gitserver for their history,
+	// matches with the PCRE engine or normalizes the files.
+	p := args.PatternInfo
+	if (len(p.Within) > 0 || p.GoCall != "" || p.GoSelector != "" || !p.ModifiedSince.IsZero() || p.ModifiedSinceRevision != "" || p.IsPCRE || p.Normalization != "") && len(zoektRepos) > 0 {
+		tr.LazyPrintf("within:, Go AST, modified:, engine:pcre or normalize: search, using searcher for %d indexed repos", len(zoektRepos))
+		searcherRepos = append(searcherRepos, zoektRepos...)
+		zoektRepos = nil
+	}
+
 	var (
 		// TODO: convert wg to an errgroup
 		wg                sync.WaitGroup
 		mu                sync.Mutex
 		searchErr         error
-		unflattened       [][]*FileMatchResolver
+		topK              = newFileMatchTopK(int(args.PatternInfo.FileMatchLimit), args.PatternInfo.Deterministic)
 		flattenedSize     int
 		overLimitCanceled bool // canceled because we were over the limit
+		memoryCanceled    bool // canceled because the results memory budget was exceeded
+		errorCanceled     bool // canceled because of searchErr
+
+		failedErr error // the error of the first repository in common.failed
+		succeeded int   // number of repository revisions searched without a fatal error
 	)
 
-	// addMatches assumes the caller holds mu.
-	addMatches := func(matches []*FileMatchResolver) {
+	memory := getSearchResultsMemory().reservation()
+	defer memory.release()
+
+	// addMatches adds the matches found by searching a number of
+	// repositories (revisions). It assumes the caller holds mu.
+	addMatches := func(matches []*FileMatchResolver, searched int) {
+		common.aggregations.addFileMatches(matches)
+		if len(matches) > 0 && spool != nil {
+			// Exhaustive search: store all matches on disk.
+			common.resultCount += int32(len(matches))
+			if err := spool.add(matches); err != nil && searchErr == nil {
+				searchErr = err
+				errorCanceled = true
+				cancel()
+			}
+			return
+		}
+		if len(matches) > 0 && stream != nil {
+			// Send matches right away instead of ranking them. Holding mu
+			// while sending keeps the stream from being used concurrently.
+			limit := int(args.PatternInfo.FileMatchLimit)
+			if flattenedSize+len(matches) > limit {
+				tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize+len(matches), limit)
+				matches = matches[:limit-flattenedSize]
+				overLimitCanceled = true
+				common.limitHit = true
+				cancel()
+			}
+			if len(matches) > 0 || searched > 0 {
+				common.resultCount += int32(len(matches))
+				flattenedSize += len(matches)
+				stream.Send(SearchEvent{Results: fileMatchesToSearchResults(matches), RepositoriesSearched: searched})
+			}
+			return
+		}
+		if len(matches) > 0 && !memory.reserve(matches) {
+			// Return the results we have so far instead of buffering more
+			// results than the frontend can hold.
+			if !memoryCanceled {
+				tr.LazyPrintf("cancel due to results memory budget")
+				searchResultsMemoryShed.Inc()
+				memoryCanceled = true
+				common.limitHit = true
+				cancel()
+			}
+			return
+		}
 		if len(matches) > 0 {
 			common.resultCount += int32(len(matches))
-			sort.Slice(matches, func(i, j int) bool {
-				a, b := matches[i].uri, matches[j].uri
-				return a > b
-			})
-			unflattened = append(unflattened, matches)
+			// The evicted matches don't use memory anymore, so that the
+			// memory of a search stays bounded by its file match limit.
+			memory.releaseMatches(topK.add(matches))
 			flattenedSize += len(matches)
 
 			// Stop searching once we have found enough matches. This does
 			// lead to potentially unstable result ordering, but is worth
-			// it for the performance benefit.
-			if flattenedSize > int(args.PatternInfo.FileMatchLimit) {
+			// it for the performance benefit. Deterministic searches search
+			// all repositories, since the matches found first depend on the
+			// order in which the repositories are searched.
+			if flattenedSize > int(args.PatternInfo.FileMatchLimit) && !args.PatternInfo.Deterministic {
 				tr.LazyPrintf("cancel due to result size: %d > %d", flattenedSize, args.PatternInfo.FileMatchLimit)
 				overLimitCanceled = true
 				common.limitHit = true
@@ -597,20 +977,52 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 			fetchTimeout = 500 * time.Millisecond
 		}
 
+		// When searching many repos, don't let a single pathological repo use
+		// up the deadline of the whole search.
+		var repoTimeout time.Duration
+		if len(searcherRepos) > 1 && !args.UseFullDeadline {
+			repoTimeout = searchRepoTimeout()
+		}
+
 		if len(searcherRepos) > 0 {
-			// The number of searcher endpoints can change over time. Inform our
-			// limiter of the new limit, which is a multiple of the number of
-			// searchers.
+			// The number of searcher endpoints and the configured
+			// concurrency per searcher can change over time. Inform the
+			// controller of our limiter, whose bounds are a multiple of
+			// both.
 			eps, err := args.SearcherURLs.Endpoints()
-			if err != nil {
+			if err != nil && fallback == nil {
 				return err
 			}
-			textSearchLimiter.SetLimit(len(eps) * 32)
+			if err == nil {
+				c := getSearcherConcurrency()
+				c.setPerSearcher(searcherConcurrencyPerSearcher())
+				c.setSearchers(len(eps))
+			}
 		}
 
+		// Resolve the revisions of all repositories concurrently, so that their
+		// searches do not wait for gitserver while holding a searcher slot, and
+		// look up which repositories are small enough to share a slot.
+		// (mockSearchFilesInRepo replaces the resolution, too.)
+		ctx := ctx
+		var small map[api.RepoID]bool
+		if mockSearchFilesInRepo == nil {
+			ctx = withRevisionPrefetch(ctx, prefetchRevisions(ctx, &wg, searcherRepos))
+			small = smallRepos(ctx, searcherRepos)
+		}
+		slots := &searcherSlots{limiter: textSearchLimiter}
+
+		queued := len(searcherRepos)
+		queuedSearchRepos.add(queued)
+		defer func() { queuedSearchRepos.add(-queued) }()
+		addSearchRepos(ctx, len(searcherRepos))
+
 	outer:
 		for _, repoAllRevs := range searcherRepos {
 			if len(repoAllRevs.Revs) == 0 {
+				queued--
+				queuedSearchRepos.add(-1)
+				doneSearchRepos(ctx, 1)
 				continue
 			}
 
@@ -622,11 +1034,20 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 			if len(revSpecs) >= 2 && !conf.SearchMultipleRevisionsPerRepository() {
 				return errMultipleRevsNotSupported
 			}
+			addSearchRepos(ctx, len(revSpecs)-1)
 
 			for _, rev := range revSpecs {
+				// Stop dispatching as soon as the search is canceled, even if a
+				// searcher request slot is free.
+				select {
+				case <-ctx.Done():
+					break outer
+				default:
+				}
+
 				// Only reason acquire can fail is if ctx is cancelled. So we can stop
 				// looping through searcherRepos.
-				limitCtx, limitDone, acquireErr := textSearchLimiter.Acquire(ctx)
+				limitCtx, limitDone, acquireErr := slots.acquire(ctx, small[repoAllRevs.Repo.ID])
 				if acquireErr != nil {
 					break outer
 				}
@@ -646,19 +1067,65 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				}
 
 				wg.Add(1)
+				activeSearchRepos.add(1)
 				go func(ctx context.Context, done context.CancelFunc) {
 					defer wg.Done()
 					defer done()
+					defer activeSearchRepos.add(-1)
+					defer doneSearchRepos(ctx, 1)
+					if ctx.Err() != nil {
+						// The search was canceled while this goroutine was being
+						// started, so there is no point in calling searcher.
+						return
+					}
+					setSearchGoroutineLabels(ctx, string(repoRev.Repo.Name))
 
-					matches, repoLimitHit, err := searchFilesInRepo(ctx, args.SearcherURLs, repoRev.Repo, repoRev.GitserverRepo(), repoRev.RevSpecs()[0], args.PatternInfo, fetchTimeout)
+					if repoTimeout > 0 {
+						var cancelRepo context.CancelFunc
+						ctx, cancelRepo = context.WithTimeout(ctx, repoTimeout)
+						defer cancelRepo()
+					}
+
+					repoTr, ctx := trace.New(ctx, "searchRepo", repoRev.String())
+					repoTr.SetTag("repo", string(repoRev.Repo.Name))
+					repoTr.SetTag("rev", repoRev.RevSpecs()[0])
+					defer repoTr.Finish()
+
+					timing := &repoTiming{repo: repoRev.Repo, rev: repoRev.RevSpecs()[0]}
+					defer recordRepoTiming(ctx, timing)
+					defer func(start time.Time) { timing.total = time.Since(start) }(time.Now())
+					ctx = withRepoTiming(ctx, timing)
+
+					matches, repoLimitHit, err := func() (_ []*FileMatchResolver, _ bool, err error) {
+						defer recoverSearchPanic("text", string(repoRev.Repo.Name), &err)
+						if err := injectSearchFault(ctx, searchFaultSearchRepo, repoRev.Repo.Name); err != nil {
+							return nil, false, err
+						}
+						return searchFilesInRepo(ctx, args.SearcherURLs, repoRev.Repo, repoRev.GitserverRepo(), repoRev.RevSpecs()[0], args.PatternInfo, fetchTimeout)
+					}()
+					matches = filterSearchIgnored(ctx, matches)
+					matches, filtered := postFilterMatches(ctx, args.Query, matches)
+					repoLimitHit = repoLimitHit || filtered
+					matches, skipped := handleUnprintableMatches(ctx, matches, hexPreviews)
+					setPathMatches(pathMatchRe, matches)
+					timing.err = err != nil
+					repoTr.SetTag("results", len(matches))
+					repoTr.SetTag("limitHit", repoLimitHit)
+					repoTr.SetError(err)
 					if err != nil {
 						tr.LogFields(otlog.String("repo", string(repoRev.Repo.Name)), otlog.Error(err), otlog.Bool("timeout", errcode.IsTimeout(err)), otlog.Bool("temporary", errcode.IsTemporary(err)))
-						log15.Warn("searchFilesInRepo failed", "error", err, "repo", repoRev.Repo.Name)
+						log15.Warn("searchFilesInRepo failed", "error", err, "repo", repoRev.Repo.Name, "searchRequestID", requestID)
 					}
+					accumulateTr, _ := trace.New(ctx, "accumulate", repoRev.String())
+					defer accumulateTr.Finish()
+					defer func(start time.Time) { timing.accumulate = time.Since(start) }(time.Now())
 					mu.Lock()
 					defer mu.Unlock()
+					common.addSkippedFiles(skipped)
+					searched := 0
 					if ctx.Err() == nil {
 						common.searched = append(common.searched, repoRev.Repo)
+						searched = 1
 					}
 					if repoLimitHit {
 						// We did not return all results in this repository.
@@ -674,15 +1141,35 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 							// handle cancellations differently.
 							return
 						}
-						if searchErr == nil {
-							searchErr = errors.Wrapf(err, "failed to search %s", repoRev.String())
-							tr.LazyPrintf("cancel due to error: %v", searchErr)
-							cancel()
+						err = errors.Wrapf(err, "failed to search %s (search request ID %s)", repoRev.String(), requestID)
+						if code, _ := searchErrorCodeOf(err); code == searchErrorPatternInvalid || code == searchErrorSearcherOverloaded || gitserver.IsRevisionNotFound(err) {
+							// The pattern is invalid for every repository, searcher is
+							// rejecting requests, or a revision the user asked for does not
+							// exist (which should have been checked earlier), so stop
+							// searching.
+							if searchErr == nil {
+								searchErr = err
+								errorCanceled = true
+								tr.LazyPrintf("cancel due to error: %v", searchErr)
+								cancel()
+							}
+						} else {
+							// Report the repository as failed, but keep the results of the
+							// other repositories.
+							common.failed = append(common.failed, repoRev.Repo)
+							if failedErr == nil {
+								failedErr = err
+							}
+							tr.LazyPrintf("failed: %v", err)
 						}
+					} else {
+						succeeded++
 					}
-					addMatches(matches)
+					addMatches(matches, searched)
 				}(limitCtx, limitDone) // ends the Go routine for a call to searcher for a repo
 			} // ends the for loop iterating over repo's revs
+			queued--
+			queuedSearchRepos.add(-1)
 		} // ends the for loop iterating over repos
 		return nil
 	} // ends callSearcherOverRepos
@@ -691,18 +1178,32 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 	go func() {
 		// TODO limitHit, handleRepoSearchResult
 		defer wg.Done()
+		setSearchGoroutineLabels(ctx, "")
 		var matches []*FileMatchResolver
 		var reposLimitHit map[string]struct{}
 		var limitHit bool
 		var err error
-		if !args.PatternInfo.IsStructuralPat {
-			matches, limitHit, reposLimitHit, err = zoektSearchHEAD(ctx, args, zoektRepos, false, time.Since)
-		} else {
-			matches, limitHit, reposLimitHit, err = zoektSearchHEADOnlyFiles(ctx, args, zoektRepos, false, time.Since)
-		}
+		addSearchRepos(ctx, len(zoektRepos))
+		defer doneSearchRepos(ctx, len(zoektRepos))
+		func() {
+			defer recoverSearchPanic("indexed", "indexed repositories", &err)
+			if !args.PatternInfo.IsStructuralPat {
+				matches, limitHit, reposLimitHit, err = zoektSearchHEAD(ctx, args, zoektRepos, false, time.Since)
+			} else {
+				matches, limitHit, reposLimitHit, err = zoektSearchHEADOnlyFiles(ctx, args, zoektRepos, false, time.Since)
+			}
+		}()
+		matches = filterSearchIgnored(ctx, matches)
+		matches, filtered := postFilterMatches(ctx, args.Query, matches)
+		limitHit = limitHit || filtered
+		matches, skipped := handleUnprintableMatches(ctx, matches, hexPreviews)
+		setPathMatches(pathMatchRe, matches)
 		mu.Lock()
 		defer mu.Unlock()
+		common.addSkippedFiles(skipped)
+		searched := 0
 		if ctx.Err() == nil {
+			searched = len(zoektRepos)
 			for _, repo := range zoektRepos {
 				common.searched = append(common.searched, repo.Repo)
 				common.indexed = append(common.indexed, repo.Repo)
@@ -727,6 +1228,7 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 		tr.LogFields(otlog.Error(err), otlog.Bool("overLimitCanceled", overLimitCanceled))
 		if err != nil && err != errNoResultsInTimeout && searchErr == nil && !overLimitCanceled {
 			searchErr = err
+			errorCanceled = true
 			tr.LazyPrintf("cancel indexed search due to error: %v", err)
 			cancel()
 		}
@@ -765,7 +1267,7 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 				searchErr = err
 			}
 		} else {
-			addMatches(matches)
+			addMatches(matches, searched)
 		}
 	}()
 
@@ -779,56 +1281,21 @@ func searchFilesInRepos(ctx context.Context, args *search.TextParameters) (res [
 	}
 
 	wg.Wait()
-	if searchErr != nil {
-		return nil, common, searchErr
+	common.degraded = fallback.wasUsed()
+	common.cancellationReason = cancellationReasonOf(parentCtx, overLimitCanceled, errorCanceled)
+	if common.cancellationReason == "" && memoryCanceled {
+		common.cancellationReason = searchCanceledMemoryBudgetExceeded
 	}
-
-	flattened := flattenFileMatches(unflattened, int(args.PatternInfo.FileMatchLimit))
-	return flattened, common, nil
-}
-
-func flattenFileMatches(unflattened [][]*FileMatchResolver, fileMatchLimit int) []*FileMatchResolver {
-	// Return early so we don't have to worry about empty lists in later
-	// calculations.
-	if len(unflattened) == 0 {
-		return nil
+	if common.cancellationReason != "" {
+		tr.SetTag("cancellationReason", string(common.cancellationReason))
 	}
-
-	// We pass in a limit to each repository so we may end up with R*limit
-	// results where R is the number of repositories we searched. To ensure we
-	// have results from all repositories unflattened contains the results per
-	// repo. We then want to create an idempontent order of results, but
-	// ensuring every repo has atleast one result.
-	sort.Slice(unflattened, func(i, j int) bool {
-		a, b := unflattened[i][0].uri, unflattened[j][0].uri
-		return a > b
-	})
-	var flattened []*FileMatchResolver
-	initialPortion := fileMatchLimit / len(unflattened)
-	for _, matches := range unflattened {
-		if initialPortion < len(matches) {
-			flattened = append(flattened, matches[:initialPortion]...)
-		} else {
-			flattened = append(flattened, matches...)
-		}
+	if searchErr == nil && failedErr != nil && succeeded == 0 && len(zoektRepos) == 0 {
+		// Every repository failed, so there are no partial results.
+		searchErr = failedErr
 	}
-	// We now have at most initialPortion from each repo. We add the rest of the
-	// results until we hit our limit.
-	for _, matches := range unflattened {
-		low := initialPortion
-		high := low + (fileMatchLimit - len(flattened))
-		if high <= len(matches) {
-			flattened = append(flattened, matches[low:high]...)
-		} else if low < len(matches) {
-			flattened = append(flattened, matches[low:]...)
-		}
+	if searchErr != nil {
+		return nil, common, searchErr
 	}
-	// Sort again since we constructed flattened by adding more results at the
-	// end.
-	sort.Slice(flattened, func(i, j int) bool {
-		a, b := flattened[i].uri, flattened[j].uri
-		return a > b
-	})
 
-	return flattened
+	return dedupeMirroredFileMatches(topK.results()), common, nil
 }
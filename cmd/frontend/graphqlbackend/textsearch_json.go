@@ -0,0 +1,622 @@
+package graphqlbackend
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// searcherResponse is the response of a searcher (see protocol.Response).
+type searcherResponse struct {
+	Matches     []*FileMatchResolver
+	LimitHit    bool
+	DeadlineHit bool
+	Cached      bool
+}
+
+// decodeSearcherResponseJSON decodes the JSON encoding of a searcher response
+// in data into r. It is equivalent to json.Unmarshal for the responses of
+// searcher, but much faster since the fields of the response are fixed: it
+// does not use reflection, and allocates the results in bulk. Unknown fields
+// are ignored.
+func decodeSearcherResponseJSON(data []byte, r *searcherResponse) error {
+	d := &jsonDecoder{data: data}
+	*r = searcherResponse{}
+	more, err := d.openObject()
+	for more && err == nil {
+		var key []byte
+		if key, err = d.key(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "Matches":
+			r.Matches, err = d.fileMatches()
+		case "LimitHit":
+			r.LimitHit, err = d.bool()
+		case "DeadlineHit":
+			r.DeadlineHit, err = d.bool()
+		case "Cached":
+			r.Cached, err = d.bool()
+		default:
+			err = d.skip()
+		}
+		if err == nil {
+			more, err = d.more('}')
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if d.skipSpace(); d.pos != len(d.data) {
+		return d.errorf("unexpected data after the response")
+	}
+	return nil
+}
+
+func (d *jsonDecoder) fileMatches() ([]*FileMatchResolver, error) {
+	if d.null() {
+		return nil, nil
+	}
+	fms := []*FileMatchResolver{}
+	more, err := d.openArray()
+	for more && err == nil {
+		fm := d.newFileMatch()
+		fms = append(fms, fm)
+		if err = d.fileMatch(fm); err == nil {
+			more, err = d.more(']')
+		}
+	}
+	return fms, err
+}
+
+func (d *jsonDecoder) fileMatch(fm *FileMatchResolver) error {
+	more, err := d.openObject()
+	for more && err == nil {
+		var key []byte
+		if key, err = d.key(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "Path":
+			fm.JPath, err = d.string()
+		case "LineMatches":
+			fm.JLineMatches, err = d.lineMatches()
+		case "MatchCount":
+			var n int64
+			n, err = d.int()
+			fm.MatchCount = int(n)
+		case "LimitHit":
+			fm.JLimitHit, err = d.bool()
+		case "MatchRanges":
+			fm.JMatchRanges, err = d.matchRanges()
+		default:
+			err = d.skip()
+		}
+		if err == nil {
+			more, err = d.more('}')
+		}
+	}
+	return err
+}
+
+func (d *jsonDecoder) lineMatches() ([]*lineMatch, error) {
+	if d.null() {
+		return nil, nil
+	}
+	lms := []*lineMatch{}
+	more, err := d.openArray()
+	for more && err == nil {
+		lm := d.newLineMatch()
+		lms = append(lms, lm)
+		if err = d.lineMatch(lm); err == nil {
+			more, err = d.more(']')
+		}
+	}
+	return lms, err
+}
+
+func (d *jsonDecoder) lineMatch(lm *lineMatch) error {
+	more, err := d.openObject()
+	for more && err == nil {
+		var key []byte
+		if key, err = d.key(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "Preview":
+			lm.JPreview, err = d.string()
+		case "LineNumber":
+			lm.JLineNumber, err = d.int32()
+		case "OffsetAndLengths":
+			lm.JOffsetAndLengths, err = d.offsetAndLengths()
+		case "LimitHit":
+			lm.JLimitHit, err = d.bool()
+		default:
+			err = d.skip()
+		}
+		if err == nil {
+			more, err = d.more('}')
+		}
+	}
+	return err
+}
+
+func (d *jsonDecoder) offsetAndLengths() ([][2]int32, error) {
+	if d.null() {
+		return nil, nil
+	}
+	// The pairs are appended to d.offsetBuf, so that the pairs of many line
+	// matches share an allocation.
+	if len(d.offsetBuf) == cap(d.offsetBuf) {
+		d.offsetBuf = make([][2]int32, 0, 256)
+	}
+	start := len(d.offsetBuf)
+	more, err := d.openArray()
+	for more && err == nil {
+		var ol [2]int32
+		if ol, err = d.offsetAndLength(); err == nil {
+			d.offsetBuf = append(d.offsetBuf, ol)
+			more, err = d.more(']')
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	end := len(d.offsetBuf)
+	return d.offsetBuf[start:end:end], nil
+}
+
+func (d *jsonDecoder) offsetAndLength() (ol [2]int32, err error) {
+	more, err := d.openArray()
+	for i := 0; more && err == nil; i++ {
+		var n int32
+		if n, err = d.int32(); err != nil {
+			break
+		}
+		if i < len(ol) {
+			ol[i] = n
+		}
+		more, err = d.more(']')
+	}
+	return ol, err
+}
+
+func (d *jsonDecoder) matchRanges() ([]protocol.Range, error) {
+	if d.null() {
+		return nil, nil
+	}
+	ranges := []protocol.Range{}
+	more, err := d.openArray()
+	for more && err == nil {
+		var r protocol.Range
+		if err = d.rangeObject(&r); err == nil {
+			ranges = append(ranges, r)
+			more, err = d.more(']')
+		}
+	}
+	return ranges, err
+}
+
+func (d *jsonDecoder) rangeObject(r *protocol.Range) error {
+	more, err := d.openObject()
+	for more && err == nil {
+		var key []byte
+		if key, err = d.key(); err != nil {
+			break
+		}
+		switch string(key) {
+		case "Start":
+			err = d.position(&r.Start)
+		case "End":
+			err = d.position(&r.End)
+		default:
+			err = d.skip()
+		}
+		if err == nil {
+			more, err = d.more('}')
+		}
+	}
+	return err
+}
+
+func (d *jsonDecoder) position(p *protocol.Position) error {
+	more, err := d.openObject()
+	for more && err == nil {
+		var key []byte
+		if key, err = d.key(); err != nil {
+			break
+		}
+		var n int64
+		switch string(key) {
+		case "Line":
+			n, err = d.int()
+			p.Line = int(n)
+		case "Character":
+			n, err = d.int()
+			p.Character = int(n)
+		default:
+			err = d.skip()
+		}
+		if err == nil {
+			more, err = d.more('}')
+		}
+	}
+	return err
+}
+
+// newFileMatch and newLineMatch allocate results in bulk.
+
+func (d *jsonDecoder) newFileMatch() *FileMatchResolver {
+	if len(d.fileMatchBuf) == 0 {
+		d.fileMatchBuf = make([]FileMatchResolver, 32)
+	}
+	fm := &d.fileMatchBuf[0]
+	d.fileMatchBuf = d.fileMatchBuf[1:]
+	return fm
+}
+
+func (d *jsonDecoder) newLineMatch() *lineMatch {
+	if len(d.lineMatchBuf) == 0 {
+		d.lineMatchBuf = make([]lineMatch, 128)
+	}
+	lm := &d.lineMatchBuf[0]
+	d.lineMatchBuf = d.lineMatchBuf[1:]
+	return lm
+}
+
+// jsonDecoder is a minimal decoder of JSON values in data, for decoding
+// messages with a fixed structure without reflection.
+type jsonDecoder struct {
+	data []byte
+	pos  int
+
+	// Storage for results that is not handed out yet.
+	fileMatchBuf []FileMatchResolver
+	lineMatchBuf []lineMatch
+	offsetBuf    [][2]int32
+}
+
+var errUnexpectedEOF = errors.New("unexpected end of JSON input")
+
+func (d *jsonDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid JSON at offset %d: %s", d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *jsonDecoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next byte that is not whitespace.
+func (d *jsonDecoder) peek() (byte, error) {
+	d.skipSpace()
+	if d.pos == len(d.data) {
+		return 0, errUnexpectedEOF
+	}
+	return d.data[d.pos], nil
+}
+
+func (d *jsonDecoder) consume(c byte) error {
+	got, err := d.peek()
+	if err != nil {
+		return err
+	}
+	if got != c {
+		return d.errorf("got %q, want %q", got, c)
+	}
+	d.pos++
+	return nil
+}
+
+// null consumes a null literal if that is the next value, and reports whether
+// it did.
+func (d *jsonDecoder) null() bool {
+	if c, _ := d.peek(); c == 'n' && d.literal("null") == nil {
+		return true
+	}
+	return false
+}
+
+func (d *jsonDecoder) literal(lit string) error {
+	if len(d.data)-d.pos < len(lit) || string(d.data[d.pos:d.pos+len(lit)]) != lit {
+		return d.errorf("invalid literal")
+	}
+	d.pos += len(lit)
+	return nil
+}
+
+// openObject consumes the start of an object, and reports whether it has any
+// fields. A null is an object without fields. Each field is consumed with key
+// and one of the value methods, followed by more.
+func (d *jsonDecoder) openObject() (bool, error) {
+	return d.open('{', '}')
+}
+
+// openArray consumes the start of an array, and reports whether it has any
+// elements. A null is an empty array. Each element is consumed with one of the
+// value methods, followed by more.
+func (d *jsonDecoder) openArray() (bool, error) {
+	return d.open('[', ']')
+}
+
+func (d *jsonDecoder) open(start, end byte) (bool, error) {
+	if d.null() {
+		return false, nil
+	}
+	if err := d.consume(start); err != nil {
+		return false, err
+	}
+	c, err := d.peek()
+	if err != nil {
+		return false, err
+	}
+	if c == end {
+		d.pos++
+		return false, nil
+	}
+	return true, nil
+}
+
+// more consumes the separator after a field or element of an object or array
+// that ends with end, and reports whether another one follows.
+func (d *jsonDecoder) more(end byte) (bool, error) {
+	c, err := d.peek()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case ',':
+		d.pos++
+		return true, nil
+	case end:
+		d.pos++
+		return false, nil
+	}
+	return false, d.errorf("got %q, want ',' or %q", c, end)
+}
+
+// key consumes the key of an object field and returns it. Keys with escape
+// sequences are returned as is, which is fine since none of the keys we
+// decode have any.
+func (d *jsonDecoder) key() ([]byte, error) {
+	start, end, _, err := d.scanString()
+	if err != nil {
+		return nil, err
+	}
+	return d.data[start:end], d.consume(':')
+}
+
+func (d *jsonDecoder) string() (string, error) {
+	start, end, escaped, err := d.scanString()
+	if err != nil {
+		return "", err
+	}
+	if !escaped {
+		return string(d.data[start:end]), nil
+	}
+	s, ok := unescapeJSONString(d.data[start:end])
+	if !ok {
+		d.pos = start
+		return "", d.errorf("invalid escape sequence in string")
+	}
+	return s, nil
+}
+
+// scanString consumes a string and returns the offsets of its contents in
+// d.data, and whether it has escape sequences.
+func (d *jsonDecoder) scanString() (start, end int, escaped bool, err error) {
+	if err := d.consume('"'); err != nil {
+		return 0, 0, false, err
+	}
+	start = d.pos
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case '"':
+			end = d.pos
+			d.pos++
+			return start, end, escaped, nil
+		case '\\':
+			escaped = true
+			d.pos++
+		}
+		d.pos++
+	}
+	return 0, 0, false, errUnexpectedEOF
+}
+
+// unescapeJSONString returns the string with the JSON encoded contents b.
+// Escape sequences are common, since encoding/json escapes <, > and & by
+// default.
+func unescapeJSONString(b []byte) (string, bool) {
+	buf := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c != '\\' {
+			buf = append(buf, c)
+			continue
+		}
+		i++
+		if i == len(b) {
+			return "", false
+		}
+		switch b[i] {
+		case '"', '\\', '/':
+			buf = append(buf, b[i])
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, ok := hexRune(b[i+1:])
+			if !ok {
+				return "", false
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				// A surrogate pair is encoded as two escape sequences. Lone
+				// surrogates are replaced, like encoding/json does.
+				r2, ok := rune(-1), false
+				if i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+					r2, ok = hexRune(b[i+3:])
+				}
+				if r = utf16.DecodeRune(r, r2); ok && r != utf8.RuneError {
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			buf = append(buf, string(r)...)
+		default:
+			return "", false
+		}
+	}
+	return string(buf), true
+}
+
+// hexRune decodes the 4 hex digits at the start of b.
+func hexRune(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+func (d *jsonDecoder) int() (int64, error) {
+	if _, err := d.peek(); err != nil {
+		return 0, err
+	}
+	neg := d.data[d.pos] == '-'
+	if neg {
+		d.pos++
+	}
+	start := d.pos
+	var n int64
+	for d.pos < len(d.data) && '0' <= d.data[d.pos] && d.data[d.pos] <= '9' {
+		digit := int64(d.data[d.pos] - '0')
+		if n > (math.MaxInt64-digit)/10 {
+			return 0, d.errorf("integer out of range")
+		}
+		n = n*10 + digit
+		d.pos++
+	}
+	if d.pos == start {
+		return 0, d.errorf("invalid integer")
+	}
+	if d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case '.', 'e', 'E':
+			return 0, d.errorf("invalid integer")
+		}
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// int32 decodes an integer that fits in an int32. Like json.Unmarshal, it
+// returns an error for integers out of range instead of truncating them.
+func (d *jsonDecoder) int32() (int32, error) {
+	n, err := d.int()
+	if err != nil {
+		return 0, err
+	}
+	if n < math.MinInt32 || n > math.MaxInt32 {
+		return 0, d.errorf("integer out of range")
+	}
+	return int32(n), nil
+}
+
+func (d *jsonDecoder) bool() (bool, error) {
+	c, err := d.peek()
+	if err != nil {
+		return false, err
+	}
+	switch c {
+	case 't':
+		return true, d.literal("true")
+	case 'f':
+		return false, d.literal("false")
+	case 'n':
+		return false, d.literal("null")
+	}
+	return false, d.errorf("invalid boolean")
+}
+
+// skip consumes a value of any type.
+func (d *jsonDecoder) skip() error {
+	c, err := d.peek()
+	if err != nil {
+		return err
+	}
+	switch c {
+	case '{', '[':
+		end := byte('}')
+		if c == '[' {
+			end = ']'
+		}
+		more, err := d.open(c, end)
+		for more && err == nil {
+			if c == '{' {
+				if _, err = d.key(); err != nil {
+					break
+				}
+			}
+			if err = d.skip(); err == nil {
+				more, err = d.more(end)
+			}
+		}
+		return err
+	case '"':
+		_, _, _, err := d.scanString()
+		return err
+	case 't':
+		return d.literal("true")
+	case 'f':
+		return d.literal("false")
+	case 'n':
+		return d.literal("null")
+	}
+	start := d.pos
+	for d.pos < len(d.data) && isNumberByte(d.data[d.pos]) {
+		d.pos++
+	}
+	if d.pos == start {
+		return d.errorf("invalid value")
+	}
+	return nil
+}
+
+func isNumberByte(c byte) bool {
+	return '0' <= c && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E'
+}
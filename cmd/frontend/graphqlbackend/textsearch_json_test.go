@@ -0,0 +1,104 @@
+package graphqlbackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+func TestDecodeSearcherResponseJSON(t *testing.T) {
+	tests := map[string]string{
+		"empty":   `{}`,
+		"null":    `{"Matches":null,"LimitHit":false,"DeadlineHit":false,"Cached":false}`,
+		"escapes": `{"Matches":[{"Path":"a\"b\\c\/dü","LineMatches":[{"Preview":"if a < b && c\t😀 \ud83d x \udc00","LineNumber":3,"OffsetAndLengths":[[1,2],[3,4]],"LimitHit":true}],"MatchCount":2,"LimitHit":true}],"LimitHit":true,"DeadlineHit":true,"Cached":true}`,
+		"ranges":  `{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"a(","LineNumber":0,"OffsetAndLengths":[[1,1]]},{"Preview":")","LineNumber":1,"OffsetAndLengths":[[0,1]]}],"MatchCount":2,"MatchRanges":[{"Start":{"Line":0,"Character":1},"End":{"Line":1,"Character":1}},{"Start":{},"End":{"Line":0,"Character":1,"Unknown":1}}]}]}`,
+		"unknown": ` { "Unknown" : [ {"a": [1, -2.5e3, "x", true, null]} ], "Matches" : [ { "Path" : "a.go" , "Other": {} } ] , "Cached" : true } `,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			var want searcherResponse
+			if err := json.Unmarshal([]byte(data), &want); err != nil {
+				t.Fatal(err)
+			}
+			var got searcherResponse
+			if err := decodeSearcherResponseJSON([]byte(data), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(want)
+				t.Errorf("got  %s\nwant %s", gotJSON, wantJSON)
+			}
+		})
+	}
+
+	for _, data := range []string{
+		``,
+		`[]`,
+		`{"Matches":[{"Path":"a.go"}`,
+		`{"Matches":[{"Path":"a.go"}]}}`,
+		`{"Cached":1}`,
+		`{"Matches":[{"MatchCount":1.5}]}`,
+		`{"Matches":[{"Path":"\x"}]}`,
+		`{"Matches":[{"Path":"a.go"},]}`,
+		`{"Matches":[{"LineMatches":[{"LineNumber":2147483648}]}]}`,
+		`{"Matches":[{"LineMatches":[{"OffsetAndLengths":[[0,-2147483649]]}]}]}`,
+		`{"Matches":[{"MatchCount":99999999999999999999}]}`,
+	} {
+		var r searcherResponse
+		if err := decodeSearcherResponseJSON([]byte(data), &r); err == nil {
+			t.Errorf("expected an error decoding %q", data)
+		}
+	}
+}
+
+func searcherResponseBenchmarkData(b *testing.B) []byte {
+	var r protocol.Response
+	for i := 0; i < 500; i++ {
+		m := protocol.FileMatch{Path: fmt.Sprintf("cmd/frontend/graphqlbackend/file%d.go", i), MatchCount: 10}
+		for j := 0; j < 10; j++ {
+			m.LineMatches = append(m.LineMatches, protocol.LineMatch{
+				Preview:          "	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil && len(r.Matches) > 0 {",
+				LineNumber:       j * 10,
+				OffsetAndLengths: [][2]int{{5, 3}, {20, 4}},
+			})
+		}
+		r.Matches = append(r.Matches, m)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkDecodeSearcherResponseJSON(b *testing.B) {
+	data := searcherResponseBenchmarkData(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var r searcherResponse
+		if err := decodeSearcherResponseJSON(data, &r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeSearcherResponseJSON_encodingJSON(b *testing.B) {
+	data := searcherResponseBenchmarkData(b)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		var r searcherResponse
+		if err := json.Unmarshal(data, &r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package graphqlbackend
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"regexp"
 	"sort"
@@ -15,6 +18,7 @@ import (
 	zoektquery "github.com/google/zoekt/query"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
@@ -312,6 +316,8 @@ func TestSearchFilesInRepos(t *testing.T) {
 			return nil, false, context.DeadlineExceeded
 		case "foo/no-rev":
 			return nil, false, &gitserver.RevisionNotFoundError{Repo: repoName, Spec: "missing"}
+		case "foo/panic":
+			panic("malformed response")
 		default:
 			return nil, false, errors.New("Unexpected repo")
 		}
@@ -369,6 +375,80 @@ func TestSearchFilesInRepos(t *testing.T) {
 	if !gitserver.IsRevisionNotFound(errors.Cause(err)) {
 		t.Fatalf("searching non-existent rev expected to fail with RevisionNotFoundError got: %v", err)
 	}
+
+	// If some repositories fail, the results of the others are returned and
+	// the failed repositories are reported.
+	args.Repos = makeRepositoryRevisions("foo/one", "foo/failing")
+	results, common, err = searchFilesInRepos(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected one result, got %d", len(results))
+	}
+	if v := toRepoNames(common.failed); !reflect.DeepEqual(v, []api.RepoName{"foo/failing"}) {
+		t.Errorf("unexpected failed: %v", v)
+	}
+
+	// A panic while searching a repository only fails that repository.
+	args.Repos = makeRepositoryRevisions("foo/one", "foo/panic")
+	results, common, err = searchFilesInRepos(context.Background(), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected one result, got %d", len(results))
+	}
+	if v := toRepoNames(common.failed); !reflect.DeepEqual(v, []api.RepoName{"foo/panic"}) {
+		t.Errorf("unexpected failed: %v", v)
+	}
+
+	// If all repositories fail, the search fails.
+	args.Repos = makeRepositoryRevisions("foo/failing")
+	if _, _, err = searchFilesInRepos(context.Background(), args); err == nil {
+		t.Fatal("expected searching only failing repositories to fail")
+	}
+}
+
+func TestSearchFilesInRepos_repoTimeout(t *testing.T) {
+	mockSearchFilesInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, info *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
+		if repo.Name == "foo/slow" {
+			<-ctx.Done()
+			return nil, false, ctx.Err()
+		}
+		return []*FileMatchResolver{{uri: "git://" + string(repo.Name) + "?" + rev + "#main.go"}}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	defer func(d time.Duration) { repoSearchTimeout = d }(repoSearchTimeout)
+	repoSearchTimeout = 10 * time.Millisecond
+
+	q, err := query.ParseAndCheck("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &search.TextParameters{
+		PatternInfo: &search.TextPatternInfo{
+			FileMatchLimit: defaultMaxSearchResults,
+			Pattern:        "foo",
+		},
+		Repos:        makeRepositoryRevisions("foo/one", "foo/slow"),
+		Query:        q,
+		Zoekt:        &searchbackend.Zoekt{Client: &fakeSearcher{repos: &zoekt.RepoList{}}},
+		SearcherURLs: endpoint.Static("test"),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	results, common, err := searchFilesInRepos(ctx, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("expected one result, got %d", len(results))
+	}
+	if v := toRepoNames(common.timedout); !reflect.DeepEqual(v, []api.RepoName{"foo/slow"}) {
+		t.Errorf("unexpected timedout: %v", v)
+	}
 }
 
 func TestSearchFilesInRepos_multipleRevsPerRepo(t *testing.T) {
@@ -434,6 +514,152 @@ func TestSearchFilesInRepos_multipleRevsPerRepo(t *testing.T) {
 	}
 }
 
+func TestTextSearchURL_requestID(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(protocol.RequestIDHeader)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	ctx := search.WithRequestID(context.Background(), "abc")
+	if _, _, _, err := textSearchURL(ctx, ts.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got != "abc" {
+		t.Errorf("got request ID header %q, want %q", got, "abc")
+	}
+}
+
+func TestTextSearchURL_protobuf(t *testing.T) {
+	resp := protocol.Response{
+		Matches: []protocol.FileMatch{{
+			Path:        "a.go",
+			MatchCount:  1,
+			LineMatches: []protocol.LineMatch{{Preview: "foo", LineNumber: 2, OffsetAndLengths: [][2]int{{0, 3}}}},
+			MatchRanges: []protocol.Range{{Start: protocol.Position{Line: 2}, End: protocol.Position{Line: 2, Character: 3}}},
+		}, {
+			Path: "b.go",
+		}},
+		LimitHit: true,
+		Cached:   true,
+	}
+	var accept string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		if r.URL.Query().Get("format") == "json" {
+			_ = json.NewEncoder(w).Encode(&resp)
+			return
+		}
+		w.Header().Set("Content-Type", protocol.ProtobufContentType)
+		_, _ = w.Write(resp.MarshalProto())
+	}))
+	defer ts.Close()
+
+	got, limitHit, cached, err := textSearchURL(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(accept, protocol.ProtobufContentType) {
+		t.Errorf("got Accept header %q, want it to include %q", accept, protocol.ProtobufContentType)
+	}
+	if !limitHit || !cached {
+		t.Errorf("got limitHit %v and cached %v, want true", limitHit, cached)
+	}
+
+	// The matches are the same as those decoded from JSON.
+	want, _, _, err := textSearchURL(context.Background(), ts.URL+"?format=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestTextSearchURL_invalidMatches(t *testing.T) {
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	defer func(fast bool) { searcherFastJSON = fast }(searcherFastJSON)
+	for _, body = range []string{
+		`{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"foo","LineNumber":-1}]}]}`,
+		`{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"foo","OffsetAndLengths":[[-1,2]]}]}]}`,
+		`{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"foo","OffsetAndLengths":[[0,-2]]}]}]}`,
+		`{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"foo","OffsetAndLengths":[[2147483647,1]]}]}]}`,
+		`{"Matches":[{"Path":"a.go","LineMatches":[{"Preview":"foo","LineNumber":4294967296}]}]}`,
+	} {
+		for _, searcherFastJSON = range []bool{true, false} {
+			if _, _, _, err := textSearchURL(context.Background(), ts.URL); err == nil || !strings.Contains(err.Error(), "searcher response invalid") {
+				t.Errorf("%s (fast JSON %v): got error %v, want an invalid response error", body, searcherFastJSON, err)
+			}
+		}
+	}
+
+	// encoding/json decodes null matches to nil.
+	if err := checkSearcherMatches([]*FileMatchResolver{nil}); err == nil {
+		t.Error("got nil error for a null file match")
+	}
+	if err := checkSearcherMatches([]*FileMatchResolver{{JPath: "a.go", JLineMatches: []*lineMatch{nil}}}); err == nil {
+		t.Error("got nil error for a null line match")
+	}
+}
+
+func TestTextSearch_drainingSearcher(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+	defer func() { drainingSearchers = &searcherDrainSet{until: map[string]time.Time{}} }()
+
+	var drainingRequests int
+	draining := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		drainingRequests++
+		w.Header().Set(protocol.DrainingHeader, "true")
+		http.Error(w, "searcher is draining", http.StatusServiceUnavailable)
+	}))
+	defer draining.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ok.Close()
+
+	searcherURLs := endpoint.Static(draining.URL, ok.URL)
+	for i := 0; i < 20; i++ {
+		repo := gitserver.Repo{Name: api.RepoName(fmt.Sprintf("repo-%d", i))}
+		if _, _, err := textSearch(context.Background(), searcherURLs, repo, "deadbeef", &search.TextPatternInfo{Pattern: "p"}, time.Second); err != nil {
+			t.Fatalf("%s: %v", repo.Name, err)
+		}
+	}
+	// Once a searcher announced that it is draining, it is sent no more
+	// requests.
+	if drainingRequests != 1 {
+		t.Errorf("got %d requests to the draining searcher, want 1", drainingRequests)
+	}
+}
+
+func TestTextSearch_trimsRegexpWildcards(t *testing.T) {
+	defer conf.Mock(nil)
+	conf.Mock(&conf.Unified{})
+
+	fake := &FakeSearcherClient{}
+	defer func(c SearcherClient) { DefaultSearcherClient = c }(DefaultSearcherClient)
+	DefaultSearcherClient = fake
+
+	p := &search.TextPatternInfo{Pattern: ".*foo.*", IsRegExp: true}
+	if _, _, err := textSearch(context.Background(), endpoint.Static("http://searcher"), gitserver.Repo{Name: "foo"}, "deadbeef", p, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.Requests()[0].Pattern; got != "foo" {
+		t.Errorf("got searcher pattern %q, want %q", got, "foo")
+	}
+	// The pattern info is unchanged for the other users of it.
+	if p.Pattern != ".*foo.*" {
+		t.Errorf("got pattern %q, want %q", p.Pattern, ".*foo.*")
+	}
+}
+
 func TestRepoShouldBeSearched(t *testing.T) {
 	mockTextSearch = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.TextPatternInfo, fetchTimeout time.Duration) (matches []*FileMatchResolver, limitHit bool, err error) {
 		repoName := repo.Name
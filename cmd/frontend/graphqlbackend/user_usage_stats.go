@@ -96,5 +96,7 @@ func (*schemaResolver) LogEvent(ctx context.Context, args *struct {
 		UserCookieID: args.UserCookieID,
 		Source:       args.Source,
 		Argument:     payload,
+		// Tag the event for comparing the arms of search experiments.
+		ExperimentArms: searchExperimentArms(ctx),
 	})
 }
@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"net/url"
 	"regexp/syntax"
+	"sort"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -17,6 +17,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/gituri"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
 	"github.com/sourcegraph/sourcegraph/internal/symbols/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/trace"
 )
@@ -45,9 +46,60 @@ func zoektResultCountFactor(numRepos int, query *search.TextPatternInfo) int {
 	return k
 }
 
+// sortZoektFilesDeterministic sorts files by score (as Zoekt does), and files
+// with the same score by repository and name.
+func sortZoektFilesDeterministic(files []zoekt.FileMatch) {
+	sort.Slice(files, func(i, j int) bool {
+		a, b := &files[i], &files[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		return a.FileName < b.FileName
+	})
+}
+
+// filterZoektIdentifierMatches removes the line fragments of files that are
+// not identifiers (with the given word characters) in the language of their
+// file (Zoekt searches identifier searches for the pattern anywhere), and the
+// files without matches left.
+func filterZoektIdentifierMatches(files []zoekt.FileMatch, wordCharacters identifier.WordCharacters, subTokens bool) []zoekt.FileMatch {
+	filtered := files[:0]
+	for _, file := range files {
+		m := wordCharacters.Matcher(file.Language, subTokens)
+		if file.Language == "" {
+			m = wordCharacters.MatcherForPath(file.FileName, subTokens)
+		}
+		lines := file.LineMatches[:0]
+		for _, l := range file.LineMatches {
+			if l.FileName {
+				lines = append(lines, l)
+				continue
+			}
+			fragments := l.LineFragments[:0]
+			for _, f := range l.LineFragments {
+				if m.Match(l.Line, f.LineOffset, f.LineOffset+f.MatchLength) {
+					fragments = append(fragments, f)
+				}
+			}
+			if len(fragments) > 0 {
+				l.LineFragments = fragments
+				lines = append(lines, l)
+			}
+		}
+		if len(lines) > 0 {
+			file.LineMatches = lines
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
 func zoektSearchOpts(k int, query *search.TextPatternInfo) zoekt.SearchOptions {
 	searchOpts := zoekt.SearchOptions{
-		MaxWallTime:            defaultTimeout,
+		MaxWallTime:            searchDefaultTimeout(),
 		ShardMaxMatchCount:     100 * k,
 		TotalMaxMatchCount:     100 * k,
 		ShardMaxImportantMatch: 15 * k,
@@ -69,6 +121,25 @@ func zoektSearchOpts(k int, query *search.TextPatternInfo) zoekt.SearchOptions {
 		searchOpts.MaxWallTime *= time.Duration(3 * float64(query.FileMatchLimit) / float64(defaultMaxSearchResults))
 	}
 
+	if query.FirstMatchPerRepo {
+		// Like the searches of repohasfile:, stop searching each shard (a
+		// repository, unless it is large) after its first match.
+		searchOpts.ShardMaxMatchCount = 1
+		searchOpts.ShardMaxImportantMatch = 1
+		searchOpts.TotalMaxMatchCount = math.MaxInt32
+		searchOpts.TotalMaxImportantMatch = math.MaxInt32
+	}
+
+	if query.Deterministic {
+		// Zoekt stops searching shards once the total limits are hit, and
+		// truncates the files to display before ordering files with the same
+		// score, so the results would depend on the order in which the shards
+		// finished. The matches per shard are still limited.
+		searchOpts.TotalMaxMatchCount = math.MaxInt32
+		searchOpts.TotalMaxImportantMatch = math.MaxInt32
+		searchOpts.MaxDocDisplayCount = 0
+	}
+
 	return searchOpts
 }
 
@@ -151,6 +222,10 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 		return nil, false, nil, errNoResultsInTimeout
 	}
 	limitHit = resp.FilesSkipped+resp.ShardsSkipped > 0
+	if args.PatternInfo.FirstMatchPerRepo {
+		// The files skipped after the first match of each shard don't matter.
+		limitHit = resp.ShardsSkipped > 0
+	}
 	// Repositories that weren't fully evaluated because they hit the Zoekt or Sourcegraph file match limits.
 	reposLimitHit = make(map[string]struct{})
 	if limitHit {
@@ -168,6 +243,15 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 		return nil, false, nil, nil
 	}
 
+	if args.PatternInfo.IsIdentifierMatch && !isSymbol {
+		if resp.Files = filterZoektIdentifierMatches(resp.Files, args.PatternInfo.WordCharacters, args.PatternInfo.IdentifierSubTokens); len(resp.Files) == 0 {
+			return nil, limitHit, reposLimitHit, nil
+		}
+	}
+	if args.PatternInfo.Deterministic {
+		sortZoektFilesDeterministic(resp.Files)
+	}
+
 	maxLineMatches := 25 + k
 	maxLineFragmentMatches := 3 + k
 	if limit := int(args.PatternInfo.FileMatchLimit); len(resp.Files) > limit {
@@ -201,7 +285,7 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 		}
 		repoRev := repoMap[api.RepoName(strings.ToLower(string(file.Repository)))]
 		inputRev := repoRev.RevSpecs()[0]
-		baseURI := &gituri.URI{URL: url.URL{Scheme: "git://", Host: string(repoRev.Repo.Name), RawQuery: "?" + url.QueryEscape(inputRev)}}
+		baseURI := gituri.New(repoRev.Repo.Name, inputRev, "")
 		lines := make([]*lineMatch, 0, len(file.LineMatches))
 		symbols := []*searchSymbolResult{}
 		for _, l := range file.LineMatches {
@@ -254,6 +338,7 @@ func zoektSearchHEAD(ctx context.Context, args *search.TextParameters, repos []*
 			Repo:         repoRev.Repo,
 			CommitID:     api.CommitID(file.Version),
 		}
+		truncatePreviews(matches[i])
 	}
 
 	return matches, limitHit, reposLimitHit, nil
@@ -19,6 +19,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/db/dbtesting"
 	"github.com/sourcegraph/sourcegraph/internal/search"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/schema"
 )
@@ -306,3 +307,66 @@ func TestZoektSingleIndexedRepo(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterZoektIdentifierMatches(t *testing.T) {
+	line := []byte("h := requestHandler(valid)")
+	files := []zoekt.FileMatch{
+		{
+			FileName: "a.go",
+			Language: "Go",
+			LineMatches: []zoekt.LineMatch{{
+				Line: line,
+				LineFragments: []zoekt.LineFragmentMatch{
+					{LineOffset: 12, MatchLength: 7}, // Handler
+					{LineOffset: 23, MatchLength: 2}, // id
+				},
+			}},
+		},
+		{
+			FileName:    "handler.go",
+			LineMatches: []zoekt.LineMatch{{FileName: true, Line: []byte("handler.go")}},
+		},
+	}
+	cloneFiles := func() []zoekt.FileMatch {
+		c := make([]zoekt.FileMatch, len(files))
+		for i, f := range files {
+			c[i] = f
+			c[i].LineMatches = append([]zoekt.LineMatch(nil), f.LineMatches...)
+			for j, l := range c[i].LineMatches {
+				c[i].LineMatches[j].LineFragments = append([]zoekt.LineFragmentMatch(nil), l.LineFragments...)
+			}
+		}
+		return c
+	}
+
+	// Without sub-tokens, only the file name match is left.
+	if got := filterZoektIdentifierMatches(cloneFiles(), nil, false); len(got) != 1 || got[0].FileName != "handler.go" {
+		t.Errorf("got files %+v, want only handler.go", got)
+	}
+
+	got := filterZoektIdentifierMatches(cloneFiles(), nil, true)
+	if len(got) != 2 {
+		t.Fatalf("got %d files, want 2", len(got))
+	}
+	if fragments := got[0].LineMatches[0].LineFragments; len(fragments) != 1 || fragments[0].LineOffset != 12 {
+		t.Errorf("got fragments %+v, want only the one of Handler", fragments)
+	}
+
+	// "-" is a word character in CSS, unless the word characters say otherwise.
+	css := func() []zoekt.FileMatch {
+		return []zoekt.FileMatch{{
+			FileName: "a.css",
+			Language: "CSS",
+			LineMatches: []zoekt.LineMatch{{
+				Line:          []byte("#box-id {}"),
+				LineFragments: []zoekt.LineFragmentMatch{{LineOffset: 5, MatchLength: 2}},
+			}},
+		}}
+	}
+	if got := filterZoektIdentifierMatches(css(), nil, false); len(got) != 0 {
+		t.Errorf("got files %+v, want none", got)
+	}
+	if got := filterZoektIdentifierMatches(css(), identifier.WordCharacters{"*": ""}, false); len(got) != 1 {
+		t.Errorf("got %d files, want 1", len(got))
+	}
+}
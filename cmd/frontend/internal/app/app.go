@@ -35,6 +35,7 @@ func NewHandler() http.Handler {
 	r.Get(router.RobotsTxt).Handler(trace.TraceRoute(http.HandlerFunc(robotsTxt)))
 	r.Get(router.Favicon).Handler(trace.TraceRoute(http.HandlerFunc(favicon)))
 	r.Get(router.OpenSearch).Handler(trace.TraceRoute(http.HandlerFunc(openSearch)))
+	r.Get(router.OpenSearchSuggestions).Handler(trace.TraceRoute(http.HandlerFunc(openSearchSuggestions)))
 
 	r.Get(router.RepoBadge).Handler(trace.TraceRoute(errorutil.Handler(serveRepoBadge)))
 
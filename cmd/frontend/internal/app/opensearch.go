@@ -2,13 +2,19 @@ package app
 
 import (
 	"bytes"
+	"encoding/json"
 	"html/template"
 	"net/http"
 
 	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
 )
 
+// maxOpenSearchSuggestions is the number of suggestions returned to browsers,
+// which only show a few of them.
+const maxOpenSearchSuggestions = 8
+
 var openSearchDescription = template.Must(template.New("").Parse(`
 <OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/" xmlns:moz="http://www.mozilla.org/2006/browser/search/">
   <ShortName>{{.SiteName}}</ShortName>
@@ -16,20 +22,27 @@ var openSearchDescription = template.Must(template.New("").Parse(`
   <InputEncoding>UTF-8</InputEncoding>
   <Image height="16" width="16">data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAACAAAAAgCAYAAABzenr0AAAHEklEQVR42o2Xf4wV1RXHz8wsb9sVEpBdlhXbYDRqrYqNkaY2XWMKStNUjRoTKwi7b0NWghWladP0n4aEVtMWbQ2tTeQvTaFdWN4Pdg2giG3UtDamusZQqcUgWqEpb9+vmXnz3tzT75l37nN4hayTfN65c99kvt977r0zZ+hCR5gdoHB0QKKj7fvBKcDgOLhd+oETbOjH//0kR56Cblzg7CGf/gikrwDmPMKxQRF1gcRVgBvZJQbEQEzUwfXAXqc3D4VEGNEBdIAiEXXTxuY85IaN0SUukPaEiDZGB6JwNBFvqImn5X/g+RuWiYiQiOcw2hwFGbQvB4ukXwzlqEp5UCB/DgPZJQQRB4iBwyKIvhaiwXlLDbxVfuAip75+IVW/e6UV94DEb4Jj6GsilsBPXiGmaWp+tiyEbWEPSHxCp6AJDDJh1EALXNPI9hM/Qk5KfBgEGDEDA1o4Z7AlrybnzsBIf9rAmsSAZkBp6oIcD0aW0rGVP0a6k7m/BpwRccSmGJCo538rIgNTxKkMFMsWF3gaiQ4y+dlBRw0sBmd01HHbwEBT18ME/4hwyE39Idz4PRVrSVQDsfa983v6D+2japeBAyparKANpqokZhZOnnTfeGzY1XUw1TbQngZEGJEMDJ7iR6lvJ3Ev0vp6WyhsWnGNDZ2CHUDXSZjIi6BDz58UwZvAA+BGKsy6gJbvnqE3t3y9h79NVBsb+n7Y3glNRMmAgbhEbo7PH95L/HzBqYtYdI54Kv1ggRpw8p01sL8ko98FGMTKDNgupjb+auc8f2SAzLr5X2mM9jOMGD+7VDIAlpp4/HP8l8smTk0St2Ag7hJvqfgJcKkVB+dMwVbAyERTiYGYMcq7dKC2fXDyxM2Yhvd5XYbN+oviBkbffHgez9y43ewjhqCIBx3x1LyfBdfb1AP7wOoY+JMKtiQCo+0oZYZpqh5dvmcm3PrLn/MrW9eYaOMC/mTF98w0MRecIN7vBibnYp7FBMR18TXAqvS8p0dvDbygIiJorIluM8nUTIeM3WFoKuArd7/Jd2dL/PhVGKXn8xGM9pCmPzHjBAzWqmAP+hGBzn3awN2AVVDEmtgNMUibSKJTnDU9hbMskaYbTEcrMDTLlzxT4btGa/z4l3yT8wIjZl6lcByRRu6p99J02c3sLRO9F2DRV0kWuGB3gZgYBafbQmUr3Bm5zQjaNrJbnGUvj+sLOD+EeLQsMb70NxW+9bHqid6/zt40tKeSIT5Li/IQf6EmWp4+a8giP4466kP7dvA78CFICVZiN48MFKRtzQCN0o//JYoJQ0fKem35n+BJ8A3cP0OFsujYQbuSDXSUOiftCyqCmFmNbDyDeNIRMy/jxgfLJi2mJtLR/m/EtO3TwbwPngK3QKdXjMjg26L55ETwIOo50zAxgz4+RnSm1DtYKK++9dFqacmzuOGL5VjSDTNiQqZB1gSLSHqaVDhO7yZgzXwA7sA1ug7y+hjG6B++rZ6kaMGeirf23nrvJAX0Zwq3vUQh78sE8bYVvvnWQzUe2IUbHobwy1WWBZleoKmFez4zLTXqg5WUPmy5xMTJ1pFtg630Q2wrBnGRQnMYRo6AyYzP266N+DubT/NlE3+HiaizRUWsewGfu5DLkbZ3d4sLKp7EdYD1fW4AqxlToEZ8kJpc+tp9xh9fyAd/cKfZ9NTTvGwCe22q3kxt7U/NFDsmbBYOWfE0nsbbQATx2D5W24i435okNseHN+Nx3MPRyKDhB/tavK5X3hWv0VFe6RZKv9aFl97asX3c61q4p9uAq/EGUBLBlLgR8aJTk2d/+PZ1Oz42m+ZxMDJk8HaUF1RcHxviaLS/bNb2DX248Qpa8dxrvbIFdSseV1Ervh1GbPrD9FvqC+ADfa+3rLjGqODU+A/EP+UtdC9ex1IftJJXc7tUayFyZWzZfbwGD77NN3if339adpowD6I36yv/Wtn2mBInNf+hC6S9V8WjbvF8Euu5aOxi8jdcshziPsQZsVOmadX0Wxihtx/5ak9P4b+ePAGlyFFRu+1dkOinR78oVc+ZlLgtKl4teqU+fpaInyOC0Ovtanmgu1p+p75+setvWEy17BDE/P8v+9Lvg7a41PH+fLRP2ZoOyLxHen4MDOaoQf8afigTohCF0M8w+k61HAI1YMB1gIAb4NvhQsf5pmAHsKOOVfwTcLXdJWaThzoxuflqrZbj81TLm9SA1xiZw0COQpsFiRmwE9RABN5o74qwU1TUH/yirZYXgX+nqmVdB4mBXWgT8NCeOwMW/Z6T9gBYDlNegTi9TiCc3NhWy3k1EIUQRruh57+Q64AXZvvnMuB3mQjc/VQXM4IIu9pWA/3pD9c15/lwrYIv2zVAn+XIqXgKR6GCIlNlP1zFhHya61pYCz7CuUH8B1il4k6IijrIXngK/gfdr57PmtoVUQAAAABJRU5ErkJggg==</Image>
   <Url type="text/html" method="GET" template="{{.SearchURL}}" />
+  <Url type="application/x-suggestions+json" method="GET" template="{{.SuggestionsURL}}" />
+  <Url type="application/json" method="GET" template="{{.APISearchURL}}" />
+  <moz:SearchForm>{{.BaseURL}}/search</moz:SearchForm>
 </OpenSearchDescription>
 `))
 
 func openSearch(w http.ResponseWriter, r *http.Request) {
 	type vars struct {
-		SiteName  string
-		BaseURL   string
-		SearchURL string
+		SiteName       string
+		BaseURL        string
+		SearchURL      string
+		SuggestionsURL string
+		APISearchURL   string
 	}
 	externalURL := globals.ExternalURL()
 	externalURLStr := externalURL.String()
 	data := vars{
-		BaseURL:   externalURLStr,
-		SearchURL: externalURLStr + "/search?q={searchTerms}",
+		BaseURL:        externalURLStr,
+		SearchURL:      externalURLStr + "/search?q={searchTerms}",
+		SuggestionsURL: externalURLStr + "/-/opensearch/suggestions?q={searchTerms}",
+		APISearchURL:   externalURLStr + "/.api/search?q={searchTerms}",
 	}
 	if externalURLStr == "https://sourcegraph.com" {
 		data.SiteName = "Sourcegraph"
@@ -47,3 +60,32 @@ func openSearch(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/xml")
 	_, _ = buf.WriteTo(w)
 }
+
+// openSearchSuggestions returns search suggestions in the OpenSearch
+// suggestions format, so that browsers and launchers that use Sourcegraph as a
+// keyword search engine suggest repositories, files and symbols while the user
+// types. The response is an array of the query, the suggested queries, their
+// descriptions and their URLs.
+func openSearchSuggestions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	completions, descriptions, urls := []string{}, []string{}, []string{}
+	if q != "" {
+		suggestions, err := graphqlbackend.KeywordSearchSuggestions(r.Context(), q, maxOpenSearchSuggestions)
+		if err != nil {
+			// Browsers ignore failed suggestion requests, so there is
+			// nothing better to return than no suggestions.
+			log15.Warn("Failed to get OpenSearch suggestions", "query", q, "err", err)
+		}
+		externalURL := globals.ExternalURL().String()
+		for _, s := range suggestions {
+			completions = append(completions, s.Query)
+			descriptions = append(descriptions, s.Description)
+			urls = append(urls, externalURL+s.URL)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-suggestions+json")
+	if err := json.NewEncoder(w).Encode([]interface{}{q, completions, descriptions, urls}); err != nil {
+		log15.Error("Failed to write OpenSearch suggestions", "err", err)
+	}
+}
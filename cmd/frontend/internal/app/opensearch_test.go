@@ -0,0 +1,32 @@
+package app
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenSearch(t *testing.T) {
+	rec := httptest.NewRecorder()
+	openSearch(rec, httptest.NewRequest("GET", "/opensearch.xml", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<Url type="application/x-suggestions+json" method="GET" template="http://example.com/-/opensearch/suggestions?q={searchTerms}" />`,
+		`<Url type="application/json" method="GET" template="http://example.com/.api/search?q={searchTerms}" />`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got descriptor %s, want it to contain %s", body, want)
+		}
+	}
+}
+
+func TestOpenSearchSuggestions_emptyQuery(t *testing.T) {
+	rec := httptest.NewRecorder()
+	openSearchSuggestions(rec, httptest.NewRequest("GET", "/-/opensearch/suggestions?q=", nil))
+	if got, want := rec.Body.String(), "[\"\",[],[],[]]\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := rec.Header().Get("Content-Type"), "application/x-suggestions+json"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+}
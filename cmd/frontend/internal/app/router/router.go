@@ -15,7 +15,8 @@ const (
 	RobotsTxt = "robots-txt"
 	Favicon   = "favicon"
 
-	OpenSearch = "opensearch"
+	OpenSearch            = "opensearch"
+	OpenSearchSuggestions = "opensearch.suggestions"
 
 	RepoBadge = "repo.badge"
 
@@ -62,6 +63,7 @@ func newRouter() *mux.Router {
 	base.Path("/robots.txt").Methods("GET").Name(RobotsTxt)
 	base.Path("/favicon.ico").Methods("GET").Name(Favicon)
 	base.Path("/opensearch.xml").Methods("GET").Name(OpenSearch)
+	base.Path("/-/opensearch/suggestions").Methods("GET").Name(OpenSearchSuggestions)
 
 	base.Path("/-/logout").Methods("GET").Name(Logout)
 
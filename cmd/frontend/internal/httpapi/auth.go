@@ -68,6 +68,15 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 				requiredScope = authz.ScopeSiteAdminSudo
 			}
 			subjectUserID, err := db.AccessTokens.Lookup(r.Context(), token, requiredScope)
+			if err == db.ErrAccessTokenNotFound && sudoUser == "" {
+				// The token may be a search-only token, which is only accepted by the GraphQL
+				// API. Its restrictions are enforced by the GraphQL layer.
+				var searchScope *authz.SearchScope
+				subjectUserID, searchScope, err = lookupSearchToken(r, token)
+				if err == nil {
+					r = r.WithContext(authz.WithSearchScope(r.Context(), searchScope))
+				}
+			}
 			if err != nil {
 				log15.Error("Invalid access token.", "token", token, "err", err)
 				http.Error(w, "Invalid access token.", http.StatusUnauthorized)
@@ -111,3 +120,30 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// searchTokenPath is the only path that accepts search-only access tokens.
+const searchTokenPath = "/.api/graphql"
+
+// lookupSearchToken validates a search-only access token and returns its subject
+// user and search restrictions.
+//
+// 🚨 SECURITY: Search-only tokens must never authenticate requests other than
+// GraphQL search requests.
+func lookupSearchToken(r *http.Request, token string) (subjectUserID int32, scope *authz.SearchScope, err error) {
+	if r.URL.Path != searchTokenPath {
+		return 0, nil, db.ErrAccessTokenNotFound
+	}
+	subjectUserID, err = db.AccessTokens.Lookup(r.Context(), token, authz.ScopeSearch)
+	if err != nil {
+		return 0, nil, err
+	}
+	at, err := db.AccessTokens.GetByToken(r.Context(), token)
+	if err != nil {
+		return 0, nil, err
+	}
+	scope, err = authz.ParseSearchScope(at.Scopes)
+	if err != nil {
+		return 0, nil, err
+	}
+	return subjectUserID, scope, nil
+}
@@ -280,4 +280,29 @@ func TestAccessTokenAuthMiddleware(t *testing.T) {
 			t.Error("!calledUsersGetByUsername")
 		}
 	})
+
+	t.Run("valid search token", func(t *testing.T) {
+		mockSearchToken := func() {
+			db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+				if requiredScope != authz.ScopeSearch {
+					return 0, db.ErrAccessTokenNotFound
+				}
+				return 123, nil
+			}
+			db.Mocks.AccessTokens.GetByToken = func(tokenHexEncoded string) (*db.AccessToken, error) {
+				return &db.AccessToken{SubjectUserID: 123, Scopes: []string{authz.ScopeSearch, "search:repo:^foo$"}}, nil
+			}
+		}
+		defer func() { db.Mocks = db.MockStores{} }()
+
+		mockSearchToken()
+		req, _ := http.NewRequest("POST", "/.api/graphql", nil)
+		req.Header.Set("Authorization", "token abcdef")
+		checkHTTPResponse(t, req, http.StatusOK, "user 123")
+
+		// Search tokens are only accepted by the GraphQL API.
+		req, _ = http.NewRequest("GET", "/.api/repos/foo", nil)
+		req.Header.Set("Authorization", "token abcdef")
+		checkHTTPResponse(t, req, http.StatusUnauthorized, "Invalid access token.\n")
+	})
 }
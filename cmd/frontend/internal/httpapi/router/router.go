@@ -15,6 +15,10 @@ const (
 
 	Registry = "registry"
 
+	Search       = "search"
+	SearchStream = "search.stream"
+	SearchExport = "search.export"
+
 	RepoShield  = "repo.shield"
 	RepoRefresh = "repo.refresh"
 	Telemetry   = "telemetry"
@@ -49,6 +53,7 @@ const (
 	ReposListEnabled       = "internal.repos.list-enabled"
 	Configuration          = "internal.configuration"
 	SearchConfiguration    = "internal.search-configuration"
+	SearchCandidateFiles   = "internal.search.candidate-files"
 	ExternalServiceConfigs = "internal.external-services.configs"
 	ExternalServicesList   = "internal.external-services.list"
 )
@@ -69,6 +74,9 @@ func New(base *mux.Router) *mux.Router {
 	base.Path("/lsif/upload").Methods("POST").Name(LSIFUpload)
 	base.Path("/src-cli/version").Methods("GET").Name(SrcCliVersion)
 	base.Path("/src-cli/{rest:.*}").Methods("GET").Name(SrcCliDownload)
+	base.Path("/search").Methods("GET", "POST").Name(Search)
+	base.Path("/search/stream").Methods("GET", "POST").Name(SearchStream)
+	base.Path("/search/export/{ID}").Methods("GET").Name(SearchExport)
 
 	// repo contains routes that are NOT specific to a revision. In these routes, the URL may not contain a revspec after the repo (that is, no "github.com/foo/bar@myrevspec").
 	repoPath := `/repos/` + routevar.Repo
@@ -119,6 +127,7 @@ func NewInternal(base *mux.Router) *mux.Router {
 	base.Path("/repos/{RepoName:.*}").Methods("POST").Name(ReposGetByName)
 	base.Path("/configuration").Methods("POST").Name(Configuration)
 	base.Path("/search/configuration").Methods("GET").Name(SearchConfiguration)
+	base.Path("/search/candidate-files").Methods("POST").Name(SearchCandidateFiles)
 	base.Path("/telemetry").Methods("POST").Name(Telemetry)
 	addRegistryRoute(base)
 	addGraphQLRoute(base)
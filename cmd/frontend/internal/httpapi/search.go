@@ -0,0 +1,372 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/replace"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// searchRequest is a request to the REST search API. It is decoded from the
+// JSON body of POST requests, and from the URL query of GET requests (where
+// the query is the "q" parameter).
+//
+// A request either contains a complete search query (Query), or describes
+// the search like the searcher protocol's PatternInfo, in which case the
+// query is constructed from the fields. Both can be combined to add filters
+// that have no field (e.g. "lang:go") to Query.
+type searchRequest struct {
+	Query          string  `schema:"q"`
+	Version        string  `schema:"version"`
+	PatternType    *string `schema:"patternType"`
+	VersionContext *string `schema:"versionContext"`
+
+	// Pattern is the search pattern, which is a regular expression if
+	// IsRegExp is true, a Comby pattern if IsStructuralPat is true and a
+	// fixed string otherwise.
+	Pattern         string
+	IsRegExp        bool
+	IsStructuralPat bool
+	IsCaseSensitive bool
+
+	// IncludePatterns and ExcludePattern are regular expressions that the
+	// paths of matching files must all match and must not match,
+	// respectively (like the file: and -file: filters).
+	IncludePatterns []string
+	ExcludePattern  string
+
+	// Repos are the names of the repositories to search. If empty, all
+	// repositories are searched.
+	Repos []string
+
+	// FileMatchLimit limits the number of file matches returned.
+	FileMatchLimit int
+
+	// Replacement, if set, is a replacement for the matches of Pattern (see
+	// replace.New). Each line match of the response then has a Diff that
+	// previews the replacement. Nothing is changed.
+	Replacement *string `schema:"replacement"`
+
+	// IncludeContent is whether each file match includes the content of the
+	// file at the searched commit (see searchContentLoader), so that clients
+	// can show the files without requesting them separately.
+	IncludeContent bool `schema:"includeContent"`
+
+	// OffsetUnit is the unit of the OffsetAndLengths of line matches (see
+	// search.ParseOffsetUnit). By default, they are measured in characters.
+	OffsetUnit string `schema:"offsetUnit"`
+
+	// Format is the format of the response: a JSON document (searchResponse)
+	// by default, or newline-delimited JSON (searchNDJSONLine) if "ndjson".
+	Format string `schema:"format"`
+}
+
+// searchArgs returns the arguments of the search described by req.
+func (req *searchRequest) searchArgs() (*graphqlbackend.SearchArgs, error) {
+	args := &graphqlbackend.SearchArgs{
+		Version:        req.Version,
+		PatternType:    req.PatternType,
+		VersionContext: req.VersionContext,
+	}
+	if args.Version == "" {
+		args.Version = "V2"
+	}
+
+	var parts []string
+	if req.Query != "" {
+		parts = append(parts, req.Query)
+	}
+	if len(req.Repos) > 0 {
+		patterns := make([]string, len(req.Repos))
+		for i, repo := range req.Repos {
+			patterns[i] = regexp.QuoteMeta(repo)
+		}
+		parts = append(parts, "repo:"+quoteSearchFilterValue("^("+strings.Join(patterns, "|")+")$"))
+	}
+	for _, p := range req.IncludePatterns {
+		parts = append(parts, "file:"+quoteSearchFilterValue(p))
+	}
+	if req.ExcludePattern != "" {
+		parts = append(parts, "-file:"+quoteSearchFilterValue(req.ExcludePattern))
+	}
+	if req.IsCaseSensitive {
+		parts = append(parts, "case:yes")
+	}
+	if req.FileMatchLimit > 0 {
+		parts = append(parts, "count:"+strconv.Itoa(req.FileMatchLimit))
+	}
+	if req.Pattern != "" {
+		if args.PatternType == nil {
+			patternType := "literal"
+			if req.IsStructuralPat {
+				patternType = "structural"
+			} else if req.IsRegExp {
+				patternType = "regexp"
+			}
+			args.PatternType = &patternType
+		}
+		parts = append(parts, req.Pattern)
+	}
+	if _, err := req.replacer(); err != nil {
+		return nil, err
+	}
+	if req.Format != "" && req.Format != "json" && req.Format != "ndjson" {
+		return nil, fmt.Errorf("invalid format %q (valid values are: json, ndjson)", req.Format)
+	}
+	if _, err := search.ParseOffsetUnit(req.OffsetUnit); err != nil {
+		return nil, err
+	}
+	if req.IncludeContent && req.Format == "ndjson" {
+		return nil, fmt.Errorf("file contents are not included in ndjson responses")
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("the search request has no query or pattern")
+	}
+	args.Query = strings.Join(parts, " ")
+	return args, nil
+}
+
+// replacer returns the replacer of the matches of req's pattern, or nil if req
+// has no replacement.
+func (req *searchRequest) replacer() (*replace.Replacer, error) {
+	if req.Replacement == nil {
+		return nil, nil
+	}
+	return replace.New(&search.TextPatternInfo{
+		Pattern:         req.Pattern,
+		IsRegExp:        req.IsRegExp,
+		IsStructuralPat: req.IsStructuralPat,
+		IsCaseSensitive: req.IsCaseSensitive,
+	}, *req.Replacement)
+}
+
+// offsetUnit returns the unit of the offsets of line matches in the response
+// to req.
+func (req *searchRequest) offsetUnit() search.OffsetUnit {
+	unit, _ := search.ParseOffsetUnit(req.OffsetUnit) // validated by searchArgs
+	return unit
+}
+
+// quoteSearchFilterValue quotes the value of a search query filter if it
+// contains characters that would end the value.
+func quoteSearchFilterValue(value string) string {
+	if strings.ContainsAny(value, " \t\n\"") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// searchResponse is the response of the REST search API. Its file matches
+// have the same fields as those of the searcher protocol, plus their
+// repository and commit.
+type searchResponse struct {
+	Results    []searchFileMatch
+	MatchCount int32
+	LimitHit   bool
+	Cloning    []string
+	Missing    []string
+	Timedout   []string
+	Alert      *searchResponseAlert `json:",omitempty"`
+
+	// SkippedFiles are the numbers of file matches that were removed because
+	// they can't be shown as text, by reason (e.g. "BINARY").
+	SkippedFiles map[string]int32 `json:",omitempty"`
+}
+
+type searchFileMatch struct {
+	Repository  string
+	Commit      string
+	InputRev    string `json:",omitempty"`
+	Path        string
+	LineMatches []searchLineMatch
+	LimitHit    bool
+
+	// PathOffsetAndLengths are the matches of the pattern in Path, if the
+	// search matches paths.
+	PathOffsetAndLengths [][2]int32 `json:",omitempty"`
+
+	// Content is the content of the file, if the request has IncludeContent.
+	// If the file is too large, Content is omitted and ContentOmitted is set.
+	Content        *string `json:",omitempty"`
+	ContentOmitted bool    `json:",omitempty"`
+}
+
+type searchLineMatch struct {
+	Preview          string
+	LineNumber       int32
+	OffsetAndLengths [][2]int32
+	LimitHit         bool
+
+	// PreviewIsHex is whether Preview contains the bytes of the line in
+	// hexadecimal, for searches with hexpreview:yes.
+	PreviewIsHex bool `json:",omitempty"`
+
+	// Diff is a unified diff hunk that previews the replacement of the
+	// request in the line. It is only set for changed lines.
+	Diff string `json:",omitempty"`
+}
+
+type searchResponseAlert struct {
+	Title       string
+	Description string `json:",omitempty"`
+}
+
+// serveSearch runs a search and returns its file matches. It is a plain
+// HTTP alternative to the search field of the GraphQL API for scripts and
+// integrations. Other kinds of results (such as repositories and commits)
+// are only available through the GraphQL API.
+func serveSearch(w http.ResponseWriter, r *http.Request) error {
+	req, args, err := decodeSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	ctx := trace.WithRequestSource(r.Context(), guessSource(r))
+	s, err := graphqlbackend.NewSearchImplementer(args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	replacer, _ := req.replacer() // validated by decodeSearchRequest
+	if req.Format == "ndjson" {
+		return serveSearchNDJSON(ctx, w, s, replacer, req.offsetUnit())
+	}
+	results, err := s.Results(ctx)
+	if err != nil {
+		return err
+	}
+	resp := newSearchResponse(results)
+	previewReplacement(replacer, resp.Results)
+	convertOffsets(req.offsetUnit(), resp.Results)
+	if req.IncludeContent {
+		newSearchContentLoader().load(ctx, resp.Results)
+	}
+	return writeJSON(w, resp)
+}
+
+// decodeSearchRequest decodes the search request in the JSON body (of POST
+// requests) or URL query (of GET requests) of r and returns it together with
+// the arguments of the search it describes.
+func decodeSearchRequest(r *http.Request) (*searchRequest, *graphqlbackend.SearchArgs, error) {
+	var req searchRequest
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, nil, err
+		}
+	} else if err := schemaDecoder.Decode(&req, r.URL.Query()); err != nil {
+		return nil, nil, err
+	}
+	args, err := req.searchArgs()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &req, args, nil
+}
+
+func newSearchResponse(results *graphqlbackend.SearchResultsResolver) *searchResponse {
+	resp := &searchResponse{
+		MatchCount: results.MatchCount(),
+		LimitHit:   results.LimitHit(),
+		Cloning:    repositoryNames(results.Cloning()),
+		Missing:    repositoryNames(results.Missing()),
+		Timedout:   repositoryNames(results.Timedout()),
+		Alert:      newSearchResponseAlert(results),
+	}
+	for _, skipped := range results.SkippedFiles() {
+		if resp.SkippedFiles == nil {
+			resp.SkippedFiles = map[string]int32{}
+		}
+		resp.SkippedFiles[skipped.Reason()] = skipped.Count()
+	}
+	resp.Results = toSearchFileMatches(results.Results())
+	return resp
+}
+
+func newSearchResponseAlert(results *graphqlbackend.SearchResultsResolver) *searchResponseAlert {
+	alert := results.Alert()
+	if alert == nil {
+		return nil
+	}
+	a := &searchResponseAlert{Title: alert.Title()}
+	if description := alert.Description(); description != nil {
+		a.Description = *description
+	}
+	return a
+}
+
+// toSearchFileMatches returns the file matches of results, omitting other
+// kinds of results.
+func toSearchFileMatches(results []graphqlbackend.SearchResultResolver) []searchFileMatch {
+	matches := []searchFileMatch{}
+	for _, result := range results {
+		fm, ok := result.ToFileMatch()
+		if !ok {
+			continue
+		}
+		m := searchFileMatch{
+			Commit:      string(fm.CommitID),
+			Path:        fm.JPath,
+			LineMatches: make([]searchLineMatch, len(fm.JLineMatches)),
+			LimitHit:    fm.JLimitHit,
+
+			PathOffsetAndLengths: fm.JPathOffsetAndLengths,
+		}
+		if fm.Repo != nil {
+			m.Repository = string(fm.Repo.Name)
+		}
+		if fm.InputRev != nil {
+			m.InputRev = *fm.InputRev
+		}
+		for i, lm := range fm.JLineMatches {
+			m.LineMatches[i] = searchLineMatch{
+				Preview:          lm.JPreview,
+				LineNumber:       lm.JLineNumber,
+				OffsetAndLengths: lm.JOffsetAndLengths,
+				LimitHit:         lm.JLimitHit,
+				PreviewIsHex:     lm.PreviewIsHex(),
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// previewReplacement sets the Diff of the line matches of matches to the
+// changes replacer makes to them. It does nothing if replacer is nil.
+func previewReplacement(replacer *replace.Replacer, matches []searchFileMatch) {
+	if replacer == nil {
+		return
+	}
+	for _, fm := range matches {
+		for i, lm := range fm.LineMatches {
+			fm.LineMatches[i].Diff = replace.LineHunk(int(lm.LineNumber), lm.Preview, replacer.Replace(lm.Preview))
+		}
+	}
+}
+
+// convertOffsets converts the OffsetAndLengths of the line matches of matches
+// from characters to unit.
+func convertOffsets(unit search.OffsetUnit, matches []searchFileMatch) {
+	for i, fm := range matches {
+		matches[i].PathOffsetAndLengths = search.ConvertOffsets(fm.Path, fm.PathOffsetAndLengths, unit)
+		for j, lm := range fm.LineMatches {
+			fm.LineMatches[j].OffsetAndLengths = search.ConvertOffsets(lm.Preview, lm.OffsetAndLengths, unit)
+		}
+	}
+}
+
+func repositoryNames(repos []*graphqlbackend.RepositoryResolver) []string {
+	names := make([]string, len(repos))
+	for i, repo := range repos {
+		names[i] = repo.Name()
+	}
+	return names
+}
@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// defaultCandidateFilesLimit is the number of candidate files returned if the
+// request has no limit.
+const defaultCandidateFilesLimit = 1000
+
+// serveSearchCandidateFiles returns the files of a repository that contain
+// any of a set of identifiers (see api.SearchCandidateFilesRequest). Language
+// servers use it to narrow down the files they parse for workspace/symbol and
+// textDocument/references requests to those that can contain a match.
+func serveSearchCandidateFiles(w http.ResponseWriter, r *http.Request) error {
+	var req api.SearchCandidateFilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return err
+	}
+	args, err := candidateFilesSearchArgs(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	s, err := graphqlbackend.NewSearchImplementer(args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+	results, err := s.Results(r.Context())
+	if err != nil {
+		return err
+	}
+
+	resp := api.SearchCandidateFilesResponse{Paths: []string{}, LimitHit: results.LimitHit()}
+	for _, fm := range toSearchFileMatches(results.SearchResults) {
+		// Files whose path matched are not candidates, only their contents
+		// matter.
+		if len(fm.LineMatches) > 0 {
+			resp.Paths = append(resp.Paths, fm.Path)
+		}
+	}
+	return writeJSON(w, resp)
+}
+
+// candidateFilesSearchArgs returns the arguments of the search for the
+// candidate files of req.
+func candidateFilesSearchArgs(req *api.SearchCandidateFilesRequest) (*graphqlbackend.SearchArgs, error) {
+	if req.Repo == "" {
+		return nil, fmt.Errorf("no repository")
+	}
+	if len(req.Identifiers) == 0 {
+		return nil, fmt.Errorf("no identifiers")
+	}
+	patterns := make([]string, len(req.Identifiers))
+	for i, id := range req.Identifiers {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
+		}
+		patterns[i] = identifierPattern(id)
+	}
+
+	repo := "^" + regexp.QuoteMeta(string(req.Repo)) + "$"
+	if req.CommitID != "" {
+		repo += "@" + string(req.CommitID)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultCandidateFilesLimit
+	}
+	return (&searchRequest{
+		Query:           "repo:" + quoteSearchFilterValue(repo),
+		Pattern:         "(?:" + strings.Join(patterns, "|") + ")",
+		IsRegExp:        true,
+		IsCaseSensitive: true,
+		IncludePatterns: req.IncludePatterns,
+		FileMatchLimit:  limit,
+	}).searchArgs()
+}
+
+// identifierPattern returns a regular expression that matches id as a whole
+// word. Word boundaries are only required next to word characters, so that
+// identifiers such as "$foo" or "operator+" also match.
+func identifierPattern(id string) string {
+	isWordByte := func(b byte) bool {
+		return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+	}
+	pattern := regexp.QuoteMeta(id)
+	if isWordByte(id[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordByte(id[len(id)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
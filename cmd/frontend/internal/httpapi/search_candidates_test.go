@@ -0,0 +1,66 @@
+package httpapi
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestCandidateFilesSearchArgs(t *testing.T) {
+	args, err := candidateFilesSearchArgs(&api.SearchCandidateFilesRequest{
+		Repo:            "github.com/gorilla/mux",
+		CommitID:        "c1",
+		Identifiers:     []string{"NewRouter", "$route"},
+		IncludePatterns: []string{`\.go$`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `repo:^github\.com/gorilla/mux$@c1 file:\.go$ case:yes count:1000 (?:\bNewRouter\b|\$route\b)`
+	if args.Query != want {
+		t.Errorf("got query %q, want %q", args.Query, want)
+	}
+	if args.PatternType == nil || *args.PatternType != "regexp" {
+		t.Errorf("got pattern type %v, want regexp", args.PatternType)
+	}
+	if _, err := graphqlbackend.NewSearchImplementer(args); err != nil {
+		t.Errorf("invalid query: %s", err)
+	}
+
+	for _, req := range []api.SearchCandidateFilesRequest{
+		{Identifiers: []string{"foo"}},
+		{Repo: "r"},
+		{Repo: "r", Identifiers: []string{""}},
+	} {
+		if _, err := candidateFilesSearchArgs(&req); err == nil {
+			t.Errorf("got nil error for invalid request %+v", req)
+		}
+	}
+}
+
+func TestIdentifierPattern(t *testing.T) {
+	tests := []struct {
+		id      string
+		matches []string
+		misses  []string
+	}{
+		{id: "foo", matches: []string{"foo()", "x.foo"}, misses: []string{"foobar", "_foo"}},
+		{id: "$foo", matches: []string{"x = $foo;"}, misses: []string{"$foobar"}},
+		{id: "operator+", matches: []string{"operator+(a)"}, misses: []string{"xoperator+"}},
+	}
+	for _, test := range tests {
+		re := regexp.MustCompile(identifierPattern(test.id))
+		for _, s := range test.matches {
+			if !re.MatchString(s) {
+				t.Errorf("%q does not match %q", test.id, s)
+			}
+		}
+		for _, s := range test.misses {
+			if re.MatchString(s) {
+				t.Errorf("%q matches %q", test.id, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"context"
+	"sync"
+
+	"github.com/inconshreveable/log15"
+	"github.com/neelance/parallel"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+const (
+	// maxSearchContentSize is the maximum size of a file whose content is
+	// included in a file match.
+	maxSearchContentSize = 1 << 20
+
+	// maxSearchContentTotalSize is the maximum total size of the contents of
+	// the files included in the response to a search request.
+	maxSearchContentTotalSize = 32 << 20
+
+	// searchContentParallelism is the number of files whose contents are
+	// read at the same time.
+	searchContentParallelism = 16
+)
+
+// searchContentLoader sets the contents of file matches for requests with
+// IncludeContent. It limits the total size of the contents of a response to
+// maxSearchContentTotalSize.
+type searchContentLoader struct {
+	mu        sync.Mutex
+	remaining int64
+}
+
+func newSearchContentLoader() *searchContentLoader {
+	return &searchContentLoader{remaining: maxSearchContentTotalSize}
+}
+
+// load sets the Content of matches to the contents of their files at the
+// searched commit. Files that are larger than maxSearchContentSize, or that
+// would exceed the total size limit, have ContentOmitted set instead. A file
+// whose content can't be read has neither, and clients should fetch it
+// themselves.
+func (l *searchContentLoader) load(ctx context.Context, matches []searchFileMatch) {
+	run := parallel.NewRun(searchContentParallelism)
+	for i := range matches {
+		m := &matches[i]
+		run.Acquire()
+		goroutine.Go(func() {
+			defer run.Release()
+			// Read one more byte than the limit to detect larger files.
+			content, err := git.ReadFile(ctx, gitserver.Repo{Name: api.RepoName(m.Repository)}, api.CommitID(m.Commit), m.Path, maxSearchContentSize+1)
+			if err != nil {
+				if ctx.Err() == nil {
+					log15.Warn("Failed to read content of search result", "repo", m.Repository, "commit", m.Commit, "path", m.Path, "error", err)
+				}
+				return
+			}
+			if len(content) > maxSearchContentSize || !l.reserve(len(content)) {
+				m.ContentOmitted = true
+				return
+			}
+			s := string(content)
+			m.Content = &s
+		})
+	}
+	_ = run.Wait()
+}
+
+// reserve reports whether n more bytes of contents fit in the response, and
+// if so deducts them from the remaining total size.
+func (l *searchContentLoader) reserve(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if int64(n) > l.remaining {
+		return false
+	}
+	l.remaining -= int64(n)
+	return true
+}
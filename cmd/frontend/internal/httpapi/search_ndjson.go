@@ -0,0 +1,103 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/search/replace"
+)
+
+// searchNDJSONLine is a line of a newline-delimited JSON search response. There
+// is one line per line match, and one line without LineNumber for each file
+// whose path matched.
+type searchNDJSONLine struct {
+	Repository       string
+	Commit           string
+	InputRev         string `json:",omitempty"`
+	Path             string
+	LineNumber       *int32     `json:",omitempty"`
+	Preview          string     `json:",omitempty"`
+	OffsetAndLengths [][2]int32 `json:",omitempty"`
+	Diff             string     `json:",omitempty"`
+
+	// Error is only set on the last line of a response if the search failed
+	// after other lines were written.
+	Error string `json:",omitempty"`
+}
+
+// serveSearchNDJSON writes the matches of s as newline-delimited JSON while the
+// search is running, so that clients can process huge result sets (e.g. with
+// jq) without buffering one JSON document.
+func serveSearchNDJSON(ctx context.Context, w http.ResponseWriter, s graphqlbackend.SearchImplementer, replacer *replace.Replacer, unit search.OffsetUnit) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	var (
+		wrote    bool
+		writeErr error
+	)
+	write := func(results []graphqlbackend.SearchResultResolver) error {
+		matches := toSearchFileMatches(results)
+		if len(matches) == 0 {
+			return nil
+		}
+		previewReplacement(replacer, matches)
+		convertOffsets(unit, matches)
+		for _, fm := range matches {
+			for _, line := range searchNDJSONLines(fm) {
+				if err := enc.Encode(line); err != nil {
+					return err
+				}
+			}
+		}
+		wrote = true
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	_, err := graphqlbackend.StreamSearch(ctx, s, graphqlbackend.SearchStreamFunc(func(e graphqlbackend.SearchEvent) {
+		if writeErr == nil {
+			writeErr = write(e.Results)
+		}
+	}))
+	if writeErr != nil {
+		// The client is gone, so there is nobody to report the error to.
+		return nil
+	}
+	if err != nil {
+		if !wrote {
+			return err
+		}
+		if err := enc.Encode(searchNDJSONLine{Error: err.Error()}); err != nil {
+			return nil
+		}
+		return bw.Flush()
+	}
+	return nil
+}
+
+// searchNDJSONLines returns the lines of a file match.
+func searchNDJSONLines(fm searchFileMatch) []searchNDJSONLine {
+	line := searchNDJSONLine{Repository: fm.Repository, Commit: fm.Commit, InputRev: fm.InputRev, Path: fm.Path}
+	if len(fm.LineMatches) == 0 {
+		return []searchNDJSONLine{line}
+	}
+	lines := make([]searchNDJSONLine, len(fm.LineMatches))
+	for i, lm := range fm.LineMatches {
+		lineNumber := lm.LineNumber
+		lines[i] = line
+		lines[i].LineNumber, lines[i].Preview, lines[i].OffsetAndLengths, lines[i].Diff = &lineNumber, lm.Preview, lm.OffsetAndLengths, lm.Diff
+	}
+	return lines
+}
@@ -0,0 +1,47 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSearchNDJSONLines(t *testing.T) {
+	encode := func(lines []searchNDJSONLine) string {
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		for _, line := range lines {
+			if err := enc.Encode(line); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return b.String()
+	}
+
+	t.Run("line matches", func(t *testing.T) {
+		got := encode(searchNDJSONLines(searchFileMatch{
+			Repository: "r",
+			Commit:     "c",
+			Path:       "p",
+			LineMatches: []searchLineMatch{
+				{Preview: "foo", LineNumber: 0, OffsetAndLengths: [][2]int32{{0, 3}}},
+				{Preview: "a foo", LineNumber: 7, OffsetAndLengths: [][2]int32{{2, 3}}},
+			},
+		}))
+		want := `{"Repository":"r","Commit":"c","Path":"p","LineNumber":0,"Preview":"foo","OffsetAndLengths":[[0,3]]}
+{"Repository":"r","Commit":"c","Path":"p","LineNumber":7,"Preview":"a foo","OffsetAndLengths":[[2,3]]}
+`
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("path match", func(t *testing.T) {
+		got := encode(searchNDJSONLines(searchFileMatch{Repository: "r", Commit: "c", InputRev: "main", Path: "p"}))
+		want := `{"Repository":"r","Commit":"c","InputRev":"main","Path":"p"}
+`
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+	"github.com/sourcegraph/sourcegraph/internal/trace"
+)
+
+// searchProgress is the data of "progress" events of a search stream. The
+// counts are totals since the search started.
+type searchProgress struct {
+	RepositoriesSearched int
+	MatchCount           int32
+}
+
+// searchStreamDone is the data of the final "done" event of a search stream.
+type searchStreamDone struct {
+	searchProgress
+	LimitHit            bool
+	Cloning             []string
+	Missing             []string
+	Timedout            []string
+	Alert               *searchResponseAlert `json:",omitempty"`
+	ElapsedMilliseconds int32
+}
+
+// serveSearchStream runs a search (described like requests to serveSearch)
+// and streams its progress as server-sent events, so that clients can show
+// the results of long searches while they are running. The events are:
+//
+//	matches   a JSON array of file matches (see searchFileMatch)
+//	progress  the repositories searched and matches found so far
+//	done      the final statistics of the search (see searchStreamDone)
+//	error     the error message of a failed search
+//
+// Like serveSearch, only file matches are sent.
+func serveSearchStream(w http.ResponseWriter, r *http.Request) {
+	req, args, err := decodeSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := graphqlbackend.NewSearchImplementer(args)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	events := &eventStreamWriter{w: w, flusher: flusher}
+
+	replacer, _ := req.replacer() // validated by decodeSearchRequest
+	var contents *searchContentLoader
+	if req.IncludeContent {
+		contents = newSearchContentLoader()
+	}
+	ctx := trace.WithRequestSource(r.Context(), guessSource(r))
+	var progress searchProgress
+	send := func(results []graphqlbackend.SearchResultResolver) error {
+		if matches := toSearchFileMatches(results); len(matches) > 0 {
+			previewReplacement(replacer, matches)
+			convertOffsets(req.offsetUnit(), matches)
+			if contents != nil {
+				contents.load(ctx, matches)
+			}
+			if err := events.event("matches", matches); err != nil {
+				return err
+			}
+		}
+		progress.MatchCount += (&graphqlbackend.SearchResultsResolver{SearchResults: results}).MatchCount()
+		return events.event("progress", progress)
+	}
+
+	var writeErr error
+	results, err := graphqlbackend.StreamSearch(ctx, s, graphqlbackend.SearchStreamFunc(func(e graphqlbackend.SearchEvent) {
+		if writeErr != nil {
+			// The client is gone. The search is canceled with the request
+			// context.
+			return
+		}
+		progress.RepositoriesSearched += e.RepositoriesSearched
+		writeErr = send(e.Results)
+	}))
+	if writeErr != nil {
+		log15.Debug("Search stream client disconnected", "error", writeErr)
+		return
+	}
+	if err != nil {
+		_ = events.event("error", struct{ Message string }{err.Error()})
+		return
+	}
+	if results == nil {
+		_ = events.event("done", searchStreamDone{searchProgress: progress})
+		return
+	}
+
+	// Searches that don't stream (e.g. and/or queries) only report the
+	// repositories they searched at the end.
+	progress.RepositoriesSearched = len(results.RepositoriesSearched())
+	_ = events.event("done", searchStreamDone{
+		searchProgress:      progress,
+		LimitHit:            results.LimitHit(),
+		Cloning:             repositoryNames(results.Cloning()),
+		Missing:             repositoryNames(results.Missing()),
+		Timedout:            repositoryNames(results.Timedout()),
+		Alert:               newSearchResponseAlert(results),
+		ElapsedMilliseconds: results.ElapsedMilliseconds(),
+	})
+}
+
+// eventStreamWriter writes server-sent events with JSON data.
+type eventStreamWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// event writes an event and flushes it to the client.
+func (e *eventStreamWriter) event(name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,26 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventStreamWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	events := &eventStreamWriter{w: rec, flusher: rec}
+	if err := events.event("progress", searchProgress{RepositoriesSearched: 2, MatchCount: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.event("done", searchStreamDone{Cloning: []string{"a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "event: progress\ndata: {\"RepositoriesSearched\":2,\"MatchCount\":5}\n\n" +
+		"event: done\ndata: {\"RepositoriesSearched\":0,\"MatchCount\":0,\"LimitHit\":false,\"Cloning\":[\"a\"],\"Missing\":null,\"Timedout\":null,\"ElapsedMilliseconds\":0}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !rec.Flushed {
+		t.Error("expected events to be flushed")
+	}
+}
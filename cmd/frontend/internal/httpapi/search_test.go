@@ -0,0 +1,195 @@
+package httpapi
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
+)
+
+func TestSearchRequest_searchArgs(t *testing.T) {
+	tests := []struct {
+		name            string
+		req             searchRequest
+		wantQuery       string
+		wantPatternType string
+	}{
+		{
+			name:      "query",
+			req:       searchRequest{Query: "repo:foo bar"},
+			wantQuery: "repo:foo bar",
+		},
+		{
+			name:            "literal pattern",
+			req:             searchRequest{Pattern: "foo(", IsCaseSensitive: true},
+			wantQuery:       "case:yes foo(",
+			wantPatternType: "literal",
+		},
+		{
+			name: "pattern info",
+			req: searchRequest{
+				Query:           "lang:go",
+				Pattern:         "fo+",
+				IsRegExp:        true,
+				Repos:           []string{"github.com/a/b", "github.com/c/d.e"},
+				IncludePatterns: []string{`\.go$`, "my dir/"},
+				ExcludePattern:  "_test",
+				FileMatchLimit:  10,
+			},
+			wantQuery:       `lang:go repo:^(github\.com/a/b|github\.com/c/d\.e)$ file:\.go$ file:"my dir/" -file:_test count:10 fo+`,
+			wantPatternType: "regexp",
+		},
+		{
+			name:            "structural pattern",
+			req:             searchRequest{Pattern: "foo(:[x])", IsStructuralPat: true, IsRegExp: true},
+			wantQuery:       "foo(:[x])",
+			wantPatternType: "structural",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := test.req.searchArgs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if args.Query != test.wantQuery {
+				t.Errorf("got query %q, want %q", args.Query, test.wantQuery)
+			}
+			var patternType string
+			if args.PatternType != nil {
+				patternType = *args.PatternType
+			}
+			if patternType != test.wantPatternType {
+				t.Errorf("got pattern type %q, want %q", patternType, test.wantPatternType)
+			}
+			if args.Version != "V2" {
+				t.Errorf("got version %q, want V2", args.Version)
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		if _, err := (&searchRequest{}).searchArgs(); err == nil {
+			t.Error("got nil error, want error for empty request")
+		}
+	})
+
+	t.Run("replacement without pattern", func(t *testing.T) {
+		replacement := "bar"
+		if _, err := (&searchRequest{Query: "foo", Replacement: &replacement}).searchArgs(); err == nil {
+			t.Error("got nil error, want error for replacement without pattern")
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		if _, err := (&searchRequest{Query: "foo", Format: "xml"}).searchArgs(); err == nil {
+			t.Error("got nil error, want error for invalid format")
+		}
+	})
+
+	t.Run("invalid offset unit", func(t *testing.T) {
+		if _, err := (&searchRequest{Query: "foo", OffsetUnit: "rune"}).searchArgs(); err == nil {
+			t.Error("got nil error, want error for invalid offset unit")
+		}
+	})
+
+	t.Run("ndjson with content", func(t *testing.T) {
+		if _, err := (&searchRequest{Query: "foo", Format: "ndjson", IncludeContent: true}).searchArgs(); err == nil {
+			t.Error("got nil error, want error for ndjson response with contents")
+		}
+	})
+}
+
+func TestSearchRequest_decodeURLQuery(t *testing.T) {
+	var req searchRequest
+	if err := schemaDecoder.Decode(&req, url.Values{"q": {"foo"}, "patternType": {"regexp"}}); err != nil {
+		t.Fatal(err)
+	}
+	if req.Query != "foo" {
+		t.Errorf("got query %q, want %q", req.Query, "foo")
+	}
+	if req.PatternType == nil || *req.PatternType != "regexp" {
+		t.Errorf("got pattern type %v, want regexp", req.PatternType)
+	}
+}
+
+func TestPreviewReplacement(t *testing.T) {
+	replacement := "errors.New("
+	replacer, err := (&searchRequest{Pattern: "fmt.Errorf(", Replacement: &replacement}).replacer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := []searchFileMatch{{
+		Path: "a.go",
+		LineMatches: []searchLineMatch{
+			{Preview: `	return fmt.Errorf("x")`, LineNumber: 9},
+			{Preview: `	return FMT.ERRORF("x")`, LineNumber: 12},
+		},
+	}}
+	previewReplacement(replacer, matches)
+	var got []string
+	for _, lm := range matches[0].LineMatches {
+		got = append(got, lm.Diff)
+	}
+	want := []string{
+		"@@ -10 +10 @@\n-\treturn fmt.Errorf(\"x\")\n+\treturn errors.New(\"x\")\n",
+		"@@ -13 +13 @@\n-\treturn FMT.ERRORF(\"x\")\n+\treturn errors.New(\"x\")\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSearchContentLoader(t *testing.T) {
+	git.Mocks.ReadFile = func(commit api.CommitID, name string) ([]byte, error) {
+		switch name {
+		case "large.bin":
+			return make([]byte, maxSearchContentSize+1), nil
+		case "missing.go":
+			return nil, os.ErrNotExist
+		}
+		return []byte("package " + name), nil
+	}
+	defer git.ResetMocks()
+
+	matches := []searchFileMatch{
+		{Repository: "github.com/a/b", Commit: "c1", Path: "a"},
+		{Repository: "github.com/a/b", Commit: "c1", Path: "large.bin"},
+		{Repository: "github.com/a/b", Commit: "c1", Path: "missing.go"},
+		{Repository: "github.com/a/b", Commit: "c1", Path: "b"},
+	}
+	l := newSearchContentLoader()
+	l.remaining = int64(len("package a"))
+	l.load(context.Background(), matches)
+
+	// Only one of the files a and b fits in the remaining total size.
+	if (matches[0].Content == nil) == (matches[3].Content == nil) || matches[0].ContentOmitted == matches[3].ContentOmitted {
+		t.Errorf("got contents %v and %v, want exactly one", matches[0].Content, matches[3].Content)
+	}
+	if matches[1].Content != nil || !matches[1].ContentOmitted {
+		t.Errorf("got content for file larger than maxSearchContentSize")
+	}
+	if matches[2].Content != nil || matches[2].ContentOmitted {
+		t.Errorf("got content or ContentOmitted for missing file")
+	}
+}
+
+func TestConvertOffsets(t *testing.T) {
+	matches := []searchFileMatch{{
+		Path:                 "日本/foo.go",
+		PathOffsetAndLengths: [][2]int32{{3, 3}},
+		LineMatches:          []searchLineMatch{{Preview: `s := "日本" + foo`, OffsetAndLengths: [][2]int32{{12, 3}}}},
+	}}
+	convertOffsets(search.OffsetUnitByte, matches)
+	if got, want := matches[0].LineMatches[0].OffsetAndLengths, [][2]int32{{16, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := matches[0].PathOffsetAndLengths, [][2]int32{{7, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got path offsets %v, want %v", got, want)
+	}
+}
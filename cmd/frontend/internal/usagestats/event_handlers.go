@@ -24,6 +24,9 @@ type Event struct {
 	URL          string
 	Source       string
 	Argument     json.RawMessage
+	// ExperimentArms are the arms of the search experiments that the user
+	// was assigned to, by experiment name.
+	ExperimentArms map[string]string
 }
 
 // LogBackendEvent is a convenience function for logging backend events.
@@ -49,7 +52,7 @@ func LogEvent(ctx context.Context, args Event) error {
 			return err
 		}
 	}
-	return logLocalEvent(ctx, args.EventName, args.URL, args.UserID, args.UserCookieID, args.Source, args.Argument)
+	return logLocalEvent(ctx, args.EventName, args.URL, args.UserID, args.UserCookieID, args.Source, args.Argument, args.ExperimentArms)
 }
 
 type bigQueryEvent struct {
@@ -86,7 +89,7 @@ func publishSourcegraphDotComEvent(args Event) error {
 }
 
 // logLocalEvent logs users events.
-func logLocalEvent(ctx context.Context, name, url string, userID int32, userCookieID, source string, argument json.RawMessage) error {
+func logLocalEvent(ctx context.Context, name, url string, userID int32, userCookieID, source string, argument json.RawMessage, experimentArms map[string]string) error {
 	if name == "SearchResultsQueried" {
 		err := logSiteSearchOccurred()
 		if err != nil {
@@ -108,6 +111,7 @@ func logLocalEvent(ctx context.Context, name, url string, userID int32, userCook
 		Source:          source,
 		Argument:        argument,
 		Timestamp:       timeNow().UTC(),
+		ExperimentArms:  experimentArms,
 	}
 	return db.EventLogs.Insert(ctx, info)
 }
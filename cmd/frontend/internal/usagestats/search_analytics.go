@@ -0,0 +1,162 @@
+package usagestats
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// searchAnalyticsFlushInterval is how often recorded search query features are
+// written to the database.
+const searchAnalyticsFlushInterval = time.Minute
+
+var (
+	patternLengthBuckets = []struct {
+		max   int
+		label string
+	}{
+		{0, "0"},
+		{3, "1-3"},
+		{10, "4-10"},
+		{30, "11-30"},
+		{100, "31-100"},
+	}
+	latencyBuckets = []struct {
+		max   time.Duration
+		label string
+	}{
+		{100 * time.Millisecond, "<100ms"},
+		{500 * time.Millisecond, "100ms-500ms"},
+		{time.Second, "500ms-1s"},
+		{5 * time.Second, "1s-5s"},
+		{20 * time.Second, "5s-20s"},
+	}
+)
+
+// NewSearchQueryFeatures returns the anonymized features of a search. Only the
+// names of filters are retained, never their values.
+func NewSearchQueryFeatures(patternType string, patternLength int, filters []string, latency time.Duration, zeroResults bool) types.SearchQueryFeatures {
+	f := types.SearchQueryFeatures{
+		PatternType:   patternType,
+		PatternLength: "101+",
+		Latency:       "20s+",
+		ZeroResults:   zeroResults,
+	}
+	for _, b := range patternLengthBuckets {
+		if patternLength <= b.max {
+			f.PatternLength = b.label
+			break
+		}
+	}
+	for _, b := range latencyBuckets {
+		if latency < b.max {
+			f.Latency = b.label
+			break
+		}
+	}
+
+	names := make([]string, 0, len(filters))
+	seen := make(map[string]bool, len(filters))
+	for _, name := range filters {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	f.Filters = strings.Join(names, ",")
+	return f
+}
+
+// searchAnalyticsAggregator counts searches by features in memory until they
+// are flushed to the database, so that searches do not incur a database write.
+type searchAnalyticsAggregator struct {
+	mu     sync.Mutex
+	counts map[time.Time]map[types.SearchQueryFeatures]int32 // keyed by UTC day
+}
+
+var searchAnalytics = &searchAnalyticsAggregator{}
+
+// RecordSearchQuery counts a search with the given features towards today's
+// search analytics rollup.
+func RecordSearchQuery(f types.SearchQueryFeatures) {
+	searchAnalytics.record(time.Now(), f)
+}
+
+func (a *searchAnalyticsAggregator) record(now time.Time, f types.SearchQueryFeatures) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addLocked(now.UTC().Truncate(24*time.Hour), f, 1)
+}
+
+func (a *searchAnalyticsAggregator) addLocked(day time.Time, f types.SearchQueryFeatures, n int32) {
+	if a.counts == nil {
+		a.counts = make(map[time.Time]map[types.SearchQueryFeatures]int32)
+	}
+	if a.counts[day] == nil {
+		a.counts[day] = make(map[types.SearchQueryFeatures]int32)
+	}
+	a.counts[day][f] += n
+}
+
+// flush writes all recorded counts to the database. Counts that could not be
+// written are kept and retried on the next flush.
+func (a *searchAnalyticsAggregator) flush(ctx context.Context) error {
+	a.mu.Lock()
+	counts := a.counts
+	a.counts = nil
+	a.mu.Unlock()
+
+	var err error
+	for day, c := range counts {
+		if err == nil {
+			err = db.SearchAnalytics.Add(ctx, day, c)
+			if err == nil {
+				continue
+			}
+		}
+		a.mu.Lock()
+		for f, n := range c {
+			a.addLocked(day, f, n)
+		}
+		a.mu.Unlock()
+	}
+	return err
+}
+
+// FlushSearchAnalyticsPeriodically writes recorded search analytics to the
+// database every minute and deletes rollups older than the event log retention
+// period. It never returns.
+func FlushSearchAnalyticsPeriodically(ctx context.Context) {
+	lastCleanup := time.Time{}
+	for {
+		time.Sleep(searchAnalyticsFlushInterval)
+		if err := searchAnalytics.flush(ctx); err != nil {
+			log15.Error("writing search analytics rollups", "error", err)
+		}
+		if time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			if err := db.SearchAnalytics.DeleteBefore(ctx, time.Now().AddDate(0, 0, -maxStorageDays)); err != nil {
+				log15.Error("deleting expired search analytics rollups", "error", err)
+			}
+		}
+	}
+}
+
+// GetSearchAnalytics returns the search analytics rollups of the last days
+// days (including today), most recent day first.
+func GetSearchAnalytics(ctx context.Context, days int) ([]*types.SearchAnalyticsRollup, error) {
+	days = minIntOrZero(maxStorageDays, days)
+	// Include searches which have not been flushed yet.
+	if err := searchAnalytics.flush(ctx); err != nil {
+		return nil, err
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	return db.SearchAnalytics.List(ctx, since)
+}
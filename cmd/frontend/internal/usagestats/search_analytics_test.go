@@ -0,0 +1,70 @@
+package usagestats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestNewSearchQueryFeatures(t *testing.T) {
+	have := NewSearchQueryFeatures("literal", 5, []string{"repo", "file", "repo"}, 700*time.Millisecond, true)
+	want := types.SearchQueryFeatures{
+		PatternType:   "literal",
+		PatternLength: "4-10",
+		Filters:       "file,repo",
+		Latency:       "500ms-1s",
+		ZeroResults:   true,
+	}
+	if diff := cmp.Diff(want, have); diff != "" {
+		t.Fatalf("features mismatch (-want +have):\n%s", diff)
+	}
+
+	have = NewSearchQueryFeatures("regexp", 500, nil, time.Minute, false)
+	if have.PatternLength != "101+" || have.Latency != "20s+" || have.Filters != "" {
+		t.Fatalf("unexpected features for large values: %+v", have)
+	}
+}
+
+func TestSearchAnalyticsAggregator(t *testing.T) {
+	defer func() { db.Mocks.SearchAnalytics = db.MockSearchAnalytics{} }()
+
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := types.SearchQueryFeatures{PatternType: "literal"}
+
+	var a searchAnalyticsAggregator
+	a.record(day.Add(time.Hour), f)
+	a.record(day.Add(2*time.Hour), f)
+	a.record(day.Add(25*time.Hour), f)
+
+	// Failed flushes keep the counts.
+	db.Mocks.SearchAnalytics.Add = func(ctx context.Context, day time.Time, counts map[types.SearchQueryFeatures]int32) error {
+		return errors.New("boom")
+	}
+	if err := a.flush(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	written := map[time.Time]map[types.SearchQueryFeatures]int32{}
+	db.Mocks.SearchAnalytics.Add = func(ctx context.Context, day time.Time, counts map[types.SearchQueryFeatures]int32) error {
+		written[day] = counts
+		return nil
+	}
+	if err := a.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[time.Time]map[types.SearchQueryFeatures]int32{
+		day:                  {f: 2},
+		day.AddDate(0, 0, 1): {f: 1},
+	}
+	if diff := cmp.Diff(want, written); diff != "" {
+		t.Fatalf("written counts mismatch (-want +have):\n%s", diff)
+	}
+	if len(a.counts) != 0 {
+		t.Fatalf("expected no counts after flush, got %v", a.counts)
+	}
+}
@@ -122,7 +122,7 @@ func TestUserUsageStatistics_LogPageView(t *testing.T) {
 	user := types.User{
 		ID: 1,
 	}
-	err := logLocalEvent(context.Background(), "ViewRepo", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil)
+	err := logLocalEvent(context.Background(), "ViewRepo", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,7 +152,7 @@ func TestUserUsageStatistics_LogSearchQuery(t *testing.T) {
 	user := types.User{
 		ID: 1,
 	}
-	err := logLocalEvent(context.Background(), "SearchResultsQueried", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil)
+	err := logLocalEvent(context.Background(), "SearchResultsQueried", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -172,7 +172,7 @@ func TestUserUsageStatistics_LogCodeIntelAction(t *testing.T) {
 	user := types.User{
 		ID: 1,
 	}
-	err := logLocalEvent(context.Background(), "hover", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil)
+	err := logLocalEvent(context.Background(), "hover", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -192,7 +192,7 @@ func TestUserUsageStatistics_LogCodeHostIntegrationUsage(t *testing.T) {
 	user := types.User{
 		ID: 1,
 	}
-	err := logLocalEvent(context.Background(), "hover", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "CODEHOSTINTEGRATION", nil)
+	err := logLocalEvent(context.Background(), "hover", "https://sourcegraph.example.com/", user.ID, "test-cookie-id", "CODEHOSTINTEGRATION", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -220,7 +220,7 @@ func TestUserUsageStatistics_getUsersActiveToday(t *testing.T) {
 	}
 
 	// Test single user
-	err := logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err := logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -234,19 +234,19 @@ func TestUserUsageStatistics_getUsersActiveToday(t *testing.T) {
 	}
 
 	// Test multiple users, with repeats
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "test-cookie-id-3", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "test-cookie-id-3", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil, nil)
 
 	if err != nil {
 		t.Fatal(err)
@@ -292,74 +292,74 @@ func TestUserUsageStatistics_DAUs_WAUs_MAUs(t *testing.T) {
 
 	// 2018/02/27 (2 users, 1 registered)
 	mockTimeNow(oneMonthFourDaysAgo)
-	err := logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err := logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "CODEHOSTINTEGRATION", nil)
+	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "CODEHOSTINTEGRATION", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// 2018/02/28 (2 users, 1 registered)
 	mockTimeNow(oneMonthThreeDaysAgo)
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "30dd2661-2e73-4774-bc2b-7a126f360734", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "30dd2661-2e73-4774-bc2b-7a126f360734", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// 2018/03/15 (2 users, 1 registered)
 	mockTimeNow(twoWeeksTwoDaysAgo)
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "068ccbfa-8529-4fa7-859e-2c3514af2434", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// 2018/03/17 (2 users, 1 registered)
 	mockTimeNow(twoWeeksAgo)
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "b309dad0-b6f9-440d-bf0a-4cf38030ca70", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", 0, "b309dad0-b6f9-440d-bf0a-4cf38030ca70", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "CODEHOSTINTEGRATION", nil)
+	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "CODEHOSTINTEGRATION", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// 2018/03/26 (1 user, 1 registered)
 	mockTimeNow(fiveDaysAgo)
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// 2018/03/28 (2 users, 2 registered)
 	mockTimeNow(threeDaysAgo)
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil)
+	err = logLocalEvent(ctx, "ViewBlob", "https://sourcegraph.example.com/", user2.ID, "test-cookie-id-2", "WEB", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "CODEHOSTINTEGRATION", nil)
+	err = logLocalEvent(ctx, "hover", "https://sourcegraph.example.com/", user1.ID, "test-cookie-id-1", "CODEHOSTINTEGRATION", nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
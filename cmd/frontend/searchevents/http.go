@@ -0,0 +1,88 @@
+package searchevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// httpSinkBufferSize is the number of events an http sink buffers while
+// previous events are being sent.
+const httpSinkBufferSize = 1000
+
+// httpSink POSTs each event as a JSON object to a URL.
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	events chan Event
+}
+
+func newHTTPSink(c schema.SearchEventSink) (Sink, error) {
+	if c.Url == "" {
+		return nil, errors.New(`"url" is required`)
+	}
+	s := &httpSink{
+		url:     c.Url,
+		headers: c.Headers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		events:  make(chan Event, httpSinkBufferSize),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *httpSink) Send(e Event) {
+	select {
+	case s.events <- e:
+	default:
+		DroppedEvents.WithLabelValues("http").Inc()
+	}
+}
+
+// Close stops the sink once the buffered events have been sent.
+func (s *httpSink) Close() {
+	close(s.events)
+}
+
+func (s *httpSink) run() {
+	for e := range s.events {
+		if err := s.post(e); err != nil {
+			log15.Warn("Failed to send search event.", "url", s.url, "error", err)
+		}
+	}
+}
+
+func (s *httpSink) post(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Package searchevents emits an event for every completed search to the sinks
+// configured in the "observability.searchEvents" site configuration property.
+//
+// The search code path only depends on the Sink interface. Sinks for external
+// systems are registered with RegisterSink by type; this package provides the
+// "http" (JSON over HTTP) and "statsd" sinks.
+package searchevents
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// Event describes a completed search. It intentionally contains neither the
+// query, the user nor error messages (which can contain both), since events
+// are sent to external systems.
+type Event struct {
+	Timestamp   time.Time `json:"timestamp"`
+	RequestID   string    `json:"requestID,omitempty"`
+	Source      string    `json:"source"`
+	PatternType string    `json:"patternType"`
+
+	// Status is the outcome of the search, e.g. "success", "timeout" or
+	// "error" (see the src_graphql_search_response metric).
+	Status    string `json:"status"`
+	AlertType string `json:"alertType,omitempty"`
+
+	DurationMs int64 `json:"durationMs"`
+	Results    int   `json:"results"`
+	LimitHit   bool  `json:"limitHit"`
+
+	Repos    int `json:"repos"`
+	Searched int `json:"searched"`
+	Indexed  int `json:"indexed"`
+	Cloning  int `json:"cloning"`
+	Missing  int `json:"missing"`
+	Timedout int `json:"timedout"`
+
+	// ErrorCode is the code of the search error, e.g. "Timeout", or
+	// "Internal" for errors without a code.
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+// A Sink receives search events.
+type Sink interface {
+	// Send sends e to the sink. It is called on the search code path, so it
+	// must not block: sinks that talk to remote systems should buffer events
+	// and drop them (see DroppedEvents) when the buffer is full.
+	Send(e Event)
+
+	// Close releases the resources of the sink. Send is not called after
+	// Close.
+	Close()
+}
+
+// SinkFactory returns a Sink for an entry of the "observability.searchEvents"
+// site configuration property.
+type SinkFactory func(c schema.SearchEventSink) (Sink, error)
+
+// DroppedEvents counts the events a sink of the given type could not keep up
+// with.
+var DroppedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "src_search_events_dropped_total",
+	Help: "Number of search events dropped because a sink could not keep up, by sink type.",
+}, []string{"type"})
+
+var (
+	factoriesMu sync.Mutex
+	factories   = map[string]SinkFactory{
+		"http":   newHTTPSink,
+		"statsd": newStatsdSink,
+	}
+
+	mu      sync.RWMutex
+	sinks   []Sink
+	configs []*schema.SearchEventSink
+)
+
+// RegisterSink registers the factory for sinks of the given type. It must be
+// called before the site configuration is loaded, e.g. in an init function.
+func RegisterSink(typ string, f SinkFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[typ] = f
+}
+
+// Emit sends e to all configured sinks.
+func Emit(e Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range sinks {
+		s.Send(e)
+	}
+}
+
+// WatchConfig watches for changes to the "observability.searchEvents" site
+// configuration property and updates the sinks accordingly.
+func WatchConfig() {
+	conf.Watch(func() {
+		update(conf.Get().ObservabilitySearchEvents)
+	})
+}
+
+// update replaces the configured sinks if their configuration changed.
+func update(cs []*schema.SearchEventSink) {
+	mu.Lock()
+	defer mu.Unlock()
+	if reflect.DeepEqual(cs, configs) {
+		return
+	}
+
+	for _, s := range sinks {
+		s.Close()
+	}
+	sinks, configs = nil, cs
+
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	for _, c := range cs {
+		f, ok := factories[c.Type]
+		if !ok {
+			log15.Error("Unknown search event sink type in site configuration.", "type", c.Type)
+			continue
+		}
+		s, err := f(*c)
+		if err != nil {
+			log15.Error("Invalid search event sink in site configuration.", "type", c.Type, "error", err)
+			continue
+		}
+		sinks = append(sinks, s)
+	}
+}
@@ -0,0 +1,114 @@
+package searchevents
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type fakeSink struct {
+	events []Event
+	closed bool
+}
+
+func (s *fakeSink) Send(e Event) { s.events = append(s.events, e) }
+func (s *fakeSink) Close()       { s.closed = true }
+
+func TestUpdate(t *testing.T) {
+	var created []*fakeSink
+	RegisterSink("fake", func(schema.SearchEventSink) (Sink, error) {
+		s := &fakeSink{}
+		created = append(created, s)
+		return s, nil
+	})
+	defer update(nil)
+
+	update([]*schema.SearchEventSink{{Type: "fake"}, {Type: "unknown"}, {Type: "http"}})
+	if len(sinks) != 1 || len(created) != 1 {
+		t.Fatalf("got %d sinks (%d created), want 1", len(sinks), len(created))
+	}
+
+	Emit(Event{Status: "success"})
+	if want := []Event{{Status: "success"}}; !cmp.Equal(created[0].events, want) {
+		t.Errorf("events: %s", cmp.Diff(want, created[0].events))
+	}
+
+	// Sinks are only recreated when their configuration changes.
+	update([]*schema.SearchEventSink{{Type: "fake"}, {Type: "unknown"}, {Type: "http"}})
+	if len(created) != 1 {
+		t.Fatalf("got %d sinks created, want 1", len(created))
+	}
+	update([]*schema.SearchEventSink{{Type: "fake", Prefix: "x"}})
+	if len(created) != 2 || !created[0].closed {
+		t.Fatalf("expected sink to be closed and recreated")
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(chan Event, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token x" {
+			t.Errorf("got Authorization %q", got)
+		}
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		received <- e
+	}))
+	defer ts.Close()
+
+	s, err := newHTTPSink(schema.SearchEventSink{Type: "http", Url: ts.URL, Headers: map[string]string{"Authorization": "token x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	want := Event{Status: "timeout", Results: 3, ErrorCode: "Timeout"}
+	s.Send(want)
+	select {
+	case got := <-received:
+		if !cmp.Equal(got, want) {
+			t.Errorf("event: %s", cmp.Diff(want, got))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	s, err := newStatsdSink(schema.SearchEventSink{Type: "statsd", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Send(Event{Status: "success", DurationMs: 120, Results: 7})
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"sourcegraph.search.searches.success:1|c",
+		"sourcegraph.search.duration:120|ms",
+		"sourcegraph.search.results:7|ms",
+	}
+	if got := strings.Split(string(buf[:n]), "\n"); !cmp.Equal(got, want) {
+		t.Errorf("metrics: %s", cmp.Diff(want, got))
+	}
+}
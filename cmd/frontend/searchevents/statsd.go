@@ -0,0 +1,56 @@
+package searchevents
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+const defaultStatsdPrefix = "sourcegraph.search"
+
+// statsdSink sends metrics about each event to a statsd server over UDP:
+//
+//	<prefix>.searches.<status>:1|c
+//	<prefix>.duration:<durationMs>|ms
+//	<prefix>.results:<results>|ms
+//
+// Results are sent as a timer so that statsd computes their distribution.
+type statsdSink struct {
+	prefix string
+	conn   net.Conn
+}
+
+func newStatsdSink(c schema.SearchEventSink) (Sink, error) {
+	if c.Address == "" {
+		return nil, errors.New(`"address" is required`)
+	}
+	// Dialing UDP does not send anything, so this does not fail if the
+	// server is down.
+	conn, err := net.Dial("udp", c.Address)
+	if err != nil {
+		return nil, err
+	}
+	prefix := c.Prefix
+	if prefix == "" {
+		prefix = defaultStatsdPrefix
+	}
+	return &statsdSink{prefix: strings.TrimSuffix(prefix, "."), conn: conn}, nil
+}
+
+func (s *statsdSink) Send(e Event) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s.searches.%s:1|c\n", s.prefix, e.Status)
+	fmt.Fprintf(&b, "%s.duration:%d|ms\n", s.prefix, e.DurationMs)
+	fmt.Fprintf(&b, "%s.results:%d|ms", s.prefix, e.Results)
+	if _, err := s.conn.Write(b.Bytes()); err != nil {
+		DroppedEvents.WithLabelValues("statsd").Inc()
+	}
+}
+
+func (s *statsdSink) Close() {
+	_ = s.conn.Close()
+}
@@ -366,3 +366,53 @@ type Event struct {
 	Version         string
 	Timestamp       time.Time
 }
+
+// SearchQueryFeatures are the anonymized features of a search query that are
+// aggregated into search analytics rollups. They never contain query text,
+// repository names or user information.
+type SearchQueryFeatures struct {
+	PatternType   string // "literal", "regexp" or "structural"
+	PatternLength string // bucketed length of the search pattern, e.g. "4-10"
+	Filters       string // comma separated, sorted names of the filters used in the query
+	Latency       string // bucketed latency of the search, e.g. "500ms-1s"
+	ZeroResults   bool   // whether the search returned no results
+}
+
+// SearchAnalyticsRollup is the number of searches on a day that had the same
+// SearchQueryFeatures.
+type SearchAnalyticsRollup struct {
+	Day time.Time
+	SearchQueryFeatures
+	Count int32
+}
+
+// SearchFeatureFlagOverride enables or disables a search feature flag for a
+// user or an organization. Exactly one of UserID and OrgID is set.
+type SearchFeatureFlagOverride struct {
+	Flag      string
+	UserID    int32
+	OrgID     int32
+	Enabled   bool
+	UpdatedAt time.Time
+}
+
+// SearchExcludedRepo is a repository that a site admin excluded from search.
+type SearchExcludedRepo struct {
+	RepoID    api.RepoID
+	Reason    string
+	CreatedAt time.Time
+}
+
+// SearchExport is an export of the results of a search. Its Contents are only
+// loaded when it is downloaded.
+type SearchExport struct {
+	ID          string
+	UserID      int32
+	Format      string // "CSV", "JSON" or "SARIF"
+	State       string // "PROCESSING", "COMPLETED" or "ERRORED"
+	Error       string
+	ResultCount int32
+	SigningKey  []byte // signs the download URLs of the export
+	CreatedAt   time.Time
+	ExpiresAt   *time.Time // nil while processing
+}
@@ -223,7 +223,7 @@ func (s *Server) Handler() http.Handler {
 	})
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/archive", s.handleArchive)
+	mux.HandleFunc(protocol.ArchivePath, s.handleArchive)
 	mux.HandleFunc("/exec", s.handleExec)
 	mux.HandleFunc("/list", s.handleList)
 	mux.HandleFunc("/list-gitolite", s.handleListGitolite)
@@ -457,11 +457,11 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
 	var (
-		q       = r.URL.Query()
-		treeish = q.Get("treeish")
-		repo    = q.Get("repo")
-		format  = q.Get("format")
-		paths   = q["path"]
+		req     = protocol.ParseArchiveRequest(r.URL.Query())
+		treeish = req.Treeish
+		repo    = req.Repo
+		format  = req.Format
+		paths   = req.Paths
 	)
 
 	if err := checkSpecArgSafety(treeish); err != nil {
@@ -476,8 +476,8 @@ func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req := &protocol.ExecRequest{
-		Repo: api.RepoName(repo),
+	execReq := &protocol.ExecRequest{
+		Repo: repo,
 		Args: []string{
 			"archive",
 
@@ -496,13 +496,18 @@ func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request) {
 		// Compression level of 0 (no compression) seems to perform the
 		// best overall on fast network links, but this has not been tuned
 		// thoroughly.
-		req.Args = append(req.Args, "-0")
+		execReq.Args = append(execReq.Args, "-0")
 	}
 
-	req.Args = append(req.Args, treeish, "--")
-	req.Args = append(req.Args, paths...)
+	execReq.Args = append(execReq.Args, treeish, "--")
+	execReq.Args = append(execReq.Args, paths...)
 
-	s.exec(w, r, req)
+	w = &successHeaderResponseWriter{
+		ResponseWriter: w,
+		key:            "Cache-Control",
+		value:          protocol.ArchiveCacheControl(treeish),
+	}
+	s.exec(w, r, execReq)
 }
 
 func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
@@ -461,6 +461,38 @@ func (f *flushingResponseWriter) Close() {
 	f.mu.Unlock()
 }
 
+// successHeaderResponseWriter is a http.ResponseWriter that sets the header
+// key to value only on successful (200 OK) responses, e.g. so that error
+// responses aren't cached.
+type successHeaderResponseWriter struct {
+	http.ResponseWriter
+	key, value  string
+	wroteHeader bool
+}
+
+func (w *successHeaderResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code == http.StatusOK {
+		w.Header().Set(w.key, w.value)
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *successHeaderResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, so that a flushingResponseWriter can wrap
+// w.
+func (w *successHeaderResponseWriter) Flush() {
+	if f := hackilyGetHTTPFlusher(w.ResponseWriter); f != nil {
+		f.Flush()
+	}
+}
+
 // progressWriter is an io.Writer that writes to a buffer.
 // '\r' resets the write offset to the index after last '\n' in the buffer,
 // or the beginning of the buffer if a '\n' has not been written yet.
@@ -163,6 +163,21 @@ func serveTestNotification(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if webhookURL := args.SavedSearch.Config.WebhookURL; webhookURL != nil && *webhookURL != "" {
+		if err := webhookNotify(r.Context(), *webhookURL, &webhookPayload{
+			Description: args.SavedSearch.Config.Description,
+			Query:       args.SavedSearch.Config.Query,
+			URL:         searchURL(args.SavedSearch.Config.Query, utmSourceWebhook),
+			ResultCount: "0",
+			Test:        true,
+		}); err != nil {
+			// 🚨 SECURITY: Don't return the error, which would tell the user
+			// how the host of the URL responded.
+			log15.Error("Failed to post test webhook notification for saved search.", "spec", args.SavedSearch.Spec, "error", err)
+			writeError(w, errors.New("error sending webhook notification, see the query-runner logs for details"))
+			return
+		}
+	}
 
 	log15.Info("saved query test notification sent", "spec", args.SavedSearch.Spec, "key", args.SavedSearch.Spec.Key)
 }
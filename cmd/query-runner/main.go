@@ -116,6 +116,7 @@ func (e *executorT) run(ctx context.Context) error {
 			sendNotificationsForCreatedOrUpdatedOrDeleted(oldList, allSavedQueries)
 		}
 		oldList = allSavedQueries
+		differ.forgetDeleted(allSavedQueries)
 
 		start := time.Now()
 		for spec, config := range allSavedQueries {
@@ -138,15 +139,10 @@ func (e *executorT) run(ctx context.Context) error {
 // runQuery runs the given query if an appropriate amount of time has elapsed
 // since it last ran.
 func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSavedQuery) error {
-	if !query.Notify && !query.NotifySlack {
+	if !query.Notify && !query.NotifySlack && (query.WebhookURL == nil || *query.WebhookURL == "") {
 		// No need to run this query because there will be nobody to notify.
 		return nil
 	}
-	if !strings.Contains(query.Query, "type:diff") && !strings.Contains(query.Query, "type:commit") {
-		// TODO(slimsag): we temporarily do not support non-commit search
-		// queries, since those do not support the after:"time" operator.
-		return nil
-	}
 
 	info, err := api.InternalClient.SavedQueriesGetInfo(ctx, query.Query)
 	if err != nil {
@@ -176,17 +172,21 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 	}
 
 	// Construct a new query which finds search results introduced after the
-	// last time we queried.
-	var latestKnownResult time.Time
-	if info != nil {
-		latestKnownResult = info.LatestResult
-	} else {
-		// We've never executed this search query before, so use the current
-		// time. We'll most certainly find nothing, which is okay.
-		latestKnownResult = time.Now()
+	// last time we queried. Other queries don't support the after:"time"
+	// operator, so their results are diffed against the previous run below.
+	newQuery := query.Query
+	if isCommitQuery(query.Query) {
+		var latestKnownResult time.Time
+		if info != nil {
+			latestKnownResult = info.LatestResult
+		} else {
+			// We've never executed this search query before, so use the current
+			// time. We'll most certainly find nothing, which is okay.
+			latestKnownResult = time.Now()
+		}
+		afterTime := latestKnownResult.UTC().Format(time.RFC3339)
+		newQuery = strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
 	}
-	afterTime := latestKnownResult.UTC().Format(time.RFC3339)
-	newQuery := strings.Join([]string{query.Query, fmt.Sprintf(`after:"%s"`, afterTime)}, " ")
 	if debugPretendSavedQueryResultsExist {
 		debugPretendSavedQueryResultsExist = false
 		newQuery = query.Query
@@ -211,6 +211,13 @@ func (e *executorT) runQuery(ctx context.Context, spec api.SavedQueryIDSpec, que
 		return searchErr
 	}
 
+	if !isCommitQuery(query.Query) {
+		results := &v.Data.Search.Results
+		incomplete := results.LimitHit || len(results.Timedout) > 0 || len(results.Cloning) > 0
+		results.Results = differ.diff(query.Query, results.Results, incomplete)
+		results.ApproximateResultCount = resultCount(results.Results)
+	}
+
 	// Send notifications for new search results in a separate goroutine, so
 	// that we don't block other search queries from running in sequence (which
 	// is done intentionally, to ensure no overloading of searcher/gitserver).
@@ -297,9 +304,10 @@ func notify(ctx context.Context, spec api.SavedQueryIDSpec, query api.ConfigSave
 		recipients: recipients,
 	}
 
-	// Send Slack and email notifications.
+	// Send Slack, email and webhook notifications.
 	n.slackNotify(ctx)
 	n.emailNotify(ctx)
+	n.webhookNotify(ctx)
 	return nil
 }
 
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// isCommitQuery reports whether query searches commits or diffs, which support
+// the after:"time" operator. For other queries, new results are determined by
+// diffing against the results of the previous run (see resultsDiffer).
+func isCommitQuery(query string) bool {
+	return strings.Contains(query, "type:diff") || strings.Contains(query, "type:commit")
+}
+
+// resultsDiffer remembers the results of the last run of each saved query
+// that does not support after:"time", so that the next run can tell which of
+// its results are new. The results are only kept in memory: the first run
+// after the query-runner starts only records the results, and never notifies.
+type resultsDiffer struct {
+	mu   sync.Mutex
+	seen map[string]map[string]struct{} // query -> result keys
+}
+
+var differ = &resultsDiffer{seen: map[string]map[string]struct{}{}}
+
+// diff returns the results that were not in the previous run of query, and
+// records results for the next run. If incomplete is true (e.g. the search hit
+// the limit, or some repositories timed out or were cloning), results of the
+// previous run that are missing are kept instead of forgotten (otherwise they
+// would be reported as new once they are returned again). File matches are
+// returned with only their new line matches.
+func (d *resultsDiffer) diff(query string, results []interface{}, incomplete bool) (newResults []interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.seen[query]
+	cur := make(map[string]struct{}, len(prev))
+	if incomplete {
+		for k := range prev {
+			cur[k] = struct{}{}
+		}
+	}
+	defer func() { d.seen[query] = cur }()
+
+	for _, result := range results {
+		m, isMap := result.(map[string]interface{})
+		lineMatches, hasLineMatches := m["lineMatches"].([]interface{})
+		if !isMap || m["__typename"] != "FileMatch" || !hasLineMatches || len(lineMatches) == 0 {
+			k := resultKey(result)
+			cur[k] = struct{}{}
+			if _, seen := prev[k]; ok && !seen {
+				newResults = append(newResults, result)
+			}
+			continue
+		}
+
+		// Compare file matches line by line, so that a new match in a file
+		// that already matched is reported. Line numbers are not part of the
+		// key, because they change when other lines are added or removed.
+		resource, _ := m["resource"].(string)
+		var newLineMatches []interface{}
+		for _, lm := range lineMatches {
+			lmm, _ := lm.(map[string]interface{})
+			preview, _ := lmm["preview"].(string)
+			k := resource + "\x00" + strings.TrimSpace(preview)
+			cur[k] = struct{}{}
+			if _, seen := prev[k]; ok && !seen {
+				newLineMatches = append(newLineMatches, lm)
+			}
+		}
+		if len(newLineMatches) > 0 {
+			fm := make(map[string]interface{}, len(m))
+			for k, v := range m {
+				fm[k] = v
+			}
+			fm["lineMatches"] = newLineMatches
+			newResults = append(newResults, fm)
+		}
+	}
+	return newResults
+}
+
+// forgetDeleted forgets the results of saved queries that no longer exist.
+func (d *resultsDiffer) forgetDeleted(savedQueries map[api.SavedQueryIDSpec]api.ConfigSavedQuery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	exists := make(map[string]bool, len(savedQueries))
+	for _, config := range savedQueries {
+		exists[config.Query] = true
+	}
+	for query := range d.seen {
+		if !exists[query] {
+			delete(d.seen, query)
+		}
+	}
+}
+
+func resultKey(result interface{}) string {
+	if m, ok := result.(map[string]interface{}); ok {
+		if resource, ok := m["resource"].(string); ok {
+			return resource
+		}
+	}
+	b, _ := json.Marshal(result)
+	return string(b)
+}
+
+// resultCount returns the number of results, counting every line match of
+// file matches, in the format of approximateResultCount.
+func resultCount(results []interface{}) string {
+	n := 0
+	for _, result := range results {
+		m, _ := result.(map[string]interface{})
+		if lineMatches, ok := m["lineMatches"].([]interface{}); ok && len(lineMatches) > 0 {
+			n += len(lineMatches)
+		} else {
+			n++
+		}
+	}
+	return strconv.Itoa(n)
+}
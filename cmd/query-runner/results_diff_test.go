@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestResultsDiffer(t *testing.T) {
+	parse := func(s string) []interface{} {
+		var results []interface{}
+		if err := json.Unmarshal([]byte(s), &results); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	d := &resultsDiffer{seen: map[string]map[string]struct{}{}}
+	const query = "oldAPI( patternType:literal"
+
+	// The first run only records the results.
+	first := parse(`[
+		{"__typename": "FileMatch", "resource": "git://r?c#a.go", "lineMatches": [{"preview": "oldAPI()", "lineNumber": 1}]},
+		{"__typename": "FileMatch", "resource": "git://r?c#b.go", "lineMatches": []}
+	]`)
+	if got := d.diff(query, first, false); len(got) != 0 {
+		t.Fatalf("got new results %v on first run, want none", got)
+	}
+
+	// A new line in a file that already matched and a new file are new, a
+	// match that moved to a different line is not.
+	second := parse(`[
+		{"__typename": "FileMatch", "resource": "git://r?c#a.go", "lineMatches": [
+			{"preview": "  oldAPI()", "lineNumber": 5},
+			{"preview": "x := oldAPI()", "lineNumber": 9}
+		]},
+		{"__typename": "FileMatch", "resource": "git://r?c#b.go", "lineMatches": []},
+		{"__typename": "FileMatch", "resource": "git://r?c#c.go", "lineMatches": [{"preview": "oldAPI()", "lineNumber": 1}]}
+	]`)
+	want := parse(`[
+		{"__typename": "FileMatch", "resource": "git://r?c#a.go", "lineMatches": [{"preview": "x := oldAPI()", "lineNumber": 9}]},
+		{"__typename": "FileMatch", "resource": "git://r?c#c.go", "lineMatches": [{"preview": "oldAPI()", "lineNumber": 1}]}
+	]`)
+	got := d.diff(query, second, false)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := resultCount(got), "2"; got != want {
+		t.Errorf("got result count %s, want %s", got, want)
+	}
+
+	// Results missing from an incomplete run are not forgotten.
+	if got := d.diff(query, second[:1], true); len(got) != 0 {
+		t.Errorf("got new results %v, want none", got)
+	}
+	if got := d.diff(query, second[2:], false); len(got) != 0 {
+		t.Errorf("got new results %v after limited run, want none", got)
+	}
+
+	d.forgetDeleted(map[api.SavedQueryIDSpec]api.ConfigSavedQuery{})
+	if len(d.seen) != 0 {
+		t.Errorf("got %d remembered queries after deletion, want 0", len(d.seen))
+	}
+}
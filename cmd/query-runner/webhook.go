@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+
+	"github.com/inconshreveable/log15"
+	"github.com/sourcegraph/sourcegraph/internal/env"
+	"github.com/sourcegraph/sourcegraph/internal/webhook"
+)
+
+const (
+	utmSourceWebhook = "saved-search-webhook"
+	webhookEvent     = "saved_search.results"
+)
+
+var webhookSecret = env.Get("SAVED_SEARCH_WEBHOOK_SECRET", "", "Secret used to sign saved search webhook notifications (see the X-Sourcegraph-Signature header).")
+
+// webhookPayload is the JSON body POSTed to the webhook URL of a saved search.
+//
+// 🚨 SECURITY: It must not include the results themselves. Saved searches are
+// run as an internal actor, which bypasses repository permissions, and any
+// user can set the webhook URL of their saved searches. Receivers follow URL
+// to see the results as the user they sign in as.
+type webhookPayload struct {
+	Description string `json:"description"`
+	Query       string `json:"query"`
+	URL         string `json:"url"`
+	ResultCount string `json:"resultCount"`
+	Test        bool   `json:"test,omitempty"`
+}
+
+func (n *notifier) webhookNotify(ctx context.Context) {
+	if n.query.WebhookURL == nil || *n.query.WebhookURL == "" {
+		return
+	}
+	payload := &webhookPayload{
+		Description: n.query.Description,
+		Query:       n.query.Query,
+		URL:         searchURL(n.newQuery, utmSourceWebhook),
+		ResultCount: n.results.Data.Search.Results.ApproximateResultCount,
+	}
+	if err := webhookNotify(ctx, *n.query.WebhookURL, payload); err != nil {
+		log15.Error("Failed to post webhook notification for new saved search results.", "description", n.query.Description, "error", err)
+		return
+	}
+	logEvent(0, "SavedSearchWebhookNotificationSent", "results")
+}
+
+// webhookDispatcher delivers saved search webhooks. Since any user can set the
+// webhook URL of their saved searches, it only connects to public addresses.
+var webhookDispatcher = &webhook.Dispatcher{Client: webhook.PublicClient}
+
+// webhookNotify POSTs payload to webhookURL as JSON, signed with
+// SAVED_SEARCH_WEBHOOK_SECRET if it is set.
+func webhookNotify(ctx context.Context, webhookURL string, payload *webhookPayload) error {
+	return webhookDispatcher.Deliver(ctx, webhook.Endpoint{URL: webhookURL, Secret: webhookSecret}, webhookEvent, payload)
+}
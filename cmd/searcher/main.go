@@ -4,7 +4,6 @@ package main
 
 import (
 	"context"
-	"io"
 	"log"
 	"net"
 	"net/http"
@@ -12,12 +11,13 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/inconshreveable/log15"
 
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/search"
-	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/debugserver"
 	"github.com/sourcegraph/sourcegraph/internal/env"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
@@ -28,6 +28,7 @@ import (
 
 var cacheDir = env.Get("CACHE_DIR", "/tmp", "directory to store cached archives.")
 var cacheSizeMB = env.Get("SEARCHER_CACHE_SIZE_MB", "100000", "maximum size of the on disk cache in megabytes")
+var drainTimeout = env.Get("SEARCHER_DRAIN_TIMEOUT", "25s", "maximum time to wait for search requests in flight to finish and to shut down (must be shorter than the termination grace period of the pod)")
 
 const port = "3181"
 
@@ -48,9 +49,7 @@ func main() {
 
 	service := &search.Service{
 		Store: &store.Store{
-			FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
-				return gitserver.DefaultClient.Archive(ctx, repo, gitserver.ArchiveOptions{Treeish: string(commit), Format: "tar"})
-			},
+			FetchTar:          gitserver.DefaultClient.FetchTar,
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
@@ -68,8 +67,14 @@ func main() {
 	server := &http.Server{
 		Addr: addr,
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// For cluster liveness and readiness probes
+			// For cluster liveness and readiness probes. While draining, we
+			// fail them so that we are removed from the service endpoints.
 			if r.URL.Path == "/healthz" {
+				if service.Draining() {
+					w.Header().Set(protocol.DrainingHeader, "true")
+					http.Error(w, "draining", http.StatusServiceUnavailable)
+					return
+				}
 				w.WriteHeader(200)
 				_, _ = w.Write([]byte("ok"))
 				return
@@ -77,7 +82,7 @@ func main() {
 			handler.ServeHTTP(w, r)
 		}),
 	}
-	go shutdownOnSIGINT(server)
+	go shutdownOnSignal(server, service)
 
 	log15.Info("searcher: listening", "addr", server.Addr)
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -85,13 +90,41 @@ func main() {
 	}
 }
 
-func shutdownOnSIGINT(s *http.Server) {
+// shutdownOnSignal drains service and shuts down s on SIGINT or SIGTERM
+// (which Kubernetes sends to pods it deletes, e.g. in a rolling deploy).
+// Requests in flight are given time to finish, while new requests are rejected
+// so that clients send them to other searchers. Draining and shutting down s
+// together take at most SEARCHER_DRAIN_TIMEOUT, which must be shorter than the
+// termination grace period of the pod (30s by default) so that searcher is not
+// killed first.
+func shutdownOnSignal(s *http.Server, service *search.Service) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	timeout, err := time.ParseDuration(drainTimeout)
+	if err != nil {
+		log15.Error("searcher: invalid SEARCHER_DRAIN_TIMEOUT, using 25s", "value", drainTimeout, "error", err)
+		timeout = 25 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	err := s.Shutdown(ctx)
+
+	// Leave part of the time for shutting down s, which waits for the
+	// responses of the requests that finished draining to be written.
+	shutdownTime := timeout / 5
+	if shutdownTime > 5*time.Second {
+		shutdownTime = 5 * time.Second
+	}
+	log15.Info("searcher: draining", "timeout", timeout-shutdownTime)
+	service.Drain()
+	drainCtx, cancelDrain := context.WithTimeout(ctx, timeout-shutdownTime)
+	defer cancelDrain()
+	if err := service.WaitInFlight(drainCtx); err != nil {
+		log15.Warn("searcher: search requests still in flight after drain timeout", "error", err)
+	}
+
+	err = s.Shutdown(ctx)
 	if err != nil {
 		log.Fatal("graceful server shutdown failed, will exit:", err)
 	}
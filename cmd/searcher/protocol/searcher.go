@@ -9,6 +9,17 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 )
 
+// RequestIDHeader is the HTTP header containing the ID of the search request
+// (across all repositories) that a request to searcher is part of. Searcher
+// includes it in its logs and traces.
+const RequestIDHeader = "X-Sourcegraph-Search-Request-Id"
+
+// DrainingHeader is the HTTP header that searcher sets to "true" on its
+// responses while it is draining (e.g. during a rolling deploy). A draining
+// searcher finishes the requests in flight but rejects new ones with status
+// 503, so clients should send requests to other searchers instead.
+const DrainingHeader = "X-Sourcegraph-Searcher-Draining"
+
 // Request represents a request to searcher
 type Request struct {
 	// Repo is the name of the repository to search. eg "github.com/gorilla/mux"
@@ -60,13 +71,67 @@ type PatternInfo struct {
 	// IsStructuralPat if true will treat the pattern as a Comby structural search pattern.
 	IsStructuralPat bool
 
-	// IsWordMatch if true will only match the pattern at word boundaries.
+	// IsPCRE if true will match the regular expression Pattern with the
+	// PCRE-compatible engine of internal/search/pcre, which supports
+	// lookarounds and backreferences, instead of Go's RE2 engine. The time
+	// that matching each file takes is limited.
+	IsPCRE bool
+
+	// IsWordMatch if true will only match the pattern where it is a whole
+	// word. Words are identifiers of the language of a file (see
+	// IsIdentifierMatch), and WordCharacters can add characters to them.
 	IsWordMatch bool
 
+	// IsIdentifierMatch if true will only match the pattern where it is a
+	// whole identifier of the language of a file (see
+	// internal/search/identifier). If IdentifierSubTokens is also true,
+	// sub-tokens of identifiers (such as "Handler" in "requestHandler")
+	// match, too.
+	IsIdentifierMatch   bool
+	IdentifierSubTokens bool
+
+	// WordCharacters overrides the characters besides letters, digits and
+	// "_" that are part of words and identifiers, in entries of the form
+	// "language:characters" (see identifier.WordCharacters). The language
+	// "*" applies to all languages without entries of their own.
+	WordCharacters []string
+
+	// Within is the names of the content classes (see
+	// internal/search/contentclass) that matches must start in: "code",
+	// "comment" or "string". If empty, matches can be anywhere.
+	Within []string
+
+	// GoCall and GoSelector if set mean that the pattern is only used to
+	// find candidate Go files, which are parsed to match the calls of the
+	// functions or methods named GoCall, or the selectors named GoSelector
+	// (see internal/search/goast), such as "mux.NewRouter" or "Handler".
+	GoCall     string
+	GoSelector string
+
+	// ModifiedSince (a time in RFC 3339 format) and ModifiedSinceRevision if
+	// set mean that only the files that were added or modified by the
+	// commits of Commit that were committed after ModifiedSince, or that
+	// aren't ancestors of the revision ModifiedSinceRevision, are searched
+	// (see git.ChangedPaths). If the repository has no revision
+	// ModifiedSinceRevision, no files are searched.
+	ModifiedSince         string
+	ModifiedSinceRevision string
+
 	// IsCaseSensitive if false will ignore the case of text and pattern
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// CaseFold if true (and IsCaseSensitive is false) will fold the case of
+	// all Unicode letters when finding matches, instead of only the case of
+	// ASCII letters (which is faster).
+	CaseFold bool
+
+	// Normalization if set is the Unicode normalization form, "nfc" or
+	// "nfd", that the pattern and the text are converted to when finding
+	// matches, so that text matches regardless of whether its accented
+	// characters are composed or decomposed.
+	Normalization string
+
 	// ExcludePattern is a pattern that may not match the returned files' paths.
 	// eg '**/node_modules'
 	ExcludePattern string
@@ -105,13 +170,36 @@ type PatternInfo struct {
 
 	// CombyRule is a rule that constrains matching for structural search. It only applies when IsStructuralPat is true.
 	CombyRule string
+
+	// CountOnly if true means that only the number of matches in each file is
+	// returned (FileMatch.MatchCount), without LineMatches. This is much
+	// cheaper when the matches themselves are not displayed.
+	CountOnly bool
+
+	// MaxLineMatches is the maximum number of matches returned per file. If
+	// 0, searcher's default limit is used. It is capped at
+	// MaxLineMatchesLimit.
+	MaxLineMatches int
+
+	// Deterministic if true means that if FileMatchLimit is hit, the matches
+	// of the first files by path are returned, instead of those of the first
+	// files searched (which depends on the scheduling of the workers). All
+	// files are searched, which is slower.
+	Deterministic bool
 }
 
+// MaxLineMatchesLimit is the largest PatternInfo.MaxLineMatches searcher
+// accepts. Larger values are treated as this limit.
+const MaxLineMatchesLimit = 10000
+
 func (p *PatternInfo) String() string {
 	args := []string{fmt.Sprintf("%q", p.Pattern)}
 	if p.IsRegExp {
 		args = append(args, "re")
 	}
+	if p.IsPCRE {
+		args = append(args, "pcre")
+	}
 	if p.IsStructuralPat {
 		if p.CombyRule != "" {
 			args = append(args, fmt.Sprintf("comby:%s", p.CombyRule))
@@ -122,9 +210,40 @@ func (p *PatternInfo) String() string {
 	if p.IsWordMatch {
 		args = append(args, "word")
 	}
+	if p.IsIdentifierMatch {
+		if p.IdentifierSubTokens {
+			args = append(args, "identifier:subtokens")
+		} else {
+			args = append(args, "identifier")
+		}
+	}
+	if len(p.WordCharacters) > 0 {
+		args = append(args, fmt.Sprintf("wordchars:%q", p.WordCharacters))
+	}
+	for _, class := range p.Within {
+		args = append(args, "within:"+class)
+	}
+	if p.GoCall != "" {
+		args = append(args, "gocall:"+p.GoCall)
+	}
+	if p.GoSelector != "" {
+		args = append(args, "goselector:"+p.GoSelector)
+	}
+	if p.ModifiedSince != "" {
+		args = append(args, "modified:"+p.ModifiedSince)
+	}
+	if p.ModifiedSinceRevision != "" {
+		args = append(args, "modified:"+p.ModifiedSinceRevision)
+	}
 	if p.IsCaseSensitive {
 		args = append(args, "case")
 	}
+	if p.CaseFold {
+		args = append(args, "casefold")
+	}
+	if p.Normalization != "" {
+		args = append(args, "normalize:"+p.Normalization)
+	}
 	if !p.PatternMatchesContent {
 		args = append(args, "nocontent")
 	}
@@ -134,6 +253,15 @@ func (p *PatternInfo) String() string {
 	if p.FileMatchLimit > 0 {
 		args = append(args, fmt.Sprintf("filematchlimit:%d", p.FileMatchLimit))
 	}
+	if p.CountOnly {
+		args = append(args, "countonly")
+	}
+	if p.MaxLineMatches > 0 {
+		args = append(args, fmt.Sprintf("maxlinematches:%d", p.MaxLineMatches))
+	}
+	if p.Deterministic {
+		args = append(args, "deterministic")
+	}
 	for _, lang := range p.Languages {
 		args = append(args, fmt.Sprintf("lang:%s", lang))
 	}
@@ -164,6 +292,10 @@ type Response struct {
 
 	// DeadlineHit is true if Matches may not include all FileMatches because a deadline was hit.
 	DeadlineHit bool
+
+	// Cached is true if the archive of the repository was served from searcher's
+	// local cache, rather than fetched from gitserver.
+	Cached bool
 }
 
 // FileMatch is the struct used by vscode to receive search results
@@ -175,6 +307,28 @@ type FileMatch struct {
 
 	// LimitHit is true if LineMatches may not include all LineMatches.
 	LimitHit bool
+
+	// MatchRanges are the ranges of the matches in the file. Unlike
+	// LineMatches, a match that spans multiple lines is a single range. It is
+	// empty in the responses of searchers that predate it.
+	MatchRanges []Range `json:",omitempty"`
+}
+
+// Range is the range of a match in a file.
+type Range struct {
+	// Start is the position of the first character of the match, and End the
+	// position after its last character.
+	Start, End Position
+}
+
+// Position is a position in a file.
+type Position struct {
+	// Line is the 0-based line number.
+	Line int
+
+	// Character is the 0-based offset in the line, measured in characters,
+	// not bytes (like LineMatch.OffsetAndLengths).
+	Character int
 }
 
 // LineMatch is the struct used by vscode to receive search results for a line.
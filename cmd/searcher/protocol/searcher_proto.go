@@ -0,0 +1,281 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// ProtobufContentType is the content type of responses encoded with
+// MarshalProto. Clients that accept it (in the Accept header) get responses
+// in this format instead of JSON, which is much cheaper to decode for large
+// result sets.
+const ProtobufContentType = "application/x-protobuf"
+
+// The Protocol Buffers encoding of Response is described by searcher.proto.
+// It is hand-written, since it only has a few fields and avoids the
+// reflection that makes JSON decoding slow.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Field numbers (see searcher.proto).
+const (
+	responseMatches     = 1
+	responseLimitHit    = 2
+	responseDeadlineHit = 3
+	responseCached      = 4
+
+	fileMatchPath        = 1
+	fileMatchLineMatches = 2
+	fileMatchMatchCount  = 3
+	fileMatchLimitHit    = 4
+	fileMatchMatchRanges = 5
+
+	lineMatchPreview          = 1
+	lineMatchLineNumber       = 2
+	lineMatchOffsetAndLengths = 3
+	lineMatchLimitHit         = 4
+)
+
+// MarshalProto returns the Protocol Buffers encoding of r.
+func (r *Response) MarshalProto() []byte {
+	var e, fm, lm protoEncoder
+	for _, m := range r.Matches {
+		fm.reset()
+		fm.string(fileMatchPath, m.Path)
+		for _, l := range m.LineMatches {
+			lm.reset()
+			lm.string(lineMatchPreview, l.Preview)
+			lm.varint(lineMatchLineNumber, uint64(l.LineNumber))
+			if len(l.OffsetAndLengths) > 0 {
+				var packed protoEncoder
+				for _, ol := range l.OffsetAndLengths {
+					packed.uvarint(uint64(ol[0]))
+					packed.uvarint(uint64(ol[1]))
+				}
+				lm.bytes(lineMatchOffsetAndLengths, packed.buf)
+			}
+			lm.bool(lineMatchLimitHit, l.LimitHit)
+			fm.bytes(fileMatchLineMatches, lm.buf)
+		}
+		fm.varint(fileMatchMatchCount, uint64(m.MatchCount))
+		fm.bool(fileMatchLimitHit, m.LimitHit)
+		if len(m.MatchRanges) > 0 {
+			var packed protoEncoder
+			for _, r := range m.MatchRanges {
+				packed.uvarint(uint64(r.Start.Line))
+				packed.uvarint(uint64(r.Start.Character))
+				packed.uvarint(uint64(r.End.Line))
+				packed.uvarint(uint64(r.End.Character))
+			}
+			fm.bytes(fileMatchMatchRanges, packed.buf)
+		}
+		e.bytes(responseMatches, fm.buf)
+	}
+	e.bool(responseLimitHit, r.LimitHit)
+	e.bool(responseDeadlineHit, r.DeadlineHit)
+	e.bool(responseCached, r.Cached)
+	return e.buf
+}
+
+// UnmarshalProto decodes the Protocol Buffers encoding of a Response (see
+// MarshalProto) into r.
+func (r *Response) UnmarshalProto(b []byte) error {
+	*r = Response{}
+	return decodeProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch field {
+		case responseMatches:
+			if wire != wireBytes {
+				return nil
+			}
+			var m FileMatch
+			if err := m.unmarshalProto(data); err != nil {
+				return err
+			}
+			r.Matches = append(r.Matches, m)
+		case responseLimitHit:
+			r.LimitHit = v != 0
+		case responseDeadlineHit:
+			r.DeadlineHit = v != 0
+		case responseCached:
+			r.Cached = v != 0
+		}
+		return nil
+	})
+}
+
+func (m *FileMatch) unmarshalProto(b []byte) error {
+	return decodeProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch field {
+		case fileMatchPath:
+			m.Path = string(data)
+		case fileMatchLineMatches:
+			if wire != wireBytes {
+				return nil
+			}
+			var l LineMatch
+			if err := l.unmarshalProto(data); err != nil {
+				return err
+			}
+			m.LineMatches = append(m.LineMatches, l)
+		case fileMatchMatchCount:
+			m.MatchCount = int(v)
+		case fileMatchLimitHit:
+			m.LimitHit = v != 0
+		case fileMatchMatchRanges:
+			if wire != wireBytes {
+				return nil
+			}
+			for len(data) > 0 {
+				var v [4]int
+				for i := range v {
+					x, n := binary.Uvarint(data)
+					if n <= 0 {
+						return errInvalidProto
+					}
+					data = data[n:]
+					v[i] = int(x)
+				}
+				m.MatchRanges = append(m.MatchRanges, Range{
+					Start: Position{Line: v[0], Character: v[1]},
+					End:   Position{Line: v[2], Character: v[3]},
+				})
+			}
+		}
+		return nil
+	})
+}
+
+func (l *LineMatch) unmarshalProto(b []byte) error {
+	return decodeProto(b, func(field int, wire int, v uint64, data []byte) error {
+		switch field {
+		case lineMatchPreview:
+			l.Preview = string(data)
+		case lineMatchLineNumber:
+			l.LineNumber = int(v)
+		case lineMatchOffsetAndLengths:
+			if wire != wireBytes {
+				return nil
+			}
+			for len(data) > 0 {
+				offset, n := binary.Uvarint(data)
+				if n <= 0 {
+					return errInvalidProto
+				}
+				data = data[n:]
+				length, n := binary.Uvarint(data)
+				if n <= 0 {
+					return errInvalidProto
+				}
+				data = data[n:]
+				l.OffsetAndLengths = append(l.OffsetAndLengths, [2]int{int(offset), int(length)})
+			}
+		case lineMatchLimitHit:
+			l.LimitHit = v != 0
+		}
+		return nil
+	})
+}
+
+var errInvalidProto = errors.New("invalid protobuf encoding of searcher response")
+
+// decodeProto calls f for each field of the message encoded in b. v is the
+// value of varint fields, and data the contents of length-delimited ones.
+// Fields of other wire types are skipped.
+func decodeProto(b []byte, f func(field int, wire int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 || tag>>3 > math.MaxInt32 {
+			return errInvalidProto
+		}
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+
+		var (
+			v    uint64
+			data []byte
+		)
+		switch wire {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errInvalidProto
+			}
+			b = b[n:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errInvalidProto
+			}
+			data = b[n : n+int(size)]
+			b = b[n+int(size):]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if wire == wireFixed32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errInvalidProto
+			}
+			b = b[size:]
+			continue
+		default:
+			return errInvalidProto
+		}
+		if err := f(field, wire, v, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type protoEncoder struct {
+	buf []byte
+	tmp [binary.MaxVarintLen64]byte
+}
+
+func (e *protoEncoder) reset() { e.buf = e.buf[:0] }
+
+func (e *protoEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.tmp[:], v)
+	e.buf = append(e.buf, e.tmp[:n]...)
+}
+
+func (e *protoEncoder) tag(field, wire int) {
+	e.uvarint(uint64(field)<<3 | uint64(wire))
+}
+
+// Zero values are omitted, as in proto3.
+
+func (e *protoEncoder) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.uvarint(v)
+}
+
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.varint(field, 1)
+	}
+}
+
+func (e *protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *protoEncoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
@@ -0,0 +1,51 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResponse_MarshalProto(t *testing.T) {
+	tests := []Response{
+		{},
+		{LimitHit: true, DeadlineHit: true, Cached: true},
+		{Matches: []FileMatch{
+			{Path: "a.go", MatchCount: 3, LimitHit: true, LineMatches: []LineMatch{
+				{Preview: "foo(foo)", LineNumber: 0, OffsetAndLengths: [][2]int{{0, 3}, {4, 3}}},
+				{Preview: "ünïcode foo", LineNumber: 300, OffsetAndLengths: [][2]int{{8, 3}}, LimitHit: true},
+			}},
+			{Path: "b.go", MatchCount: 1, LineMatches: []LineMatch{
+				{Preview: "foo(", LineNumber: 1, OffsetAndLengths: [][2]int{{0, 4}}},
+				{Preview: ")", LineNumber: 2, OffsetAndLengths: [][2]int{{0, 1}}},
+			}, MatchRanges: []Range{{Start: Position{Line: 1}, End: Position{Line: 2, Character: 1}}}},
+			{Path: "c.go", MatchCount: 0},
+			{},
+		}},
+	}
+	for _, want := range tests {
+		var got Response
+		if err := got.UnmarshalProto(want.MarshalProto()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestResponse_UnmarshalProto_invalid(t *testing.T) {
+	b := (&Response{Matches: []FileMatch{{Path: "a.go"}}}).MarshalProto()
+	var r Response
+	if err := r.UnmarshalProto(b[:len(b)-1]); err == nil {
+		t.Error("got no error for truncated input")
+	}
+
+	// Unknown fields are skipped, so that fields can be added.
+	unknown := append([]byte{15<<3 | wireVarint, 1, 14<<3 | wireFixed32, 0, 0, 0, 0}, b...)
+	if err := r.UnmarshalProto(unknown); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Matches) != 1 || r.Matches[0].Path != "a.go" {
+		t.Errorf("got %+v, want the match a.go", r)
+	}
+}
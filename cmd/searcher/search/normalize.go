@@ -0,0 +1,54 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// parseNormalization returns the Unicode normalization form of
+// protocol.PatternInfo.Normalization. ok is false if there is none.
+func parseNormalization(s string) (f norm.Form, ok bool, err error) {
+	switch s {
+	case "":
+		return 0, false, nil
+	case "nfc":
+		return norm.NFC, true, nil
+	case "nfd":
+		return norm.NFD, true, nil
+	}
+	return 0, false, fmt.Errorf("invalid normalization %q, expected nfc or nfd", s)
+}
+
+// normSegment is the start of a segment of normalized text, at norm in the
+// normalized text and at orig in the original text.
+type normSegment struct {
+	norm, orig int
+}
+
+// normalizeWithOffsets appends the normalization of src in the form f to
+// dst, and the starts of its segments (followed by the ends of the texts) to
+// segs, which map the offsets in the normalized text back to src.
+func normalizeWithOffsets(f norm.Form, dst []byte, segs []normSegment, src []byte) ([]byte, []normSegment) {
+	var it norm.Iter
+	it.Init(f, src)
+	for !it.Done() {
+		segs = append(segs, normSegment{norm: len(dst), orig: it.Pos()})
+		dst = append(dst, it.Next()...)
+	}
+	return dst, append(segs, normSegment{norm: len(dst), orig: len(src)})
+}
+
+// origOffset returns the offset in the original text of the offset i in
+// the normalized text that segs describe. Offsets within a segment, which
+// characters were composed or decomposed in, are moved to its start, or to
+// its end if roundUp is set, so that a match covers the whole segment.
+func origOffset(segs []normSegment, i int, roundUp bool) int {
+	if roundUp {
+		j := sort.Search(len(segs), func(j int) bool { return segs[j].norm >= i })
+		return segs[j].orig
+	}
+	j := sort.Search(len(segs), func(j int) bool { return segs[j].norm > i }) - 1
+	return segs[j].orig
+}
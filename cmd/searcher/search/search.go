@@ -18,13 +18,18 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/inconshreveable/log15"
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 	nettrace "golang.org/x/net/trace"
 
 	"github.com/pkg/errors"
@@ -39,8 +44,8 @@ const (
 	// maxFileMatches is the limit on number of matching files we return.
 	maxFileMatches = 1000
 
-	// maxLineMatches is the limit on number of matches to return in a
-	// file.
+	// maxLineMatches is the default limit on number of matches to return in
+	// a file. Requests can change it with PatternInfo.MaxLineMatches.
 	maxLineMatches = 100
 
 	// numWorkers is how many concurrent readerGreps run in the case of
@@ -53,6 +58,36 @@ const (
 type Service struct {
 	Store *store.Store
 	Log   log15.Logger
+
+	draining int32 // accessed atomically
+	inFlight int64 // accessed atomically
+}
+
+// Drain makes s reject new search requests, telling clients that it is
+// draining (see protocol.DrainingHeader), while the requests in flight
+// finish.
+func (s *Service) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+}
+
+// Draining reports whether Drain was called.
+func (s *Service) Draining() bool {
+	return atomic.LoadInt32(&s.draining) == 1
+}
+
+// WaitInFlight waits until no search requests are in flight, or until ctx is
+// done.
+func (s *Service) WaitInFlight(ctx context.Context) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&s.inFlight) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
 }
 
 var decoder = schema.NewDecoder()
@@ -64,6 +99,16 @@ func init() {
 // ServeHTTP handles HTTP based search requests
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	if id := r.Header.Get(protocol.RequestIDHeader); id != "" {
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+	}
+	if s.Draining() {
+		w.Header().Set(protocol.DrainingHeader, "true")
+		http.Error(w, "searcher is draining", http.StatusServiceUnavailable)
+		return
+	}
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
 	running.Inc()
 	defer running.Dec()
 
@@ -99,7 +144,7 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matches, limitHit, deadlineHit, err := s.search(ctx, &p)
+	matches, limitHit, deadlineHit, cached, err := s.search(ctx, &p)
 	if err != nil {
 		code := http.StatusInternalServerError
 		if isBadRequest(err) || ctx.Err() == context.Canceled {
@@ -107,7 +152,7 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		} else if isTemporary(err) {
 			code = http.StatusServiceUnavailable
 		} else {
-			log.Printf("internal error serving %#+v: %s", p, err)
+			log.Printf("internal error serving %#+v (search request ID %q): %s", p, requestID(ctx), err)
 		}
 		http.Error(w, err.Error(), code)
 		return
@@ -117,12 +162,18 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		matches = make([]protocol.FileMatch, 0)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	resp := protocol.Response{
 		Matches:     matches,
 		LimitHit:    limitHit,
 		DeadlineHit: deadlineHit,
+		Cached:      cached,
+	}
+	if strings.Contains(r.Header.Get("Accept"), protocol.ProtobufContentType) {
+		w.Header().Set("Content-Type", protocol.ProtobufContentType)
+		_, _ = w.Write(resp.MarshalProto())
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	// The only reasonable error is the client going away now since we know we
 	// can encode resp. This happens relatively often due to our
 	// graphqlbackend regularly cancelling in-flight requests. We can't send
@@ -130,7 +181,7 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(&resp)
 }
 
-func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []protocol.FileMatch, limitHit, deadlineHit bool, err error) {
+func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []protocol.FileMatch, limitHit, deadlineHit, cached bool, err error) {
 	tr := nettrace.New("search", fmt.Sprintf("%s@%s", p.Repo, p.Commit))
 	tr.LazyPrintf("%s", p.Pattern)
 
@@ -142,15 +193,22 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	span.SetTag("pattern", p.Pattern)
 	span.SetTag("isRegExp", strconv.FormatBool(p.IsRegExp))
 	span.SetTag("isStructuralPat", strconv.FormatBool(p.IsStructuralPat))
+	span.SetTag("isPCRE", strconv.FormatBool(p.IsPCRE))
 	span.SetTag("languages", p.Languages)
 	span.SetTag("isWordMatch", strconv.FormatBool(p.IsWordMatch))
 	span.SetTag("isCaseSensitive", strconv.FormatBool(p.IsCaseSensitive))
 	span.SetTag("pathPatternsAreRegExps", strconv.FormatBool(p.PathPatternsAreRegExps))
 	span.SetTag("pathPatternsAreCaseSensitive", strconv.FormatBool(p.PathPatternsAreCaseSensitive))
 	span.SetTag("fileMatchLimit", p.FileMatchLimit)
+	span.SetTag("countOnly", p.CountOnly)
+	span.SetTag("maxLineMatches", p.MaxLineMatches)
 	span.SetTag("patternMatchesContent", p.PatternMatchesContent)
 	span.SetTag("patternMatchesPath", p.PatternMatchesPath)
 	span.SetTag("deadline", p.Deadline)
+	if id := requestID(ctx); id != "" {
+		span.SetTag("searchRequestID", id)
+		tr.LazyPrintf("search request ID: %s", id)
+	}
 	defer func(start time.Time) {
 		code := "200"
 		// We often have canceled and timed out requests. We do not want to
@@ -182,15 +240,27 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 		span.LogFields(otlog.Int("matches.len", len(matches)))
 		span.SetTag("limitHit", limitHit)
 		span.SetTag("deadlineHit", deadlineHit)
+		span.SetTag("cached", cached)
 		span.Finish()
 		if s.Log != nil {
-			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isStructuralPat", p.IsStructuralPat, "languages", p.Languages, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "err", err)
+			s.Log.Debug("search request", "repo", p.Repo, "commit", p.Commit, "pattern", p.Pattern, "isRegExp", p.IsRegExp, "isStructuralPat", p.IsStructuralPat, "languages", p.Languages, "isWordMatch", p.IsWordMatch, "isCaseSensitive", p.IsCaseSensitive, "patternMatchesContent", p.PatternMatchesContent, "patternMatchesPath", p.PatternMatchesPath, "matches", len(matches), "code", code, "duration", time.Since(start), "searchRequestID", requestID(ctx), "err", err)
 		}
 	}(time.Now())
 
 	rg, err := compile(&p.PatternInfo)
 	if err != nil {
-		return nil, false, false, badRequestError{err.Error()}
+		return nil, false, false, false, badRequestError{err.Error()}
+	}
+	if p.ModifiedSince != "" || p.ModifiedSinceRevision != "" {
+		paths, err := changedPaths(ctx, p)
+		if err != nil {
+			return nil, false, false, false, err
+		}
+		tr.LazyPrintf("changed paths=%d", len(paths))
+		if len(paths) == 0 {
+			return nil, false, false, false, nil
+		}
+		rg.matchPath = changedPathsMatcher{PathMatcher: rg.matchPath, paths: paths}
 	}
 
 	if p.FetchTimeout == "" {
@@ -198,13 +268,14 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 	}
 	fetchTimeout, err := time.ParseDuration(p.FetchTimeout)
 	if err != nil {
-		return nil, false, false, err
+		return nil, false, false, false, err
 	}
 	prepareCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
 	defer cancel()
 
 	getZf := func() (string, *store.ZipFile, error) {
-		path, err := s.Store.PrepareZip(prepareCtx, p.GitserverRepo(), p.Commit)
+		path, zipCached, err := s.Store.PrepareZipCached(prepareCtx, p.GitserverRepo(), p.Commit)
+		cached = zipCached
 		if err != nil {
 			return "", nil, err
 		}
@@ -214,7 +285,7 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 
 	zipPath, zf, err := store.GetZipFileWithRetry(getZf)
 	if err != nil {
-		return nil, false, false, errors.Wrap(err, "failed to get archive")
+		return nil, false, false, false, errors.Wrap(err, "failed to get archive")
 	}
 	defer zf.Close()
 
@@ -229,10 +300,68 @@ func (s *Service) search(ctx context.Context, p *protocol.Request) (matches []pr
 
 	if p.IsStructuralPat {
 		matches, limitHit, err = structuralSearch(ctx, zipPath, p.Pattern, p.CombyRule, p.Languages, p.IncludePatterns, p.Repo)
+		if p.CountOnly {
+			for i := range matches {
+				matches[i].LineMatches = nil
+			}
+		}
 	} else {
-		matches, limitHit, err = regexSearch(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath)
+		matches, limitHit, err = regexSearch(ctx, rg, zf, p.FileMatchLimit, p.PatternMatchesContent, p.PatternMatchesPath, p.Deterministic)
+	}
+	return matches, limitHit, false, cached, err
+}
+
+// changedPaths returns the set of the paths of the files that were changed
+// by the commits of p.Commit that p.ModifiedSince and p.ModifiedSinceRevision
+// select.
+func changedPaths(ctx context.Context, p *protocol.Request) (map[string]struct{}, error) {
+	var opt git.ChangedPathsOptions
+	if p.ModifiedSince != "" {
+		since, err := time.Parse(time.RFC3339, p.ModifiedSince)
+		if err != nil {
+			return nil, badRequestError{fmt.Sprintf("invalid ModifiedSince: %s", err)}
+		}
+		opt.Since = since
+	}
+	if p.ModifiedSinceRevision != "" {
+		if _, err := git.ResolveRevision(ctx, p.GitserverRepo(), nil, p.ModifiedSinceRevision, nil); err != nil {
+			if gitserver.IsRevisionNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		opt.Base = p.ModifiedSinceRevision
 	}
-	return matches, limitHit, false, err
+	paths, err := git.ChangedPaths(ctx, p.GitserverRepo(), p.Commit, opt)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		set[path] = struct{}{}
+	}
+	return set, nil
+}
+
+// changedPathsMatcher is a pathmatch.PathMatcher that only matches the paths
+// that PathMatcher matches and that are in paths.
+type changedPathsMatcher struct {
+	pathmatch.PathMatcher
+	paths map[string]struct{}
+}
+
+func (m changedPathsMatcher) MatchPath(path string) bool {
+	_, ok := m.paths[path]
+	return ok && m.PathMatcher.MatchPath(path)
+}
+
+type requestIDKey struct{}
+
+// requestID returns the ID of the frontend search request that the searcher
+// request in ctx is part of (see protocol.RequestIDHeader), or "".
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
 }
 
 func validateParams(p *protocol.Request) error {
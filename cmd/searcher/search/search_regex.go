@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"path"
 	"regexp"
 	"regexp/syntax"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,11 +18,16 @@ import (
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/internal/pathmatch"
+	"github.com/sourcegraph/sourcegraph/internal/search/contentclass"
+	"github.com/sourcegraph/sourcegraph/internal/search/goast"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
+	"github.com/sourcegraph/sourcegraph/internal/search/pcre"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
 
 	"github.com/opentracing/opentracing-go/ext"
 	otlog "github.com/opentracing/opentracing-go/log"
+	"golang.org/x/text/unicode/norm"
 )
 
 // readerGrep is responsible for finding LineMatches. It is not concurrency
@@ -41,7 +48,11 @@ import (
 // TODO(keegan) return search statistics
 type readerGrep struct {
 	// re is the regexp to match, or nil if empty ("match all files' content").
-	re *regexp.Regexp
+	re matcher
+
+	// timedOut is whether matching the last file with a PCRE pattern timed
+	// out (see pcreMatchTimeout), so that its matches may be incomplete.
+	timedOut bool
 
 	// ignoreCase if true means we need to do case insensitive matching.
 	ignoreCase bool
@@ -52,10 +63,46 @@ type readerGrep struct {
 	// ignoreCase.
 	transformBuf []byte
 
+	// normalize if true means that the pattern and the input are converted
+	// to the Unicode normalization form normForm before matching, so that
+	// canonically equivalent text (such as "é" composed or decomposed)
+	// matches. normBuf and normSegments are reused between file searches,
+	// and normSegments maps the matches in the last normalized input back to
+	// the original, or is nil if the input was already normalized.
+	normalize    bool
+	normForm     norm.Form
+	normBuf      []byte
+	normSegments []normSegment
+
 	// matchPath is compiled from the include/exclude path patterns and reports
 	// whether a file path matches (and should be searched).
 	matchPath pathmatch.PathMatcher
 
+	// countOnly if true means only the number of matches in each file is
+	// needed, so line matches (with their previews and offsets) are not built.
+	countOnly bool
+
+	// maxLineMatches is the limit on number of matches to return in a file.
+	maxLineMatches int
+
+	// identifier if true means that only the matches of re that are whole
+	// identifiers (or sub-tokens of identifiers, if identifierSubTokens) in
+	// the language of a file are matches. Word matches are identifier
+	// matches, too. wordCharacters overrides the characters of identifiers.
+	identifier          bool
+	identifierSubTokens bool
+	wordCharacters      identifier.WordCharacters
+
+	// within is the set of the content classes that matches must start in.
+	// If it isn't contentclass.All, files are lexed (if their language is
+	// supported, otherwise they are all code) to filter their matches.
+	within contentclass.Set
+
+	// goAST if set returns the locations of the matches in the source of a
+	// Go file, which are found by parsing it (see protocol.GoCall). re only
+	// selects the files that are parsed.
+	goAST func(src []byte) [][]int
+
 	// literalSubstring is used to test if a file is worth considering for
 	// matches. literalSubstring is guaranteed to appear in any match found by
 	// re. It is the output of the longestLiteral function. It is only set if
@@ -63,46 +110,84 @@ type readerGrep struct {
 	literalSubstring []byte
 }
 
+// matcher is a compiled regexp of either engine: a *regexp.Regexp, or a
+// *pcre.Regexp for PCRE patterns (see protocol.PatternInfo.IsPCRE).
+type matcher interface {
+	FindAllIndex(b []byte, n int) [][]int
+	Match(b []byte) bool
+	MatchString(s string) bool
+	String() string
+}
+
+// pcreMatchTimeout is the limit on the time that matching a file with a PCRE
+// pattern takes, since backtracking can take exponential time. The matches
+// found before are returned with LimitHit set.
+const pcreMatchTimeout = 100 * time.Millisecond
+
 // compile returns a readerGrep for matching p.
 func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	var (
-		re               *regexp.Regexp
+		re               matcher
 		literalSubstring []byte
 	)
-	if p.Pattern != "" {
-		expr := p.Pattern
+	normForm, normalize, err := parseNormalization(p.Normalization)
+	if err != nil {
+		return nil, err
+	}
+	pattern := p.Pattern
+	if normalize {
+		pattern = normForm.String(pattern)
+	}
+	// With CaseFold, the regexp engine folds the case of all Unicode
+	// letters, instead of the input being lowercased (ASCII only).
+	foldCase := !p.IsCaseSensitive && (p.CaseFold || p.IsPCRE)
+
+	if pattern != "" && p.IsRegExp && p.IsPCRE {
+		// The PCRE engine matches case-insensitively itself, and has no
+		// literal substring to prune files with.
+		expr := "(?m:" + pattern + ")"
+		if !p.IsCaseSensitive {
+			expr = "(?i)" + expr
+		}
+		pcreRe, err := pcre.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		re = pcreRe.WithTimeout(pcreMatchTimeout)
+	} else if pattern != "" {
+		expr := pattern
 		if !p.IsRegExp {
 			expr = regexp.QuoteMeta(expr)
 		}
-		if p.IsWordMatch {
-			expr = `\b` + expr + `\b`
-		}
 		if p.IsRegExp {
 			// We don't do the search line by line, therefore we want the
 			// regex engine to consider newlines for anchors (^$).
 			expr = "(?m:" + expr + ")"
 		}
-		if !p.IsCaseSensitive {
+		if foldCase {
+			expr = "(?i)" + expr
+		} else if !p.IsCaseSensitive {
 			// We don't just use (?i) because regexp library doesn't seem
 			// to contain good optimizations for case insensitive
 			// search. Instead we lowercase the input and pattern.
 			re, err := syntax.Parse(expr, syntax.Perl)
 			if err != nil {
-				return nil, err
+				return nil, pcre.RE2Error(expr, err)
 			}
 			lowerRegexpASCII(re)
 			expr = re.String()
 		}
 
-		var err error
-		re, err = regexp.Compile(expr)
+		re2, err := regexp.Compile(expr)
 		if err != nil {
-			return nil, err
+			return nil, pcre.RE2Error(expr, err)
 		}
+		re = re2
 
 		// Only use literalSubstring optimization if the regex engine doesn't
-		// have a prefix to use.
-		if pre, _ := re.LiteralPrefix(); pre == "" {
+		// have a prefix to use. The literals of case-folding regexps match
+		// other cases, too.
+		if pre, _ := re2.LiteralPrefix(); pre == "" && !foldCase {
 			ast, err := syntax.Parse(expr, syntax.Perl)
 			if err != nil {
 				return nil, err
@@ -121,11 +206,45 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 		return nil, err
 	}
 
+	wordCharacters, err := identifier.ParseWordCharacters(p.WordCharacters)
+	if err != nil {
+		return nil, err
+	}
+
+	within, err := contentclass.ParseSet(p.Within)
+	if err != nil {
+		return nil, err
+	}
+
+	goAST, err := compileGoAST(p)
+	if err != nil {
+		return nil, err
+	}
+	if goAST != nil && re == nil {
+		return nil, errors.New("Go AST searches require a pattern")
+	}
+
+	limit := p.MaxLineMatches
+	if limit <= 0 {
+		limit = maxLineMatches
+	} else if limit > protocol.MaxLineMatchesLimit {
+		limit = protocol.MaxLineMatchesLimit
+	}
+
 	return &readerGrep{
-		re:               re,
-		ignoreCase:       !p.IsCaseSensitive,
-		matchPath:        matchPath,
-		literalSubstring: literalSubstring,
+		re:                  re,
+		ignoreCase:          !p.IsCaseSensitive && !foldCase,
+		normalize:           normalize,
+		normForm:            normForm,
+		matchPath:           matchPath,
+		countOnly:           p.CountOnly,
+		maxLineMatches:      limit,
+		identifier:          p.IsIdentifierMatch || p.IsWordMatch,
+		identifierSubTokens: p.IsIdentifierMatch && p.IdentifierSubTokens,
+		wordCharacters:      wordCharacters,
+		within:              within,
+		goAST:               goAST,
+		literalSubstring:    literalSubstring,
 	}, nil
 }
 
@@ -133,10 +252,19 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 // goroutine.
 func (rg *readerGrep) Copy() *readerGrep {
 	return &readerGrep{
-		re:               rg.re,
-		ignoreCase:       rg.ignoreCase,
-		matchPath:        rg.matchPath,
-		literalSubstring: rg.literalSubstring,
+		re:                  rg.re,
+		ignoreCase:          rg.ignoreCase,
+		normalize:           rg.normalize,
+		normForm:            rg.normForm,
+		matchPath:           rg.matchPath,
+		countOnly:           rg.countOnly,
+		maxLineMatches:      rg.maxLineMatches,
+		identifier:          rg.identifier,
+		identifierSubTokens: rg.identifierSubTokens,
+		wordCharacters:      rg.wordCharacters,
+		within:              rg.within,
+		goAST:               rg.goAST,
+		literalSubstring:    rg.literalSubstring,
 	}
 }
 
@@ -149,16 +277,18 @@ func (rg *readerGrep) matchString(s string) bool {
 	if rg.ignoreCase {
 		s = strings.ToLower(s)
 	}
+	if rg.normalize {
+		s = rg.normForm.String(s)
+	}
 	return rg.re.MatchString(s)
 }
 
-// Find returns a LineMatch for each line that matches rg in reader.
-// LimitHit is true if some matches may not have been included in the result.
+// matchBuf returns fileBuf as rg's regexp needs to run on it (e.g. lowercased if
+// ignoring case, or normalized). The result is only valid until the next call
+// of matchBuf. The offsets of the matches that rg.findAll returns are in
+// fileBuf, though.
 // NOTE: This is not safe to use concurrently.
-func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, limitHit bool, err error) {
-	// fileMatchBuf is what we run match on, fileBuf is the original
-	// data (for Preview).
-	fileBuf := zf.DataFor(f)
+func (rg *readerGrep) matchBuf(zf *store.ZipFile, fileBuf []byte) []byte {
 	fileMatchBuf := fileBuf
 
 	// If we are ignoring case, we transform the input instead of
@@ -174,6 +304,26 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 		bytesToLowerASCII(fileMatchBuf, fileBuf)
 	}
 
+	// Normalizing changes the offsets of the text, so it is only done for
+	// files that aren't normalized already, which most aren't (e.g. ASCII).
+	rg.normSegments = rg.normSegments[:0]
+	if rg.normalize && !rg.normForm.IsNormal(fileMatchBuf) {
+		rg.normBuf, rg.normSegments = normalizeWithOffsets(rg.normForm, rg.normBuf[:0], rg.normSegments, fileMatchBuf)
+		fileMatchBuf = rg.normBuf
+	}
+	return fileMatchBuf
+}
+
+// Find returns a LineMatch for each line that matches rg in reader, and the
+// range of each match. LimitHit is true if some matches may not have been
+// included in the result.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []protocol.LineMatch, ranges []protocol.Range, limitHit bool, err error) {
+	// fileMatchBuf is what we run match on, fileBuf is the original
+	// data (for Preview).
+	fileBuf := zf.DataFor(f)
+	fileMatchBuf := rg.matchBuf(zf, fileBuf)
+
 	// Most files will not have a match and we bound the number of matched
 	// files we return. So we can avoid the overhead of parsing out new lines
 	// and repeatedly running the regex engine by running a single match over
@@ -182,10 +332,15 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 	// per-line. Additionally if we have a non-empty literalSubstring, we use
 	// that to prune out files since doing bytes.Index is very fast.
 	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
-		return nil, false, nil
+		return nil, nil, false, nil
 	}
 
-	locs := rg.re.FindAllIndex(fileMatchBuf, maxLineMatches+1)
+	locs := rg.findAllIndex(f.Name, fileBuf, fileMatchBuf)
+	limitHit = rg.timedOut
+	if len(rg.normSegments) > 0 {
+		// The locations are in fileBuf, which has the same lines.
+		fileMatchBuf = fileBuf
+	}
 	lastStart := 0
 	lastLineNumber := 0
 	lastMatchIndex := 0
@@ -217,14 +372,118 @@ func (rg *readerGrep) Find(zf *store.ZipFile, f *store.SrcFile) (matches []proto
 		lastMatchIndex = matchIndex
 		lastLineNumber = lineNumber
 		matches = appendMatches(matches, fileBuf[lineStart:lineEnd], fileMatchBuf[lineStart:lineEnd], lineNumber, start-lineStart, end-lineStart)
+		ranges = append(ranges, matchRange(fileMatchBuf, lineNumber, lineStart, start, end))
 
-		if len(matches) > maxLineMatches {
-			matches = matches[:maxLineMatches]
+		if len(matches) > rg.maxLineMatches {
+			matches = matches[:rg.maxLineMatches]
 			limitHit = true
 			break
 		}
 	}
-	return matches, limitHit, nil
+	return matches, ranges, limitHit, nil
+}
+
+// findAllIndex returns the locations of up to rg.maxLineMatches+1 matches of
+// rg in fileMatchBuf, the transformed data of the file with the given name.
+// The matches of identifier searches are checked against fileBuf, the
+// original data, whose case separates camelCase sub-tokens, and so are the
+// content classes of matches. It sets rg.timedOut.
+func (rg *readerGrep) findAllIndex(name string, fileBuf, fileMatchBuf []byte) [][]int {
+	rg.timedOut = false
+	if rg.goAST == nil && !rg.identifier && rg.within == contentclass.All {
+		return rg.findAll(fileMatchBuf, rg.maxLineMatches+1)
+	}
+	var candidates [][]int
+	if rg.goAST != nil {
+		if path.Ext(name) != ".go" || len(rg.findAll(fileMatchBuf, 1)) == 0 {
+			return nil
+		}
+		candidates = rg.goAST(fileBuf)
+	} else {
+		candidates = rg.findAll(fileMatchBuf, -1)
+	}
+
+	var (
+		m      identifier.Matcher
+		ranges contentclass.Ranges
+	)
+	if rg.identifier {
+		m = rg.wordCharacters.MatcherForPath(name, rg.identifierSubTokens)
+	}
+	if rg.within != contentclass.All {
+		ranges, _ = contentclass.LexPath(name, fileBuf)
+	}
+	var locs [][]int
+	for _, loc := range candidates {
+		if rg.identifier && !m.Match(fileBuf, loc[0], loc[1]) {
+			continue
+		}
+		if rg.within != contentclass.All && !rg.within.Has(ranges.ClassAt(loc[0])) {
+			continue
+		}
+		locs = append(locs, loc)
+		if len(locs) > rg.maxLineMatches {
+			break
+		}
+	}
+	return locs
+}
+
+// findAll returns the locations of up to n (or all, if n < 0) matches of
+// rg.re in b, the result of matchBuf, as offsets in the original data of the
+// file. If matching with the PCRE engine times out, it sets rg.timedOut and
+// returns the matches found before.
+func (rg *readerGrep) findAll(b []byte, n int) [][]int {
+	var locs [][]int
+	if re, ok := rg.re.(*pcre.Regexp); ok {
+		var timedOut bool
+		locs, timedOut = re.FindAllIndexTimeout(b, n)
+		rg.timedOut = rg.timedOut || timedOut
+	} else {
+		locs = rg.re.FindAllIndex(b, n)
+	}
+	if len(rg.normSegments) > 0 {
+		for _, loc := range locs {
+			loc[0], loc[1] = origOffset(rg.normSegments, loc[0], false), origOffset(rg.normSegments, loc[1], true)
+		}
+	}
+	return locs
+}
+
+// compileGoAST returns the function that finds the matches of the Go AST
+// search of p, or nil if p isn't a Go AST search.
+func compileGoAST(p *protocol.PatternInfo) (func(src []byte) [][]int, error) {
+	var find func(src []byte, n goast.Name) [][]int
+	s := p.GoCall
+	switch {
+	case p.GoCall != "" && p.GoSelector != "":
+		return nil, errors.New("GoCall and GoSelector are mutually exclusive")
+	case p.GoCall != "":
+		find = goast.Calls
+	case p.GoSelector != "":
+		find, s = goast.Selectors, p.GoSelector
+	default:
+		return nil, nil
+	}
+	n, err := goast.ParseName(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(src []byte) [][]int { return find(src, n) }, nil
+}
+
+// matchRange returns the range of the match fileBuf[start:end], which starts
+// on the line with the given number that starts at lineStart.
+func matchRange(fileBuf []byte, lineNumber, lineStart, start, end int) protocol.Range {
+	endLine, endLineStart := lineNumber, lineStart
+	if idx := bytes.LastIndexByte(fileBuf[start:end], '\n'); idx >= 0 {
+		endLine += bytes.Count(fileBuf[start:end], []byte{'\n'})
+		endLineStart = start + idx + 1
+	}
+	return protocol.Range{
+		Start: protocol.Position{Line: lineNumber, Character: utf8.RuneCount(fileBuf[lineStart:start])},
+		End:   protocol.Position{Line: endLine, Character: utf8.RuneCount(fileBuf[endLineStart:end])},
+	}
 }
 
 func hydrateLineNumbers(fileBuf []byte, lastLineNumber, lastMatchIndex, lineStart int, match []int) (lineNumber, matchIndex int) {
@@ -283,19 +542,47 @@ func appendMatches(matches []protocol.LineMatch, fileBuf []byte, matchLineBuf []
 	return matches
 }
 
-// FindZip is a convenience function to run Find on f.
+// Count returns the number of matches of rg in f, without building
+// LineMatches. LimitHit is true if there are more than rg.maxLineMatches
+// matches, in which case rg.maxLineMatches is returned, or if matching timed
+// out.
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) Count(zf *store.ZipFile, f *store.SrcFile) (count int, limitHit bool) {
+	fileBuf := zf.DataFor(f)
+	fileMatchBuf := rg.matchBuf(zf, fileBuf)
+	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
+		return 0, false
+	}
+	count = len(rg.findAllIndex(f.Name, fileBuf, fileMatchBuf))
+	if count > rg.maxLineMatches {
+		return rg.maxLineMatches, true
+	}
+	return count, rg.timedOut
+}
+
+// FindZip is a convenience function to run Find (or Count, if rg is count
+// only) on f.
 func (rg *readerGrep) FindZip(zf *store.ZipFile, f *store.SrcFile) (protocol.FileMatch, error) {
-	lm, limitHit, err := rg.Find(zf, f)
+	if rg.countOnly {
+		count, limitHit := rg.Count(zf, f)
+		return protocol.FileMatch{
+			Path:       f.Name,
+			MatchCount: count,
+			LimitHit:   limitHit,
+		}, nil
+	}
+	lm, ranges, limitHit, err := rg.Find(zf, f)
 	return protocol.FileMatch{
 		Path:        f.Name,
 		LineMatches: lm,
 		MatchCount:  len(lm),
 		LimitHit:    limitHit,
+		MatchRanges: ranges,
 	}, err
 }
 
 // regexSearch concurrently searches files in zr looking for matches using rg.
-func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths bool) (fm []protocol.FileMatch, limitHit bool, err error) {
+func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMatchLimit int, patternMatchesContent, patternMatchesPaths, deterministic bool) (fm []protocol.FileMatch, limitHit bool, err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "RegexSearch")
 	ext.Component.Set(span, "regex_search")
 	if rg.re != nil {
@@ -360,6 +647,7 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 		wgErr         error
 		filesSkipped  uint32 // accessed atomically
 		filesSearched uint32 // accessed atomically
+		filesTimedOut uint32 // accessed atomically
 	)
 
 	// Start workers. They read from files and write to matches.
@@ -403,7 +691,14 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 					})
 					return
 				}
-				match := len(fm.LineMatches) > 0
+				if rg.timedOut {
+					// The file may have more matches than were found.
+					atomic.AddUint32(&filesTimedOut, 1)
+					matchesmu.Lock()
+					limitHit = true
+					matchesmu.Unlock()
+				}
+				match := len(fm.LineMatches) > 0 || fm.MatchCount > 0
 				if !match && patternMatchesPaths {
 					// Try matching against the file path.
 					match = rg.matchString(f.Name)
@@ -415,6 +710,14 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 					matchesmu.Lock()
 					if len(matches) < fileMatchLimit {
 						matches = append(matches, fm)
+					} else if deterministic {
+						// Keep searching, and only keep the matches of the
+						// first files by path.
+						limitHit = true
+						matches = append(matches, fm)
+						if len(matches) >= 2*fileMatchLimit {
+							matches = firstFileMatches(matches, fileMatchLimit)
+						}
 					} else {
 						limitHit = true
 						cancel()
@@ -436,11 +739,25 @@ func regexSearch(ctx context.Context, rg *readerGrep, zf *store.ZipFile, fileMat
 	span.LogFields(
 		otlog.Int("filesSkipped", int(atomic.LoadUint32(&filesSkipped))),
 		otlog.Int("filesSearched", int(atomic.LoadUint32(&filesSearched))),
+		otlog.Int("filesTimedOut", int(atomic.LoadUint32(&filesTimedOut))),
 	)
 
+	if deterministic {
+		matches = firstFileMatches(matches, fileMatchLimit)
+	}
 	return matches, limitHit, err
 }
 
+// firstFileMatches returns the (at most) limit matches of the first files by
+// path, sorted by path.
+func firstFileMatches(matches []protocol.FileMatch, limit int) []protocol.FileMatch {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
 // lowerRegexpASCII lowers rune literals and expands char classes to include
 // lowercase. It does it inplace. We can't just use strings.ToLower since it
 // will change the meaning of regex shorthands like \S or \B.
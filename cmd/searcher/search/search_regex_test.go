@@ -4,12 +4,14 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"reflect"
 	"regexp"
 	"regexp/syntax"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"testing/iotest"
 	"testing/quick"
@@ -264,7 +266,7 @@ func benchSearchRegex(b *testing.B, p *protocol.Request) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		_, _, err := regexSearch(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath)
+		_, _, err := regexSearch(ctx, rg, zf, 0, p.PatternMatchesContent, p.PatternMatchesPath, false)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -454,7 +456,7 @@ func TestMaxMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false)
+	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -480,6 +482,444 @@ func TestMaxMatches(t *testing.T) {
 // - IncludePatterns can match the path in any order
 // - A path must match all (not any) of the IncludePatterns
 // - An empty pattern is allowed
+func TestMaxMatches_deterministic(t *testing.T) {
+	pattern := "foo"
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for i := 0; i < 100; i++ {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			// In reverse order, so that the first files searched are the
+			// last ones by path.
+			Name:   fmt.Sprintf("%03d", 99-i),
+			Method: zip.Store,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(pattern + "\n"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rg, err := compile(&protocol.PatternInfo{Pattern: pattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, 5, true, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit {
+		t.Error("expected limitHit on regexSearch")
+	}
+	var paths []string
+	for _, fm := range fileMatches {
+		paths = append(paths, fm.Path)
+	}
+	if want := []string{"000", "001", "002", "003", "004"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got file matches %v, want %v", paths, want)
+	}
+}
+
+func TestMaxLineMatches(t *testing.T) {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "a", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3*maxLineMatches; i++ {
+		_, _ = w.Write([]byte("foo\n"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		maxLineMatches int
+		wantCount      int
+		wantLimitHit   bool
+	}{
+		{0, maxLineMatches, true},
+		{2 * maxLineMatches, 2 * maxLineMatches, true},
+		{10 * maxLineMatches, 3 * maxLineMatches, false},
+	}
+	for _, test := range tests {
+		rg, err := compile(&protocol.PatternInfo{Pattern: "foo", MaxLineMatches: test.maxLineMatches})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fileMatches) != 1 {
+			t.Fatalf("MaxLineMatches %d: got %d file matches, want 1", test.maxLineMatches, len(fileMatches))
+		}
+		fm := fileMatches[0]
+		if len(fm.LineMatches) != test.wantCount || fm.LimitHit != test.wantLimitHit {
+			t.Errorf("MaxLineMatches %d: got %d line matches (limitHit %v), want %d (limitHit %v)", test.maxLineMatches, len(fm.LineMatches), fm.LimitHit, test.wantCount, test.wantLimitHit)
+		}
+	}
+
+	rg, err := compile(&protocol.PatternInfo{Pattern: "foo", MaxLineMatches: 10 * protocol.MaxLineMatchesLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rg.maxLineMatches != protocol.MaxLineMatchesLimit {
+		t.Errorf("got maxLineMatches %d, want it capped at %d", rg.maxLineMatches, protocol.MaxLineMatchesLimit)
+	}
+}
+
+func TestPCRETimeout(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"a": "ok\n" + strings.Repeat("a", 40) + "!\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second alternative backtracks catastrophically on the second line.
+	rg, err := compile(&protocol.PatternInfo{Pattern: `^ok$|^(\w+\s?)*$`, IsRegExp: true, IsPCRE: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMatches, limitHit, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !limitHit {
+		t.Error("got limitHit false, want true when matching times out")
+	}
+	if len(fileMatches) != 1 || len(fileMatches[0].LineMatches) != 1 || !fileMatches[0].LimitHit {
+		t.Fatalf("got file matches %+v, want the match before the timeout with LimitHit", fileMatches)
+	}
+}
+
+func TestUnicodeMatches(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"composed":   "x café\n",
+		"decomposed": "x cafe\u0301\n",
+		"upper":      "ÉCOLE\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		p    protocol.PatternInfo
+		want []string // path:offset:length of each match
+	}{
+		{protocol.PatternInfo{Pattern: "café", IsCaseSensitive: true}, []string{"composed:2:4"}},
+		{protocol.PatternInfo{Pattern: "café", IsCaseSensitive: true, Normalization: "nfc"}, []string{"composed:2:4", "decomposed:2:5"}},
+		{protocol.PatternInfo{Pattern: "cafe\u0301", IsCaseSensitive: true, Normalization: "nfd"}, []string{"composed:2:4", "decomposed:2:5"}},
+		{protocol.PatternInfo{Pattern: `f.$`, IsRegExp: true, IsCaseSensitive: true, Normalization: "nfc"}, []string{"composed:4:2", "decomposed:4:3"}},
+		{protocol.PatternInfo{Pattern: "école"}, nil},
+		{protocol.PatternInfo{Pattern: "école", CaseFold: true}, []string{"upper:0:5"}},
+		{protocol.PatternInfo{Pattern: "CAFÉ", CaseFold: true, Normalization: "nfc"}, []string{"composed:2:4", "decomposed:2:5"}},
+	}
+	for _, test := range tests {
+		rg, err := compile(&test.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, fm := range fileMatches {
+			for _, lm := range fm.LineMatches {
+				for _, ol := range lm.OffsetAndLengths {
+					got = append(got, fmt.Sprintf("%s:%d:%d", fm.Path, ol[0], ol[1]))
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", &test.p, got, test.want)
+		}
+	}
+
+	if _, err := compile(&protocol.PatternInfo{Pattern: "x", Normalization: "nfkc"}); err == nil {
+		t.Error("got no error for an invalid normalization")
+	}
+}
+
+func TestMatchRanges(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"a": "é foo\nbar baz\nfoo\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rg, err := compile(&protocol.PatternInfo{Pattern: `foo\nbar|foo\n$`, IsRegExp: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fileMatches) != 1 {
+		t.Fatalf("got %d file matches, want 1", len(fileMatches))
+	}
+	want := []protocol.Range{
+		{Start: protocol.Position{Line: 0, Character: 2}, End: protocol.Position{Line: 1, Character: 3}},
+		{Start: protocol.Position{Line: 2, Character: 0}, End: protocol.Position{Line: 3, Character: 0}},
+	}
+	if got := fileMatches[0].MatchRanges; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ranges %+v, want %+v", got, want)
+	}
+}
+
+func TestIdentifierMatches(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go":   "id := valid(id)\nrequestHandler(ID)\nHandler\n",
+		"main.js":   "$id = id\n",
+		"style.css": "#box-id, #id {}\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern   string
+		subTokens bool
+		want      map[string][][2]int // offsets of the matches by path
+	}{
+		{"id", false, map[string][][2]int{
+			"main.go":   {{0, 2}, {12, 2}, {15, 2}},
+			"main.js":   {{6, 2}},
+			"style.css": {{10, 2}},
+		}},
+		{"handler", false, map[string][][2]int{
+			"main.go": {{0, 7}},
+		}},
+		{"handler", true, map[string][][2]int{
+			"main.go": {{7, 7}, {0, 7}},
+		}},
+	}
+	for _, test := range tests {
+		rg, err := compile(&protocol.PatternInfo{Pattern: test.pattern, IsIdentifierMatch: true, IdentifierSubTokens: test.subTokens})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][][2]int{}
+		for _, fm := range fileMatches {
+			for _, lm := range fm.LineMatches {
+				got[fm.Path] = append(got[fm.Path], lm.OffsetAndLengths...)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q (sub-tokens %v): got matches %v, want %v", test.pattern, test.subTokens, got, test.want)
+		}
+	}
+}
+
+func TestWordMatches(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go":   "x := f(x)\n",
+		"style.css": "#box-x, #x {}\n",
+		"run.sh":    "echo $x\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern        string
+		wordCharacters []string
+		want           map[string][][2]int // offsets of the matches by path
+	}{
+		{"x", nil, map[string][][2]int{
+			"main.go":   {{0, 1}, {7, 1}},
+			"style.css": {{9, 1}},
+			"run.sh":    {{6, 1}},
+		}},
+		{"f(", nil, map[string][][2]int{
+			"main.go": {{5, 2}},
+		}},
+		{"x", []string{"Shell:$", "CSS:"}, map[string][][2]int{
+			"main.go":   {{0, 1}, {7, 1}},
+			"style.css": {{5, 1}, {9, 1}},
+		}},
+		{"$x", []string{"*:$"}, map[string][][2]int{
+			"run.sh": {{5, 2}},
+		}},
+	}
+	for _, test := range tests {
+		rg, err := compile(&protocol.PatternInfo{Pattern: test.pattern, IsWordMatch: true, WordCharacters: test.wordCharacters})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][][2]int{}
+		for _, fm := range fileMatches {
+			for _, lm := range fm.LineMatches {
+				got[fm.Path] = append(got[fm.Path], lm.OffsetAndLengths...)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q (word characters %q): got matches %v, want %v", test.pattern, test.wordCharacters, got, test.want)
+		}
+	}
+
+	if _, err := compile(&protocol.PatternInfo{Pattern: "x", IsWordMatch: true, WordCharacters: []string{"Go"}}); err == nil {
+		t.Error("got no error for invalid word characters")
+	}
+}
+
+func TestWithinMatches(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go":   "// TODO: a\nf(\"TODO\") /* TODO */\n",
+		"README.md": "TODO\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		within []string
+		want   map[string][][2]int // offsets of the matches by path
+	}{
+		{nil, map[string][][2]int{
+			"main.go":   {{3, 4}, {3, 4}, {13, 4}},
+			"README.md": {{0, 4}},
+		}},
+		{[]string{"comment"}, map[string][][2]int{
+			"main.go": {{3, 4}, {13, 4}},
+		}},
+		{[]string{"string"}, map[string][][2]int{
+			"main.go": {{3, 4}},
+		}},
+		{[]string{"code"}, map[string][][2]int{
+			"README.md": {{0, 4}}, // Markdown is not lexed
+		}},
+	}
+	for _, test := range tests {
+		rg, err := compile(&protocol.PatternInfo{Pattern: "TODO", IsCaseSensitive: true, Within: test.within})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][][2]int{}
+		for _, fm := range fileMatches {
+			for _, lm := range fm.LineMatches {
+				got[fm.Path] = append(got[fm.Path], lm.OffsetAndLengths...)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("within %q: got matches %v, want %v", test.within, got, test.want)
+		}
+	}
+
+	if _, err := compile(&protocol.PatternInfo{Pattern: "TODO", Within: []string{"docs"}}); err == nil {
+		t.Error("got no error for an invalid content class")
+	}
+}
+
+func TestGoASTMatches(t *testing.T) {
+	zipData, err := testutil.CreateZip(map[string]string{
+		"main.go":   "package main\n\n// New calls New.\nfunc New() { x.New(New) }\n",
+		"README.md": "New()\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zf, err := store.MockZipFile(zipData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		p    protocol.PatternInfo
+		want map[string][][2]int // offsets of the matches by path
+	}{
+		{protocol.PatternInfo{Pattern: "New", GoCall: "New"}, map[string][][2]int{
+			"main.go": {{15, 3}},
+		}},
+		{protocol.PatternInfo{Pattern: "New", GoCall: "y.New"}, map[string][][2]int{}},
+		{protocol.PatternInfo{Pattern: "New", GoSelector: "x.New"}, map[string][][2]int{
+			"main.go": {{13, 5}},
+		}},
+	}
+	for _, test := range tests {
+		test.p.IsCaseSensitive = true
+		rg, err := compile(&test.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileMatches, _, err := regexSearch(context.Background(), rg, zf, maxFileMatches, true, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][][2]int{}
+		for _, fm := range fileMatches {
+			for _, lm := range fm.LineMatches {
+				got[fm.Path] = append(got[fm.Path], lm.OffsetAndLengths...)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got matches %v, want %v", test.p.String(), got, test.want)
+		}
+	}
+
+	for _, p := range []protocol.PatternInfo{
+		{Pattern: "New", GoCall: "New()"},
+		{Pattern: "New", GoCall: "New", GoSelector: "New"},
+		{GoCall: "New"},
+	} {
+		if _, err := compile(&p); err == nil {
+			t.Errorf("%s: got no error", p.String())
+		}
+	}
+}
+
 func TestPathMatches(t *testing.T) {
 	zipData, err := testutil.CreateZip(map[string]string{
 		"a":   "",
@@ -506,7 +946,7 @@ func TestPathMatches(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	fileMatches, _, err := regexSearch(context.Background(), rg, zf, 10, true, true)
+	fileMatches, _, err := regexSearch(context.Background(), rg, zf, 10, true, true, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -581,7 +1021,7 @@ func TestRegexSearch(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotFm, gotLimitHit, err := regexSearch(tt.args.ctx, tt.args.rg, tt.args.zf, tt.args.fileMatchLimit, tt.args.patternMatchesContent, tt.args.patternMatchesPaths)
+			gotFm, gotLimitHit, err := regexSearch(tt.args.ctx, tt.args.rg, tt.args.zf, tt.args.fileMatchLimit, tt.args.patternMatchesContent, tt.args.patternMatchesPaths, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("regexSearch() error = %v, wantErr %v", err, tt.wantErr)
 				return
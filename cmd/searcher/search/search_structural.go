@@ -68,8 +68,14 @@ func highlightMultipleLines(r *comby.Match) (matches []protocol.LineMatch) {
 func ToFileMatch(combyMatches []comby.FileMatch) (matches []protocol.FileMatch) {
 	for _, m := range combyMatches {
 		var lineMatches []protocol.LineMatch
+		var ranges []protocol.Range
 		for _, r := range m.Matches {
 			lineMatches = append(lineMatches, highlightMultipleLines(&r)...)
+			// Comby lines and columns are 1-based.
+			ranges = append(ranges, protocol.Range{
+				Start: protocol.Position{Line: r.Range.Start.Line - 1, Character: r.Range.Start.Column - 1},
+				End:   protocol.Position{Line: r.Range.End.Line - 1, Character: r.Range.End.Column - 1},
+			})
 		}
 		matches = append(matches,
 			protocol.FileMatch{
@@ -77,6 +83,7 @@ func ToFileMatch(combyMatches []comby.FileMatch) (matches []protocol.FileMatch)
 				LineMatches: lineMatches,
 				MatchCount:  len(m.Matches),
 				LimitHit:    false,
+				MatchRanges: ranges,
 			})
 	}
 	return matches
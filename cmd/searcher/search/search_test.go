@@ -13,10 +13,12 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
 	"github.com/sourcegraph/sourcegraph/cmd/searcher/search"
@@ -24,6 +26,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 	"github.com/sourcegraph/sourcegraph/internal/store"
 	"github.com/sourcegraph/sourcegraph/internal/testutil"
+	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
 
 func TestSearch(t *testing.T) {
@@ -86,6 +89,11 @@ main.go:5:func main() {
 		{protocol.PatternInfo{Pattern: "main", IsWordMatch: true}, `
 main.go:1:package main
 main.go:5:func main() {
+`},
+
+		{protocol.PatternInfo{Pattern: "hello", IsWordMatch: true, WordCharacters: []string{`*:"`}}, `
+README.md:1:# Hello World
+README.md:3:Hello world example in go
 `},
 
 		// Ensure we handle CaseInsensitive regexp searches with
@@ -180,6 +188,15 @@ main.go:7:}
 `},
 
 		{protocol.PatternInfo{Pattern: "^$", IsRegExp: true}, ``},
+
+		{protocol.PatternInfo{Pattern: "hello (?=world)", IsRegExp: true, IsPCRE: true}, `
+README.md:1:# Hello World
+README.md:3:Hello world example in go
+main.go:6:	fmt.Println("Hello world")
+`},
+		{protocol.PatternInfo{Pattern: `(?<!")Hello (?!World)\w+`, IsRegExp: true, IsPCRE: true, IsCaseSensitive: true}, `
+README.md:3:Hello world example in go
+`},
 	}
 
 	store, cleanup, err := newStore(files)
@@ -225,6 +242,206 @@ main.go:7:}
 	}
 }
 
+func TestSearch_countOnly(t *testing.T) {
+	files := map[string]string{
+		"README.md": "# Hello World\n\nHello world example in go",
+		"main.go":   "package main\n\nfunc main() {\n\tfmt.Println(\"Hello world\")\n}\n",
+		"abc.txt":   "w",
+	}
+	store, cleanup, err := newStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&search.Service{Store: store})
+	defer ts.Close()
+
+	m, err := doSearch(ts.URL, &protocol.Request{
+		Repo:   "foo",
+		URL:    "u",
+		Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+		PatternInfo: protocol.PatternInfo{
+			Pattern:               "world",
+			PatternMatchesContent: true,
+			CountOnly:             true,
+		},
+		FetchTimeout: "2000ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Sort(sortByPath(m))
+	want := []protocol.FileMatch{
+		{Path: "README.md", MatchCount: 2},
+		{Path: "main.go", MatchCount: 1},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %+v, want %+v", m, want)
+	}
+}
+
+func TestSearch_modified(t *testing.T) {
+	files := map[string]string{
+		"README.md": "# Hello World\n\nHello world example in go",
+		"main.go":   "package main\n\nfunc main() {\n\tfmt.Println(\"Hello world\")\n}\n",
+		"old.go":    "package main\n\n// Hello world\n",
+	}
+	store, cleanup, err := newStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&search.Service{Store: store})
+	defer ts.Close()
+
+	since := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	git.Mocks.ResolveRevision = func(spec string, opt *git.ResolveRevisionOptions) (api.CommitID, error) {
+		if spec != "v1" {
+			return "", &gitserver.RevisionNotFoundError{Repo: "foo", Spec: spec}
+		}
+		return "cafecafecafecafecafecafecafecafecafecafe", nil
+	}
+	git.Mocks.ChangedPaths = func(head api.CommitID, opt git.ChangedPathsOptions) ([]string, error) {
+		if opt.Base == "v1" {
+			return []string{"README.md", "deleted.go"}, nil
+		}
+		if !opt.Since.Equal(since) {
+			t.Errorf("got since %s, want %s", opt.Since, since)
+		}
+		return []string{"main.go"}, nil
+	}
+	defer git.ResetMocks()
+
+	cases := []struct {
+		since, revision string
+		want            []string
+	}{
+		{since: since.Format(time.RFC3339), want: []string{"main.go"}},
+		{revision: "v1", want: []string{"README.md"}},
+		{revision: "v2", want: nil},
+	}
+	for _, c := range cases {
+		m, err := doSearch(ts.URL, &protocol.Request{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				Pattern:               "world",
+				PatternMatchesContent: true,
+				ModifiedSince:         c.since,
+				ModifiedSinceRevision: c.revision,
+			},
+			FetchTimeout: "2000ms",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, fm := range m {
+			got = append(got, fm.Path)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("since %q revision %q: got %q, want %q", c.since, c.revision, got, c.want)
+		}
+	}
+}
+
+func TestSearch_protobuf(t *testing.T) {
+	store, cleanup, err := newStore(map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	ts := httptest.NewServer(&search.Service{Store: store})
+	defer ts.Close()
+
+	form := url.Values{
+		"Repo":                  []string{"foo"},
+		"URL":                   []string{"u"},
+		"Commit":                []string{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"},
+		"Pattern":               []string{"main"},
+		"FetchTimeout":          []string{"2000ms"},
+		"PatternMatchesContent": []string{"true"},
+	}
+	req, err := http.NewRequest("POST", ts.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", protocol.ProtobufContentType)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != protocol.ProtobufContentType {
+		t.Fatalf("got Content-Type %q, want %q", got, protocol.ProtobufContentType)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r protocol.Response
+	if err := r.UnmarshalProto(body); err != nil {
+		t.Fatal(err)
+	}
+	want := []protocol.FileMatch{{
+		Path:        "main.go",
+		MatchCount:  2,
+		LineMatches: []protocol.LineMatch{{Preview: "package main", LineNumber: 0, OffsetAndLengths: [][2]int{{8, 4}}}, {Preview: "func main() {}", LineNumber: 2, OffsetAndLengths: [][2]int{{5, 4}}}},
+		MatchRanges: []protocol.Range{
+			{Start: protocol.Position{Line: 0, Character: 8}, End: protocol.Position{Line: 0, Character: 12}},
+			{Start: protocol.Position{Line: 2, Character: 5}, End: protocol.Position{Line: 2, Character: 9}},
+		},
+	}}
+	if !reflect.DeepEqual(r.Matches, want) {
+		t.Errorf("got %+v, want %+v", r.Matches, want)
+	}
+}
+
+func TestSearch_draining(t *testing.T) {
+	store, cleanup, err := newStore(map[string]string{"main.go": "package main\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	service := &search.Service{Store: store}
+	ts := httptest.NewServer(service)
+	defer ts.Close()
+
+	p := &protocol.Request{
+		Repo:         "foo",
+		URL:          "u",
+		Commit:       "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+		PatternInfo:  protocol.PatternInfo{Pattern: "main", PatternMatchesContent: true},
+		FetchTimeout: "2000ms",
+	}
+	if _, err := doSearch(ts.URL, p); err != nil {
+		t.Fatal(err)
+	}
+
+	service.Drain()
+	form := url.Values{
+		"Repo":         []string{string(p.Repo)},
+		"URL":          []string{p.URL},
+		"Commit":       []string{string(p.Commit)},
+		"Pattern":      []string{p.Pattern},
+		"FetchTimeout": []string{p.FetchTimeout},
+	}
+	resp, err := http.PostForm(ts.URL, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get(protocol.DrainingHeader) != "true" {
+		t.Errorf("got status %d and %s %q, want 503 and true", resp.StatusCode, protocol.DrainingHeader, resp.Header.Get(protocol.DrainingHeader))
+	}
+	if err := service.WaitInFlight(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSearch_badrequest(t *testing.T) {
 	cases := []protocol.Request{
 		// Bad regexp
@@ -249,6 +466,18 @@ func TestSearch_badrequest(t *testing.T) {
 			},
 		},
 
+		// Unsupported lookbehind in a PCRE regexp
+		{
+			Repo:   "foo",
+			URL:    "u",
+			Commit: "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			PatternInfo: protocol.PatternInfo{
+				Pattern:  `(?<=a+)b`,
+				IsRegExp: true,
+				IsPCRE:   true,
+			},
+		},
+
 		// No repo
 		{
 			URL:    "u",
@@ -353,6 +582,7 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 		"FetchTimeout":    []string{p.FetchTimeout},
 		"IncludePatterns": p.IncludePatterns,
 		"ExcludePattern":  []string{p.ExcludePattern},
+		"WordCharacters":  p.WordCharacters,
 	}
 	if p.IsRegExp {
 		form.Set("IsRegExp", "true")
@@ -360,6 +590,9 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.IsStructuralPat {
 		form.Set("IsStructuralPat", "true")
 	}
+	if p.IsPCRE {
+		form.Set("IsPCRE", "true")
+	}
 	if p.IsWordMatch {
 		form.Set("IsWordMatch", "true")
 	}
@@ -378,6 +611,18 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.PatternMatchesPath {
 		form.Set("PatternMatchesPath", "true")
 	}
+	if p.CountOnly {
+		form.Set("CountOnly", "true")
+	}
+	if p.MaxLineMatches > 0 {
+		form.Set("MaxLineMatches", strconv.Itoa(p.MaxLineMatches))
+	}
+	if p.ModifiedSince != "" {
+		form.Set("ModifiedSince", p.ModifiedSince)
+	}
+	if p.ModifiedSinceRevision != "" {
+		form.Set("ModifiedSinceRevision", p.ModifiedSinceRevision)
+	}
 	resp, err := http.PostForm(u, form)
 	if err != nil {
 		return nil, err
@@ -28,6 +28,10 @@ var (
 type PermsStore struct {
 	db    dbutil.DB
 	clock func() time.Time
+
+	// committed are the functions to call after the transaction of the store
+	// commits (see onCommit).
+	committed []func()
 }
 
 // NewPermsStore returns a new PermsStore with given parameters.
@@ -142,13 +146,24 @@ func (s *PermsStore) SetUserPermissions(ctx context.Context, p *authz.UserPermis
 
 	ctx, save := s.observe(ctx, "SetUserPermissions", "")
 	defer func() { save(&err, p.TracingFields()...) }()
+	// Deferred before the transaction is done so that it runs after it.
+	defer func() {
+		if err == nil {
+			s.onCommit(func() { authz.InvalidateUser(p.UserID) })
+		}
+	}()
 
 	// Open a transaction for update consistency.
-	txs, err := s.Transact(ctx)
-	if err != nil {
-		return err
+	var txs *PermsStore
+	if s.inTx() {
+		txs = s
+	} else {
+		txs, err = s.Transact(ctx)
+		if err != nil {
+			return err
+		}
+		defer txs.Done(&err)
 	}
-	defer txs.Done(&err)
 
 	// Retrieve currently stored object IDs of this user.
 	var oldIDs *roaring.Bitmap
@@ -298,6 +313,11 @@ func (s *PermsStore) SetRepoPermissions(ctx context.Context, p *authz.RepoPermis
 
 	ctx, save := s.observe(ctx, "SetRepoPermissions", "")
 	defer func() { save(&err, p.TracingFields()...) }()
+	defer func() {
+		if err == nil {
+			s.onCommit(func() { authz.InvalidateRepo(api.RepoID(p.RepoID)) })
+		}
+	}()
 
 	var txs *PermsStore
 	if s.inTx() {
@@ -937,6 +957,11 @@ DO UPDATE SET
 func (s *PermsStore) GrantPendingPermissions(ctx context.Context, userID int32, p *authz.UserPendingPermissions) (err error) {
 	ctx, save := s.observe(ctx, "GrantPendingPermissions", "")
 	defer func() { save(&err, append(p.TracingFields(), otlog.Int32("userID", userID))...) }()
+	defer func() {
+		if err == nil {
+			s.onCommit(func() { authz.InvalidateUser(userID) })
+		}
+	}()
 
 	var txs *PermsStore
 	if s.inTx() {
@@ -1173,6 +1198,11 @@ AND service_id = %s
 func (s *PermsStore) DeleteAllUserPermissions(ctx context.Context, userID int32) (err error) {
 	ctx, save := s.observe(ctx, "DeleteAllUserPermissions", "")
 	defer func() { save(&err, otlog.Int32("userID", userID)) }()
+	defer func() {
+		if err == nil {
+			s.onCommit(func() { authz.InvalidateUser(userID) })
+		}
+	}()
 
 	// NOTE: Practically, we don't need to clean up "repo_permissions" table because the value of "id" column
 	// that is associated with this user will be invalidated automatically by deleting this row.
@@ -1653,13 +1683,31 @@ func (s *PermsStore) Done(err *error) {
 	}
 
 	tx := s.db.(*sql.Tx)
+	committed := s.committed
+	s.committed = nil
 	if err == nil || *err == nil {
-		_ = tx.Commit()
+		if tx.Commit() == nil {
+			for _, f := range committed {
+				f()
+			}
+		}
 	} else {
 		_ = tx.Rollback()
 	}
 }
 
+// onCommit calls f after the transaction of s commits, or immediately if s
+// is not in a transaction. It is used to invalidate caches of the stored
+// permissions only once the changes are visible to other readers, which
+// could otherwise cache the old permissions again.
+func (s *PermsStore) onCommit(f func()) {
+	if !s.inTx() {
+		f()
+		return
+	}
+	s.committed = append(s.committed, f)
+}
+
 func (s *PermsStore) observe(ctx context.Context, family, title string) (context.Context, func(*error, ...otlog.Field)) {
 	began := s.clock()
 	tr, ctx := trace.New(ctx, "db.PermsStore."+family, title)
@@ -15,6 +15,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/keegancsmith/sqlf"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/extsvc"
 	"golang.org/x/sync/errgroup"
@@ -1656,6 +1657,57 @@ func testPermsStore_DeleteAllUserPermissions(db *sql.DB) func(*testing.T) {
 	}
 }
 
+func testPermsStore_InvalidateCacheAfterCommit(db *sql.DB) func(*testing.T) {
+	return func(t *testing.T) {
+		s := NewPermsStore(db, clock)
+		t.Cleanup(func() {
+			cleanupPermsTables(t, s)
+		})
+
+		authz.DefaultCache.SetTTL(time.Hour)
+		defer authz.DefaultCache.SetTTL(0)
+		defer authz.DefaultCache.InvalidateAll()
+
+		// isCached returns true if the decision of user=1 on repo=1 is cached.
+		isCached := func() bool {
+			cached := true
+			_, err := authz.DefaultCache.Filter(1, []*types.Repo{{ID: 1}}, authz.Read, func(repos []*types.Repo) ([]*types.Repo, error) {
+				cached = false
+				return repos, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cached
+		}
+
+		ctx := context.Background()
+		isCached()
+
+		txs, err := s.Transact(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := txs.SetRepoPermissions(ctx, &authz.RepoPermissions{
+			RepoID:  1,
+			Perm:    authz.Read,
+			UserIDs: toBitmap(2),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// A reader could still cache the old permissions until the transaction
+		// commits, so the cache must not be invalidated before.
+		if !isCached() {
+			t.Fatal("cache was invalidated before the transaction committed")
+		}
+		txs.Done(nil)
+		if isCached() {
+			t.Fatal("cache was not invalidated after the transaction committed")
+		}
+	}
+}
+
 func testPermsStore_DeleteAllUserPendingPermissions(db *sql.DB) func(*testing.T) {
 	return func(t *testing.T) {
 		s := NewPermsStore(db, clock)
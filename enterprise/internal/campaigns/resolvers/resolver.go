@@ -548,6 +548,60 @@ func (r *Resolver) CreatePatchSetFromPatches(ctx context.Context, args graphqlba
 	return &patchSetResolver{store: r.store, patchSet: patchSet}, nil
 }
 
+func (r *Resolver) CreatePatchSetFromSearch(ctx context.Context, args *graphqlbackend.CreatePatchSetFromSearchArgs) (_ graphqlbackend.PatchSetResolver, err error) {
+	tr, ctx := trace.New(ctx, "Resolver.CreatePatchSetFromSearch", fmt.Sprintf("Query: %q", args.Query))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	// 🚨 SECURITY: Only site admins may create patch sets for now.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := backend.CurrentUser(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%v", backend.ErrNotAuthenticated)
+	}
+	if user == nil {
+		return nil, backend.ErrNotAuthenticated
+	}
+
+	searchPatches, err := graphqlbackend.SearchReplacementPatches(ctx, &graphqlbackend.SearchArgs{
+		Version:     "V2",
+		PatternType: args.PatternType,
+		Query:       args.Query,
+	}, args.Replacement)
+	if err != nil {
+		return nil, err
+	}
+	if len(searchPatches) == 0 {
+		return nil, errors.New("the search has no matches to replace")
+	}
+
+	patches := make([]*campaigns.Patch, len(searchPatches))
+	for i, sp := range searchPatches {
+		patches[i] = &campaigns.Patch{
+			RepoID:  sp.Repo.ID,
+			Rev:     sp.Commit,
+			BaseRef: sp.BaseRef,
+			Diff:    sp.Diff,
+		}
+		if err := patches[i].ComputeDiffStat(); err != nil {
+			return nil, errors.Wrapf(err, "patch for repository %q", sp.Repo.Name)
+		}
+	}
+
+	svc := ee.NewService(r.store, r.httpFactory)
+	patchSet, err := svc.CreatePatchSetFromPatches(ctx, patches, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchSetResolver{store: r.store, patchSet: patchSet}, nil
+}
+
 func (r *Resolver) CloseCampaign(ctx context.Context, args *graphqlbackend.CloseCampaignArgs) (_ graphqlbackend.CampaignResolver, err error) {
 	tr, ctx := trace.New(ctx, "Resolver.CloseCampaign", fmt.Sprintf("Campaign: %q", args.Campaign))
 	defer func() {
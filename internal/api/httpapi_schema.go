@@ -49,3 +49,32 @@ type ExternalServicesListRequest struct {
 	Kind  string   `json:"kind"`
 	Kinds []string `json:"kinds"`
 }
+
+// SearchCandidateFilesRequest is a request for the files of a repository that
+// contain any of a set of identifiers. Language servers use it to pre-filter the
+// files they must parse to find workspace symbols or references.
+type SearchCandidateFilesRequest struct {
+	Repo     RepoName `json:"repo"`
+	CommitID CommitID `json:"commitID"`
+
+	// Identifiers are the identifiers to search for. They are matched as whole
+	// words, case-sensitively.
+	Identifiers []string `json:"identifiers"`
+
+	// IncludePatterns are regular expressions that the paths of candidate
+	// files must all match (e.g. `\.go$`).
+	IncludePatterns []string `json:"includePatterns,omitempty"`
+
+	// Limit is the maximum number of files returned. If zero, a default limit
+	// is used.
+	Limit int `json:"limit,omitempty"`
+}
+
+// SearchCandidateFilesResponse is the response to a SearchCandidateFilesRequest.
+type SearchCandidateFilesResponse struct {
+	Paths []string `json:"paths"`
+
+	// LimitHit is true if there are more candidate files than Paths. Callers
+	// must not rely on Paths being complete then.
+	LimitHit bool `json:"limitHit"`
+}
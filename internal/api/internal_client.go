@@ -98,6 +98,7 @@ type ConfigSavedQuery struct {
 	UserID          *int32  `json:"userID"`
 	OrgID           *int32  `json:"orgID"`
 	SlackWebhookURL *string `json:"slackWebhookURL"`
+	WebhookURL      *string `json:"webhookURL,omitempty"`
 }
 
 func (sq ConfigSavedQuery) Equals(other ConfigSavedQuery) bool {
@@ -298,6 +299,16 @@ func (c *internalClient) ExternalServicesList(ctx context.Context, opts External
 	return extsvcs, c.postInternal(ctx, "external-services/list", &opts, &extsvcs)
 }
 
+// SearchCandidateFiles returns the files of a repository that contain any of
+// the identifiers of the request, found with text search.
+func (c *internalClient) SearchCandidateFiles(ctx context.Context, req SearchCandidateFilesRequest) (*SearchCandidateFilesResponse, error) {
+	var resp SearchCandidateFilesResponse
+	if err := c.postInternal(ctx, "search/candidate-files", &req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
 func (c *internalClient) LogTelemetry(ctx context.Context, reqBody interface{}) error {
 	return c.postInternal(ctx, "telemetry", reqBody, nil)
 }
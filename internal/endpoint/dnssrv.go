@@ -0,0 +1,86 @@
+package endpoint
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+)
+
+var (
+	// dnsSRVRefreshInterval is how often the DNS SRV records of a Map are
+	// looked up again.
+	dnsSRVRefreshInterval = 30 * time.Second
+
+	// lookupSRV is net.LookupSRV, mocked in tests.
+	lookupSRV = net.LookupSRV
+)
+
+// newDNSSRV returns a Map for a "dnssrv+" URL specifier (see New).
+func newDNSSRV(urlspec string) *Map {
+	m := &Map{urlspec: urlspec}
+	lookup, interval := lookupSRV, dnsSRVRefreshInterval
+
+	m.init = func() (*hashMap, error) {
+		u, err := url.Parse(strings.TrimPrefix(urlspec, "dnssrv+"))
+		if err != nil {
+			return nil, err
+		}
+		if u.Port() != "" {
+			return nil, fmt.Errorf("invalid dnssrv url. the port is taken from the SRV records, expected dnssrv+http://_service._proto.name/path, got %s", urlspec)
+		}
+
+		urls, err := lookupSRVURLs(lookup, u)
+		if err != nil {
+			return nil, err
+		}
+
+		// Kick off refreshing in the background. If a lookup fails, we keep
+		// the last URLs, since DNS failures are usually transient.
+		go func() {
+			for {
+				time.Sleep(interval)
+				urls, err := lookupSRVURLs(lookup, u)
+				if err != nil {
+					log15.Warn("failed to look up DNS SRV records", "name", u.Host, "error", err)
+					continue
+				}
+				m.mu.Lock()
+				m.urls, m.err = urls, nil
+				m.mu.Unlock()
+			}
+		}()
+
+		return urls, nil
+	}
+
+	return m
+}
+
+// lookupSRVURLs returns a hash map of the URLs of the targets of the DNS SRV
+// records named by the host of u.
+func lookupSRVURLs(lookup func(service, proto, name string) (string, []*net.SRV, error), u *url.URL) (*hashMap, error) {
+	_, addrs, err := lookup("", "", u.Hostname())
+	if err != nil {
+		return nil, errors.Wrap(err, "LookupSRV")
+	}
+	urls := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		uCopy := *u
+		uCopy.Host = net.JoinHostPort(strings.TrimSuffix(addr.Target, "."), strconv.Itoa(int(addr.Port)))
+		if uCopy.Scheme == "rpc" {
+			urls = append(urls, uCopy.Host)
+		} else {
+			urls = append(urls, uCopy.String())
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.Errorf("no DNS SRV records could be found for %s", u.Hostname())
+	}
+	return newConsistentHashMap(urls), nil
+}
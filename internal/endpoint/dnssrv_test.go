@@ -0,0 +1,66 @@
+package endpoint
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew_DNSSRV(t *testing.T) {
+	defer func(f func(string, string, string) (string, []*net.SRV, error), d time.Duration) {
+		lookupSRV, dnsSRVRefreshInterval = f, d
+	}(lookupSRV, dnsSRVRefreshInterval)
+	dnsSRVRefreshInterval = 10 * time.Millisecond
+
+	var (
+		mu      sync.Mutex
+		records = map[string][]*net.SRV{
+			"_http._tcp.searcher": {
+				{Target: "searcher-0.searcher.", Port: 3181},
+				{Target: "searcher-1.searcher.", Port: 3181},
+			},
+			"_rpc._tcp.indexed-search": {
+				{Target: "indexed-search-0.", Port: 6070},
+			},
+		}
+	)
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return name, records[name], nil
+	}
+
+	m := New("dnssrv+http://_http._tcp.searcher/path")
+	expectEndpoints(t, m, nil, "http://searcher-0.searcher:3181/path", "http://searcher-1.searcher:3181/path")
+
+	// Scaling up is picked up by the next lookup.
+	mu.Lock()
+	records["_http._tcp.searcher"] = append(records["_http._tcp.searcher"], &net.SRV{Target: "searcher-2.searcher.", Port: 3181})
+	mu.Unlock()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		eps, err := m.Endpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(eps) == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got endpoints %v, want 3 after refresh", eps)
+		}
+		time.Sleep(dnsSRVRefreshInterval)
+	}
+	expectEndpoints(t, m, nil, "http://searcher-0.searcher:3181/path", "http://searcher-1.searcher:3181/path", "http://searcher-2.searcher:3181/path")
+
+	m = New("dnssrv+rpc://_rpc._tcp.indexed-search")
+	expectEndpoints(t, m, nil, "indexed-search-0:6070")
+
+	if _, err := New("dnssrv+http://_http._tcp.searcher:3181").Endpoints(); err == nil {
+		t.Error("got no error for a dnssrv URL with a port, want an error")
+	}
+	if _, err := New("dnssrv+http://_http._tcp.unknown").Endpoints(); err == nil {
+		t.Error("got no error without SRV records, want an error")
+	}
+}
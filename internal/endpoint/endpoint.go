@@ -19,8 +19,9 @@ import (
 )
 
 // Map is a consistent hash map to URLs. It uses the kubernetes API to watch
-// the endpoints for a service and update the map when they change. It can
-// also fallback to static URLs if not configured for kubernetes.
+// the endpoints for a service, or DNS SRV records, and update the map when
+// they change. It can also fallback to static URLs if not configured for
+// either.
 type Map struct {
 	mu      sync.Mutex
 	init    func() (*hashMap, error)
@@ -37,16 +38,26 @@ type Map struct {
 // the endpoints for the Kubernetes service. The values returned by Get will
 // look like http://endpoint:port/path.
 //
-// If the scheme is not prefixed with "k8s+", a space separated list of URLs is
-// expected. The map will consistently hash against these URLs in this case.
-// This is useful for specifying non-Kubernetes endpoints.
+// If the scheme is prefixed with "dnssrv+", one URL is expected whose host is
+// the name of DNS SRV records, e.g. dnssrv+http://_http._tcp.searcher/path.
+// URLs of this form will consistently hash among the targets of the records,
+// which are looked up periodically. The values returned by Get will look like
+// http://target:port/path.
+//
+// Otherwise, a space separated list of URLs is expected. The map will
+// consistently hash against these URLs in this case. This is useful for
+// specifying non-Kubernetes endpoints.
 //
 // Examples URL specifiers:
 //
 // 	"k8s+http://searcher"
+// 	"dnssrv+http://_http._tcp.searcher.prod.svc.cluster.local"
 // 	"http://searcher-1 http://searcher-2 http://searcher-3"
 //
 func New(urlspec string) *Map {
+	if strings.HasPrefix(urlspec, "dnssrv+") {
+		return newDNSSRV(urlspec)
+	}
 	if !strings.HasPrefix(urlspec, "k8s+") {
 		return &Map{
 			urlspec: urlspec,
@@ -35,6 +35,7 @@ type Repository struct {
 	IsFork           bool   // whether the repository is a fork of another repository
 	IsArchived       bool   // whether the repository is archived on the code host
 	ViewerPermission string // ADMIN, WRITE, READ, or empty if unknown. Only the graphql api populates this. https://developer.github.com/v4/enum/repositorypermission/
+	DiskUsage        int    // the size of the repository on disk in kibibytes, or 0 if unknown
 }
 
 // repositoryFieldsGraphQLFragment returns a GraphQL fragment that contains the fields needed to populate the
@@ -51,6 +52,7 @@ fragment RepositoryFields on Repository {
 	isPrivate
 	isFork
 	isArchived
+	diskUsage
 	viewerPermission
 }
 	`
@@ -68,6 +70,7 @@ fragment RepositoryFields on Repository {
 	isPrivate
 	isFork
 	isArchived
+	diskUsage
 }
 	`
 }
@@ -227,6 +230,7 @@ type restRepository struct {
 	Private     bool
 	Fork        bool
 	Archived    bool
+	Size        int                       `json:"size"` // in kibibytes
 	Permissions restRepositoryPermissions `json:"permissions"`
 }
 
@@ -258,6 +262,7 @@ func convertRestRepo(restRepo restRepository) *Repository {
 		IsPrivate:        restRepo.Private,
 		IsFork:           restRepo.Fork,
 		IsArchived:       restRepo.Archived,
+		DiskUsage:        restRepo.Size,
 		ViewerPermission: convertRestRepoPermissions(restRepo.Permissions),
 	}
 }
@@ -142,22 +142,19 @@ func (a *archiveReader) Close() error {
 }
 
 // ArchiveURL returns a URL from which an archive of the given Git repository can
-// be downloaded from.
+// be downloaded from. See protocol.ArchivePath for the contract of the URL.
 func (c *Client) ArchiveURL(ctx context.Context, repo Repo, opt ArchiveOptions) *url.URL {
-	q := url.Values{
-		"repo":    {string(repo.Name)},
-		"treeish": {opt.Treeish},
-		"format":  {opt.Format},
-	}
-
-	for _, path := range opt.Paths {
-		q.Add("path", path)
-	}
+	q := (&protocol.ArchiveRequest{
+		Repo:    repo.Name,
+		Treeish: opt.Treeish,
+		Format:  opt.Format,
+		Paths:   opt.Paths,
+	}).Query()
 
 	return &url.URL{
 		Scheme:   "http",
 		Host:     c.AddrForRepo(ctx, repo.Name),
-		Path:     "/archive",
+		Path:     protocol.ArchivePath,
 		RawQuery: q.Encode(),
 	}
 }
@@ -217,6 +214,16 @@ func (c *Client) Archive(ctx context.Context, repo Repo, opt ArchiveOptions) (_
 	}
 }
 
+// FetchTar returns a tar archive of repo at commit, which is how the searcher
+// and other services fetch the contents of repositories. commit must be a
+// full commit ID, whose archives gitserver serves as immutable.
+func (c *Client) FetchTar(ctx context.Context, repo Repo, commit api.CommitID) (io.ReadCloser, error) {
+	if len(commit) != 40 {
+		return nil, fmt.Errorf("commit must be resolved (commit=%q)", commit)
+	}
+	return c.Archive(ctx, repo, ArchiveOptions{Treeish: string(commit), Format: "tar"})
+}
+
 type badRequestError struct{ error }
 
 func (e badRequestError) BadRequest() bool { return true }
@@ -362,8 +369,8 @@ func (c *Cmd) CombinedOutput(ctx context.Context) ([]byte, error) {
 func (c *Cmd) String() string { return fmt.Sprintf("%q", c.Args) }
 
 // StdoutReader returns an io.ReadCloser of stdout of c. If the command has a
-// non-zero return value, Read returns a non io.EOF error. Do not pass in a
-// started command.
+// non-zero return value, Read returns a non io.EOF error. Once all of stdout
+// has been read, c.ExitStatus is set. Do not pass in a started command.
 func StdoutReader(ctx context.Context, c *Cmd) (io.ReadCloser, error) {
 	rc, trailer, err := c.sendExec(ctx)
 	if err != nil {
@@ -371,12 +378,14 @@ func StdoutReader(ctx context.Context, c *Cmd) (io.ReadCloser, error) {
 	}
 
 	return &cmdReader{
+		cmd:     c,
 		rc:      rc,
 		trailer: trailer,
 	}, nil
 }
 
 type cmdReader struct {
+	cmd     *Cmd
 	rc      io.ReadCloser
 	trailer http.Header
 }
@@ -384,6 +393,9 @@ type cmdReader struct {
 func (c *cmdReader) Read(p []byte) (int, error) {
 	n, err := c.rc.Read(p)
 	if err == io.EOF {
+		if c.cmd != nil {
+			c.cmd.ExitStatus, _ = strconv.Atoi(c.trailer.Get("X-Exec-Exit-Status"))
+		}
 		stderr := c.trailer.Get("X-Exec-Stderr")
 		if len(stderr) > 100 {
 			stderr = stderr[:100] + "... (truncated)"
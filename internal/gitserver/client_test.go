@@ -76,9 +76,11 @@ func TestClient_Archive(t *testing.T) {
 	}
 
 	tests := map[api.RepoName]struct {
-		remote string
-		want   map[string]string
-		err    error
+		remote       string
+		treeish      string
+		want         map[string]string
+		cacheControl string
+		err          error
 	}{
 		"simple": {
 			remote: createSimpleGitRepo(t, root),
@@ -87,10 +89,13 @@ func TestClient_Archive(t *testing.T) {
 				"dir1/file1": "infile1",
 				"file 2":     "infile2",
 			},
+			cacheControl: "no-cache",
 		},
 		"repo-with-dotgit-dir": {
-			remote: createRepoWithDotGitDir(t, root),
-			want:   map[string]string{"file1": "hello\n", ".git/mydir/file2": "milton\n", ".git/mydir/": "", ".git/": ""},
+			remote:       createRepoWithDotGitDir(t, root),
+			treeish:      "aa600fc517ea6546f31ae8198beb1932f13b0e4c",
+			want:         map[string]string{"file1": "hello\n", ".git/mydir/file2": "milton\n", ".git/mydir/": "", ".git/": ""},
+			cacheControl: "public, max-age=31536000, immutable",
 		},
 		"not-found": {
 			err: errors.New("repository does not exist: not-found"),
@@ -106,7 +111,22 @@ func TestClient_Archive(t *testing.T) {
 				}
 			}
 
-			rc, err := cli.Archive(ctx, gitserver.Repo{Name: name}, gitserver.ArchiveOptions{Treeish: "HEAD", Format: "zip"})
+			treeish := test.treeish
+			if treeish == "" {
+				treeish = "HEAD"
+			}
+			opt := gitserver.ArchiveOptions{Treeish: treeish, Format: "zip"}
+
+			resp, err := http.Get(cli.ArchiveURL(ctx, gitserver.Repo{Name: name}, opt).String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if have, want := resp.Header.Get("Cache-Control"), test.cacheControl; have != want {
+				t.Errorf("archive: have Cache-Control %q, want %q", have, want)
+			}
+
+			rc, err := cli.Archive(ctx, gitserver.Repo{Name: name}, opt)
 			if have, want := fmt.Sprint(err), fmt.Sprint(test.err); have != want {
 				t.Errorf("archive: have err %v, want %v", have, want)
 			}
@@ -0,0 +1,74 @@
+package protocol
+
+import (
+	"net/url"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+// ArchivePath is the path of gitserver's endpoint that serves archives of
+// repositories, in response to GET requests whose query is an
+// ArchiveRequest.
+//
+// The response is the output of git archive, with the trailers of an exec
+// request. Archives of commits never change, so successful responses to
+// requests for a commit ID are cacheable indefinitely (see
+// ArchiveCacheControl).
+const ArchivePath = "/archive"
+
+// ArchiveRequest is a request for an archive of a repository.
+type ArchiveRequest struct {
+	Repo    api.RepoName
+	Treeish string   // the tree or commit to produce an archive for
+	Format  string   // format of the resulting archive ("tar" or "zip")
+	Paths   []string // if nonempty, only include these paths
+}
+
+// Query returns the query of a request to ArchivePath for r.
+func (r *ArchiveRequest) Query() url.Values {
+	q := url.Values{
+		"repo":    {string(r.Repo)},
+		"treeish": {r.Treeish},
+		"format":  {r.Format},
+	}
+	for _, path := range r.Paths {
+		q.Add("path", path)
+	}
+	return q
+}
+
+// ParseArchiveRequest returns the ArchiveRequest of the query of a request
+// to ArchivePath.
+func ParseArchiveRequest(q url.Values) *ArchiveRequest {
+	return &ArchiveRequest{
+		Repo:    api.RepoName(q.Get("repo")),
+		Treeish: q.Get("treeish"),
+		Format:  q.Get("format"),
+		Paths:   q["path"],
+	}
+}
+
+// ArchiveCacheControl returns the Cache-Control header of a successful
+// response to an archive request for treeish. Archives of commit IDs are
+// immutable, so caches (such as a proxy in front of gitserver) may keep them
+// for a year without revalidating them. Archives of anything else, such as a
+// branch, change when the repository is updated and must not be cached.
+func ArchiveCacheControl(treeish string) string {
+	if isCommitID(treeish) {
+		return "public, max-age=31536000, immutable"
+	}
+	return "no-cache"
+}
+
+// isCommitID reports whether s is a full (40-character) commit ID.
+func isCommitID(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, r := range s {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
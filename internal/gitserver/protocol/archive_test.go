@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArchiveRequest(t *testing.T) {
+	want := &ArchiveRequest{
+		Repo:    "github.com/foo/bar",
+		Treeish: "HEAD",
+		Format:  "tar",
+		Paths:   []string{"a", "b/c"},
+	}
+	if got := ParseArchiveRequest(want.Query()); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestArchiveCacheControl(t *testing.T) {
+	cases := map[string]string{
+		"e69de29bb2d1d6434b8b29ae775ad8c2e48c5391": "public, max-age=31536000, immutable",
+		"E69DE29BB2D1D6434B8B29AE775AD8C2E48C5391": "public, max-age=31536000, immutable",
+		"HEAD":    "no-cache",
+		"master":  "no-cache",
+		"e69de29": "no-cache",
+		"g69de29bb2d1d6434b8b29ae775ad8c2e48c5391": "no-cache",
+	}
+	for treeish, want := range cases {
+		if got := ArchiveCacheControl(treeish); got != want {
+			t.Errorf("ArchiveCacheControl(%q): got %q want %q", treeish, got, want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
 )
 
 // A URI is a wrapper around url.URL that makes it easier to get and
@@ -33,6 +34,35 @@ type URI struct {
 	url.URL
 }
 
+// hostPattern matches the first path component of repository names that can
+// be used as the host of a URI as is (such as "github.com" or
+// "gitlab.example.com:8443").
+var hostPattern = lazyregexp.New(`^[A-Za-z0-9._~-]+(:[0-9]+)?$`)
+
+// New returns the git:// URI of the file path (or, if path is empty, the
+// root) of the repository at the revision rev (or the default branch, if rev
+// is empty).
+//
+// Unlike concatenating the components, New escapes the characters of
+// repository names from any code host that have a meaning in URLs (such as
+// "?", "#", "%" or spaces), so that Repo, Rev and FilePath of the parsed URI
+// return the original components. If the first component of the repository
+// name isn't a valid host, the whole name is used as the path of the URI
+// (e.g. "git:///my repo?rev#path").
+func New(repo api.RepoName, rev, path string) *URI {
+	u := url.URL{Scheme: "git", RawQuery: url.QueryEscape(rev), Fragment: path}
+	host, rest := string(repo), ""
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host, rest = host[:i], host[i:]
+	}
+	if hostPattern.MatchString(host) {
+		u.Host, u.Path = host, rest
+	} else {
+		u.Path = "/" + string(repo)
+	}
+	return &URI{u}
+}
+
 // Parse parses uriStr to a URI. The uriStr should be an absolute URL.
 func Parse(uriStr string) (*URI, error) {
 	u, err := url.Parse(uriStr)
@@ -55,11 +85,24 @@ func (u *URI) CloneURL() *url.URL {
 }
 
 // Repo returns the repository name (e.g., "github.com/foo/bar").
-func (u *URI) Repo() api.RepoName { return api.RepoName(u.Host + strings.TrimPrefix(u.Path, ".git")) }
+func (u *URI) Repo() api.RepoName {
+	if u.Host == "" {
+		// The URI has no host if the repository name doesn't begin with
+		// one (see New).
+		return api.RepoName(strings.TrimPrefix(u.Path, "/"))
+	}
+	return api.RepoName(u.Host + strings.TrimPrefix(u.Path, ".git"))
+}
 
-// Rev returns the repository revision component of the URI (the raw
-// query string).
-func (u *URI) Rev() string { return u.RawQuery }
+// Rev returns the repository revision component of the URI (the
+// unescaped query string).
+func (u *URI) Rev() string {
+	rev, err := url.QueryUnescape(u.RawQuery)
+	if err != nil {
+		return u.RawQuery
+	}
+	return rev
+}
 
 // FilePath returns the cleaned file path component of the URI (in the
 // URL fragment). Leading slashes are removed. If it is ".", an empty
@@ -4,6 +4,8 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
 )
 
 func TestParse(t *testing.T) {
@@ -111,3 +113,41 @@ func TestURI_FilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		repo      api.RepoName
+		rev, path string
+		want      string
+	}{
+		{"github.com/foo/bar", "", "", "git://github.com/foo/bar"},
+		{"github.com/foo/bar", "v", "f", "git://github.com/foo/bar?v#f"},
+		{"github.com/foo/bar", "refs/heads/a+b", "d/f", "git://github.com/foo/bar?refs%2Fheads%2Fa%2Bb#d/f"},
+		{"gitlab.example.com:8443/foo/bar", "v", "", "git://gitlab.example.com:8443/foo/bar?v"},
+		{"code.example.com/foo bar/b?z#q", "v", "f", "git://code.example.com/foo%20bar/b%3Fz%23q?v#f"},
+		{"my repo", "v", "f", "git:///my%20repo?v#f"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.repo), func(t *testing.T) {
+			uri := New(test.repo, test.rev, test.path)
+			if got := uri.String(); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+
+			// The components must survive a round trip through Parse.
+			parsed, err := Parse(uri.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Repo() != test.repo {
+				t.Errorf("got repo %q, want %q", parsed.Repo(), test.repo)
+			}
+			if parsed.Rev() != test.rev {
+				t.Errorf("got rev %q, want %q", parsed.Rev(), test.rev)
+			}
+			if parsed.FilePath() != test.path {
+				t.Errorf("got path %q, want %q", parsed.FilePath(), test.path)
+			}
+		})
+	}
+}
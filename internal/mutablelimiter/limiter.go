@@ -16,11 +16,16 @@ import (
 // state. We do not expose a way to stop this goroutine, so ensure the number
 // of Limiters created is bounded.
 type Limiter struct {
-	adjustLimit chan int
+	adjustLimit chan limitAdjustment
 	acquire     chan acquireRequest
 	getLimit    chan struct{ cap, len int }
 }
 
+type limitAdjustment struct {
+	limit  int
+	cancel bool
+}
+
 type acquireResponse struct {
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -34,7 +39,7 @@ type acquireRequest struct {
 // New returns a new Limiter (Semaphore).
 func New(limit int) *Limiter {
 	l := &Limiter{
-		adjustLimit: make(chan int),
+		adjustLimit: make(chan limitAdjustment),
 		getLimit:    make(chan struct{ cap, len int }),
 		acquire:     make(chan acquireRequest),
 	}
@@ -46,7 +51,14 @@ func New(limit int) *Limiter {
 // acquired, then contexts are canceled until we are within limit. Contexts
 // are canceled such that the older contexts are canceled.
 func (l *Limiter) SetLimit(limit int) {
-	l.adjustLimit <- limit
+	l.adjustLimit <- limitAdjustment{limit: limit, cancel: true}
+}
+
+// SetLimitWithoutCancel adjusts the limit like SetLimit, except that no
+// contexts are canceled if we currently have more than limit contexts
+// acquired. Instead Acquire blocks until enough of them are released.
+func (l *Limiter) SetLimitWithoutCancel(limit int) {
+	l.adjustLimit <- limitAdjustment{limit: limit}
 }
 
 // GetLimit reports the current state of the limiter, returning the
@@ -90,15 +102,16 @@ func (l *Limiter) do(limit int) {
 		// Use our acquire channel if we are not at limit, otherwise use a
 		// channel which is never written to (to avoid acquiring).
 		acquire := l.acquire
-		if cancelFuncs.Len() == limit {
+		if limit >= 0 && cancelFuncs.Len() >= limit {
 			acquire = hidden
 		}
 
 		select {
-		case limit = <-l.adjustLimit:
+		case adjust := <-l.adjustLimit:
+			limit = adjust.limit
 			// If we adjust the limit down we need to release until we are
 			// within limit.
-			for limit >= 0 && cancelFuncs.Len() > limit {
+			for adjust.cancel && limit >= 0 && cancelFuncs.Len() > limit {
 				el := cancelFuncs.Front()
 				cancelFuncs.Remove(el)
 				el.Value.(context.CancelFunc)()
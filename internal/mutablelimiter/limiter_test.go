@@ -80,3 +80,42 @@ func TestLimiter(t *testing.T) {
 	}
 	defer cancel4()
 }
+
+func TestLimiter_SetLimitWithoutCancel(t *testing.T) {
+	l := New(2)
+
+	ctx1, cancel1, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel1()
+	ctx2, cancel2, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel2()
+
+	// Adjust limit down, should not cancel any job but block new ones
+	l.SetLimitWithoutCancel(1)
+	if ctx1.Err() != nil || ctx2.Err() != nil {
+		t.Fatal("expected contexts to still be running")
+	}
+	if cap, len := l.GetLimit(); cap != 1 || len != 2 {
+		t.Fatalf("got cap %d and len %d, want 1 and 2", cap, len)
+	}
+
+	cancel1()
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if _, _, err := l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected acquire to fail while over the limit")
+	}
+
+	// Once within limit we should be able to acquire again
+	cancel2()
+	_, cancel3, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel3()
+}
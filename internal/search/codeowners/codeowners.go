@@ -0,0 +1,90 @@
+// Package codeowners parses the ownership files of repositories (such as
+// CODEOWNERS files), which list the owners of the files in a repository.
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/search/ignore"
+)
+
+// DefaultPaths are the paths that an ownership file is read from if the
+// search.ownershipFiles site setting is not set, in order of precedence. They
+// are the locations of CODEOWNERS files that GitHub and GitLab support.
+var DefaultPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Ruleset is the list of rules of an ownership file.
+type Ruleset struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern *ignore.Matcher
+	owners  []string
+}
+
+// Parse parses the contents of an ownership file in the CODEOWNERS format.
+// Each line is a pattern followed by the owners of the files that match it
+// (e.g. "/docs/ @org/docs-team alice@example.com"), and blank lines and lines
+// starting with "#" are skipped. The patterns have the syntax of
+// .sourcegraph/ignore files (see ignore.Parse).
+//
+// The last rule whose pattern matches a file determines its owners, so a rule
+// without owners makes the matching files unowned. GitLab's sections (lines
+// such as "[Docs]") are skipped.
+func Parse(data []byte) (*Ruleset, error) {
+	var rs Ruleset
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; s.Scan(); lineNumber++ {
+		line := s.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[") {
+			continue
+		}
+		m, err := ignore.Parse([]byte(fields[0]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		rs.rules = append(rs.rules, rule{pattern: m, owners: fields[1:]})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// Owners returns the owners of the file at path (relative to the repository
+// root), or nil if it has none. A nil Ruleset has no owners.
+func (rs *Ruleset) Owners(path string) []string {
+	if rs == nil {
+		return nil
+	}
+	for i := len(rs.rules) - 1; i >= 0; i-- {
+		if r := rs.rules[i]; r.pattern.Match(path) {
+			if len(r.owners) == 0 {
+				return nil
+			}
+			return r.owners
+		}
+	}
+	return nil
+}
+
+// HasOwner reports whether owner is one of owners. Owners are compared
+// case-insensitively, and the "@" of user and team names is optional, so
+// "org/team" is the owner "@org/team".
+func HasOwner(owners []string, owner string) bool {
+	owner = strings.TrimPrefix(owner, "@")
+	for _, o := range owners {
+		if strings.EqualFold(strings.TrimPrefix(o, "@"), owner) {
+			return true
+		}
+	}
+	return false
+}
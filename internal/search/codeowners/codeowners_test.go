@@ -0,0 +1,59 @@
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleset(t *testing.T) {
+	rs, err := Parse([]byte(`
+# Default owners
+*       @org/everyone
+
+*.go    @org/go-team alice@example.com  # Go code
+/docs/  @org/docs-team
+
+[Generated]
+/docs/generated/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]string{
+		"README.md":               {"@org/everyone"},
+		"cmd/a/main.go":           {"@org/go-team", "alice@example.com"},
+		"docs/index.md":           {"@org/docs-team"},
+		"docs/tools/gen.go":       {"@org/docs-team"},
+		"docs/generated/api.md":   nil,
+		"x/docs/generated/api.md": {"@org/everyone"},
+	}
+	for path, want := range tests {
+		if got := rs.Owners(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got owners %q, want %q", path, got, want)
+		}
+	}
+
+	if got := (*Ruleset)(nil).Owners("a.go"); got != nil {
+		t.Errorf("got owners %q from a nil ruleset, want none", got)
+	}
+
+	if _, err := Parse([]byte("!a.go @org/team\n")); err == nil {
+		t.Error("got no error for a negated pattern")
+	}
+}
+
+func TestHasOwner(t *testing.T) {
+	owners := []string{"@Org/Go-Team", "alice@example.com"}
+	tests := map[string]bool{
+		"@org/go-team":      true,
+		"org/go-team":       true,
+		"alice@example.com": true,
+		"@org/docs-team":    false,
+		"org":               false,
+	}
+	for owner, want := range tests {
+		if got := HasOwner(owners, owner); got != want {
+			t.Errorf("%s: got %v, want %v", owner, got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package contentclass classifies the contents of files as comments, string
+// literals or code (see the within: search filter), with lightweight lexers
+// of the comment and string syntax of programming languages. The lexers
+// don't parse the languages, so they can be wrong for unusual code (such as
+// a "//" in a regexp literal in JavaScript), but they are fast and need no
+// dependencies.
+package contentclass
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/src-d/enry/v2"
+)
+
+// Class is the class of a part of the contents of a file.
+type Class uint8
+
+const (
+	Code Class = 1 << iota
+	Comment
+	String
+
+	// All is the Set of all classes.
+	All = Set(Code | Comment | String)
+)
+
+var classNames = map[Class]string{
+	Code:    "code",
+	Comment: "comment",
+	String:  "string",
+}
+
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// Parse parses the name of a class ("code", "comment" or "string").
+func Parse(name string) (Class, error) {
+	for c, n := range classNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid content class %q, expected code, comment or string", name)
+}
+
+// Set is a set of classes.
+type Set uint8
+
+// Has reports whether c is in s.
+func (s Set) Has(c Class) bool {
+	return s&Set(c) != 0
+}
+
+// Names returns the names of the classes in s, sorted.
+func (s Set) Names() []string {
+	var names []string
+	for c, n := range classNames {
+		if s.Has(c) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSet parses the set of the classes with the given names. An empty list
+// of names is the set of all classes.
+func ParseSet(names []string) (Set, error) {
+	if len(names) == 0 {
+		return All, nil
+	}
+	var s Set
+	for _, name := range names {
+		c, err := Parse(name)
+		if err != nil {
+			return 0, err
+		}
+		s |= Set(c)
+	}
+	return s, nil
+}
+
+// Range is a comment or string literal at data[Start:End] of the data of a
+// file. Comments include their delimiters ("//" or "/* */"), and string
+// literals their quotes.
+type Range struct {
+	Start, End int
+	Class      Class
+}
+
+// Ranges are the comments and string literals of a file, in order. The rest
+// of the file is code.
+type Ranges []Range
+
+// ClassAt returns the class of the byte at offset.
+func (rs Ranges) ClassAt(offset int) Class {
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].End > offset })
+	if i < len(rs) && rs[i].Start <= offset {
+		return rs[i].Class
+	}
+	return Code
+}
+
+// delimiter is the syntax of a kind of comments or string literals.
+type delimiter struct {
+	open, close string // close is "" for line comments
+	class       Class
+	escapes     bool // a backslash escapes the next character
+	multiline   bool // whether it can span lines
+}
+
+var (
+	lineComment = func(open string) delimiter {
+		return delimiter{open: open, class: Comment}
+	}
+	blockComment = func(open, close string) delimiter {
+		return delimiter{open: open, close: close, class: Comment, multiline: true}
+	}
+	quoted = func(quote string) delimiter {
+		return delimiter{open: quote, close: quote, class: String, escapes: true}
+	}
+	raw = func(quote string) delimiter {
+		return delimiter{open: quote, close: quote, class: String, multiline: true}
+	}
+	multiline = func(quote string) delimiter {
+		return delimiter{open: quote, close: quote, class: String, escapes: true, multiline: true}
+	}
+)
+
+var (
+	cLike   = []delimiter{lineComment("//"), blockComment("/*", "*/"), quoted(`"`), quoted("'")}
+	hash    = []delimiter{lineComment("#"), quoted(`"`), quoted("'")}
+	js      = []delimiter{lineComment("//"), blockComment("/*", "*/"), quoted(`"`), quoted("'"), multiline("`")}
+	css     = []delimiter{blockComment("/*", "*/"), quoted(`"`), quoted("'")}
+	sass    = []delimiter{lineComment("//"), blockComment("/*", "*/"), quoted(`"`), quoted("'")}
+	shell   = []delimiter{lineComment("#"), multiline(`"`), raw("'")}
+	python  = []delimiter{lineComment("#"), multiline(`"""`), multiline("'''"), quoted(`"`), quoted("'")}
+	sql     = []delimiter{lineComment("--"), blockComment("/*", "*/"), quoted(`"`), quoted("'")}
+	haskell = []delimiter{lineComment("--"), blockComment("{-", "-}"), quoted(`"`)}
+	lua     = []delimiter{blockComment("--[[", "]]"), lineComment("--"), {open: "[[", close: "]]", class: String, multiline: true}, quoted(`"`), quoted("'")}
+	lisp    = []delimiter{lineComment(";"), multiline(`"`)}
+	markup  = []delimiter{blockComment("<!--", "-->")}
+)
+
+// languages are the delimiters of the languages (as named by enry) that are
+// lexed.
+var languages = map[string][]delimiter{
+	"C":           cLike,
+	"C++":         cLike,
+	"C#":          cLike,
+	"Objective-C": cLike,
+	"Java":        cLike,
+	"Scala":       cLike,
+	"Groovy":      cLike,
+	"Kotlin":      append([]delimiter{multiline(`"""`)}, cLike...),
+	"Swift":       append([]delimiter{multiline(`"""`)}, cLike...),
+	"Dart":        append([]delimiter{multiline(`"""`), multiline("'''")}, cLike...),
+	"Rust":        {lineComment("//"), blockComment("/*", "*/"), multiline(`"`)},
+	"Go":          {lineComment("//"), blockComment("/*", "*/"), quoted(`"`), quoted("'"), raw("`")},
+	"PHP":         append([]delimiter{lineComment("#")}, cLike...),
+
+	"JavaScript": js,
+	"TypeScript": js,
+	"TSX":        js,
+	"JSX":        js,
+
+	"CSS":  css,
+	"SCSS": sass,
+	"Less": sass,
+
+	"Python":     python,
+	"Ruby":       hash,
+	"Perl":       hash,
+	"R":          hash,
+	"Elixir":     append([]delimiter{multiline(`"""`)}, hash...),
+	"Makefile":   hash,
+	"Dockerfile": hash,
+	"YAML":       hash,
+	"TOML":       hash,
+	"Shell":      shell,
+
+	"SQL":     sql,
+	"PLSQL":   sql,
+	"PLpgSQL": sql,
+	"Haskell": haskell,
+	"Lua":     lua,
+
+	"Clojure":     lisp,
+	"Common Lisp": lisp,
+	"Emacs Lisp":  lisp,
+	"Scheme":      lisp,
+	"Racket":      lisp,
+
+	"HTML": markup,
+	"XML":  markup,
+}
+
+// Lex returns the comments and string literals of data, the contents of a
+// file in language (as named by enry, e.g. "Go"). ok is false if language
+// isn't lexed, in which case all of data is code.
+func Lex(language string, data []byte) (ranges Ranges, ok bool) {
+	delimiters, ok := languages[language]
+	if !ok {
+		return nil, false
+	}
+	return lex(delimiters, data), true
+}
+
+// LexPath is like Lex, for the file at path, whose language is detected by
+// its name.
+func LexPath(path string, data []byte) (ranges Ranges, ok bool) {
+	language, _ := enry.GetLanguageByExtension(path)
+	if language == "" {
+		language, _ = enry.GetLanguageByFilename(path)
+	}
+	return Lex(language, data)
+}
+
+func lex(delimiters []delimiter, data []byte) Ranges {
+	var ranges Ranges
+	for i := 0; i < len(data); {
+		d, ok := openingDelimiter(delimiters, data[i:])
+		if !ok {
+			i++
+			continue
+		}
+		start := i
+		for i += len(d.open); i < len(data); i++ {
+			if d.escapes && data[i] == '\\' {
+				i++
+				continue
+			}
+			if data[i] == '\n' && !d.multiline {
+				break
+			}
+			if d.close != "" && bytes.HasPrefix(data[i:], []byte(d.close)) {
+				i += len(d.close)
+				break
+			}
+		}
+		if i > len(data) {
+			i = len(data) // an escape at the end of data
+		}
+		ranges = append(ranges, Range{Start: start, End: i, Class: d.class})
+	}
+	return ranges
+}
+
+// openingDelimiter returns the first of delimiters that opens at the start
+// of data. Longer delimiters that start with shorter ones (such as `"""` and
+// `"`) must come first.
+func openingDelimiter(delimiters []delimiter, data []byte) (delimiter, bool) {
+	for _, d := range delimiters {
+		if data[0] == d.open[0] && bytes.HasPrefix(data, []byte(d.open)) {
+			return d, true
+		}
+	}
+	return delimiter{}, false
+}
@@ -0,0 +1,80 @@
+package contentclass
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		language string
+		data     string
+		want     []string // the comments and strings of data, prefixed with their class
+	}{
+		{"Go", "x := f(\"a // b\") // c\n/* d\ne */ y", []string{`string:"a // b"`, "comment:// c", "comment:/* d\ne */"}},
+		{"Go", "s := `a\n\"b`", []string{"string:`a\n\"b`"}},
+		{"Go", `"a\"b" + 'c'`, []string{`string:"a\"b"`, "string:'c'"}},
+		{"Go", "\"unterminated\nx", []string{`string:"unterminated`}},
+		{"Go", `"escape at the end\`, []string{`string:"escape at the end\`}},
+		{"JavaScript", "f(`a ${b}\n`) // c", []string{"string:`a ${b}\n`", "comment:// c"}},
+		{"Python", "x = \"\"\"a\n# b\"\"\" # c", []string{"string:\"\"\"a\n# b\"\"\"", "comment:# c"}},
+		{"Shell", `echo 'a\' # b`, []string{`string:'a\'`, "comment:# b"}},
+		{"Rust", "fn f<'a>(x: &'a str) {} // c", []string{"comment:// c"}},
+		{"Lua", "--[[ a\nb ]] x = [[c]] -- d", []string{"comment:--[[ a\nb ]]", "string:[[c]]", "comment:-- d"}},
+		{"HTML", "<a title=\"it's\"><!-- b --></a>", []string{"comment:<!-- b -->"}},
+	}
+	for _, test := range tests {
+		ranges, ok := Lex(test.language, []byte(test.data))
+		if !ok {
+			t.Fatalf("%s is not lexed", test.language)
+		}
+		var got []string
+		for _, r := range ranges {
+			got = append(got, r.Class.String()+":"+test.data[r.Start:r.End])
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %q: got %q, want %q", test.language, test.data, got, test.want)
+		}
+	}
+
+	if _, ok := Lex("Markdown", []byte("# a")); ok {
+		t.Error("Markdown is lexed")
+	}
+}
+
+func TestRanges_ClassAt(t *testing.T) {
+	data := `f("a") // b`
+	ranges, _ := LexPath("main.go", []byte(data))
+	for offset, want := range map[int]Class{
+		strings.Index(data, "f"):  Code,
+		strings.Index(data, `"`):  String,
+		strings.Index(data, "a"):  String,
+		strings.Index(data, ")"):  Code,
+		strings.Index(data, "//"): Comment,
+		len(data) - 1:             Comment,
+	} {
+		if got := ranges.ClassAt(offset); got != want {
+			t.Errorf("class at %d: got %s, want %s", offset, got, want)
+		}
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	s, err := ParseSet([]string{"string", "comment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Has(String) || !s.Has(Comment) || s.Has(Code) {
+		t.Errorf("got set %v, want comment and string", s.Names())
+	}
+	if got, want := s.Names(), []string{"comment", "string"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got names %q, want %q", got, want)
+	}
+	if s, _ := ParseSet(nil); s != All {
+		t.Errorf("got set %v for no names, want all", s.Names())
+	}
+	if _, err := ParseSet([]string{"docs"}); err == nil {
+		t.Error("got no error for an invalid class")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/zoekt/query"
 	"github.com/google/zoekt/rpc"
+	"github.com/inconshreveable/log15"
 	"github.com/sourcegraph/sourcegraph/internal/conf"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/env"
@@ -20,7 +21,14 @@ var (
 	searcherURL = env.Get("SEARCHER_URL", "k8s+http://searcher:3181", "searcher server URL")
 
 	searcherURLsOnce sync.Once
+	searcherURLsMu   sync.Mutex
 	searcherURLs     *endpoint.Map
+	searcherURLSpec  string
+
+	// tenantSearcherURLs are the endpoints of the dedicated searcher
+	// instances of tenants, by URL specifier.
+	tenantSearcherURLsMu sync.Mutex
+	tenantSearcherURLs   = map[string]*endpoint.Map{}
 
 	indexedSearchOnce sync.Once
 	indexedSearch     *backend.Zoekt
@@ -29,17 +37,64 @@ var (
 	indexers     *backend.Indexers
 )
 
+// SearcherURLs returns the endpoints of the searcher service. They are those
+// of the search.searcherURL site configuration, or of SEARCHER_URL if it is
+// unset, and follow changes to the site configuration.
 func SearcherURLs() *endpoint.Map {
 	searcherURLsOnce.Do(func() {
-		if len(strings.Fields(searcherURL)) == 0 {
-			searcherURLs = endpoint.Empty(errors.New("a searcher service has not been configured"))
-		} else {
-			searcherURLs = endpoint.New(searcherURL)
-		}
+		conf.Watch(func() {
+			setSearcherURLSpec(SearcherURLSpecFromConfig())
+		})
 	})
+	searcherURLsMu.Lock()
+	defer searcherURLsMu.Unlock()
 	return searcherURLs
 }
 
+// SearcherURLSpecFromConfig returns the URL specifier of the searcher
+// service (see SearcherURLs), and whether it is from the site configuration.
+func SearcherURLSpecFromConfig() (spec string, fromSiteConfig bool) {
+	if spec := conf.Get().SearchSearcherURL; strings.TrimSpace(spec) != "" {
+		return spec, true
+	}
+	return searcherURL, false
+}
+
+func setSearcherURLSpec(spec string, fromSiteConfig bool) {
+	searcherURLsMu.Lock()
+	defer searcherURLsMu.Unlock()
+	if searcherURLs != nil && spec == searcherURLSpec {
+		return
+	}
+	if searcherURLs != nil {
+		log15.Info("Searcher URL changed.", "url", spec, "fromSiteConfig", fromSiteConfig)
+	}
+	searcherURLSpec = spec
+	if len(strings.Fields(spec)) == 0 {
+		searcherURLs = endpoint.Empty(errors.New("a searcher service has not been configured"))
+	} else {
+		searcherURLs = endpoint.New(spec)
+	}
+}
+
+// SearcherURLsForTenant returns the endpoints of the searcher instances of
+// the tenant of the search running in ctx, if it has dedicated ones, and
+// otherwise SearcherURLs.
+func SearcherURLsForTenant(ctx context.Context) *endpoint.Map {
+	t := TenantFromContext(ctx)
+	if t == nil || strings.TrimSpace(t.SearcherURL) == "" {
+		return SearcherURLs()
+	}
+	tenantSearcherURLsMu.Lock()
+	defer tenantSearcherURLsMu.Unlock()
+	m, ok := tenantSearcherURLs[t.SearcherURL]
+	if !ok {
+		m = endpoint.New(t.SearcherURL)
+		tenantSearcherURLs[t.SearcherURL] = m
+	}
+	return m
+}
+
 func Indexed() *backend.Zoekt {
 	indexedSearchOnce.Do(func() {
 		indexedSearch = &backend.Zoekt{}
@@ -1,9 +1,13 @@
 package search
 
 import (
+	"context"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 func TestZoektAddr(t *testing.T) {
@@ -53,3 +57,61 @@ func TestZoektAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestSearcherURLs(t *testing.T) {
+	defer conf.Mock(nil)
+	defer func(spec string) { searcherURL = spec }(searcherURL)
+	searcherURL = "http://searcher-env:3181"
+
+	endpoints := func() []string {
+		eps, err := SearcherURLs().Endpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var urls []string
+		for u := range eps {
+			urls = append(urls, u)
+		}
+		sort.Strings(urls)
+		return urls
+	}
+
+	conf.Mock(&conf.Unified{})
+	setSearcherURLSpec(SearcherURLSpecFromConfig())
+	if got, want := endpoints(), []string{"http://searcher-env:3181"}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v from SEARCHER_URL", got, want)
+	}
+
+	// The site configuration takes precedence over SEARCHER_URL.
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{
+		SearchSearcherURL: "http://searcher-0:3181 http://searcher-1:3181",
+	}})
+	if spec, fromSiteConfig := SearcherURLSpecFromConfig(); !fromSiteConfig {
+		t.Errorf("got spec %q from SEARCHER_URL, want from site configuration", spec)
+	}
+	setSearcherURLSpec(SearcherURLSpecFromConfig())
+	if got, want := endpoints(), []string{"http://searcher-0:3181", "http://searcher-1:3181"}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v from site configuration", got, want)
+	}
+}
+
+func TestSearcherURLsForTenant(t *testing.T) {
+	if got := SearcherURLsForTenant(context.Background()); got != SearcherURLs() {
+		t.Error("got dedicated searcher endpoints for a search without a tenant")
+	}
+	shared := WithTenant(context.Background(), &schema.SearchTenant{Name: "shared"})
+	if got := SearcherURLsForTenant(shared); got != SearcherURLs() {
+		t.Error("got dedicated searcher endpoints for a tenant without a searcherURL")
+	}
+
+	acme := WithTenant(context.Background(), &schema.SearchTenant{Name: "acme", SearcherURL: "http://searcher-acme:3181"})
+	m := SearcherURLsForTenant(acme)
+	if got, err := m.Get("repo@commit", nil); err != nil {
+		t.Fatal(err)
+	} else if want := "http://searcher-acme:3181"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if SearcherURLsForTenant(acme) != m {
+		t.Error("got new endpoints for the same searcherURL, want them to be reused")
+	}
+}
@@ -0,0 +1,73 @@
+// Package exclusions has the paths that searches exclude by default, such as
+// the build output of programming languages, which would otherwise dominate
+// the results of many searches. Searches opt out with defaultexclusions:no.
+package exclusions
+
+import (
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/ignore"
+)
+
+// defaultPatterns are the built-in default exclusions. The build output
+// directories are anchored to the repository root, since directories named
+// "target" or "dist" elsewhere often contain source code.
+var defaultPatterns = Patterns{
+	"CSS":        {"*.min.css"},
+	"Java":       {"/target/"},
+	"JavaScript": {"/dist/", "*.min.js"},
+	"Python":     {"__pycache__/"},
+	"Rust":       {"/target/"},
+	"TypeScript": {"/dist/"},
+}
+
+// Patterns are the patterns of the excluded paths, in the syntax of the
+// search ignore file (see package ignore), by language (as named by enry,
+// e.g. "JavaScript"). The entry "*" applies to all languages.
+type Patterns map[string][]string
+
+// WithDefaults returns the built-in default exclusions, overridden by the
+// entries of p. The entry of a language in p replaces its defaults, so an
+// empty entry disables them.
+func (p Patterns) WithDefaults() Patterns {
+	all := make(Patterns, len(defaultPatterns)+len(p))
+	for lang, patterns := range defaultPatterns {
+		all[lang] = patterns
+	}
+	for lang, patterns := range p {
+		all[lang] = patterns
+	}
+	return all
+}
+
+// Regexps returns the sorted and deduplicated regexps of the paths that the
+// patterns of languages (and "*") exclude. If languages is empty, the
+// patterns of all languages apply.
+func (p Patterns) Regexps(languages []string) ([]string, error) {
+	var patterns []string
+	if len(languages) == 0 {
+		for _, lang := range p {
+			patterns = append(patterns, lang...)
+		}
+	} else {
+		patterns = append(patterns, p["*"]...)
+		for _, lang := range languages {
+			patterns = append(patterns, p[lang]...)
+		}
+	}
+
+	seen := make(map[string]bool, len(patterns))
+	exprs := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr, err := ignore.Regexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[expr] {
+			seen[expr] = true
+			exprs = append(exprs, expr)
+		}
+	}
+	sort.Strings(exprs)
+	return exprs, nil
+}
@@ -0,0 +1,56 @@
+package exclusions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatterns_Regexps(t *testing.T) {
+	p := Patterns{
+		"*":      {"*.generated.go"},
+		"Python": {},
+		"Go":     {"/vendor"},
+	}.WithDefaults()
+
+	tests := []struct {
+		languages []string
+		want      []string
+	}{
+		{
+			languages: nil,
+			want: []string{
+				`(?:^|/)[^/]*\.generated\.go(?:/|$)`,
+				`(?:^|/)[^/]*\.min\.css(?:/|$)`,
+				`(?:^|/)[^/]*\.min\.js(?:/|$)`,
+				`^dist/`,
+				`^target/`,
+				`^vendor(?:/|$)`,
+			},
+		},
+		{
+			languages: []string{"Go"},
+			want:      []string{`(?:^|/)[^/]*\.generated\.go(?:/|$)`, `^vendor(?:/|$)`},
+		},
+		{
+			languages: []string{"Python"},
+			want:      []string{`(?:^|/)[^/]*\.generated\.go(?:/|$)`},
+		},
+		{
+			languages: []string{"Rust", "Java"},
+			want:      []string{`(?:^|/)[^/]*\.generated\.go(?:/|$)`, `^target/`},
+		},
+	}
+	for _, test := range tests {
+		got, err := p.Regexps(test.languages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %q, want %q", test.languages, got, test.want)
+		}
+	}
+
+	if _, err := (Patterns{"Go": {"[a"}}).Regexps(nil); err == nil {
+		t.Error("got nil error for an invalid pattern")
+	}
+}
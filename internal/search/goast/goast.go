@@ -0,0 +1,114 @@
+// Package goast finds the calls of Go functions and the uses of Go selectors
+// in Go source files by parsing them (see the gocall: and goselector: search
+// filters), instead of matching their names anywhere, such as in comments,
+// strings or the names of other functions.
+package goast
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Name is the name of a function or selector, optionally qualified by the
+// name of a package or variable, such as "mux.NewRouter".
+type Name struct {
+	Qualifier string // "" matches any qualifier, and no qualifier
+	Name      string
+}
+
+func (n Name) String() string {
+	if n.Qualifier == "" {
+		return n.Name
+	}
+	return n.Qualifier + "." + n.Name
+}
+
+// ParseName parses a name of the form "Name" or "Qualifier.Name", where both
+// are Go identifiers.
+func ParseName(s string) (Name, error) {
+	var n Name
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		n.Qualifier, n.Name = s[:i], s[i+1:]
+		if !token.IsIdentifier(n.Qualifier) {
+			return Name{}, fmt.Errorf("invalid Go name %q, expected Name or Qualifier.Name", s)
+		}
+	} else {
+		n.Name = s
+	}
+	if !token.IsIdentifier(n.Name) {
+		return Name{}, fmt.Errorf("invalid Go name %q, expected Name or Qualifier.Name", s)
+	}
+	return n, nil
+}
+
+// matchRange returns the range of the name of e if it is a use of n: an
+// identifier (if n isn't qualified) or a selector. Selectors match
+// unqualified names regardless of the expression they select from, in which
+// case only the name is matched.
+func (n Name) matchRange(e ast.Expr) (start, end token.Pos, ok bool) {
+	switch e := e.(type) {
+	case *ast.Ident:
+		if n.Qualifier == "" && e.Name == n.Name {
+			return e.Pos(), e.End(), true
+		}
+	case *ast.SelectorExpr:
+		if e.Sel.Name != n.Name {
+			return 0, 0, false
+		}
+		if n.Qualifier == "" {
+			return e.Sel.Pos(), e.Sel.End(), true
+		}
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == n.Qualifier {
+			return e.Pos(), e.End(), true
+		}
+	case *ast.ParenExpr:
+		return n.matchRange(e.X)
+	}
+	return 0, 0, false
+}
+
+// Calls returns the locations (as [start, end] offsets, in order) of the
+// names of the functions and methods named n that are called in src, the
+// source of a Go file. Files with syntax errors are searched as far as they
+// can be parsed.
+func Calls(src []byte, n Name) [][]int {
+	return find(src, func(node ast.Node) (start, end token.Pos, ok bool) {
+		if call, ok := node.(*ast.CallExpr); ok {
+			return n.matchRange(call.Fun)
+		}
+		return 0, 0, false
+	})
+}
+
+// Selectors returns the locations (as [start, end] offsets, in order) of the
+// selectors named n (e.g. "http.Handler" or ".Handler" in "s.Handler") in
+// src, the source of a Go file.
+func Selectors(src []byte, n Name) [][]int {
+	return find(src, func(node ast.Node) (start, end token.Pos, ok bool) {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			return n.matchRange(sel)
+		}
+		return 0, 0, false
+	})
+}
+
+func find(src []byte, match func(ast.Node) (start, end token.Pos, ok bool)) [][]int {
+	fset := token.NewFileSet()
+	f, _ := parser.ParseFile(fset, "", src, 0)
+	if f == nil {
+		return nil
+	}
+	var locs [][]int
+	ast.Inspect(f, func(node ast.Node) bool {
+		if start, end, ok := match(node); ok {
+			locs = append(locs, []int{fset.Position(start).Offset, fset.Position(end).Offset})
+		}
+		return true
+	})
+	sort.Slice(locs, func(i, j int) bool { return locs[i][0] < locs[j][0] })
+	return locs
+}
@@ -0,0 +1,97 @@
+package goast
+
+import (
+	"reflect"
+	"testing"
+)
+
+const src = `package main
+
+import "github.com/gorilla/mux"
+
+// New returns a New router (mux.NewRouter).
+func New() *mux.Router {
+	r := mux.NewRouter()
+	r.Handle("/", (New)().NotFoundHandler)
+	return other.NewRouter(New())
+}
+`
+
+func TestCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"New", []string{"New", "New"}},
+		{"NewRouter", []string{"NewRouter", "NewRouter"}},
+		{"mux.NewRouter", []string{"mux.NewRouter"}},
+		{"Handle", []string{"Handle"}},
+		{"NotFoundHandler", nil},
+		{"mux.New", nil},
+	}
+	for _, test := range tests {
+		n, err := ParseName(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, loc := range Calls([]byte(src), n) {
+			got = append(got, src[loc[0]:loc[1]])
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("calls of %s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Router", []string{"Router"}},
+		{"NotFoundHandler", []string{"NotFoundHandler"}},
+		{"mux.Router", []string{"mux.Router"}},
+		{"New", nil},
+	}
+	for _, test := range tests {
+		n, err := ParseName(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, loc := range Selectors([]byte(src), n) {
+			got = append(got, src[loc[0]:loc[1]])
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("selectors %s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCalls_syntaxError(t *testing.T) {
+	src := "package main\nfunc f() { g(); h( }\n"
+	if got := Calls([]byte(src), Name{Name: "g"}); len(got) != 1 {
+		t.Errorf("got %v, want the call of g", got)
+	}
+	if got := Calls([]byte("not Go"), Name{Name: "g"}); len(got) != 0 {
+		t.Errorf("got %v, want no calls", got)
+	}
+}
+
+func TestParseName(t *testing.T) {
+	for s, want := range map[string]Name{
+		"New":           {Name: "New"},
+		"mux.NewRouter": {Qualifier: "mux", Name: "NewRouter"},
+	} {
+		got, err := ParseName(s)
+		if err != nil || got != want || got.String() != s {
+			t.Errorf("%q: got %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "a.b.c", ".New", "New.", "New()", "1x"} {
+		if _, err := ParseName(s); err == nil {
+			t.Errorf("%q: got no error", s)
+		}
+	}
+}
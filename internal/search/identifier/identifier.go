@@ -0,0 +1,191 @@
+// Package identifier matches search patterns against whole identifiers of
+// programming languages (see the identifier: search filter), instead of at
+// regexp word boundaries. Which characters make up an identifier depends on
+// the language: "id" does not match "valid" or "$id" in JavaScript, nor
+// "box-id" in CSS. Matches can optionally also be sub-tokens of camelCase,
+// PascalCase, snake_case or kebab-case identifiers, so that "Handler"
+// matches "requestHandler".
+//
+// The same matchers implement word matches (PatternInfo.IsWordMatch), whose
+// word characters can be configured per language (see WordCharacters).
+package identifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/src-d/enry/v2"
+)
+
+// defaultWordCharacters are the characters besides letters, digits and "_"
+// that can be part of identifiers, by language (as named by enry).
+var defaultWordCharacters = WordCharacters{
+	"JavaScript": "$",
+	"TypeScript": "$",
+	"TSX":        "$",
+	"JSX":        "$",
+	"Java":       "$",
+	"Scala":      "$",
+	"Groovy":     "$",
+
+	"CSS":  "-",
+	"SCSS": "-",
+	"Less": "-",
+	"Sass": "-",
+	"HTML": "-",
+
+	"Clojure":     "-?!*+<>=",
+	"Common Lisp": "-?!*+<>=",
+	"Emacs Lisp":  "-?!*+<>=",
+	"Scheme":      "-?!*+<>=",
+	"Racket":      "-?!*+<>=",
+
+	"Ruby":    "?!",
+	"Elixir":  "?!",
+	"Crystal": "?!",
+}
+
+// Matcher reports whether matches in the lines of a file are identifiers.
+type Matcher struct {
+	extra     string
+	subTokens bool
+}
+
+// New returns a Matcher for the identifiers of language (as named by enry,
+// e.g. "JavaScript"). Unknown languages (and "") have identifiers of
+// letters, digits and "_". If subTokens is true, sub-tokens of identifiers
+// match, too.
+func New(language string, subTokens bool) Matcher {
+	return WordCharacters(nil).Matcher(language, subTokens)
+}
+
+// ForPath returns a Matcher for the identifiers of the language of the file
+// at path, which is detected by its name.
+func ForPath(path string, subTokens bool) Matcher {
+	return WordCharacters(nil).MatcherForPath(path, subTokens)
+}
+
+// WordCharacters overrides the characters besides letters, digits and "_"
+// that are part of words (and identifiers), by language (as named by enry).
+// The entry "*" applies to the languages without entries of their own. The
+// languages without either use the built-in defaults, such as "-" for CSS.
+type WordCharacters map[string]string
+
+// ParseWordCharacters parses entries of the form "language:characters", as
+// returned by WordCharacters.Entries.
+func ParseWordCharacters(entries []string) (WordCharacters, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	w := make(WordCharacters, len(entries))
+	for _, e := range entries {
+		i := strings.IndexByte(e, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid word characters %q, expected language:characters", e)
+		}
+		if err := CheckWordCharacters(e[i+1:]); err != nil {
+			return nil, err
+		}
+		w[e[:i]] = e[i+1:]
+	}
+	return w, nil
+}
+
+// CheckWordCharacters returns an error if chars contains characters that
+// can't be configured as word characters. Only ASCII punctuation can be,
+// because letters and digits always are word characters and other
+// characters never are.
+func CheckWordCharacters(chars string) error {
+	for _, r := range chars {
+		if r >= utf8.RuneSelf || !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return fmt.Errorf("invalid word character %q, expected ASCII punctuation", r)
+		}
+	}
+	return nil
+}
+
+// Entries returns the entries of w in the form "language:characters", sorted
+// by language.
+func (w WordCharacters) Entries() []string {
+	if len(w) == 0 {
+		return nil
+	}
+	entries := make([]string, 0, len(w))
+	for language, chars := range w {
+		entries = append(entries, language+":"+chars)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Matcher returns a Matcher (see New) for the identifiers of language with
+// the word characters of w.
+func (w WordCharacters) Matcher(language string, subTokens bool) Matcher {
+	extra, ok := w[language]
+	if !ok {
+		if extra, ok = w["*"]; !ok {
+			extra = defaultWordCharacters[language]
+		}
+	}
+	return Matcher{extra: extra, subTokens: subTokens}
+}
+
+// MatcherForPath returns a Matcher (see ForPath) for the identifiers of the
+// language of the file at path with the word characters of w.
+func (w WordCharacters) MatcherForPath(path string, subTokens bool) Matcher {
+	language, _ := enry.GetLanguageByExtension(path)
+	if language == "" {
+		language, _ = enry.GetLanguageByFilename(path)
+	}
+	return w.Matcher(language, subTokens)
+}
+
+func (m Matcher) isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || (r < utf8.RuneSelf && strings.ContainsRune(m.extra, r))
+}
+
+// Match reports whether line[start:end] (a match of a search pattern) is a
+// whole identifier, or a sequence of whole sub-tokens of an identifier if m
+// matches sub-tokens. Matches that start or end with characters that are
+// not part of identifiers (such as "foo(") only need the identifier
+// characters at their ends to be whole.
+func (m Matcher) Match(line []byte, start, end int) bool {
+	if start < 0 || end > len(line) || start >= end {
+		return false
+	}
+	return m.boundary(line, start) && m.boundary(line, end)
+}
+
+// boundary reports whether a match can start or end at line[i].
+func (m Matcher) boundary(line []byte, i int) bool {
+	prev, _ := utf8.DecodeLastRune(line[:i])
+	next, size := utf8.DecodeRune(line[i:])
+	if i == 0 || i == len(line) || !m.isIdentifierRune(prev) || !m.isIdentifierRune(next) {
+		return true
+	}
+	if !m.subTokens {
+		return false
+	}
+
+	// Within an identifier, sub-tokens are separated by the characters that
+	// aren't letters or digits (e.g. "_" or "-"), and start with an upper
+	// case letter that follows a lower case letter or digit ("request|Handler")
+	// or that is followed by a lower case letter ("HTTP|Server").
+	switch {
+	case !isAlnum(prev) || !isAlnum(next):
+		return true
+	case unicode.IsUpper(next) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+		return true
+	case unicode.IsUpper(next) && unicode.IsUpper(prev):
+		after, _ := utf8.DecodeRune(line[i+size:])
+		return unicode.IsLower(after)
+	}
+	return false
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,107 @@
+package identifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		language  string
+		subTokens bool
+		line      string
+		match     string // the first occurrence of match in line is matched
+		want      bool
+	}{
+		{"Go", false, "id := valid(id)", "id", true},
+		{"Go", false, "valid", "id", false},
+		{"Go", false, "user_id", "id", false},
+		{"Go", false, "id2", "id", false},
+		{"Go", false, "$id", "id", true},
+		{"Go", false, "foo(id)", "foo(", true},
+		{"Go", false, "afoo(id)", "foo(", false},
+		{"Go", false, "héllo", "llo", false},
+		{"JavaScript", false, "$id", "id", false},
+		{"JavaScript", false, "$id", "$id", true},
+		{"CSS", false, "box-id", "id", false},
+		{"CSS", false, "box id", "id", true},
+		{"Clojure", false, "(valid? x)", "valid", false},
+		{"", false, "(valid? x)", "valid", true},
+
+		{"Go", false, "requestHandler", "Handler", false},
+		{"Go", true, "requestHandler", "Handler", true},
+		{"Go", true, "requestHandler", "request", true},
+		{"Go", true, "requestHandler", "quest", false},
+		{"Go", true, "requestHandler", "Hand", false},
+		{"Go", true, "HTTPServer", "Server", true},
+		{"Go", true, "HTTPServer", "HTTP", true},
+		{"Go", true, "HTTPServer", "TTP", false},
+		{"Go", true, "user_id", "id", true},
+		{"Go", true, "v2Handler", "Handler", true},
+		{"CSS", true, "box-id", "id", true},
+		{"Go", true, "valid", "id", false},
+	}
+	for _, test := range tests {
+		start := strings.Index(test.line, test.match)
+		got := New(test.language, test.subTokens).Match([]byte(test.line), start, start+len(test.match))
+		if got != test.want {
+			t.Errorf("%s (sub-tokens %v): %q in %q: got %v, want %v", test.language, test.subTokens, test.match, test.line, got, test.want)
+		}
+	}
+}
+
+func TestForPath(t *testing.T) {
+	if New("JavaScript", false) != ForPath("web/src/index.js", false) {
+		t.Error("got a different matcher for a .js file than for JavaScript")
+	}
+	if New("", true) != ForPath("README", true) {
+		t.Error("got a language-specific matcher for a file without a language")
+	}
+}
+
+func TestWordCharacters(t *testing.T) {
+	w := WordCharacters{"CSS": "", "*": "-"}
+	tests := []struct {
+		language string
+		line     string
+		match    string
+		want     bool
+	}{
+		{"CSS", "box-id", "id", true},     // overridden for CSS
+		{"Go", "box-id", "id", false},     // overridden for all languages
+		{"Go", "$id", "id", true},         // "*" replaces the defaults
+		{"Shell", "(x-id)", "x-id", true}, // "-" is a word character
+	}
+	for _, test := range tests {
+		start := strings.Index(test.line, test.match)
+		got := w.Matcher(test.language, false).Match([]byte(test.line), start, start+len(test.match))
+		if got != test.want {
+			t.Errorf("%s: %q in %q: got %v, want %v", test.language, test.match, test.line, got, test.want)
+		}
+	}
+
+	if WordCharacters(nil).Matcher("CSS", false) != New("CSS", false) {
+		t.Error("got different matchers for no word characters and the defaults")
+	}
+	if w.MatcherForPath("style.css", false) != w.Matcher("CSS", false) {
+		t.Error("got a different matcher for a .css file than for CSS")
+	}
+}
+
+func TestParseWordCharacters(t *testing.T) {
+	w := WordCharacters{"*": "$", "CSS": "-:", "Go": ""}
+	got, err := ParseWordCharacters(w.Entries())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, w) {
+		t.Errorf("got %v, want %v", got, w)
+	}
+
+	for _, entries := range [][]string{{"CSS"}, {":-"}, {"Go:a"}, {"Go: "}, {"Go:é"}} {
+		if _, err := ParseWordCharacters(entries); err == nil {
+			t.Errorf("%q: got no error", entries)
+		}
+	}
+}
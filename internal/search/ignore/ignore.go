@@ -0,0 +1,195 @@
+// Package ignore parses the search ignore file of repositories, which lists
+// the paths that never appear in the search results for a repository.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/pkg/errors"
+)
+
+// Path is the path of the ignore file in a repository.
+const Path = ".sourcegraph/ignore"
+
+// Matcher matches the paths listed in an ignore file.
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	glob glob.Glob
+	// anchored patterns are matched against the path from the repository
+	// root. Other patterns are matched against each path component.
+	anchored bool
+	// dirOnly patterns only match directories.
+	dirOnly bool
+}
+
+// Parse parses the contents of an ignore file. Each line of the file is a glob
+// pattern (see github.com/gobwas/glob, with "/" as the separator) and blank
+// lines and lines starting with "#" are skipped. As in .gitignore files:
+//
+// - A pattern without a "/" matches a file or directory with that name
+//   anywhere in the repository, e.g. "*.min.js" or "node_modules".
+// - A pattern with a "/" is relative to the repository root, e.g.
+//   "/vendor" or "docs/generated/*.md".
+// - A pattern with a trailing "/" only matches directories, e.g. "testdata/".
+//
+// Ignoring a directory ignores all files below it. Negated patterns ("!") are
+// not supported.
+func Parse(data []byte) (*Matcher, error) {
+	var m Matcher
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; s.Scan(); lineNumber++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			return nil, errors.Errorf("line %d: negated patterns are not supported", lineNumber)
+		}
+
+		var p pattern
+		line, p.anchored, p.dirOnly = splitPattern(line)
+		if line == "" {
+			continue
+		}
+		g, err := glob.Compile(line, '/')
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", lineNumber)
+		}
+		p.glob = g
+		m.patterns = append(m.patterns, p)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// splitPattern returns the glob of a pattern of an ignore file, and whether
+// the pattern is anchored (contains a "/") and only matches directories (has
+// a trailing "/").
+func splitPattern(line string) (glob string, anchored, dirOnly bool) {
+	if strings.HasSuffix(line, "/") {
+		dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	return line, anchored, dirOnly
+}
+
+// Regexp returns a regexp that matches the paths (relative to the repository
+// root, without a leading "/") that the pattern of an ignore file ignores,
+// such as "*.min.js" or "dist/". It is used to exclude the paths from searches
+// instead of filtering the results.
+//
+// The regexp supports the glob syntax "*", "**", "?", "[...]" and "{a,b}",
+// and "\" escapes.
+func Regexp(pattern string) (string, error) {
+	g, anchored, dirOnly := splitPattern(strings.TrimSpace(pattern))
+	if g == "" || strings.HasPrefix(g, "!") {
+		return "", errors.Errorf("invalid pattern %q", pattern)
+	}
+	if _, err := glob.Compile(g, '/'); err != nil {
+		return "", errors.Wrapf(err, "invalid pattern %q", pattern)
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(?:^|/)")
+	}
+	inClass, inAlternatives := false, false
+	for i := 0; i < len(g); i++ {
+		c := g[i]
+		switch {
+		case c == '\\' && i+1 < len(g):
+			i++
+			b.WriteString(regexp.QuoteMeta(g[i : i+1]))
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			b.WriteByte(c)
+		case c == '[':
+			inClass = true
+			b.WriteByte('[')
+			if i+1 < len(g) && g[i+1] == '!' {
+				i++
+				b.WriteByte('^')
+			}
+		case c == '*' && i+1 < len(g) && g[i+1] == '*' && anchored:
+			// Only anchored patterns can match several path components.
+			i++
+			b.WriteString(".*")
+		case c == '*':
+			for i+1 < len(g) && g[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '{':
+			inAlternatives = true
+			b.WriteString("(?:")
+		case c == ',' && inAlternatives:
+			b.WriteByte('|')
+		case c == '}' && inAlternatives:
+			inAlternatives = false
+			b.WriteByte(')')
+		default:
+			b.WriteString(regexp.QuoteMeta(g[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/")
+	} else {
+		b.WriteString("(?:/|$)")
+	}
+	return b.String(), nil
+}
+
+// Match reports whether the file at path (relative to the repository root)
+// is ignored. A nil Matcher matches nothing.
+func (m *Matcher) Match(path string) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	path = strings.TrimPrefix(path, "/")
+	for _, p := range m.patterns {
+		if p.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p pattern) match(path string) bool {
+	// Check each directory containing the file, then the file itself.
+	for start, i := 0, 0; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		isDir := i < len(path)
+		if isDir || !p.dirOnly {
+			name := path[start:i]
+			if p.anchored {
+				name = path[:i]
+			}
+			if p.glob.Match(name) {
+				return true
+			}
+		}
+		start = i + 1
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package ignore
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	m, err := Parse([]byte(`
+# Generated code
+*.pb.go
+/vendor
+docs/generated/*.md
+testdata/
+third_party/**/LICENSE
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"a.pb.go":                       true,
+		"cmd/a/a.pb.go":                 true,
+		"a.go":                          false,
+		"vendor/github.com/a/b/b.go":    true,
+		"vendor":                        true,
+		"cmd/vendor/a.go":               false,
+		"docs/generated/a.md":           true,
+		"docs/generated/sub/a.md":       false,
+		"x/docs/generated/a.md":         false,
+		"testdata/a.txt":                true,
+		"pkg/testdata/in/a.txt":         true,
+		"testdata":                      false,
+		"third_party/a/b/LICENSE":       true,
+		"third_party/a/LICENSE.md":      false,
+		"/cmd/a/a.pb.go":                true,
+		"# Generated code":              false,
+		"docs/generated":                false,
+		"docs/generated/a.md/README.md": true,
+	}
+	for path, want := range tests {
+		if got := m.Match(path); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	var nilMatcher *Matcher
+	if nilMatcher.Match("a.go") {
+		t.Error("nil Matcher matched a path")
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	for _, data := range []string{"!a.go", "[a"} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q): got nil error", data)
+		}
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	patterns := []string{"*.pb.go", "/vendor", "docs/generated/*.md", "testdata/", "third_party/**/LICENSE", "*.min.{js,css}", "[!a-c]?.go", `a\*.go`}
+	paths := []string{
+		"a.pb.go", "cmd/a/a.pb.go", "a.go", "vendor/github.com/a/b/b.go", "vendor", "cmd/vendor/a.go",
+		"docs/generated/a.md", "docs/generated/sub/a.md", "x/docs/generated/a.md", "testdata/a.txt",
+		"pkg/testdata/in/a.txt", "testdata", "third_party/a/b/LICENSE", "third_party/a/LICENSE.md",
+		"dist/app.min.js", "app.min.css", "app.min.jsx", "dx.go", "ax.go", "a*.go", "ab.go",
+	}
+	for _, pattern := range patterns {
+		m, err := Parse([]byte(pattern))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expr, err := Regexp(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		re := regexp.MustCompile(expr)
+		for _, path := range paths {
+			if got, want := re.MatchString(path), m.Match(path); got != want {
+				t.Errorf("Regexp(%q) = %q matches %q: got %v, want %v", pattern, expr, path, got, want)
+			}
+		}
+	}
+
+	for _, pattern := range []string{"", "/", "!a.go", "[a"} {
+		if _, err := Regexp(pattern); err == nil {
+			t.Errorf("Regexp(%q): got nil error", pattern)
+		}
+	}
+}
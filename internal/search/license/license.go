@@ -0,0 +1,135 @@
+// Package license detects the licenses of files in repositories, from the
+// SPDX-License-Identifier header of a file or the license files (such as
+// LICENSE) of the directories that contain it.
+package license
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Unknown is the license of files whose license is not detected. The
+// license: search filter matches it like a license identifier.
+const Unknown = "unknown"
+
+// A license is detected in a text if the text contains all of its phrases.
+type license struct {
+	id      string // the SPDX identifier
+	phrases []string
+}
+
+// licenses are the detected licenses. Licenses whose texts contain the
+// phrases of other licenses come first.
+var licenses = []license{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"EPL-1.0", []string{"eclipse public license", "1.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted free of charge", "the above copyright notice and this permission notice shall be included"}},
+	{"ISC", []string{"permission to use copy modify and", "distribute this software for any purpose with or without fee is hereby granted"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+}
+
+func init() {
+	for _, l := range licenses {
+		for i, p := range l.phrases {
+			l.phrases[i] = normalize(p)
+		}
+	}
+}
+
+// normalize lowercases text and replaces punctuation, comment markers and
+// line breaks with single spaces, so that phrases match across the lines of
+// license headers in comments. Dots are only kept in version numbers.
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r == '.')
+	})
+	var b strings.Builder
+	b.WriteByte(' ')
+	for _, w := range words {
+		if w = strings.Trim(w, "."); w != "" {
+			b.WriteString(w)
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// Detect returns the SPDX identifier of the license whose text (or standard
+// header) is text, or "" if it is not detected.
+func Detect(text []byte) string {
+	t := normalize(string(text))
+	for _, l := range licenses {
+		found := true
+		for _, p := range l.phrases {
+			if !strings.Contains(t, p) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return l.id
+		}
+	}
+	return ""
+}
+
+var spdxHeader = regexp.MustCompile(`SPDX-License-Identifier:[ \t]*([A-Za-z0-9.+:() \t-]+)`)
+
+// Header returns the license expression of the SPDX-License-Identifier
+// header in data, the beginning of a file (e.g. "Apache-2.0 OR MIT"), or ""
+// if there is none.
+func Header(data []byte) string {
+	m := spdxHeader.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	var expr []string
+	for _, f := range strings.Fields(string(m[1])) {
+		if strings.Trim(f, "-") == "" {
+			continue // e.g. the end of an HTML comment
+		}
+		expr = append(expr, f)
+	}
+	return strings.Join(expr, " ")
+}
+
+// IsLicenseFile reports whether the file at path is a license file, such as
+// LICENSE, LICENSE.md, COPYING or LICENSE-MIT.
+func IsLicenseFile(p string) bool {
+	name := strings.ToLower(path.Base(p))
+	if i := strings.IndexAny(name, ".-"); i >= 0 {
+		name = name[:i]
+	}
+	switch name {
+	case "license", "licence", "copying", "unlicense":
+		return true
+	}
+	return false
+}
+
+// Matches reports whether the license expression expr (an SPDX license
+// expression, such as "MIT" or "Apache-2.0 OR MIT") includes the license id.
+// Licenses are compared case-insensitively, and Unknown matches the empty
+// expression.
+func Matches(expr, id string) bool {
+	if expr == "" {
+		return strings.EqualFold(id, Unknown)
+	}
+	for _, f := range strings.FieldsFunc(expr, func(r rune) bool { return r == ' ' || r == '\t' || r == '(' || r == ')' }) {
+		if strings.EqualFold(strings.TrimSuffix(f, "+"), strings.TrimSuffix(id, "+")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package license
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		`Copyright (c) 2020 Example
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction...
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.`: "MIT",
+
+		`// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.`: "Apache-2.0",
+
+		`                    GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007`: "GPL-3.0",
+
+		`                    GNU GENERAL PUBLIC LICENSE
+                       Version 2, June 1991`: "GPL-2.0",
+
+		`                   GNU LESSER GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007
+
+  This version of the GNU Lesser General Public License incorporates
+the terms and conditions of version 3 of the GNU General Public
+License`: "LGPL-3.0",
+
+		`Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+ * Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products`: "BSD-3-Clause",
+
+		`Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:`: "BSD-2-Clause",
+
+		`This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this`: "MPL-2.0",
+
+		`Permission to use, copy, modify, and/or distribute this software for any
+purpose with or without fee is hereby granted`: "ISC",
+
+		`This is free and unencumbered software released into the public domain.`: "Unlicense",
+
+		`All rights reserved.`: "",
+	}
+	for text, want := range tests {
+		if got := Detect([]byte(text)); got != want {
+			t.Errorf("got %q, want %q for:\n%s", got, want, text)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tests := map[string]string{
+		"// SPDX-License-Identifier: MIT\npackage a":                 "MIT",
+		"/* SPDX-License-Identifier: Apache-2.0 OR MIT */\n":         "Apache-2.0 OR MIT",
+		"<!-- SPDX-License-Identifier: GPL-2.0-or-later -->":         "GPL-2.0-or-later",
+		"# SPDX-License-Identifier: (MIT OR Apache-2.0) WITH LLVM\n": "(MIT OR Apache-2.0) WITH LLVM",
+		"// Copyright 2020 Example\npackage a":                       "",
+	}
+	for data, want := range tests {
+		if got := Header([]byte(data)); got != want {
+			t.Errorf("%q: got %q, want %q", data, got, want)
+		}
+	}
+}
+
+func TestIsLicenseFile(t *testing.T) {
+	tests := map[string]bool{
+		"LICENSE":             true,
+		"sub/LICENSE.md":      true,
+		"LICENSE-APACHE":      true,
+		"COPYING":             true,
+		"Licence.txt":         true,
+		"UNLICENSE":           true,
+		"license_checker.go":  false,
+		"licenses/README.md":  false,
+		"docs/licensing.html": false,
+	}
+	for path, want := range tests {
+		if got := IsLicenseFile(path); got != want {
+			t.Errorf("%s: got %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		expr, id string
+		want     bool
+	}{
+		{"MIT", "mit", true},
+		{"Apache-2.0 OR MIT", "MIT", true},
+		{"(MIT OR Apache-2.0) WITH LLVM", "Apache-2.0", true},
+		{"GPL-2.0+", "GPL-2.0", true},
+		{"GPL-2.0", "GPL-3.0", false},
+		{"", "unknown", true},
+		{"MIT", "unknown", false},
+	}
+	for _, test := range tests {
+		if got := Matches(test.expr, test.id); got != test.want {
+			t.Errorf("Matches(%q, %q): got %v, want %v", test.expr, test.id, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// OffsetUnit is the unit of the offsets and lengths of matches in a line.
+type OffsetUnit string
+
+const (
+	// OffsetUnitCharacter offsets count Unicode code points (runes), where
+	// each byte that is not valid UTF-8 is one code point. This is the unit
+	// that searcher and zoekt matches are converted to.
+	OffsetUnitCharacter OffsetUnit = "CHARACTER"
+	// OffsetUnitByte offsets count the bytes of the UTF-8 encoded line, as
+	// used for indexing strings in Go.
+	OffsetUnitByte OffsetUnit = "BYTE"
+	// OffsetUnitUTF16 offsets count UTF-16 code units, as used for indexing
+	// strings in JavaScript.
+	OffsetUnitUTF16 OffsetUnit = "UTF16"
+)
+
+// ParseOffsetUnit parses an offset unit case-insensitively. The empty string
+// is OffsetUnitCharacter.
+func ParseOffsetUnit(s string) (OffsetUnit, error) {
+	switch u := OffsetUnit(strings.ToUpper(s)); u {
+	case "":
+		return OffsetUnitCharacter, nil
+	case OffsetUnitCharacter, OffsetUnitByte, OffsetUnitUTF16:
+		return u, nil
+	}
+	return "", fmt.Errorf("invalid offset unit %q (valid values are: character, byte, utf16)", s)
+}
+
+// ConvertOffsets converts offsetAndLengths, which are [offset, length] pairs
+// measured in characters (see OffsetUnitCharacter) of line, to unit. Offsets
+// beyond the end of line are clamped to it.
+func ConvertOffsets(line string, offsetAndLengths [][2]int32, unit OffsetUnit) [][2]int32 {
+	if unit == OffsetUnitCharacter || unit == "" || len(offsetAndLengths) == 0 {
+		return offsetAndLengths
+	}
+
+	// offsets[i] is the offset in unit of the i-th character of line.
+	offsets := make([]int32, 1, len(line)+1)
+	var offset int32
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		switch {
+		case unit == OffsetUnitByte:
+			offset += int32(size)
+		case r >= 0x10000:
+			// Characters outside the Basic Multilingual Plane are encoded
+			// as surrogate pairs.
+			offset += 2
+		default:
+			offset++
+		}
+		offsets = append(offsets, offset)
+		i += size
+	}
+	at := func(chars int32) int32 {
+		if chars < 0 {
+			chars = 0
+		}
+		if int(chars) >= len(offsets) {
+			chars = int32(len(offsets) - 1)
+		}
+		return offsets[chars]
+	}
+
+	converted := make([][2]int32, len(offsetAndLengths))
+	for i, ol := range offsetAndLengths {
+		start, end := at(ol[0]), at(ol[0]+ol[1])
+		converted[i] = [2]int32{start, end - start}
+	}
+	return converted
+}
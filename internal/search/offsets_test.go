@@ -0,0 +1,34 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertOffsets(t *testing.T) {
+	// "é" is 2 bytes and 1 UTF-16 code unit, "😀" is 4 bytes and 2 UTF-16
+	// code units.
+	line := "é😀 foo \xff bar"
+	offsets := [][2]int32{{2, 4}, {9, 3}, {100, 1}}
+	tests := map[OffsetUnit][][2]int32{
+		OffsetUnitCharacter: {{2, 4}, {9, 3}, {100, 1}},
+		OffsetUnitByte:      {{6, 4}, {13, 3}, {16, 0}},
+		OffsetUnitUTF16:     {{3, 4}, {10, 3}, {13, 0}},
+	}
+	for unit, want := range tests {
+		if got := ConvertOffsets(line, offsets, unit); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", unit, got, want)
+		}
+	}
+}
+
+func TestParseOffsetUnit(t *testing.T) {
+	for s, want := range map[string]OffsetUnit{"": OffsetUnitCharacter, "utf16": OffsetUnitUTF16, "BYTE": OffsetUnitByte} {
+		if got, err := ParseOffsetUnit(s); err != nil || got != want {
+			t.Errorf("ParseOffsetUnit(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseOffsetUnit("rune"); err == nil {
+		t.Error("got nil error for invalid unit")
+	}
+}
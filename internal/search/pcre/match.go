@@ -0,0 +1,466 @@
+package pcre
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// checkDeadlineSteps is how many steps of matching are taken between
+	// checks of the deadline.
+	checkDeadlineSteps = 1024
+
+	// maxDepth is the limit on the number of nested continuations of a
+	// match, which are on the stack. Matches that exceed it are aborted like
+	// matches that time out.
+	maxDepth = 20000
+)
+
+// node is a parsed part of a regexp.
+type node interface {
+	// match reports whether the node matches the input at i, followed by a
+	// match of the continuation k at the end of the node's match. It tries
+	// the ends in order of preference.
+	match(m *machine, i int, k func(int) bool) bool
+}
+
+// single is a node that matches a single character.
+type single interface {
+	node
+
+	// matchOne returns the end of the character at i that the node
+	// matches.
+	matchOne(m *machine, i int) (int, bool)
+}
+
+// machine is the state of matching an input.
+type machine struct {
+	input    []byte
+	caps     []int // the start and end of each capturing group, or -1
+	steps    int
+	depth    int
+	deadline time.Time // zero if there is no deadline
+	timedOut bool      // the deadline (or maxDepth) was exceeded
+}
+
+func (re *Regexp) machine(b []byte) *machine {
+	m := &machine{input: b, caps: make([]int, 2*(re.numCaps+1))}
+	if re.timeout > 0 {
+		m.deadline = time.Now().Add(re.timeout)
+	}
+	return m
+}
+
+// matchAt returns the end of the match of n at i, if any.
+func (m *machine) matchAt(n node, i int) (end int, ok bool) {
+	for j := range m.caps {
+		m.caps[j] = -1
+	}
+	m.depth = 0
+	ok = n.match(m, i, func(j int) bool {
+		end = j
+		return true
+	})
+	return end, ok && !m.timedOut
+}
+
+// step counts a step of matching. It returns false if matching must stop.
+func (m *machine) step() bool {
+	if m.timedOut {
+		return false
+	}
+	m.steps++
+	if m.steps%checkDeadlineSteps == 0 && !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		m.timedOut = true
+	}
+	return !m.timedOut
+}
+
+// call calls the continuation k at i, keeping track of the depth.
+func (m *machine) call(k func(int) bool, i int) bool {
+	if m.depth++; m.depth > maxDepth {
+		m.timedOut = true
+	}
+	ok := !m.timedOut && k(i)
+	m.depth--
+	return ok
+}
+
+// saveCaps returns a copy of the captures, to restore them when
+// backtracking out of a group that may have set them.
+func (m *machine) saveCaps() []int {
+	return append([]int(nil), m.caps...)
+}
+
+// literal matches a string.
+type literal struct {
+	runes []rune
+	fold  bool // matches the other cases of the runes too
+}
+
+func (n *literal) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	for _, r := range n.runes {
+		if i >= len(m.input) {
+			return false
+		}
+		c, size := utf8.DecodeRune(m.input[i:])
+		if c != r && !(n.fold && equalFold(c, r)) {
+			return false
+		}
+		i += size
+	}
+	return k(i)
+}
+
+func (n *literal) matchOne(m *machine, i int) (int, bool) {
+	if len(n.runes) != 1 || i >= len(m.input) {
+		return 0, false
+	}
+	c, size := utf8.DecodeRune(m.input[i:])
+	if c != n.runes[0] && !(n.fold && equalFold(c, n.runes[0])) {
+		return 0, false
+	}
+	return i + size, true
+}
+
+func equalFold(a, b rune) bool {
+	for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+		if r == b {
+			return true
+		}
+	}
+	return false
+}
+
+// charClass matches a character in a set.
+type charClass struct {
+	ranges []rune // sorted pairs of the first and last rune of each range
+}
+
+func (n *charClass) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	end, ok := n.matchOne(m, i)
+	return ok && k(end)
+}
+
+func (n *charClass) matchOne(m *machine, i int) (int, bool) {
+	if i >= len(m.input) {
+		return 0, false
+	}
+	c, size := utf8.DecodeRune(m.input[i:])
+	lo, hi := 0, len(n.ranges)/2
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		switch {
+		case c < n.ranges[2*mid]:
+			hi = mid
+		case c > n.ranges[2*mid+1]:
+			lo = mid + 1
+		default:
+			return i + size, true
+		}
+	}
+	return 0, false
+}
+
+// anyChar matches any character, except \n unless nl is set.
+type anyChar struct {
+	nl bool
+}
+
+func (n *anyChar) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	end, ok := n.matchOne(m, i)
+	return ok && k(end)
+}
+
+func (n *anyChar) matchOne(m *machine, i int) (int, bool) {
+	if i >= len(m.input) || !n.nl && m.input[i] == '\n' {
+		return 0, false
+	}
+	_, size := utf8.DecodeRune(m.input[i:])
+	return i + size, true
+}
+
+type assertionKind int
+
+const (
+	beginText         assertionKind = iota // \A, and ^ without (?m)
+	endText                                // \z
+	endTextOptionalNL                      // \Z, and $ without (?m): the end, or before a final \n
+	beginLine                              // ^ with (?m)
+	endLine                                // $ with (?m)
+	wordBoundary                           // \b
+	noWordBoundary                         // \B
+)
+
+// assertion matches the empty string at positions of a kind.
+type assertion struct {
+	kind assertionKind
+}
+
+func (n *assertion) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	in := m.input
+	var ok bool
+	switch n.kind {
+	case beginText:
+		ok = i == 0
+	case endText:
+		ok = i == len(in)
+	case endTextOptionalNL:
+		ok = i == len(in) || i == len(in)-1 && in[i] == '\n'
+	case beginLine:
+		ok = i == 0 || in[i-1] == '\n'
+	case endLine:
+		ok = i == len(in) || in[i] == '\n'
+	case wordBoundary, noWordBoundary:
+		before := i > 0 && isWordByte(in[i-1])
+		after := i < len(in) && isWordByte(in[i])
+		ok = (before != after) == (n.kind == wordBoundary)
+	}
+	return ok && k(i)
+}
+
+// isWordByte reports whether c is an ASCII word character, which \b and \B
+// are defined by (like in Go regexps).
+func isWordByte(c byte) bool {
+	return c == '_' || isAlnum(c)
+}
+
+// concat matches a sequence of nodes.
+type concat []node
+
+func (n concat) match(m *machine, i int, k func(int) bool) bool {
+	return n.matchFrom(m, 0, i, k)
+}
+
+func (n concat) matchFrom(m *machine, idx, i int, k func(int) bool) bool {
+	if idx == len(n) {
+		return m.call(k, i)
+	}
+	return n[idx].match(m, i, func(j int) bool {
+		return n.matchFrom(m, idx+1, j, k)
+	})
+}
+
+// alternate matches one of its nodes, preferring the first.
+type alternate []node
+
+func (n alternate) match(m *machine, i int, k func(int) bool) bool {
+	for _, alt := range n {
+		if alt.match(m, i, k) {
+			return true
+		}
+		if m.timedOut {
+			return false
+		}
+	}
+	return false
+}
+
+// repeat matches sub repeated from min to max (or any, if max < 0) times.
+type repeat struct {
+	sub        node
+	min, max   int
+	greedy     bool // prefer more repetitions
+	possessive bool // don't backtrack (the repeat is in an atomic node)
+}
+
+func (n *repeat) match(m *machine, i int, k func(int) bool) bool {
+	if sub, ok := n.sub.(single); ok {
+		return n.matchSingle(m, sub, i, k)
+	}
+	return n.matchFrom(m, 0, i, k)
+}
+
+func (n *repeat) matchFrom(m *machine, count, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	more := func() bool {
+		if n.max >= 0 && count >= n.max {
+			return false
+		}
+		return n.sub.match(m, i, func(j int) bool {
+			if j == i && count >= n.min {
+				return false // an empty repetition would repeat forever
+			}
+			return m.call(func(j int) bool { return n.matchFrom(m, count+1, j, k) }, j)
+		})
+	}
+	if n.greedy {
+		return more() || count >= n.min && !m.timedOut && k(i)
+	}
+	return count >= n.min && k(i) || !m.timedOut && more()
+}
+
+// matchSingle matches repetitions of a node that matches a single character
+// iteratively, so that the stack doesn't grow with the length of the match.
+func (n *repeat) matchSingle(m *machine, sub single, i int, k func(int) bool) bool {
+	ends := []int{i}
+	next := func() bool {
+		if n.max >= 0 && len(ends)-1 >= n.max || !m.step() {
+			return false
+		}
+		end, ok := sub.matchOne(m, ends[len(ends)-1])
+		if ok {
+			ends = append(ends, end)
+		}
+		return ok
+	}
+	for len(ends)-1 < n.min {
+		if !next() {
+			return false
+		}
+	}
+	if n.greedy {
+		for next() {
+		}
+		for j := len(ends) - 1; j >= n.min; j-- {
+			if k(ends[j]) {
+				return true
+			}
+			if m.timedOut {
+				return false
+			}
+		}
+		return false
+	}
+	for {
+		if k(ends[len(ends)-1]) {
+			return true
+		}
+		if m.timedOut || !next() {
+			return false
+		}
+	}
+}
+
+// capture matches sub and records its match as the group n.
+type capture struct {
+	n   int
+	sub node
+}
+
+func (n *capture) match(m *machine, i int, k func(int) bool) bool {
+	start, end := m.caps[2*n.n], m.caps[2*n.n+1]
+	ok := n.sub.match(m, i, func(j int) bool {
+		prevStart, prevEnd := m.caps[2*n.n], m.caps[2*n.n+1]
+		m.caps[2*n.n], m.caps[2*n.n+1] = i, j
+		if m.call(k, j) {
+			return true
+		}
+		m.caps[2*n.n], m.caps[2*n.n+1] = prevStart, prevEnd
+		return false
+	})
+	if !ok {
+		m.caps[2*n.n], m.caps[2*n.n+1] = start, end
+	}
+	return ok
+}
+
+// atomic matches the first match of sub, without backtracking into it.
+type atomic struct {
+	sub node
+}
+
+func (n *atomic) match(m *machine, i int, k func(int) bool) bool {
+	caps := m.saveCaps()
+	end := -1
+	n.sub.match(m, i, func(j int) bool {
+		end = j
+		return true
+	})
+	if end >= 0 && !m.timedOut && m.call(k, end) {
+		return true
+	}
+	copy(m.caps, caps)
+	return false
+}
+
+// lookaround asserts that sub matches (or doesn't match, if negate) after
+// (or before, if behind) the position.
+type lookaround struct {
+	sub                node
+	negate             bool
+	behind             bool
+	minWidth, maxWidth int // the number of characters that sub matches, if behind
+}
+
+func (n *lookaround) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	caps := m.saveCaps()
+	matched := false
+	if n.behind {
+		// Try the starts from which sub can match up to i, nearest first.
+		start := i
+		for w := 0; w <= n.maxWidth && !matched; w++ {
+			if w > 0 {
+				if start == 0 {
+					break
+				}
+				_, size := utf8.DecodeLastRune(m.input[:start])
+				start -= size
+			}
+			if w >= n.minWidth {
+				matched = n.sub.match(m, start, func(j int) bool { return j == i })
+			}
+		}
+	} else {
+		matched = n.sub.match(m, i, func(int) bool { return true })
+	}
+	if m.timedOut {
+		return false
+	}
+	if n.negate {
+		copy(m.caps, caps) // groups in negative assertions are never set
+		return !matched && k(i)
+	}
+	if matched && k(i) {
+		return true
+	}
+	copy(m.caps, caps)
+	return false
+}
+
+// backreference matches the text that the group n matched.
+type backreference struct {
+	n    int
+	fold bool
+}
+
+func (n *backreference) match(m *machine, i int, k func(int) bool) bool {
+	if !m.step() {
+		return false
+	}
+	start, end := m.caps[2*n.n], m.caps[2*n.n+1]
+	if start < 0 || end < 0 {
+		return false // like in PCRE, groups that didn't match never match
+	}
+	group := m.input[start:end]
+	for len(group) > 0 {
+		if i >= len(m.input) {
+			return false
+		}
+		r, size := utf8.DecodeRune(group)
+		c, csize := utf8.DecodeRune(m.input[i:])
+		if c != r && !(n.fold && equalFold(c, r)) {
+			return false
+		}
+		group, i = group[size:], i+csize
+	}
+	return k(i)
+}
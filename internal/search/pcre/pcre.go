@@ -0,0 +1,820 @@
+// Package pcre implements regexps with the syntax of PCRE (Perl compatible
+// regular expressions), for the search patterns that Go's RE2-based regexp
+// package rejects (see the engine:pcre search filter): lookahead and
+// lookbehind assertions, backreferences, atomic groups and possessive
+// quantifiers. Character classes are parsed by regexp/syntax, so they have
+// the same syntax and meaning as in Go regexps.
+//
+// Unlike RE2, the engine matches by backtracking, which can take time
+// exponential in the length of the input, so matching is limited in time
+// (see Regexp.WithTimeout).
+package pcre
+
+import (
+	"bytes"
+	"fmt"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTimeout is the default limit on the time that matching an input
+// takes (see Regexp.WithTimeout).
+const DefaultTimeout = 100 * time.Millisecond
+
+// Regexp is a compiled PCRE regexp. It is safe for concurrent use.
+type Regexp struct {
+	expr    string
+	prog    node
+	numCaps int    // the number of capturing groups
+	prefix  []byte // a literal prefix of all matches, if any
+	timeout time.Duration
+}
+
+// Error describes why a pattern was rejected.
+type Error struct {
+	Msg  string // what is wrong
+	Expr string // the part of the pattern that is wrong
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("error parsing PCRE regexp: %s: `%s`", e.Msg, e.Expr)
+}
+
+// Compile parses a PCRE regexp. Its matches are limited to DefaultTimeout.
+func Compile(expr string) (*Regexp, error) {
+	p := &parser{expr: expr, s: expr, names: map[string]int{}}
+	prog, err := p.parseAlternate()
+	if err != nil {
+		return nil, err
+	}
+	if p.s != "" {
+		return nil, &Error{Msg: "unexpected )", Expr: expr}
+	}
+	for _, b := range p.backrefs {
+		if b.n > p.numCaps {
+			return nil, &Error{Msg: "invalid backreference to a group that doesn't exist", Expr: b.expr}
+		}
+	}
+	re := &Regexp{expr: expr, prog: prog, numCaps: p.numCaps, timeout: DefaultTimeout}
+	re.prefix = literalPrefix(prog)
+	return re, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed.
+func MustCompile(expr string) *Regexp {
+	re, err := Compile(expr)
+	if err != nil {
+		panic(`pcre: Compile(` + strconv.Quote(expr) + `): ` + err.Error())
+	}
+	return re
+}
+
+// RE2Error returns err, the error of Go's regexp package for expr, with an
+// explanation that the PCRE engine supports expr if it does.
+func RE2Error(expr string, err error) error {
+	if _, ok := err.(*syntax.Error); !ok {
+		return err
+	}
+	if _, pcreErr := Compile(expr); pcreErr != nil {
+		return err
+	}
+	return fmt.Errorf("%s (the default regexp engine, RE2, doesn't support lookaround, backreferences, atomic groups or possessive quantifiers: search with engine:pcre to use the PCRE-compatible engine)", err)
+}
+
+// String returns the source text of re.
+func (re *Regexp) String() string {
+	return re.expr
+}
+
+// WithTimeout returns a copy of re whose matches are limited to the time d,
+// or unlimited if d is 0.
+func (re *Regexp) WithTimeout(d time.Duration) *Regexp {
+	re2 := *re
+	re2.timeout = d
+	return &re2
+}
+
+// FindAllIndexTimeout returns the locations of up to n (or all, if n < 0)
+// successive non-overlapping matches of re in b, like the FindAllIndex
+// method of regexp.Regexp. If matching b takes longer than the timeout of
+// re, it returns the matches found before and timedOut is true.
+func (re *Regexp) FindAllIndexTimeout(b []byte, n int) (locs [][]int, timedOut bool) {
+	m := re.machine(b)
+	prevEnd := -1
+	for pos := 0; pos <= len(b) && (n < 0 || len(locs) < n); {
+		if len(re.prefix) > 0 {
+			i := bytes.Index(b[pos:], re.prefix)
+			if i < 0 {
+				break
+			}
+			pos += i
+		}
+		end, ok := m.matchAt(re.prog, pos)
+		if m.timedOut {
+			return locs, true
+		}
+		// Empty matches abutting a preceding match are ignored, like in
+		// Go regexps.
+		if ok && !(end == pos && pos == prevEnd) {
+			locs = append(locs, []int{pos, end})
+			prevEnd = end
+			if end > pos {
+				pos = end
+				continue
+			}
+		}
+		if pos == len(b) {
+			break
+		}
+		_, size := utf8.DecodeRune(b[pos:])
+		pos += size
+	}
+	return locs, false
+}
+
+// FindAllIndex is like FindAllIndexTimeout, without reporting whether
+// matching timed out.
+func (re *Regexp) FindAllIndex(b []byte, n int) [][]int {
+	locs, _ := re.FindAllIndexTimeout(b, n)
+	return locs
+}
+
+// Match reports whether b contains a match of re. It returns false if
+// matching times out before a match is found.
+func (re *Regexp) Match(b []byte) bool {
+	return len(re.FindAllIndex(b, 1)) > 0
+}
+
+// MatchString is like Match for a string.
+func (re *Regexp) MatchString(s string) bool {
+	return re.Match([]byte(s))
+}
+
+// flags are the matching modes that (?imsx) sets.
+type flags struct {
+	caseInsensitive bool // i: letters match both cases
+	multiLine       bool // m: ^ and $ match at the start and end of lines
+	dotNL           bool // s: . matches \n
+	extended        bool // x: whitespace and # comments in the pattern are ignored
+}
+
+type backref struct {
+	n    int
+	expr string
+}
+
+type parser struct {
+	expr     string // the whole pattern
+	s        string // the rest of the pattern to parse
+	flags    flags
+	numCaps  int
+	names    map[string]int
+	backrefs []backref
+}
+
+func (p *parser) errorf(expr, format string, args ...interface{}) error {
+	return &Error{Msg: fmt.Sprintf(format, args...), Expr: expr}
+}
+
+// parseAlternate parses alternatives up to the end of the enclosing group.
+func (p *parser) parseAlternate() (node, error) {
+	var alts alternate
+	for {
+		n, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, n)
+		if !strings.HasPrefix(p.s, "|") {
+			break
+		}
+		p.s = p.s[1:]
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return alts, nil
+}
+
+// parseConcat parses a sequence of repeated atoms up to a "|" or ")".
+func (p *parser) parseConcat() (node, error) {
+	var seq concat
+	for {
+		p.skipExtended()
+		if p.s == "" || p.s[0] == '|' || p.s[0] == ')' {
+			break
+		}
+		start := p.s
+		n, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			continue // a flag group or a comment
+		}
+		if q, ok := n.(concat); ok {
+			// A quantifier after \Q...\E applies to the last character.
+			seq, n = append(seq, q[:len(q)-1]...), q[len(q)-1]
+		}
+		n, err = p.parseRepeat(n, start)
+		if err != nil {
+			return nil, err
+		}
+		// Merge adjacent literals, so that they are matched at once.
+		if l, ok := n.(*literal); ok && len(seq) > 0 {
+			if prev, ok := seq[len(seq)-1].(*literal); ok && prev.fold == l.fold {
+				seq[len(seq)-1] = &literal{runes: append(prev.runes[:len(prev.runes):len(prev.runes)], l.runes...), fold: l.fold}
+				continue
+			}
+		}
+		seq = append(seq, n)
+	}
+	if len(seq) == 1 {
+		return seq[0], nil
+	}
+	return seq, nil
+}
+
+// skipExtended skips the whitespace and comments of the pattern in extended
+// mode.
+func (p *parser) skipExtended() {
+	for p.flags.extended && p.s != "" {
+		switch c := p.s[0]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			p.s = p.s[1:]
+		case c == '#':
+			if i := strings.IndexByte(p.s, '\n'); i >= 0 {
+				p.s = p.s[i+1:]
+			} else {
+				p.s = ""
+			}
+		default:
+			return
+		}
+	}
+}
+
+// parseRepeat parses the quantifier (if any) of n, which was parsed from the
+// start of atom.
+func (p *parser) parseRepeat(n node, atom string) (node, error) {
+	p.skipExtended()
+	min, max, ok := 0, 0, true
+	rest := p.s
+	switch {
+	case strings.HasPrefix(rest, "*"):
+		min, max, rest = 0, -1, rest[1:]
+	case strings.HasPrefix(rest, "+"):
+		min, max, rest = 1, -1, rest[1:]
+	case strings.HasPrefix(rest, "?"):
+		min, max, rest = 0, 1, rest[1:]
+	case strings.HasPrefix(rest, "{"):
+		min, max, rest, ok = parseCounts(rest)
+	default:
+		ok = false
+	}
+	if !ok {
+		return n, nil
+	}
+	expr := atom[:len(atom)-len(rest)]
+	if max >= 0 && min > max {
+		return nil, p.errorf(expr, "invalid repeat count")
+	}
+	if min > 1000 || max > 1000 {
+		return nil, p.errorf(expr, "invalid repeat count")
+	}
+	if _, ok := n.(*assertion); ok {
+		return nil, p.errorf(expr, "missing argument to repetition operator")
+	}
+	r := &repeat{sub: n, min: min, max: max, greedy: true}
+	switch {
+	case strings.HasPrefix(rest, "?"):
+		r.greedy, rest = false, rest[1:]
+	case strings.HasPrefix(rest, "+"):
+		r.possessive, rest = true, rest[1:]
+	}
+	p.s = rest
+	if strings.HasPrefix(p.s, "*") || strings.HasPrefix(p.s, "+") || strings.HasPrefix(p.s, "?") {
+		return nil, p.errorf(atom[:len(atom)-len(p.s)+1], "invalid nested repetition operator")
+	}
+	if r.possessive {
+		return &atomic{sub: r}, nil
+	}
+	return r, nil
+}
+
+// parseCounts parses a {n}, {n,} or {n,m} quantifier at the start of s. ok
+// is false if s doesn't start with one, in which case "{" is a literal.
+func parseCounts(s string) (min, max int, rest string, ok bool) {
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return 0, 0, s, false
+	}
+	counts := s[1:end]
+	lo, hi := counts, counts
+	if i := strings.IndexByte(counts, ','); i >= 0 {
+		lo, hi = counts[:i], counts[i+1:]
+	}
+	var err error
+	if min, err = strconv.Atoi(lo); err != nil || lo[0] == '+' || lo[0] == '-' {
+		return 0, 0, s, false
+	}
+	if hi == "" {
+		max = -1
+	} else if max, err = strconv.Atoi(hi); err != nil || hi[0] == '+' || hi[0] == '-' {
+		return 0, 0, s, false
+	}
+	return min, max, s[end+1:], true
+}
+
+// parseAtom parses a character, character class, escape sequence or group.
+// It returns a nil node for flag groups and comments.
+func (p *parser) parseAtom() (node, error) {
+	switch c := p.s[0]; c {
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return p.parseClass()
+	case '.':
+		p.s = p.s[1:]
+		return &anyChar{nl: p.flags.dotNL}, nil
+	case '^':
+		p.s = p.s[1:]
+		if p.flags.multiLine {
+			return &assertion{kind: beginLine}, nil
+		}
+		return &assertion{kind: beginText}, nil
+	case '$':
+		p.s = p.s[1:]
+		if p.flags.multiLine {
+			return &assertion{kind: endLine}, nil
+		}
+		return &assertion{kind: endTextOptionalNL}, nil
+	case '\\':
+		return p.parseEscape()
+	case '*', '+', '?':
+		return nil, p.errorf(p.s[:1], "missing argument to repetition operator")
+	case '{':
+		if _, _, _, ok := parseCounts(p.s); ok {
+			return nil, p.errorf(p.s[:strings.IndexByte(p.s, '}')+1], "missing argument to repetition operator")
+		}
+	}
+	r, size := utf8.DecodeRuneInString(p.s)
+	p.s = p.s[size:]
+	return p.literal(r), nil
+}
+
+func (p *parser) literal(r rune) node {
+	return &literal{runes: []rune{r}, fold: p.flags.caseInsensitive && hasFolds(r)}
+}
+
+// parseGroup parses a group starting with "(".
+func (p *parser) parseGroup() (node, error) {
+	start := p.s
+	var (
+		kind    = "capture"
+		name    string
+		special = []struct{ prefix, kind string }{
+			{"(?:", "group"},
+			{"(?=", "lookahead"},
+			{"(?!", "negative lookahead"},
+			{"(?<=", "lookbehind"},
+			{"(?<!", "negative lookbehind"},
+			{"(?>", "atomic"},
+			{"(?#", "comment"},
+		}
+	)
+	p.s = p.s[1:]
+	for _, s := range special {
+		if strings.HasPrefix(start, s.prefix) {
+			kind, p.s = s.kind, start[len(s.prefix):]
+			break
+		}
+	}
+	if kind == "comment" {
+		end := strings.IndexByte(p.s, ')')
+		if end < 0 {
+			return nil, p.errorf(start, "missing closing )")
+		}
+		p.s = p.s[end+1:]
+		return nil, nil
+	}
+	if kind == "capture" && strings.HasPrefix(p.s, "?") {
+		// Named groups: (?P<name>...), (?<name>...) and (?'name'...).
+		var open, close string
+		switch {
+		case strings.HasPrefix(p.s, "?P<"):
+			open, close = "?P<", ">"
+		case strings.HasPrefix(p.s, "?<"):
+			open, close = "?<", ">"
+		case strings.HasPrefix(p.s, "?'"):
+			open, close = "?'", "'"
+		}
+		if open != "" {
+			end := strings.Index(p.s[len(open):], close)
+			if end < 0 {
+				return nil, p.errorf(start, "invalid named capture")
+			}
+			name = p.s[len(open) : len(open)+end]
+			if !isGroupName(name) {
+				return nil, p.errorf(start[:1+len(open)+end+len(close)], "invalid named capture")
+			}
+			if _, ok := p.names[name]; ok {
+				return nil, p.errorf(start[:1+len(open)+end+len(close)], "duplicate capture group name")
+			}
+			p.s = p.s[len(open)+end+len(close):]
+		} else {
+			return p.parseFlags(start)
+		}
+	}
+
+	var n int
+	if kind == "capture" {
+		p.numCaps++
+		n = p.numCaps
+		if name != "" {
+			p.names[name] = n
+		}
+	}
+	// Flags set in the group only apply to the rest of the group.
+	saved := p.flags
+	sub, err := p.parseAlternate()
+	p.flags = saved
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(p.s, ")") {
+		return nil, p.errorf(start, "missing closing )")
+	}
+	p.s = p.s[1:]
+
+	switch kind {
+	case "capture":
+		return &capture{n: n, sub: sub}, nil
+	case "group":
+		return sub, nil
+	case "atomic":
+		return &atomic{sub: sub}, nil
+	case "lookahead", "negative lookahead":
+		return &lookaround{sub: sub, negate: kind == "negative lookahead"}, nil
+	}
+	min, max, ok := width(sub)
+	if !ok {
+		return nil, p.errorf(start[:len(start)-len(p.s)], "lookbehind assertions must have a bounded length")
+	}
+	return &lookaround{sub: sub, behind: true, negate: kind == "negative lookbehind", minWidth: min, maxWidth: max}, nil
+}
+
+// parseFlags parses a (?flags) or (?flags:...) group starting at start.
+func (p *parser) parseFlags(start string) (node, error) {
+	f := p.flags
+	on := true
+	for i := 1; i < len(p.s); i++ {
+		switch c := p.s[i]; c {
+		case 'i':
+			f.caseInsensitive = on
+		case 'm':
+			f.multiLine = on
+		case 's':
+			f.dotNL = on
+		case 'x':
+			f.extended = on
+		case '-':
+			if !on {
+				return nil, p.errorf(start[:i+2], "invalid or unsupported Perl syntax")
+			}
+			on = false
+		case ')':
+			// The flags apply to the rest of the enclosing group.
+			p.flags, p.s = f, p.s[i+1:]
+			return nil, nil
+		case ':':
+			saved := p.flags
+			p.flags, p.s = f, p.s[i+1:]
+			sub, err := p.parseAlternate()
+			p.flags = saved
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(p.s, ")") {
+				return nil, p.errorf(start, "missing closing )")
+			}
+			p.s = p.s[1:]
+			return sub, nil
+		default:
+			return nil, p.errorf(start[:i+2], "invalid or unsupported Perl syntax")
+		}
+	}
+	return nil, p.errorf(start, "missing closing )")
+}
+
+func isGroupName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseClass parses a character class starting with "[". The class is
+// parsed by regexp/syntax.
+func (p *parser) parseClass() (node, error) {
+	i := 1
+	if i < len(p.s) && p.s[i] == '^' {
+		i++
+	}
+	if i < len(p.s) && p.s[i] == ']' {
+		i++ // a leading ] is a literal
+	}
+	for ; i < len(p.s) && p.s[i] != ']'; i++ {
+		switch {
+		case p.s[i] == '\\':
+			i++
+		case strings.HasPrefix(p.s[i:], "[:"):
+			if end := strings.Index(p.s[i+2:], ":]"); end >= 0 {
+				i += 2 + end + 1
+			}
+		}
+	}
+	if i >= len(p.s) {
+		return nil, p.errorf(p.s, "missing closing ]")
+	}
+	expr := p.s[:i+1]
+	p.s = p.s[i+1:]
+	return p.class(expr)
+}
+
+// class returns the node matching the characters of expr, a character class
+// or an escape sequence for a class, like regexp/syntax.
+func (p *parser) class(expr string) (node, error) {
+	fl := syntax.Perl
+	if p.flags.caseInsensitive {
+		fl |= syntax.FoldCase
+	}
+	re, err := syntax.Parse(expr, fl)
+	if err != nil {
+		if serr, ok := err.(*syntax.Error); ok {
+			return nil, p.errorf(serr.Expr, "%s", serr.Code)
+		}
+		return nil, err
+	}
+	switch re.Op {
+	case syntax.OpCharClass:
+		return &charClass{ranges: re.Rune}, nil
+	case syntax.OpLiteral:
+		return &literal{runes: re.Rune, fold: re.Flags&syntax.FoldCase != 0}, nil
+	case syntax.OpAnyChar:
+		return &anyChar{nl: true}, nil
+	case syntax.OpAnyCharNotNL:
+		return &anyChar{}, nil
+	case syntax.OpNoMatch:
+		return &charClass{}, nil
+	}
+	return nil, p.errorf(expr, "invalid character class")
+}
+
+// parseEscape parses an escape sequence starting with "\".
+func (p *parser) parseEscape() (node, error) {
+	if len(p.s) < 2 {
+		return nil, p.errorf(p.s, "trailing backslash at end of expression")
+	}
+	start := p.s
+	c := p.s[1]
+	p.s = p.s[2:]
+	switch c {
+	case 'd', 'D', 'w', 'W', 's', 'S':
+		return p.class(start[:2])
+	case 'p', 'P':
+		end := 3
+		if strings.HasPrefix(p.s, "{") {
+			end = strings.IndexByte(start, '}') + 1
+			if end == 0 {
+				return nil, p.errorf(start, "invalid character class range")
+			}
+		}
+		if end > len(start) {
+			return nil, p.errorf(start, "invalid character class range")
+		}
+		p.s = start[end:]
+		return p.class(start[:end])
+	case 'b':
+		return &assertion{kind: wordBoundary}, nil
+	case 'B':
+		return &assertion{kind: noWordBoundary}, nil
+	case 'A':
+		return &assertion{kind: beginText}, nil
+	case 'z':
+		return &assertion{kind: endText}, nil
+	case 'Z':
+		return &assertion{kind: endTextOptionalNL}, nil
+	case 'Q':
+		// \Q...\E quotes a literal string.
+		end := strings.Index(p.s, `\E`)
+		lit := p.s
+		if end >= 0 {
+			lit, p.s = p.s[:end], p.s[end+2:]
+		} else {
+			p.s = ""
+		}
+		var seq concat
+		for _, r := range lit {
+			seq = append(seq, p.literal(r))
+		}
+		if len(seq) == 1 {
+			return seq[0], nil
+		}
+		return seq, nil
+	case 'E':
+		return nil, nil // a \E without a \Q is ignored
+	case 'n':
+		return p.literal('\n'), nil
+	case 't':
+		return p.literal('\t'), nil
+	case 'r':
+		return p.literal('\r'), nil
+	case 'f':
+		return p.literal('\f'), nil
+	case 'v':
+		return p.literal('\v'), nil
+	case 'a':
+		return p.literal('\a'), nil
+	case 'e':
+		return p.literal(0x1b), nil
+	case 'x':
+		return p.parseHex(start)
+	case 'k':
+		// \k<name>, \k{name} and \k'name' refer to named groups.
+		if p.s == "" {
+			return nil, p.errorf(start, "invalid backreference")
+		}
+		close := map[byte]string{'<': ">", '{': "}", '\'': "'"}[p.s[0]]
+		end := -1
+		if close != "" {
+			end = strings.Index(p.s[1:], close)
+		}
+		if end < 0 {
+			return nil, p.errorf(start, "invalid backreference")
+		}
+		name := p.s[1 : 1+end]
+		p.s = p.s[1+end+1:]
+		n, ok := p.names[name]
+		if !ok {
+			return nil, p.errorf(start[:len(start)-len(p.s)], "invalid backreference to a group that doesn't exist")
+		}
+		return &backreference{n: n, fold: p.flags.caseInsensitive}, nil
+	case 'g':
+		// \gN and \g{N} refer to numbered groups.
+		digits := p.s
+		if strings.HasPrefix(digits, "{") {
+			end := strings.IndexByte(digits, '}')
+			if end < 0 {
+				return nil, p.errorf(start, "invalid backreference")
+			}
+			digits, p.s = digits[1:end], digits[end+1:]
+		} else {
+			i := 0
+			for i < len(digits) && '0' <= digits[i] && digits[i] <= '9' {
+				i++
+			}
+			digits, p.s = digits[:i], digits[i:]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n <= 0 {
+			return nil, p.errorf(start[:len(start)-len(p.s)], "invalid backreference")
+		}
+		return p.backreference(n, start[:len(start)-len(p.s)]), nil
+	case '0':
+		// \0 and \0nn are octal escapes.
+		i := 0
+		for i < 2 && i < len(p.s) && '0' <= p.s[i] && p.s[i] <= '7' {
+			i++
+		}
+		v, _ := strconv.ParseUint("0"+p.s[:i], 8, 32)
+		p.s = p.s[i:]
+		return p.literal(rune(v)), nil
+	}
+	if '1' <= c && c <= '9' {
+		i := 0
+		for i < len(p.s) && '0' <= p.s[i] && p.s[i] <= '9' {
+			i++
+		}
+		n, _ := strconv.Atoi(start[1 : 2+i])
+		p.s = p.s[i:]
+		return p.backreference(n, start[:2+i]), nil
+	}
+	if c < utf8.RuneSelf && !isAlnum(c) {
+		return p.literal(rune(c)), nil // an escaped punctuation character
+	}
+	return nil, p.errorf(start[:2], "invalid escape sequence")
+}
+
+func (p *parser) backreference(n int, expr string) node {
+	// Groups are numbered in order, so whether a group n exists is only
+	// known once the whole pattern is parsed.
+	p.backrefs = append(p.backrefs, backref{n: n, expr: expr})
+	return &backreference{n: n, fold: p.flags.caseInsensitive}
+}
+
+// parseHex parses a \xHH or \x{H...} escape sequence, of which p.s is the
+// rest after the "\x" at the start of start.
+func (p *parser) parseHex(start string) (node, error) {
+	var digits string
+	if strings.HasPrefix(p.s, "{") {
+		end := strings.IndexByte(p.s, '}')
+		if end < 0 {
+			return nil, p.errorf(start, "invalid escape sequence")
+		}
+		digits, p.s = p.s[1:end], p.s[end+1:]
+	} else {
+		if len(p.s) < 2 {
+			return nil, p.errorf(start, "invalid escape sequence")
+		}
+		digits, p.s = p.s[:2], p.s[2:]
+	}
+	v, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || v > unicode.MaxRune {
+		return nil, p.errorf(start[:len(start)-len(p.s)], "invalid escape sequence")
+	}
+	return p.literal(rune(v)), nil
+}
+
+func isAlnum(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+// hasFolds reports whether r has other cases.
+func hasFolds(r rune) bool {
+	return unicode.SimpleFold(r) != r
+}
+
+// literalPrefix returns the bytes that all matches of n start with, if they
+// are matched case-sensitively.
+func literalPrefix(n node) []byte {
+	switch n := n.(type) {
+	case *literal:
+		if !n.fold {
+			return []byte(string(n.runes))
+		}
+	case concat:
+		if len(n) > 0 {
+			return literalPrefix(n[0])
+		}
+	case *capture:
+		return literalPrefix(n.sub)
+	}
+	return nil
+}
+
+// width returns the minimum and maximum number of characters that n
+// matches. ok is false if the maximum is unbounded.
+func width(n node) (min, max int, ok bool) {
+	switch n := n.(type) {
+	case *literal:
+		return len(n.runes), len(n.runes), true
+	case *charClass, *anyChar:
+		return 1, 1, true
+	case *assertion, *lookaround:
+		return 0, 0, true
+	case *capture:
+		return width(n.sub)
+	case *atomic:
+		return width(n.sub)
+	case concat:
+		for _, sub := range n {
+			subMin, subMax, subOK := width(sub)
+			if !subOK {
+				return 0, 0, false
+			}
+			min, max = min+subMin, max+subMax
+		}
+		return min, max, true
+	case alternate:
+		for i, sub := range n {
+			subMin, subMax, subOK := width(sub)
+			if !subOK {
+				return 0, 0, false
+			}
+			if i == 0 || subMin < min {
+				min = subMin
+			}
+			if subMax > max {
+				max = subMax
+			}
+		}
+		return min, max, true
+	case *repeat:
+		subMin, subMax, subOK := width(n.sub)
+		if !subOK || n.max < 0 && subMax > 0 {
+			return 0, 0, false
+		}
+		return n.min * subMin, n.max * subMax, true
+	}
+	return 0, 0, false // backreferences
+}
@@ -0,0 +1,179 @@
+package pcre
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindAllIndex(t *testing.T) {
+	tests := []struct {
+		expr  string
+		input string
+		want  []string
+	}{
+		// Lookaround.
+		{`foo(?=bar)`, "foobar foobaz", []string{"foo"}},
+		{`foo(?!bar)`, "foobar foobaz", []string{"foo"}},
+		{`(?<=\$)\d+`, "$10 20 $30", []string{"10", "30"}},
+		{`(?<!\$)\b\d+`, "$10 20 $30", []string{"20"}},
+		{`(?<=ab|c)x`, "abx cx bx", []string{"x", "x"}},
+		{`^(?=.*\d)(?=.*[a-z]).{6,}$`, "abc123", []string{"abc123"}},
+		{`^(?=.*\d)(?=.*[a-z]).{6,}$`, "abcdef", nil},
+
+		// Backreferences.
+		{`(\w)\1`, "hello aa bcd", []string{"ll", "aa"}},
+		{`(?P<q>['"]).*?\k<q>`, `x = "a'b" + 'c'`, []string{`"a'b"`, `'c'`}},
+		{`(?i)(a)\1`, "aA", []string{"aA"}},
+		{`(a)|\1b`, "b", nil},
+
+		// Atomic groups and possessive quantifiers.
+		{`(?>a+)b`, "aaab", []string{"aaab"}},
+		{`(?>a+)a`, "aaaa", nil},
+		{`a++a`, "aaaa", nil},
+		{`"[^"]*+"`, `"x" "y`, []string{`"x"`}},
+
+		// Quantifiers.
+		{`a{2,3}`, "aaaaaaa", []string{"aaa", "aaa"}},
+		{`a{2}`, "aaaaa", []string{"aa", "aa"}},
+		{`a+?`, "aaa", []string{"a", "a", "a"}},
+		{`<.*?>`, "<a><b>", []string{"<a>", "<b>"}},
+		{`(ab)*c`, "ababc c", []string{"ababc", "c"}},
+		{`(a|ab)(c|bcd)(d*)`, "abcd", []string{"abcd"}},
+		{`x{`, "x{", []string{"x{"}},
+
+		// Flags, anchors and escapes.
+		{`(?i)hello`, "HeLLo", []string{"HeLLo"}},
+		{`(?i:h)ello`, "Hello HELLO", []string{"Hello"}},
+		{`(?m)^\w+$`, "ab\ncd", []string{"ab", "cd"}},
+		{`^\w+$`, "ab\ncd", nil},
+		{`\w+\Z`, "ab cd\n", []string{"cd"}},
+		{`(?s)a.b`, "a\nb", []string{"a\nb"}},
+		{`a.b`, "a\nb", nil},
+		{`(?x) a b # comment`, "ab", []string{"ab"}},
+		{`\Qa.b\E+`, "a.bb axb", []string{"a.bb"}},
+		{`\x41\x{42}`, "AB", []string{"AB"}},
+		{`a(?#comment)b`, "ab", []string{"ab"}},
+		{`[[:upper:]]\p{Greek}`, "Aα", []string{"Aα"}},
+		{`(?i)[a-c]+`, "xAbCx", []string{"AbC"}},
+		{`\bfoo\b`, "foo foobar", []string{"foo"}},
+
+		// Empty matches.
+		{`a*`, "baaab", []string{"", "aaa", ""}},
+		{`(?=a)`, "aa", []string{"", ""}},
+	}
+	for _, test := range tests {
+		re, err := Compile(test.expr)
+		if err != nil {
+			t.Errorf("%s: %s", test.expr, err)
+			continue
+		}
+		var got []string
+		for _, loc := range re.FindAllIndex([]byte(test.input), -1) {
+			got = append(got, test.input[loc[0]:loc[1]])
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s in %q: got %q, want %q", test.expr, test.input, got, test.want)
+		}
+	}
+}
+
+// TestFindAllIndex_RE2 tests that patterns that RE2 supports match the same
+// as in Go regexps.
+func TestFindAllIndex_RE2(t *testing.T) {
+	exprs := []string{
+		`a`, `a*`, `a+?b`, `(a|b)*c`, `[^a-z]+`, `\d{2,}`, `(?i)foo|BAR`, `(?m)^x|y$`,
+		`\bx\w*`, `.`, `(?s).+`, `(a*)*`, `(a|ab)(c|bcd)(d*)`, `\S+\s*`, `héllo|ö+`,
+	}
+	inputs := []string{
+		"", "a", "aab aaab c", "abcd abc", "Foo bar BAR\nxy\nyx", "12 345 6", "héllo öö\n",
+	}
+	for _, expr := range exprs {
+		want := regexp.MustCompile(expr)
+		got := MustCompile(expr)
+		for _, input := range inputs {
+			if w, g := want.FindAllIndex([]byte(input), -1), got.FindAllIndex([]byte(input), -1); !reflect.DeepEqual(g, w) {
+				t.Errorf("%s in %q: got %v, want %v", expr, input, g, w)
+			}
+		}
+	}
+}
+
+func TestFindAllIndex_limit(t *testing.T) {
+	re := MustCompile(`a`)
+	if got, want := re.FindAllIndex([]byte("aaa"), 2), [][]int{{0, 1}, {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindAllIndexTimeout(t *testing.T) {
+	// Catastrophic backtracking.
+	re := MustCompile(`^(\w+\s?)*$`).WithTimeout(10 * time.Millisecond)
+	input := []byte("ok\n" + strings.Repeat("a", 40) + "!")
+
+	start := time.Now()
+	_, timedOut := re.FindAllIndexTimeout(input, -1)
+	if !timedOut {
+		t.Error("got timedOut false, want true")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("matching took %s, want it to time out", elapsed)
+	}
+	if re.Match(input) {
+		t.Error("got Match true, want false when timing out")
+	}
+
+	// Deep recursion is aborted too.
+	re = MustCompile(`(?:a|b)*c`).WithTimeout(0)
+	if _, timedOut := re.FindAllIndexTimeout([]byte(strings.Repeat("a", 100000)+"c"), 1); !timedOut {
+		t.Error("got timedOut false for a deep match, want true")
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`(a`, "missing closing )"},
+		{`a)`, "unexpected )"},
+		{`[a`, "missing closing ]"},
+		{`*a`, "missing argument to repetition operator"},
+		{`a**`, "invalid nested repetition operator"},
+		{`a{3,2}`, "invalid repeat count"},
+		{`(?<=a+)b`, "lookbehind assertions must have a bounded length"},
+		{`(?<=(a)\1)b`, "lookbehind assertions must have a bounded length"},
+		{`(a)\2`, "invalid backreference to a group that doesn't exist"},
+		{`\k<x>`, "invalid backreference to a group that doesn't exist"},
+		{`(?<x>a)(?<x>b)`, "duplicate capture group name"},
+		{`(?z)`, "invalid or unsupported Perl syntax"},
+		{`\q`, "invalid escape sequence"},
+		{`[z-a]`, "invalid character class range"},
+		{`a\`, "trailing backslash at end of expression"},
+	}
+	for _, test := range tests {
+		_, err := Compile(test.expr)
+		if err == nil {
+			t.Errorf("%s: got no error, want %q", test.expr, test.want)
+			continue
+		}
+		if e, ok := err.(*Error); !ok || e.Msg != test.want {
+			t.Errorf("%s: got error %q, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestRE2Error(t *testing.T) {
+	_, err := regexp.Compile(`foo(?=bar)`)
+	if got := RE2Error(`foo(?=bar)`, err).Error(); !strings.Contains(got, "engine:pcre") {
+		t.Errorf("got %q, want it to suggest engine:pcre", got)
+	}
+
+	// Patterns that neither engine supports have the RE2 error.
+	_, err = regexp.Compile(`(a`)
+	if got := RE2Error(`(a`, err); got != err {
+		t.Errorf("got %q, want %q", got, err)
+	}
+}
@@ -0,0 +1,34 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
+)
+
+// The regexp engines of search patterns, the values of the engine: field.
+const (
+	EngineRE2  = "re2"  // Go's regexp package, which matches in linear time
+	EnginePCRE = "pcre" // the PCRE-compatible engine of package pcre, which supports lookarounds and backreferences
+)
+
+// ParseEngine parses the value of the engine: field.
+func ParseEngine(s string) (string, error) {
+	switch engine := strings.ToLower(s); engine {
+	case EngineRE2, EnginePCRE:
+		return engine, nil
+	}
+	return "", fmt.Errorf("invalid engine:%s, expected re2 or pcre", s)
+}
+
+// usesPCRE reports whether the query selects the PCRE-compatible engine, so
+// that its patterns must be typechecked as PCRE regexps.
+func usesPCRE(parseTree syntax.ParseTree) bool {
+	for _, expr := range parseTree {
+		if expr.Field == FieldEngine && !expr.Not && strings.EqualFold(strings.Trim(expr.Value, `"'`), EnginePCRE) {
+			return true
+		}
+	}
+	return false
+}
@@ -32,6 +32,26 @@ var allFields = map[string]struct{}{
 	FieldIndex:              empty,
 	FieldCount:              empty,
 	FieldStable:             empty,
+	FieldSubmodules:         empty,
+	FieldHexPreview:         empty,
+	FieldDeterministic:      empty,
+	FieldIdentifier:         empty,
+	FieldWordChars:          empty,
+	FieldWithin:             empty,
+	FieldGoCall:             empty,
+	FieldGoSelector:         empty,
+	FieldModified:           empty,
+	FieldFirstMatch:         empty,
+	FieldEngine:             empty,
+	FieldNormalize:          empty,
+	FieldCaseFold:           empty,
+	FieldDefaultExclusions:  empty,
+	FieldOwner:              empty,
+	FieldLicense:            empty,
+	FieldRuleID:             empty,
+	FieldSeverity:           empty,
+	FieldRank:               empty,
+	FieldHistory:            empty,
 	FieldMax:                empty,
 	FieldTimeout:            empty,
 	FieldReplace:            empty,
@@ -17,3 +17,19 @@ func Fuzz(data []byte) int {
 	}
 	return 1
 }
+
+// FuzzProcess is an entry point for fuzzing the parsing, typechecking and
+// validation of ordinary (non-and/or) queries with go-fuzz. Run
+// go-fuzz-build -func FuzzProcess and then go-fuzz in this directory.
+func FuzzProcess(data []byte) int {
+	ok := false
+	for _, searchType := range []SearchType{SearchTypeRegex, SearchTypeLiteral, SearchTypeStructural} {
+		if _, err := Process(string(data), searchType); err == nil {
+			ok = true
+		}
+	}
+	if !ok {
+		return 0
+	}
+	return 1
+}
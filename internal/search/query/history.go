@@ -0,0 +1,24 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseHistoryRange parses the value of the history: field, a revision range
+// of the form "since..head". The head may be omitted (as in "v1.0.."), in
+// which case the searched revisions are used.
+func ParseHistoryRange(s string) (since, head string, err error) {
+	i := strings.Index(s, "..")
+	if i < 0 || strings.Contains(s, "...") {
+		return "", "", fmt.Errorf("invalid history range %q, expected a range of the form since..head (e.g. v1.0..master or v1.0..)", s)
+	}
+	since, head = s[:i], s[i+len(".."):]
+	if since == "" {
+		return "", "", fmt.Errorf("invalid history range %q, the revision to search the history since is missing", s)
+	}
+	if strings.HasPrefix(since, "-") || strings.HasPrefix(head, "-") {
+		return "", "", fmt.Errorf("invalid history range %q, revisions must not begin with '-'", s)
+	}
+	return since, head, nil
+}
@@ -0,0 +1,19 @@
+package query
+
+import "fmt"
+
+// ParseIdentifierMode parses the value of the identifier: field. "yes" means
+// that the pattern only matches whole identifiers, "subtokens" that it also
+// matches sub-tokens of identifiers (such as "Handler" in "requestHandler"),
+// and "no" (the default) that it matches anywhere.
+func ParseIdentifierMode(s string) (identifier, subTokens bool, err error) {
+	switch s {
+	case "y", "Y", "yes", "YES", "Yes", "true":
+		return true, false, nil
+	case "subtokens", "SUBTOKENS", "subTokens":
+		return true, true, nil
+	case "n", "N", "no", "NO", "No", "false", "":
+		return false, false, nil
+	}
+	return false, false, fmt.Errorf("invalid identifier:%s, expected yes, no or subtokens", s)
+}
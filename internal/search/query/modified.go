@@ -0,0 +1,32 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseModified parses the value of the modified: field, either a duration
+// of the form "<number>h", "<number>d" or "<number>w" (e.g. "14d"), which
+// means the files that were changed in that many hours, days or weeks, or a
+// revision (e.g. "v1.0"), which means the files that were changed by the
+// commits since the revision. Exactly one of within and revision is set.
+func ParseModified(s string) (within time.Duration, revision string, err error) {
+	if s == "" {
+		return 0, "", fmt.Errorf("invalid modified:, expected a duration (e.g. 14d) or a revision (e.g. v1.0)")
+	}
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil {
+			if n <= 0 {
+				return 0, "", fmt.Errorf("invalid modified:%s, the duration must be positive", s)
+			}
+			return time.Duration(n) * unit, "", nil
+		}
+	}
+	if strings.HasPrefix(s, "-") || strings.ContainsAny(s, " \t\n") {
+		return 0, "", fmt.Errorf("invalid modified:%s, expected a duration (e.g. 14d) or a revision (e.g. v1.0)", s)
+	}
+	return 0, s, nil
+}
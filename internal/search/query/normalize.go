@@ -0,0 +1,16 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseNormalization parses the value of the normalize: field, the Unicode
+// normalization form "nfc" (composed) or "nfd" (decomposed).
+func ParseNormalization(s string) (string, error) {
+	switch form := strings.ToLower(s); form {
+	case "nfc", "nfd":
+		return form, nil
+	}
+	return "", fmt.Errorf("invalid normalize:%s, expected nfc or nfd", s)
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The ranking modes of search results, the values of the rank: field.
+const (
+	RankDefault  = "default"  // results are ordered by repository and path
+	RankExamples = "examples" // file matches that are good usage examples come first
+)
+
+// ParseRank parses the value of the rank: field.
+func ParseRank(s string) (string, error) {
+	switch rank := strings.ToLower(s); rank {
+	case RankDefault, RankExamples:
+		return rank, nil
+	}
+	return "", fmt.Errorf("invalid rank:%s, expected default or examples", s)
+}
+
+var (
+	// qualifiedSymbol matches qualified names, such as http.NewRequest,
+	// os.path.join, std::move, $this->render and Array#map.
+	qualifiedSymbol = regexp.MustCompile(`^[A-Za-z_$][\w$]*(?:(?:\.|::|->|#)[A-Za-z_$][\w$]*)+$`)
+
+	// compoundSymbol matches identifiers of several words, such as
+	// NewRequest, getElementById and json_decode, unlike plain words.
+	compoundSymbol = regexp.MustCompile(`^_*[A-Za-z][A-Za-z0-9]*(?:[a-z0-9][A-Z]|_[A-Za-z0-9])[\w$]*$`)
+)
+
+// LooksLikeSymbol reports whether a search pattern looks like the name of an
+// API symbol, optionally followed by "(" or "()", so that its most useful
+// results are usage examples. Escaped dots and parentheses of regexp
+// patterns are unescaped.
+func LooksLikeSymbol(pattern string) bool {
+	pattern = strings.NewReplacer(`\.`, ".", `\(`, "(", `\)`, ")").Replace(pattern)
+	pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "()"), "(")
+	return qualifiedSymbol.MatchString(pattern) || compoundSymbol.MatchString(pattern)
+}
@@ -0,0 +1,27 @@
+package query
+
+import "testing"
+
+func TestLooksLikeSymbol(t *testing.T) {
+	tests := map[string]bool{
+		"http.NewRequest":   true,
+		`http\.NewRequest`:  true,
+		"os.path.join(":     true,
+		"std::move":         true,
+		"$this->render":     true,
+		"Array#map":         true,
+		"NewRequest":        true,
+		"getElementById()":  true,
+		"json_decode":       true,
+		"request":           false,
+		"TODO":              false,
+		"http.NewRequest x": false,
+		"a.*b":              false,
+		"":                  false,
+	}
+	for pattern, want := range tests {
+		if got := LooksLikeSymbol(pattern); got != want {
+			t.Errorf("%q: got %v, want %v", pattern, got, want)
+		}
+	}
+}
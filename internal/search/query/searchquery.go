@@ -35,13 +35,33 @@ const (
 	FieldMessage   = "message"
 
 	// Temporary experimental fields:
-	FieldIndex     = "index"
-	FieldCount     = "count"  // Searches that specify `count:` will fetch at least that number of results, or the full result set
-	FieldStable    = "stable" // Forces search to return a stable result ordering (currently limited to file content matches).
-	FieldMax       = "max"    // Deprecated alias for count
-	FieldTimeout   = "timeout"
-	FieldReplace   = "replace"
-	FieldCombyRule = "rule"
+	FieldIndex             = "index"
+	FieldCount             = "count"             // Searches that specify `count:` will fetch at least that number of results, or the full result set
+	FieldStable            = "stable"            // Forces search to return a stable result ordering (currently limited to file content matches).
+	FieldSubmodules        = "submodules"        // Also searches the submodules of searched repositories, at their pinned commits.
+	FieldHistory           = "history"           // Searches every commit in a revision range instead of the searched revisions.
+	FieldHexPreview        = "hexpreview"        // Returns hex previews of matches in binary files and files that are not valid UTF-8, instead of skipping them.
+	FieldDeterministic     = "deterministic"     // Returns the same results in the same order for repeated searches of unchanged repositories.
+	FieldIdentifier        = "identifier"        // Only matches whole identifiers (or their sub-tokens) instead of anywhere.
+	FieldWordChars         = "wordchars"         // Characters besides letters, digits and "_" that are part of identifiers, in all languages.
+	FieldWithin            = "within"            // Only matches in comments, string literals or code (or, negated, not in them).
+	FieldGoCall            = "gocall"            // Matches the calls of a Go function or method, found by parsing Go files.
+	FieldGoSelector        = "goselector"        // Matches the uses of a Go selector, found by parsing Go files.
+	FieldFirstMatch        = "firstmatch"        // Stops searching each repository after its first match, and returns the matching repositories.
+	FieldModified          = "modified"          // Only searches the files changed recently (e.g. modified:14d) or since a revision (e.g. modified:v1.0).
+	FieldEngine            = "engine"            // The regexp engine of the search pattern: re2 (the default) or pcre, which supports lookarounds and backreferences.
+	FieldNormalize         = "normalize"         // Matches the Unicode normalization (nfc or nfd) of the pattern and contents, so that composed and decomposed accents match.
+	FieldCaseFold          = "casefold"          // Folds the case of all Unicode letters in case-insensitive searches, instead of only ASCII letters.
+	FieldDefaultExclusions = "defaultexclusions" // Whether the paths that are excluded by default, such as build output, are excluded (the default).
+	FieldOwner             = "owner"             // Only matches files with the owner (or, negated, without it) in the ownership file of the repository, such as CODEOWNERS.
+	FieldLicense           = "license"           // Only matches files whose license (from an SPDX-License-Identifier header or a license file such as LICENSE) includes the SPDX license (or, negated, doesn't).
+	FieldRuleID            = "ruleid"            // Only matches lines that hit the rule of the search.rules site setting (or, negated, don't).
+	FieldSeverity          = "severity"          // Only matches lines that hit a rule of the search.rules site setting with at least the severity.
+	FieldRank              = "rank"              // The ranking of results: default, or examples to rank good usage examples of an API symbol first (the default for patterns that look like API symbols).
+	FieldMax               = "max"               // Deprecated alias for count
+	FieldTimeout           = "timeout"
+	FieldReplace           = "replace"
+	FieldCombyRule         = "rule"
 )
 
 var (
@@ -73,13 +93,33 @@ var (
 			FieldMessage:   regexpNegatableFieldType,
 
 			// Experimental fields:
-			FieldIndex:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldCount:     {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldStable:    {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
-			FieldMax:       {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldTimeout:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldReplace:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldCombyRule: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldIndex:             {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldCount:             {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldStable:            {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldSubmodules:        {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldHexPreview:        {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldDeterministic:     {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldIdentifier:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldWordChars:         {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldWithin:            {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldGoCall:            {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldGoSelector:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldFirstMatch:        {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldModified:          {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldEngine:            {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldNormalize:         {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldCaseFold:          {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldDefaultExclusions: {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldOwner:             {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldLicense:           {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldRuleID:            {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldSeverity:          {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldRank:              {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldHistory:           {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldMax:               {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldTimeout:           {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldReplace:           {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldCombyRule:         {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 		},
 		FieldAliases: map[string]string{
 			"r":        FieldRepo,
@@ -93,6 +133,17 @@ var (
 			"msg":      FieldMessage,
 		},
 	}
+
+	// pcreConf is the typechecker config of queries with engine:pcre, whose
+	// search patterns are regexps for the PCRE-compatible engine.
+	pcreConf = func() types.Config {
+		fieldTypes := make(map[string]types.FieldType, len(conf.FieldTypes))
+		for field, typ := range conf.FieldTypes {
+			fieldTypes[field] = typ
+		}
+		fieldTypes[FieldDefault] = types.FieldType{Literal: types.PCREType, Quoted: types.StringType}
+		return types.Config{FieldTypes: fieldTypes, FieldAliases: conf.FieldAliases}
+	}()
 )
 
 // A Query is the typechecked representation of a search query.
@@ -116,11 +167,15 @@ func Parse(input string) (syntax.ParseTree, error) {
 }
 
 func Check(parseTree syntax.ParseTree) (QueryInfo, error) {
-	checkedFields, err := conf.Check(parseTree)
+	c := &conf
+	if usesPCRE(parseTree) {
+		c = &pcreConf
+	}
+	checkedFields, err := c.Check(parseTree)
 	if err != nil {
 		return nil, err
 	}
-	query := &Query{conf: &conf, Fields: *checkedFields}
+	query := &Query{conf: c, Fields: *checkedFields}
 	return &OrdinaryQuery{Query: query}, nil
 }
 
@@ -174,6 +229,16 @@ func Validate(q QueryInfo, searchType SearchType) error {
 			return errors.New(`the parameter "type:" is not valid for structural search, search is always performed on file content`)
 		}
 	}
+	if engine, _ := q.StringValue(FieldEngine); engine != "" {
+		if _, err := ParseEngine(engine); err != nil {
+			return err
+		}
+	}
+	if history, _ := q.StringValue(FieldHistory); history != "" {
+		if _, _, err := ParseHistoryRange(history); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
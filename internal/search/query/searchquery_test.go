@@ -2,6 +2,7 @@ package query
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -163,6 +164,24 @@ func TestQuery_Validate(t *testing.T) {
 			SearchType: SearchTypeStructural,
 			Want:       "",
 		},
+		{
+			Name:       `History requires a revision range`,
+			Query:      `history:v1.0 foo`,
+			SearchType: SearchTypeLiteral,
+			Want:       `invalid history range "v1.0", expected a range of the form since..head (e.g. v1.0..master or v1.0..)`,
+		},
+		{
+			Name:       `History validates with an open revision range`,
+			Query:      `history:v1.0.. foo`,
+			SearchType: SearchTypeLiteral,
+			Want:       "",
+		},
+		{
+			Name:       `Engine must be re2 or pcre`,
+			Query:      `engine:perl foo`,
+			SearchType: SearchTypeRegex,
+			Want:       `invalid engine:perl, expected re2 or pcre`,
+		},
 	}
 	for _, tt := range cases {
 		t.Run(tt.Name, func(t *testing.T) {
@@ -193,6 +212,27 @@ func TestQuery_CaseInsensitiveFields(t *testing.T) {
 	}
 }
 
+func TestQuery_EnginePCRE(t *testing.T) {
+	_, err := ParseAndCheck(`foo(?=bar)`)
+	if err == nil || !strings.Contains(err.Error(), "engine:pcre") {
+		t.Errorf("got error %v, want it to suggest engine:pcre", err)
+	}
+
+	query, err := ParseAndCheck(`foo(?=bar) engine:pcre`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := query.Values(FieldDefault)
+	if len(values) != 1 || values[0].PCRE == nil || values[0].ToString() != `foo(?=bar)` {
+		t.Errorf("got pattern values %v, want the PCRE regexp foo(?=bar)", values)
+	}
+
+	_, err = ParseAndCheck(`foo(?<=a+) engine:pcre`)
+	if want := "lookbehind assertions must have a bounded length"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
+
 func checkPanic(t *testing.T, msg string, f func()) {
 	t.Helper()
 	defer func() {
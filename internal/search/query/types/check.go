@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/sourcegraph/sourcegraph/internal/lazyregexp"
+	"github.com/sourcegraph/sourcegraph/internal/search/pcre"
 	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
 )
 
@@ -95,6 +96,11 @@ func (c *Config) checkExpr(expr *syntax.Expr) (field string, fieldType FieldType
 	switch expr.ValueType {
 	case syntax.TokenLiteral:
 		if err := setValue(value, expr.Value, fieldType.Literal); err != nil {
+			if resolvedField == "" && fieldType.Literal == RegexpType {
+				// Explain patterns that only the PCRE-compatible engine
+				// supports, such as lookarounds.
+				err = pcre.RE2Error(expr.Value, err)
+			}
 			return "", FieldType{}, nil, &TypeError{Pos: expr.Pos, Err: err}
 		}
 
@@ -108,7 +114,11 @@ func (c *Config) checkExpr(expr *syntax.Expr) (field string, fieldType FieldType
 		}
 
 	case syntax.TokenPattern:
-		if err := setValue(value, expr.Value, RegexpType); err != nil {
+		patternType := RegexpType
+		if fieldType.Literal == PCREType {
+			patternType = PCREType
+		}
+		if err := setValue(value, expr.Value, patternType); err != nil {
 			return "", FieldType{}, nil, &TypeError{Pos: expr.Pos, Err: err}
 		}
 	}
@@ -127,6 +137,13 @@ func setValue(dst *Value, valueString string, valueType ValueType) error {
 			return err
 		}
 		dst.Regexp = p
+	case PCREType:
+		valueString = autoFix(valueString)
+		p, err := pcre.Compile(valueString)
+		if err != nil {
+			return err
+		}
+		dst.PCRE = p
 	case BoolType:
 		b, err := parseBool(valueString)
 		if err != nil {
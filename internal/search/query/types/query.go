@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sourcegraph/sourcegraph/internal/search/pcre"
 	"github.com/sourcegraph/sourcegraph/internal/search/query/syntax"
 )
 
@@ -22,6 +23,8 @@ func (f *Fields) String() string {
 				fields = append(fields, fmt.Sprintf("%s:%q", key, s))
 			case *regexp.Regexp:
 				fields = append(fields, fmt.Sprintf("%s~%q", key, s))
+			case *pcre.Regexp:
+				fields = append(fields, fmt.Sprintf("%s~%q", key, s))
 			case bool:
 				fields = append(fields, fmt.Sprintf("%s:%v", key, s))
 			default:
@@ -41,6 +44,7 @@ const (
 	StringType ValueType = 1 << iota
 	RegexpType
 	BoolType
+	PCREType // a regexp for the PCRE-compatible engine (see package pcre)
 )
 
 // A Value is a field value in a query.
@@ -50,6 +54,7 @@ type Value struct {
 	String *string        // if a string value, the string value (with escape sequences interpreted)
 	Regexp *regexp.Regexp // if a regexp pattern, the compiled regular expression (call its String method to get source pattern string)
 	Bool   *bool          // if a bool value, the bool value
+	PCRE   *pcre.Regexp   // if a PCRE regexp pattern, the compiled regular expression
 }
 
 // Not returns whether the value is negated in the query (e.g., -value or -field:value).
@@ -69,6 +74,8 @@ func (v *Value) Value() interface{} {
 		return v.Regexp
 	case v.Bool != nil:
 		return *v.Bool
+	case v.PCRE != nil:
+		return v.PCRE
 	default:
 		panic("no value")
 	}
@@ -82,6 +89,8 @@ func (v *Value) ToString() string {
 		return v.Regexp.String()
 	case v.Bool != nil:
 		return strconv.FormatBool(*v.Bool)
+	case v.PCRE != nil:
+		return v.PCRE.String()
 	default:
 		return "<unable to get querytypes.Value as string>"
 	}
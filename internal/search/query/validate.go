@@ -6,6 +6,10 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/sourcegraph/sourcegraph/internal/search/contentclass"
+	"github.com/sourcegraph/sourcegraph/internal/search/goast"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
+	"github.com/sourcegraph/sourcegraph/internal/search/rules"
 	"github.com/src-d/enry/v2"
 )
 
@@ -187,6 +191,76 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		return nil
 	}
 
+	isHistoryRange := func() error {
+		_, _, err := ParseHistoryRange(value)
+		return err
+	}
+
+	isIdentifierMode := func() error {
+		_, _, err := ParseIdentifierMode(value)
+		return err
+	}
+
+	isWordCharacters := func() error {
+		return identifier.CheckWordCharacters(value)
+	}
+
+	isContentClass := func() error {
+		_, err := contentclass.Parse(value)
+		return err
+	}
+
+	isGoName := func() error {
+		_, err := goast.ParseName(value)
+		return err
+	}
+
+	isModified := func() error {
+		_, _, err := ParseModified(value)
+		return err
+	}
+
+	isEngine := func() error {
+		_, err := ParseEngine(value)
+		return err
+	}
+
+	isNormalization := func() error {
+		_, err := ParseNormalization(value)
+		return err
+	}
+
+	isOwner := func() error {
+		if strings.TrimPrefix(value, "@") == "" {
+			return fmt.Errorf("field %q requires an owner, such as @org/team", field)
+		}
+		return nil
+	}
+
+	isLicense := func() error {
+		if value == "" {
+			return fmt.Errorf("field %q requires an SPDX license identifier, such as MIT", field)
+		}
+		return nil
+	}
+
+	isRuleID := func() error {
+		if value == "" {
+			return fmt.Errorf("field %q requires a rule ID, such as go/weak-hash", field)
+		}
+		return nil
+	}
+
+	isSeverity := func() error {
+		_, err := rules.ParseSeverity(value)
+		return err
+	}
+
+	isRank := func() error {
+		_, err := ParseRank(value)
+		return err
+	}
+
 	isUnrecognizedField := func() error {
 		return fmt.Errorf("unrecognized field %q", field)
 	}
@@ -252,8 +326,54 @@ func validateField(field, value string, negated bool, seen map[string]struct{})
 		FieldCount:
 		return satisfies(isSingular, isNumber, isNotNegated)
 	case
-		FieldStable:
+		FieldStable,
+		FieldSubmodules,
+		FieldHexPreview,
+		FieldDeterministic,
+		FieldFirstMatch,
+		FieldCaseFold,
+		FieldDefaultExclusions:
 		return satisfies(isSingular, isBoolean, isNotNegated)
+	case
+		FieldHistory:
+		return satisfies(isSingular, isNotNegated, isHistoryRange)
+	case
+		FieldOwner:
+		return satisfies(isOwner)
+	case
+		FieldLicense:
+		return satisfies(isLicense)
+	case
+		FieldRuleID:
+		return satisfies(isRuleID)
+	case
+		FieldSeverity:
+		return satisfies(isSingular, isNotNegated, isSeverity)
+	case
+		FieldRank:
+		return satisfies(isSingular, isNotNegated, isRank)
+	case
+		FieldIdentifier:
+		return satisfies(isSingular, isNotNegated, isIdentifierMode)
+	case
+		FieldWordChars:
+		return satisfies(isSingular, isNotNegated, isWordCharacters)
+	case
+		FieldWithin:
+		return satisfies(isContentClass)
+	case
+		FieldGoCall,
+		FieldGoSelector:
+		return satisfies(isSingular, isNotNegated, isGoName)
+	case
+		FieldModified:
+		return satisfies(isSingular, isNotNegated, isModified)
+	case
+		FieldEngine:
+		return satisfies(isSingular, isNotNegated, isEngine)
+	case
+		FieldNormalize:
+		return satisfies(isSingular, isNotNegated, isNormalization)
 	case
 		FieldMax,
 		FieldTimeout,
@@ -45,6 +45,58 @@ func TestAndOrQuery_Validation(t *testing.T) {
 			input: "count:-1",
 			want:  "field count requires a positive number",
 		},
+		{
+			input: "history:..master",
+			want:  `invalid history range "..master", the revision to search the history since is missing`,
+		},
+		{
+			input: "identifier:maybe",
+			want:  "invalid identifier:maybe, expected yes, no or subtokens",
+		},
+		{
+			input: "wordchars:-a",
+			want:  "invalid word character 'a', expected ASCII punctuation",
+		},
+		{
+			input: "-within:docs",
+			want:  `invalid content class "docs", expected code, comment or string`,
+		},
+		{
+			input: "gocall:a.b.c",
+			want:  `invalid Go name "a.b.c", expected Name or Qualifier.Name`,
+		},
+		{
+			input: "modified:0d",
+			want:  "invalid modified:0d, the duration must be positive",
+		},
+		{
+			input: "engine:pcre2",
+			want:  "invalid engine:pcre2, expected re2 or pcre",
+		},
+		{
+			input: "normalize:nfkc",
+			want:  "invalid normalize:nfkc, expected nfc or nfd",
+		},
+		{
+			input: "defaultexclusions:maybe",
+			want:  `invalid boolean "maybe"`,
+		},
+		{
+			input: "owner:@",
+			want:  `field "owner" requires an owner, such as @org/team`,
+		},
+		{
+			input: "license:\"\"",
+			want:  `field "license" requires an SPDX license identifier, such as MIT`,
+		},
+		{
+			input: "severity:urgent",
+			want:  `invalid severity "urgent", expected low, medium, high or critical`,
+		},
+		{
+			input: "rank:relevance",
+			want:  `invalid rank:relevance, expected default or examples`,
+		},
 	}
 	for _, c := range cases {
 		t.Run("validate and/or query", func(t *testing.T) {
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+)
+
+// MaxRegexpProgramSize is the maximum number of instructions of a compiled
+// search regexp. Go regexps run in linear time, but the constant factor grows
+// with the size of the program, so patterns with large (or nested) repetition
+// counts like (a{100}){100} would slow down every searcher replica.
+const MaxRegexpProgramSize = 10000
+
+// RegexpSafetyError is returned for a search regexp that would be too
+// expensive to run.
+type RegexpSafetyError struct {
+	Pattern string
+	Reason  string
+}
+
+func (e *RegexpSafetyError) Error() string {
+	return fmt.Sprintf("the regexp %q %s", e.Pattern, e.Reason)
+}
+
+// CheckRegexpSafety returns a *RegexpSafetyError if searching for the regexp
+// pattern would be too expensive: if it matches every line (e.g. ".*" or
+// "a?"), or if its compiled program is larger than MaxRegexpProgramSize. The
+// pattern must be valid.
+func CheckRegexpSafety(pattern string) error {
+	if pattern == "" {
+		// An empty pattern means that file contents aren't searched.
+		return nil
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return err
+	}
+	if matchesEveryLine(re) {
+		return &RegexpSafetyError{Pattern: pattern, Reason: "matches every line of every file. Add a literal string to the pattern, or use a repo: or file: filter instead"}
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return err
+	}
+	if len(prog.Inst) > MaxRegexpProgramSize {
+		return &RegexpSafetyError{Pattern: pattern, Reason: "is too complex. Use smaller repetition counts (e.g. {10} instead of {1000}) or fewer alternatives"}
+	}
+	return nil
+}
+
+// matchesEveryLine reports whether re matches the empty string regardless of
+// its position, which means it matches (a prefix of) every line.
+func matchesEveryLine(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpStar, syntax.OpQuest:
+		return true
+	case syntax.OpRepeat:
+		return re.Min == 0 || matchesEveryLine(re.Sub[0])
+	case syntax.OpPlus, syntax.OpCapture:
+		return matchesEveryLine(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !matchesEveryLine(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if matchesEveryLine(sub) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// TrimRegexpWildcards removes a leading and a trailing ".*" from pattern. They
+// do not change which lines match, but make searcher report (and the regexp
+// engine scan for) the whole rest of the line as the match, which is slow
+// for long lines. pattern is returned unchanged if trimming would change its
+// meaning.
+func TrimRegexpWildcards(pattern string) string {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil || re.Op != syntax.OpConcat {
+		return pattern
+	}
+	subs, trimmed := re.Sub, pattern
+	if isAnyCharStar(subs[0]) && strings.HasPrefix(trimmed, ".*") {
+		subs, trimmed = subs[1:], trimmed[len(".*"):]
+	}
+	if len(subs) > 0 && isAnyCharStar(subs[len(subs)-1]) && strings.HasSuffix(trimmed, ".*") {
+		subs, trimmed = subs[:len(subs)-1], trimmed[:len(trimmed)-len(".*")]
+	}
+	if len(subs) == len(re.Sub) || len(subs) == 0 {
+		return pattern
+	}
+
+	// Make sure that the trimmed pattern is what is left of the original, and
+	// not e.g. the remainder of an escape sequence.
+	want := &syntax.Regexp{Op: syntax.OpConcat, Flags: re.Flags, Sub: subs}
+	if len(subs) == 1 {
+		want = subs[0]
+	}
+	got, err := syntax.Parse(trimmed, syntax.Perl)
+	if err != nil || !got.Equal(want) || matchesEveryLine(got) {
+		return pattern
+	}
+	return trimmed
+}
+
+func isAnyCharStar(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpStar && re.Flags&syntax.NonGreedy == 0 && re.Sub[0].Op == syntax.OpAnyCharNotNL
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRegexpSafety(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr string
+	}{
+		{pattern: ""},
+		{pattern: "foo"},
+		{pattern: "foo.*bar"},
+		{pattern: "^$"},
+		{pattern: "a{100}"},
+		{pattern: ".*", wantErr: "matches every line"},
+		{pattern: "a?", wantErr: "matches every line"},
+		{pattern: "(foo|)", wantErr: "matches every line"},
+		{pattern: "(.*)+", wantErr: "matches every line"},
+		{pattern: "(a{1000}){1000}", wantErr: "invalid repeat count"},
+		{pattern: "(abcdefghijk){1000}", wantErr: "too complex"},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			err := CheckRegexpSafety(test.pattern)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got error %v, want error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrimRegexpWildcards(t *testing.T) {
+	tests := map[string]string{
+		"foo":         "foo",
+		".*foo":       "foo",
+		"foo.*":       "foo",
+		".*foo.*":     "foo",
+		".*foo.*bar":  "foo.*bar",
+		".*":          ".*",
+		".*?foo":      ".*?foo",
+		"foo\\.*":     "foo\\.*",
+		".*(foo|bar)": "(foo|bar)",
+		".*foo|bar":   ".*foo|bar",
+		"(.*foo)":     "(.*foo)",
+		".*a?":        ".*a?",
+	}
+	for pattern, want := range tests {
+		if got := TrimRegexpWildcards(pattern); got != want {
+			t.Errorf("TrimRegexpWildcards(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package replace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines around the changes of a hunk.
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// FileDiff returns a unified diff (in the format of "git diff", without the
+// a/ and b/ path prefixes) that changes the file at path from original to
+// rewritten. It returns "" if the contents are equal.
+func FileDiff(path, original, rewritten string) string {
+	if original == rewritten {
+		return ""
+	}
+
+	dmp := diffmatchpatch.New()
+	chars1, chars2, lines := dmp.DiffLinesToChars(original, rewritten)
+	var all []diffLine
+	for _, d := range dmp.DiffCharsToLines(dmp.DiffMain(chars1, chars2, false), lines) {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, text := range splitLines(d.Text) {
+			all = append(all, diffLine{op: op, text: text})
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git %s %s\n--- %s\n+++ %s\n", path, path, path, path)
+	// oldLine and newLine are the numbers of the lines before all[i].
+	var oldLine, newLine int
+	for i := 0; i < len(all); {
+		for i < len(all) && all[i].op == ' ' {
+			oldLine, newLine = oldLine+1, newLine+1
+			i++
+		}
+		if i == len(all) {
+			break
+		}
+
+		// The hunk starts with up to diffContext unchanged lines, and ends
+		// after the last change that is followed by more than 2*diffContext
+		// unchanged lines (or by the end of the file).
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		for j := start; j < i; j++ {
+			oldLine, newLine = oldLine-1, newLine-1
+		}
+		end := i
+		for j := i; j < len(all) && j-end < 2*diffContext; j++ {
+			if all[j].op != ' ' {
+				end = j + 1
+			}
+		}
+		end += diffContext
+		if end > len(all) {
+			end = len(all)
+		}
+
+		var oldCount, newCount int
+		for _, l := range all[start:end] {
+			if l.op != '+' {
+				oldCount++
+			}
+			if l.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, l := range all[start:end] {
+			b.WriteByte(l.op)
+			b.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		oldLine, newLine = oldLine+oldCount, newLine+newCount
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange returns the range of a hunk header for count lines after the
+// first before lines.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		lines = append(lines, s[:i])
+		s = s[i:]
+	}
+	return lines
+}
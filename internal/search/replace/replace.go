@@ -0,0 +1,76 @@
+// Package replace rewrites the matches of text search patterns, so that
+// search-and-replace changes can be previewed (and later applied) without
+// running an external tool.
+package replace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+// Replacer replaces the matches of a search pattern.
+type Replacer struct {
+	re       *regexp.Regexp
+	template string
+	literal  bool
+}
+
+// New returns a Replacer that replaces the matches of the pattern of p with
+// template. If p is a regular expression, template may refer to submatches of
+// the pattern as "$1" or "${name}" (see regexp.Regexp.Expand). Otherwise,
+// template is inserted literally.
+//
+// Structural patterns are not supported. They are rewritten by the replacer
+// service (see the replace: search filter).
+func New(p *search.TextPatternInfo, template string) (*Replacer, error) {
+	if p.IsStructuralPat {
+		return nil, errors.New("structural patterns are replaced with the replace: filter")
+	}
+	if p.Pattern == "" {
+		return nil, errors.New("a pattern is required to replace matches")
+	}
+
+	expr := p.Pattern
+	if !p.IsRegExp {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if p.IsWordMatch {
+		expr = `\b` + expr + `\b`
+	}
+	// Like searcher, anchors match at line boundaries.
+	flags := "m"
+	if !p.IsCaseSensitive {
+		flags += "i"
+	}
+	re, err := regexp.Compile("(?" + flags + ":" + expr + ")")
+	if err != nil {
+		return nil, err
+	}
+	return &Replacer{re: re, template: template, literal: !p.IsRegExp}, nil
+}
+
+// Replace returns s with all matches of the pattern replaced.
+func (r *Replacer) Replace(s string) string {
+	if r.literal {
+		return r.re.ReplaceAllLiteralString(s, r.template)
+	}
+	return r.re.ReplaceAllString(s, r.template)
+}
+
+// LineHunk returns a unified diff hunk that changes the line with the given
+// 0-based number (as in search line matches) from original to rewritten. It
+// returns "" if the line is unchanged.
+func LineHunk(lineNumber int, original, rewritten string) string {
+	if original == rewritten {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%d +%d @@\n", lineNumber+1, lineNumber+1)
+	b.WriteString("-" + original + "\n")
+	b.WriteString("+" + rewritten + "\n")
+	return b.String()
+}
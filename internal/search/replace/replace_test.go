@@ -0,0 +1,123 @@
+package replace
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/search"
+)
+
+func TestReplacer(t *testing.T) {
+	tests := []struct {
+		name     string
+		p        search.TextPatternInfo
+		template string
+		in, want string
+	}{
+		{
+			name:     "literal",
+			p:        search.TextPatternInfo{Pattern: "a.b", IsCaseSensitive: true},
+			template: "$1",
+			in:       "a.b axb A.B",
+			want:     "$1 axb A.B",
+		},
+		{
+			name:     "case insensitive",
+			p:        search.TextPatternInfo{Pattern: "foo"},
+			template: "bar",
+			in:       "Foo foo",
+			want:     "bar bar",
+		},
+		{
+			name:     "regexp submatches",
+			p:        search.TextPatternInfo{Pattern: `(\w+)\.Errorf\(`, IsRegExp: true, IsCaseSensitive: true},
+			template: "errors.Errorf(",
+			in:       `return fmt.Errorf("x")`,
+			want:     `return errors.Errorf("x")`,
+		},
+		{
+			name:     "regexp named submatches",
+			p:        search.TextPatternInfo{Pattern: `(?P<a>\w+) = (?P<b>\w+)`, IsRegExp: true},
+			template: "${b} = ${a}",
+			in:       "x = y",
+			want:     "y = x",
+		},
+		{
+			name:     "word match",
+			p:        search.TextPatternInfo{Pattern: "id", IsWordMatch: true},
+			template: "ID",
+			in:       "id ids",
+			want:     "ID ids",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := New(&test.p, test.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Replace(test.in); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	for _, p := range []search.TextPatternInfo{{}, {Pattern: "x", IsStructuralPat: true}, {Pattern: "(", IsRegExp: true}} {
+		if _, err := New(&p, ""); err == nil {
+			t.Errorf("got nil error for pattern %+v", p)
+		}
+	}
+}
+
+func TestLineHunk(t *testing.T) {
+	if got, want := LineHunk(4, "foo()", "bar()"), "@@ -5 +5 @@\n-foo()\n+bar()\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := LineHunk(4, "foo()", "foo()"); got != "" {
+		t.Errorf("got %q for unchanged line, want empty hunk", got)
+	}
+}
+
+func TestFileDiff(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	rewritten := strings.Replace(strings.Replace(original, "b\n", "B\n", 1), "m\n", "M\n", 1)
+	want := `diff --git x.txt x.txt
+--- x.txt
++++ x.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,5 +10,5 @@
+ j
+ k
+ l
+-m
++M
+ n
+`
+	if got := FileDiff("x.txt", original, rewritten); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = `diff --git x.txt x.txt
+--- x.txt
++++ x.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++B
+\ No newline at end of file
+`
+	if got := FileDiff("x.txt", "a\nb", "a\nB"); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := FileDiff("x.txt", "a", "a"); got != "" {
+		t.Errorf("got %q for equal contents, want empty diff", got)
+	}
+}
@@ -26,6 +26,12 @@ type RevisionSpecifier struct {
 	ExcludeRefGlob string
 }
 
+// LatestReleaseRevSpec is a revspec that refers to the newest release of a
+// repository, i.e. its tag with the greatest semantic version that is not a
+// prerelease (such as "v1.2.3"). It is resolved by the frontend, since git
+// does not know it.
+const LatestReleaseRevSpec = "LATEST_RELEASE"
+
 func (r1 RevisionSpecifier) String() string {
 	if r1.ExcludeRefGlob != "" {
 		return "*!" + r1.ExcludeRefGlob
@@ -0,0 +1,28 @@
+package search
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type requestIDKey struct{}
+
+// NewRequestID returns a new unique ID for a search request.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// WithRequestID returns a context carrying the ID of the search request it
+// belongs to. The ID is sent to searcher and included in errors and logs of
+// both services, so that a failed search can be correlated across them.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the search request ctx belongs to, or "" if
+// there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
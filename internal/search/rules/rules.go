@@ -0,0 +1,198 @@
+// Package rules checks search results against the rules of the search.rules
+// site setting, such as security rules that flag the uses of insecure APIs.
+// The line matches of search results are tagged with the IDs of the rules
+// that they hit.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/inconshreveable/log15"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func init() {
+	conf.ContributeValidator(func(c conf.Unified) (problems conf.Problems) {
+		if _, err := Compile(c.SearchRules); err != nil {
+			problems = append(problems, conf.NewSiteProblem(fmt.Sprintf("Invalid search.rules: %s. Search results are not checked against rules.", err)))
+		}
+		return problems
+	})
+}
+
+// Severity is the severity of the problems that a rule finds.
+type Severity int
+
+const (
+	Low Severity = iota + 1
+	Medium
+	High
+	Critical
+)
+
+var severityNames = []string{Low: "low", Medium: "medium", High: "high", Critical: "critical"}
+
+// ParseSeverity parses the name of a severity, such as "high".
+func ParseSeverity(s string) (Severity, error) {
+	for sev, name := range severityNames {
+		if name != "" && strings.EqualFold(s, name) {
+			return Severity(sev), nil
+		}
+	}
+	return 0, errors.Errorf("invalid severity %q, expected low, medium, high or critical", s)
+}
+
+func (s Severity) String() string {
+	if s < Low || s > Critical {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// SARIFLevel returns the level of the SARIF results of problems with the
+// severity.
+func (s Severity) SARIFLevel() string {
+	switch s {
+	case Low:
+		return "note"
+	case Medium:
+		return "warning"
+	}
+	return "error"
+}
+
+// Rule is a compiled rule of the search.rules site setting.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+
+	pattern *regexp.Regexp
+	file    *regexp.Regexp // nil if the rule applies to all files
+}
+
+// Ruleset is a list of rules.
+type Ruleset struct {
+	rules []*Rule
+	byID  map[string]*Rule
+}
+
+// Compile compiles the rules of the search.rules site setting.
+func Compile(config []*schema.SearchRule) (*Ruleset, error) {
+	rs := &Ruleset{byID: make(map[string]*Rule, len(config))}
+	for _, c := range config {
+		if _, ok := rs.byID[c.Id]; ok {
+			return nil, errors.Errorf("duplicate rule ID %q", c.Id)
+		}
+		severity, err := ParseSeverity(c.Severity)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rule %q", c.Id)
+		}
+		expr := c.Pattern
+		switch c.PatternType {
+		case "", "regexp":
+		case "structural":
+			expr = structuralRegexp(c.Pattern)
+		default:
+			return nil, errors.Errorf("rule %q: invalid pattern type %q", c.Id, c.PatternType)
+		}
+		r := &Rule{ID: c.Id, Description: c.Description, Severity: severity}
+		if r.pattern, err = regexp.Compile(expr); err != nil {
+			return nil, errors.Wrapf(err, "rule %q: pattern", c.Id)
+		}
+		if c.File != "" {
+			if r.file, err = regexp.Compile(c.File); err != nil {
+				return nil, errors.Wrapf(err, "rule %q: file", c.Id)
+			}
+		}
+		rs.rules = append(rs.rules, r)
+		rs.byID[r.ID] = r
+	}
+	return rs, nil
+}
+
+// compiledRules are the compiled rules of a site configuration.
+type compiledRules struct {
+	config *conf.Unified
+	rs     *Ruleset
+}
+
+// current caches the compiled rules of the site configuration. The site
+// configuration is replaced, never modified, when it changes, so its pointer
+// identifies it.
+var current atomic.Value // *compiledRules
+
+// Current returns the compiled rules of the search.rules site setting, or nil
+// if there are none or they are invalid.
+func Current() *Ruleset {
+	config := conf.Get()
+	if c, _ := current.Load().(*compiledRules); c != nil && c.config == config {
+		return c.rs
+	}
+	rs, err := Compile(config.SearchRules)
+	if err != nil {
+		log15.Warn("Invalid search.rules site setting", "error", err)
+	}
+	current.Store(&compiledRules{config: config, rs: rs})
+	return rs
+}
+
+// Len returns the number of rules. A nil Ruleset has none.
+func (rs *Ruleset) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.rules)
+}
+
+// Rules returns the rules in the order they were configured.
+func (rs *Ruleset) Rules() []*Rule {
+	if rs == nil {
+		return nil
+	}
+	return rs.rules
+}
+
+// Rule returns the rule with the ID, or nil if there is none.
+func (rs *Ruleset) Rule(id string) *Rule {
+	if rs == nil {
+		return nil
+	}
+	return rs.byID[id]
+}
+
+// Match returns the IDs of the rules that the line of the file at path hits.
+func (rs *Ruleset) Match(path, line string) []string {
+	if rs == nil {
+		return nil
+	}
+	var ids []string
+	for _, r := range rs.rules {
+		if (r.file == nil || r.file.MatchString(path)) && r.pattern.MatchString(line) {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids
+}
+
+// structuralHole matches the holes of structural search patterns, such as
+// :[x], :[[x]], :[x.], :[x\n] and :[ x].
+var structuralHole = regexp.MustCompile(`:\[\[\w*\]\]|:\[\w*(?:\.|\\n)?\]|:\[ +\w*\]`)
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// structuralRegexp returns a regexp that approximates a structural search
+// pattern within a line, like StructuralPatToRegexpQuery in the frontend:
+// holes match any text, and whitespace matches any whitespace.
+func structuralRegexp(pattern string) string {
+	pieces := structuralHole.Split(pattern, -1)
+	for i, p := range pieces {
+		pieces[i] = whitespace.ReplaceAllLiteralString(regexp.QuoteMeta(p), `\s+`)
+	}
+	return strings.Join(pieces, ".*?")
+}
@@ -0,0 +1,83 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestRuleset(t *testing.T) {
+	rs, err := Compile([]*schema.SearchRule{
+		{Id: "go/weak-hash", Pattern: `\b(md5|sha1)\.(New|Sum)\b`, Severity: "high", File: `\.go$`},
+		{Id: "js/eval", Pattern: "eval(:[code])", PatternType: "structural", Severity: "critical"},
+		{Id: "todo", Pattern: "TODO", Severity: "low"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path, line string
+		want       []string
+	}{
+		{"a.go", "h := md5.Sum(data) // TODO", []string{"go/weak-hash", "todo"}},
+		{"a.py", "h := md5.Sum(data)", nil},
+		{"a.js", "return eval( input );", []string{"js/eval"}},
+		{"a.js", "evaluate(input)", nil},
+	}
+	for _, test := range tests {
+		if got := rs.Match(test.path, test.line); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %q: got %q, want %q", test.path, test.line, got, test.want)
+		}
+	}
+
+	if r := rs.Rule("js/eval"); r == nil || r.Severity != Critical {
+		t.Errorf("got rule %+v, want js/eval with critical severity", r)
+	}
+	if rs.Len() != 3 || rs.Rule("missing") != nil {
+		t.Errorf("got %d rules, want 3", rs.Len())
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	tests := map[string][]*schema.SearchRule{
+		"duplicate": {{Id: "a", Pattern: "a", Severity: "low"}, {Id: "a", Pattern: "b", Severity: "low"}},
+		"severity":  {{Id: "a", Pattern: "a", Severity: "urgent"}},
+		"pattern":   {{Id: "a", Pattern: "(", Severity: "low"}},
+		"file":      {{Id: "a", Pattern: "a", Severity: "low", File: "["}},
+		"type":      {{Id: "a", Pattern: "a", Severity: "low", PatternType: "literal"}},
+	}
+	for name, config := range tests {
+		if _, err := Compile(config); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	s, err := ParseSeverity("HIGH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != High || s.String() != "high" || s.SARIFLevel() != "error" || !(s > Medium) {
+		t.Errorf("got severity %v, want high", s)
+	}
+	if Low.SARIFLevel() != "note" || Medium.SARIFLevel() != "warning" {
+		t.Error("got wrong SARIF levels")
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchRules: []*schema.SearchRule{{Id: "a", Pattern: "a", Severity: "low"}}}})
+	defer conf.Mock(nil)
+	if rs := Current(); rs.Rule("a") == nil {
+		t.Error("got no rule a")
+	}
+
+	conf.Mock(&conf.Unified{SiteConfiguration: schema.SiteConfiguration{SearchRules: []*schema.SearchRule{{Id: "a", Pattern: "(", Severity: "low"}}}})
+	if rs := Current(); rs != nil {
+		t.Errorf("got rules %+v for an invalid setting, want none", rs)
+	}
+}
@@ -0,0 +1,31 @@
+package search
+
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the tenant (see the search.tenants
+// site configuration) that the search running in it belongs to.
+func WithTenant(ctx context.Context, t *schema.SearchTenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant that the search running in ctx belongs
+// to, or nil if it is not isolated.
+func TenantFromContext(ctx context.Context) *schema.SearchTenant {
+	t, _ := ctx.Value(tenantKey{}).(*schema.SearchTenant)
+	return t
+}
+
+// TenantName returns the name of the tenant that the search running in ctx
+// belongs to, or "" if it is not isolated.
+func TenantName(ctx context.Context) string {
+	if t := TenantFromContext(ctx); t != nil {
+		return t.Name
+	}
+	return ""
+}
@@ -3,6 +3,8 @@ package search
 
 import (
 	"regexp/syntax"
+
+	"github.com/sourcegraph/sourcegraph/internal/search/pcre"
 )
 
 func (p *TextPatternInfo) IsEmpty() bool {
@@ -10,10 +12,19 @@ func (p *TextPatternInfo) IsEmpty() bool {
 }
 
 func (p *TextPatternInfo) Validate() error {
-	if p.IsRegExp {
+	if p.IsRegExp && p.IsPCRE {
+		// The PCRE-compatible engine limits the time that matching takes,
+		// so the pattern needn't be checked for safety.
+		if _, err := pcre.Compile(p.Pattern); err != nil {
+			return err
+		}
+	} else if p.IsRegExp {
 		if _, err := syntax.Parse(p.Pattern, syntax.Perl); err != nil {
 			return err
 		}
+		if err := CheckRegexpSafety(p.Pattern); err != nil {
+			return err
+		}
 	}
 
 	if p.PathPatternsAreRegExps {
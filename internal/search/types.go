@@ -3,11 +3,13 @@ package search
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/internal/api"
 	"github.com/sourcegraph/sourcegraph/internal/endpoint"
 	"github.com/sourcegraph/sourcegraph/internal/gitserver"
 	searchbackend "github.com/sourcegraph/sourcegraph/internal/search/backend"
+	"github.com/sourcegraph/sourcegraph/internal/search/identifier"
 	"github.com/sourcegraph/sourcegraph/internal/search/query"
 	"github.com/sourcegraph/sourcegraph/internal/vcs/git"
 )
@@ -114,6 +116,52 @@ type TextPatternInfo struct {
 	IsCaseSensitive bool
 	FileMatchLimit  int32
 
+	// IsIdentifierMatch if true means that the pattern only matches whole
+	// identifiers (see the identifier: filter), and IdentifierSubTokens that
+	// it also matches sub-tokens of identifiers.
+	IsIdentifierMatch   bool
+	IdentifierSubTokens bool
+
+	// WordCharacters overrides the characters that are part of words and
+	// identifiers (see the search.wordCharacters site setting and the
+	// wordchars: filter).
+	WordCharacters identifier.WordCharacters
+
+	// Within is the names of the content classes that matches must start in
+	// (see the within: filter), or nil if matches can be anywhere.
+	Within []string
+
+	// GoCall and GoSelector are the names of the Go functions whose calls,
+	// or the Go selectors whose uses, are matched (see the gocall: and
+	// goselector: filters). Pattern then only selects the files that are
+	// parsed.
+	GoCall     string
+	GoSelector string
+
+	// ModifiedSince and ModifiedSinceRevision, if set, restrict the search
+	// to the files that were changed by the commits after ModifiedSince, or
+	// since the revision ModifiedSinceRevision (see the modified: filter).
+	ModifiedSince         time.Time
+	ModifiedSinceRevision string
+
+	// FirstMatchPerRepo is whether the search of each repository stops after
+	// its first match, because only the repositories with matches are
+	// results (see the firstmatch: filter).
+	FirstMatchPerRepo bool
+
+	// IsPCRE if true means that the regexp Pattern is matched by the
+	// PCRE-compatible engine of package pcre instead of Go's RE2 engine (see
+	// the engine: filter), which supports lookarounds and backreferences.
+	IsPCRE bool
+
+	// CaseFold is whether case-insensitive matches fold the case of all
+	// Unicode letters instead of only ASCII letters (see the casefold:
+	// filter), and Normalization is the Unicode normalization form ("nfc" or
+	// "nfd") that the pattern and contents are matched in, if any (see the
+	// normalize: filter).
+	CaseFold      bool
+	Normalization string
+
 	// We do not support IsMultiline
 	// IsMultiline     bool
 	IncludePatterns []string
@@ -129,6 +177,19 @@ type TextPatternInfo struct {
 	PatternMatchesPath    bool
 
 	Languages []string
+
+	// CountOnly if true means that only the number of matches in each file
+	// is needed, so searcher does not return line matches.
+	CountOnly bool
+
+	// MaxLineMatches is the maximum number of line matches searcher returns
+	// per file. If 0, searcher's default is used.
+	MaxLineMatches int
+
+	// Deterministic if true means that the results must not depend on the
+	// order in which files and repositories are searched (see the
+	// deterministic: filter), even if that is slower.
+	Deterministic bool
 }
 
 func (p *TextPatternInfo) String() string {
@@ -136,6 +197,9 @@ func (p *TextPatternInfo) String() string {
 	if p.IsRegExp {
 		args = append(args, "re")
 	}
+	if p.IsPCRE {
+		args = append(args, "pcre")
+	}
 	if p.IsStructuralPat {
 		if p.CombyRule != "" {
 			args = append(args, fmt.Sprintf("comby:%s", p.CombyRule))
@@ -146,9 +210,43 @@ func (p *TextPatternInfo) String() string {
 	if p.IsWordMatch {
 		args = append(args, "word")
 	}
+	if p.IsIdentifierMatch {
+		if p.IdentifierSubTokens {
+			args = append(args, "identifier:subtokens")
+		} else {
+			args = append(args, "identifier")
+		}
+	}
+	if len(p.WordCharacters) > 0 {
+		args = append(args, fmt.Sprintf("wordchars:%q", p.WordCharacters.Entries()))
+	}
+	for _, class := range p.Within {
+		args = append(args, "within:"+class)
+	}
+	if p.GoCall != "" {
+		args = append(args, "gocall:"+p.GoCall)
+	}
+	if p.GoSelector != "" {
+		args = append(args, "goselector:"+p.GoSelector)
+	}
+	if !p.ModifiedSince.IsZero() {
+		args = append(args, "modified:"+p.ModifiedSince.UTC().Format(time.RFC3339))
+	}
+	if p.ModifiedSinceRevision != "" {
+		args = append(args, "modified:"+p.ModifiedSinceRevision)
+	}
+	if p.FirstMatchPerRepo {
+		args = append(args, "firstmatch")
+	}
 	if p.IsCaseSensitive {
 		args = append(args, "case")
 	}
+	if p.CaseFold {
+		args = append(args, "casefold")
+	}
+	if p.Normalization != "" {
+		args = append(args, "normalize:"+p.Normalization)
+	}
 	if !p.PatternMatchesContent {
 		args = append(args, "nocontent")
 	}
@@ -158,6 +256,15 @@ func (p *TextPatternInfo) String() string {
 	if p.FileMatchLimit > 0 {
 		args = append(args, fmt.Sprintf("filematchlimit:%d", p.FileMatchLimit))
 	}
+	if p.CountOnly {
+		args = append(args, "countonly")
+	}
+	if p.MaxLineMatches > 0 {
+		args = append(args, fmt.Sprintf("maxlinematches:%d", p.MaxLineMatches))
+	}
+	if p.Deterministic {
+		args = append(args, "deterministic")
+	}
 	for _, lang := range p.Languages {
 		args = append(args, fmt.Sprintf("lang:%s", lang))
 	}
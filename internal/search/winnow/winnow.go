@@ -0,0 +1,208 @@
+// Package winnow finds the regions of code that are near-duplicates of a
+// snippet, with the winnowing algorithm of "Winnowing: Local Algorithms for
+// Document Fingerprinting" (Schleimer, Wilkerson and Aiken, 2003). Code is
+// split into tokens, and a subset of the hashes of its k-grams of tokens is
+// selected as its fingerprints. Code that shares a long enough sequence of
+// tokens with the snippet shares fingerprints with it, regardless of
+// whitespace and of the code around it.
+package winnow
+
+import (
+	"hash/fnv"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// k is the number of tokens of the k-grams that are hashed. Shared
+	// sequences of fewer tokens are noise.
+	k = 8
+
+	// window is the number of consecutive k-gram hashes that a fingerprint is
+	// selected from. Every shared sequence of at least k+window-1 tokens has a
+	// fingerprint in common.
+	window = 4
+)
+
+// token is a token of code: an identifier or number, or a single other
+// non-space character.
+type token struct {
+	text string
+	line int // 0-based
+}
+
+// tokenize returns the tokens of text.
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		line   int
+	)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n':
+			line++
+			i += size
+		case unicode.IsSpace(r):
+			i += size
+		case isWordRune(r):
+			start := i
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if !isWordRune(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{text: text[start:i], line: line})
+		default:
+			tokens = append(tokens, token{text: text[i : i+size], line: line})
+			i += size
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Fingerprint is a selected hash of a k-gram of tokens.
+type Fingerprint struct {
+	Hash      uint64
+	StartLine int // the line of the first token of the k-gram (0-based)
+	EndLine   int // the line of the last token of the k-gram (0-based)
+}
+
+// Fingerprints returns the fingerprints of text in order. Text with fewer
+// than k tokens has none.
+func Fingerprints(text string) []Fingerprint {
+	tokens := tokenize(text)
+	if len(tokens) < k {
+		return nil
+	}
+
+	hashes := make([]uint64, len(tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+k] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+
+	// Select the minimum hash of each window, the rightmost one if there
+	// are several, unless it was already selected for the previous window.
+	var fps []Fingerprint
+	selected := -1
+	for start := 0; start+window <= len(hashes) || start == 0; start++ {
+		end := start + window
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		min := start
+		for i := start; i < end; i++ {
+			if hashes[i] <= hashes[min] {
+				min = i
+			}
+		}
+		if min != selected {
+			selected = min
+			fps = append(fps, Fingerprint{Hash: hashes[min], StartLine: tokens[min].line, EndLine: tokens[min+k-1].line})
+		}
+	}
+	return fps
+}
+
+// Snippet is the code that near-duplicates are found of.
+type Snippet struct {
+	hashes map[uint64]bool
+	lines  int // the number of lines that the fingerprints span
+}
+
+// NewSnippet returns the snippet of text. It returns an error if text is too
+// short to have fingerprints.
+func NewSnippet(text string) (*Snippet, error) {
+	fps := Fingerprints(text)
+	if len(fps) == 0 {
+		return nil, errors.Errorf("the snippet is too short to find near-duplicates of, it needs at least %d tokens", k)
+	}
+	s := &Snippet{hashes: make(map[uint64]bool, len(fps))}
+	for _, fp := range fps {
+		s.hashes[fp.Hash] = true
+	}
+	s.lines = fps[len(fps)-1].EndLine - fps[0].StartLine + 1
+	return s, nil
+}
+
+// Region is a region of code that is similar to a snippet.
+type Region struct {
+	StartLine int // 0-based
+	EndLine   int // 0-based, inclusive
+
+	// Similarity is the fraction of the fingerprints of the snippet that the
+	// region contains, from 0 to 1. It is 1 for exact copies of the snippet
+	// (ignoring whitespace).
+	Similarity float64
+}
+
+// Regions returns the regions of text that are at least minSimilarity similar
+// to the snippet, in order. Fingerprints of the snippet that are found at
+// most as many lines apart as the snippet is long belong to the same region.
+func (s *Snippet) Regions(text string, minSimilarity float64) []Region {
+	type cluster struct {
+		startLine, endLine int
+		hashes             map[uint64]bool
+	}
+	var clusters []*cluster
+	for _, fp := range Fingerprints(text) {
+		if !s.hashes[fp.Hash] {
+			continue
+		}
+		if n := len(clusters); n > 0 && fp.StartLine <= clusters[n-1].endLine+s.lines {
+			c := clusters[n-1]
+			c.hashes[fp.Hash] = true
+			if fp.EndLine > c.endLine {
+				c.endLine = fp.EndLine
+			}
+			continue
+		}
+		clusters = append(clusters, &cluster{startLine: fp.StartLine, endLine: fp.EndLine, hashes: map[uint64]bool{fp.Hash: true}})
+	}
+
+	var regions []Region
+	for _, c := range clusters {
+		similarity := float64(len(c.hashes)) / float64(len(s.hashes))
+		if similarity >= minSimilarity {
+			regions = append(regions, Region{StartLine: c.startLine, EndLine: c.endLine, Similarity: similarity})
+		}
+	}
+	return regions
+}
+
+// Identifiers returns the n longest distinct identifiers of text with at
+// least 3 characters, longest first. They are the most distinctive words of
+// the text, which files with near-duplicates of it likely contain too.
+func Identifiers(text string, n int) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, t := range tokenize(text) {
+		r, _ := utf8.DecodeRuneInString(t.text)
+		if seen[t.text] || utf8.RuneCountInString(t.text) < 3 || !(r == '_' || unicode.IsLetter(r)) {
+			continue
+		}
+		seen[t.text] = true
+		ids = append(ids, t.text)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return utf8.RuneCountInString(ids[i]) > utf8.RuneCountInString(ids[j])
+	})
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
@@ -0,0 +1,60 @@
+package winnow
+
+import (
+	"reflect"
+	"testing"
+)
+
+const snippet = `func hashPassword(password string) string {
+	sum := md5.Sum([]byte(password + salt))
+	return hex.EncodeToString(sum[:])
+}`
+
+func TestSnippet_Regions(t *testing.T) {
+	s, err := NewSnippet(snippet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		text string
+		want []Region
+	}{
+		"copy": {
+			text: "package a\n\n" + snippet + "\n",
+			want: []Region{{StartLine: 2, EndLine: 4, Similarity: 1}},
+		},
+		"reformatted": {
+			text: "package a\n\nfunc hashPassword(password string) string {\n\tsum := md5.Sum(\n\t\t[]byte(password + salt))\n\treturn hex.EncodeToString(sum[:])\n}\n",
+			want: []Region{{StartLine: 2, EndLine: 5, Similarity: 1}},
+		},
+		"unrelated": {
+			text: "package a\n\nfunc add(a, b int) int {\n\treturn a + b\n}\n",
+		},
+	}
+	for name, test := range tests {
+		if got := s.Regions(test.text, 0.5); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, test.want)
+		}
+	}
+
+	// A modified copy is similar, but less so.
+	modified := "func hashPassword(password string) string {\n\tsum := sha256.Sum256([]byte(password + salt))\n\treturn hex.EncodeToString(sum[:])\n}\n"
+	regions := s.Regions(modified, 0)
+	if len(regions) != 1 || regions[0].Similarity >= 1 || regions[0].Similarity <= 0 {
+		t.Errorf("got %+v, want a single region with a similarity between 0 and 1", regions)
+	}
+}
+
+func TestNewSnippet_tooShort(t *testing.T) {
+	if _, err := NewSnippet("a := b"); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	got := Identifiers(snippet, 3)
+	if want := []string{"EncodeToString", "hashPassword", "password"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -114,6 +114,14 @@ func (s *Store) Start() {
 // PrepareZip returns the path to a local zip archive of repo at commit.
 // It will first consult the local cache, otherwise will fetch from the network.
 func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (path string, err error) {
+	path, _, err = s.PrepareZipCached(ctx, repo, commit)
+	return path, err
+}
+
+// PrepareZipCached is like PrepareZip, but also reports whether the archive
+// was served from the local cache. Requests waiting on a concurrent fetch of
+// the same archive are reported as cached.
+func (s *Store) PrepareZipCached(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (path string, cached bool, err error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "Store.prepareZip")
 	ext.Component.Set(span, "store")
 	defer func() {
@@ -130,7 +138,7 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 	// We already validate commit is absolute in ServeHTTP, but since we
 	// rely on it for caching we check again.
 	if len(commit) != 40 {
-		return "", errors.Errorf("commit must be resolved (repo=%q, commit=%q)", repo.Name, commit)
+		return "", false, errors.Errorf("commit must be resolved (repo=%q, commit=%q)", repo.Name, commit)
 	}
 
 	largeFilePatterns := conf.Get().SearchLargeFiles
@@ -143,15 +151,18 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 	// Our fetch can take a long time, and the frontend aggressively cancels
 	// requests. So we open in the background to give it extra time.
 	type result struct {
-		path string
-		err  error
+		path   string
+		cached bool
+		err    error
 	}
 	resC := make(chan result, 1)
 	go func() {
 		// TODO: consider adding a cache method that doesn't actually bother opening the file,
 		// since we're just going to close it again immediately.
 		bgctx := opentracing.ContextWithSpan(context.Background(), opentracing.SpanFromContext(ctx))
+		fetched := false
 		f, err := s.cache.Open(bgctx, key, func(ctx context.Context) (io.ReadCloser, error) {
+			fetched = true
 			return s.fetch(ctx, repo, commit, largeFilePatterns)
 		})
 		var path string
@@ -161,18 +172,19 @@ func (s *Store) PrepareZip(ctx context.Context, repo gitserver.Repo, commit api.
 				f.File.Close()
 			}
 		}
-		resC <- result{path, err}
+		resC <- result{path, !fetched, err}
 	}()
 
 	select {
 	case <-ctx.Done():
-		return "", ctx.Err()
+		return "", false, ctx.Err()
 
 	case res := <-resC:
 		if res.err != nil {
-			return "", res.err
+			return "", false, res.err
 		}
-		return res.path, nil
+		span.SetTag("cached", res.cached)
+		return res.path, res.cached, nil
 	}
 }
 
@@ -81,6 +81,26 @@ func TestPrepareZip(t *testing.T) {
 	}
 }
 
+func TestPrepareZipCached(t *testing.T) {
+	s, cleanup := tmpStore(t)
+	defer cleanup()
+	s.FetchTar = func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+		return emptyTar(t), nil
+	}
+
+	repo := gitserver.Repo{Name: "foo"}
+	commit := api.CommitID("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef")
+	for i, want := range []bool{false, true} {
+		_, cached, err := s.PrepareZipCached(context.Background(), repo, commit)
+		if err != nil {
+			t.Fatal("expected PrepareZipCached to succeed:", err)
+		}
+		if cached != want {
+			t.Errorf("call %d: got cached=%v want %v", i, cached, want)
+		}
+	}
+}
+
 func TestPrepareZip_fetchTarFail(t *testing.T) {
 	fetchErr := errors.New("test")
 	s, cleanup := tmpStore(t)
@@ -93,6 +93,13 @@ func (t *Trace) LogFields(fields ...log.Field) {
 	t.trace.LazyLog(fieldsStringer(fields), false)
 }
 
+// SetTag sets a tag on the opentracing.Span and logs it to the
+// nettrace.Trace.
+func (t *Trace) SetTag(key string, value interface{}) {
+	t.span.SetTag(key, value)
+	t.trace.LazyPrintf("%s: %v", key, value)
+}
+
 // SetError declares that this trace and span resulted in an error.
 func (t *Trace) SetError(err error) {
 	if err == nil {
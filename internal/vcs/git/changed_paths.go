@@ -0,0 +1,74 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// ChangedPathsOptions specifies the commits whose changed paths ChangedPaths
+// returns.
+type ChangedPathsOptions struct {
+	Since time.Time // if nonzero, only commits committed after Since
+	Base  string    // if set, only commits that are not ancestors of the revision Base
+}
+
+// ChangedPaths returns the paths of the files that were added or modified by
+// the commits of head (and its ancestors) that opt selects, sorted and
+// without duplicates. Some of them may not exist at head anymore.
+func ChangedPaths(ctx context.Context, repo gitserver.Repo, head api.CommitID, opt ChangedPathsOptions) ([]string, error) {
+	if Mocks.ChangedPaths != nil {
+		return Mocks.ChangedPaths(head, opt)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: ChangedPaths")
+	span.SetTag("Head", head)
+	span.SetTag("Opt", opt)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(head)); err != nil {
+		return nil, err
+	}
+	if err := checkSpecArgSafety(opt.Base); err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", "--format=", "--name-only", "--no-renames", "--diff-filter=AM", "-z"}
+	if !opt.Since.IsZero() {
+		args = append(args, "--since="+strconv.FormatInt(opt.Since.Unix(), 10))
+	}
+	if opt.Base != "" {
+		args = append(args, opt.Base+".."+string(head))
+	} else {
+		args = append(args, string(head))
+	}
+	cmd := gitserver.DefaultClient.Command("git", append(args, "--")...)
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+
+	seen := map[string]struct{}{}
+	var paths []string
+	for _, p := range bytes.Split(out, []byte{0}) {
+		p = bytes.TrimPrefix(p, []byte{'\n'}) // commits are separated by newlines
+		if len(p) == 0 {
+			continue
+		}
+		if _, ok := seen[string(p)]; !ok {
+			seen[string(p)] = struct{}{}
+			paths = append(paths, string(p))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
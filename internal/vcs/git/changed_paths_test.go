@@ -0,0 +1,65 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChangedPaths(t *testing.T) {
+	t.Parallel()
+
+	commit := func(date, msg string) string {
+		return "GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=" + date + " git commit -m " + msg + " --author='a <a@a.com>' --date " + date
+	}
+	repo := MakeGitRepository(t,
+		"echo 1 > old.txt && echo 1 > changed.txt && echo 1 > deleted.txt",
+		"git add -A",
+		commit("2006-01-02T15:04:05Z", "first"),
+		"git tag base",
+		"echo 2 > changed.txt && mkdir dir && echo 2 > 'dir/new file.txt' && git rm -q deleted.txt",
+		"git add -A",
+		commit("2006-02-02T15:04:05Z", "second"),
+		"echo 3 > changed.txt",
+		"git add -A",
+		commit("2006-03-02T15:04:05Z", "third"),
+	)
+	head, err := ResolveRevision(ctx, repo, nil, "HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		opt  ChangedPathsOptions
+		want []string
+	}{
+		"all": {
+			want: []string{"changed.txt", "deleted.txt", "dir/new file.txt", "old.txt"},
+		},
+		"since": {
+			opt:  ChangedPathsOptions{Since: time.Date(2006, 2, 15, 0, 0, 0, 0, time.UTC)},
+			want: []string{"changed.txt"},
+		},
+		"base": {
+			opt:  ChangedPathsOptions{Base: "base"},
+			want: []string{"changed.txt", "dir/new file.txt"},
+		},
+		"nothing": {
+			opt: ChangedPathsOptions{Base: "HEAD"},
+		},
+	}
+	for label, test := range tests {
+		got, err := ChangedPaths(ctx, repo, head, test.opt)
+		if err != nil {
+			t.Errorf("%s: %s", label, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", label, got, test.want)
+		}
+	}
+
+	if _, err := ChangedPaths(ctx, repo, head, ChangedPathsOptions{Base: "--all"}); err == nil {
+		t.Error("got no error for a base that begins with '-'")
+	}
+}
@@ -41,6 +41,7 @@ type CommitsOptions struct {
 
 	Author string // include only commits whose author matches this
 	After  string // include only commits after this date
+	Before string // include only commits before this date
 
 	Path string // only commits modifying the given path are selected (optional)
 
@@ -212,6 +213,9 @@ func commitLogArgs(initialArgs []string, opt CommitsOptions) (args []string, err
 	if opt.After != "" {
 		args = append(args, "--after="+opt.After)
 	}
+	if opt.Before != "" {
+		args = append(args, "--before="+opt.Before)
+	}
 
 	if opt.MessageQuery != "" {
 		args = append(args, "--fixed-strings", "--regexp-ignore-case", "--grep="+opt.MessageQuery)
@@ -227,6 +231,68 @@ func commitLogArgs(initialArgs []string, opt CommitsOptions) (args []string, err
 	return args, nil
 }
 
+// CommitTree is a commit and the ID of its root tree.
+type CommitTree struct {
+	CommitID api.CommitID
+	TreeID   OID
+}
+
+// ListCommitTrees returns the commits that are reachable from head and are
+// not ancestors of since (i.e. since and the commits after it), oldest first,
+// together with their root trees. If n is not 0, only the newest n commits
+// are returned.
+func ListCommitTrees(ctx context.Context, repo gitserver.Repo, since, head string, n uint) ([]CommitTree, error) {
+	if Mocks.ListCommitTrees != nil {
+		return Mocks.ListCommitTrees(since, head, n)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: ListCommitTrees")
+	span.SetTag("Since", since)
+	span.SetTag("Head", head)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(since); err != nil {
+		return nil, err
+	}
+	if err := checkSpecArgSafety(head); err != nil {
+		return nil, err
+	}
+	if head == "" {
+		head = "HEAD"
+	}
+
+	args := []string{"log", "--format=format:%H %T", "--reverse"}
+	if n != 0 {
+		args = append(args, "-n", strconv.FormatUint(uint64(n), 10))
+	}
+	// since^@ are the parents of since, so that since itself is included.
+	args = append(args, head, "--not", since+"^@", "--")
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	out, err := cmd.CombinedOutput(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("git command %v failed (output: %q)", cmd.Args, out))
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	commits := make([]CommitTree, 0, len(lines))
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, " ")
+		if len(parts) != 2 || !IsAbsoluteRevision(parts[0]) {
+			return nil, fmt.Errorf("invalid git log output line: %q", line)
+		}
+		treeID, err := decodeOID(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, CommitTree{CommitID: api.CommitID(parts[0]), TreeID: treeID})
+	}
+	return commits, nil
+}
+
 // CommitCount returns the number of commits that would be returned by Commits.
 func CommitCount(ctx context.Context, repo gitserver.Repo, opt CommitsOptions) (uint, error) {
 	span, ctx := ot.StartSpanFromContext(ctx, "Git: CommitCount")
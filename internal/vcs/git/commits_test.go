@@ -211,6 +211,58 @@ func TestRepository_Commits(t *testing.T) {
 	}
 }
 
+func TestRepository_ListCommitTrees(t *testing.T) {
+	t.Parallel()
+
+	gitCommands := []string{
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit --allow-empty -m root --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+		"echo a > a",
+		"git add a",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:06Z git commit -m v1 --author='a <a@a.com>' --date 2006-01-02T15:04:06Z",
+		"git tag v1",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:07Z git commit --allow-empty -m same-tree --author='a <a@a.com>' --date 2006-01-02T15:04:07Z",
+		"echo b > b",
+		"git add b",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:08Z git commit -m b --author='a <a@a.com>' --date 2006-01-02T15:04:08Z",
+	}
+	repo := MakeGitRepository(t, gitCommands...)
+
+	v1, err := ResolveRevision(ctx, repo, nil, "v1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := ResolveRevision(ctx, repo, nil, "HEAD", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := ListCommitTrees(ctx, repo, "v1", "master", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("got %d commits, want 3 (v1 and the commits after it)", len(commits))
+	}
+	if commits[0].CommitID != v1 || commits[2].CommitID != head {
+		t.Errorf("got commits %v, want oldest first from %s to %s", commits, v1, head)
+	}
+	if commits[0].TreeID != commits[1].TreeID || commits[1].TreeID == commits[2].TreeID {
+		t.Errorf("got trees %s %s %s, want the first two to be equal", commits[0].TreeID, commits[1].TreeID, commits[2].TreeID)
+	}
+
+	newest, err := ListCommitTrees(ctx, repo, "v1", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newest) != 2 || newest[0] != commits[1] || newest[1] != commits[2] {
+		t.Errorf("got commits %v, want the newest 2 of %v", newest, commits)
+	}
+
+	if _, err := ListCommitTrees(ctx, repo, "--all", "", 0); err == nil {
+		t.Error("expected an error for an unsafe revision")
+	}
+}
+
 func TestRepository_Commits_options(t *testing.T) {
 	t.Parallel()
 
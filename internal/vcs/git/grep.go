@@ -0,0 +1,117 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+	"github.com/sourcegraph/sourcegraph/internal/trace/ot"
+)
+
+// GrepOptions specifies options for Grep.
+type GrepOptions struct {
+	Pattern         string // the pattern to search for
+	IsRegExp        bool   // interpret Pattern as a Perl-compatible regexp instead of a fixed string
+	IsCaseSensitive bool   // match case-sensitively
+	IsWordMatch     bool   // only match whole words
+
+	MaxMatches int // the maximum number of matching lines to return (0 means no limit)
+}
+
+// GrepMatch is a line of a file that matches the pattern of a Grep.
+type GrepMatch struct {
+	Path       string
+	LineNumber int // 1-based
+	Line       string
+}
+
+// Grep returns the lines of files in the tree of commit that match
+// opt.Pattern, as reported by git grep. Binary files are skipped. limitHit is
+// true if there were more than opt.MaxMatches matches.
+//
+// Regexp patterns are interpreted as Perl-compatible regexps, which support
+// the RE2 syntax of search queries (such as \d, \b and non-greedy repetition)
+// unlike POSIX regexps. Grep is still far less capable than searcher (matches
+// are reported per line) and is meant as a fallback for when searcher is
+// unavailable.
+func Grep(ctx context.Context, repo gitserver.Repo, commit api.CommitID, opt GrepOptions) (matches []GrepMatch, limitHit bool, err error) {
+	if Mocks.Grep != nil {
+		return Mocks.Grep(commit, opt)
+	}
+
+	span, ctx := ot.StartSpanFromContext(ctx, "Git: Grep")
+	span.SetTag("Commit", commit)
+	span.SetTag("Pattern", opt.Pattern)
+	defer span.Finish()
+
+	if err := checkSpecArgSafety(string(commit)); err != nil {
+		return nil, false, err
+	}
+
+	args := []string{"grep", "--null", "--line-number", "--no-color", "-I", "--full-name"}
+	if opt.IsRegExp {
+		args = append(args, "--perl-regexp")
+	} else {
+		args = append(args, "--fixed-strings")
+	}
+	if !opt.IsCaseSensitive {
+		args = append(args, "--ignore-case")
+	}
+	if opt.IsWordMatch {
+		args = append(args, "--word-regexp")
+	}
+	args = append(args, "-e", opt.Pattern, string(commit), "--")
+
+	// Stop git grep once we have read enough matches.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := gitserver.DefaultClient.Command("git", args...)
+	cmd.Repo = repo
+	rc, err := gitserver.StdoutReader(ctx, cmd)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+
+	// Each line of output is "<commit>:<path>\x00<line number>\x00<line>".
+	prefix := []byte(string(commit) + ":")
+	r := bufio.NewReader(rc)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+			fields := bytes.SplitN(line, []byte{0}, 3)
+			if len(fields) != 3 {
+				return nil, false, fmt.Errorf("unexpected git grep output line %q", line)
+			}
+			lineNumber, err := strconv.Atoi(string(fields[1]))
+			if err != nil {
+				return nil, false, fmt.Errorf("unexpected git grep output line %q", line)
+			}
+			if opt.MaxMatches > 0 && len(matches) == opt.MaxMatches {
+				return matches, true, nil
+			}
+			matches = append(matches, GrepMatch{
+				Path:       string(bytes.TrimPrefix(fields[0], prefix)),
+				LineNumber: lineNumber,
+				Line:       string(fields[2]),
+			})
+		}
+		if err == io.EOF {
+			return matches, false, nil
+		}
+		if err != nil {
+			if len(matches) == 0 && cmd.ExitStatus == 1 {
+				// git grep exits with status 1 if nothing matched.
+				return nil, false, nil
+			}
+			return nil, false, errors.WithMessage(err, fmt.Sprintf("git command %v failed", cmd.Args))
+		}
+	}
+}
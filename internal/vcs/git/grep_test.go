@@ -0,0 +1,81 @@
+package git
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/internal/api"
+)
+
+func TestGrep(t *testing.T) {
+	t.Parallel()
+
+	repo := MakeGitRepository(t,
+		"mkdir dir",
+		"printf 'foo bar\\nFoo\\nfoobar\\n' > dir/a",
+		"printf 'nothing\\n' > b",
+		"printf 'foo\\0' > c.bin",
+		"git add dir/a b c.bin",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m commit1 --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	commitID := api.CommitID(ComputeCommitHash(repo.URL, true))
+
+	tests := map[string]struct {
+		opt          GrepOptions
+		wantMatches  []GrepMatch
+		wantLimitHit bool
+	}{
+		"case insensitive": {
+			opt: GrepOptions{Pattern: "foo"},
+			wantMatches: []GrepMatch{
+				{Path: "dir/a", LineNumber: 1, Line: "foo bar"},
+				{Path: "dir/a", LineNumber: 2, Line: "Foo"},
+				{Path: "dir/a", LineNumber: 3, Line: "foobar"},
+			},
+		},
+		"case sensitive word": {
+			opt: GrepOptions{Pattern: "foo", IsCaseSensitive: true, IsWordMatch: true},
+			wantMatches: []GrepMatch{
+				{Path: "dir/a", LineNumber: 1, Line: "foo bar"},
+			},
+		},
+		"regexp": {
+			opt: GrepOptions{Pattern: "^no+th", IsRegExp: true},
+			wantMatches: []GrepMatch{
+				{Path: "b", LineNumber: 1, Line: "nothing"},
+			},
+		},
+		"regexp with RE2 syntax": {
+			opt: GrepOptions{Pattern: `\bfo+?\s`, IsRegExp: true},
+			wantMatches: []GrepMatch{
+				{Path: "dir/a", LineNumber: 1, Line: "foo bar"},
+			},
+		},
+		"fixed string": {
+			opt: GrepOptions{Pattern: "^no+th"},
+		},
+		"limit": {
+			opt: GrepOptions{Pattern: "foo", MaxMatches: 2},
+			wantMatches: []GrepMatch{
+				{Path: "dir/a", LineNumber: 1, Line: "foo bar"},
+				{Path: "dir/a", LineNumber: 2, Line: "Foo"},
+			},
+			wantLimitHit: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			matches, limitHit, err := Grep(context.Background(), repo, commitID, test.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(matches, test.wantMatches) {
+				t.Errorf("matches: %s", cmp.Diff(test.wantMatches, matches))
+			}
+			if limitHit != test.wantLimitHit {
+				t.Errorf("got limitHit %v, want %v", limitHit, test.wantLimitHit)
+			}
+		})
+	}
+}
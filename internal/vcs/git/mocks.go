@@ -24,6 +24,9 @@ var Mocks, emptyMocks struct {
 	Stat             func(commit api.CommitID, name string) (os.FileInfo, error)
 	GetObject        func(objectName string) (OID, ObjectType, error)
 	Commits          func(repo gitserver.Repo, opt CommitsOptions) ([]*Commit, error)
+	ListCommitTrees  func(since, head string, n uint) ([]CommitTree, error)
+	Grep             func(commit api.CommitID, opt GrepOptions) ([]GrepMatch, bool, error)
+	ChangedPaths     func(head api.CommitID, opt ChangedPathsOptions) ([]string, error)
 }
 
 // ResetMocks clears the mock functions set on Mocks (so that subsequent tests don't inadvertently
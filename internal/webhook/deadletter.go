@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/inconshreveable/log15"
+)
+
+// DeadLetter is a delivery that failed permanently.
+type DeadLetter struct {
+	Delivery
+	// Attempts is the number of delivery attempts that were made.
+	Attempts int
+	// Error is the error of the last attempt.
+	Error string
+	Time  time.Time
+}
+
+// DeadLetterLog logs failed deliveries and keeps the most recent ones in
+// memory, so that they can be inspected (e.g. by site admins) and redelivered.
+type DeadLetterLog struct {
+	mu      sync.Mutex
+	size    int
+	letters []DeadLetter
+}
+
+// DefaultDeadLetters is the dead-letter log used by dispatchers without one.
+var DefaultDeadLetters = NewDeadLetterLog(100)
+
+// NewDeadLetterLog returns a dead-letter log that keeps the size most recent
+// failed deliveries.
+func NewDeadLetterLog(size int) *DeadLetterLog {
+	return &DeadLetterLog{size: size}
+}
+
+// Add records a failed delivery, evicting the oldest one if the log is full.
+func (l *DeadLetterLog) Add(letter DeadLetter) {
+	log15.Error("Webhook delivery failed.", "event", letter.Event, "delivery", letter.ID, "url", letter.URL, "attempts", letter.Attempts, "error", letter.Error)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.size <= 0 {
+		return
+	}
+	if len(l.letters) >= l.size {
+		l.letters = append(l.letters[:0], l.letters[len(l.letters)-l.size+1:]...)
+	}
+	l.letters = append(l.letters, letter)
+}
+
+// List returns the failed deliveries in the log, oldest first.
+func (l *DeadLetterLog) List() []DeadLetter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]DeadLetter(nil), l.letters...)
+}
+
+// Remove removes the failed delivery with the given ID from the log and
+// returns it, e.g. to redeliver it.
+func (l *DeadLetterLog) Remove(id string) (DeadLetter, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, letter := range l.letters {
+		if letter.ID == id {
+			l.letters = append(l.letters[:i], l.letters[i+1:]...)
+			return letter, true
+		}
+	}
+	return DeadLetter{}, false
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNonPublicAddress is returned for webhook URLs whose host is, or resolves
+// to, an address that is not public.
+var ErrNonPublicAddress = errors.New("webhook URL must point to a public address")
+
+// nonPublicNetworks are the networks that webhooks set by untrusted users must
+// not be delivered to, since deliveries are made from inside the Sourcegraph
+// deployment and could reach its internal services and the metadata services
+// of cloud providers.
+var nonPublicNetworks = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // shared address space, used by some cluster networks
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, including cloud metadata services
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved and broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // IPv4/IPv6 translation
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+// internalHostSuffixes are the suffixes of host names that only resolve
+// inside a deployment, such as the names of Kubernetes services.
+var internalHostSuffixes = []string{".localhost", ".local", ".internal", ".svc", ".cluster.local"}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// IsPublicIP reports whether ip is a public address, i.e. not a loopback,
+// private, link-local, multicast or otherwise reserved address.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, n := range nonPublicNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckPublicURL returns an error if rawURL is not an absolute HTTP(S) URL of
+// a public host. The host must not be a single-label or deployment-internal
+// name (e.g. "gitserver" or "frontend.default.svc"), and all of its addresses
+// must be public.
+//
+// Since the addresses of a host can change after the check, deliveries to such
+// URLs must still use PublicClient.
+func CheckPublicURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrNonPublicAddress
+		}
+		return nil
+	}
+	if !strings.Contains(host, ".") {
+		return ErrNonPublicAddress
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return ErrNonPublicAddress
+		}
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return errors.Errorf("webhook URL host %q could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return ErrNonPublicAddress
+		}
+	}
+	return nil
+}
+
+// PublicClient is an HTTP client for delivering webhooks set by untrusted
+// users. It only connects to public addresses (see IsPublicIP). The address is
+// checked when dialing, after DNS resolution, so that redirects to internal
+// hosts and hosts whose addresses changed since CheckPublicURL are rejected
+// too. It doesn't use the proxy of the environment, which could reach internal
+// hosts on its behalf.
+var PublicClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   dialPublicOnly,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// dialPublicOnly is a net.Dialer Control function that rejects connections to
+// addresses that are not public.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return ErrNonPublicAddress
+	}
+	return nil
+}
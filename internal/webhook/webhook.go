@@ -0,0 +1,229 @@
+// Package webhook delivers JSON payloads to webhook URLs of external systems
+// (e.g. Slack relays and ticketing systems) when search events occur.
+//
+// Deliveries are signed with HMAC-SHA256 if the endpoint has a secret, retried
+// with exponential backoff on transient failures, and recorded in a dead-letter
+// log if they fail permanently.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Headers set on every delivery.
+const (
+	// EventHeader is the name of the event that caused the delivery.
+	EventHeader = "X-Sourcegraph-Event"
+	// DeliveryHeader is a unique ID of the delivery. It is the same for all
+	// attempts of a delivery, so that receivers can drop duplicates.
+	DeliveryHeader = "X-Sourcegraph-Delivery"
+	// TimestampHeader is the Unix time at which the delivery was signed.
+	TimestampHeader = "X-Sourcegraph-Timestamp"
+	// SignatureHeader is the signature of the delivery (see Sign). It is only
+	// set if the endpoint has a secret.
+	SignatureHeader = "X-Sourcegraph-Signature"
+)
+
+// Endpoint is a webhook URL that receives deliveries.
+type Endpoint struct {
+	URL string
+	// Secret, if set, is used to sign deliveries.
+	Secret string
+}
+
+// Sign returns the signature of a delivery to an endpoint with the given
+// secret: "sha256=" followed by the hex-encoded HMAC-SHA256 of the timestamp,
+// a period and the body. Receivers verify deliveries by computing the same
+// signature from the TimestampHeader and the body.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = io.WriteString(mac, timestamp+".")
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Default values of Dispatcher fields.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = 30 * time.Second
+)
+
+// Dispatcher delivers payloads to endpoints. The zero value is ready to use.
+type Dispatcher struct {
+	// Client is the HTTP client used for deliveries. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	// MaxAttempts is the number of times a delivery is attempted before it
+	// fails. If zero, DefaultMaxAttempts is used.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry of a delivery. It doubles
+	// with every retry. If zero, DefaultBackoff is used.
+	Backoff time.Duration
+
+	// Timeout is the timeout of a single attempt. If zero, DefaultTimeout is
+	// used.
+	Timeout time.Duration
+
+	// DeadLetters records deliveries that failed. If nil, DefaultDeadLetters
+	// is used.
+	DeadLetters *DeadLetterLog
+}
+
+// DefaultDispatcher is the Dispatcher used by the package-level functions.
+var DefaultDispatcher = &Dispatcher{}
+
+// Deliver delivers payload to endpoint using DefaultDispatcher.
+func Deliver(ctx context.Context, endpoint Endpoint, event string, payload interface{}) error {
+	return DefaultDispatcher.Deliver(ctx, endpoint, event, payload)
+}
+
+// Deliver delivers payload, encoded as JSON, to endpoint. It blocks until the
+// endpoint responds with a 2xx status, the delivery fails permanently or ctx is
+// done. Failed deliveries are recorded in the dead-letter log.
+//
+// Network errors, 429 and 5xx responses are retried. Other responses fail the
+// delivery immediately.
+func (d *Dispatcher) Deliver(ctx context.Context, endpoint Endpoint, event string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "Marshal")
+	}
+	delivery := &Delivery{ID: newDeliveryID(), URL: endpoint.URL, Event: event, Payload: body}
+
+	attempts, err := d.deliver(ctx, endpoint, delivery)
+	if err != nil {
+		deliveries.WithLabelValues(event, "failed").Inc()
+		d.deadLetters().Add(DeadLetter{Delivery: *delivery, Attempts: attempts, Error: err.Error(), Time: time.Now()})
+		return err
+	}
+	deliveries.WithLabelValues(event, "succeeded").Inc()
+	return nil
+}
+
+// DeliverAsync is like Deliver, but returns immediately. The outcome of the
+// delivery is only visible in the dead-letter log.
+func (d *Dispatcher) DeliverAsync(endpoint Endpoint, event string, payload interface{}) {
+	go func() {
+		_ = d.Deliver(context.Background(), endpoint, event, payload)
+	}()
+}
+
+// deliver attempts the delivery until it succeeds or fails permanently, and
+// returns the number of attempts.
+func (d *Dispatcher) deliver(ctx context.Context, endpoint Endpoint, delivery *Delivery) (attempts int, err error) {
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	backoff := d.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	for attempts = 1; ; attempts++ {
+		var retry bool
+		retry, err = d.attempt(ctx, endpoint, delivery)
+		if err == nil || !retry || attempts >= maxAttempts {
+			return attempts, err
+		}
+		deliveries.WithLabelValues(delivery.Event, "retried").Inc()
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return attempts, errors.Wrap(ctx.Err(), err.Error())
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes a single delivery attempt and reports whether a failed attempt
+// should be retried.
+func (d *Dispatcher) attempt(ctx context.Context, endpoint Endpoint, delivery *Delivery) (retry bool, err error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequest("POST", endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return false, errors.Wrap(err, "NewRequest")
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, timestamp)
+	if endpoint.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, delivery.Payload))
+	}
+
+	resp, err := ctxhttp.Do(ctx, d.Client, req)
+	if err != nil {
+		return !errors.Is(err, ErrNonPublicAddress), errors.Wrap(err, "Post")
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded with HTTP status %d", resp.StatusCode)
+}
+
+func (d *Dispatcher) deadLetters() *DeadLetterLog {
+	if d.DeadLetters != nil {
+		return d.DeadLetters
+	}
+	return DefaultDeadLetters
+}
+
+// Delivery is a payload delivered to a webhook URL.
+type Delivery struct {
+	ID    string
+	URL   string
+	Event string
+	// Payload is the JSON body of the delivery.
+	Payload json.RawMessage
+}
+
+func newDeliveryID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+var deliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "webhook",
+	Name:      "deliveries_total",
+	Help:      "Total number of webhook delivery outcomes (succeeded, retried or failed) by event.",
+}, []string{"event", "outcome"})
+
+func init() {
+	prometheus.MustRegister(deliveries)
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_Deliver(t *testing.T) {
+	var (
+		calls     int32
+		responses []int
+		lastReq   *http.Request
+		lastBody  []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := atomic.AddInt32(&calls, 1) - 1
+		lastReq = r
+		lastBody, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(responses[i])
+	}))
+	defer srv.Close()
+
+	send := func(t *testing.T, codes ...int) (*DeadLetterLog, error) {
+		t.Helper()
+		atomic.StoreInt32(&calls, 0)
+		responses = codes
+		deadLetters := NewDeadLetterLog(10)
+		d := &Dispatcher{MaxAttempts: 3, Backoff: time.Millisecond, DeadLetters: deadLetters}
+		err := d.Deliver(context.Background(), Endpoint{URL: srv.URL, Secret: "s"}, "test", map[string]string{"a": "b"})
+		return deadLetters, err
+	}
+
+	t.Run("signed", func(t *testing.T) {
+		if _, err := send(t, http.StatusOK); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(lastBody), `{"a":"b"}`; got != want {
+			t.Errorf("got body %q, want %q", got, want)
+		}
+		if got := lastReq.Header.Get(EventHeader); got != "test" {
+			t.Errorf("got event %q, want %q", got, "test")
+		}
+		if lastReq.Header.Get(DeliveryHeader) == "" {
+			t.Error("got no delivery ID")
+		}
+		want := Sign("s", lastReq.Header.Get(TimestampHeader), lastBody)
+		if got := lastReq.Header.Get(SignatureHeader); got != want {
+			t.Errorf("got signature %q, want %q", got, want)
+		}
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		deadLetters, err := send(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != 3 {
+			t.Errorf("got %d attempts, want 3", calls)
+		}
+		if letters := deadLetters.List(); len(letters) != 0 {
+			t.Errorf("got dead letters %+v, want none", letters)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		deadLetters, err := send(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+		if err == nil {
+			t.Fatal("got nil error, want error")
+		}
+		letters := deadLetters.List()
+		if len(letters) != 1 || letters[0].Attempts != 3 || letters[0].Event != "test" {
+			t.Fatalf("got dead letters %+v, want one after 3 attempts", letters)
+		}
+		if _, ok := deadLetters.Remove(letters[0].ID); !ok {
+			t.Error("could not remove dead letter")
+		}
+	})
+
+	t.Run("not retried", func(t *testing.T) {
+		if _, err := send(t, http.StatusBadRequest); err == nil {
+			t.Fatal("got nil error, want error")
+		}
+		if calls != 1 {
+			t.Errorf("got %d attempts, want 1", calls)
+		}
+	})
+}
+
+func TestDeadLetterLog(t *testing.T) {
+	l := NewDeadLetterLog(2)
+	for _, id := range []string{"a", "b", "c"} {
+		l.Add(DeadLetter{Delivery: Delivery{ID: id}})
+	}
+	letters := l.List()
+	if len(letters) != 2 || letters[0].ID != "b" || letters[1].ID != "c" {
+		t.Errorf("got %+v, want b and c", letters)
+	}
+}
+
+func TestPublicClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	deadLetters := NewDeadLetterLog(10)
+	d := &Dispatcher{Client: PublicClient, MaxAttempts: 3, Backoff: time.Millisecond, DeadLetters: deadLetters}
+	err := d.Deliver(context.Background(), Endpoint{URL: srv.URL}, "test", nil)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("got error %v, want %v", err, ErrNonPublicAddress)
+	}
+	if letters := deadLetters.List(); len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Errorf("got dead letters %+v, want a single attempt", letters)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":          true,
+		"2001:4860::8888":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.20.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"::ffff:127.0.0.1": false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+	}
+	for ip, want := range tests {
+		if got := IsPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("%s: got %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestCheckPublicURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://8.8.8.8/hook":                  true,
+		"file:///etc/passwd":                    false,
+		"https:///hook":                         false,
+		"http://127.0.0.1:3090/.internal":       false,
+		"http://[::1]/hook":                     false,
+		"http://169.254.169.254/latest":         false,
+		"http://gitserver-0:3178/exec":          false,
+		"http://localhost/hook":                 false,
+		"http://frontend.default.svc/hook":      false,
+		"http://sourcegraph-frontend.local/":    false,
+		"http://metadata.google.internal/":      false,
+		"http://searcher.ns.svc.cluster.local/": false,
+	}
+	for u, want := range tests {
+		if err := CheckPublicURL(context.Background(), u); (err == nil) != want {
+			t.Errorf("%s: got error %v, want public %v", u, err, want)
+		}
+	}
+}
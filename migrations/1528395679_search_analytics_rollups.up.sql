@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Daily counts of searches by anonymized query features. No query text,
+-- repository or user information is stored.
+CREATE TABLE search_analytics_rollups (
+    day date NOT NULL,
+    pattern_type text NOT NULL,
+    pattern_length text NOT NULL,
+    filters text NOT NULL,
+    latency text NOT NULL,
+    zero_results boolean NOT NULL,
+    count integer NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, pattern_type, pattern_length, filters, latency, zero_results)
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- A generic webhook that the query-runner POSTs new results of the saved
+-- search to (in addition to email and Slack notifications).
+ALTER TABLE saved_searches ADD COLUMN webhook_url text;
+
+COMMIT;
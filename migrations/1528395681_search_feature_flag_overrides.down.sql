@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE search_feature_flag_overrides;
+
+COMMIT;
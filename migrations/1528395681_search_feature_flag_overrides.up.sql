@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Overrides of the flags that gate experimental search behavior, for a user or
+-- an organization. Exactly one of user_id and org_id is set.
+CREATE TABLE search_feature_flag_overrides (
+    flag text NOT NULL,
+    user_id integer REFERENCES users(id) ON DELETE CASCADE,
+    org_id integer REFERENCES orgs(id) ON DELETE CASCADE,
+    enabled boolean NOT NULL,
+    updated_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT search_feature_flag_overrides_user_or_org CHECK ((user_id IS NULL) <> (org_id IS NULL))
+);
+
+CREATE UNIQUE INDEX search_feature_flag_overrides_flag_user_id ON search_feature_flag_overrides(flag, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX search_feature_flag_overrides_flag_org_id ON search_feature_flag_overrides(flag, org_id) WHERE org_id IS NOT NULL;
+
+COMMIT;
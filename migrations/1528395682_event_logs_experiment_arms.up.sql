@@ -0,0 +1,7 @@
+BEGIN;
+
+-- The arms of the search experiments that the user was assigned to when the
+-- event was logged, by experiment name (e.g. {"streaming": "enabled"}).
+ALTER TABLE event_logs ADD COLUMN experiment_arms jsonb;
+
+COMMIT;
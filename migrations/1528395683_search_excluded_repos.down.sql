@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE search_excluded_repos;
+
+COMMIT;
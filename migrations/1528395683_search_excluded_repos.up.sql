@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Repositories that site admins excluded from search (e.g. because they are
+-- sensitive or too large to search).
+CREATE TABLE search_excluded_repos (
+    repo_id integer PRIMARY KEY REFERENCES repo(id) ON DELETE CASCADE,
+    reason text NOT NULL DEFAULT '',
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Exports of the results of searches (see the exportSearch GraphQL mutation).
+-- They are stored in the database so that every frontend replica can report
+-- their state and serve their downloads.
+CREATE TABLE search_exports (
+    id text PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format text NOT NULL,
+    state text NOT NULL DEFAULT 'PROCESSING',
+    error text NOT NULL DEFAULT '',
+    result_count integer NOT NULL DEFAULT 0,
+    contents bytea,
+    signing_key bytea NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    expires_at timestamp with time zone
+);
+
+CREATE INDEX search_exports_user_id_state ON search_exports(user_id, state);
+
+COMMIT;
@@ -58,6 +58,18 @@
 // 1528395677_add_index_user_external_accounts_user_id.up.sql (157B)
 // 1528395678_lsif_auto_index.down.sql (110B)
 // 1528395678_lsif_auto_index.up.sql (868B)
+// 1528395679_search_analytics_rollups.down.sql (54B)
+// 1528395679_search_analytics_rollups.up.sql (478B)
+// 1528395680_saved_search_webhooks.down.sql (69B)
+// 1528395680_saved_search_webhooks.up.sql (208B)
+// 1528395681_search_feature_flag_overrides.down.sql (59B)
+// 1528395681_search_feature_flag_overrides.up.sql (820B)
+// 1528395682_event_logs_experiment_arms.down.sql (69B)
+// 1528395682_event_logs_experiment_arms.up.sql (224B)
+// 1528395683_search_excluded_repos.down.sql (51B)
+// 1528395683_search_excluded_repos.up.sql (343B)
+// 1528395684_search_exports.down.sql (44B)
+// 1528395684_search_exports.up.sql (729B)
 
 package migrations
 
@@ -1286,6 +1298,246 @@ func _1528395678_lsif_auto_indexUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395679_search_analytics_rollupsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x36\x00\xc9\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x65\x61\x72\x63\x68\x5f\x61\x6e\x61\x6c\x79\x74\x69\x63\x73\x5f\x72\x6f\x6c\x6c\x75\x70\x73\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x15\x26\xcd\xe7\x36\x00\x00\x00")
+
+func _1528395679_search_analytics_rollupsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395679_search_analytics_rollupsDownSql,
+		"1528395679_search_analytics_rollups.down.sql",
+	)
+}
+
+func _1528395679_search_analytics_rollupsDownSql() (*asset, error) {
+	bytes, err := _1528395679_search_analytics_rollupsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395679_search_analytics_rollups.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xb5, 0xc3, 0xb5, 0x5e, 0xb3, 0x73, 0x9d, 0xf0, 0xce, 0x22, 0x3c, 0xb8, 0xbd, 0x10, 0x41, 0x63, 0x78, 0x4c, 0xfd, 0xf3, 0xe4, 0x7c, 0x15, 0xd4, 0x59, 0x7, 0x9e, 0x60, 0xfb, 0x8b, 0xa0, 0x70}}
+	return a, nil
+}
+
+var __1528395679_search_analytics_rollupsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x90\xcd\x6e\xc2\x30\x10\x84\xef\x7e\x8a\x39\x82\x14\x50\xef\x9c\x02\xa4\x15\x6a\x08\x15\x0a\x07\x4e\x91\x49\x36\x60\xc9\xd8\xe9\x7a\x23\xd5\x3c\x7d\xc5\x6f\xd5\x8a\x1e\xbd\xdf\x6a\xfc\xcd\x4e\xb3\xb7\x45\x31\x51\x6a\x34\xc2\x5c\x1b\x1b\x51\xfb\xde\x49\x80\x6f\x11\x48\x73\x7d\xa0\x80\x5d\x84\x76\xde\xc5\xa3\x39\x51\x83\xcf\x9e\x38\xa2\x25\x2d\x3d\x53\x18\xa3\xf0\xb7\x91\xd0\x97\x24\xe7\x20\xa6\xce\x07\x23\x9e\x23\x3c\xa3\x0f\xc4\x30\xae\xf5\x7c\xd4\x62\xbc\x83\x09\x08\xe2\x99\x9a\xb1\x9a\xad\xb3\xb4\xcc\x50\xa6\xd3\x3c\xbb\xfd\x57\x69\xa7\x6d\x14\x53\x87\x8a\xbd\xb5\x7d\x17\x30\x50\x00\xd0\xe8\x88\x46\x0b\xa1\x58\x95\x28\x36\x79\x9e\x5c\xc6\x9d\x16\x21\x76\x95\xc4\x8e\x2e\x0a\xff\x70\x4b\x6e\x2f\x87\x67\x1b\xad\xb1\x42\x1c\x9e\x21\xab\x85\x5c\x1d\x9f\xa1\x13\xb1\xaf\x98\x42\x6f\x25\x60\xe7\xbd\x25\xed\xfe\xac\x5c\x4e\x09\xe3\x84\xf6\xc4\x0f\x86\x79\xf6\x9a\x6e\xf2\x12\x2f\xd7\xa0\x8f\xf5\x62\x99\xae\xb7\x78\xcf\xb6\x18\x34\x3a\x26\x0f\xe5\x73\xa5\x9f\xd7\xb5\x40\x72\xd7\x4d\xee\x72\xc9\x2f\x95\xa1\x1a\x4e\x94\x9a\xad\x96\xcb\x45\x39\x51\xdf\x03\x00\xfb\x04\x29\xfc\xde\x01\x00\x00")
+
+func _1528395679_search_analytics_rollupsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395679_search_analytics_rollupsUpSql,
+		"1528395679_search_analytics_rollups.up.sql",
+	)
+}
+
+func _1528395679_search_analytics_rollupsUpSql() (*asset, error) {
+	bytes, err := _1528395679_search_analytics_rollupsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395679_search_analytics_rollups.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x9f, 0xf7, 0xf4, 0xde, 0x8d, 0x9a, 0xc5, 0xdd, 0x7c, 0x4, 0x31, 0xd0, 0x76, 0x16, 0xe3, 0xb4, 0x8b, 0x6d, 0x7b, 0xd, 0x90, 0x1a, 0x32, 0xc, 0xa9, 0x8f, 0x4, 0x8d, 0x49, 0x4c, 0xb, 0x4e}}
+	return a, nil
+}
+
+var __1528395680_saved_search_webhooksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x45\x00\xba\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x73\x61\x76\x65\x64\x5f\x73\x65\x61\x72\x63\x68\x65\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x77\x65\x62\x68\x6f\x6f\x6b\x5f\x75\x72\x6c\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x4b\x07\x3b\xed\x45\x00\x00\x00")
+
+func _1528395680_saved_search_webhooksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395680_saved_search_webhooksDownSql,
+		"1528395680_saved_search_webhooks.down.sql",
+	)
+}
+
+func _1528395680_saved_search_webhooksDownSql() (*asset, error) {
+	bytes, err := _1528395680_saved_search_webhooksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395680_saved_search_webhooks.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd4, 0x75, 0xc5, 0x24, 0xaa, 0x8, 0x61, 0x1b, 0xcd, 0x39, 0xa, 0x46, 0xcf, 0x46, 0x4f, 0x62, 0x66, 0xba, 0xa7, 0xff, 0xba, 0xb, 0x7d, 0x41, 0xba, 0x20, 0x14, 0xbb, 0x43, 0x4a, 0x60, 0x56}}
+	return a, nil
+}
+
+var __1528395680_saved_search_webhooksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x34\xce\xc1\x4e\xc3\x30\x10\x04\xd0\xbb\xbf\x62\x8e\x70\x08\x3f\x90\x53\xda\x46\xa8\x52\xd2\x20\x6a\xce\xd5\x62\x6f\xb1\x55\xb3\x16\xf6\x9a\xc2\xdf\xa3\x50\xf5\x38\x9a\xd1\xd3\x6c\xc6\xe7\xfd\xa1\x37\xa6\xeb\x30\xe0\x83\x85\x4b\x74\xb8\xf2\x7b\xc8\xf9\x02\x0d\xa4\xd0\xc0\xf8\x6a\x5c\x7e\xbb\xd2\x44\xb8\xe0\x65\x39\xda\x0a\xe1\x2b\x0a\xd7\x96\xb4\x22\x9f\xff\x57\x95\xbe\xd9\xaf\x52\x65\x2a\x2e\x40\x33\x1e\xa2\x80\xbc\x8f\x1a\xb3\xac\x99\x3f\x29\x26\x90\x78\x1c\x13\xb9\x0b\x24\x6b\x3c\x47\x47\x6b\x5f\x1f\x9f\xcc\x30\xd9\xf1\x15\x76\xd8\x4c\xe3\x8d\x3b\xdd\x2c\xae\x18\x76\x3b\x6c\x97\xe9\x6d\x3e\xdc\xff\x9d\x5a\x49\x50\xfe\xd1\xde\x98\xed\x32\xcf\x7b\xdb\x9b\xbf\x01\x00\x87\xde\x06\xa2\xd0\x00\x00\x00")
+
+func _1528395680_saved_search_webhooksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395680_saved_search_webhooksUpSql,
+		"1528395680_saved_search_webhooks.up.sql",
+	)
+}
+
+func _1528395680_saved_search_webhooksUpSql() (*asset, error) {
+	bytes, err := _1528395680_saved_search_webhooksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395680_saved_search_webhooks.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x30, 0x0, 0xbf, 0x97, 0x20, 0xd6, 0x8a, 0xb8, 0xdf, 0x47, 0xea, 0x73, 0x4f, 0x8a, 0x4e, 0x2c, 0xa9, 0x1f, 0x6, 0x40, 0x53, 0x42, 0xd8, 0xe, 0x53, 0xfd, 0x3, 0xef, 0x56, 0xf5, 0x56, 0x65}}
+	return a, nil
+}
+
+var __1528395681_search_feature_flag_overridesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x3b\x00\xc4\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x65\x61\x72\x63\x68\x5f\x66\x65\x61\x74\x75\x72\x65\x5f\x66\x6c\x61\x67\x5f\x6f\x76\x65\x72\x72\x69\x64\x65\x73\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x1a\xb7\x13\x96\x3b\x00\x00\x00")
+
+func _1528395681_search_feature_flag_overridesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395681_search_feature_flag_overridesDownSql,
+		"1528395681_search_feature_flag_overrides.down.sql",
+	)
+}
+
+func _1528395681_search_feature_flag_overridesDownSql() (*asset, error) {
+	bytes, err := _1528395681_search_feature_flag_overridesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395681_search_feature_flag_overrides.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe1, 0xf7, 0xc5, 0xb4, 0x4e, 0xba, 0x68, 0x15, 0x4f, 0x13, 0x8e, 0xa0, 0xf7, 0x27, 0x95, 0xf6, 0xba, 0x66, 0x11, 0xfa, 0x86, 0x47, 0xa5, 0x5b, 0xe5, 0x1f, 0x22, 0x18, 0x4f, 0xa, 0xca, 0x49}}
+	return a, nil
+}
+
+var __1528395681_search_feature_flag_overridesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9c\x92\xd1\x6e\x9b\x4c\x10\x85\xef\x79\x8a\x73\x09\x92\x93\x17\xf0\xaf\x5f\x22\x78\xd2\xa0\x92\x45\x05\xac\xf6\x0e\x8d\xc3\x18\x56\xc2\xbb\xd6\xb2\x49\xdc\x3c\x7d\x05\xf6\x5a\x55\x2b\xb9\x55\xef\x98\xd5\x39\x9c\x6f\xce\xee\x03\x7d\xca\xd5\x3a\x8a\xee\xee\x50\xbe\x89\x73\xba\x93\x09\x76\x0f\x3f\x08\xf6\x23\xf7\x13\xfc\xc0\x1e\x3d\x7b\x81\x9c\x8e\xe2\xf4\x41\x8c\xe7\x11\x93\xb0\x7b\x19\xb0\x93\x81\xdf\xb4\x75\x2b\xec\xad\x03\xe3\x75\x12\x07\xeb\xe6\xff\xb1\x81\x75\x3d\x1b\xfd\xc1\x5e\x5b\x73\x0f\x3a\xf1\x8b\x1f\xbf\xc3\x1a\x99\x23\x66\x69\xab\x3b\xb0\xe9\x66\xe1\xfc\xa9\x27\x4c\xe2\xef\xa3\xac\xa2\xb4\x21\x34\xe9\x43\x41\x97\xa4\x76\x2f\xec\x5f\x9d\xb4\x33\x55\x6b\xaf\xac\x71\x04\x60\x41\x85\x97\x93\x87\x2a\x1b\xa8\x6d\x51\xac\x96\xf3\x90\xa1\x8d\x97\x5e\x1c\x2a\x7a\xa4\x8a\x54\x46\xf5\x42\x3a\xc5\xba\x4b\x50\x2a\x6c\xa8\xa0\x86\x90\xa5\x75\x96\x6e\xe8\xec\x0d\x4c\xbf\x5b\xad\xeb\x6f\x3a\xc5\xf0\x6e\x94\x0e\x3b\x6b\x47\x61\xf3\x2b\xd3\xb1\x63\x2f\x5d\xcb\x1e\x5e\x1f\x64\xf2\x7c\x38\xe2\x5d\xfb\x61\x19\xf1\x31\xd7\x13\x1c\xd8\xd0\x63\xba\x2d\x1a\x18\xfb\x1e\x27\x67\xae\xac\x54\x75\x53\xa5\xb9\x6a\x6e\x57\xd3\xce\x1b\xb6\xd6\xb5\xd6\xf5\xc8\x9e\x28\xfb\x8c\x38\x0e\x85\xe4\xf5\x42\x94\xe0\xbf\xff\x11\x5f\x36\x0d\x67\x49\x94\xac\xa3\x70\x07\x5b\x95\x7f\xd9\x12\x72\xb5\xa1\x6f\x7f\xc8\x5b\xc6\x10\x50\xaa\xdb\xea\x78\x1e\x57\xe1\x11\x24\xf8\xfa\x44\x15\x5d\xdf\x44\x5e\x5f\x2b\x58\xff\x2b\xc9\x65\xab\xbf\x04\x39\xab\x03\xc7\x4f\x8d\x5c\x31\xa2\xac\x7c\x7e\xce\x9b\x75\xf4\x63\x00\x70\xa5\x43\xfe\x34\x03\x00\x00")
+
+func _1528395681_search_feature_flag_overridesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395681_search_feature_flag_overridesUpSql,
+		"1528395681_search_feature_flag_overrides.up.sql",
+	)
+}
+
+func _1528395681_search_feature_flag_overridesUpSql() (*asset, error) {
+	bytes, err := _1528395681_search_feature_flag_overridesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395681_search_feature_flag_overrides.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x53, 0xee, 0x85, 0x53, 0xb7, 0xd2, 0x60, 0xfa, 0x6c, 0xec, 0x97, 0xe4, 0xf7, 0x59, 0xa, 0xde, 0xc6, 0x48, 0xc2, 0x57, 0x37, 0x77, 0xe1, 0xea, 0xaa, 0xd1, 0x4c, 0xe5, 0x44, 0x1e, 0x4f, 0x78}}
+	return a, nil
+}
+
+var __1528395682_event_logs_experiment_armsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x45\x00\xba\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x65\x76\x65\x6e\x74\x5f\x6c\x6f\x67\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x65\x78\x70\x65\x72\x69\x6d\x65\x6e\x74\x5f\x61\x72\x6d\x73\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x7e\xc1\x96\x0f\x45\x00\x00\x00")
+
+func _1528395682_event_logs_experiment_armsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395682_event_logs_experiment_armsDownSql,
+		"1528395682_event_logs_experiment_arms.down.sql",
+	)
+}
+
+func _1528395682_event_logs_experiment_armsDownSql() (*asset, error) {
+	bytes, err := _1528395682_event_logs_experiment_armsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395682_event_logs_experiment_arms.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x18, 0x9c, 0x87, 0xe4, 0x5e, 0x94, 0xc3, 0xac, 0x60, 0x8d, 0xc2, 0x98, 0x70, 0xe3, 0x48, 0x57, 0xa, 0xd, 0x14, 0x2, 0x89, 0x91, 0xc9, 0xd9, 0x83, 0x5b, 0xa, 0x49, 0x62, 0x1, 0xa8, 0xb5}}
+	return a, nil
+}
+
+var __1528395682_event_logs_experiment_armsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x4c\xce\xc1\x4a\xc3\x40\x18\xc4\xf1\xfb\x3e\xc5\x90\x93\x82\xcd\x03\x98\x53\xda\x06\x29\x24\x2d\x48\x3c\x97\x8d\x19\x77\x23\xd9\x6f\x65\xbf\xd5\x2a\xe2\xbb\x4b\xd3\x4b\xce\xff\xe1\xc7\x6c\x9b\xa7\xc3\xb1\x32\x66\xb3\x41\xef\x09\x9b\x82\x22\xbe\x21\x7b\x42\x69\xd3\xab\x07\xbf\x3f\x98\xa6\x40\xc9\x8a\xec\x6d\x5e\xda\xa7\x32\xe1\x62\x15\x56\x75\x72\xc2\x11\x39\xe2\xe2\x29\xd7\x7a\xc5\xf8\x45\xc9\xcb\x62\x8e\xce\x71\x7c\xc0\xf0\xb3\xa2\x20\x36\x10\x77\x2c\x5d\x89\xdf\x42\x73\xa2\x0d\x93\xb8\xe2\x11\x05\xc5\x0e\x33\xc7\xe2\xef\xbe\x34\x75\xdb\x37\xcf\xe8\xeb\x6d\xdb\xdc\xc4\xf3\x1c\x9d\xa2\xde\xef\xb1\x3b\xb5\x2f\xdd\x71\x45\x9e\x97\xef\xef\x1a\x65\xa8\x8c\xd9\x9d\xba\xee\xd0\x57\xe6\x7f\x00\x3d\xcd\xdf\x1b\xe0\x00\x00\x00")
+
+func _1528395682_event_logs_experiment_armsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395682_event_logs_experiment_armsUpSql,
+		"1528395682_event_logs_experiment_arms.up.sql",
+	)
+}
+
+func _1528395682_event_logs_experiment_armsUpSql() (*asset, error) {
+	bytes, err := _1528395682_event_logs_experiment_armsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395682_event_logs_experiment_arms.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x1e, 0x4c, 0xf9, 0x40, 0x9d, 0x6d, 0x98, 0x6e, 0xbd, 0xa6, 0xa7, 0xa4, 0x9d, 0xfd, 0x63, 0xdd, 0xa8, 0xe7, 0x31, 0x93, 0x3a, 0x48, 0x9e, 0xfc, 0xfd, 0xec, 0xcf, 0x97, 0x7e, 0xb4, 0xe5, 0x2d}}
+	return a, nil
+}
+
+var __1528395683_search_excluded_reposDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x33\x00\xcc\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x65\x61\x72\x63\x68\x5f\x65\x78\x63\x6c\x75\x64\x65\x64\x5f\x72\x65\x70\x6f\x73\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x62\x91\xbe\x5d\x33\x00\x00\x00")
+
+func _1528395683_search_excluded_reposDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395683_search_excluded_reposDownSql,
+		"1528395683_search_excluded_repos.down.sql",
+	)
+}
+
+func _1528395683_search_excluded_reposDownSql() (*asset, error) {
+	bytes, err := _1528395683_search_excluded_reposDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395683_search_excluded_repos.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x7a, 0x99, 0xd2, 0xea, 0x51, 0xb6, 0xc8, 0xc4, 0xdd, 0xfb, 0xbe, 0xfa, 0xf3, 0xbc, 0xc7, 0xe, 0x15, 0xf8, 0x41, 0x53, 0x64, 0x83, 0x9c, 0xe3, 0xe5, 0xc7, 0x21, 0xda, 0x2f, 0x45, 0x15, 0x3b}}
+	return a, nil
+}
+
+var __1528395683_search_excluded_reposUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x64\x8e\xc1\x6e\xea\x30\x10\x45\xf7\xfe\x8a\xbb\x23\x91\x1e\xfc\x00\xab\x10\x86\x27\xd4\x10\x2a\x63\x16\xac\x22\x37\x9e\x12\x4b\xc4\x46\xf6\x50\x68\xbf\xbe\x82\xd2\x55\x77\x33\xa3\x7b\xce\xdc\x05\xfd\x5f\xb7\x73\xa5\xa6\x53\x68\x3e\xc7\xec\x25\x26\xcf\x19\x32\x58\x41\xf6\xc2\xb0\x6e\xf4\x21\x83\x6f\xfd\xe9\xe2\xd8\xe1\x3d\xc5\x11\x99\x6d\xea\x07\x14\x3c\x3b\xce\xf0\xc6\xbd\xbd\x64\x86\x0c\xfc\x09\x9b\xf8\x2e\xcb\x1c\xb2\x17\xff\xc1\x88\x09\x12\x23\x4e\x36\x1d\x19\x12\x9f\x68\x39\x53\xb5\xa6\xca\x10\x4c\xb5\x68\xe8\x79\xed\x7e\xbf\x74\xe9\x5e\x06\x85\x02\x80\xfb\xdc\x79\x07\x1f\x84\x8f\x9c\xf0\xaa\xd7\x9b\x4a\x1f\xf0\x42\x07\x68\x5a\x91\xa6\xb6\xa6\xdd\x23\x56\x78\x57\x62\xdb\x62\x49\x0d\x19\x42\x5d\xed\xea\x6a\x49\xff\x9e\x1a\x9b\x63\x80\xf0\x4d\xd0\x6e\x0d\xda\x7d\xd3\x60\x49\xab\x6a\xdf\x18\x4c\x26\x3f\xa1\x3e\xb1\x15\x76\x9d\x15\x88\x1f\x39\x8b\x1d\xcf\xb8\x7a\x19\x1e\x2b\xbe\x62\xe0\xbf\x70\x88\xd7\xa2\x54\xe5\x5c\xa9\x7a\xbb\xd9\xac\xcd\x5c\x7d\x0f\x00\xa6\x8c\xc0\xf3\x57\x01\x00\x00")
+
+func _1528395683_search_excluded_reposUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395683_search_excluded_reposUpSql,
+		"1528395683_search_excluded_repos.up.sql",
+	)
+}
+
+func _1528395683_search_excluded_reposUpSql() (*asset, error) {
+	bytes, err := _1528395683_search_excluded_reposUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395683_search_excluded_repos.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd6, 0x93, 0xa3, 0xe6, 0xf8, 0xa1, 0x67, 0x71, 0xbf, 0x78, 0x9d, 0x63, 0xfa, 0x19, 0x70, 0x24, 0x8d, 0x68, 0x75, 0x9e, 0x1a, 0x72, 0x2a, 0xbc, 0x18, 0xca, 0xaa, 0xb1, 0xa1, 0xbd, 0xdb, 0xb0}}
+	return a, nil
+}
+
+var __1528395684_search_exportsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x2c\x00\xd3\xff\x42\x45\x47\x49\x4e\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x73\x65\x61\x72\x63\x68\x5f\x65\x78\x70\x6f\x72\x74\x73\x3b\x0a\x0a\x43\x4f\x4d\x4d\x49\x54\x3b\x0a\x03\x00\x63\xac\xbd\x13\x2c\x00\x00\x00")
+
+func _1528395684_search_exportsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395684_search_exportsDownSql,
+		"1528395684_search_exports.down.sql",
+	)
+}
+
+func _1528395684_search_exportsDownSql() (*asset, error) {
+	bytes, err := _1528395684_search_exportsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395684_search_exports.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x28, 0x2a, 0xea, 0x54, 0xd5, 0x5c, 0x90, 0x61, 0xfd, 0xa9, 0x85, 0xf3, 0xb4, 0xe1, 0x73, 0x42, 0x77, 0xf9, 0x8, 0xc7, 0x8, 0xfa, 0x9e, 0x55, 0xf4, 0x61, 0x6c, 0xd0, 0x70, 0xc1, 0xe6, 0x91}}
+	return a, nil
+}
+
+var __1528395684_search_exportsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x52\x4f\x6f\xa3\x3e\x10\xbd\xf3\x29\xde\xad\x89\xd4\x56\xbf\x7b\x4e\x94\xb8\x11\xfa\x11\xe8\x02\x95\xb6\x27\xe4\xc2\x24\x58\x9b\xd8\x68\x3c\x69\x92\xfd\xf4\x2b\x30\x59\x69\x5b\xed\xde\xf0\xbc\x3f\x7e\xe3\xc7\x93\xda\xa4\xf9\x2a\x8a\x1e\x1e\xa0\x2e\x83\x63\xf1\x70\x3b\x48\x4f\x60\xf2\xa7\x43\x38\x7a\xd2\xdc\xf6\xe4\xb1\xf0\x44\x13\x48\x13\xb7\x9a\xe6\xd8\xb0\x1e\xfa\x6f\x19\x8e\x27\xd1\x62\x9c\x5d\x3e\x8e\x76\x75\x4f\x57\x68\x26\x78\x71\x4c\x1d\x8c\x9d\x94\x9d\x16\xfd\xae\x3d\xc1\x3b\x48\xaf\x05\xf4\x41\x7c\xc5\x8e\x9d\x15\xb2\x1d\x98\x86\x83\x69\x35\x5a\x6d\xc7\x6f\xc7\x32\x9a\x49\x4f\x86\xe1\x45\x0b\x41\xdb\x0e\x9e\xf8\x83\xe6\x69\xe7\xce\xf6\xe0\x74\xe7\x1f\xa3\xa4\x54\x71\xad\x50\xc7\x4f\x99\x9a\x53\x37\x34\xaf\xb5\x88\x00\xc0\x74\x10\xba\x08\x5e\xca\x74\x1b\x97\x6f\xf8\x5f\xbd\xdd\x4f\xc0\xc9\x13\x37\x66\x8c\x29\xb4\x27\x46\x5e\xd4\xc8\x5f\xb3\x0c\xa5\x7a\x56\xa5\xca\x13\x55\x4d\x1c\xbf\x30\xdd\x12\x45\x8e\xb5\xca\x54\xad\x90\xc4\x55\x12\xaf\x55\x30\xd9\x39\x3e\x6a\x09\x37\xdc\x0c\x02\x12\xa2\xff\x01\x60\xad\x9e\xe3\xd7\xac\xc6\xdd\x4b\x59\x24\xaa\xaa\xd2\x7c\x73\x17\xd8\xc4\xec\xf8\x6f\xec\x99\x13\xfa\x69\x5a\x77\xb2\xf2\x35\xf5\x8d\xfd\x5f\x20\xb7\xd3\xeb\x8a\xc7\xfb\x55\x48\xcf\x91\xcc\xde\x1a\xbb\x6f\x7e\xd0\x35\x8c\x7f\xab\x67\x0d\x93\x16\xea\x9a\x71\x21\x73\x24\x2f\xfa\x38\xe0\x6c\xa4\x9f\x8e\xf8\xe9\x2c\x7d\xbd\xcf\xba\xf3\x62\x39\x2f\x71\x19\x0c\x93\xff\x97\x3e\x5a\xae\xa2\x5b\x69\x69\xbe\x56\xdf\x3f\x95\xd6\xcc\xad\x34\xe1\xf9\x8a\xfc\x13\xbe\x98\xf1\xfb\xf0\x6b\x4c\x6e\xc5\x76\x9b\xd6\xab\xe8\xd7\x00\xfa\x10\x2a\xa5\xd9\x02\x00\x00")
+
+func _1528395684_search_exportsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395684_search_exportsUpSql,
+		"1528395684_search_exports.up.sql",
+	)
+}
+
+func _1528395684_search_exportsUpSql() (*asset, error) {
+	bytes, err := _1528395684_search_exportsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395684_search_exports.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xde, 0xa7, 0xbc, 0xa9, 0xb3, 0x2b, 0x63, 0xa5, 0x47, 0xb1, 0x49, 0x8, 0x7b, 0x95, 0x7b, 0x15, 0x31, 0x70, 0xca, 0xc8, 0x3c, 0xc6, 0x3a, 0x8c, 0x7, 0x38, 0x7b, 0xf5, 0x69, 0x14, 0x4b, 0x14}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -1435,17 +1687,31 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395677_add_index_user_external_accounts_user_id.up.sql":              _1528395677_add_index_user_external_accounts_user_idUpSql,
 	"1528395678_lsif_auto_index.down.sql":                                     _1528395678_lsif_auto_indexDownSql,
 	"1528395678_lsif_auto_index.up.sql":                                       _1528395678_lsif_auto_indexUpSql,
+	"1528395679_search_analytics_rollups.down.sql":                            _1528395679_search_analytics_rollupsDownSql,
+	"1528395679_search_analytics_rollups.up.sql":                              _1528395679_search_analytics_rollupsUpSql,
+	"1528395680_saved_search_webhooks.down.sql":                               _1528395680_saved_search_webhooksDownSql,
+	"1528395680_saved_search_webhooks.up.sql":                                 _1528395680_saved_search_webhooksUpSql,
+	"1528395681_search_feature_flag_overrides.down.sql":                       _1528395681_search_feature_flag_overridesDownSql,
+	"1528395681_search_feature_flag_overrides.up.sql":                         _1528395681_search_feature_flag_overridesUpSql,
+	"1528395682_event_logs_experiment_arms.down.sql":                          _1528395682_event_logs_experiment_armsDownSql,
+	"1528395682_event_logs_experiment_arms.up.sql":                            _1528395682_event_logs_experiment_armsUpSql,
+	"1528395683_search_excluded_repos.down.sql":                               _1528395683_search_excluded_reposDownSql,
+	"1528395683_search_excluded_repos.up.sql":                                 _1528395683_search_excluded_reposUpSql,
+	"1528395684_search_exports.down.sql":                                      _1528395684_search_exportsDownSql,
+	"1528395684_search_exports.up.sql":                                        _1528395684_search_exportsUpSql,
 }
 
 // AssetDir returns the file names below a certain
 // directory embedded in the file by go-bindata.
 // For example if you run go-bindata on data/... and data contains the
 // following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
 // then AssetDir("data") would return []string{"foo.txt", "img"},
 // AssetDir("data/img") would return []string{"a.png", "b.png"},
 // AssetDir("foo.txt") and AssetDir("notexist") would return an error, and
@@ -1536,6 +1802,18 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395677_add_index_user_external_accounts_user_id.up.sql":              {_1528395677_add_index_user_external_accounts_user_idUpSql, map[string]*bintree{}},
 	"1528395678_lsif_auto_index.down.sql":                                     {_1528395678_lsif_auto_indexDownSql, map[string]*bintree{}},
 	"1528395678_lsif_auto_index.up.sql":                                       {_1528395678_lsif_auto_indexUpSql, map[string]*bintree{}},
+	"1528395679_search_analytics_rollups.down.sql":                            {_1528395679_search_analytics_rollupsDownSql, map[string]*bintree{}},
+	"1528395679_search_analytics_rollups.up.sql":                              {_1528395679_search_analytics_rollupsUpSql, map[string]*bintree{}},
+	"1528395680_saved_search_webhooks.down.sql":                               {_1528395680_saved_search_webhooksDownSql, map[string]*bintree{}},
+	"1528395680_saved_search_webhooks.up.sql":                                 {_1528395680_saved_search_webhooksUpSql, map[string]*bintree{}},
+	"1528395681_search_feature_flag_overrides.down.sql":                       {_1528395681_search_feature_flag_overridesDownSql, map[string]*bintree{}},
+	"1528395681_search_feature_flag_overrides.up.sql":                         {_1528395681_search_feature_flag_overridesUpSql, map[string]*bintree{}},
+	"1528395682_event_logs_experiment_arms.down.sql":                          {_1528395682_event_logs_experiment_armsDownSql, map[string]*bintree{}},
+	"1528395682_event_logs_experiment_arms.up.sql":                            {_1528395682_event_logs_experiment_armsUpSql, map[string]*bintree{}},
+	"1528395683_search_excluded_repos.down.sql":                               {_1528395683_search_excluded_reposDownSql, map[string]*bintree{}},
+	"1528395683_search_excluded_repos.up.sql":                                 {_1528395683_search_excluded_reposUpSql, map[string]*bintree{}},
+	"1528395684_search_exports.down.sql":                                      {_1528395684_search_exportsDownSql, map[string]*bintree{}},
+	"1528395684_search_exports.up.sql":                                        {_1528395684_search_exportsUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
@@ -824,6 +824,60 @@ type SMTPServerConfig struct {
 	// Username description: The username to use when communicating with the SMTP server.
 	Username string `json:"username,omitempty"`
 }
+
+// SearchEventSink description: A sink for search completion events.
+type SearchEventSink struct {
+	// Address description: The host:port of the statsd server (for type "statsd").
+	Address string `json:"address,omitempty"`
+	// Headers description: Additional HTTP headers sent with each event (for type "http"), e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Prefix description: The prefix of statsd metric names (for type "statsd").
+	Prefix string `json:"prefix,omitempty"`
+	// Type description: The type of the sink. "http" POSTs each event as a JSON object to `url`. "statsd" sends the latency and result count of each search as statsd metrics over UDP to `address`. Other types are available if a sink implementation of that type is registered.
+	Type string `json:"type"`
+	// Url description: The URL events are POSTed to (for type "http").
+	Url string `json:"url,omitempty"`
+}
+
+// SearchLimits description: Limits on the resources and time used by searches. Changes apply to new searches without a restart. See also maxReposToSearch.
+type SearchLimits struct {
+	// DefaultTimeout description: The time after which searches without a timeout: or count: filter stop, e.g. "20s".
+	DefaultTimeout string `json:"defaultTimeout,omitempty"`
+	// MaxResultsPerRequest description: The maximum number of results of a paginated search request (the first argument of the GraphQL API), and the maximum count: of stable: searches.
+	MaxResultsPerRequest int `json:"maxResultsPerRequest,omitempty"`
+	// MaxSearcherRequestsInFlight description: The maximum number of concurrent searcher requests across all searches of a frontend instance. Requests that wait longer than SEARCHER_MAX_QUEUE_WAIT for a free slot fail their search. 0 means no limit. If unset, SEARCHER_MAX_IN_FLIGHT is used.
+	MaxSearcherRequestsInFlight *int `json:"maxSearcherRequestsInFlight,omitempty"`
+	// MaxTimeout description: The maximum time a search runs, even if its timeout: filter is longer, e.g. "1m". It is also the timeout of searches with a count: filter.
+	MaxTimeout string `json:"maxTimeout,omitempty"`
+	// RepositoryTimeout description: The maximum time spent searching a single repository without an index when searching multiple repositories, after which it is reported as timed out, e.g. "10s". "0s" means no limit. If unset, SEARCH_REPO_TIMEOUT is used.
+	RepositoryTimeout string `json:"repositoryTimeout,omitempty"`
+	// SearcherConcurrency description: The number of concurrent requests a frontend instance sends to each searcher instance. If SEARCHER_LATENCY_TARGET is set, this is the initial number, which is adjusted between 1/8 and 4 times this number based on searcher latency.
+	SearcherConcurrency int `json:"searcherConcurrency,omitempty"`
+}
+
+// SearchMirrorDeduplication description: Deduplicates file matches in repositories that are mirrors of each other, such as a repository that is available under several names after a migration between code hosts. Matches of the same file at the same commit in several repositories are only returned once.
+type SearchMirrorDeduplication struct {
+	// Enabled description: Whether file matches in mirrored repositories are deduplicated.
+	Enabled bool `json:"enabled,omitempty"`
+	// Preference description: Regular expressions of repository names, in order of preference. The match in the repository whose name matches the earliest pattern is kept, and the first match is kept among those in equally preferred repositories.
+	Preference []string `json:"preference,omitempty"`
+}
+
+// SearchRule description: A rule that search results are checked against.
+type SearchRule struct {
+	// Description description: A description of the problem that the rule finds, which SARIF exports report.
+	Description string `json:"description,omitempty"`
+	// File description: A regular expression of the paths of the files that the rule applies to. If unset, it applies to all files.
+	File string `json:"file,omitempty"`
+	// Id description: The ID of the rule, which the ruleid: search filter matches.
+	Id string `json:"id"`
+	// Pattern description: The pattern that matching lines contain.
+	Pattern string `json:"pattern"`
+	// PatternType description: The syntax of the pattern: a regular expression, or a structural search pattern, whose holes (such as :[x]) match within a line.
+	PatternType string `json:"patternType,omitempty"`
+	// Severity description: The severity of the problems that the rule finds.
+	Severity string `json:"severity"`
+}
 type SearchSavedQueries struct {
 	// Description description: Description of this saved query
 	Description string `json:"description"`
@@ -850,6 +904,26 @@ type SearchScope struct {
 	// Value description: The query string of this search scope
 	Value string `json:"value"`
 }
+type SearchTenant struct {
+	// MaxConcurrentSearches description: The maximum number of concurrent searches of the tenant per frontend instance. Further searches fail with the TenantQuotaExceeded error code. 0 means no limit.
+	MaxConcurrentSearches int `json:"maxConcurrentSearches,omitempty"`
+	// Name description: The name of the tenant, used in metrics labels and logs.
+	Name string `json:"name"`
+	// Organizations description: The names of the organizations whose members belong to the tenant.
+	Organizations []string `json:"organizations"`
+	// RepositoryPatterns description: Regular expressions of the names of the repositories of the tenant. Searches of the tenant only search repositories whose names match one of them.
+	RepositoryPatterns []string `json:"repositoryPatterns"`
+	// SearcherURL description: The URL of searcher instances dedicated to the tenant, in the format of search.searcherURL. If unset, the tenant's searches are sent to the shared searcher instances.
+	SearcherURL string `json:"searcherURL,omitempty"`
+}
+
+// SearchUsageExamples description: Configures the ranking of searches for usage examples of an API symbol, such as http.NewRequest. File matches that are good usage examples come first: files that are not tests, not vendored, short and recently edited. Searches with rank:examples, and searches whose pattern looks like an API symbol (unless rank:default is given), are ranked this way.
+type SearchUsageExamples struct {
+	// AutoDetect description: Whether searches whose pattern looks like an API symbol (such as http.NewRequest, os.path.join or std::move) are ranked for usage examples without rank:examples.
+	AutoDetect *bool `json:"autoDetect,omitempty"`
+	// Weights description: The weights of the qualities of usage examples. A file match scores the weight of each quality it has (short files and recent edits score a fraction of their weight, depending on the size of the file and on the age of its last commit), and matches with higher scores come first. Qualities that are not listed have weight 0.
+	Weights *Weights `json:"weights,omitempty"`
+}
 
 // Sentry description: Configuration for Sentry
 type Sentry struct {
@@ -1007,8 +1081,10 @@ type SiteConfiguration struct {
 	MaxReposToSearch int `json:"maxReposToSearch,omitempty"`
 	// ObservabilityLogSlowGraphQLRequests description: (debug) logs all GraphQL requests slower than the specified number of milliseconds.
 	ObservabilityLogSlowGraphQLRequests int `json:"observability.logSlowGraphQLRequests,omitempty"`
-	// ObservabilityLogSlowSearches description: (debug) logs all search queries (issued by users, code intelligence, or API requests) slower than the specified number of milliseconds.
+	// ObservabilityLogSlowSearches description: (debug) logs all search queries (issued by users, code intelligence, or API requests) slower than the specified number of milliseconds. The log record includes the shape of the query, repository counts, whether limits were hit and the slowest repositories searched.
 	ObservabilityLogSlowSearches int `json:"observability.logSlowSearches,omitempty"`
+	// ObservabilitySearchEvents description: Sinks that receive an event for every completed search, with its latency, result and repository counts and error code (if any). Events never contain the query, the user or error messages. Events are sent asynchronously and dropped if a sink cannot keep up.
+	ObservabilitySearchEvents []*SearchEventSink `json:"observability.searchEvents,omitempty"`
 	// ObservabilityTracing description: Controls the settings for distributed tracing.
 	ObservabilityTracing *ObservabilityTracing `json:"observability.tracing,omitempty"`
 	// ParentSourcegraph description: URL to fetch unreachable repository details from. Defaults to "https://sourcegraph.com"
@@ -1019,12 +1095,34 @@ type SiteConfiguration struct {
 	PermissionsUserMapping *PermissionsUserMapping `json:"permissions.userMapping,omitempty"`
 	// RepoListUpdateInterval description: Interval (in minutes) for checking code hosts (such as GitHub, Gitolite, etc.) for new repositories.
 	RepoListUpdateInterval int `json:"repoListUpdateInterval,omitempty"`
+	// SearchDefaultExclusions description: Glob patterns of paths that searches exclude by default, such as build output, by language (as named by https://github.com/github/linguist, e.g. "JavaScript"). The patterns have the syntax of .sourcegraph/ignore files. The entry of a language replaces its built-in defaults (such as "/dist/" and "*.min.js" for JavaScript, "__pycache__/" for Python and "/target/" for Java and Rust), so an empty list disables them, and the entry "*" applies to all languages. Searches with lang: filters only exclude the paths of those languages, and searches with defaultexclusions:no exclude none. Paths named by the file: filters of a search are not excluded.
+	SearchDefaultExclusions map[string][]string `json:"search.defaultExclusions,omitempty"`
+	// SearchExperiments description: Runs experiments on search feature flags (see search.featureFlags), by flag name: the percentage of signed-in users for whom the flag is enabled. Each user is deterministically assigned to the enabled or the disabled arm of an experiment, and their analytics events are tagged with their arms, so that the arms can be compared. Users with overrides of a flag and anonymous users are not part of its experiment.
+	SearchExperiments map[string]int `json:"search.experiments,omitempty"`
+	// SearchFeatureFlags description: Enables or disables flags that gate experimental search behavior for all users, by flag name. Overrides for users and organizations (see the setSearchFeatureFlagOverride GraphQL mutation) take precedence. The flags and their defaults are listed by the site.searchFeatureFlags GraphQL field.
+	SearchFeatureFlags map[string]bool `json:"search.featureFlags,omitempty"`
 	// SearchIndexEnabled description: Whether indexed search is enabled. If unset Sourcegraph detects the environment to decide if indexed search is enabled. Indexed search is RAM heavy, and is disabled by default in the single docker image. All other environments will have it enabled by default. The size of all your repository working copies is the amount of additional RAM required.
 	SearchIndexEnabled *bool `json:"search.index.enabled,omitempty"`
 	// SearchIndexSymbolsEnabled description: Whether indexed symbol search is enabled. This is contingent on the indexed search configuration, and is true by default for instances with indexed search enabled. Enabling this will cause every repository to re-index, which is a time consuming (several hours) operation. Additionally, it requires more storage and ram to accommodate the added symbols information in the search index.
 	SearchIndexSymbolsEnabled *bool `json:"search.index.symbols.enabled,omitempty"`
 	// SearchLargeFiles description: A list of file glob patterns where matching files will be indexed and searched regardless of their size. The glob pattern syntax can be found here: https://golang.org/pkg/path/filepath/#Match.
 	SearchLargeFiles []string `json:"search.largeFiles,omitempty"`
+	// SearchLimits description: Limits on the resources and time used by searches. Changes apply to new searches without a restart. See also maxReposToSearch.
+	SearchLimits *SearchLimits `json:"search.limits,omitempty"`
+	// SearchMirrorDeduplication description: Deduplicates file matches in repositories that are mirrors of each other, such as a repository that is available under several names after a migration between code hosts. Matches of the same file at the same commit in several repositories are only returned once.
+	SearchMirrorDeduplication *SearchMirrorDeduplication `json:"search.mirrorDeduplication,omitempty"`
+	// SearchOwnershipFiles description: The paths of the ownership files of repositories, in the CODEOWNERS format, in order of precedence. The first that exists at the searched commit of a repository determines the owners of its files, which search results show and the owner: search filter matches. Defaults to the locations that GitHub and GitLab support: .github/CODEOWNERS, CODEOWNERS and docs/CODEOWNERS.
+	SearchOwnershipFiles []string `json:"search.ownershipFiles,omitempty"`
+	// SearchRules description: Rules (such as security rules) that search results are checked against. The line matches of file matches are tagged with the IDs of the rules whose patterns match the line, the ruleid: and severity: search filters only return the matches of rules, and SARIF exports of searches report the rules that matches hit.
+	SearchRules []*SearchRule `json:"search.rules,omitempty"`
+	// SearchSearcherURL description: The URL of the searcher service, in the format of the SEARCHER_URL environment variable (which is used if this is unset): a space-separated list of URLs, a single URL with a "k8s+" scheme prefix whose endpoints are discovered with the Kubernetes API, or a single URL with a "dnssrv+" scheme prefix whose host names DNS SRV records that are looked up every 30 seconds. Changes apply to new searches without a restart.
+	SearchSearcherURL string `json:"search.searcherURL,omitempty"`
+	// SearchTenants description: (experimental) Isolates the searches of the tenants of a multi-tenant deployment. The searches of members of a tenant's organizations (of the first tenant, if they are members of several) only search the tenant's repositories, are sent to the tenant's searcher instances, and count against the tenant's quota. Searches of other users are not isolated.
+	SearchTenants []*SearchTenant `json:"search.tenants,omitempty"`
+	// SearchUsageExamples description: Configures the ranking of searches for usage examples of an API symbol, such as http.NewRequest. File matches that are good usage examples come first: files that are not tests, not vendored, short and recently edited. Searches with rank:examples, and searches whose pattern looks like an API symbol (unless rank:default is given), are ranked this way.
+	SearchUsageExamples *SearchUsageExamples `json:"search.usageExamples,omitempty"`
+	// SearchWordCharacters description: The characters besides letters, digits and "_" that are part of words in word and identifier searches (see the identifier: search filter), by language (as named by https://github.com/github/linguist, e.g. "CSS"). The entry "*" applies to all languages without entries of their own, and languages without either use built-in defaults (such as "-" for CSS and "$" for JavaScript). The wordchars: search filter overrides this setting for a search.
+	SearchWordCharacters map[string]string `json:"search.wordCharacters,omitempty"`
 	// UpdateChannel description: The channel on which to automatically check for Sourcegraph updates.
 	UpdateChannel string `json:"update.channel,omitempty"`
 	// UseJaeger description: DEPRECATED. Use `"observability.tracing": { "sampling": "all" }`, instead. Enables Jaeger tracing.
@@ -1066,3 +1164,15 @@ type Webhooks struct {
 	// Secret description: Secret for authenticating incoming webhook payloads
 	Secret string `json:"secret,omitempty"`
 }
+
+// Weights description: The weights of the qualities of usage examples. A file match scores the weight of each quality it has (short files and recent edits score a fraction of their weight, depending on the size of the file and on the age of its last commit), and matches with higher scores come first. Qualities that are not listed have weight 0.
+type Weights struct {
+	// NonTest description: The weight of files that are not tests. Defaults to 4.
+	NonTest float64 `json:"nonTest,omitempty"`
+	// NonVendored description: The weight of files that are not vendored. Defaults to 4.
+	NonVendored float64 `json:"nonVendored,omitempty"`
+	// RecentEdit description: The weight of files that were edited recently. It halves every 90 days since the last commit that changed the file. Defaults to 2.
+	RecentEdit float64 `json:"recentEdit,omitempty"`
+	// ShortFile description: The weight of short files. Files of up to 4 KB score it fully. Defaults to 2.
+	ShortFile float64 `json:"shortFile,omitempty"`
+}
@@ -43,6 +43,278 @@ const SiteSchemaJSON = `{
       "group": "Search",
       "examples": [["go.sum", "package-lock.json", "*.thrift"]]
     },
+    "search.ownershipFiles": {
+      "description": "The paths of the ownership files of repositories, in the CODEOWNERS format, in order of precedence. The first that exists at the searched commit of a repository determines the owners of its files, which search results show and the owner: search filter matches. Defaults to the locations that GitHub and GitLab support: .github/CODEOWNERS, CODEOWNERS and docs/CODEOWNERS.",
+      "type": "array",
+      "items": {
+        "type": "string"
+      },
+      "group": "Search",
+      "examples": [[".github/CODEOWNERS", "OWNERS"]]
+    },
+    "search.rules": {
+      "description": "Rules (such as security rules) that search results are checked against. The line matches of file matches are tagged with the IDs of the rules whose patterns match the line, the ruleid: and severity: search filters only return the matches of rules, and SARIF exports of searches report the rules that matches hit.",
+      "type": "array",
+      "items": {
+        "title": "SearchRule",
+        "description": "A rule that search results are checked against.",
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["id", "pattern", "severity"],
+        "properties": {
+          "id": {
+            "description": "The ID of the rule, which the ruleid: search filter matches.",
+            "type": "string",
+            "pattern": "^[a-zA-Z0-9_./-]+$"
+          },
+          "description": {
+            "description": "A description of the problem that the rule finds, which SARIF exports report.",
+            "type": "string"
+          },
+          "pattern": {
+            "description": "The pattern that matching lines contain.",
+            "type": "string"
+          },
+          "patternType": {
+            "description": "The syntax of the pattern: a regular expression, or a structural search pattern, whose holes (such as :[x]) match within a line.",
+            "type": "string",
+            "enum": ["regexp", "structural"],
+            "default": "regexp"
+          },
+          "severity": {
+            "description": "The severity of the problems that the rule finds.",
+            "type": "string",
+            "enum": ["low", "medium", "high", "critical"]
+          },
+          "file": {
+            "description": "A regular expression of the paths of the files that the rule applies to. If unset, it applies to all files.",
+            "type": "string",
+            "format": "regex"
+          }
+        }
+      },
+      "group": "Search",
+      "examples": [
+        [
+          {
+            "id": "go/weak-hash",
+            "description": "Use of a weak hash function",
+            "pattern": "\\b(md5|sha1)\\.(New|Sum)\\b",
+            "severity": "high",
+            "file": "\\.go$"
+          },
+          {
+            "id": "js/eval",
+            "pattern": "eval(:[code])",
+            "patternType": "structural",
+            "severity": "critical"
+          }
+        ]
+      ]
+    },
+    "search.usageExamples": {
+      "description": "Configures the ranking of searches for usage examples of an API symbol, such as http.NewRequest. File matches that are good usage examples come first: files that are not tests, not vendored, short and recently edited. Searches with rank:examples, and searches whose pattern looks like an API symbol (unless rank:default is given), are ranked this way.",
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "autoDetect": {
+          "description": "Whether searches whose pattern looks like an API symbol (such as http.NewRequest, os.path.join or std::move) are ranked for usage examples without rank:examples.",
+          "type": "boolean",
+          "default": true
+        },
+        "weights": {
+          "description": "The weights of the qualities of usage examples. A file match scores the weight of each quality it has (short files and recent edits score a fraction of their weight, depending on the size of the file and on the age of its last commit), and matches with higher scores come first. Qualities that are not listed have weight 0.",
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "nonTest": {
+              "description": "The weight of files that are not tests. Defaults to 4.",
+              "type": "number",
+              "minimum": 0
+            },
+            "nonVendored": {
+              "description": "The weight of files that are not vendored. Defaults to 4.",
+              "type": "number",
+              "minimum": 0
+            },
+            "shortFile": {
+              "description": "The weight of short files. Files of up to 4 KB score it fully. Defaults to 2.",
+              "type": "number",
+              "minimum": 0
+            },
+            "recentEdit": {
+              "description": "The weight of files that were edited recently. It halves every 90 days since the last commit that changed the file. Defaults to 2.",
+              "type": "number",
+              "minimum": 0
+            }
+          }
+        }
+      },
+      "group": "Search",
+      "examples": [{ "autoDetect": false }, { "weights": { "nonTest": 4, "nonVendored": 8, "shortFile": 1, "recentEdit": 0 } }]
+    },
+    "search.defaultExclusions": {
+      "description": "Glob patterns of paths that searches exclude by default, such as build output, by language (as named by https://github.com/github/linguist, e.g. \"JavaScript\"). The patterns have the syntax of .sourcegraph/ignore files. The entry of a language replaces its built-in defaults (such as \"/dist/\" and \"*.min.js\" for JavaScript, \"__pycache__/\" for Python and \"/target/\" for Java and Rust), so an empty list disables them, and the entry \"*\" applies to all languages. Searches with lang: filters only exclude the paths of those languages, and searches with defaultexclusions:no exclude none. Paths named by the file: filters of a search are not excluded.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "array",
+        "items": { "type": "string" }
+      },
+      "group": "Search",
+      "examples": [{ "JavaScript": ["dist/", "*.min.js", "*.bundle.js"], "Python": [], "*": ["/third_party"] }]
+    },
+    "search.wordCharacters": {
+      "description": "The characters besides letters, digits and \"_\" that are part of words in word and identifier searches (see the identifier: search filter), by language (as named by https://github.com/github/linguist, e.g. \"CSS\"). The entry \"*\" applies to all languages without entries of their own, and languages without either use built-in defaults (such as \"-\" for CSS and \"$\" for JavaScript). The wordchars: search filter overrides this setting for a search.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "string",
+        "pattern": "^[!-/:-@\\[-` + "`" + `{-~]*$"
+      },
+      "group": "Search",
+      "examples": [{ "Shell": "$", "Makefile": "$-", "*": "" }]
+    },
+    "search.mirrorDeduplication": {
+      "description": "Deduplicates file matches in repositories that are mirrors of each other, such as a repository that is available under several names after a migration between code hosts. Matches of the same file at the same commit in several repositories are only returned once.",
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "enabled": {
+          "description": "Whether file matches in mirrored repositories are deduplicated.",
+          "type": "boolean",
+          "default": false
+        },
+        "preference": {
+          "description": "Regular expressions of repository names, in order of preference. The match in the repository whose name matches the earliest pattern is kept, and the first match is kept among those in equally preferred repositories.",
+          "type": "array",
+          "items": {
+            "type": "string",
+            "format": "regex"
+          }
+        }
+      },
+      "group": "Search",
+      "examples": [{ "enabled": true, "preference": ["^github\\.com/", "^gitlab\\.example\\.com/"] }]
+    },
+    "search.limits": {
+      "description": "Limits on the resources and time used by searches. Changes apply to new searches without a restart. See also maxReposToSearch.",
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "searcherConcurrency": {
+          "description": "The number of concurrent requests a frontend instance sends to each searcher instance. If SEARCHER_LATENCY_TARGET is set, this is the initial number, which is adjusted between 1/8 and 4 times this number based on searcher latency.",
+          "type": "integer",
+          "minimum": 1,
+          "default": 32
+        },
+        "maxSearcherRequestsInFlight": {
+          "description": "The maximum number of concurrent searcher requests across all searches of a frontend instance. Requests that wait longer than SEARCHER_MAX_QUEUE_WAIT for a free slot fail their search. 0 means no limit. If unset, SEARCHER_MAX_IN_FLIGHT is used.",
+          "type": "integer",
+          "!go": { "pointer": true },
+          "minimum": 0
+        },
+        "maxResultsPerRequest": {
+          "description": "The maximum number of results of a paginated search request (the first argument of the GraphQL API), and the maximum count: of stable: searches.",
+          "type": "integer",
+          "minimum": 1,
+          "default": 5000
+        },
+        "defaultTimeout": {
+          "description": "The time after which searches without a timeout: or count: filter stop, e.g. \"20s\".",
+          "type": "string",
+          "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$",
+          "default": "20s"
+        },
+        "maxTimeout": {
+          "description": "The maximum time a search runs, even if its timeout: filter is longer, e.g. \"1m\". It is also the timeout of searches with a count: filter.",
+          "type": "string",
+          "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$",
+          "default": "1m"
+        },
+        "repositoryTimeout": {
+          "description": "The maximum time spent searching a single repository without an index when searching multiple repositories, after which it is reported as timed out, e.g. \"10s\". \"0s\" means no limit. If unset, SEARCH_REPO_TIMEOUT is used.",
+          "type": "string",
+          "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+        }
+      },
+      "group": "Search",
+      "examples": [{ "searcherConcurrency": 16, "maxSearcherRequestsInFlight": 256 }]
+    },
+    "search.searcherURL": {
+      "description": "The URL of the searcher service, in the format of the SEARCHER_URL environment variable (which is used if this is unset): a space-separated list of URLs, a single URL with a \"k8s+\" scheme prefix whose endpoints are discovered with the Kubernetes API, or a single URL with a \"dnssrv+\" scheme prefix whose host names DNS SRV records that are looked up every 30 seconds. Changes apply to new searches without a restart.",
+      "type": "string",
+      "group": "Search",
+      "examples": ["http://searcher-0:3181 http://searcher-1:3181", "k8s+http://searcher:3181", "dnssrv+http://_http._tcp.searcher"]
+    },
+    "search.featureFlags": {
+      "description": "Enables or disables flags that gate experimental search behavior for all users, by flag name. Overrides for users and organizations (see the setSearchFeatureFlagOverride GraphQL mutation) take precedence. The flags and their defaults are listed by the site.searchFeatureFlags GraphQL field.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "boolean"
+      },
+      "group": "Search",
+      "examples": [{ "indexedSearch": false }]
+    },
+    "search.experiments": {
+      "description": "Runs experiments on search feature flags (see search.featureFlags), by flag name: the percentage of signed-in users for whom the flag is enabled. Each user is deterministically assigned to the enabled or the disabled arm of an experiment, and their analytics events are tagged with their arms, so that the arms can be compared. Users with overrides of a flag and anonymous users are not part of its experiment.",
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer",
+        "minimum": 0,
+        "maximum": 100
+      },
+      "group": "Search",
+      "examples": [{ "streaming": 50 }]
+    },
+    "search.tenants": {
+      "description": "(experimental) Isolates the searches of the tenants of a multi-tenant deployment. The searches of members of a tenant's organizations (of the first tenant, if they are members of several) only search the tenant's repositories, are sent to the tenant's searcher instances, and count against the tenant's quota. Searches of other users are not isolated.",
+      "type": "array",
+      "items": {
+        "title": "SearchTenant",
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name", "organizations", "repositoryPatterns"],
+        "properties": {
+          "name": {
+            "description": "The name of the tenant, used in metrics labels and logs.",
+            "type": "string",
+            "pattern": "^[a-zA-Z0-9_-]+$"
+          },
+          "organizations": {
+            "description": "The names of the organizations whose members belong to the tenant.",
+            "type": "array",
+            "items": { "type": "string" },
+            "minItems": 1
+          },
+          "repositoryPatterns": {
+            "description": "Regular expressions of the names of the repositories of the tenant. Searches of the tenant only search repositories whose names match one of them.",
+            "type": "array",
+            "items": { "type": "string", "format": "regex" },
+            "minItems": 1
+          },
+          "searcherURL": {
+            "description": "The URL of searcher instances dedicated to the tenant, in the format of search.searcherURL. If unset, the tenant's searches are sent to the shared searcher instances.",
+            "type": "string"
+          },
+          "maxConcurrentSearches": {
+            "description": "The maximum number of concurrent searches of the tenant per frontend instance. Further searches fail with the TenantQuotaExceeded error code. 0 means no limit.",
+            "type": "integer",
+            "minimum": 0
+          }
+        }
+      },
+      "group": "Search",
+      "examples": [
+        [
+          {
+            "name": "acme",
+            "organizations": ["acme"],
+            "repositoryPatterns": ["^github\\.com/acme/"],
+            "searcherURL": "k8s+http://searcher-acme:3181",
+            "maxConcurrentSearches": 20
+          }
+        ]
+      ]
+    },
     "debug.search.symbolsParallelism": {
       "description": "(debug) controls the amount of symbol search parallelism. Defaults to 20. It is not recommended to change this outside of debugging scenarios. This option will be removed in a future version.",
       "type": "integer",
@@ -609,11 +881,18 @@ const SiteSchemaJSON = `{
       }
     },
     "observability.logSlowSearches": {
-      "description": "(debug) logs all search queries (issued by users, code intelligence, or API requests) slower than the specified number of milliseconds.",
+      "description": "(debug) logs all search queries (issued by users, code intelligence, or API requests) slower than the specified number of milliseconds. The log record includes the shape of the query, repository counts, whether limits were hit and the slowest repositories searched.",
       "type": "integer",
       "group": "Debug",
       "examples": [["10000"]]
     },
+    "observability.searchEvents": {
+      "description": "Sinks that receive an event for every completed search, with its latency, result and repository counts and error code (if any). Events never contain the query, the user or error messages. Events are sent asynchronously and dropped if a sink cannot keep up.",
+      "type": "array",
+      "items": { "$ref": "#/definitions/SearchEventSink" },
+      "group": "Debug",
+      "examples": [[{ "type": "http", "url": "https://events.example.com/sourcegraph" }, { "type": "statsd", "address": "localhost:8125" }]]
+    },
     "observability.logSlowGraphQLRequests": {
       "description": "(debug) logs all GraphQL requests slower than the specified number of milliseconds.",
       "type": "integer",
@@ -949,6 +1228,38 @@ const SiteSchemaJSON = `{
           "type": "string"
         }
       }
+    },
+    "SearchEventSink": {
+      "description": "A sink for search completion events.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type"],
+      "properties": {
+        "type": {
+          "description": "The type of the sink. \"http\" POSTs each event as a JSON object to ` + "`" + `url` + "`" + `. \"statsd\" sends the latency and result count of each search as statsd metrics over UDP to ` + "`" + `address` + "`" + `. Other types are available if a sink implementation of that type is registered.",
+          "type": "string",
+          "examples": ["http", "statsd"]
+        },
+        "url": {
+          "description": "The URL events are POSTed to (for type \"http\").",
+          "type": "string",
+          "format": "uri"
+        },
+        "headers": {
+          "description": "Additional HTTP headers sent with each event (for type \"http\"), e.g. for authentication.",
+          "type": "object",
+          "additionalProperties": { "type": "string" }
+        },
+        "address": {
+          "description": "The host:port of the statsd server (for type \"statsd\").",
+          "type": "string"
+        },
+        "prefix": {
+          "description": "The prefix of statsd metric names (for type \"statsd\").",
+          "type": "string",
+          "default": "sourcegraph.search"
+        }
+      }
     }
   }
 }